@@ -0,0 +1,105 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"os"
+	"slices"
+	"sync/atomic"
+)
+
+// AddSummary are the statistics of [Collection.AddDocumentsWithSummary], e.g.
+// for ingestion jobs to report how much work was done.
+type AddSummary struct {
+	// Embedded is the number of embeddings created by the embedding function.
+	Embedded int
+	// Cached is the number of embeddings served by an embedding cache (see
+	// [WithEmbeddingCache]) instead of the embedding function.
+	Cached int
+	// Precomputed is the number of documents that were added with their
+	// embedding.
+	Precomputed int
+	// Skipped is the number of unchanged documents that weren't added again.
+	Skipped int
+	// BytesPersisted is the size of the written document files. It's 0 for
+	// non-persistent DBs. Files of the content store aren't included.
+	BytesPersisted int64
+}
+
+// addStats collects the statistics of adding documents. It's passed to
+// addDocument and the embedding cache via the context.
+type addStats struct {
+	created        atomic.Int64
+	cached         atomic.Int64
+	precomputed    atomic.Int64
+	skipped        atomic.Int64
+	bytesPersisted atomic.Int64
+}
+
+type addStatsContextKey struct{}
+
+// addStatsFromContext returns the statistics to collect, or nil.
+func addStatsFromContext(ctx context.Context) *addStats {
+	stats, _ := ctx.Value(addStatsContextKey{}).(*addStats)
+	return stats
+}
+
+// summary returns the collected statistics.
+func (s *addStats) summary() AddSummary {
+	cached := s.cached.Load()
+	return AddSummary{
+		// Cache hits are returned by the embedding function as well.
+		Embedded:       int(s.created.Load() - cached),
+		Cached:         int(cached),
+		Precomputed:    int(s.precomputed.Load()),
+		Skipped:        int(s.skipped.Load()),
+		BytesPersisted: s.bytesPersisted.Load(),
+	}
+}
+
+// AddDocumentsWithSummary is like [Collection.AddDocuments], but returns
+// statistics about the added documents. Also, documents without an embedding
+// are skipped if a document with the same ID, content, embedding text, data and
+// metadata exists, so that re-running an ingestion job doesn't embed them again.
+//
+// Upon error, the statistics of the documents that were added until then are
+// returned along with the error.
+func (c *Collection) AddDocumentsWithSummary(ctx context.Context, documents []Document, concurrency int) (AddSummary, error) {
+	stats := &addStats{}
+	ctx = context.WithValue(ctx, addStatsContextKey{}, stats)
+	err := c.AddDocuments(ctx, documents, concurrency)
+	return stats.summary(), err
+}
+
+// isUnchanged returns whether a document with the same ID, content, embedding
+// text, data and metadata exists. The embedding isn't compared, as the
+// document's embedding is going to be created from the same text.
+func (c *Collection) isUnchanged(doc Document) bool {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	existing, ok := c.documents[doc.ID]
+	if !ok || c.discardContent {
+		return false
+	}
+	existing, err := c.readable(existing)
+	if err != nil {
+		return false
+	}
+	return existing.Content == doc.Content &&
+		existing.embeddingText() == doc.embeddingText() &&
+		existing.MIMEType == doc.MIMEType &&
+		bytes.Equal(existing.Data, doc.Data) &&
+		maps.Equal(existing.Metadata, doc.Metadata) &&
+		slices.Equal(existing.SparseEmbedding.Indices, doc.SparseEmbedding.Indices) &&
+		slices.Equal(existing.SparseEmbedding.Values, doc.SparseEmbedding.Values) &&
+		slices.EqualFunc(existing.TokenEmbeddings, doc.TokenEmbeddings, slices.Equal[[]float32])
+}
+
+// recordPersisted adds the size of the document's file to the statistics.
+func (s *addStats) recordPersisted(path string) {
+	fi, err := os.Stat(path)
+	if err == nil {
+		s.bytesPersisted.Add(fi.Size())
+	}
+}
@@ -0,0 +1,66 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollection_AddDocumentsWithSummary(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	calls := 0
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		calls++
+		return []float32{float32(len(text)), 1}, nil
+	}
+	c, err := db.CreateCollection("test", nil, ChainEmbeddingFunc(embed, WithEmbeddingCache(10)))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	docs := []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "foo"},
+		{ID: "3", Content: "bar", Embedding: []float32{1, 0}},
+	}
+	// Sequentially, so that the second "foo" is a cache hit.
+	summary, err := c.AddDocumentsWithSummary(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if summary.Embedded != 1 || summary.Cached != 1 || summary.Precomputed != 1 || summary.Skipped != 0 {
+		t.Fatal("expected 1 embedded, 1 cached, 1 precomputed, got", summary)
+	}
+	if summary.BytesPersisted <= 0 {
+		t.Fatal("expected persisted bytes, got", summary.BytesPersisted)
+	}
+	if calls != 1 {
+		t.Fatal("expected 1 call of the embedding function, got", calls)
+	}
+
+	// Unchanged documents are skipped on the second run, changed ones are added.
+	docs[1].Metadata = map[string]string{"a": "b"}
+	summary, err = c.AddDocumentsWithSummary(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if summary.Embedded != 0 || summary.Cached != 1 || summary.Precomputed != 1 || summary.Skipped != 1 {
+		t.Fatal("expected 1 cached, 1 precomputed, 1 skipped, got", summary)
+	}
+	doc, err := c.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["a"] != "b" {
+		t.Fatal("expected updated metadata, got", doc.Metadata)
+	}
+}
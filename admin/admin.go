@@ -0,0 +1,277 @@
+// Package admin provides an optional HTTP handler with a small web UI for
+// chromem-go, to browse collections, inspect documents and their metadata, run
+// test queries against the live DB, and view stats. It's meant for development.
+// To expose it beyond localhost, wrap it with [RequireAuth] and serve it via TLS,
+// optionally with client certificates (see [NewMutualTLSConfig]).
+//
+// The handler only uses relative links, so it can be mounted under any path
+// with [http.StripPrefix]:
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", admin.NewHandler(db, embeddingFunc)))
+package admin
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+const pageSize = 50
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"pathEscape": url.PathEscape,
+	"truncate":   truncate,
+	"add":        func(a, b int) int { return a + b },
+	"formatTime": formatTime,
+}).ParseFS(templateFS, "templates/*.html"))
+
+type handler struct {
+	db    *chromem.DB
+	embed chromem.EmbeddingFunc
+}
+
+// NewHandler returns an HTTP handler serving the admin UI for the given DB.
+//
+//   - db: The DB to browse and query.
+//   - embeddingFunc: The embedding function for test queries on collections that
+//     don't have one yet, e.g. after loading a persistent DB. If nil, the default
+//     embedding function is used, see [chromem.DB.GetCollection].
+func NewHandler(db *chromem.DB, embeddingFunc chromem.EmbeddingFunc) http.Handler {
+	return &handler{
+		db:    db,
+		embed: embeddingFunc,
+	}
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Split the escaped path, so that names and IDs can contain slashes.
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(s)
+		if err != nil {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+	// Links are relative to the root of the handler, which depends on the depth
+	// of the current page.
+	root := "./"
+	if depth := len(segments) - 1; depth > 0 {
+		root = strings.Repeat("../", depth)
+	}
+
+	switch {
+	case len(segments) == 1 && segments[0] == "":
+		h.serveIndex(w, r, root)
+	case len(segments) == 2 && segments[0] == "collections":
+		h.serveCollection(w, r, root, segments[1])
+	case len(segments) == 4 && segments[0] == "collections" && segments[2] == "documents":
+		h.serveDocument(w, r, root, segments[1], segments[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type collectionInfo struct {
+	Name           string
+	Count          int
+	Metadata       map[string]string
+	EmbeddingModel string
+	ModifiedAt     time.Time
+}
+
+func (h *handler) serveIndex(w http.ResponseWriter, r *http.Request, root string) {
+	collections := h.db.ListCollections()
+	infos := make([]collectionInfo, 0, len(collections))
+	totalDocs := 0
+	for _, c := range collections {
+		if !allowed(r.Context(), c.CurrentName()) {
+			continue
+		}
+		count := c.Count()
+		totalDocs += count
+		infos = append(infos, collectionInfo{
+			Name:           c.CurrentName(),
+			Count:          count,
+			Metadata:       c.Metadata(),
+			EmbeddingModel: c.EmbeddingModel(),
+			ModifiedAt:     c.ModifiedAt(),
+		})
+	}
+	slices.SortFunc(infos, func(a, b collectionInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	render(w, "index.html", map[string]any{
+		"Root":        root,
+		"Collections": infos,
+		"TotalDocs":   totalDocs,
+		"HeapAlloc":   formatBytes(memStats.HeapAlloc),
+		"Goroutines":  runtime.NumGoroutine(),
+	})
+}
+
+func (h *handler) serveCollection(w http.ResponseWriter, r *http.Request, root, name string) {
+	if !allowed(r.Context(), name) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	c := h.db.GetCollection(name, h.embed)
+	if c == nil {
+		http.Error(w, fmt.Sprintf("collection '%s' doesn't exist", name), http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	data := map[string]any{
+		"Root":           root,
+		"Name":           c.CurrentName(),
+		"Count":          c.Count(),
+		"Metadata":       c.Metadata(),
+		"EmbeddingModel": c.EmbeddingModel(),
+		"CreatedAt":      c.CreatedAt(),
+		"ModifiedAt":     c.ModifiedAt(),
+		"Query":          query.Get("q"),
+		"Where":          query.Get("where"),
+		"N":              10,
+	}
+
+	// Test query
+	if q := query.Get("q"); q != "" {
+		n := 10
+		if s := query.Get("n"); s != "" {
+			var err error
+			n, err = strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+		data["N"] = n
+		var where map[string]string
+		if s := query.Get("where"); s != "" {
+			err := json.Unmarshal([]byte(s), &where)
+			if err != nil {
+				http.Error(w, "where must be a JSON object with string values", http.StatusBadRequest)
+				return
+			}
+		}
+		res, err := c.Query(r.Context(), q, min(n, c.Count()), where, nil)
+		if err != nil {
+			data["QueryError"] = err.Error()
+		} else {
+			data["Results"] = res
+		}
+	}
+
+	// Documents, paginated
+	ids := c.ListIDs()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	offset = max(0, min(offset, len(ids)))
+	end := min(offset+pageSize, len(ids))
+	docs := make([]chromem.Document, 0, end-offset)
+	for _, id := range ids[offset:end] {
+		doc, err := c.GetByID(r.Context(), id)
+		if err != nil {
+			// Deleted in the meantime
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	data["Documents"] = docs
+	data["Offset"] = offset
+	if offset > 0 {
+		data["PrevOffset"] = max(0, offset-pageSize)
+	}
+	if end < len(ids) {
+		data["NextOffset"] = end
+	}
+
+	render(w, "collection.html", data)
+}
+
+func (h *handler) serveDocument(w http.ResponseWriter, r *http.Request, root, name, id string) {
+	if !allowed(r.Context(), name) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	c := h.db.GetCollection(name, h.embed)
+	if c == nil {
+		http.Error(w, fmt.Sprintf("collection '%s' doesn't exist", name), http.StatusNotFound)
+		return
+	}
+	doc, err := c.GetByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	render(w, "document.html", map[string]any{
+		"Root":       root,
+		"Collection": c.CurrentName(),
+		"Document":   doc,
+		"Dimensions": len(doc.Embedding),
+	})
+}
+
+func render(w http.ResponseWriter, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		http.Error(w, "couldn't render template: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// truncate shortens s to at most n runes, adding an ellipsis if it was cut.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
+
+// formatTime formats t in UTC, or returns "unknown" for the zero time of
+// collections that didn't record it.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.UTC().Format(time.DateTime)
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	db := chromem.NewDB()
+	c, err := db.CreateCollection("my/collection", map[string]string{"foo": "bar"}, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []chromem.Document{
+		{ID: "doc 1", Content: "<b>hello</b>", Metadata: map[string]string{"category": "news"}},
+		{ID: "doc/2", Content: "world"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", NewHandler(db, embeddingFunc)))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	tt := []struct {
+		name     string
+		path     string
+		status   int
+		contains []string
+	}{
+		{
+			name:     "Index",
+			path:     "/admin/",
+			status:   http.StatusOK,
+			contains: []string{`href="./collections/my%2Fcollection"`, "<td>2</td>"},
+		},
+		{
+			name:   "Collection",
+			path:   "/admin/collections/my%2Fcollection",
+			status: http.StatusOK,
+			contains: []string{
+				`href="../collections/my%2Fcollection/documents/doc%2F2"`,
+				"&lt;b&gt;hello&lt;/b&gt;",
+				"<code>foo</code>: bar",
+			},
+		},
+		{
+			name:     "Query",
+			path:     `/admin/collections/my%2Fcollection?q=test&n=5&where={"category":"news"}`,
+			status:   http.StatusOK,
+			contains: []string{"<td>1.0000</td>", "documents/doc%201"},
+		},
+		{
+			name:   "Invalid where",
+			path:   "/admin/collections/my%2Fcollection?q=test&where=invalid",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:     "Document",
+			path:     "/admin/collections/my%2Fcollection/documents/doc%2F2",
+			status:   http.StatusOK,
+			contains: []string{`href="../../../"`, "<pre>world</pre>", "<td>2</td>"},
+		},
+		{
+			name:   "Unknown collection",
+			path:   "/admin/collections/unknown",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "Unknown document",
+			path:   "/admin/collections/my%2Fcollection/documents/unknown",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(ts.URL + tc.path)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if resp.StatusCode != tc.status {
+				t.Fatal("expected status", tc.status, "got", resp.StatusCode, string(body))
+			}
+			for _, s := range tc.contains {
+				if !strings.Contains(string(body), s) {
+					t.Fatal("expected body to contain", s, "got", string(body))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIKey is a credential for [RequireAuth], with optional collection scoping and
+// rate limiting.
+type APIKey struct {
+	// Key is the secret. Clients send it as "Authorization: Bearer <key>", as
+	// "X-API-Key: <key>" header or as basic auth password (with any username), so
+	// it also works in browsers. Optional if ClientCommonName is set.
+	Key string
+	// ClientCommonName authenticates requests with a verified TLS client certificate
+	// with this subject common name, see [NewMutualTLSConfig]. Optional.
+	ClientCommonName string
+	// Collections limits the key to these collections. If empty, all collections
+	// are accessible.
+	Collections []string
+	// RateLimit is the number of requests allowed per RateLimitPer, with bursts
+	// of up to RateLimit requests. If 0, there's no limit.
+	RateLimit    int
+	RateLimitPer time.Duration
+}
+
+type apiKeyContextKey struct{}
+
+// RequireAuth returns a handler that only passes requests to next if they're
+// authenticated with one of the keys, and rejects them with status 401 otherwise.
+// Requests exceeding the key's rate limit are rejected with status 429 and a
+// Retry-After header. The admin UI only shows and serves the collections that
+// the key is scoped to.
+func RequireAuth(next http.Handler, keys ...APIKey) http.Handler {
+	limiters := make([]*rateLimiter, len(keys))
+	for i, k := range keys {
+		if k.RateLimit > 0 {
+			limiters[i] = newRateLimiter(k.RateLimit, k.RateLimitPer)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := authenticate(r, keys)
+		if i < 0 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chromem-go"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if limiters[i] != nil {
+			if wait := limiters[i].reserve(time.Now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), apiKeyContextKey{}, &keys[i])
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate returns the index of the key that the request is authenticated
+// with, or -1.
+func authenticate(r *http.Request, keys []APIKey) int {
+	var secret string
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		secret = strings.TrimPrefix(auth, "Bearer ")
+	} else if key := r.Header.Get("X-API-Key"); key != "" {
+		secret = key
+	} else if _, password, ok := r.BasicAuth(); ok {
+		secret = password
+	}
+
+	if secret != "" {
+		for i, k := range keys {
+			if k.Key != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(k.Key)) == 1 {
+				return i
+			}
+		}
+	}
+
+	// The TLS server only puts verified certificates into VerifiedChains.
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for i, k := range keys {
+			if k.ClientCommonName != "" && k.ClientCommonName == cn {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// allowed returns whether the request's API key, if any, is scoped to the
+// collection.
+func allowed(ctx context.Context, collection string) bool {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	if !ok || len(key.Collections) == 0 {
+		return true
+	}
+	return slices.Contains(key.Collections, collection)
+}
+
+// NewMutualTLSConfig returns a TLS config for an [http.Server] that requires
+// clients to present a certificate signed by one of the CAs in the clientCAFile.
+// Combine it with [APIKey.ClientCommonName] to map certificates to keys.
+//
+//   - certFile, keyFile: The PEM encoded server certificate and key.
+//   - clientCAFile: The PEM encoded CA certificates to verify clients with.
+func NewMutualTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load server certificate: %w", err)
+	}
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read client CA file: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("client CA file contains no certificates")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// rateLimiter allows n requests per duration, with bursts of up to n requests.
+// It's a GCRA, so it only needs the theoretical arrival time as state.
+type rateLimiter struct {
+	interval  time.Duration
+	tolerance time.Duration
+	tat       time.Time
+	lock      sync.Mutex
+}
+
+func newRateLimiter(n int, per time.Duration) *rateLimiter {
+	interval := per / time.Duration(n)
+	return &rateLimiter{
+		interval:  interval,
+		tolerance: per - interval,
+	}
+}
+
+// reserve returns 0 if the request is allowed, or how long to wait otherwise.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if wait := tat.Sub(now) - l.tolerance; wait > 0 {
+		return wait
+	}
+	l.tat = tat.Add(l.interval)
+	return 0
+}
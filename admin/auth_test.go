@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestRequireAuth(t *testing.T) {
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db := chromem.NewDB()
+	for _, name := range []string{"public", "private"} {
+		_, err := db.CreateCollection(name, nil, embeddingFunc)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	h := RequireAuth(NewHandler(db, embeddingFunc),
+		APIKey{Key: "admin-key"},
+		APIKey{Key: "scoped-key", Collections: []string{"public"}, RateLimit: 2, RateLimitPer: time.Minute},
+		APIKey{ClientCommonName: "client.example.com"},
+	)
+
+	tt := []struct {
+		name        string
+		path        string
+		setup       func(r *http.Request)
+		status      int
+		contains    string
+		notContains string
+	}{
+		{
+			name:   "No credentials",
+			path:   "/",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "Wrong key",
+			path: "/",
+			setup: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer wrong")
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "Bearer",
+			path: "/",
+			setup: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer admin-key")
+			},
+			status:   http.StatusOK,
+			contains: "collections/private",
+		},
+		{
+			name: "Basic auth",
+			path: "/collections/private",
+			setup: func(r *http.Request) {
+				r.SetBasicAuth("", "admin-key")
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "Scoped index",
+			path: "/",
+			setup: func(r *http.Request) {
+				r.Header.Set("X-API-Key", "scoped-key")
+			},
+			status:      http.StatusOK,
+			contains:    "collections/public",
+			notContains: "collections/private",
+		},
+		{
+			name: "Scoped forbidden",
+			path: "/collections/private",
+			setup: func(r *http.Request) {
+				r.Header.Set("X-API-Key", "scoped-key")
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			// The scoped key's burst of 2 is used up now
+			name: "Rate limited",
+			path: "/collections/public",
+			setup: func(r *http.Request) {
+				r.Header.Set("X-API-Key", "scoped-key")
+			},
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name: "Client certificate",
+			path: "/",
+			setup: func(r *http.Request) {
+				r.TLS = &tls.ConnectionState{
+					VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "client.example.com"}}}},
+				}
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "Unverified client certificate",
+			path: "/",
+			setup: func(r *http.Request) {
+				r.TLS = &tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "client.example.com"}}},
+				}
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.setup != nil {
+				tc.setup(r)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Fatal("expected status", tc.status, "got", w.Code, w.Body.String())
+			}
+			if tc.contains != "" && !strings.Contains(w.Body.String(), tc.contains) {
+				t.Fatal("expected body to contain", tc.contains, "got", w.Body.String())
+			}
+			if tc.notContains != "" && strings.Contains(w.Body.String(), tc.notContains) {
+				t.Fatal("expected body not to contain", tc.notContains, "got", w.Body.String())
+			}
+			if tc.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Fatal("expected Retry-After header")
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// Buckets of the query duration histogram, in seconds.
+var queryDurationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Metrics collects DB and collection stats and query latencies, and exposes them
+// in the Prometheus text format.
+type Metrics struct {
+	db *chromem.DB
+
+	instrumented map[*chromem.Collection]struct{}
+	queries      map[string]*histogram // By collection name
+	lock         sync.Mutex
+}
+
+// NewMetrics returns metrics for the DB. Query latencies are recorded via
+// [chromem.Hooks.AfterQuery], which is registered on all existing collections,
+// and on new collections on each scrape.
+func NewMetrics(db *chromem.DB) *Metrics {
+	m := &Metrics{
+		db:           db,
+		instrumented: make(map[*chromem.Collection]struct{}),
+		queries:      make(map[string]*histogram),
+	}
+	m.instrument(db.ListCollections())
+	return m
+}
+
+// instrument registers the query hook on collections that don't have it yet.
+// Collections that are gone are forgotten, but their histograms are kept, in
+// case a collection with the same name is created again.
+func (m *Metrics) instrument(collections map[string]*chromem.Collection) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for c := range m.instrumented {
+		if collections[c.CurrentName()] != c {
+			delete(m.instrumented, c)
+		}
+	}
+	for name, c := range collections {
+		if _, ok := m.instrumented[c]; ok {
+			continue
+		}
+		m.instrumented[c] = struct{}{}
+		h, ok := m.queries[name]
+		if !ok {
+			h = newHistogram(queryDurationBuckets)
+			m.queries[name] = h
+		}
+		c.AddHooks(chromem.Hooks{
+			AfterQuery: func(_ context.Context, duration time.Duration, err error) {
+				h.observe(duration.Seconds(), err != nil)
+			},
+		})
+	}
+}
+
+// ServeHTTP implements [http.Handler], for a "/metrics" endpoint.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WriteText(w)
+}
+
+// WriteText writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteText(w io.Writer) error {
+	collections := m.db.ListCollections()
+	m.instrument(collections)
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	b := &strings.Builder{}
+
+	fmt.Fprintln(b, "# HELP chromem_collections Number of collections.")
+	fmt.Fprintln(b, "# TYPE chromem_collections gauge")
+	fmt.Fprintln(b, "chromem_collections", len(collections))
+
+	fmt.Fprintln(b, "# HELP chromem_documents Number of documents per collection.")
+	fmt.Fprintln(b, "# TYPE chromem_documents gauge")
+	for _, name := range names {
+		fmt.Fprintf(b, "chromem_documents{collection=\"%s\"} %d\n", escapeLabel(name), collections[name].Count())
+	}
+
+	fmt.Fprintln(b, "# HELP chromem_query_duration_seconds Duration of nearest neighbor searches, without creating the query embedding.")
+	fmt.Fprintln(b, "# TYPE chromem_query_duration_seconds histogram")
+	m.lock.Lock()
+	queryNames := make([]string, 0, len(m.queries))
+	for name := range m.queries {
+		queryNames = append(queryNames, name)
+	}
+	queries := make(map[string]histogramSnapshot, len(m.queries))
+	for name, h := range m.queries {
+		queries[name] = h.snapshot()
+	}
+	m.lock.Unlock()
+	slices.Sort(queryNames)
+	for _, name := range queryNames {
+		s := queries[name]
+		label := escapeLabel(name)
+		for i, le := range s.buckets {
+			fmt.Fprintf(b, "chromem_query_duration_seconds_bucket{collection=\"%s\",le=\"%g\"} %d\n", label, le, s.counts[i])
+		}
+		fmt.Fprintf(b, "chromem_query_duration_seconds_bucket{collection=\"%s\",le=\"+Inf\"} %d\n", label, s.count)
+		fmt.Fprintf(b, "chromem_query_duration_seconds_sum{collection=\"%s\"} %g\n", label, s.sum)
+		fmt.Fprintf(b, "chromem_query_duration_seconds_count{collection=\"%s\"} %d\n", label, s.count)
+	}
+
+	fmt.Fprintln(b, "# HELP chromem_query_errors_total Number of failed nearest neighbor searches.")
+	fmt.Fprintln(b, "# TYPE chromem_query_errors_total counter")
+	for _, name := range queryNames {
+		fmt.Fprintf(b, "chromem_query_errors_total{collection=\"%s\"} %d\n", escapeLabel(name), queries[name].errors)
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	fmt.Fprintln(b, "# HELP go_goroutines Number of goroutines that currently exist.")
+	fmt.Fprintln(b, "# TYPE go_goroutines gauge")
+	fmt.Fprintln(b, "go_goroutines", runtime.NumGoroutine())
+	fmt.Fprintln(b, "# HELP go_memstats_heap_alloc_bytes Number of heap bytes allocated and still in use.")
+	fmt.Fprintln(b, "# TYPE go_memstats_heap_alloc_bytes gauge")
+	fmt.Fprintln(b, "go_memstats_heap_alloc_bytes", memStats.HeapAlloc)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// NewHealthHandler returns a handler for a "/healthz" endpoint. It responds with
+// status 200 if [chromem.DB.Ping] succeeds, and 503 otherwise, with a JSON body
+// like {"status":"ok"}.
+func NewHealthHandler(db *chromem.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		err := db.Ping()
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	})
+}
+
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // Cumulative
+	count   uint64
+	sum     float64
+	errors  uint64
+	lock    sync.Mutex
+}
+
+type histogramSnapshot struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+	errors  uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64, failed bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for i, le := range h.buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+	if failed {
+		h.errors++
+	}
+}
+
+func (h *histogram) snapshot() histogramSnapshot {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return histogramSnapshot{
+		buckets: h.buckets,
+		counts:  slices.Clone(h.counts),
+		count:   h.count,
+		sum:     h.sum,
+		errors:  h.errors,
+	}
+}
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	db := chromem.NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, chromem.Document{ID: "1", Content: "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	m := NewMetrics(db)
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, map[string]string{"a": "b"}, map[string]string{"$invalid": ""})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Collection created after the metrics, instrumented on the next scrape
+	_, err = db.CreateCollection(`with "quotes"`, nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, s := range []string{
+		"chromem_collections 2\n",
+		"chromem_documents{collection=\"test\"} 1\n",
+		"chromem_documents{collection=\"with \\\"quotes\\\"\"} 0\n",
+		"chromem_query_duration_seconds_bucket{collection=\"test\",le=\"+Inf\"} 2\n",
+		"chromem_query_duration_seconds_count{collection=\"test\"} 2\n",
+		"chromem_query_duration_seconds_count{collection=\"with \\\"quotes\\\"\"} 0\n",
+		"chromem_query_errors_total{collection=\"test\"} 1\n",
+		"# TYPE chromem_query_duration_seconds histogram\n",
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatal("expected metrics to contain", s, "got", body)
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	db := chromem.NewDB()
+	ts := httptest.NewServer(NewHealthHandler(db))
+	defer ts.Close()
+
+	check := func(status int, contains string) {
+		t.Helper()
+		resp, err := http.Get(ts.URL)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != status || !strings.Contains(string(body), contains) {
+			t.Fatal("expected", status, contains, "got", resp.StatusCode, string(body))
+		}
+	}
+
+	check(http.StatusOK, `"status":"ok"`)
+	err := db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	check(http.StatusServiceUnavailable, "DB is closed")
+}
@@ -0,0 +1,108 @@
+package chromem
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// AggregateGroup is a group of documents of [Collection.Aggregate].
+type AggregateGroup struct {
+	// Value is the metadata value of the group key.
+	Value string
+	// Missing is true for the group of documents without the group key. Its
+	// Value is empty.
+	Missing bool
+	// Count is the number of documents in the group.
+	Count int
+	// Numeric are the statistics of the numeric metadata values in the group,
+	// per metadata key. Keys whose values aren't numbers in any document of the
+	// group are omitted.
+	Numeric map[string]NumericStats
+}
+
+// NumericStats are statistics of numeric metadata values.
+type NumericStats struct {
+	// Count is the number of documents with a numeric value of the key. Values
+	// that aren't numbers are ignored.
+	Count int
+	Min   float64
+	Max   float64
+}
+
+// Aggregate groups the documents that match the where filter by the value of
+// the metadata key, and returns the number of documents per group and the
+// minimum and maximum of numeric metadata values, e.g. the distribution of
+// sources in the corpus and their date range. The groups are sorted by their
+// count in descending order, then by value.
+//
+//   - where: Conditional filtering on metadata. Optional.
+//   - groupByKey: The metadata key to group by. Documents without the key are
+//     in a group with Missing set to true. If it's empty, all matching
+//     documents are in one group.
+func (c *Collection) Aggregate(where map[string]string, groupByKey string) ([]AggregateGroup, error) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	// The groups by value, and the group of documents without the key.
+	groups := make(map[string]*AggregateGroup)
+	var missing *AggregateGroup
+	for _, doc := range filterDocSlice(c.column.docs(c.documents), where, nil) {
+		var g *AggregateGroup
+		if v, ok := doc.Metadata[groupByKey]; ok || groupByKey == "" {
+			g = groups[v]
+			if g == nil {
+				g = &AggregateGroup{Value: v, Numeric: make(map[string]NumericStats)}
+				groups[v] = g
+			}
+		} else {
+			if missing == nil {
+				missing = &AggregateGroup{Missing: true, Numeric: make(map[string]NumericStats)}
+			}
+			g = missing
+		}
+
+		g.Count++
+		for k, v := range doc.Metadata {
+			f, err := strconv.ParseFloat(v, 64)
+			// ParseFloat accepts "NaN" and "Inf", which aren't meant as numbers.
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				continue
+			}
+			stats, ok := g.Numeric[k]
+			if !ok {
+				stats = NumericStats{Min: f, Max: f}
+			}
+			stats.Count++
+			stats.Min = min(stats.Min, f)
+			stats.Max = max(stats.Max, f)
+			g.Numeric[k] = stats
+		}
+	}
+
+	res := make([]AggregateGroup, 0, len(groups)+1)
+	for _, g := range groups {
+		res = append(res, *g)
+	}
+	if missing != nil {
+		res = append(res, *missing)
+	}
+	slices.SortFunc(res, func(a, b AggregateGroup) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		if a.Missing != b.Missing {
+			// The missing group comes last among groups with the same count.
+			if a.Missing {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return res, nil
+}
@@ -0,0 +1,50 @@
+package chromem
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCollection_Aggregate(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"source": "wiki", "year": "2019", "lang": "en"}, Embedding: []float32{1, 0}},
+		{ID: "2", Metadata: map[string]string{"source": "wiki", "year": "2023", "lang": "de"}, Embedding: []float32{1, 0}},
+		{ID: "3", Metadata: map[string]string{"source": "blog", "year": "unknown", "lang": "en"}, Embedding: []float32{1, 0}},
+		{ID: "4", Metadata: map[string]string{"year": "2020", "lang": "en"}, Embedding: []float32{1, 0}},
+		{ID: "5", Metadata: map[string]string{"source": "wiki", "year": "2021", "lang": "en"}, Embedding: []float32{1, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	groups, err := c.Aggregate(nil, "source")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := []AggregateGroup{
+		{Value: "wiki", Count: 3, Numeric: map[string]NumericStats{"year": {Count: 3, Min: 2019, Max: 2023}}},
+		{Value: "blog", Count: 1, Numeric: map[string]NumericStats{}},
+		{Missing: true, Count: 1, Numeric: map[string]NumericStats{"year": {Count: 1, Min: 2020, Max: 2020}}},
+	}
+	if !reflect.DeepEqual(groups, exp) {
+		t.Fatal("expected", exp, "got", groups)
+	}
+
+	// With a where filter and without group key
+	groups, err = c.Aggregate(map[string]string{"lang": "en"}, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp = []AggregateGroup{
+		{Count: 4, Numeric: map[string]NumericStats{"year": {Count: 3, Min: 2019, Max: 2021}}},
+	}
+	if !reflect.DeepEqual(groups, exp) {
+		t.Fatal("expected", exp, "got", groups)
+	}
+}
@@ -0,0 +1,186 @@
+package chromem
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Analyzer splits a text into the terms of a keyword index, e.g. for [BM25].
+// Queries and documents must be analyzed the same way.
+type Analyzer func(text string) []string
+
+// AnalyzerOptions configures [NewAnalyzer].
+type AnalyzerOptions struct {
+	// Lowercase converts the terms to lowercase.
+	Lowercase bool
+	// FoldDiacritics removes diacritics from Latin letters, e.g. "é" becomes
+	// "e" and "ß" becomes "ss", so that spelling variants match. Combining
+	// marks are removed as well, so decomposed (NFD) text is handled like
+	// composed (NFC) text.
+	FoldDiacritics bool
+	// Stopwords are removed from the terms, e.g. [Stopwords] of a language.
+	// They're normalized like the terms, so they can be given in lowercase and
+	// with diacritics.
+	Stopwords []string
+	// Stem reduces the terms to their stem, e.g. [StemEnglish]. Optional.
+	Stem func(term string) string
+	// MinLength is the minimum number of characters of a term. Shorter terms
+	// are removed. Optional.
+	MinLength int
+}
+
+// NewAnalyzer returns an analyzer that splits texts into terms at characters
+// that are neither letters nor digits, and then normalizes, filters and stems
+// the terms according to the options. CJK characters are separate terms, as
+// these scripts don't separate words with spaces.
+func NewAnalyzer(options AnalyzerOptions) Analyzer {
+	normalize := func(term string) string {
+		if options.Lowercase {
+			term = strings.ToLower(term)
+		}
+		if options.FoldDiacritics {
+			term = foldDiacritics(term)
+		}
+		return term
+	}
+	stopwords := make(map[string]struct{}, len(options.Stopwords))
+	for _, sw := range options.Stopwords {
+		stopwords[normalize(sw)] = struct{}{}
+	}
+
+	return func(text string) []string {
+		var terms []string
+		add := func(term string) {
+			term = normalize(term)
+			if _, ok := stopwords[term]; ok {
+				return
+			}
+			if options.Stem != nil {
+				term = options.Stem(term)
+			}
+			if term == "" || len([]rune(term)) < options.MinLength {
+				return
+			}
+			terms = append(terms, term)
+		}
+
+		start := -1
+		for i, r := range text {
+			isCJK := unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+			// Combining marks belong to the preceding letter.
+			isPart := unicode.IsLetter(r) || unicode.IsDigit(r) || (start >= 0 && unicode.Is(unicode.Mn, r))
+			if start >= 0 && (!isPart || isCJK) {
+				add(text[start:i])
+				start = -1
+			}
+			if isCJK {
+				add(string(r))
+			} else if isPart && start < 0 {
+				start = i
+			}
+		}
+		if start >= 0 {
+			add(text[start:])
+		}
+		return terms
+	}
+}
+
+// NewLanguageAnalyzer returns an analyzer for the language, which lowercases
+// the terms, folds diacritics and removes the language's [Stopwords]. English
+// ("en") terms are stemmed with [StemEnglish]. For other languages, use
+// [NewAnalyzer] with a stemmer of your choice.
+func NewLanguageAnalyzer(language string) Analyzer {
+	options := AnalyzerOptions{
+		Lowercase:      true,
+		FoldDiacritics: true,
+		Stopwords:      Stopwords(language),
+	}
+	if language == "en" {
+		options.Stem = StemEnglish
+	}
+	return NewAnalyzer(options)
+}
+
+// diacriticFolds maps Latin letters with diacritics to their base letters.
+var diacriticFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Ā': "A", 'Ă': "A", 'Ą': "A",
+	'ç': "c", 'ć': "c", 'č': "c", 'Ç': "C", 'Ć': "C", 'Č': "C",
+	'ď': "d", 'đ': "d", 'Ď': "D", 'Đ': "D",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ē': "E", 'Ė': "E", 'Ę': "E", 'Ě': "E",
+	'ğ': "g", 'Ğ': "G",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'Ī': "I", 'Į': "I", 'İ': "I",
+	'ł': "l", 'Ł': "L",
+	'ñ': "n", 'ń': "n", 'ň': "n", 'Ñ': "N", 'Ń': "N", 'Ň': "N",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O", 'Ō': "O", 'Ő': "O",
+	'ř': "r", 'Ř': "R",
+	'ś': "s", 'š': "s", 'ş': "s", 'Ś': "S", 'Š': "S", 'Ş': "S",
+	'ť': "t", 'ţ': "t", 'Ť': "T", 'Ţ': "T",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ū': "U", 'Ů': "U", 'Ű': "U", 'Ų': "U",
+	'ý': "y", 'ÿ': "y", 'Ý': "Y", 'Ÿ': "Y",
+	'ź': "z", 'ż': "z", 'ž': "z", 'Ź': "Z", 'Ż': "Z", 'Ž': "Z",
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'þ': "th", 'Þ': "TH", 'ð': "d", 'Ð': "D",
+}
+
+// foldDiacritics removes diacritics from Latin letters and removes combining
+// marks.
+func foldDiacritics(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range s {
+		if fold, ok := diacriticFolds[r]; ok {
+			sb.WriteString(fold)
+		} else if !unicode.Is(unicode.Mn, r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+var stopwords = map[string][]string{
+	"en": strings.Fields(`a about above after again against all am an and any are as at be because been
+		before being below between both but by can could did do does doing down during each few for
+		from further had has have having he her here hers herself him himself his how i if in into is
+		it its itself just me more most my myself no nor not now of off on once only or other our ours
+		ourselves out over own same she should so some such than that the their theirs them
+		themselves then there these they this those through to too under until up very was we were
+		what when where which while who whom why will with would you your yours yourself yourselves`),
+	"de": strings.Fields(`aber alle allem allen aller alles als also am an ander andere anderem anderen
+		anderer anderes auch auf aus bei bin bis bist da damit dann das dass dasselbe dazu dein deine
+		deinem deinen deiner dem den denn der derselbe des desselben dessen dich die dies diese diesem
+		diesen dieser dieses dir doch dort du durch ein eine einem einen einer eines einig er es etwas
+		euch euer eure für gegen gewesen hab habe haben hat hatte hatten hier hin hinter ich ihm ihn
+		ihnen ihr ihre ihrem ihren ihrer im in indem ins ist jede jedem jeden jeder jedes jene jenem
+		jenen jener jenes jetzt kann kein keine keinem keinen keiner können könnte machen man manche
+		mein meine meinem meinen meiner mich mir mit muss musste nach nicht nichts noch nun nur ob oder
+		ohne sehr sein seine seinem seinen seiner sich sie sind so solche soll sollte sondern sonst um
+		und uns unser unsere unter viel vom von vor während war waren warst was weg weil weiter welche
+		welchem welchen welcher welches wenn werde werden wie wieder will wir wird wirst wo wollen
+		wollte würde würden zu zum zur zwar zwischen`),
+	"fr": strings.Fields(`au aux avec ce ces dans de des du elle en et eux il ils je la le les leur lui
+		ma mais me même mes moi mon ne nos notre nous on ou où par pas pour qu que qui sa se ses son
+		sur ta te tes toi ton tu un une vos votre vous c d j l à m n s t y été étée étées étés étant
+		suis es est sommes êtes sont serai seras sera serons serez seront serais serait serions seriez
+		seraient étais était étions étiez étaient fus fut fûmes fûtes furent sois soit soyons soyez
+		soient ai as avons avez ont aurai auras aura aurons aurez auront aurais aurait aurions auriez
+		auraient avais avait avions aviez avaient eut eûmes eûtes eurent aie aies ait ayons ayez aient`),
+	"es": strings.Fields(`a al algo algunas algunos ante antes como con contra cual cuando de del desde
+		donde durante e el ella ellas ellos en entre era erais eran eras eres es esa esas ese eso esos
+		esta estaba estado estamos estar estas este esto estos estoy fue fueron fui fuimos ha habéis
+		haber había han has hasta hay he la las le les lo los más me mi mis mucho muchos muy nada ni
+		no nos nosotros o os otra otras otro otros para pero poco por porque que quien quienes qué se
+		sea ser si sido sin sobre sois somos son soy su sus también tanto te tenemos tener tengo ti
+		tiene tienen todo todos tu tus un una uno unos vosotros y ya yo`),
+}
+
+// Stopwords returns common words of the language that don't carry meaning on
+// their own, for the analyzer options. Supported languages are "en", "de", "fr"
+// and "es" (ISO 639-1 codes). For other languages it returns nil.
+func Stopwords(language string) []string {
+	return append([]string(nil), stopwords[language]...)
+}
@@ -0,0 +1,49 @@
+package chromem
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewAnalyzer(t *testing.T) {
+	tt := []struct {
+		name    string
+		options AnalyzerOptions
+		text    string
+		want    []string
+	}{
+		{"split only", AnalyzerOptions{}, "Hello, World! it's 2024", []string{"Hello", "World", "it", "s", "2024"}},
+		{"lowercase", AnalyzerOptions{Lowercase: true}, "Hello WORLD", []string{"hello", "world"}},
+		{"fold diacritics", AnalyzerOptions{FoldDiacritics: true}, "Café Straße", []string{"Cafe", "Strasse"}},
+		// "e" followed by a combining acute accent
+		{"decomposed", AnalyzerOptions{FoldDiacritics: true}, "Café", []string{"Cafe"}},
+		{"stopwords", AnalyzerOptions{Lowercase: true, FoldDiacritics: true, Stopwords: []string{"the", "für"}}, "The book fur you", []string{"book", "you"}},
+		{"stem", AnalyzerOptions{Stem: StemEnglish}, "running dogs", []string{"run", "dog"}},
+		{"min length", AnalyzerOptions{MinLength: 2}, "a bc d", []string{"bc"}},
+		{"CJK", AnalyzerOptions{}, "東京abc", []string{"東", "京", "abc"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewAnalyzer(tc.options)(tc.text)
+			if !slices.Equal(got, tc.want) {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+		})
+	}
+}
+
+func TestNewLanguageAnalyzer(t *testing.T) {
+	got := NewLanguageAnalyzer("en")("The Connections of the Cafés")
+	want := []string{"connect", "cafe"}
+	if !slices.Equal(got, want) {
+		t.Fatal("expected", want, "got", got)
+	}
+	got = NewLanguageAnalyzer("de")("Die Häuser und die Straßen")
+	want = []string{"hauser", "strassen"}
+	if !slices.Equal(got, want) {
+		t.Fatal("expected", want, "got", got)
+	}
+	if Stopwords("xx") != nil {
+		t.Fatal("expected no stopwords for unknown language")
+	}
+}
@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Name of the audit log file in a collection's directory.
+const auditLogFileName = "audit.jsonl"
+
+type actorContextKey struct{}
+
+// ContextWithActor returns a context that carries the actor, e.g. a user or
+// service name, who makes changes with it. The actor is recorded in the audit
+// log, see [Collection.EnableAuditLog].
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor set via [ContextWithActor], or an empty
+// string.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey{}).(string)
+	return actor
+}
+
+// AuditEntry is an entry of the audit log, see [Collection.EnableAuditLog].
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor,omitempty"`
+	Action     EventType `json:"action"`
+	Collection string    `json:"collection"`
+	DocumentID string    `json:"document_id"`
+	// Version is the document's version after adding or updating it, and the
+	// deleted version for deletions.
+	Version uint64 `json:"version"`
+}
+
+type auditLog struct {
+	collection string
+	w          io.Writer
+	// Only set if we opened the file, so we have to close it.
+	f *os.File
+}
+
+// write appends an entry. It's a no-op for a nil audit log.
+func (l *auditLog) write(ctx context.Context, action EventType, docID string, version uint64) error {
+	if l == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(AuditEntry{
+		Time:       time.Now().UTC(),
+		Actor:      ActorFromContext(ctx),
+		Action:     action,
+		Collection: l.collection,
+		DocumentID: docID,
+		Version:    version,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't marshal audit entry: %w", err)
+	}
+	// One write per entry, so that entries aren't interleaved with other
+	// writers of the same file.
+	_, err = l.w.Write(append(b, '\n'))
+	return err
+}
+
+func (l *auditLog) close() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// EnableAuditLog enables an append-only audit log of the collection's changes,
+// for compliance-sensitive deployments. For each document that's added, updated
+// or deleted, a JSON line with the time, actor (see [ContextWithActor]),
+// action, document ID and version is written (see [AuditEntry]). The entry is
+// written before the change is applied, and the change fails if the entry can't
+// be written, so there are no unlogged changes.
+//
+// If w is nil, the log is appended to the file "audit.jsonl" in the
+// collection's directory, which requires a persistent DB. The file is closed by
+// [DB.Close].
+func (c *Collection) EnableAuditLog(w io.Writer) error {
+	l := &auditLog{
+		collection: c.CurrentName(),
+		w:          w,
+	}
+	if w == nil {
+		if c.persistDirectory == "" {
+			return errors.New("audit log file requires a persistent DB, pass a writer instead")
+		}
+		err := c.perms.mkdirAll(c.persistDirectory)
+		if err != nil {
+			return fmt.Errorf("couldn't create collection directory: %w", err)
+		}
+		f, err := c.perms.openFile(filepath.Join(c.persistDirectory, auditLogFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("couldn't open audit log file: %w", err)
+		}
+		l.w = f
+		l.f = f
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		_ = l.close()
+		return ErrClosed
+	}
+
+	err := c.auditLog.close()
+	c.auditLog = l
+	if err != nil {
+		return fmt.Errorf("couldn't close previous audit log: %w", err)
+	}
+	return nil
+}
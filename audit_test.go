@@ -0,0 +1,118 @@
+package chromem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollection_EnableAuditLog(t *testing.T) {
+	ctx := ContextWithActor(context.Background(), "alice")
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.EnableAuditLog(nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(context.Background(), Document{ID: "1", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	f, err := os.Open(filepath.Join(c.persistDirectory, auditLogFileName))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		entries = append(entries, e)
+	}
+
+	want := []AuditEntry{
+		{Actor: "alice", Action: EventAdd, Collection: "test", DocumentID: "1", Version: 1},
+		{Actor: "", Action: EventUpdate, Collection: "test", DocumentID: "1", Version: 2},
+		{Actor: "alice", Action: EventDelete, Collection: "test", DocumentID: "1", Version: 2},
+	}
+	if len(entries) != len(want) {
+		t.Fatal("expected", len(want), "entries, got", len(entries))
+	}
+	for i, e := range entries {
+		if e.Time.IsZero() {
+			t.Fatal("expected time to be set")
+		}
+		e.Time = want[i].Time
+		if e != want[i] {
+			t.Fatal("expected", want[i], "got", e)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCollection_EnableAuditLog_Error(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The file requires persistence
+	err = c.EnableAuditLog(nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Changes fail if they can't be logged
+	err = c.EnableAuditLog(failingWriter{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if c.Count() != 0 {
+		t.Fatal("expected no documents, got", c.Count())
+	}
+}
@@ -0,0 +1,243 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Number of documents that are scored against all queries of a batch at once.
+// Their embeddings fit into the CPU cache for common dimensions, so they're only
+// read from memory once per batch instead of once per query.
+const batchTileSize = 8
+
+// QueryBatch is like [Collection.Query], but for multiple query texts at once.
+// See [Collection.QueryEmbeddings] for details.
+func (c *Collection) QueryBatch(ctx context.Context, queryTexts []string, nResults int, where, whereDocument map[string]string) ([][]Result, error) {
+	if len(queryTexts) == 0 {
+		return nil, errors.New("queryTexts are empty")
+	}
+	for i, queryText := range queryTexts {
+		if queryText == "" {
+			return nil, fmt.Errorf("queryText %d is empty", i)
+		}
+	}
+
+	// Make sure the queries are embedded with the same model as the documents.
+	err := c.checkEmbeddingModel()
+	if err != nil {
+		return nil, err
+	}
+
+	embed := c.getEmbed()
+	embedCtx := embeddingContext(ctx, c.CurrentName(), EmbeddingOperationQuery, "")
+	queryEmbeddings := make([][]float32, len(queryTexts))
+	for i, queryText := range queryTexts {
+		queryEmbeddings[i], err = embed(embedCtx, queryText)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create embedding of query %d: %w: %w", i, ErrEmbeddingUnavailable, err)
+		}
+	}
+
+	return c.QueryEmbeddings(ctx, queryEmbeddings, nResults, where, whereDocument)
+}
+
+// QueryEmbeddings performs exhaustive nearest neighbor searches for multiple
+// query embeddings at once, with the same filters. It returns one result slice
+// per query, in the same order.
+//
+// Instead of scanning the documents once per query, it computes the similarities
+// like a matrix multiplication of the query embeddings with the document
+// embeddings: each tile of documents is scored against all queries while its
+// embeddings are in the CPU cache. For many concurrent queries, like in a server,
+// batching them this way reduces memory bandwidth and improves the throughput,
+// in particular for collections that don't fit into the CPU cache.
+//
+// Two-stage Matryoshka search (see [Collection.SetMatryoshkaSearch]) and the query
+// cache aren't used for batches. With a similarity function (see
+// [Collection.SetSimilarityFunc]), the queries are processed one by one.
+//
+//   - queryEmbeddings: The embeddings of the queries. See [Collection.QueryEmbedding].
+//   - nResults: The number of results to return per query. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) QueryEmbeddings(ctx context.Context, queryEmbeddings [][]float32, nResults int, where, whereDocument map[string]string) ([][]Result, error) {
+	if len(queryEmbeddings) == 0 {
+		return nil, errors.New("queryEmbeddings are empty")
+	}
+	for i, queryEmbedding := range queryEmbeddings {
+		if len(queryEmbedding) == 0 {
+			return nil, fmt.Errorf("queryEmbedding %d is empty", i)
+		}
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if nResults > len(c.documents) {
+		return nil, errors.New("nResults must be <= the number of documents in the collection")
+	}
+
+	results := make([][]Result, len(queryEmbeddings))
+	if len(c.documents) == 0 {
+		return results, nil
+	}
+
+	// Validate whereDocument operators
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	queries := make([][]float32, len(queryEmbeddings))
+	for i, queryEmbedding := range queryEmbeddings {
+		// Project the query to the dimensions of the documents if necessary.
+		queryEmbedding = c.projection.projectIfInput(queryEmbedding)
+		// Normalize embedding if not the case yet, like the documents were
+		// normalized when added to the collection, unless the policy says otherwise.
+		queries[i] = c.normalization.apply(queryEmbedding)
+	}
+
+	// Filter docs by metadata and content
+	filteredDocs, err := c.filter(c.column.docs(c.documents), where, whereDocument)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+
+	// No need to continue if the filters got rid of all documents
+	if len(filteredDocs) == 0 {
+		return results, nil
+	}
+
+	similarity, err := c.similarityFunc(nil)
+	if err != nil {
+		return nil, err
+	}
+	var docSims [][]docSim
+	if similarity != nil {
+		docSims = make([][]docSim, len(queries))
+		for i, query := range queries {
+			docSims[i], err = getMostSimilarDocsFunc(ctx, filteredDocs, nResults, func(doc *Document) (float32, error) {
+				return similarity(query, doc.Embedding)
+			})
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		docSims, err = getMostSimilarDocsBatch(ctx, queries, filteredDocs, nResults, c.normalization != NormalizeNever)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+	for i := range queries {
+		results[i], err = c.docSimsToResults(docSims[i], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// getMostSimilarDocsBatch is like getMostSimilarDocs, but for multiple queries.
+// It returns the most similar documents per query. Hopeless documents are only
+// abandoned early if the embeddings are normalized.
+func getMostSimilarDocsBatch(ctx context.Context, queries [][]float32, docs []*Document, n int, normalized bool) ([][]docSim, error) {
+	// Determine concurrency. Use number of docs or CPUs, whichever is smaller.
+	concurrency := min(runtime.NumCPU(), len(docs))
+
+	// Each goroutine keeps its own top n per query, so they don't contend for
+	// locks. They're merged at the end.
+	nMaxDocsPerWorker := make([][]*maxDocSims, concurrency)
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+		}
+	}
+
+	eas := make([]earlyAbandon, len(queries))
+	for q, query := range queries {
+		if normalized {
+			eas[q] = newEarlyAbandon(query)
+		} else {
+			// Without rest norms, the full dot product is calculated.
+			eas[q] = earlyAbandon{query: query}
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	subSliceSize := len(docs) / concurrency // Can leave remainder, e.g. 10/3 = 3; leaves 1
+	rem := len(docs) % concurrency
+	for i := 0; i < concurrency; i++ {
+		start := i * subSliceSize
+		end := start + subSliceSize
+		// Add remainder to last goroutine
+		if i == concurrency-1 {
+			end += rem
+		}
+
+		nMaxDocs := make([]*maxDocSims, len(queries))
+		for q := range queries {
+			nMaxDocs[q] = newMaxDocSims(n)
+		}
+		nMaxDocsPerWorker[i] = nMaxDocs
+
+		wg.Add(1)
+		go func(subSlice []*Document) {
+			defer wg.Done()
+			for tileStart := 0; tileStart < len(subSlice); tileStart += batchTileSize {
+				// Stop work if another goroutine encountered an error, or the
+				// context was canceled.
+				if ctx.Err() != nil {
+					setSharedErr(ctx.Err())
+					return
+				}
+
+				tile := subSlice[tileStart:min(tileStart+batchTileSize, len(subSlice))]
+				for q, ea := range eas {
+					for _, doc := range tile {
+						// As the vectors are normalized, the dot product is the
+						// cosine similarity.
+						sim, err := ea.dotProduct(doc.Embedding, nMaxDocs[q].minSimilarity())
+						if err != nil {
+							setSharedErr(fmt.Errorf("couldn't calculate similarity for document '%s': %w", doc.ID, err))
+							return
+						}
+						nMaxDocs[q].add(docSim{docID: doc.ID, similarity: sim})
+					}
+				}
+			}
+		}(docs[start:end])
+	}
+
+	wg.Wait()
+
+	if sharedErr != nil {
+		return nil, sharedErr
+	}
+
+	// Merge the top n of the goroutines per query
+	res := make([][]docSim, len(queries))
+	for q := range queries {
+		merged := newMaxDocSims(n)
+		for _, nMaxDocs := range nMaxDocsPerWorker {
+			for _, ds := range nMaxDocs[q].h {
+				merged.add(ds)
+			}
+		}
+		res[q] = merged.values()
+	}
+
+	return res, nil
+}
@@ -0,0 +1,121 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_QueryEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = r.Float32() - 0.5
+		}
+		return v
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 100; i++ {
+		err = c.AddDocument(ctx, Document{
+			ID:        strconv.Itoa(i),
+			Metadata:  map[string]string{"even": strconv.FormatBool(i%2 == 0)},
+			Embedding: randomVector(),
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	queries := [][]float32{randomVector(), randomVector(), randomVector()}
+	where := map[string]string{"even": "true"}
+	res, err := c.QueryEmbeddings(ctx, queries, 5, where, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != len(queries) {
+		t.Fatal("expected", len(queries), "result slices, got", len(res))
+	}
+	// The batch must return the same results as single queries.
+	for i, q := range queries {
+		want, err := c.QueryEmbedding(ctx, q, 5, where, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res[i]) != len(want) {
+			t.Fatal("expected", len(want), "results, got", len(res[i]))
+		}
+		for j := range want {
+			if res[i][j].ID != want[j].ID || res[i][j].Similarity != want[j].Similarity {
+				t.Fatal("expected", want[j].ID, want[j].Similarity, "got", res[i][j].ID, res[i][j].Similarity)
+			}
+		}
+	}
+
+	_, err = c.QueryEmbeddings(ctx, [][]float32{queries[0], nil}, 5, nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func BenchmarkCollection_QueryEmbeddings_Batch16_25000(b *testing.B) {
+	benchmarkCollection_QueryEmbeddings(b, 25000, 16, true)
+}
+
+func BenchmarkCollection_QueryEmbeddings_Sequential16_25000(b *testing.B) {
+	benchmarkCollection_QueryEmbeddings(b, 25000, 16, false)
+}
+
+func benchmarkCollection_QueryEmbeddings(b *testing.B, n, queries int, batch bool) {
+	ctx := context.Background()
+
+	// Seed to make deterministic
+	r := rand.New(rand.NewSource(42))
+
+	d := 1536 // dimensions, same as text-embedding-3-small
+	randomVector := func() []float32 {
+		v := make([]float32, d)
+		for j := 0; j < d; j++ {
+			v[j] = r.Float32()
+		}
+		return normalizeVector(v)
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < n; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector()})
+		if err != nil {
+			b.Fatal("expected no error, got", err)
+		}
+	}
+	qvs := make([][]float32, queries)
+	for i := range qvs {
+		qvs[i] = randomVector()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			_, err = c.QueryEmbeddings(ctx, qvs, 10, nil, nil)
+			if err != nil {
+				b.Fatal("expected nil, got", err)
+			}
+			continue
+		}
+		for _, qv := range qvs {
+			_, err = c.QueryEmbedding(ctx, qv, 10, nil, nil)
+			if err != nil {
+				b.Fatal("expected nil, got", err)
+			}
+		}
+	}
+}
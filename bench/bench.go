@@ -0,0 +1,215 @@
+// Package bench benchmarks chromem-go on your hardware with synthetic corpora,
+// to compare the throughput, latency, recall and memory usage of the search
+// modes, like exact search, IVF indexes and Matryoshka search, before choosing
+// one for a real corpus. It's also available as "bench" command of the CLI in
+// cmd/chromem.
+//
+// The embeddings are generated, so no embedding API is needed and the results
+// only reflect chromem-go itself.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+	"github.com/philippgille/chromem-go/eval"
+)
+
+// Options configures [Run].
+type Options struct {
+	// Documents is the number of documents of the corpus. Defaults to 10,000.
+	Documents int
+	// Dimensions is the number of dimensions of the embeddings. Defaults to 384.
+	Dimensions int
+	// Queries is the number of queries per mode. Defaults to 100.
+	Queries int
+	// K is the number of results per query. Defaults to 10.
+	K int
+	// Concurrency is the concurrency for adding the documents. Defaults to the
+	// number of CPUs.
+	Concurrency int
+	// Modes are the search modes to compare. The recall of each mode is
+	// relative to the exact search. Defaults to [eval.Exact], [eval.IVF] with
+	// 1 and 4 probes and [eval.Matryoshka] with a quarter of the dimensions.
+	Modes []eval.Setting
+	// Seed of the random corpus and queries, for reproducible runs.
+	Seed int64
+}
+
+// Report is the result of [Run].
+type Report struct {
+	Documents  int
+	Dimensions int
+	// AddDuration is the duration of adding all documents, with embeddings.
+	AddDuration time.Duration
+	// AddThroughput is the number of added documents per second.
+	AddThroughput float64
+	// Modes are the results per mode, in the order of the options.
+	Modes []ModeResult
+}
+
+// ModeResult is the result of a search mode.
+type ModeResult struct {
+	Mode string
+	// SetupDuration is the duration of applying the mode, e.g. of building an
+	// index.
+	SetupDuration time.Duration
+	// QueryThroughput is the number of sequential queries per second.
+	QueryThroughput float64
+	// MeanLatency is the average duration of a query.
+	MeanLatency time.Duration
+	// Recall is the recall@k compared to the exact search.
+	Recall float64
+	// Memory is the approximate memory usage of the collection in bytes,
+	// including indexes, see [chromem.Collection.MemoryUsage].
+	Memory int64
+}
+
+// Run generates a corpus with the configured size, adds it to a new in-memory
+// collection and runs the queries with each mode.
+//
+// The corpus consists of clusters of similar documents, with the variance
+// decreasing over the dimensions, similar to embeddings of Matryoshka models.
+// Queries are close to random documents. This approximates real corpora better
+// than uniformly random vectors, for which approximate search performs
+// unrealistically badly.
+func Run(ctx context.Context, options Options) (Report, error) {
+	if options.Documents == 0 {
+		options.Documents = 10000
+	}
+	if options.Dimensions == 0 {
+		options.Dimensions = 384
+	}
+	if options.Queries == 0 {
+		options.Queries = 100
+	}
+	if options.K == 0 {
+		options.K = 10
+	}
+	if options.Concurrency == 0 {
+		options.Concurrency = runtime.NumCPU()
+	}
+	if options.Documents < 0 || options.Dimensions < 0 || options.Queries < 0 || options.K < 0 || options.Concurrency < 0 {
+		return Report{}, errors.New("options must be >= 0")
+	}
+	if options.K > options.Documents {
+		return Report{}, errors.New("k must be <= the number of documents")
+	}
+	if options.Modes == nil {
+		options.Modes = []eval.Setting{
+			eval.Exact(),
+			eval.IVF(0, 1),
+			eval.IVF(0, 4),
+			eval.Matryoshka(max(1, options.Dimensions/4), 0),
+		}
+	}
+
+	r := rand.New(rand.NewSource(options.Seed))
+	g := newGenerator(r, options.Dimensions, max(1, int(math.Sqrt(float64(options.Documents)))))
+	docs := make([]chromem.Document, options.Documents)
+	for i := range docs {
+		docs[i] = chromem.Document{ID: strconv.Itoa(i), Embedding: g.vector(r)}
+	}
+
+	// The embedding function is never called, as all documents and queries
+	// have embeddings.
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return nil, errors.New("unexpected call of embedding function")
+	}
+	c, err := chromem.NewDB().CreateCollection("bench", nil, embeddingFunc)
+	if err != nil {
+		return Report{}, err
+	}
+	report := Report{Documents: options.Documents, Dimensions: options.Dimensions}
+	start := time.Now()
+	err = c.AddDocuments(ctx, docs, options.Concurrency)
+	if err != nil {
+		return Report{}, fmt.Errorf("couldn't add documents: %w", err)
+	}
+	report.AddDuration = time.Since(start)
+	report.AddThroughput = float64(options.Documents) / report.AddDuration.Seconds()
+
+	queries := make([]eval.Query, options.Queries)
+	for i := range queries {
+		queries[i] = eval.Query{Embedding: g.near(r, docs[r.Intn(len(docs))].Embedding)}
+	}
+	queries, err = eval.GroundTruth(ctx, c, queries, options.K, nil)
+	if err != nil {
+		return Report{}, fmt.Errorf("couldn't determine ground truth: %w", err)
+	}
+
+	for _, mode := range options.Modes {
+		res := ModeResult{Mode: mode.Name}
+		start := time.Now()
+		if mode.Apply != nil {
+			err = mode.Apply(ctx, c)
+			if err != nil {
+				return Report{}, fmt.Errorf("couldn't apply mode '%s': %w", mode.Name, err)
+			}
+		}
+		res.SetupDuration = time.Since(start)
+		m, err := eval.Evaluate(ctx, eval.CollectionSearch(c), queries, options.K)
+		if err != nil {
+			return Report{}, fmt.Errorf("couldn't run queries of mode '%s': %w", mode.Name, err)
+		}
+		res.MeanLatency = m.MeanLatency
+		if m.MeanLatency > 0 {
+			res.QueryThroughput = float64(time.Second) / float64(m.MeanLatency)
+		}
+		res.Recall = m.Recall
+		res.Memory = c.MemoryUsage().Total()
+		report.Modes = append(report.Modes, res)
+	}
+
+	return report, nil
+}
+
+// generator generates clustered vectors.
+type generator struct {
+	centroids [][]float32
+	// Scale per dimension, decreasing like the variance of Matryoshka embeddings.
+	scales []float32
+}
+
+func newGenerator(r *rand.Rand, dimensions, clusters int) *generator {
+	g := &generator{
+		centroids: make([][]float32, clusters),
+		scales:    make([]float32, dimensions),
+	}
+	for i := range g.scales {
+		g.scales[i] = float32(1 / math.Sqrt(1+float64(i)/16))
+	}
+	for i := range g.centroids {
+		g.centroids[i] = g.noise(r, 1)
+	}
+	return g
+}
+
+// vector returns a vector of a random cluster.
+func (g *generator) vector(r *rand.Rand) []float32 {
+	return g.near(r, g.centroids[r.Intn(len(g.centroids))])
+}
+
+// near returns a vector close to v.
+func (g *generator) near(r *rand.Rand, v []float32) []float32 {
+	res := g.noise(r, 0.5)
+	for i := range res {
+		res[i] += v[i]
+	}
+	return res
+}
+
+func (g *generator) noise(r *rand.Rand, stddev float64) []float32 {
+	v := make([]float32, len(g.scales))
+	for i := range v {
+		v[i] = float32(r.NormFloat64()*stddev) * g.scales[i]
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/philippgille/chromem-go/eval"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	report, err := Run(ctx, Options{Documents: 500, Dimensions: 32, Queries: 10, K: 5})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if report.Documents != 500 || report.Dimensions != 32 || report.AddThroughput <= 0 {
+		t.Fatal("expected report of 500 added documents with 32 dimensions, got", report)
+	}
+	if len(report.Modes) != 4 {
+		t.Fatal("expected 4 default modes, got", len(report.Modes))
+	}
+	exact := report.Modes[0]
+	if exact.Mode != "exact" || exact.Recall != 1 || exact.QueryThroughput <= 0 || exact.Memory <= 0 {
+		t.Fatal("expected exact mode with perfect recall, got", exact)
+	}
+	ivf := report.Modes[1]
+	if ivf.Recall <= 0 || ivf.Memory <= exact.Memory {
+		t.Fatal("expected IVF mode with recall and index memory, got", ivf)
+	}
+
+	// Custom modes
+	report, err = Run(ctx, Options{Documents: 100, Dimensions: 8, Modes: []eval.Setting{eval.IVF(5, 5)}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Modes) != 1 || report.Modes[0].Recall != 1 {
+		t.Fatal("expected IVF mode that probes all lists with perfect recall, got", report.Modes)
+	}
+
+	_, err = Run(ctx, Options{Documents: 5, K: 10})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
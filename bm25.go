@@ -0,0 +1,168 @@
+package chromem
+
+import (
+	"hash/fnv"
+	"math"
+	"slices"
+	"sync"
+)
+
+// BM25 encodes texts as sparse vectors whose dot product is the BM25 score of
+// the document for the query, for keyword search with [Collection.QuerySparse]
+// and hybrid search with [Collection.QueryHybridEmbedding]. The terms are
+// mapped to the dimensions by their hash.
+//
+// The document vectors contain the term frequency components, which depend on
+// the average document length, and the query vectors contain the inverse
+// document frequencies. So the statistics of the corpus must be collected with
+// [BM25.Fit] before encoding documents and queries. Documents that are encoded
+// before most of the corpus is fitted, or before its average document length
+// changes considerably, should be encoded again.
+//
+// The statistics are exported fields, so they can be persisted, e.g. with gob
+// or JSON, instead of fitting the corpus again after a restart.
+type BM25 struct {
+	// DocumentCount is the number of fitted documents.
+	DocumentCount int
+	// TermCount is the total number of terms of the fitted documents.
+	TermCount int
+	// DocumentFrequencies is the number of fitted documents per term hash.
+	DocumentFrequencies map[uint32]int
+
+	analyzer Analyzer
+	k1, b    float64
+	lock     sync.RWMutex
+}
+
+// NewBM25 creates a BM25 encoder.
+//
+//   - analyzer: Splits the texts into terms, e.g. [NewLanguageAnalyzer].
+//     Optional, defaults to lowercased terms with folded diacritics.
+//   - k1: Controls the term frequency saturation. If it's 0 or less, the
+//     common default of 1.2 is used.
+//   - b: Controls the document length normalization, between 0 and 1. If it's
+//     less than 0, the common default of 0.75 is used.
+func NewBM25(analyzer Analyzer, k1, b float64) *BM25 {
+	if analyzer == nil {
+		analyzer = NewAnalyzer(AnalyzerOptions{Lowercase: true, FoldDiacritics: true})
+	}
+	if k1 <= 0 {
+		k1 = 1.2
+	}
+	if b < 0 {
+		b = 0.75
+	}
+	return &BM25{
+		DocumentFrequencies: make(map[uint32]int),
+		analyzer:            analyzer,
+		k1:                  k1,
+		b:                   min(b, 1),
+	}
+}
+
+// Fit adds the texts to the corpus statistics.
+func (e *BM25) Fit(texts ...string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	// The map is nil when the statistics were decoded into a zero value.
+	if e.DocumentFrequencies == nil {
+		e.DocumentFrequencies = make(map[uint32]int)
+	}
+	for _, text := range texts {
+		tf := e.termFrequencies(text)
+		e.DocumentCount++
+		for term, n := range tf {
+			e.TermCount += n
+			e.DocumentFrequencies[term]++
+		}
+	}
+}
+
+// EncodeDocument encodes the document text as sparse vector, with the BM25
+// term frequency component per term.
+func (e *BM25) EncodeDocument(text string) SparseVector {
+	tf := e.termFrequencies(text)
+	length := 0
+	for _, n := range tf {
+		length += n
+	}
+
+	e.lock.RLock()
+	avgLength := float64(length)
+	if e.DocumentCount > 0 {
+		avgLength = float64(e.TermCount) / float64(e.DocumentCount)
+	}
+	e.lock.RUnlock()
+
+	weights := make(map[uint32]float32, len(tf))
+	for term, n := range tf {
+		f := float64(n)
+		norm := 1 - e.b
+		if avgLength > 0 {
+			norm += e.b * float64(length) / avgLength
+		}
+		weights[term] = float32(f * (e.k1 + 1) / (f + e.k1*norm))
+	}
+	return sparseVectorFromMap(weights)
+}
+
+// EncodeQuery encodes the query text as sparse vector, with the inverse
+// document frequency per term. Terms that occur multiple times are weighted
+// accordingly.
+func (e *BM25) EncodeQuery(text string) SparseVector {
+	tf := e.termFrequencies(text)
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	weights := make(map[uint32]float32, len(tf))
+	for term, n := range tf {
+		df := float64(e.DocumentFrequencies[term])
+		idf := math.Log(1 + (float64(e.DocumentCount)-df+0.5)/(df+0.5))
+		weights[term] = float32(float64(n) * idf)
+	}
+	return sparseVectorFromMap(weights)
+}
+
+// QueryTerms returns the analyzed terms of the query text by their dimension
+// in the sparse vector of [BM25.EncodeQuery], e.g. to show the matched terms
+// with [Collection.QueryHybridEmbeddingExplained].
+func (e *BM25) QueryTerms(text string) map[uint32]string {
+	terms := make(map[uint32]string)
+	for _, term := range e.analyzer(text) {
+		terms[termHash(term)] = term
+	}
+	return terms
+}
+
+// termFrequencies returns the number of occurrences per term hash.
+func (e *BM25) termFrequencies(text string) map[uint32]int {
+	tf := make(map[uint32]int)
+	for _, term := range e.analyzer(text) {
+		tf[termHash(term)]++
+	}
+	return tf
+}
+
+// termHash returns the dimension of the term in the sparse vectors.
+func termHash(term string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(term))
+	return h.Sum32()
+}
+
+// sparseVectorFromMap returns the sparse vector with the values per index,
+// sorted by index.
+func sparseVectorFromMap(m map[uint32]float32) SparseVector {
+	v := SparseVector{
+		Indices: make([]uint32, 0, len(m)),
+		Values:  make([]float32, 0, len(m)),
+	}
+	for i := range m {
+		v.Indices = append(v.Indices, i)
+	}
+	slices.Sort(v.Indices)
+	for _, i := range v.Indices {
+		v.Values = append(v.Values, m[i])
+	}
+	return v
+}
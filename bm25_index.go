@@ -0,0 +1,452 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// indexDirName is the name of the subdirectory of a collection's directory
+// with the files of its persisted indexes.
+const indexDirName = "index"
+
+// bm25IndexFileName is the name of the BM25 index file in the index directory,
+// without the file extension.
+const bm25IndexFileName = "bm25"
+
+// BM25IndexOptions configures the BM25 index of a collection, see
+// [Collection.EnableBM25Index]. Unlike an [Analyzer], the options can be
+// persisted, so the index can be loaded with the DB.
+type BM25IndexOptions struct {
+	// Language of the analyzer, see [NewLanguageAnalyzer]. Optional, defaults
+	// to lowercased terms with folded diacritics, like [NewBM25].
+	Language string
+	// K1 controls the term frequency saturation. If it's 0 or less, the common
+	// default of 1.2 is used.
+	K1 float64
+	// B controls the document length normalization, between 0 and 1. If it's
+	// less than 0, the common default of 0.75 is used.
+	B float64
+}
+
+// bm25Doc is the entry of a document in the BM25 index.
+type bm25Doc struct {
+	// Version of the document when it was indexed, see [Document.Version].
+	Version uint64
+	// Number of occurrences per term hash
+	Terms map[uint32]int
+
+	// Number of terms
+	length int
+}
+
+// persistedBM25Index is the content of the BM25 index file. Only the terms per
+// document are persisted. The postings are derived from them when the index is
+// loaded, which is cheap compared to analyzing the contents again.
+type persistedBM25Index struct {
+	Options BM25IndexOptions
+	Docs    map[string]bm25Doc
+}
+
+// bm25Index is an inverted index of the terms of the documents' contents, which
+// scores the documents for a query text with BM25. The corpus statistics are
+// updated with each added or removed document, so the scores are always
+// current. It must only be accessed while holding the collection's documents
+// lock, and modified while holding the write lock.
+type bm25Index struct {
+	options BM25IndexOptions
+	// Analyzer and parameters
+	encoder *BM25
+	docs    map[string]bm25Doc
+	// Number of occurrences per term hash and document ID
+	postings map[uint32]map[string]int
+	// Total number of terms of all documents
+	termCount int
+	// Whether the index was modified since it was persisted
+	modified bool
+}
+
+func newBM25Index(options BM25IndexOptions) *bm25Index {
+	var analyzer Analyzer
+	if options.Language != "" {
+		analyzer = NewLanguageAnalyzer(options.Language)
+	}
+	return &bm25Index{
+		options:  options,
+		encoder:  NewBM25(analyzer, options.K1, options.B),
+		docs:     make(map[string]bm25Doc),
+		postings: make(map[uint32]map[string]int),
+		modified: true,
+	}
+}
+
+// add indexes the content of the document, replacing its previous entry. It's
+// a no-op on a nil index.
+func (idx *bm25Index) add(id string, version uint64, content string) {
+	if idx == nil {
+		return
+	}
+	idx.put(id, bm25Doc{Version: version, Terms: idx.encoder.termFrequencies(content)})
+}
+
+// setVersion sets the version of the document's entry, if it has one, e.g.
+// after the document was replaced without changing its content. It's a no-op
+// on a nil index.
+func (idx *bm25Index) setVersion(id string, version uint64) {
+	if idx == nil {
+		return
+	}
+	if doc, ok := idx.docs[id]; ok {
+		doc.Version = version
+		idx.docs[id] = doc
+		idx.modified = true
+	}
+}
+
+// put adds the entry of the document, replacing its previous one.
+func (idx *bm25Index) put(id string, doc bm25Doc) {
+	idx.remove(id)
+	for term, n := range doc.Terms {
+		postings, ok := idx.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			idx.postings[term] = postings
+		}
+		postings[id] = n
+		doc.length += n
+	}
+	idx.docs[id] = doc
+	idx.termCount += doc.length
+	idx.modified = true
+}
+
+// remove removes the document from the index. It's a no-op on a nil index or
+// for documents that aren't indexed.
+func (idx *bm25Index) remove(id string) {
+	if idx == nil {
+		return
+	}
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.Terms {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docs, id)
+	idx.termCount -= doc.length
+	idx.modified = true
+}
+
+// scores returns the BM25 score of each document that contains any of the
+// query's terms. Terms that occur multiple times in the query are weighted
+// accordingly.
+func (idx *bm25Index) scores(queryText string) map[string]float32 {
+	scores := make(map[string]float32)
+	if len(idx.docs) == 0 {
+		return scores
+	}
+	n := float64(len(idx.docs))
+	avgLength := float64(idx.termCount) / n
+	k1, b := idx.encoder.k1, idx.encoder.b
+	for term, qn := range idx.encoder.termFrequencies(queryText) {
+		postings := idx.postings[term]
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			norm := 1 - b
+			if avgLength > 0 {
+				norm += b * float64(idx.docs[id].length) / avgLength
+			}
+			f := float64(tf)
+			scores[id] += float32(float64(qn) * idf * f * (k1 + 1) / (f + k1*norm))
+		}
+	}
+	return scores
+}
+
+// EnableBM25Index enables an inverted index of the terms of the documents'
+// contents, for keyword search with [Collection.QueryBM25] and hybrid search
+// with [Collection.QueryHybrid]. Unlike with [BM25] and sparse embeddings, the
+// corpus statistics are maintained by the collection, so documents don't have
+// to be encoded again when the corpus grows.
+//
+// The index is built from the existing documents and updated when documents
+// are added or deleted. If the DB is persistent, the index is written to the
+// collection's directory when it's enabled and when the collection is flushed
+// (see [Collection.Flush] and [DB.Close]), and loaded with the DB instead of
+// analyzing all contents again. Documents that were added, replaced or deleted
+// after the last flush, e.g. before a crash, are detected by their version (see
+// [Document.Version]) and indexed again when the DB is loaded.
+//
+// Enabling the index again with other options rebuilds it. It can't be
+// combined with discarding the content or content encryption, as the persisted
+// terms would reveal the content.
+func (c *Collection) EnableBM25Index(options BM25IndexOptions) error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.discardContent {
+		return errors.New("BM25 index can't be combined with discarding the content")
+	}
+	if c.contentEncrypted {
+		return errors.New("BM25 index can't be combined with content encryption")
+	}
+	if c.bm25 != nil && c.bm25.options == options {
+		return nil
+	}
+	c.buildBM25Index(options)
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	err = c.writeBM25Index()
+	if err != nil {
+		return fmt.Errorf("couldn't persist BM25 index: %w", err)
+	}
+	return nil
+}
+
+// DisableBM25Index removes the BM25 index, including its file, see
+// [Collection.EnableBM25Index].
+func (c *Collection) DisableBM25Index() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.bm25 == nil {
+		return nil
+	}
+	c.bm25 = nil
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	if c.persistDirectory != "" {
+		err = os.Remove(c.bm25IndexPath())
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("couldn't remove BM25 index: %w", err)
+		}
+	}
+	return nil
+}
+
+// QueryBM25 performs a keyword search with the BM25 index (see
+// [Collection.EnableBM25Index]). Documents without any of the query's terms
+// are left out, so there might be fewer results than requested. The results'
+// similarity is the BM25 score, which is not limited to [-1, 1].
+//
+//   - queryText: The text to search for. It's analyzed like the contents.
+//   - nResults: The maximum number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) QueryBM25(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if queryText == "" {
+		return nil, errors.New("queryText is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if c.bm25 == nil {
+		return nil, errors.New("collection has no BM25 index")
+	}
+
+	scores := c.bm25.scores(queryText)
+	candidates := make([]*Document, 0, len(scores))
+	for id := range scores {
+		candidates = append(candidates, c.documents[id])
+	}
+	filteredDocs, err := c.filter(candidates, where, whereDocument)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	if len(filteredDocs) == 0 {
+		return nil, nil
+	}
+
+	docSims, err := getMostSimilarDocsFunc(ctx, filteredDocs, min(nResults, len(filteredDocs)), func(doc *Document) (float32, error) {
+		return scores[doc.ID], nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+
+	return c.docSimsToResults(docSims, true)
+}
+
+// QueryHybrid performs an exhaustive search that fuses the cosine similarity
+// of the embeddings with the BM25 score of the BM25 index (see
+// [Collection.EnableBM25Index]), like [Collection.QueryHybridEmbedding] does
+// with the sparse embeddings. The query text is embedded with the collection's
+// embedding function. If that fails and the collection has a query fallback
+// (see [Collection.SetQueryFallback]), the results of [Collection.QueryBM25]
+// are returned instead.
+//
+//   - queryText: The text to search for.
+//   - nResults: The number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+//   - alpha: Weight of the dense score, between 0 and 1. 1 is a pure dense
+//     search, 0 a pure keyword one.
+func (c *Collection) QueryHybrid(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string, alpha float32) ([]Result, error) {
+	return c.QueryHybridWithOptions(ctx, QueryOptions{QueryText: queryText, NResults: nResults, Where: where, WhereDocument: whereDocument}, alpha)
+}
+
+// QueryHybridWithOptions is like [Collection.QueryHybrid], but with the options
+// in one struct, e.g. to override the embedding function for the query text.
+// Only the options QueryText, QueryEmbedding, NResults, Where, WhereDocument
+// and EmbeddingFunc apply. QueryText is required for the BM25 score, and it's
+// only embedded if QueryEmbedding isn't set. NResults must be > 0.
+func (c *Collection) QueryHybridWithOptions(ctx context.Context, options QueryOptions, alpha float32) ([]Result, error) {
+	if options.QueryText == "" {
+		return nil, errors.New("QueryText option is empty")
+	}
+
+	queryEmbedding := options.QueryEmbedding
+	if len(queryEmbedding) == 0 {
+		// Make sure the query is embedded with the same model as the
+		// documents, unless only the BM25 score counts.
+		if alpha > 0 {
+			err := c.checkEmbeddingModel()
+			if err != nil {
+				return nil, err
+			}
+		}
+		embed := options.EmbeddingFunc
+		if embed == nil {
+			embed = c.getEmbed()
+		}
+		var err error
+		queryEmbedding, err = embed(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationQuery, ""), options.QueryText)
+		if err != nil {
+			return c.queryBM25WithFallback(ctx, options, err)
+		}
+	}
+
+	return c.queryHybridEmbedding(ctx, queryEmbedding, SparseVector{}, options.NResults, options.Where, options.WhereDocument, alpha, false, nil, options.QueryText)
+}
+
+// buildBM25Index builds the BM25 index from the documents. Must be called
+// while holding the documents write lock.
+func (c *Collection) buildBM25Index(options BM25IndexOptions) {
+	c.bm25 = newBM25Index(options)
+	for id, doc := range c.documents {
+		c.indexBM25(id, doc)
+	}
+}
+
+// indexBM25 adds the stored document to the BM25 index, if there is one.
+// Documents whose content can't be read aren't indexed. Must be called while
+// holding the documents write lock.
+func (c *Collection) indexBM25(id string, doc *Document) {
+	if c.bm25 == nil {
+		return
+	}
+	readable, err := c.readable(doc)
+	if err != nil {
+		c.bm25.remove(id)
+		return
+	}
+	c.bm25.add(id, doc.Version, readable.Content)
+}
+
+// loadBM25Index loads the persisted BM25 index and brings it up to date with
+// the documents. If the collection isn't persistent, e.g. of a replica, or the
+// index file is missing, unreadable or was written with other options, the
+// index is built from the documents instead. Must be called while holding the
+// documents write lock, or before the collection is shared.
+func (c *Collection) loadBM25Index(options BM25IndexOptions) {
+	if c.persistDirectory == "" {
+		c.buildBM25Index(options)
+		return
+	}
+	pi := persistedBM25Index{}
+	err := readFromFile(c.bm25IndexPath(), &pi, c.encoding, "")
+	if err != nil || pi.Options != options {
+		c.buildBM25Index(options)
+		return
+	}
+
+	c.bm25 = newBM25Index(options)
+	// Versions aren't reused, not even after a document was deleted, so an
+	// indexed document with the stored version has the stored content.
+	for id, doc := range pi.Docs {
+		if stored, ok := c.documents[id]; ok && stored.Version == doc.Version {
+			c.bm25.put(id, doc)
+		}
+	}
+	c.bm25.modified = len(c.bm25.docs) != len(pi.Docs)
+	for id, doc := range c.documents {
+		if _, ok := c.bm25.docs[id]; !ok {
+			c.indexBM25(id, doc)
+		}
+	}
+}
+
+// bm25IndexPath returns the path of the BM25 index file.
+func (c *Collection) bm25IndexPath() string {
+	return filepath.Join(c.persistDirectory, indexDirName, bm25IndexFileName+c.fileExt())
+}
+
+// writeBM25Index writes the BM25 index file if the index was modified since it
+// was written. It's a no-op for non-persistent collections. Must be called
+// while holding the documents write lock.
+func (c *Collection) writeBM25Index() error {
+	if c.persistDirectory == "" || c.bm25 == nil || !c.bm25.modified {
+		return nil
+	}
+	pi := persistedBM25Index{
+		Options: c.bm25.options,
+		Docs:    c.bm25.docs,
+	}
+	err := persistToFile(c.bm25IndexPath(), pi, c.encoding, c.compress, "", c.perms)
+	if err != nil {
+		return err
+	}
+	c.bm25.modified = false
+	return nil
+}
+
+// persistBM25Index writes the BM25 index file if the index was modified since
+// it was written, see [Collection.Flush].
+func (c *Collection) persistBM25Index() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return nil
+	}
+	err := c.writeBM25Index()
+	if err != nil {
+		return fmt.Errorf("couldn't persist BM25 index: %w", err)
+	}
+	return nil
+}
+
+// bm25Options returns the options of the BM25 index, or nil if there's none.
+// Must be called while holding documentsLock.
+func (c *Collection) bm25Options() *BM25IndexOptions {
+	if c.bm25 == nil {
+		return nil
+	}
+	options := c.bm25.options
+	return &options
+}
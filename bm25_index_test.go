@@ -0,0 +1,157 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestCollection_BM25Index(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	embed := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	c, err := db.CreateCollection("test", nil, embed)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	texts := map[string]string{
+		"1": "The quick brown fox jumps over the lazy dog",
+		"2": "Foxes are quick and clever animals",
+		"3": "A dog sleeps all day long in the sun",
+	}
+	for id, text := range texts {
+		err = c.AddDocument(ctx, Document{ID: id, Content: text, Embedding: []float32{1, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	_, err = c.QueryBM25(ctx, "fox", 3, nil, nil)
+	if err == nil {
+		t.Fatal("expected error without index, got nil")
+	}
+	err = c.EnableBM25Index(BM25IndexOptions{Language: "en", B: -1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(c.bm25IndexPath()); err != nil {
+		t.Fatal("expected index file, got", err)
+	}
+
+	// Same scores as the BM25 encoder
+	res, err := c.QueryBM25(ctx, "the foxes", 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[1].ID != "1" {
+		t.Fatal("expected documents 2 and 1, got", res)
+	}
+	e := NewBM25(NewLanguageAnalyzer("en"), 0, -1)
+	for _, text := range texts {
+		e.Fit(text)
+	}
+	want := sparseDotProduct(e.EncodeQuery("the foxes"), e.EncodeDocument(texts["2"]))
+	if math.Abs(float64(res[0].Similarity-want)) > 1e-5 {
+		t.Fatal("expected score", want, "got", res[0].Similarity)
+	}
+
+	// Filters
+	res, err = c.QueryBM25(ctx, "fox dog", 3, nil, map[string]string{"$contains": "sun"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "3" {
+		t.Fatal("expected document 3, got", res)
+	}
+
+	// Hybrid search with equal dense scores ranks by keyword score
+	res, err = c.QueryHybrid(ctx, "dog", 3, nil, nil, 0.5)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 3 || res[0].Similarity != 1 || res[2].ID != "2" || res[2].Similarity != 0.5 {
+		t.Fatal("expected the documents without the term last, got", res)
+	}
+
+	// Incremental updates after a flush, which aren't persisted in the index
+	// file, e.g. because of a crash
+	err = c.Flush(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "Clever animals", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "4", Content: "A fox in the garden", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// A deleted and re-added document doesn't get its previous version, which
+	// the index file still has.
+	err = c.Delete(ctx, nil, nil, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "3", Content: "A cat sleeps on the mat", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	check := func(c *Collection) {
+		t.Helper()
+		res, err := c.QueryBM25(ctx, "fox", 3, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != "4" {
+			t.Fatal("expected document 4, got", res)
+		}
+		res, err = c.QueryBM25(ctx, "sun", 3, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 0 {
+			t.Fatal("expected no results for the previous content, got", res)
+		}
+	}
+	check(c)
+
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", embed)
+	check(c2)
+	if len(c2.bm25.docs) != 3 || !c2.bm25.modified {
+		t.Fatal("expected the loaded index to be updated, got", c2.bm25.docs)
+	}
+
+	err = c.DisableBM25Index()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(c.bm25IndexPath()); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected index file to be removed, got", err)
+	}
+	_, err = c.QueryHybrid(ctx, "fox", 1, nil, nil, 0.5)
+	if err == nil {
+		t.Fatal("expected error without index, got nil")
+	}
+}
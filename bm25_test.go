@@ -0,0 +1,69 @@
+package chromem
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestBM25(t *testing.T) {
+	ctx := context.Background()
+	texts := map[string]string{
+		"1": "The quick brown fox jumps over the lazy dog",
+		"2": "Foxes are quick and clever animals",
+		"3": "A dog sleeps all day long in the sun",
+	}
+	e := NewBM25(NewLanguageAnalyzer("en"), 0, -1)
+	for _, text := range texts {
+		e.Fit(text)
+	}
+	if e.DocumentCount != 3 {
+		t.Fatal("expected 3 documents, got", e.DocumentCount)
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for id, text := range texts {
+		err = c.AddDocument(ctx, Document{ID: id, Content: text, Embedding: []float32{1, 0}, SparseEmbedding: e.EncodeDocument(text)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	// "foxes" is stemmed like "fox", and "the" is a stopword
+	res, err := c.QuerySparse(ctx, e.EncodeQuery("the foxes"), 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 3 {
+		t.Fatal("expected 3 results, got", len(res))
+	}
+	// The shorter document ranks higher for the same term frequency, and the
+	// document without the term doesn't match
+	if res[0].ID != "2" || res[1].ID != "1" || res[2].Similarity != 0 {
+		t.Fatal("expected documents 2 and 1, got", res)
+	}
+
+	// The score is the BM25 score: idf * tf * (k1 + 1) / (tf + k1 * (1 - b + b * dl / avgdl))
+	idf := math.Log(1 + (3-2+0.5)/(2+0.5))
+	avgdl := float64(e.TermCount) / 3
+	dl := float64(len(NewLanguageAnalyzer("en")(texts["2"])))
+	want := idf * 2.2 / (1 + 1.2*(0.25+0.75*dl/avgdl))
+	if math.Abs(float64(res[0].Similarity)-want) > 1e-5 {
+		t.Fatal("expected score", want, "got", res[0].Similarity)
+	}
+
+	// Unknown terms don't match
+	res, err = c.QuerySparse(ctx, e.EncodeQuery("elephant"), 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, r := range res {
+		if r.Similarity != 0 {
+			t.Fatal("expected no matches, got", res)
+		}
+	}
+}
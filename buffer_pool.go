@@ -0,0 +1,114 @@
+package chromem
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// Buffers that grew larger than this aren't put back into the pool, so that a
+// single large export or response doesn't keep its memory alive.
+const maxPooledBufferSize = 4 << 20
+
+// Pools of the transient buffers of persisting and loading documents and of
+// embedding requests, which are otherwise allocated for each document and
+// request during bulk ingest. A gzip writer alone allocates several hundred KB.
+var (
+	bufferPool     = sync.Pool{New: func() any { return &bytes.Buffer{} }}
+	bufWriterPool  = sync.Pool{New: func() any { return bufio.NewWriter(nil) }}
+	bufReaderPool  = sync.Pool{New: func() any { return bufio.NewReader(nil) }}
+	gzipWriterPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	gzipReaderPool sync.Pool
+)
+
+// getBuffer returns an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns the buffer to the pool. Its bytes must not be used anymore.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// readAll reads r into a buffer from the pool, e.g. the body of an HTTP
+// response. The caller must return the buffer with putBuffer when it's done
+// with its bytes.
+func readAll(r io.Reader) (*bytes.Buffer, error) {
+	buf := getBuffer()
+	_, err := buf.ReadFrom(r)
+	if err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// getBufWriter returns a buffered writer from the pool that writes to w.
+func getBufWriter(w io.Writer) *bufio.Writer {
+	bw := bufWriterPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	return bw
+}
+
+// putBufWriter returns the writer to the pool. It must be flushed before if
+// its buffered data is needed.
+func putBufWriter(bw *bufio.Writer) {
+	bw.Reset(nil)
+	bufWriterPool.Put(bw)
+}
+
+// getBufReader returns a buffered reader from the pool that reads from r.
+func getBufReader(r io.Reader) *bufio.Reader {
+	br := bufReaderPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	return br
+}
+
+// putBufReader returns the reader to the pool.
+func putBufReader(br *bufio.Reader) {
+	br.Reset(nil)
+	bufReaderPool.Put(br)
+}
+
+// getGzipWriter returns a gzip writer from the pool that writes to w.
+func getGzipWriter(w io.Writer) *gzip.Writer {
+	gzw := gzipWriterPool.Get().(*gzip.Writer)
+	gzw.Reset(w)
+	return gzw
+}
+
+// putGzipWriter returns the writer to the pool. It must be closed before if
+// the gzip stream is needed.
+func putGzipWriter(gzw *gzip.Writer) {
+	gzw.Reset(nil)
+	gzipWriterPool.Put(gzw)
+}
+
+// getGzipReader returns a gzip reader from the pool that reads from r. Like
+// [gzip.NewReader], it reads the gzip header.
+func getGzipReader(r io.Reader) (*gzip.Reader, error) {
+	gzr, ok := gzipReaderPool.Get().(*gzip.Reader)
+	if !ok {
+		return gzip.NewReader(r)
+	}
+	err := gzr.Reset(r)
+	if err != nil {
+		gzipReaderPool.Put(gzr)
+		return nil, err
+	}
+	return gzr, nil
+}
+
+// putGzipReader returns the reader to the pool.
+func putGzipReader(gzr *gzip.Reader) {
+	gzr.Close()
+	gzipReaderPool.Put(gzr)
+}
@@ -0,0 +1,155 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// CalibrationMethod is a method of score calibration, see [Calibration].
+type CalibrationMethod string
+
+const (
+	// CalibrationMinMax scales the similarities of the candidates linearly to
+	// [0, 1], with the most similar candidate at 1 and the least similar one
+	// at 0.
+	CalibrationMinMax CalibrationMethod = "min_max"
+	// CalibrationZScore standardizes the similarities of the candidates, so the
+	// score is the number of standard deviations a result is above the mean of
+	// the candidates.
+	CalibrationZScore CalibrationMethod = "z_score"
+	// CalibrationTable maps the similarities to scores with the calibration
+	// table, see [Calibration.Table].
+	CalibrationTable CalibrationMethod = "table"
+)
+
+// CalibrationPoint is a point of a calibration table, which maps a similarity
+// to a score.
+type CalibrationPoint struct {
+	Similarity float32
+	Score      float32
+}
+
+// Calibration configures the calibration of the results' similarities to
+// relevance scores (see [Result.Score]). The range of cosine similarities
+// depends on the embedding model, e.g. relevant documents have a similarity of
+// 0.8 with one model and 0.4 with another, so a threshold of the similarity
+// doesn't carry over between models. Calibrated scores can be thresholded with
+// [QueryOptions.MinScore] instead.
+//
+// CalibrationMinMax and CalibrationZScore are relative to the candidates of the
+// query, so they're independent of the model, but the best result of a query
+// without any relevant documents still has a high score. A calibration table,
+// e.g. fitted to the probability of relevance of labeled query results of the
+// model, is absolute.
+type Calibration struct {
+	Method CalibrationMethod
+	// CandidateK is the number of most similar documents whose similarities
+	// are the basis of CalibrationMinMax and CalibrationZScore. More candidates
+	// make the scores more stable across queries. Optional, defaults to the
+	// number of results.
+	CandidateK int
+	// Table are the points of the calibration table for CalibrationTable,
+	// sorted by similarity. Similarities between two points are interpolated
+	// linearly, and the scores of the first and last point apply below and
+	// above them.
+	Table []CalibrationPoint
+}
+
+// validate checks the calibration.
+func (c *Calibration) validate() error {
+	switch c.Method {
+	case CalibrationMinMax, CalibrationZScore:
+	case CalibrationTable:
+		if len(c.Table) == 0 {
+			return errors.New("calibration table is empty")
+		}
+		for i := 1; i < len(c.Table); i++ {
+			if c.Table[i].Similarity <= c.Table[i-1].Similarity {
+				return errors.New("calibration table must be sorted by similarity, without duplicates")
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported calibration method '%s'", c.Method)
+	}
+	if c.CandidateK < 0 {
+		return errors.New("calibration candidateK must be >= 0")
+	}
+	return nil
+}
+
+// clone returns a deep copy of the calibration.
+func (c *Calibration) clone() *Calibration {
+	if c == nil {
+		return nil
+	}
+	res := *c
+	res.Table = slices.Clone(c.Table)
+	return &res
+}
+
+// apply sets the scores of the results, which must be sorted by similarity.
+func (c *Calibration) apply(results []Result) {
+	if len(results) == 0 {
+		return
+	}
+	switch c.Method {
+	case CalibrationMinMax:
+		// The results are sorted by similarity.
+		hi, lo := results[0].Similarity, results[len(results)-1].Similarity
+		for i := range results {
+			if hi == lo {
+				results[i].Score = 1
+			} else {
+				results[i].Score = (results[i].Similarity - lo) / (hi - lo)
+			}
+		}
+	case CalibrationZScore:
+		var mean float64
+		for _, r := range results {
+			mean += float64(r.Similarity)
+		}
+		mean /= float64(len(results))
+		var variance float64
+		for _, r := range results {
+			d := float64(r.Similarity) - mean
+			variance += d * d
+		}
+		std := math.Sqrt(variance / float64(len(results)))
+		for i := range results {
+			if std == 0 {
+				results[i].Score = 0
+			} else {
+				results[i].Score = float32((float64(results[i].Similarity) - mean) / std)
+			}
+		}
+	case CalibrationTable:
+		for i := range results {
+			results[i].Score = c.lookup(results[i].Similarity)
+		}
+	}
+}
+
+// lookup returns the score of the similarity according to the table.
+func (c *Calibration) lookup(similarity float32) float32 {
+	table := c.Table
+	i, _ := slices.BinarySearchFunc(table, similarity, func(p CalibrationPoint, s float32) int {
+		switch {
+		case p.Similarity < s:
+			return -1
+		case p.Similarity > s:
+			return 1
+		}
+		return 0
+	})
+	switch {
+	case i == 0:
+		return table[0].Score
+	case i == len(table):
+		return table[len(table)-1].Score
+	}
+	lo, hi := table[i-1], table[i]
+	t := (similarity - lo.Similarity) / (hi.Similarity - lo.Similarity)
+	return lo.Score + t*(hi.Score-lo.Score)
+}
@@ -0,0 +1,106 @@
+package chromem
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestCalibration_apply(t *testing.T) {
+	results := func() []Result {
+		return []Result{{Similarity: 0.9}, {Similarity: 0.7}, {Similarity: 0.5}}
+	}
+	tt := []struct {
+		name        string
+		calibration Calibration
+		exp         []float32
+	}{
+		{"min-max", Calibration{Method: CalibrationMinMax}, []float32{1, 0.5, 0}},
+		{"z-score", Calibration{Method: CalibrationZScore}, []float32{1.2247449, 0, -1.2247449}},
+		{"table", Calibration{Method: CalibrationTable, Table: []CalibrationPoint{{0.6, 0}, {0.8, 1}}}, []float32{1, 0.5, 0}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.calibration.validate()
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			res := results()
+			tc.calibration.apply(res)
+			for i, r := range res {
+				if math.Abs(float64(r.Score-tc.exp[i])) > 1e-5 {
+					t.Fatal("expected", tc.exp, "got", res)
+				}
+			}
+		})
+	}
+
+	invalid := []Calibration{
+		{Method: "foo"},
+		{Method: CalibrationTable},
+		{Method: CalibrationTable, Table: []CalibrationPoint{{0.8, 1}, {0.6, 0}}},
+		{Method: CalibrationMinMax, CandidateK: -1},
+	}
+	for _, c := range invalid {
+		if err := c.validate(); err == nil {
+			t.Fatal("expected error for", c, "got nil")
+		}
+	}
+}
+
+func TestCollection_QueryWithOptions_Calibration(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0.8, 0.6}},
+		{ID: "3", Embedding: []float32{0, 1}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The scores are relative to all 3 candidates, but only 2 results are
+	// returned.
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       2,
+		Calibration:    &Calibration{Method: CalibrationMinMax, CandidateK: 3},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].Score != 1 || math.Abs(float64(res[1].Score)-0.8) > 1e-5 {
+		t.Fatal("expected scores 1 and 0.8, got", res)
+	}
+
+	// Minimum score from the defaults
+	err = c.SetQueryDefaults(QueryDefaults{
+		Calibration: &Calibration{Method: CalibrationMinMax, CandidateK: 3},
+		MinScore:    0.9,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected only document 1, got", res)
+	}
+
+	// Minimum score without calibration
+	err = c.SetQueryDefaults(QueryDefaults{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 2, MinScore: 0.5})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
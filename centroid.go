@@ -0,0 +1,213 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+)
+
+// Default maximum number of iterations of [Collection.KMeans].
+const kMeansDefaultMaxIterations = 20
+
+// KMeansOptions configures [Collection.KMeans].
+type KMeansOptions struct {
+	// K is the number of clusters. Mandatory, must be <= the number of
+	// clustered documents.
+	K int
+	// MaxIterations limits the k-means iterations. Optional, 20 by default.
+	MaxIterations int
+	// Seed makes the clustering reproducible. The same seed returns the same
+	// clusters as long as the documents don't change. Optional, 0 means
+	// a random seed.
+	Seed int64
+
+	// Where and Filter restrict the clustering to the documents that match
+	// them. Optional, all documents are clustered by default.
+	Where  map[string]string
+	Filter Filter
+
+	// MetadataKey is a metadata key to store the cluster index of each
+	// clustered document in, e.g. to filter or aggregate by topic afterwards.
+	// The documents are replaced via [Collection.UpsertDocument], so hooks and
+	// the metadata schema apply, and their versions are incremented. Documents
+	// that already have the right value aren't touched. Optional.
+	MetadataKey string
+}
+
+// Clustering is the result of [Collection.KMeans].
+type Clustering struct {
+	// Centroids holds the normalized centroid of each cluster.
+	Centroids [][]float32
+	// Members holds the IDs of the documents of each cluster, sorted.
+	Members [][]string
+}
+
+// Centroid returns the normalized mean of the embeddings of the documents that
+// match the filters, e.g. to describe a topic or as query embedding for "more
+// like these". All of them must have the same dimensions.
+//
+//   - where: Conditional filtering on metadata. Optional.
+//   - filter: Composable metadata filter. Optional.
+func (c *Collection) Centroid(_ context.Context, where map[string]string, filter Filter) ([]float32, error) {
+	docs, err := c.embeddedDocs(where, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return meanDirection(normalizedEmbeddings(docs)), nil
+}
+
+// KMeans clusters the embeddings of the documents with spherical k-means (with
+// cosine similarity), e.g. to explore the topics of a collection. It's the
+// same algorithm that [Collection.BuildIVFIndex] uses, but runs on all
+// matching documents instead of a sample. All of them must have the same
+// dimensions. With [KMeansOptions.MetadataKey], the cluster indexes are stored
+// in the documents' metadata.
+func (c *Collection) KMeans(ctx context.Context, options KMeansOptions) (*Clustering, error) {
+	if options.K <= 0 {
+		return nil, errors.New("k must be > 0")
+	}
+	maxIterations := options.MaxIterations
+	if maxIterations < 0 {
+		return nil, errors.New("maxIterations must be >= 0")
+	} else if maxIterations == 0 {
+		maxIterations = kMeansDefaultMaxIterations
+	}
+
+	docs, err := c.embeddedDocs(options.Where, options.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if options.K > len(docs) {
+		return nil, fmt.Errorf("k must be <= the number of matching documents (%d)", len(docs))
+	}
+
+	seed := options.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	r := rand.New(rand.NewSource(seed))
+	// The documents are sorted, so shuffling them with the seed is
+	// reproducible. The first k are the initial centroids.
+	r.Shuffle(len(docs), func(i, j int) { docs[i], docs[j] = docs[j], docs[i] })
+	vectors := normalizedEmbeddings(docs)
+
+	centroids, err := kMeans(ctx, vectors, options.K, maxIterations, r, func(int) {})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't cluster documents: %w", err)
+	}
+	assignments := make([]int, len(vectors))
+	err = parallelFor(ctx, len(vectors), func(i int) {
+		assignments[i] = nearestCentroid(centroids, vectors[i])
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't assign documents: %w", err)
+	}
+
+	res := &Clustering{
+		Centroids: centroids,
+		Members:   make([][]string, len(centroids)),
+	}
+	for i, doc := range docs {
+		res.Members[assignments[i]] = append(res.Members[assignments[i]], doc.ID)
+	}
+	for _, members := range res.Members {
+		slices.Sort(members)
+	}
+
+	if options.MetadataKey != "" {
+		err = c.setClusterMetadata(ctx, docs, assignments, options.MetadataKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// embeddedDocs returns the documents that match the filters, sorted by ID. They
+// must have embeddings with the same dimensions.
+func (c *Collection) embeddedDocs(where map[string]string, filter Filter) ([]*Document, error) {
+	// The documents are never modified, only replaced, so they can be used
+	// without holding the lock.
+	c.documentsLock.RLock()
+	if c.closed {
+		c.documentsLock.RUnlock()
+		return nil, ErrClosed
+	}
+	docs, err := c.filterWith(c.column.docs(c.documents), where, nil, filter)
+	c.documentsLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no matching documents: %w", ErrNotFound)
+	}
+	// The documents might be the collection's shared slice of all documents.
+	docs = slices.Clone(docs)
+	slices.SortFunc(docs, func(a, b *Document) int { return cmp.Compare(a.ID, b.ID) })
+	dims := len(docs[0].Embedding)
+	for _, doc := range docs {
+		if len(doc.Embedding) != dims {
+			return nil, fmt.Errorf("%w: document '%s' has %d dimensions, expected %d", ErrDimensionMismatch, doc.ID, len(doc.Embedding), dims)
+		}
+	}
+	return docs, nil
+}
+
+// normalizedEmbeddings returns the embeddings of the documents, normalized if
+// they aren't yet, e.g. due to the collection's normalization policy.
+func normalizedEmbeddings(docs []*Document) [][]float32 {
+	vectors := make([][]float32, len(docs))
+	for i, doc := range docs {
+		vectors[i] = doc.Embedding
+		if !isNormalized(vectors[i]) {
+			vectors[i] = normalizeVector(vectors[i])
+		}
+	}
+	return vectors
+}
+
+// meanDirection returns the normalized mean of the normalized vectors, which
+// must have the same dimensions.
+func meanDirection(vectors [][]float32) []float32 {
+	sum := make([]float32, len(vectors[0]))
+	for _, v := range vectors {
+		for i := range sum {
+			sum[i] += v[i]
+		}
+	}
+	return normalizeVector(sum)
+}
+
+// setClusterMetadata stores the cluster index of each document in the metadata
+// key, replacing the documents that don't have it yet.
+func (c *Collection) setClusterMetadata(ctx context.Context, docs []*Document, assignments []int, key string) error {
+	for i, doc := range docs {
+		value := strconv.Itoa(assignments[i])
+		if v, ok := doc.Metadata[key]; ok && v == value {
+			continue
+		}
+
+		c.documentsLock.RLock()
+		cp, err := c.readableCopy(doc)
+		c.documentsLock.RUnlock()
+		if err != nil {
+			return fmt.Errorf("couldn't read document '%s': %w", doc.ID, err)
+		}
+		if cp.Metadata == nil {
+			cp.Metadata = make(map[string]string, 1)
+		}
+		cp.Metadata[key] = value
+		// Fails if the document was changed since it was clustered.
+		err = c.UpsertDocument(ctx, cp, doc.Version)
+		if err != nil {
+			return fmt.Errorf("couldn't set cluster of document '%s': %w", doc.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_Centroid(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"topic": "a"}},
+		{ID: "2", Embedding: []float32{0, 1}, Metadata: map[string]string{"topic": "a"}},
+		{ID: "3", Embedding: []float32{-1, 0}, Metadata: map[string]string{"topic": "b"}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	centroid, err := c.Centroid(ctx, map[string]string{"topic": "a"}, Filter{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := float32(1 / math.Sqrt2)
+	if len(centroid) != 2 || math.Abs(float64(centroid[0]-exp)) > 1e-6 || math.Abs(float64(centroid[1]-exp)) > 1e-6 {
+		t.Fatal("expected", []float32{exp, exp}, "got", centroid)
+	}
+
+	_, err = c.Centroid(ctx, map[string]string{"topic": "c"}, Filter{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+}
+
+func TestCollection_KMeans(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Two clear clusters around the axes
+	docs := []Document{
+		{ID: "x1", Embedding: []float32{1, 0.1}},
+		{ID: "x2", Embedding: []float32{1, 0}},
+		{ID: "x3", Embedding: []float32{1, -0.1}},
+		{ID: "y1", Embedding: []float32{0.1, 1}},
+		{ID: "y2", Embedding: []float32{0, 1}},
+		{ID: "y3", Embedding: []float32{-0.1, 1}, Metadata: map[string]string{"skip": "true"}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.KMeans(ctx, KMeansOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = c.KMeans(ctx, KMeansOptions{K: 7})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	res, err := c.KMeans(ctx, KMeansOptions{K: 2, Seed: 1, MetadataKey: "cluster"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res.Centroids) != 2 || len(res.Members) != 2 {
+		t.Fatal("expected 2 clusters, got", res)
+	}
+	x, y := res.Members[0], res.Members[1]
+	if x[0] != "x1" {
+		x, y = y, x
+	}
+	if !slices.Equal(x, []string{"x1", "x2", "x3"}) || !slices.Equal(y, []string{"y1", "y2", "y3"}) {
+		t.Fatal("expected the x and y clusters, got", res.Members)
+	}
+
+	// The cluster indexes are stored in the metadata.
+	for i, members := range res.Members {
+		for _, id := range members {
+			doc, err := c.GetByID(ctx, id)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if doc.Metadata["cluster"] != strconv.Itoa(i) {
+				t.Fatal("expected cluster", i, "got", doc.Metadata)
+			}
+		}
+	}
+
+	// Filtered
+	res, err = c.KMeans(ctx, KMeansOptions{K: 1, Filter: Where().Ne("skip", "true")})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res.Members[0]) != 5 {
+		t.Fatal("expected 5 documents, got", res.Members)
+	}
+}
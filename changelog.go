@@ -0,0 +1,303 @@
+package chromem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Directory of a persistent collection's change log, see [changeLog].
+	changeLogDirName = "changes"
+	changeLogExt     = ".log"
+	// Number of records per segment of the change log, and number of segments
+	// that are kept. Replicas that fall further behind read all files again.
+	changeLogSegmentSize = 1024
+	changeLogSegments    = 8
+)
+
+// Operations of change log records.
+const (
+	// The document file with the record's ID and file name was written or
+	// removed.
+	changeOpDocument = "document"
+	// The collection's metadata file was written.
+	changeOpMetadata = "metadata"
+	// Changes before the record might be missing from the log, e.g. because
+	// the leader crashed between writing a file and logging it. It's written
+	// when a collection is loaded, unless it was closed cleanly.
+	changeOpReset = "reset"
+	// The collection was closed, so all changes are logged.
+	changeOpClose = "close"
+)
+
+// changeRecord is a record of the change log.
+type changeRecord struct {
+	Seq  uint64 `json:"seq"`
+	Op   string `json:"op"`
+	ID   string `json:"id,omitempty"`
+	File string `json:"file,omitempty"`
+}
+
+// changeLog is the log of the changes of a persistent collection's files, which
+// read replicas tail instead of comparing all files, see [Replica]. The records
+// are appended as JSON lines to segment files in the collection's directory,
+// named after the sequence number of their first record. A record is appended
+// after the change was written, so a replica that reads the record also reads
+// the change. The zero value is ready to use.
+type changeLog struct {
+	lock   sync.Mutex
+	loaded bool
+	// Sequence number of the last record, and first one and number of records
+	// of the current segment
+	seq     uint64
+	segment uint64
+	count   int
+}
+
+// load reads the state of the log in the directory, if it's not loaded yet, and
+// returns the operation of the last record. Must be called while holding the
+// log's lock.
+func (l *changeLog) load(dir string) (string, error) {
+	if l.loaded {
+		return "", nil
+	}
+	segments, err := changeLogSegmentSeqs(os.DirFS(dir))
+	if err != nil {
+		return "", err
+	}
+	var lastOp string
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		f, err := os.Open(filepath.Join(dir, changeLogSegmentName(last)))
+		if err != nil {
+			return "", fmt.Errorf("couldn't open change log segment: %w", err)
+		}
+		records, complete, err := readChangeRecords(f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		l.seq, l.segment, l.count = last-1, last, len(records)
+		if len(records) > 0 {
+			l.seq = records[len(records)-1].Seq
+			lastOp = records[len(records)-1].Op
+		}
+		if !complete {
+			// Don't append to a partially written record, e.g. after a crash.
+			l.count = changeLogSegmentSize
+		}
+	}
+	l.loaded = true
+	return lastOp, nil
+}
+
+// open loads the log of a collection that was loaded from disk, and appends a
+// reset record unless it was closed cleanly.
+func (l *changeLog) open(dir string, perms filePerms) error {
+	l.lock.Lock()
+	lastOp, err := l.load(dir)
+	l.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if lastOp == changeOpClose {
+		return nil
+	}
+	return l.append(dir, perms, changeRecord{Op: changeOpReset})
+}
+
+// append appends the records to the log in the directory, with the next
+// sequence numbers. Segments are rotated when they're full, and the oldest ones
+// are removed.
+func (l *changeLog) append(dir string, perms filePerms, records ...changeRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	_, err := l.load(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(records) > 0 {
+		if l.count >= changeLogSegmentSize || l.segment == 0 {
+			l.segment, l.count = l.seq+1, 0
+			err := l.prune(dir)
+			if err != nil {
+				return err
+			}
+		}
+		n := min(len(records), changeLogSegmentSize-l.count)
+		var sb strings.Builder
+		for i := range records[:n] {
+			records[i].Seq = l.seq + uint64(i) + 1
+			line, err := json.Marshal(records[i])
+			if err != nil {
+				return fmt.Errorf("couldn't encode change log record: %w", err)
+			}
+			sb.Write(line)
+			sb.WriteByte('\n')
+		}
+		err := perms.mkdirAll(dir)
+		if err != nil {
+			return fmt.Errorf("couldn't create change log directory: %w", err)
+		}
+		f, err := perms.openFile(filepath.Join(dir, changeLogSegmentName(l.segment)), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+		if err != nil {
+			return fmt.Errorf("couldn't open change log segment: %w", err)
+		}
+		_, err = io.WriteString(f, sb.String())
+		err = errors.Join(err, f.Close())
+		if err != nil {
+			// The segment might end with a partial record now.
+			l.count = changeLogSegmentSize
+			return fmt.Errorf("couldn't write change log: %w", err)
+		}
+		l.seq += uint64(n)
+		l.count += n
+		records = records[n:]
+	}
+	return nil
+}
+
+// prune removes the oldest segments, so that a new one can be added without
+// exceeding the number of kept segments. Must be called while holding the log's
+// lock.
+func (l *changeLog) prune(dir string) error {
+	segments, err := changeLogSegmentSeqs(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	for len(segments) >= changeLogSegments {
+		err := removeFile(filepath.Join(dir, changeLogSegmentName(segments[0])))
+		if err != nil {
+			return err
+		}
+		segments = segments[1:]
+	}
+	return nil
+}
+
+// logChanges appends the records to the collection's change log. It's a no-op
+// for non-persistent collections.
+func (c *Collection) logChanges(records ...changeRecord) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+	return c.changes.append(filepath.Join(c.persistDirectory, changeLogDirName), c.perms, records...)
+}
+
+// documentChange returns the change log record of writing or removing the
+// document's file.
+func (c *Collection) documentChange(id string) changeRecord {
+	return changeRecord{Op: changeOpDocument, ID: id, File: filepath.Base(c.getDocPath(id))}
+}
+
+// changeLogSegmentName returns the file name of the segment whose first record
+// has the sequence number.
+func changeLogSegmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, changeLogExt)
+}
+
+// changeLogSegmentSeqs returns the sequence numbers of the first records of the
+// segments in the change log directory of the file system, sorted.
+func changeLogSegmentSeqs(fsys fs.FS) ([]uint64, error) {
+	dirEntries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't read change log directory: %w", err)
+	}
+	var res []uint64
+	for _, dirEntry := range dirEntries {
+		stem, ok := strings.CutSuffix(dirEntry.Name(), changeLogExt)
+		if !ok || dirEntry.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(stem, 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, seq)
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// readChangeLog returns the records of the change log in the directory of the
+// file system with a sequence number greater than after, and the sequence
+// numbers of the oldest and the newest record in the log, which are 0 if
+// there's no log. A record that's being written is left out.
+func readChangeLog(fsys fs.FS, dir string, after uint64) (records []changeRecord, first, last uint64, err error) {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	segments, err := changeLogSegmentSeqs(sub)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(segments) == 0 {
+		return nil, 0, 0, nil
+	}
+	first = segments[0]
+	for i, segment := range segments {
+		// Skip segments whose records are all applied already.
+		if i+1 < len(segments) && segments[i+1] <= after+1 {
+			continue
+		}
+		f, err := sub.Open(changeLogSegmentName(segment))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed by the leader in the meantime
+				continue
+			}
+			return nil, 0, 0, fmt.Errorf("couldn't open change log segment: %w", err)
+		}
+		segmentRecords, _, err := readChangeRecords(f)
+		f.Close()
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		for _, record := range segmentRecords {
+			last = max(last, record.Seq)
+			if record.Seq > after {
+				records = append(records, record)
+			}
+		}
+	}
+	return records, first, last, nil
+}
+
+// readChangeRecords reads the records of a segment, and whether its last line
+// is complete.
+func readChangeRecords(r io.Reader) ([]changeRecord, bool, error) {
+	br := getBufReader(r)
+	defer putBufReader(br)
+	var res []changeRecord
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return res, len(line) == 0, nil
+		} else if err != nil {
+			return nil, false, fmt.Errorf("couldn't read change log segment: %w", err)
+		}
+		var record changeRecord
+		err = json.Unmarshal(line, &record)
+		if err != nil {
+			return nil, false, fmt.Errorf("couldn't decode change log record: %w", err)
+		}
+		res = append(res, record)
+	}
+}
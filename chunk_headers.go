@@ -0,0 +1,179 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"text/template"
+)
+
+// DefaultChunkHeaderTemplate is the default template of [ChunkHeaderOptions].
+const DefaultChunkHeaderTemplate = "{{with .Title}}Document: {{.}}\n{{end}}{{with .Breadcrumbs}}Section: {{.}}\n{{end}}"
+
+// ChunkHeaderOptions configures the contextual headers of
+// [SplitStageWithHeaders].
+type ChunkHeaderOptions struct {
+	// Template is a [text/template] for the header, which is executed with
+	// [ChunkHeaderData]. Optional, defaults to [DefaultChunkHeaderTemplate].
+	Template string
+	// TitleKey is the metadata key of the document's title, e.g. set by a
+	// [TitleEnricher] in an earlier stage. Optional. Without it, or if the
+	// document doesn't have the key, the first Markdown heading of the content
+	// is the title.
+	TitleKey string
+}
+
+// ChunkHeaderData is the data of the header template of [ChunkHeaderOptions].
+type ChunkHeaderData struct {
+	// Title is the document's title, see [ChunkHeaderOptions.TitleKey].
+	Title string
+	// Sections are the Markdown headings of the sections that the chunk starts
+	// in, from the outermost to the innermost one.
+	Sections []string
+	// Breadcrumbs are the sections joined with " > ".
+	Breadcrumbs string
+	// Metadata is the document's metadata.
+	Metadata map[string]string
+	// Index is the chunk's index, starting at 0.
+	Index int
+	// Count is the number of chunks of the document.
+	Count int
+}
+
+// SplitStageWithHeaders is like [SplitStage], but prepends a contextual header,
+// like the document's title and the breadcrumbs of the chunk's section, to the
+// text that's embedded for each chunk (see [Document.EmbeddingText]), while
+// the chunk's content remains unchanged for display. Chunks on their own often
+// lack the context that makes them retrievable, like which product a "Setup"
+// section is about, which the header adds.
+//
+// Documents with a single chunk get the header as well, and for them the
+// document's ID is kept.
+func SplitStageWithHeaders(splitter TextSplitter, options ChunkHeaderOptions) (PipelineStage, error) {
+	text := options.Template
+	if text == "" {
+		text = DefaultChunkHeaderTemplate
+	}
+	tmpl, err := template.New("header").Parse(text)
+	if err != nil {
+		return PipelineStage{}, fmt.Errorf("couldn't parse header template: %w", err)
+	}
+
+	return PipelineStage{
+		Name: "split",
+		Process: func(_ context.Context, doc Document) ([]Document, error) {
+			if len(doc.Embedding) != 0 || doc.Content == "" {
+				return []Document{doc}, nil
+			}
+
+			headings := markdownHeadings(doc.Content)
+			title := doc.Metadata[options.TitleKey]
+			if options.TitleKey == "" || title == "" {
+				if len(headings) > 0 {
+					title = headings[0].text
+				}
+			}
+
+			chunks := splitter(doc.Content)
+			res := make([]Document, len(chunks))
+			// Position of the previous chunk in the content, for finding the next
+			// one, as chunks can overlap.
+			pos := -1
+			for i, content := range chunks {
+				if j := strings.Index(doc.Content[pos+1:], content); j >= 0 {
+					pos += 1 + j
+				}
+				sections := sectionsAt(headings, max(0, pos))
+				data := ChunkHeaderData{
+					Title:       title,
+					Sections:    sections,
+					Breadcrumbs: strings.Join(sections, " > "),
+					Metadata:    doc.Metadata,
+					Index:       i,
+					Count:       len(chunks),
+				}
+				var sb strings.Builder
+				err := tmpl.Execute(&sb, data)
+				if err != nil {
+					return nil, fmt.Errorf("couldn't execute header template: %w", err)
+				}
+
+				chunk := Document{
+					ID: chunkID(doc.ID, i),
+					// Copied so that later stages can modify each chunk's metadata.
+					Metadata: maps.Clone(doc.Metadata),
+					Content:  content,
+				}
+				if len(chunks) == 1 {
+					chunk = doc
+					chunk.Content = content
+				}
+				if header := strings.TrimSpace(sb.String()); header != "" {
+					chunk.EmbeddingText = header + "\n\n" + content
+				}
+				res[i] = chunk
+			}
+			return res, nil
+		},
+	}, nil
+}
+
+// markdownHeading is a heading of a Markdown text.
+type markdownHeading struct {
+	level int
+	text  string
+	// pos is the heading's position in the text.
+	pos int
+}
+
+// markdownHeadings returns the ATX headings of the Markdown text, like
+// "## Setup", in order.
+func markdownHeadings(text string) []markdownHeading {
+	var headings []markdownHeading
+	pos := 0
+	inCode := false
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineStart := pos
+		pos += len(line)
+		trimmed := strings.TrimSpace(line)
+		// Lines in fenced code blocks aren't headings, e.g. shell comments.
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if level == 0 || level > 6 || (len(trimmed) > level && trimmed[level] != ' ') {
+			continue
+		}
+		heading := strings.TrimSpace(strings.TrimRight(trimmed[level:], "#"))
+		if heading == "" {
+			continue
+		}
+		headings = append(headings, markdownHeading{level: level, text: heading, pos: lineStart})
+	}
+	return headings
+}
+
+// sectionsAt returns the headings of the sections that the position is in,
+// from the outermost to the innermost one.
+func sectionsAt(headings []markdownHeading, pos int) []string {
+	var stack []markdownHeading
+	for _, h := range headings {
+		if h.pos > pos {
+			break
+		}
+		for len(stack) > 0 && stack[len(stack)-1].level >= h.level {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, h)
+	}
+	sections := make([]string, len(stack))
+	for i, h := range stack {
+		sections[i] = h.text
+	}
+	return sections
+}
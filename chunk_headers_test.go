@@ -0,0 +1,80 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitStageWithHeaders(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	lock := sync.Mutex{}
+	var embedded []string
+	c, err := db.CreateCollection("test", nil, func(_ context.Context, text string) ([]float32, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		embedded = append(embedded, text)
+		return []float32{1, 0}, nil
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Splits at empty lines
+	splitter := func(text string) []string {
+		return strings.Split(text, "\n\n")
+	}
+
+	stage, err := SplitStageWithHeaders(splitter, ChunkHeaderOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	content := "# Widget\n\nIntro\n\n## Setup\n\n```\n# not a heading\n```\n\n### Linux\n\nRun it\n\n## Usage\n\nUse it"
+	p := Pipeline{
+		Loader: NewSliceLoader([]Document{{ID: "doc", Content: content}}),
+		Stages: []PipelineStage{stage},
+	}
+	_, err = p.Run(ctx, c)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "doc/5")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := "Document: Widget\nSection: Widget > Setup > Linux\n\nRun it"
+	if doc.Content != "Run it" || doc.EmbeddingText != expected {
+		t.Fatal("expected the original content and the stored embedding text, got", doc)
+	}
+	if !slices.Contains(embedded, expected) {
+		t.Fatal("expected", expected, "to be embedded, got", embedded)
+	}
+	expected = "Document: Widget\nSection: Widget > Usage\n\nUse it"
+	if !slices.Contains(embedded, expected) {
+		t.Fatal("expected", expected, "to be embedded, got", embedded)
+	}
+
+	// Custom template and title from the metadata, and a single chunk keeps
+	// the document's ID.
+	stage, err = SplitStageWithHeaders(func(text string) []string { return []string{text} }, ChunkHeaderOptions{
+		Template: "{{.Title}} ({{.Metadata.lang}}) {{.Index}}/{{.Count}}",
+		TitleKey: "title",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs, err := stage.Process(ctx, Document{ID: "1", Metadata: map[string]string{"title": "Manual", "lang": "en"}, Content: "Text"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "1" || docs[0].Content != "Text" || docs[0].EmbeddingText != "Manual (en) 0/1\n\nText" {
+		t.Fatal("expected the document with header, got", docs)
+	}
+
+	_, err = SplitStageWithHeaders(splitter, ChunkHeaderOptions{Template: "{{"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
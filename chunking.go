@@ -0,0 +1,155 @@
+package chromem
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenCounter returns the number of tokens of a text, as counted by the
+// tokenizer of an embedding model. You can use [CountTokensApprox] or a wrapper
+// of an exact tokenizer like tiktoken for OpenAI's models.
+type TokenCounter func(text string) int
+
+// CountTokensApprox approximates the number of tokens of a text without a
+// tokenizer, for BPE tokenizers like the ones of OpenAI, Cohere and Mistral.
+// It uses the common rules of thumb of about 4 characters or 3/4 of a word per
+// token, whichever results in more tokens. CJK characters count as one token
+// each. For English text the result is usually within 10-20% of the exact
+// count.
+func CountTokensApprox(text string) int {
+	runes, words, cjk := 0, 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			inWord = false
+			runes++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			inWord = false
+			cjk++
+		default:
+			if !inWord {
+				words++
+				inWord = true
+			}
+			runes++
+		}
+	}
+	return cjk + max((runes+3)/4, (words*4+2)/3)
+}
+
+// TextSplitter splits a text into chunks, for example to embed documents that
+// are longer than the embedding model's context in parts.
+type TextSplitter func(text string) []string
+
+// NewTextSplitter returns a splitter that splits texts into chunks of at most
+// maxTokens tokens, at whitespace. Consecutive chunks overlap by up to overlap
+// tokens, so that sentences at chunk boundaries aren't torn apart. Words that are
+// longer than maxTokens are split as well. The chunks are substrings of the text,
+// so line breaks etc. are preserved.
+//
+//   - maxTokens: The maximum number of tokens per chunk. Must be > 0.
+//   - overlap: The number of tokens of overlap. Must be >= 0 and < maxTokens.
+//   - countTokens: Counts the tokens. Optional, defaults to [CountTokensApprox].
+func NewTextSplitter(maxTokens, overlap int, countTokens TokenCounter) (TextSplitter, error) {
+	if maxTokens <= 0 {
+		return nil, errors.New("maxTokens must be > 0")
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		return nil, errors.New("overlap must be >= 0 and < maxTokens")
+	}
+	if countTokens == nil {
+		countTokens = CountTokensApprox
+	}
+
+	return func(text string) []string {
+		words := splitWords(text, maxTokens, countTokens)
+		if len(words) == 0 {
+			return nil
+		}
+
+		var chunks []string
+		start := 0
+		// Tokens of the chunk, estimated as sum of the tokens of its words, which
+		// is usually more than the exact count, as tokens can span word
+		// boundaries.
+		tokens := 0
+		for i, w := range words {
+			tokens += w.tokens
+			if tokens <= maxTokens || i == start {
+				continue
+			}
+			// Check the exact count before splitting.
+			exact := countTokens(text[words[start].start:w.end])
+			if exact <= maxTokens {
+				tokens = exact
+				continue
+			}
+			chunks = append(chunks, text[words[start].start:words[i-1].end])
+
+			// Start the next chunk with the overlap, if it fits together with
+			// the current word.
+			next := i
+			overlapTokens := 0
+			for next > start+1 && overlapTokens+words[next-1].tokens <= overlap && overlapTokens+words[next-1].tokens+w.tokens <= maxTokens {
+				next--
+				overlapTokens += words[next].tokens
+			}
+			start = next
+			tokens = overlapTokens + w.tokens
+		}
+		return append(chunks, text[words[start].start:words[len(words)-1].end])
+	}, nil
+}
+
+// word is a word of a text, with its byte offsets and number of tokens.
+type word struct {
+	start, end int
+	tokens     int
+}
+
+// splitWords splits the text at whitespace. Words with more than maxTokens
+// tokens are split into multiple parts.
+func splitWords(text string, maxTokens int, countTokens TokenCounter) []word {
+	var words []word
+	var add func(start, end int)
+	add = func(start, end int) {
+		tokens := countTokens(text[start:end])
+		runes := utf8.RuneCountInString(text[start:end])
+		// A single rune can't be split any further.
+		if tokens <= maxTokens || runes == 1 {
+			words = append(words, word{start: start, end: end, tokens: tokens})
+			return
+		}
+		// Split into parts with a proportional number of runes, which fit into
+		// maxTokens for evenly distributed tokens. Parts that don't fit are
+		// split again.
+		partRunes := min(runes-1, max(1, runes*maxTokens/tokens))
+		partStart, n := start, 0
+		for i := range text[start:end] {
+			if n == partRunes {
+				add(partStart, start+i)
+				partStart, n = start+i, 0
+			}
+			n++
+		}
+		add(partStart, end)
+	}
+
+	wordStart := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if wordStart >= 0 {
+				add(wordStart, i)
+				wordStart = -1
+			}
+		} else if wordStart < 0 {
+			wordStart = i
+		}
+	}
+	if wordStart >= 0 {
+		add(wordStart, len(text))
+	}
+	return words
+}
@@ -0,0 +1,85 @@
+package chromem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountTokensApprox(t *testing.T) {
+	tt := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 2},
+		{"hello world", 3},
+		{"The quick brown fox jumps over the lazy dog.", 12},
+		{strings.Repeat("a", 400), 100},
+		{"你好世界", 4},
+	}
+	for _, tc := range tt {
+		if got := CountTokensApprox(tc.text); got != tc.want {
+			t.Fatal("expected", tc.want, "tokens for", tc.text, "got", got)
+		}
+	}
+}
+
+func TestNewTextSplitter(t *testing.T) {
+	// Each word is one token
+	countWords := func(text string) int {
+		return len(strings.Fields(text))
+	}
+
+	_, err := NewTextSplitter(0, 0, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = NewTextSplitter(2, 2, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	t.Run("no overlap", func(t *testing.T) {
+		split, err := NewTextSplitter(3, 0, countWords)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		got := split("a b c\nd e  f g ")
+		want := []string{"a b c", "d e  f", "g"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatal("expected", want, "got", got)
+		}
+		if got := split(" \n "); len(got) != 0 {
+			t.Fatal("expected no chunks, got", got)
+		}
+	})
+
+	t.Run("overlap", func(t *testing.T) {
+		split, err := NewTextSplitter(3, 1, countWords)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		got := split("a b c d e f")
+		want := []string{"a b c", "c d e", "e f"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatal("expected", want, "got", got)
+		}
+	})
+
+	t.Run("long words", func(t *testing.T) {
+		split, err := NewTextSplitter(10, 0, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		text := "short " + strings.Repeat("x", 100) + " end"
+		chunks := split(text)
+		for _, chunk := range chunks {
+			if CountTokensApprox(chunk) > 10 {
+				t.Fatal("expected chunks with at most 10 tokens, got", chunk)
+			}
+		}
+		if strings.ReplaceAll(strings.Join(chunks, ""), " ", "") != strings.ReplaceAll(text, " ", "") {
+			t.Fatal("expected chunks to cover the text, got", chunks)
+		}
+	})
+}
@@ -0,0 +1,287 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+)
+
+// ErrMergeConflict is returned by [Collection.MergeFrom] with
+// [MergeConflictError] when a document exists in both collections.
+var ErrMergeConflict = errors.New("document exists in both collections")
+
+// MergeConflictPolicy defines how [Collection.MergeFrom] handles documents
+// that exist in both collections.
+type MergeConflictPolicy int
+
+const (
+	// MergeConflictError aborts the merge before adding any document.
+	MergeConflictError MergeConflictPolicy = iota
+	// MergeConflictSkip keeps the existing document.
+	MergeConflictSkip
+	// MergeConflictOverwrite replaces the existing document with the other one.
+	MergeConflictOverwrite
+)
+
+// CloneCollection creates the collection dst as a deep copy of the collection
+// src, e.g. to build a derived subset of a corpus. The documents are copied with
+// their embeddings, so nothing is embedded again. The collection's metadata,
+// embedding function and model, metadata schema, projection, query defaults,
+// normalization policy, similarity function, Matryoshka search, late
+// interaction, strict mode, content limit, phrase index and content storage
+// settings are copied as well. Indexes like the IVF index, hooks and the query
+// cache aren't copied, so they have to be set up for the clone if needed.
+//
+// Collections with content encryption can't be cloned, as the clone would store
+// the content unencrypted. Create the collection with content encryption and
+// use [Collection.MergeFrom] instead.
+//
+//   - src: The name of the existing collection.
+//   - dst: The name of the new collection. It must not exist yet.
+//   - filter: Only documents that match it are copied. The zero value matches
+//     all documents.
+func (db *DB) CloneCollection(ctx context.Context, src, dst string, filter Filter) (*Collection, error) {
+	if src == dst {
+		return nil, errors.New("source and destination collection are the same")
+	}
+	db.collectionsLock.RLock()
+	srcCol, srcOK := db.collections[src]
+	_, dstOK := db.collections[dst]
+	db.collectionsLock.RUnlock()
+	if !srcOK {
+		return nil, fmt.Errorf("collection '%s' %w", src, ErrNotFound)
+	}
+	if dstOK {
+		return nil, fmt.Errorf("collection '%s' already exists", dst)
+	}
+
+	srcCol.documentsLock.RLock()
+	encrypted := srcCol.contentEncrypted
+	discardContent := srcCol.discardContent
+	contentStore := srcCol.contents != nil
+	proj := srcCol.projection
+	schema := srcCol.metadataSchema
+	phraseIndex := srcCol.phrases != nil
+	statsKeys := srcCol.metadataStats.keys()
+	bm25Options := srcCol.bm25Options()
+	compressionBlockSize, compressionCodec := srcCol.contentCompression(), srcCol.contentCodec()
+	srcCol.documentsLock.RUnlock()
+	if encrypted {
+		return nil, errors.New("collections with content encryption can't be cloned")
+	}
+
+	docs, err := srcCol.copyDocuments(nil, nil, filter)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't copy documents: %w", err)
+	}
+
+	dstCol, err := db.CreateCollection(dst, srcCol.Metadata(), srcCol.getEmbed())
+	if err != nil {
+		return nil, err
+	}
+	// Remove the incomplete clone on error.
+	cleanup := func(err error) (*Collection, error) {
+		_ = db.DeleteCollection(dst)
+		return nil, err
+	}
+	err = dstCol.SetEmbeddingModel(srcCol.EmbeddingModel())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetMetadataSchema(schema)
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetQueryDefaults(srcCol.QueryDefaults())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetNormalizationPolicy(srcCol.NormalizationPolicy())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetSimilarityFunc(srcCol.SimilarityFunc())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetMatryoshkaSearch(srcCol.MatryoshkaSearch())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetLateInteraction(srcCol.LateInteraction())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetStrictMode(srcCol.StrictMode())
+	if err != nil {
+		return cleanup(err)
+	}
+	err = dstCol.SetContentLimit(srcCol.ContentLimit())
+	if err != nil {
+		return cleanup(err)
+	}
+	if contentStore {
+		err = dstCol.EnableContentStore()
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+	err = dstCol.SetDiscardContent(discardContent)
+	if err != nil {
+		return cleanup(err)
+	}
+	if phraseIndex && !discardContent {
+		err = dstCol.EnablePhraseIndex()
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+	if len(statsKeys) > 0 {
+		err = dstCol.EnableMetadataStats(statsKeys...)
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+	if bm25Options != nil && !discardContent {
+		err = dstCol.EnableBM25Index(*bm25Options)
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+	if compressionBlockSize > 0 {
+		err = dstCol.SetContentCompressionWithCodec(compressionBlockSize, compressionCodec)
+		if err != nil {
+			return cleanup(err)
+		}
+	}
+	if proj != nil {
+		// The projection isn't modified after fitting, so it can be shared.
+		dstCol.documentsLock.Lock()
+		dstCol.projection = proj
+		err = dstCol.persistMetadata()
+		dstCol.documentsLock.Unlock()
+		if err != nil {
+			return cleanup(fmt.Errorf("couldn't persist collection metadata: %w", err))
+		}
+	}
+
+	if len(docs) > 0 {
+		err = dstCol.AddDocuments(ctx, docs, runtime.NumCPU())
+		if err != nil {
+			return cleanup(fmt.Errorf("couldn't add documents: %w", err))
+		}
+	}
+	return dstCol, nil
+}
+
+// MergeFrom adds the documents of the other collection to this one, e.g. to
+// consolidate collections per source. The documents are copied with their
+// embeddings, so both collections must use the same embedding model. Documents
+// with an ID that exists in both collections are handled according to the
+// policy. The other collection isn't modified. If this collection is in strict
+// mode (see [Collection.SetStrictMode]), [MergeConflictOverwrite] fails with
+// [ErrDocumentExists] on conflicts.
+//
+// It returns the number of added or replaced documents.
+func (c *Collection) MergeFrom(ctx context.Context, other *Collection, policy MergeConflictPolicy) (int, error) {
+	if other == nil {
+		return 0, errors.New("other collection is nil")
+	}
+	if other == c {
+		return 0, errors.New("can't merge a collection into itself")
+	}
+	switch policy {
+	case MergeConflictError, MergeConflictSkip, MergeConflictOverwrite:
+	default:
+		return 0, fmt.Errorf("unsupported merge conflict policy %d", policy)
+	}
+	model, otherModel := c.EmbeddingModel(), other.EmbeddingModel()
+	if model != "" && otherModel != "" && model != otherModel {
+		return 0, fmt.Errorf("embedding models differ: '%s' and '%s'", model, otherModel)
+	}
+
+	docs, err := other.copyDocuments(nil, nil, Filter{})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't copy documents: %w", err)
+	}
+
+	if policy != MergeConflictOverwrite {
+		c.documentsLock.RLock()
+		if c.closed {
+			c.documentsLock.RUnlock()
+			return 0, ErrClosed
+		}
+		// Deletes are done in place, so docs can be filtered without allocation.
+		var conflict string
+		docs = slices.DeleteFunc(docs, func(doc Document) bool {
+			_, exists := c.documents[doc.ID]
+			if exists && conflict == "" {
+				conflict = doc.ID
+			}
+			return exists
+		})
+		c.documentsLock.RUnlock()
+		if policy == MergeConflictError && conflict != "" {
+			return 0, fmt.Errorf("%w: '%s'", ErrMergeConflict, conflict)
+		}
+	}
+
+	if len(docs) == 0 {
+		return 0, nil
+	}
+	err = c.AddDocuments(ctx, docs, runtime.NumCPU())
+	if err != nil {
+		return 0, fmt.Errorf("couldn't add documents: %w", err)
+	}
+	return len(docs), nil
+}
+
+// copyDocuments returns deep copies of the documents that match the filters,
+// with their readable content, sorted by ID.
+func (c *Collection) copyDocuments(where, whereDocument map[string]string, filter Filter) ([]Document, error) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	docs, err := c.filterWith(c.column.docs(c.documents), where, whereDocument, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		cp, err := c.readableCopy(doc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cp)
+	}
+	slices.SortFunc(res, func(a, b Document) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return res, nil
+}
+
+// readableCopy returns a deep copy of the stored document, with its readable
+// content. Must be called while holding documentsLock.
+func (c *Collection) readableCopy(doc *Document) (Document, error) {
+	doc, err := c.readable(doc)
+	if err != nil {
+		return Document{}, err
+	}
+	cp := *doc
+	cp.Metadata = maps.Clone(doc.Metadata)
+	cp.Embedding = slices.Clone(doc.Embedding)
+	cp.SparseEmbedding = SparseVector{
+		Indices: slices.Clone(doc.SparseEmbedding.Indices),
+		Values:  slices.Clone(doc.SparseEmbedding.Values),
+	}
+	cp.TokenEmbeddings = cloneTokenEmbeddings(doc.TokenEmbeddings)
+	cp.Data = slices.Clone(doc.Data)
+	return cp, nil
+}
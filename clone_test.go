@@ -0,0 +1,146 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDB_CloneCollection(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	src, err := db.CreateCollection("src", map[string]string{"foo": "bar"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = src.SetEmbeddingModel("model")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = src.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"lang": "en"}, Embedding: []float32{1, 0}, Content: "hello"},
+		{ID: "2", Metadata: map[string]string{"lang": "de"}, Embedding: []float32{0, 1}, Content: "hallo"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	dst, err := db.CloneCollection(ctx, "src", "dst", Where().Eq("lang", "de"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if dst.Count() != 1 {
+		t.Fatal("expected 1 document, got", dst.Count())
+	}
+	if !reflect.DeepEqual(dst.Metadata(), map[string]string{"foo": "bar"}) {
+		t.Fatal("expected copied metadata, got", dst.Metadata())
+	}
+	if dst.EmbeddingModel() != "model" {
+		t.Fatal("expected copied embedding model, got", dst.EmbeddingModel())
+	}
+	doc, err := dst.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "hallo" || !reflect.DeepEqual(doc.Embedding, []float32{0, 1}) {
+		t.Fatal("expected copied document, got", doc)
+	}
+
+	// The clone is independent of the source.
+	err = src.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if dst.Count() != 1 {
+		t.Fatal("expected 1 document, got", dst.Count())
+	}
+
+	// The clone is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c := db2.GetCollection("dst", nil); c == nil || c.Count() != 1 {
+		t.Fatal("expected persisted clone with 1 document")
+	}
+
+	_, err = db.CloneCollection(ctx, "src", "dst", Filter{})
+	if err == nil {
+		t.Fatal("expected error for existing collection, got nil")
+	}
+	_, err = db.CloneCollection(ctx, "missing", "other", Filter{})
+	if err == nil {
+		t.Fatal("expected error for missing collection, got nil")
+	}
+}
+
+func TestCollection_MergeFrom(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	newCollection := func(name string, docs ...Document) *Collection {
+		c, err := db.CreateCollection(name, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.AddDocuments(ctx, docs, 1)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return c
+	}
+	other := newCollection("other",
+		Document{ID: "1", Embedding: []float32{1, 0}, Content: "new"},
+		Document{ID: "3", Embedding: []float32{0, 1}, Content: "three"},
+	)
+
+	tt := []struct {
+		policy     MergeConflictPolicy
+		expErr     error
+		expMerged  int
+		expContent string
+	}{
+		{MergeConflictError, ErrMergeConflict, 0, "old"},
+		{MergeConflictSkip, nil, 1, "old"},
+		{MergeConflictOverwrite, nil, 2, "new"},
+	}
+	for _, tc := range tt {
+		c := newCollection("c",
+			Document{ID: "1", Embedding: []float32{1, 0}, Content: "old"},
+			Document{ID: "2", Embedding: []float32{1, 0}, Content: "two"},
+		)
+		merged, err := c.MergeFrom(ctx, other, tc.policy)
+		if !errors.Is(err, tc.expErr) {
+			t.Fatal("expected", tc.expErr, "got", err)
+		}
+		if merged != tc.expMerged {
+			t.Fatal("expected", tc.expMerged, "merged documents, got", merged)
+		}
+		doc, err := c.GetByID(ctx, "1")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.Content != tc.expContent {
+			t.Fatal("expected", tc.expContent, "got", doc.Content)
+		}
+		expCount := 3
+		if tc.expErr != nil {
+			expCount = 2
+		}
+		if c.Count() != expCount {
+			t.Fatal("expected", expCount, "documents, got", c.Count())
+		}
+	}
+	if other.Count() != 2 {
+		t.Fatal("expected other collection to be unchanged, got", other.Count())
+	}
+}
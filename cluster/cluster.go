@@ -0,0 +1,354 @@
+// Package cluster provides an optional high availability mode for chromem-go,
+// based on the Raft consensus algorithm (github.com/hashicorp/raft).
+//
+// Mutations are written via the leader, replicated to all nodes and applied
+// to each node's local [chromem.DB]. Documents are embedded once on the leader,
+// so the followers don't call the embedding API again. Queries via [Node.Query]
+// are consistent reads from the leader. Each node's [Node.DB] can also be queried
+// directly for reads that may be slightly stale.
+//
+// It's a separate Go module, so that the main chromem-go module stays free of
+// third-party dependencies.
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"github.com/philippgille/chromem-go"
+)
+
+// ErrNotLeader is returned when a mutation or consistent read is sent to a node
+// that's not the leader. Use [Node.Leader] to find the leader.
+var ErrNotLeader = errors.New("node is not the leader")
+
+// Config configures a [Node].
+type Config struct {
+	// ID is the unique ID of the node in the cluster. Required.
+	ID string
+	// Dir is the directory for the Raft log, stable store and snapshots.
+	// Required.
+	Dir string
+	// BindAddr is the TCP address for the Raft communication, e.g. "10.0.0.1:7000".
+	// Required unless Transport is set.
+	BindAddr string
+	// Bootstrap bootstraps a new cluster with this node as the only voter.
+	// Set it on one node only, the others join via [Node.Join] on the leader.
+	Bootstrap bool
+	// EmbeddingFunc is the embedding function for all collections. It must be
+	// the same on all nodes. Required.
+	EmbeddingFunc chromem.EmbeddingFunc
+
+	// Raft is an optional Raft configuration. If nil, [raft.DefaultConfig] is used.
+	// LocalID is always overwritten with ID.
+	Raft *raft.Config
+	// Transport is an optional Raft transport. If nil, a TCP transport on BindAddr
+	// is used.
+	Transport raft.Transport
+	// LogStore and StableStore are optional. If nil, a BoltDB in Dir is used.
+	LogStore    raft.LogStore
+	StableStore raft.StableStore
+}
+
+// Node is a member of a chromem-go cluster.
+type Node struct {
+	raft  *raft.Raft
+	fsm   *fsm
+	close func() error
+}
+
+// NewNode creates and starts a cluster node. Its DB is in-memory, as its state
+// is restored from the Raft snapshots and log on restart.
+func NewNode(config Config) (*Node, error) {
+	if config.ID == "" {
+		return nil, errors.New("ID is empty")
+	}
+	if config.Dir == "" {
+		return nil, errors.New("dir is empty")
+	}
+	if config.EmbeddingFunc == nil {
+		return nil, errors.New("embedding func is nil")
+	}
+	if config.BindAddr == "" && config.Transport == nil {
+		return nil, errors.New("bind address is empty")
+	}
+
+	err := os.MkdirAll(config.Dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create directory: %w", err)
+	}
+
+	raftConfig := raft.DefaultConfig()
+	if config.Raft != nil {
+		c := *config.Raft
+		raftConfig = &c
+	}
+	raftConfig.LocalID = raft.ServerID(config.ID)
+
+	var closers []func() error
+	closeAll := func() error {
+		var errs []error
+		for _, c := range closers {
+			errs = append(errs, c())
+		}
+		return errors.Join(errs...)
+	}
+
+	transport := config.Transport
+	if transport == nil {
+		addr, err := net.ResolveTCPAddr("tcp", config.BindAddr)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't resolve bind address: %w", err)
+		}
+		tcpTransport, err := raft.NewTCPTransport(config.BindAddr, addr, 3, 10*time.Second, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create transport: %w", err)
+		}
+		closers = append(closers, tcpTransport.Close)
+		transport = tcpTransport
+	}
+
+	logStore, stableStore := config.LogStore, config.StableStore
+	if logStore == nil || stableStore == nil {
+		boltStore, err := raftboltdb.NewBoltStore(filepath.Join(config.Dir, "raft.db"))
+		if err != nil {
+			_ = closeAll()
+			return nil, fmt.Errorf("couldn't create bolt store: %w", err)
+		}
+		closers = append(closers, boltStore.Close)
+		if logStore == nil {
+			logStore = boltStore
+		}
+		if stableStore == nil {
+			stableStore = boltStore
+		}
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(config.Dir, 2, os.Stderr)
+	if err != nil {
+		_ = closeAll()
+		return nil, fmt.Errorf("couldn't create snapshot store: %w", err)
+	}
+
+	f := &fsm{
+		db:    chromem.NewDB(),
+		embed: config.EmbeddingFunc,
+	}
+	r, err := raft.NewRaft(raftConfig, f, logStore, stableStore, snapshots, transport)
+	if err != nil {
+		_ = closeAll()
+		return nil, fmt.Errorf("couldn't create raft: %w", err)
+	}
+
+	if config.Bootstrap {
+		hasState, err := raft.HasExistingState(logStore, stableStore, snapshots)
+		if err != nil {
+			_ = r.Shutdown().Error()
+			_ = closeAll()
+			return nil, fmt.Errorf("couldn't check existing state: %w", err)
+		}
+		if !hasState {
+			err = r.BootstrapCluster(raft.Configuration{
+				Servers: []raft.Server{{ID: raftConfig.LocalID, Address: transport.LocalAddr()}},
+			}).Error()
+			if err != nil {
+				_ = r.Shutdown().Error()
+				_ = closeAll()
+				return nil, fmt.Errorf("couldn't bootstrap cluster: %w", err)
+			}
+		}
+	}
+
+	return &Node{
+		raft:  r,
+		fsm:   f,
+		close: closeAll,
+	}, nil
+}
+
+// DB returns the node's local DB. Use it for reads that may be slightly stale,
+// but don't write to it directly, as the changes aren't replicated.
+func (n *Node) DB() *chromem.DB {
+	return n.fsm.db
+}
+
+// IsLeader returns whether the node is the current leader.
+func (n *Node) IsLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// Leader returns the Raft address and ID of the current leader. They're empty
+// if there's no leader at the moment.
+func (n *Node) Leader() (addr, id string) {
+	a, i := n.raft.LeaderWithID()
+	return string(a), string(i)
+}
+
+// Join adds a node with the given ID and Raft address as voter to the cluster.
+// It must be called on the leader.
+func (n *Node) Join(ctx context.Context, id, addr string) error {
+	if !n.IsLeader() {
+		return ErrNotLeader
+	}
+	err := n.raft.AddVoter(raft.ServerID(id), raft.ServerAddress(addr), 0, timeout(ctx)).Error()
+	if err != nil {
+		return fmt.Errorf("couldn't add voter: %w", err)
+	}
+	return nil
+}
+
+// Leave removes the node with the given ID from the cluster.
+// It must be called on the leader.
+func (n *Node) Leave(ctx context.Context, id string) error {
+	if !n.IsLeader() {
+		return ErrNotLeader
+	}
+	err := n.raft.RemoveServer(raft.ServerID(id), 0, timeout(ctx)).Error()
+	if err != nil {
+		return fmt.Errorf("couldn't remove server: %w", err)
+	}
+	return nil
+}
+
+// CreateCollection creates a collection on all nodes, unless it already exists.
+// It must be called on the leader.
+func (n *Node) CreateCollection(ctx context.Context, name string, metadata map[string]string) error {
+	if name == "" {
+		return errors.New("collection name is empty")
+	}
+	return n.apply(ctx, command{
+		Op:         opCreateCollection,
+		Collection: name,
+		Metadata:   metadata,
+	})
+}
+
+// DeleteCollection deletes a collection on all nodes.
+// It must be called on the leader.
+func (n *Node) DeleteCollection(ctx context.Context, name string) error {
+	return n.apply(ctx, command{
+		Op:         opDeleteCollection,
+		Collection: name,
+	})
+}
+
+// AddDocuments adds documents to a collection on all nodes. Documents without
+// embedding are embedded on the leader. It must be called on the leader.
+func (n *Node) AddDocuments(ctx context.Context, collection string, documents []chromem.Document) error {
+	if !n.IsLeader() {
+		return ErrNotLeader
+	}
+	if n.fsm.db.GetCollection(collection, n.fsm.embed) == nil {
+		return fmt.Errorf("collection %q doesn't exist", collection)
+	}
+
+	docs := make([]chromem.Document, len(documents))
+	for i, doc := range documents {
+		if doc.ID == "" {
+			return errors.New("document ID is empty")
+		}
+		if len(doc.Embedding) == 0 {
+			if doc.Content == "" {
+				return errors.New("either document embedding or content must be filled")
+			}
+			embedding, err := n.fsm.embed(ctx, doc.Content)
+			if err != nil {
+				return fmt.Errorf("couldn't create embedding of document: %w", err)
+			}
+			doc.Embedding = embedding
+		}
+		docs[i] = doc
+	}
+
+	return n.apply(ctx, command{
+		Op:         opAddDocuments,
+		Collection: collection,
+		Documents:  docs,
+	})
+}
+
+// Delete deletes documents from a collection on all nodes, with the same
+// semantics as [chromem.Collection.Delete]. It must be called on the leader.
+func (n *Node) Delete(ctx context.Context, collection string, where, whereDocument map[string]string, ids ...string) error {
+	return n.apply(ctx, command{
+		Op:            opDelete,
+		Collection:    collection,
+		Where:         where,
+		WhereDocument: whereDocument,
+		IDs:           ids,
+	})
+}
+
+// Query performs a consistent query on the leader, with the same semantics as
+// [chromem.Collection.Query]. It returns [ErrNotLeader] on followers, or when
+// the node lost its leadership.
+func (n *Node) Query(ctx context.Context, collection, queryText string, nResults int, where, whereDocument map[string]string) ([]chromem.Result, error) {
+	if !n.IsLeader() {
+		return nil, ErrNotLeader
+	}
+	// Make sure we're still the leader, so we don't miss any writes of a new one.
+	err := n.raft.VerifyLeader().Error()
+	if err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return nil, ErrNotLeader
+		}
+		return nil, fmt.Errorf("couldn't verify leadership: %w", err)
+	}
+	// Wait for all committed entries to be applied to the local DB.
+	err = n.raft.Barrier(timeout(ctx)).Error()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't wait for log to be applied: %w", err)
+	}
+
+	c := n.fsm.db.GetCollection(collection, n.fsm.embed)
+	if c == nil {
+		return nil, fmt.Errorf("collection %q doesn't exist", collection)
+	}
+	return c.Query(ctx, queryText, nResults, where, whereDocument)
+}
+
+// Shutdown stops the node. It doesn't remove it from the cluster, use
+// [Node.Leave] on the leader for that.
+func (n *Node) Shutdown() error {
+	err := n.raft.Shutdown().Error()
+	return errors.Join(err, n.close())
+}
+
+func (n *Node) apply(ctx context.Context, cmd command) error {
+	if !n.IsLeader() {
+		return ErrNotLeader
+	}
+	b, err := cmd.encode()
+	if err != nil {
+		return fmt.Errorf("couldn't encode command: %w", err)
+	}
+	future := n.raft.Apply(b, timeout(ctx))
+	err = future.Error()
+	if err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return ErrNotLeader
+		}
+		return fmt.Errorf("couldn't apply command: %w", err)
+	}
+	if err, ok := future.Response().(error); ok && err != nil {
+		return err
+	}
+	return nil
+}
+
+// timeout returns the time until the context's deadline, or 0 (no timeout) if
+// there's none.
+func timeout(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+	return max(time.Until(deadline), time.Millisecond)
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/philippgille/chromem-go"
+)
+
+func TestCluster(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if text == "a" {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}
+
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Three nodes with connected in-memory transports and stores
+	ids := []string{"node1", "node2", "node3"}
+	transports := make([]*raft.InmemTransport, len(ids))
+	for i := range ids {
+		_, transports[i] = raft.NewInmemTransport(raft.ServerAddress(ids[i]))
+	}
+	for i := range transports {
+		for j := range transports {
+			if i != j {
+				transports[i].Connect(transports[j].LocalAddr(), transports[j])
+			}
+		}
+	}
+	nodes := make([]*Node, len(ids))
+	for i, id := range ids {
+		raftConfig := raft.DefaultConfig()
+		raftConfig.HeartbeatTimeout = 50 * time.Millisecond
+		raftConfig.ElectionTimeout = 50 * time.Millisecond
+		raftConfig.LeaderLeaseTimeout = 50 * time.Millisecond
+		raftConfig.CommitTimeout = 5 * time.Millisecond
+		raftConfig.LogOutput = io.Discard
+		store := raft.NewInmemStore()
+		nodes[i], err = NewNode(Config{
+			ID:            id,
+			Dir:           dir + "/" + id,
+			Bootstrap:     i == 0,
+			EmbeddingFunc: embeddingFunc,
+			Raft:          raftConfig,
+			Transport:     transports[i],
+			LogStore:      store,
+			StableStore:   store,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		defer nodes[i].Shutdown()
+	}
+
+	leader := nodes[0]
+	waitFor(t, leader.IsLeader)
+	for i := 1; i < len(nodes); i++ {
+		err = leader.Join(ctx, ids[i], ids[i])
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	// Mutations via the leader
+	err = leader.CreateCollection(ctx, "test", map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = leader.AddDocuments(ctx, "test", []chromem.Document{
+		{ID: "1", Content: "a"},
+		{ID: "2", Content: "b"},
+		{ID: "3", Content: "c"},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = leader.Delete(ctx, "test", nil, nil, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Consistent read from the leader
+	res, err := leader.Query(ctx, "test", "a", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+
+	// Followers
+	for _, n := range nodes[1:] {
+		_, err = n.Query(ctx, "test", "a", 1, nil, nil)
+		if !errors.Is(err, ErrNotLeader) {
+			t.Fatal("expected ErrNotLeader, got", err)
+		}
+		err = n.AddDocuments(ctx, "test", []chromem.Document{{ID: "4", Content: "d"}})
+		if !errors.Is(err, ErrNotLeader) {
+			t.Fatal("expected ErrNotLeader, got", err)
+		}
+		waitFor(t, func() bool {
+			c := n.DB().GetCollection("test", nil)
+			return c != nil && c.Count() == 2
+		})
+	}
+
+	// Snapshot and restore
+	future := leader.raft.Snapshot()
+	err = future.Error()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, rc, err := future.Open()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	f := &fsm{db: chromem.NewDB(), embed: embeddingFunc}
+	err = f.Restore(rc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c := f.db.GetCollection("test", embeddingFunc)
+	if c == nil || c.Count() != 2 {
+		t.Fatal("expected restored collection with 2 documents")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+	"github.com/philippgille/chromem-go"
+)
+
+const (
+	opCreateCollection = "create_collection"
+	opDeleteCollection = "delete_collection"
+	opAddDocuments     = "add_documents"
+	opDelete           = "delete"
+)
+
+// command is a mutation in the Raft log.
+type command struct {
+	Op            string             `json:"op"`
+	Collection    string             `json:"collection"`
+	Metadata      map[string]string  `json:"metadata,omitempty"`
+	Documents     []chromem.Document `json:"documents,omitempty"`
+	Where         map[string]string  `json:"where,omitempty"`
+	WhereDocument map[string]string  `json:"where_document,omitempty"`
+	IDs           []string           `json:"ids,omitempty"`
+}
+
+func (c command) encode() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// fsm applies the Raft log to a DB.
+type fsm struct {
+	db    *chromem.DB
+	embed chromem.EmbeddingFunc
+}
+
+var _ raft.FSM = (*fsm)(nil)
+
+// Apply implements [raft.FSM]. The returned value is nil or an error.
+func (f *fsm) Apply(l *raft.Log) any {
+	var cmd command
+	err := json.Unmarshal(l.Data, &cmd)
+	if err != nil {
+		return fmt.Errorf("couldn't decode command: %w", err)
+	}
+
+	// Commands are applied asynchronously on followers, so there's no request
+	// context to use.
+	ctx := context.Background()
+	switch cmd.Op {
+	case opCreateCollection:
+		_, err = f.db.GetOrCreateCollection(cmd.Collection, cmd.Metadata, f.embed)
+	case opDeleteCollection:
+		err = f.db.DeleteCollection(cmd.Collection)
+	case opAddDocuments:
+		c := f.db.GetCollection(cmd.Collection, f.embed)
+		if c == nil {
+			return fmt.Errorf("collection %q doesn't exist", cmd.Collection)
+		}
+		err = c.AddDocuments(ctx, cmd.Documents, 1)
+	case opDelete:
+		c := f.db.GetCollection(cmd.Collection, f.embed)
+		if c == nil {
+			return fmt.Errorf("collection %q doesn't exist", cmd.Collection)
+		}
+		err = c.Delete(ctx, cmd.Where, cmd.WhereDocument, cmd.IDs...)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd.Op)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Snapshot implements [raft.FSM]. It's not called concurrently with Apply, so
+// the export is consistent.
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	buf := &bytes.Buffer{}
+	err := f.db.ExportToWriter(buf, true, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't export DB: %w", err)
+	}
+	return &snapshot{data: buf.Bytes()}, nil
+}
+
+// Restore implements [raft.FSM].
+func (f *fsm) Restore(r io.ReadCloser) error {
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("couldn't read snapshot: %w", err)
+	}
+	err = f.db.Reset()
+	if err != nil {
+		return fmt.Errorf("couldn't reset DB: %w", err)
+	}
+	err = f.db.ImportFromReader(bytes.NewReader(b), "")
+	if err != nil {
+		return fmt.Errorf("couldn't import snapshot: %w", err)
+	}
+	return nil
+}
+
+type snapshot struct {
+	data []byte
+}
+
+var _ raft.FSMSnapshot = (*snapshot)(nil)
+
+// Persist implements [raft.FSMSnapshot].
+func (s *snapshot) Persist(sink raft.SnapshotSink) error {
+	_, err := sink.Write(s.data)
+	if err != nil {
+		_ = sink.Cancel()
+		return fmt.Errorf("couldn't write snapshot: %w", err)
+	}
+	return sink.Close()
+}
+
+// Release implements [raft.FSMSnapshot].
+func (s *snapshot) Release() {}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/philippgille/chromem-go/bench"
+)
+
+func runBench(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	docs := fs.Int("docs", 10000, "Number of documents")
+	dims := fs.Int("dims", 384, "Number of embedding dimensions")
+	queries := fs.Int("queries", 100, "Number of queries per mode")
+	k := fs.Int("k", 10, "Number of results per query")
+	seed := fs.Int64("seed", 0, "Seed of the random corpus")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	report, err := bench.Run(ctx, bench.Options{
+		Documents:  *docs,
+		Dimensions: *dims,
+		Queries:    *queries,
+		K:          *k,
+		Seed:       *seed,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't run benchmark: %w", err)
+	}
+
+	fmt.Fprintf(stdout, "Added %d documents with %d dimensions in %s (%.0f docs/s)\n\n", report.Documents, report.Dimensions, report.AddDuration, report.AddThroughput)
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODE\tSETUP\tQUERIES/S\tLATENCY\tRECALL@K\tMEMORY")
+	for _, m := range report.Modes {
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%s\t%.3f\t%.1f MiB\n", m.Mode, m.SetupDuration.Round(time.Microsecond), m.QueryThroughput, m.MeanLatency.Round(time.Microsecond), m.Recall, float64(m.Memory)/(1<<20))
+	}
+	return tw.Flush()
+}
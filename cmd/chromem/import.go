@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// jsonDocument is the JSON representation of a document for the import, with
+// the same field names as [chromem.Result].
+type jsonDocument struct {
+	ID        string            `json:"id"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Embedding []float32         `json:"embedding,omitempty"`
+	Content   string            `json:"content,omitempty"`
+}
+
+func detectFormat(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't stat path: %w", err)
+	}
+	if info.IsDir() {
+		return "dir", nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return "jsonl", nil
+	case ".csv":
+		return "csv", nil
+	}
+	return "", errors.New("couldn't detect format from path, set it explicitly")
+}
+
+func readJSONLFile(path string) ([]chromem.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open file: %w", err)
+	}
+	defer f.Close()
+	return readJSONL(f)
+}
+
+func readJSONL(r io.Reader) ([]chromem.Document, error) {
+	var docs []chromem.Document
+	scanner := bufio.NewScanner(r)
+	// Allow long lines, e.g. with large embeddings.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var jd jsonDocument
+		err := json.Unmarshal(scanner.Bytes(), &jd)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse line %d: %w", line, err)
+		}
+		docs = append(docs, chromem.Document{
+			ID:        jd.ID,
+			Metadata:  jd.Metadata,
+			Embedding: jd.Embedding,
+			Content:   jd.Content,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read lines: %w", err)
+	}
+	return docs, nil
+}
+
+func readCSVFile(path string) ([]chromem.Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open file: %w", err)
+	}
+	defer f.Close()
+	return readCSV(f)
+}
+
+// readCSV reads documents from CSV with a header row. The columns "id",
+// "content" and "embedding" (as JSON array) are mapped to the document fields,
+// all other columns to metadata.
+func readCSV(r io.Reader) ([]chromem.Document, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read header: %w", err)
+	}
+	if !slices.Contains(header, "id") {
+		return nil, errors.New(`header has no "id" column`)
+	}
+
+	var docs []chromem.Document
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't read record: %w", err)
+		}
+		var doc chromem.Document
+		for i, col := range header {
+			switch col {
+			case "id":
+				doc.ID = record[i]
+			case "content":
+				doc.Content = record[i]
+			case "embedding":
+				if record[i] == "" {
+					continue
+				}
+				err = json.Unmarshal([]byte(record[i]), &doc.Embedding)
+				if err != nil {
+					return nil, fmt.Errorf("couldn't parse embedding of document %q: %w", doc.ID, err)
+				}
+			default:
+				if doc.Metadata == nil {
+					doc.Metadata = make(map[string]string)
+				}
+				doc.Metadata[col] = record[i]
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// readDir reads all regular, non-hidden files in the directory tree as documents,
+// with the slash-separated path relative to the directory as ID and as "path"
+// metadata.
+func readDir(dir string) ([]chromem.Document, error) {
+	var docs []chromem.Document
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if len(strings.TrimSpace(string(b))) == 0 {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		docs = append(docs, chromem.Document{
+			ID:       rel,
+			Metadata: map[string]string{"path": rel},
+			Content:  string(b),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read directory: %w", err)
+	}
+	return docs, nil
+}
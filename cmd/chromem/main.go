@@ -0,0 +1,391 @@
+// Command chromem is a CLI for operational tasks on a persistent chromem-go DB,
+// like creating and listing collections, importing documents from JSONL, CSV or
+// directories, running queries, exporting backups and verifying the persistence
+// directory. It can also benchmark chromem-go on the current hardware.
+//
+// Usage:
+//
+//	chromem [global flags] <command> [flags] [args]
+//
+// Run "chromem -h" for the list of commands and flags.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/philippgille/chromem-go"
+)
+
+const usage = `Usage: chromem [global flags] <command> [flags] [args]
+
+Commands:
+  list                             List collections
+  create [-metadata k=v,...] <name>
+                                   Create a collection
+  delete <name>                    Delete a collection
+  import -collection <name> [-format jsonl|csv|dir] [-concurrency n] <path>
+                                   Import documents. JSONL lines and CSV columns
+                                   are "id", "content", "embedding" (JSON array)
+                                   and "metadata" (JSON object, JSONL only). Other
+                                   CSV columns become metadata. With "dir", each
+                                   file becomes a document with its relative path
+                                   as ID.
+  query -collection <name> [-n 10] [-where json] [-format text|json|csv] <text>
+                                   Query a collection
+  export [-compress] [-key key] <file>
+                                   Export the DB to a backup file
+  verify                           Load all collections and check their documents
+  bench [-docs n] [-dims n] [-queries n] [-k n] [-seed n]
+                                   Benchmark adding and querying a synthetic
+                                   corpus with exact, IVF and Matryoshka search.
+                                   Doesn't use the DB or the embedding provider.
+
+The API key for the embedding provider is read from the CHROMEM_API_KEY
+environment variable, or OPENAI_API_KEY for the "openai" provider.
+
+Global flags:
+`
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("chromem", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	dbPath := fs.String("db", "./chromem-go", "Path of the persistent DB")
+	compress := fs.Bool("compress", false, "Whether the DB files are gzip-compressed")
+	provider := fs.String("provider", "openai", `Embedding provider: "openai", "ollama" or "openai-compat"`)
+	model := fs.String("model", "", "Embedding model. Optional for the \"openai\" provider")
+	baseURL := fs.String("base-url", "", `Base URL of the embedding API. Optional for "openai" and "ollama"`)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("command is missing")
+	}
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	if cmd == "bench" {
+		return runBench(ctx, cmdArgs, stdout, stderr)
+	}
+
+	embeddingFunc, err := newEmbeddingFunc(*provider, *model, *baseURL)
+	if err != nil {
+		return err
+	}
+
+	db, err := chromem.NewPersistentDB(*dbPath, *compress)
+	if err != nil {
+		return fmt.Errorf("couldn't open DB: %w", err)
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "list":
+		return runList(db, stdout)
+	case "create":
+		return runCreate(db, embeddingFunc, cmdArgs, stdout, stderr)
+	case "delete":
+		return runDelete(db, cmdArgs, stdout)
+	case "import":
+		return runImport(ctx, db, embeddingFunc, cmdArgs, stdout, stderr)
+	case "query":
+		return runQuery(ctx, db, embeddingFunc, cmdArgs, stdout, stderr)
+	case "export":
+		return runExport(db, cmdArgs, stdout, stderr)
+	case "verify":
+		return runVerify(db, stdout)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+func newEmbeddingFunc(provider, model, baseURL string) (chromem.EmbeddingFunc, error) {
+	apiKey := os.Getenv("CHROMEM_API_KEY")
+	switch provider {
+	case "openai":
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		if model == "" {
+			model = string(chromem.EmbeddingModelOpenAI3Small)
+		}
+		var opts []chromem.EmbeddingFuncOption
+		if baseURL != "" {
+			opts = append(opts, chromem.WithBaseURL(baseURL))
+		}
+		return chromem.NewEmbeddingFuncOpenAI(apiKey, chromem.EmbeddingModelOpenAI(model), opts...), nil
+	case "ollama":
+		if model == "" {
+			return nil, errors.New("model is required for the ollama provider")
+		}
+		return chromem.NewEmbeddingFuncOllama(model, baseURL), nil
+	case "openai-compat":
+		if model == "" || baseURL == "" {
+			return nil, errors.New("model and base URL are required for the openai-compat provider")
+		}
+		return chromem.NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", provider)
+	}
+}
+
+func runList(db *chromem.DB, stdout io.Writer) error {
+	collections := db.ListCollections()
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(stdout, "%s\t%d\n", name, collections[name].Count())
+	}
+	return nil
+}
+
+func runCreate(db *chromem.DB, embeddingFunc chromem.EmbeddingFunc, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	metadataFlag := fs.String("metadata", "", "Collection metadata as comma-separated key=value pairs")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one collection name")
+	}
+	name := fs.Arg(0)
+
+	var metadata map[string]string
+	if *metadataFlag != "" {
+		metadata = make(map[string]string)
+		for _, pair := range strings.Split(*metadataFlag, ",") {
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid metadata pair %q, expected key=value", pair)
+			}
+			metadata[k] = v
+		}
+	}
+
+	if db.GetCollection(name, embeddingFunc) != nil {
+		return fmt.Errorf("collection %q already exists", name)
+	}
+	_, err = db.CreateCollection(name, metadata, embeddingFunc)
+	if err != nil {
+		return fmt.Errorf("couldn't create collection: %w", err)
+	}
+	fmt.Fprintf(stdout, "Created collection %q\n", name)
+	return nil
+}
+
+func runDelete(db *chromem.DB, args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("expected exactly one collection name")
+	}
+	name := args[0]
+	if db.GetCollection(name, nil) == nil {
+		return fmt.Errorf("collection %q doesn't exist", name)
+	}
+	err := db.DeleteCollection(name)
+	if err != nil {
+		return fmt.Errorf("couldn't delete collection: %w", err)
+	}
+	fmt.Fprintf(stdout, "Deleted collection %q\n", name)
+	return nil
+}
+
+func runImport(ctx context.Context, db *chromem.DB, embeddingFunc chromem.EmbeddingFunc, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	collection := fs.String("collection", "", "Name of the collection. It's created if it doesn't exist")
+	format := fs.String("format", "", `Format: "jsonl", "csv" or "dir". Default: Based on the path`)
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "Number of concurrent embedding requests")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *collection == "" {
+		return errors.New("collection is required")
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one path")
+	}
+	path := fs.Arg(0)
+
+	if *format == "" {
+		*format, err = detectFormat(path)
+		if err != nil {
+			return err
+		}
+	}
+	var docs []chromem.Document
+	switch *format {
+	case "jsonl":
+		docs, err = readJSONLFile(path)
+	case "csv":
+		docs, err = readCSVFile(path)
+	case "dir":
+		docs, err = readDir(path)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return errors.New("no documents found")
+	}
+
+	c, err := db.GetOrCreateCollection(*collection, nil, embeddingFunc)
+	if err != nil {
+		return fmt.Errorf("couldn't get or create collection: %w", err)
+	}
+	err = c.AddDocuments(ctx, docs, *concurrency)
+	if err != nil {
+		return fmt.Errorf("couldn't add documents: %w", err)
+	}
+	fmt.Fprintf(stdout, "Imported %d documents into collection %q\n", len(docs), *collection)
+	return nil
+}
+
+func runQuery(ctx context.Context, db *chromem.DB, embeddingFunc chromem.EmbeddingFunc, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	collection := fs.String("collection", "", "Name of the collection")
+	n := fs.Int("n", 10, "Number of results")
+	whereFlag := fs.String("where", "", `Metadata filter as JSON object, e.g. {"category": "news"}`)
+	format := fs.String("format", "text", `Output format: "text", "json" or "csv"`)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *collection == "" {
+		return errors.New("collection is required")
+	}
+	if fs.NArg() == 0 {
+		return errors.New("query text is missing")
+	}
+	queryText := strings.Join(fs.Args(), " ")
+
+	var where map[string]string
+	if *whereFlag != "" {
+		err = json.Unmarshal([]byte(*whereFlag), &where)
+		if err != nil {
+			return fmt.Errorf("couldn't parse where filter: %w", err)
+		}
+	}
+
+	c := db.GetCollection(*collection, embeddingFunc)
+	if c == nil {
+		return fmt.Errorf("collection %q doesn't exist", *collection)
+	}
+	res, err := c.Query(ctx, queryText, min(*n, c.Count()), where, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't query collection: %w", err)
+	}
+
+	switch *format {
+	case "text":
+		for _, r := range res {
+			fmt.Fprintf(stdout, "%.4f\t%s\t%s\n", r.Similarity, r.ID, strings.ReplaceAll(r.Content, "\n", " "))
+		}
+		return nil
+	case "json":
+		return chromem.WriteResultsJSON(stdout, res)
+	case "csv":
+		return chromem.WriteResultsCSV(stdout, res)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func runExport(db *chromem.DB, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	compress := fs.Bool("compress", false, "Compress the backup with gzip")
+	key := fs.String("key", "", "Optional encryption key, must be 32 bytes long")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one file path")
+	}
+
+	err = db.ExportToFile(fs.Arg(0), *compress, *key)
+	if err != nil {
+		return fmt.Errorf("couldn't export DB: %w", err)
+	}
+	fmt.Fprintf(stdout, "Exported %d collections to %s\n", len(db.ListCollections()), fs.Arg(0))
+	return nil
+}
+
+// runVerify checks that all collections could be loaded (which already happened
+// when opening the DB), and that all documents have an embedding with the same
+// number of dimensions within a collection.
+func runVerify(db *chromem.DB, stdout io.Writer) error {
+	collections := db.ListCollections()
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var errs []error
+	for _, name := range names {
+		c := collections[name]
+		dims := 0
+		var problems []string
+		for _, id := range c.ListIDs() {
+			doc, err := c.GetByID(context.Background(), id)
+			if err != nil {
+				// Deleted in the meantime
+				continue
+			}
+			switch {
+			case len(doc.Embedding) == 0:
+				problems = append(problems, fmt.Sprintf("document %q has no embedding", id))
+			case dims == 0:
+				dims = len(doc.Embedding)
+			case len(doc.Embedding) != dims:
+				problems = append(problems, fmt.Sprintf("document %q has %d dimensions instead of %d", id, len(doc.Embedding), dims))
+			}
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintf(stdout, "%s: %s\n", name, p)
+			}
+			errs = append(errs, fmt.Errorf("collection %q has %d problems", name, len(problems)))
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: OK, %d documents, %d dimensions\n", name, c.Count(), dims)
+	}
+	return errors.Join(errs...)
+}
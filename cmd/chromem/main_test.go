@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	// OpenAI compatible embedding API
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		embedding := []float32{0, 1}
+		if strings.Contains(req.Input, "sky") {
+			embedding = []float32{1, 0}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": []map[string]any{{"embedding": embedding}},
+		})
+	}))
+	defer ts.Close()
+
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	jsonlPath := filepath.Join(dir, "docs.jsonl")
+	err = os.WriteFile(jsonlPath, []byte(`{"id":"1","content":"The sky is blue","metadata":{"category":"nature"}}
+{"id":"2","content":"Go is a programming language","embedding":[0,1]}
+`), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	csvPath := filepath.Join(dir, "docs.csv")
+	err = os.WriteFile(csvPath, []byte("id,content,category\n3,Clouds in the sky,nature\n"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	textDir := filepath.Join(dir, "texts")
+	err = os.MkdirAll(filepath.Join(textDir, "sub"), 0o700)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = os.WriteFile(filepath.Join(textDir, "sub", "a.txt"), []byte("Rust is a language"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = os.WriteFile(filepath.Join(textDir, ".hidden"), []byte("ignored"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	global := []string{
+		"-db", filepath.Join(dir, "db"),
+		"-provider", "openai-compat",
+		"-base-url", ts.URL,
+		"-model", "test",
+	}
+	tt := []struct {
+		name     string
+		args     []string
+		contains string
+		wantErr  bool
+	}{
+		{"Create", []string{"create", "-metadata", "a=b", "test"}, `Created collection "test"`, false},
+		{"Create existing", []string{"create", "test"}, "", true},
+		{"Import JSONL", []string{"import", "-collection", "test", jsonlPath}, "Imported 2 documents", false},
+		{"Import CSV", []string{"import", "-collection", "test", csvPath}, "Imported 1 documents", false},
+		{"Import dir", []string{"import", "-collection", "other", textDir}, "Imported 1 documents", false},
+		{"List", []string{"list"}, "other\t1\ntest\t3\n", false},
+		{"Query", []string{"query", "-collection", "test", "-n", "5", "-where", `{"category":"nature"}`, "blue", "sky"}, "1.0000\t3\tClouds in the sky\n", false},
+		{"Query JSON", []string{"query", "-collection", "other", "-format", "json", "language"}, `"id":"sub/a.txt"`, false},
+		{"Verify", []string{"verify"}, "test: OK, 3 documents, 2 dimensions", false},
+		{"Export", []string{"export", "-compress", filepath.Join(dir, "backup.gob.gz")}, "Exported 2 collections", false},
+		{"Delete", []string{"delete", "other"}, `Deleted collection "other"`, false},
+		{"Bench", []string{"bench", "-docs", "200", "-dims", "8", "-queries", "5"}, "Added 200 documents with 8 dimensions", false},
+		{"Unknown command", []string{"foo"}, "", true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err := run(ctx, append(global, tc.args...), stdout, stderr)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("expected no error, got", err, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.contains) {
+				t.Fatal("expected output to contain", tc.contains, "got", stdout.String())
+			}
+		})
+	}
+}
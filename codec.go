@@ -0,0 +1,117 @@
+package chromem
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Codec compresses the blocks of contents of the in-memory content compression,
+// see [Collection.SetContentCompressionWithCodec]. The built-in one is
+// [CodecDeflate]. zstd is available via the separate module
+// github.com/philippgille/chromem-go/zstd, so that this one stays free of
+// third-party dependencies. Other codecs can be plugged in by implementing this
+// interface and registering them via [RegisterCodec].
+//
+// A codec must be safe for concurrent use.
+type Codec interface {
+	// Name identifies the codec, e.g. "zstd". Collections persist it, so it
+	// must not change.
+	Name() string
+	// Compress appends the compressed src to dst and returns the result.
+	Compress(dst, src []byte) ([]byte, error)
+	// Decompress appends the decompressed src to dst and returns the result.
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// CodecDeflate is the name of the built-in DEFLATE codec, which is part of Go's
+// standard library. It's the default of the content compression.
+const CodecDeflate = "deflate"
+
+var (
+	codecsLock sync.RWMutex
+	codecs     = map[string]Codec{
+		CodecDeflate: deflateCodec{},
+	}
+)
+
+// RegisterCodec registers a codec for the content compression by its name, so
+// that collections can use it via [Collection.SetContentCompressionWithCodec].
+// Collections only persist the name, so the codec must be registered again
+// before loading a persistent DB that uses it, e.g. in an init function. Names
+// can't be registered twice.
+func RegisterCodec(codec Codec) error {
+	if codec == nil {
+		return errors.New("codec is nil")
+	}
+	name := codec.Name()
+	if name == "" {
+		return errors.New("codec name is empty")
+	}
+
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	if _, ok := codecs[name]; ok {
+		return fmt.Errorf("codec '%s' is already registered", name)
+	}
+	codecs[name] = codec
+	return nil
+}
+
+// lookupCodec returns the codec registered by name, or the DEFLATE codec if the
+// name is empty.
+func lookupCodec(name string) (Codec, error) {
+	if name == "" {
+		name = CodecDeflate
+	}
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("codec '%s' isn't registered", name)
+	}
+	return codec, nil
+}
+
+type deflateCodec struct{}
+
+// Reused for compressing blocks
+var flateWriterPool = sync.Pool{
+	New: func() any {
+		// Only fails for an invalid level
+		w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return w
+	},
+}
+
+func (deflateCodec) Name() string { return CodecDeflate }
+
+func (deflateCodec) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(w)
+	w.Reset(buf)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (deflateCodec) Decompress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+	_, err := io.Copy(buf, r)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
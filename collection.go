@@ -2,26 +2,184 @@ package chromem
 
 import (
 	"context"
+	"crypto/cipher"
 	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"slices"
 	"sync"
+	"time"
 )
 
 // Collection represents a collection of documents.
 // It also has a configured embedding function, which is used when adding documents
 // that don't have embeddings yet.
 type Collection struct {
+	// Name is the collection's name. It's changed by [DB.RenameCollection], so
+	// read it with [Collection.CurrentName] if the collection might be renamed
+	// concurrently.
 	Name string
+	// nameLock guards Name against concurrent renames. It's never held while
+	// acquiring another lock.
+	nameLock sync.RWMutex
 
 	metadata      map[string]string
 	documents     map[string]*Document
 	documentsLock sync.RWMutex
 	embed         EmbeddingFunc
+	// column stores the documents' embeddings contiguously, see [embeddingColumn].
+	column embeddingColumn
+	// embedImage is the optional embedding function for documents with binary
+	// data, see [Collection.SetImageEmbeddingFunc].
+	embedImage ImageEmbeddingFunc
+	// embeddingModel is the name of the model the document embeddings were
+	// created with. Optional, only known if the user provided it.
+	embeddingModel string
+	// modelMismatches is the number of documents that were embedded with
+	// another model than embeddingModel, so that queries don't have to check
+	// all documents, see [Collection.checkEmbeddingModel]. Must only be
+	// accessed while holding documentsLock.
+	modelMismatches int
+
+	// generation is incremented on each write, so that cached query results
+	// can be invalidated. Must only be accessed while holding documentsLock.
+	generation uint64
+	queryCache *queryCache
+	// Settings for the two-stage search with Matryoshka embeddings, see
+	// [Collection.SetMatryoshkaSearch]. Must only be accessed while holding
+	// documentsLock.
+	matryoshka matryoshkaConfig
+	// Optional schema for the documents' metadata, see
+	// [Collection.SetMetadataSchema]. Must only be accessed while holding
+	// documentsLock.
+	metadataSchema *MetadataSchema
+	// Normalization of document and query embeddings, see
+	// [Collection.SetNormalizationPolicy]. Must only be accessed while holding
+	// documentsLock.
+	normalization NormalizationPolicy
+	// Name of the similarity function of queries, or empty for the default,
+	// see [Collection.SetSimilarityFunc]. Must only be accessed while holding
+	// documentsLock.
+	similarity string
+	// Whether documents can have token embeddings, see
+	// [Collection.SetLateInteraction]. Must only be accessed while holding
+	// documentsLock.
+	lateInteraction bool
+	// Default settings of queries, see [Collection.SetQueryDefaults]. Must
+	// only be accessed while holding documentsLock.
+	queryDefaults QueryDefaults
+	// Optional keyword search for when the query can't be embedded, see
+	// [Collection.SetQueryFallback]. Must only be accessed while holding
+	// documentsLock.
+	queryFallback *QueryFallback
+	// Optional projection to fewer dimensions, see [Collection.FitProjection].
+	// Must only be accessed while holding documentsLock.
+	projection *projection
+	// Optional IVF index for approximate nearest neighbor search, see
+	// [Collection.BuildIVFIndex]. Must only be accessed while holding
+	// documentsLock.
+	ivf *ivfIndex
+	// The DB's memory budget, see [DB.SetMemoryBudget], and the IDs of the
+	// documents whose content was evicted from memory. Must only be accessed
+	// while holding documentsLock.
+	memory  *memoryBudget
+	evicted map[string]struct{}
+	// The DB's concurrency defaults, see [DB.SetConcurrencyDefaults]. It's
+	// set when the collection is added to the DB and not changed afterwards.
+	concurrency *concurrencyConfig
+	// Optional content store, see [Collection.EnableContentStore]. Must only
+	// be accessed while holding documentsLock.
+	contents *contentStore
+	// Whether the content of new documents is discarded after embedding them,
+	// see [Collection.SetDiscardContent]. Must only be accessed while holding
+	// documentsLock.
+	discardContent bool
+	// Whether adding a document with an existing ID fails, see
+	// [Collection.SetStrictMode]. Must only be accessed while holding
+	// documentsLock.
+	strict bool
+	// Limit of the content length and the splitter of documents that exceed
+	// it, see [Collection.SetContentLimit]. Must only be accessed while holding
+	// documentsLock.
+	contentLimit    ContentLimit
+	contentSplitter TextSplitter
+	// Positional index for phrase filters, see [Collection.EnablePhraseIndex].
+	// nil if it's disabled. Must only be accessed while holding documentsLock.
+	phrases *phraseIndex
+	// Value statistics of metadata keys, see
+	// [Collection.EnableMetadataStats]. nil if they're disabled. Must only be
+	// accessed while holding documentsLock.
+	metadataStats *metadataStats
+	// Inverted index for keyword search, see [Collection.EnableBM25Index].
+	// nil if it's disabled. Must only be accessed while holding documentsLock.
+	bm25 *bm25Index
+	// Contents that are kept compressed in memory, see
+	// [Collection.SetContentCompression]. nil if the compression is disabled.
+	// Must only be accessed while holding documentsLock.
+	compressed *compressedContents
+	// Assignment of the collection as a shard of a sharded collection, see
+	// [DB.GetOrCreateShardedCollection]. nil if it isn't a shard. Must only be
+	// accessed while holding documentsLock.
+	shard *shardInfo
+	// When the collection was created and last modified, see
+	// [Collection.CreatedAt] and [Collection.ModifiedAt]. modifiedAtStale is
+	// set when the modification time isn't persisted yet. Must only be accessed
+	// while holding documentsLock.
+	createdAt       time.Time
+	modifiedAt      time.Time
+	modifiedAtStale bool
+	// The highest version assigned to a document, so that a version is never
+	// reused for an ID, even after deleting and adding it again, see
+	// [Document.Version]. persistedVersion is the one in the metadata file.
+	// Must only be accessed while holding documentsLock.
+	lastVersion      uint64
+	persistedVersion uint64
+	// Content encryption, see [Collection.SetContentEncryption]. The key is
+	// nil after loading until it's set again. Must only be accessed while
+	// holding documentsLock.
+	contentKey               cipher.AEAD
+	contentEncrypted         bool
+	contentEncryptedInMemory bool
+	// Status of IVF index builds, see [Collection.IndexStatus].
+	ivfBuild ivfBuildState
+
+	// Registered hooks, see [Collection.AddHooks].
+	hooks hooks
+	// Optional audit log, see [Collection.EnableAuditLog]. Must only be written
+	// to while holding documentsLock.
+	auditLog *auditLog
+	// Log of the changes of the collection's files for read replicas. Only
+	// used if the collection is persistent.
+	changes changeLog
+	// Subscriptions to changes, see [Collection.Watch].
+	watchers watchers
+
+	// Loaded namespaces, see [Collection.Namespace].
+	namespaces     map[string]*Collection
+	namespacesLock sync.Mutex
+
+	// State of a running or interrupted re-embedding, see [Collection.Reembed].
+	reembedding *reembedState
+	reembedLock sync.Mutex
+
+	// persistLock is held for reading while documents are persisted outside of
+	// documentsLock, so that [Collection.Flush] can wait for pending writes.
+	persistLock sync.RWMutex
+	// closed is set by [DB.Close]. Must only be accessed while holding
+	// documentsLock.
+	closed bool
 
 	persistDirectory string
 	compress         bool
+	encoding         Encoding
+	perms            filePerms
+	// readableNames names the files after the sanitized names, see
+	// [PersistentDBOptions.ReadableNames].
+	readableNames bool
+	// Name of the leader's collection directory if this is a collection of a
+	// [Replica].
+	replicaDir string
 
 	// ⚠️ When adding fields here, consider adding them to the persistence struct
 	// versions in [DB.Export] and [DB.Import] as well!
@@ -29,7 +187,7 @@ type Collection struct {
 
 // We don't export this yet to keep the API surface to the bare minimum.
 // Users create collections via [Client.CreateCollection].
-func newCollection(name string, metadata map[string]string, embed EmbeddingFunc, dbDir string, compress bool) (*Collection, error) {
+func newCollection(name string, metadata map[string]string, embed EmbeddingFunc, dbDir string, compress bool, encoding Encoding, perms filePerms, readableNames bool) (*Collection, error) {
 	// We copy the metadata to avoid data races in case the caller modifies the
 	// map after creating the collection while we range over it.
 	m := make(map[string]string, len(metadata))
@@ -43,27 +201,19 @@ func newCollection(name string, metadata map[string]string, embed EmbeddingFunc,
 		metadata:  m,
 		documents: make(map[string]*Document),
 		embed:     embed,
+		createdAt: time.Now().UTC(),
 	}
+	c.modifiedAt = c.createdAt
 
 	// Persistence
 	if dbDir != "" {
-		safeName := hash2hex(name)
-		c.persistDirectory = filepath.Join(dbDir, safeName)
+		c.readableNames = readableNames
+		c.persistDirectory = filepath.Join(dbDir, persistedName(name, readableNames))
 		c.compress = compress
+		c.encoding = encoding
+		c.perms = perms
 		// Persist name and metadata
-		metadataPath := filepath.Join(c.persistDirectory, metadataFileName)
-		metadataPath += ".gob"
-		if c.compress {
-			metadataPath += ".gz"
-		}
-		pc := struct {
-			Name     string
-			Metadata map[string]string
-		}{
-			Name:     name,
-			Metadata: m,
-		}
-		err := persistToFile(metadataPath, pc, compress, "")
+		err := c.writeMetadata()
 		if err != nil {
 			return nil, fmt.Errorf("couldn't persist collection metadata: %w", err)
 		}
@@ -72,6 +222,188 @@ func newCollection(name string, metadata map[string]string, embed EmbeddingFunc,
 	return c, nil
 }
 
+// persistedCollectionMetadata is the collection's metadata file content.
+// Exported fields so it can be encoded as gob.
+type persistedCollectionMetadata struct {
+	// Version of the persistence format of the collection's files, see
+	// [formatVersion].
+	FormatVersion    int
+	Name             string
+	Metadata         map[string]string
+	EmbeddingModel   string
+	Projection       *projection
+	MetadataSchema   *MetadataSchema
+	ContentStore     bool
+	DiscardContent   bool
+	ContentEncrypted bool
+	QueryDefaults    QueryDefaults
+	Normalization    NormalizationPolicy
+	Similarity       string
+	Matryoshka       matryoshkaConfig
+	LateInteraction  bool
+	StrictMode       bool
+	ReadableNames    bool
+	PhraseIndex      bool
+	// Metadata keys with value statistics. The statistics are rebuilt when the
+	// collection is loaded.
+	MetadataStatsKeys []string
+	// Options of the BM25 index, which is persisted in its own file. nil if
+	// there's no index.
+	BM25Index *BM25IndexOptions
+	// Block size of the in-memory content compression, 0 if it's disabled
+	ContentCompression int
+	// Name of the codec of the content compression, "" if it's disabled
+	ContentCodec string
+	// nil if the collection isn't a shard of a sharded collection
+	Shard *shardInfo
+	// The highest version assigned to a document, see [Collection.lastVersion].
+	// The versions of the documents are considered as well when loading, as
+	// they might be newer.
+	LastVersion uint64
+	CreatedAt   time.Time
+	ModifiedAt  time.Time
+	// Document statistics, so they're available without reading the documents.
+	// Like ModifiedAt, they're persisted with the next settings change or
+	// flush, so they might be outdated after a crash.
+	DocumentCount int
+	Dimensions    int
+}
+
+// persistMetadata records a change of the collection's settings and writes the
+// collection's metadata file. It's a no-op for non-persistent collections,
+// apart from recording the change.
+func (c *Collection) persistMetadata() error {
+	c.modifiedAt = time.Now().UTC()
+	return c.writeMetadata()
+}
+
+// writeMetadata writes the collection's metadata file. It's a no-op for
+// non-persistent collections.
+func (c *Collection) writeMetadata() error {
+	c.modifiedAtStale = false
+	if c.persistDirectory == "" {
+		return nil
+	}
+
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName+c.fileExt())
+	pc := c.persistedMetadata()
+	err := persistToFile(metadataPath, pc, c.encoding, c.compress, "", c.perms)
+	if err != nil {
+		return err
+	}
+	c.persistedVersion = pc.LastVersion
+	return c.logChanges(changeRecord{Op: changeOpMetadata})
+}
+
+// initVersion sets the highest version assigned to a document from the
+// persisted or exported one and the versions of the loaded documents. Must be
+// called before the collection is shared.
+func (c *Collection) initVersion(persisted uint64) {
+	c.lastVersion = persisted
+	for _, doc := range c.documents {
+		c.lastVersion = max(c.lastVersion, doc.Version)
+	}
+}
+
+// persistedMetadata returns the content of the collection's metadata file.
+func (c *Collection) persistedMetadata() persistedCollectionMetadata {
+	return persistedCollectionMetadata{
+		FormatVersion:      formatVersion(),
+		Name:               c.Name,
+		Metadata:           c.metadata,
+		EmbeddingModel:     c.embeddingModel,
+		Projection:         c.projection,
+		MetadataSchema:     c.metadataSchema,
+		ContentStore:       c.contents != nil,
+		DiscardContent:     c.discardContent,
+		ContentEncrypted:   c.contentEncrypted,
+		QueryDefaults:      c.queryDefaults,
+		Normalization:      c.normalization,
+		Similarity:         c.similarity,
+		Matryoshka:         c.matryoshka,
+		LateInteraction:    c.lateInteraction,
+		StrictMode:         c.strict,
+		ReadableNames:      c.readableNames,
+		PhraseIndex:        c.phrases != nil,
+		MetadataStatsKeys:  c.metadataStats.keys(),
+		BM25Index:          c.bm25Options(),
+		ContentCompression: c.contentCompression(),
+		ContentCodec:       c.contentCodec(),
+		Shard:              c.shard,
+		LastVersion:        c.lastVersion,
+		CreatedAt:          c.createdAt,
+		ModifiedAt:         c.modifiedAt,
+		DocumentCount:      len(c.documents),
+		Dimensions:         c.dimensions(),
+	}
+}
+
+// applyPersistedMetadata applies the settings of the persisted metadata to the
+// collection, apart from its name and the optional indexes, see
+// [Collection.applyPersistedIndexes]. Must be called while holding the
+// documents write lock, or before the collection is shared.
+func (c *Collection) applyPersistedMetadata(pc persistedCollectionMetadata) {
+	c.metadata = pc.Metadata
+	c.embeddingModel = pc.EmbeddingModel
+	c.projection = pc.Projection
+	c.metadataSchema = pc.MetadataSchema
+	c.discardContent = pc.DiscardContent
+	c.queryDefaults = pc.QueryDefaults
+	c.normalization = pc.Normalization
+	c.similarity = pc.Similarity
+	c.matryoshka = pc.Matryoshka
+	c.lateInteraction = pc.LateInteraction
+	c.strict = pc.StrictMode
+	c.readableNames = pc.ReadableNames
+	c.shard = pc.Shard
+	c.createdAt = pc.CreatedAt
+	c.modifiedAt = pc.ModifiedAt
+	if pc.ContentEncrypted && !c.contentEncrypted {
+		// The content stays encrypted in memory until the key is set.
+		c.contentEncrypted = true
+		c.contentEncryptedInMemory = true
+	}
+}
+
+// applyPersistedIndexes builds or removes the optional indexes and the content
+// compression according to the persisted metadata. Indexes whose options
+// didn't change are kept, so it can be called again when the metadata changes.
+// Must be called after the documents were loaded, while holding the documents
+// write lock or before the collection is shared.
+func (c *Collection) applyPersistedIndexes(pc persistedCollectionMetadata) error {
+	if !pc.PhraseIndex {
+		c.phrases = nil
+	} else if c.phrases == nil {
+		c.buildPhraseIndex()
+	}
+	if len(pc.MetadataStatsKeys) == 0 {
+		c.metadataStats = nil
+	} else if !slices.Equal(c.metadataStats.keys(), pc.MetadataStatsKeys) {
+		c.buildMetadataStats(pc.MetadataStatsKeys)
+	}
+	if pc.BM25Index == nil {
+		c.bm25 = nil
+	} else if c.bm25 == nil || c.bm25.options != *pc.BM25Index {
+		c.loadBM25Index(*pc.BM25Index)
+	}
+	codecName := pc.ContentCodec
+	if pc.ContentCompression > 0 && codecName == "" {
+		// Written before the codec was configurable
+		codecName = CodecDeflate
+	}
+	if pc.ContentCompression != c.contentCompression() || codecName != c.contentCodec() {
+		codec, err := lookupCodec(codecName)
+		if err != nil {
+			return err
+		}
+		err = c.setContentCompression(pc.ContentCompression, codec)
+		if err != nil {
+			return fmt.Errorf("couldn't compress contents: %w", err)
+		}
+	}
+	return nil
+}
+
 // Add embeddings to the datastore.
 //
 //   - ids: The ids of the embeddings you wish to add
@@ -90,6 +422,8 @@ func (c *Collection) Add(ctx context.Context, ids []string, embeddings [][]float
 // This is mostly useful when you don't pass any embeddings so they have to be created.
 // Upon error, concurrently running operations are canceled and the error is returned.
 //
+// A concurrency of 0 uses the DB's default, see [Collection.AddDocuments].
+//
 // This is a Chroma-like method. For a more Go-idiomatic one, see [AddDocuments].
 func (c *Collection) AddConcurrently(ctx context.Context, ids []string, embeddings [][]float32, metadatas []map[string]string, contents []string, concurrency int) error {
 	if len(ids) == 0 {
@@ -122,8 +456,8 @@ func (c *Collection) AddConcurrently(ctx context.Context, ids []string, embeddin
 		// Assign empty slice so we can simply access via index later
 		contents = make([]string, len(ids))
 	}
-	if concurrency < 1 {
-		return errors.New("concurrency must be at least 1")
+	if concurrency < 0 {
+		return errors.New("concurrency must be >= 0")
 	}
 
 	// Convert Chroma-style parameters into a slice of documents.
@@ -144,14 +478,24 @@ func (c *Collection) AddConcurrently(ctx context.Context, ids []string, embeddin
 // If the documents don't have embeddings, they will be created using the collection's
 // embedding function.
 // Upon error, concurrently running operations are canceled and the error is returned.
+//
+// A concurrency of 0 uses the DB's default, which adapts the concurrency to the
+// embedding provider automatically, see [DB.SetConcurrencyDefaults].
 func (c *Collection) AddDocuments(ctx context.Context, documents []Document, concurrency int) error {
 	if len(documents) == 0 {
 		// TODO: Should this be a no-op instead?
 		return errors.New("documents slice is nil or empty")
 	}
-	if concurrency < 1 {
-		return errors.New("concurrency must be at least 1")
+	concurrency, limiter, err := c.resolveConcurrency(concurrency)
+	if err != nil {
+		return err
 	}
+	return c.addDocuments(ctx, documents, concurrency, limiter)
+}
+
+// addDocuments adds the documents with the fixed concurrency, or with the
+// limiter's automatic concurrency if it's non-nil.
+func (c *Collection) addDocuments(ctx context.Context, documents []Document, concurrency int, limiter *aimdLimiter) error {
 	// For other validations we rely on AddDocument.
 
 	var sharedErr error
@@ -181,11 +525,15 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 				return
 			}
 
-			// Wait here while $concurrency other goroutines are creating documents.
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			err := c.AddDocument(ctx, doc)
+			var err error
+			if limiter != nil {
+				err = c.addDocumentAdaptive(ctx, doc, limiter)
+			} else {
+				// Wait here while $concurrency other goroutines are creating documents.
+				semaphore <- struct{}{}
+				err = c.AddDocument(ctx, doc)
+				<-semaphore
+			}
 			if err != nil {
 				setSharedErr(fmt.Errorf("couldn't add document '%s': %w", doc.ID, err))
 				return
@@ -201,13 +549,39 @@ func (c *Collection) AddDocuments(ctx context.Context, documents []Document, con
 // AddDocument adds a document to the collection.
 // If the document doesn't have an embedding, it will be created using the collection's
 // embedding function.
+// If a document with the same ID exists, it's replaced, and the new document's
+// version is incremented. Use [Collection.UpsertDocument] to only replace it if
+// it hasn't been changed by another writer in the meantime.
 func (c *Collection) AddDocument(ctx context.Context, doc Document) error {
+	if limit, splitter := c.getContentLimit(); limit.MaxTokens > 0 {
+		return c.addDocumentWithLimit(ctx, doc, limit, splitter)
+	}
+	return c.addDocument(ctx, doc, nil)
+}
+
+// UpsertDocument adds or replaces a document with optimistic concurrency
+// control. The write only succeeds if the document's current version (see
+// [Document.Version]) is the expected one, otherwise it fails with
+// [ErrVersionConflict]. Typically you read a document with [Collection.GetByID],
+// modify it and write it back with its version as expectedVersion. If another
+// writer changed the document in the meantime, you can read it again and retry.
+//
+//   - expectedVersion: The version the document must currently have. 0 means
+//     that the document must not exist yet.
+func (c *Collection) UpsertDocument(ctx context.Context, doc Document, expectedVersion uint64) error {
+	limit, _ := c.getContentLimit()
+	if tokens, exceeds := limit.exceeds(doc); exceeds {
+		return fmt.Errorf("%w: document '%s' has %d tokens, the limit is %d", ErrContentTooLong, doc.ID, tokens, limit.MaxTokens)
+	}
+	return c.addDocument(ctx, doc, &expectedVersion)
+}
+
+// addDocument adds the document. If expectedVersion is non-nil, the current
+// version must match it.
+func (c *Collection) addDocument(ctx context.Context, doc Document, expectedVersion *uint64) error {
 	if doc.ID == "" {
 		return errors.New("document ID is empty")
 	}
-	if len(doc.Embedding) == 0 && doc.Content == "" {
-		return errors.New("either document embedding or content must be filled")
-	}
 
 	// We copy the metadata to avoid data races in case the caller modifies the
 	// map after creating the document while we range over it.
@@ -215,66 +589,357 @@ func (c *Collection) AddDocument(ctx context.Context, doc Document) error {
 	for k, v := range doc.Metadata {
 		m[k] = v
 	}
+	doc.Metadata = m
+
+	// Hooks can validate and modify the document.
+	for _, hook := range c.hooks.get() {
+		if hook.BeforeAdd == nil {
+			continue
+		}
+		err := hook.BeforeAdd(ctx, &doc)
+		if err != nil {
+			return fmt.Errorf("document '%s' rejected by hook: %w", doc.ID, err)
+		}
+	}
+	if doc.ID == "" {
+		return errors.New("document ID is empty")
+	}
+	if len(doc.Embedding) == 0 && doc.Content == "" && len(doc.Data) == 0 && len(doc.TokenEmbeddings) == 0 {
+		return errors.New("either document embedding, content, data or token embeddings must be filled")
+	}
+
+	// Check the schema and strict mode before creating the embedding, which
+	// might be expensive. Strict mode is checked again when adding.
+	c.documentsLock.RLock()
+	schema := c.metadataSchema
+	_, exists := c.documents[doc.ID]
+	strict := c.strict && expectedVersion == nil
+	lateInteraction := c.lateInteraction
+	c.documentsLock.RUnlock()
+	if strict && exists {
+		return fmt.Errorf("%w: '%s'", ErrDocumentExists, doc.ID)
+	}
+	if schema != nil {
+		err := schema.check(doc.Metadata)
+		if err != nil {
+			return fmt.Errorf("document '%s' rejected: %w", doc.ID, err)
+		}
+	}
+
+	if !doc.SparseEmbedding.IsEmpty() {
+		sparse, err := doc.SparseEmbedding.sorted()
+		if err != nil {
+			return fmt.Errorf("invalid sparse embedding: %w", err)
+		}
+		doc.SparseEmbedding = sparse
+	}
+	if len(doc.TokenEmbeddings) > 0 {
+		if !lateInteraction {
+			return fmt.Errorf("document '%s' has token embeddings, but late interaction isn't enabled, see SetLateInteraction", doc.ID)
+		}
+		tokens, err := normalizeTokenEmbeddings(doc.TokenEmbeddings)
+		if err != nil {
+			return fmt.Errorf("invalid token embeddings: %w", err)
+		}
+		doc.TokenEmbeddings = tokens
+		if len(doc.Embedding) == 0 && doc.Content == "" && len(doc.Data) == 0 {
+			doc.Embedding = meanDirection(tokens)
+		}
+	}
+
+	stats := addStatsFromContext(ctx)
+	if stats != nil && len(doc.Embedding) == 0 && expectedVersion == nil && c.isUnchanged(doc) {
+		stats.skipped.Add(1)
+		return nil
+	}
 
 	// Create embedding if they don't exist, otherwise normalize if necessary
-	if len(doc.Embedding) == 0 {
-		embedding, err := c.embed(ctx, doc.Content)
+	if len(doc.Embedding) == 0 && doc.Content == "" {
+		// Only binary data, like an image.
+		embedImage, model := c.getEmbedImageAndModel()
+		if embedImage == nil {
+			return errors.New("document has only data, but the collection has no image embedding function")
+		}
+		embedding, err := embedImage(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationAdd, doc.ID), doc.Data, doc.MIMEType)
+		if err != nil {
+			return fmt.Errorf("couldn't create embedding of document data: %w", err)
+		}
+		doc.Embedding = embedding
+		doc.EmbeddingModel = model
+		if stats != nil {
+			stats.created.Add(1)
+		}
+	} else if len(doc.Embedding) == 0 {
+		embed, model := c.getEmbedAndModel()
+		embedding, err := embed(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationAdd, doc.ID), doc.embeddingText())
 		if err != nil {
 			return fmt.Errorf("couldn't create embedding of document: %w", err)
 		}
 		doc.Embedding = embedding
+		doc.EmbeddingModel = model
+		if stats != nil {
+			stats.created.Add(1)
+		}
 	} else {
-		if !isNormalized(doc.Embedding) {
-			doc.Embedding = normalizeVector(doc.Embedding)
+		if model := c.EmbeddingModel(); model != "" && doc.EmbeddingModel != "" && doc.EmbeddingModel != model {
+			return fmt.Errorf("%w: document embedding was created with '%s', but the collection uses '%s'", ErrEmbeddingModelMismatch, doc.EmbeddingModel, model)
+		}
+		if stats != nil {
+			stats.precomputed.Add(1)
 		}
 	}
+	// The embedding text is only stored if it differs from the content, e.g.
+	// for Reembed.
+	if doc.EmbeddingText == doc.Content || doc.Content == "" {
+		doc.EmbeddingText = ""
+	}
+
+	c.persistLock.RLock()
+	defer c.persistLock.RUnlock()
 
 	c.documentsLock.Lock()
 	// We don't defer the unlock because we want to do it earlier.
-	c.documents[doc.ID] = &doc
+	if c.closed {
+		c.documentsLock.Unlock()
+		return ErrClosed
+	}
+	if c.contentEncrypted && c.contentKey == nil && doc.Content != "" && !c.discardContent {
+		c.documentsLock.Unlock()
+		return ErrContentEncrypted
+	}
+	var currentVersion uint64
+	existing, ok := c.documents[doc.ID]
+	if ok {
+		currentVersion = existing.Version
+	}
+	if ok && c.strict && expectedVersion == nil {
+		c.documentsLock.Unlock()
+		return fmt.Errorf("%w: '%s'", ErrDocumentExists, doc.ID)
+	}
+	if expectedVersion != nil && *expectedVersion != currentVersion {
+		c.documentsLock.Unlock()
+		return fmt.Errorf("%w: document '%s' has version %d, expected %d", ErrVersionConflict, doc.ID, currentVersion, *expectedVersion)
+	}
+	embedding, err := c.normalizeDocumentEmbedding(doc.Embedding)
+	if err != nil {
+		c.documentsLock.Unlock()
+		return err
+	}
+	doc.Embedding = c.projection.projectIfInput(embedding)
+	if c.strict {
+		err = c.checkDimensions(doc.Embedding)
+		if err != nil {
+			c.documentsLock.Unlock()
+			return err
+		}
+	}
+	err = c.checkDocPathCollision(doc.ID)
+	if err != nil {
+		c.documentsLock.Unlock()
+		return err
+	}
+	// The version comes from the collection's counter instead of the existing
+	// document, so that a deleted and re-added document doesn't get the
+	// version of its predecessor, which an editor might still expect.
+	c.lastVersion = max(c.lastVersion, currentVersion) + 1
+	doc.Version = c.lastVersion
+	eventType := EventAdd
+	if currentVersion != 0 {
+		eventType = EventUpdate
+	}
+	// The audit log is written first, so that there are no unlogged changes.
+	err = c.auditLog.write(ctx, eventType, doc.ID, doc.Version)
+	if err != nil {
+		c.documentsLock.Unlock()
+		return fmt.Errorf("couldn't write audit log: %w", err)
+	}
+	doc.ContentHash = ""
+	if c.discardContent {
+		doc.Content = ""
+		doc.EmbeddingText = ""
+		doc.Data = nil
+	}
+	if c.contents != nil && doc.Content != "" {
+		doc.Content, doc.ContentHash, err = c.contents.add(doc.Content)
+		if err != nil {
+			c.documentsLock.Unlock()
+			return err
+		}
+	}
+	var releasedHash string
+	if existing != nil && existing.ContentHash != "" {
+		c.contents.release(existing.ContentHash)
+		releasedHash = existing.ContentHash
+	}
+	diskDoc := doc
+	if c.contentEncrypted {
+		encrypted, err := cryptTexts(&doc, func(s string) (string, error) { return encryptContent(c.contentKey, doc.ID, s) })
+		if err != nil {
+			c.documentsLock.Unlock()
+			return err
+		}
+		diskDoc = *encrypted
+	}
+	memDoc := &doc
+	if c.contentEncryptedInMemory {
+		memDoc = &diskDoc
+	}
+	if c.compressed != nil {
+		compressed := *memDoc
+		err = c.compressContent(&compressed)
+		if err != nil {
+			c.documentsLock.Unlock()
+			return err
+		}
+		memDoc = &compressed
+	}
+	stored := c.column.put(c.documents, memDoc)
+	c.documents[doc.ID] = stored
+	c.ivf.add(stored)
+	c.indexPhrases(doc.ID, stored)
+	c.indexBM25(doc.ID, stored)
+	c.metadataStats.remove(existing)
+	c.metadataStats.add(stored)
+	c.trackEmbeddingModel(existing, stored)
+	if c.memory != nil {
+		usage := documentMemoryUsage(stored)
+		if existing != nil {
+			usage -= documentMemoryUsage(existing)
+		}
+		c.memory.adjust(usage)
+		// Only evictable once it's persisted
+		c.memory.untrack(c, doc.ID)
+		delete(c.evicted, doc.ID)
+	}
+	memory := c.memory
+	c.invalidateQueryCache()
+	c.markModified()
+	c.watchers.emit(Event{Type: eventType, DocumentID: doc.ID, Document: &doc})
 	c.documentsLock.Unlock()
 
 	// Persist the document
 	if c.persistDirectory != "" {
-		docPath := c.getDocPath(doc.ID)
-		err := persistToFile(docPath, doc, c.compress, "")
+		err := c.writeDocument(&diskDoc)
 		if err != nil {
-			return fmt.Errorf("couldn't persist document to %q: %w", docPath, err)
+			return err
+		}
+		if stats != nil {
+			stats.recordPersisted(c.getDocPath(doc.ID))
+		}
+		memory.track(c, stored)
+		memory.enforce()
+	}
+	// The replaced document's content can only be removed after the new
+	// document file doesn't reference it anymore.
+	if releasedHash != "" && releasedHash != doc.ContentHash {
+		c.documentsLock.Lock()
+		err := c.contents.removeUnreferenced(releasedHash)
+		c.documentsLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range c.hooks.get() {
+		if hook.AfterAdd != nil {
+			hook.AfterAdd(ctx, doc)
 		}
 	}
 
 	return nil
 }
 
+// GetByID returns a document by its ID.
+// The returned document is a copy of the stored one, so modifying it doesn't
+// affect the collection.
+func (c *Collection) GetByID(_ context.Context, id string) (Document, error) {
+	if id == "" {
+		return Document{}, errors.New("document ID is empty")
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return Document{}, ErrClosed
+	}
+
+	doc, ok := c.documents[id]
+	if !ok {
+		return Document{}, fmt.Errorf("document with ID '%v' %w", id, ErrNotFound)
+	}
+	doc, err := c.readable(doc)
+	if err != nil {
+		return Document{}, err
+	}
+	c.memory.touch(c, id)
+
+	// We have to copy the document, so that the caller can't modify the stored one.
+	res := *doc
+	res.Metadata = make(map[string]string, len(doc.Metadata))
+	for k, v := range doc.Metadata {
+		res.Metadata[k] = v
+	}
+	res.Embedding = slices.Clone(doc.Embedding)
+	res.Data = slices.Clone(doc.Data)
+	res.SparseEmbedding = SparseVector{
+		Indices: slices.Clone(doc.SparseEmbedding.Indices),
+		Values:  slices.Clone(doc.SparseEmbedding.Values),
+	}
+	res.TokenEmbeddings = cloneTokenEmbeddings(doc.TokenEmbeddings)
+	return res, nil
+}
+
+// ErrVersionConflict is returned by [Collection.UpsertDocument] when the
+// document was changed by another writer.
+var ErrVersionConflict = errors.New("version conflict")
+
 // Delete removes document(s) from the collection.
 //
 //   - where: Conditional filtering on metadata. Optional.
 //   - whereDocument: Conditional filtering on documents. Optional.
 //   - ids: The ids of the documents to delete. If empty, all documents are deleted.
-func (c *Collection) Delete(_ context.Context, where, whereDocument map[string]string, ids ...string) error {
+func (c *Collection) Delete(ctx context.Context, where, whereDocument map[string]string, ids ...string) error {
+	deletedIDs, err := c.delete(ctx, where, whereDocument, ids...)
+	// Also call the hooks on errors, as some documents might have been deleted.
+	if len(deletedIDs) != 0 {
+		for _, hook := range c.hooks.get() {
+			if hook.AfterDelete != nil {
+				hook.AfterDelete(ctx, deletedIDs)
+			}
+		}
+	}
+	return err
+}
+
+// delete deletes the documents and returns the IDs of the deleted ones.
+func (c *Collection) delete(ctx context.Context, where, whereDocument map[string]string, ids ...string) ([]string, error) {
 	// must have at least one of where, whereDocument or ids
 	if len(where) == 0 && len(whereDocument) == 0 && len(ids) == 0 {
-		return fmt.Errorf("must have at least one of where, whereDocument or ids")
+		return nil, fmt.Errorf("must have at least one of where, whereDocument or ids")
 	}
 
 	if len(c.documents) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	for k := range whereDocument {
-		if !slices.Contains(supportedFilters, k) {
-			return errors.New("unsupported whereDocument operator")
-		}
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
 	}
 
 	var docIDs []string
 
 	c.documentsLock.Lock()
 	defer c.documentsLock.Unlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
 
 	if where != nil || whereDocument != nil {
 		// metadata + content filters
-		filteredDocs := filterDocs(c.documents, where, whereDocument)
+		filteredDocs, err := c.filter(c.column.docs(c.documents), where, whereDocument)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't filter documents: %w", err)
+		}
 		for _, doc := range filteredDocs {
 			docIDs = append(docIDs, doc.ID)
 		}
@@ -284,23 +949,68 @@ func (c *Collection) Delete(_ context.Context, where, whereDocument map[string]s
 
 	// No-op if no docs are left
 	if len(docIDs) == 0 {
-		return nil
+		return nil, nil
 	}
 
+	c.invalidateQueryCache()
+	c.markModified()
+	var deletedIDs []string
+	var deletedVersion uint64
 	for _, docID := range docIDs {
+		var contentHash string
+		if existing, ok := c.documents[docID]; ok {
+			contentHash = existing.ContentHash
+			deletedVersion = max(deletedVersion, existing.Version)
+			err := c.auditLog.write(ctx, EventDelete, docID, existing.Version)
+			if err != nil {
+				return deletedIDs, fmt.Errorf("couldn't write audit log: %w", err)
+			}
+			c.watchers.emit(Event{Type: EventDelete, DocumentID: docID})
+			deletedIDs = append(deletedIDs, docID)
+			c.metadataStats.remove(existing)
+			c.trackEmbeddingModel(existing, nil)
+			c.memory.adjust(-documentMemoryUsage(existing))
+			c.memory.untrack(c, docID)
+			delete(c.evicted, docID)
+		}
 		delete(c.documents, docID)
+		c.column.remove(docID)
+		c.ivf.remove(docID)
+		c.phrases.remove(docID)
+		c.bm25.remove(docID)
+		c.compressed.remove(docID)
 
 		// Remove the document from disk
 		if c.persistDirectory != "" {
 			docPath := c.getDocPath(docID)
 			err := removeFile(docPath)
 			if err != nil {
-				return fmt.Errorf("couldn't remove document at %q: %w", docPath, err)
+				return deletedIDs, fmt.Errorf("couldn't remove document at %q: %w", docPath, err)
+			}
+			err = c.logChanges(c.documentChange(docID))
+			if err != nil {
+				return deletedIDs, err
+			}
+		}
+		if contentHash != "" {
+			c.contents.release(contentHash)
+			err := c.contents.removeUnreferenced(contentHash)
+			if err != nil {
+				return deletedIDs, err
 			}
 		}
 	}
 
-	return nil
+	// The versions of deleted documents must still be known after a restart,
+	// so that they aren't reused when the documents are added again.
+	if c.persistDirectory != "" && deletedVersion > c.persistedVersion {
+		err := c.writeMetadata()
+		if err != nil {
+			return deletedIDs, fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+	}
+
+	return deletedIDs, nil
 }
 
 // Count returns the number of documents in the collection.
@@ -310,24 +1020,76 @@ func (c *Collection) Count() int {
 	return len(c.documents)
 }
 
+// ListIDs returns the IDs of all documents in the collection, sorted.
+func (c *Collection) ListIDs() []string {
+	c.documentsLock.RLock()
+	ids := make([]string, 0, len(c.documents))
+	for id := range c.documents {
+		ids = append(ids, id)
+	}
+	c.documentsLock.RUnlock()
+
+	slices.Sort(ids)
+	return ids
+}
+
+// Metadata returns a copy of the collection's metadata.
+func (c *Collection) Metadata() map[string]string {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return maps.Clone(c.metadata)
+}
+
+// CurrentName returns the collection's name, like the Name field, but it's
+// safe to call while the collection is renamed (see [DB.RenameCollection]).
+func (c *Collection) CurrentName() string {
+	c.nameLock.RLock()
+	defer c.nameLock.RUnlock()
+	return c.Name
+}
+
+// setName sets the collection's name, see [Collection.CurrentName].
+func (c *Collection) setName(name string) {
+	c.nameLock.Lock()
+	defer c.nameLock.Unlock()
+	c.Name = name
+}
+
 // Result represents a single result from a query.
+// The JSON field names are stable, so results can be returned by HTTP services
+// as they are. See [WriteResultsJSON] and [WriteResultsCSV] for helpers.
 type Result struct {
-	ID        string
-	Metadata  map[string]string
-	Embedding []float32
-	Content   string
+	ID        string            `json:"id"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Embedding []float32         `json:"embedding,omitempty"`
+	Content   string            `json:"content,omitempty"`
+
+	// The similarity between the query and the document, as calculated by the
+	// query's or collection's similarity function (see
+	// [Collection.SetSimilarityFunc]). The higher the value, the more similar
+	// the document is to the query. By default, it's the cosine similarity,
+	// in the range [-1, 1], but e.g. [SimilarityEuclidean] is at most 0, and
+	// the dot product of unnormalized embeddings isn't limited. Keyword and
+	// hybrid searches like [Collection.QueryBM25] return their score instead.
+	Similarity float32 `json:"similarity"`
+
+	// Score is the calibrated relevance score of the document, if score
+	// calibration is enabled (see [QueryOptions.Calibration]), otherwise 0.
+	// Its JSON name differs from the one of [FederatedResult.Score], which
+	// embeds the result.
+	Score float32 `json:"calibrated_score,omitempty"`
 
-	// The cosine similarity between the query and the document.
-	// The higher the value, the more similar the document is to the query.
-	// The value is in the range [-1, 1].
-	Similarity float32
+	// Explanation describes why the document matched, if requested, e.g. with
+	// [Collection.QueryHybridEmbeddingExplained], otherwise nil.
+	Explanation *MatchExplanation `json:"explanation,omitempty"`
 }
 
 // Performs an exhaustive nearest neighbor search on the collection.
 //
 //   - queryText: The text to search for. Its embedding will be created using the
 //     collection's embedding function.
-//   - nResults: The number of results to return. Must be > 0.
+//   - nResults: The number of results to return. If it's 0, the collection's
+//     default is used (see [Collection.SetQueryDefaults]), otherwise it must be > 0.
 //   - where: Conditional filtering on metadata. Optional.
 //   - whereDocument: Conditional filtering on documents. Optional.
 func (c *Collection) Query(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
@@ -335,23 +1097,154 @@ func (c *Collection) Query(ctx context.Context, queryText string, nResults int,
 		return nil, errors.New("queryText is empty")
 	}
 
-	queryVectors, err := c.embed(ctx, queryText)
+	// Make sure the query is embedded with the same model as the documents.
+	err := c.checkEmbeddingModel()
 	if err != nil {
-		return nil, fmt.Errorf("couldn't create embedding of query: %w", err)
+		return nil, err
+	}
+
+	queryVectors, err := c.getEmbed()(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationQuery, ""), queryText)
+	if err != nil {
+		return c.queryWithFallback(ctx, QueryOptions{QueryText: queryText, NResults: nResults, Where: where, WhereDocument: whereDocument}, err)
 	}
 
 	return c.QueryEmbedding(ctx, queryVectors, nResults, where, whereDocument)
 }
 
+// QueryOptions represents the options for a query.
+type QueryOptions struct {
+	// The text to search for. Required if QueryEmbedding is not set.
+	QueryText string
+
+	// The embedding of the query to search for. If set, QueryText is not
+	// embedded, which is useful when the query embedding was already created,
+	// e.g. for another collection. QueryText can then still be set for
+	// reference.
+	QueryEmbedding []float32
+
+	// The number of results to return. If it's 0, the collection's default is
+	// used (see [Collection.SetQueryDefaults]), otherwise it must be > 0.
+	NResults int
+
+	// MinSimilarity is the minimum similarity of the results. Less similar
+	// documents are left out, so there might be fewer results than requested.
+	// Optional, defaults to the collection's default.
+	MinSimilarity float32
+
+	// Include are the fields of the results. Optional, defaults to the
+	// collection's default, or all fields.
+	Include []IncludeField
+
+	// MMR enables the re-ranking of the results by maximal marginal relevance.
+	// Optional, defaults to the collection's default.
+	MMR *MMROptions
+
+	// Calibration enables the calibration of the similarities to relevance
+	// scores, see [Result.Score]. Optional, defaults to the collection's
+	// default.
+	Calibration *Calibration
+
+	// MinScore is the minimum calibrated score of the results, like
+	// MinSimilarity. It requires Calibration. Optional, defaults to the
+	// collection's default.
+	MinScore float32
+
+	// Conditional filtering on metadata. Optional.
+	Where map[string]string
+
+	// Conditional filtering on documents. Optional.
+	WhereDocument map[string]string
+
+	// Filter is a typed filter on metadata and content, built with [Where].
+	// Documents must match it in addition to Where and WhereDocument. Optional.
+	Filter Filter
+
+	// ExcludeIDs are the IDs of documents that are left out of the results,
+	// e.g. documents that were already shown to the user or that the user
+	// dismissed. They're skipped before the similarities are calculated, so
+	// the results are still the nResults most similar other documents.
+	// Optional.
+	ExcludeIDs []string
+
+	// EmbeddingFunc overrides the collection's embedding function for embedding
+	// QueryText. Optional. This is useful for models that have a separate query
+	// model or input type, like [NewEmbeddingFuncVoyage] with [InputTypeVoyageQuery],
+	// or that require an instruction prefix for queries, see [WithEmbeddingPrefix].
+	// The embeddings must be in the same vector space as the documents'.
+	EmbeddingFunc EmbeddingFunc
+
+	// SimilarityFunc overrides the collection's similarity function for this
+	// query (see [Collection.SetSimilarityFunc]), e.g. to experiment with a
+	// custom one. Optional. Results of such queries aren't cached.
+	SimilarityFunc SimilarityFunc
+
+	// FilterStrategy defines how the filters are combined with the IVF index,
+	// if the collection has one. Optional, defaults to the collection's
+	// default, or [FilterStrategyAuto].
+	FilterStrategy FilterStrategy
+}
+
+// QueryWithOptions performs an exhaustive nearest neighbor search on the
+// collection, like [Collection.Query] and [Collection.QueryEmbedding], but with
+// the options in one struct, which allows more options like overriding the
+// embedding function per query.
+func (c *Collection) QueryWithOptions(ctx context.Context, options QueryOptions) ([]Result, error) {
+	if options.QueryText == "" && len(options.QueryEmbedding) == 0 {
+		return nil, errors.New("QueryText and QueryEmbedding options are empty")
+	}
+
+	queryVectors := options.QueryEmbedding
+	if len(queryVectors) == 0 {
+		// Make sure the query is embedded with the same model as the documents.
+		err := c.checkEmbeddingModel()
+		if err != nil {
+			return nil, err
+		}
+
+		embed := options.EmbeddingFunc
+		if embed == nil {
+			embed = c.getEmbed()
+		}
+		queryVectors, err = embed(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationQuery, ""), options.QueryText)
+		if err != nil {
+			return c.queryWithFallback(ctx, options, err)
+		}
+	}
+
+	return c.queryEmbeddingWithHooks(ctx, queryVectors, options)
+}
+
 // Performs an exhaustive nearest neighbor search on the collection.
 //
 //   - queryEmbedding: The embedding of the query to search for. It must be created
 //     with the same embedding model as the document embeddings in the collection.
 //     The embedding will be normalized if it's not the case yet.
-//   - nResults: The number of results to return. Must be > 0.
+//   - nResults: The number of results to return. If it's 0, the collection's
+//     default is used (see [Collection.SetQueryDefaults]), otherwise it must be > 0.
 //   - where: Conditional filtering on metadata. Optional.
 //   - whereDocument: Conditional filtering on documents. Optional.
 func (c *Collection) QueryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return c.queryEmbeddingWithHooks(ctx, queryEmbedding, QueryOptions{NResults: nResults, Where: where, WhereDocument: whereDocument})
+}
+
+func (c *Collection) queryEmbeddingWithHooks(ctx context.Context, queryEmbedding []float32, options QueryOptions) ([]Result, error) {
+	hooks := c.hooks.get()
+	if len(hooks) == 0 {
+		return c.queryWithDefaults(ctx, queryEmbedding, options)
+	}
+
+	start := time.Now()
+	res, err := c.queryWithDefaults(ctx, queryEmbedding, options)
+	duration := time.Since(start)
+	for _, hook := range hooks {
+		if hook.AfterQuery != nil {
+			hook.AfterQuery(ctx, duration, err)
+		}
+	}
+	return res, err
+}
+
+func (c *Collection) queryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string, filter Filter, excludeIDs map[string]struct{}, similarity SimilarityFunc, strategy FilterStrategy, withContent bool) ([]Result, error) {
 	if len(queryEmbedding) == 0 {
 		return nil, errors.New("queryEmbedding is empty")
 	}
@@ -360,6 +1253,9 @@ func (c *Collection) QueryEmbedding(ctx context.Context, queryEmbedding []float3
 	}
 	c.documentsLock.RLock()
 	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
 	if nResults > len(c.documents) {
 		return nil, errors.New("nResults must be <= the number of documents in the collection")
 	}
@@ -369,54 +1265,245 @@ func (c *Collection) QueryEmbedding(ctx context.Context, queryEmbedding []float3
 	}
 
 	// Validate whereDocument operators
-	for k := range whereDocument {
-		if !slices.Contains(supportedFilters, k) {
-			return nil, errors.New("unsupported operator")
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	// Project the query to the dimensions of the documents if necessary.
+	queryEmbedding = c.projection.projectIfInput(queryEmbedding)
+
+	// Serve from the cache if enabled. The generation can't change while we
+	// hold the read lock. Functions can't be part of the key, so queries with
+	// their own similarity function aren't cached.
+	cache := c.queryCache != nil && similarity == nil
+	var cacheKey string
+	if cache {
+		cacheKey = queryCacheKey(queryEmbedding, nResults, where, whereDocument, filter, excludeIDs, strategy)
+		if res, ok := c.queryCache.get(cacheKey, c.generation); ok {
+			return res, nil
 		}
 	}
 
-	// Filter docs by metadata and content
-	filteredDocs := filterDocs(c.documents, where, whereDocument)
+	// Normalize embedding if not the case yet, like the documents were
+	// normalized when added to the collection, unless the policy says otherwise.
+	normalized := c.normalization != NormalizeNever
+	queryEmbedding = c.normalization.apply(queryEmbedding)
+
+	// Filter docs by metadata and content. With an IVF index, only the docs in
+	// the nearest clusters are candidates, unless the filter strategy says
+	// otherwise or, except for post-filtering, the filters leave too few.
+	var filteredDocs []*Document
+	var err error
+	exhaustive := true
+	if c.ivf.usable() {
+		candidates := c.ivf.docs(c.documents, queryEmbedding)
+		filtered := len(where) > 0 || len(whereDocument) > 0 || !filter.isEmpty()
+		switch {
+		case !filtered || strategy == FilterStrategyPost:
+			exhaustive = false
+		case strategy == FilterStrategyAuto:
+			selectivity := c.estimateSelectivity(candidates, where, whereDocument, filter)
+			exhaustive = selectivity*float64(len(candidates)) < float64(nResults)
+		}
+		if !exhaustive {
+			filteredDocs, err = c.filterWith(excludeDocs(candidates, excludeIDs), where, whereDocument, filter)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't filter documents: %w", err)
+			}
+			exhaustive = len(filteredDocs) < nResults && strategy != FilterStrategyPost
+		}
+	}
+	if exhaustive {
+		filteredDocs, err = c.filterWith(excludeDocs(c.column.docs(c.documents), excludeIDs), where, whereDocument, filter)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't filter documents: %w", err)
+		}
+	}
 
 	// No need to continue if the filters got rid of all documents
 	if len(filteredDocs) == 0 {
 		return nil, nil
 	}
 
-	// Normalize embedding if not the case yet. We only support cosine similarity
-	// for now and all documents were already normalized when added to the collection.
-	if !isNormalized(queryEmbedding) {
-		queryEmbedding = normalizeVector(queryEmbedding)
+	similarity, err = c.similarityFunc(similarity)
+	if err != nil {
+		return nil, err
 	}
 
 	// For the remaining documents, get the most similar docs.
-	nMaxDocs, err := getMostSimilarDocs(ctx, queryEmbedding, filteredDocs, nResults)
+	var nMaxDocs []docSim
+	if similarity != nil {
+		nMaxDocs, err = getMostSimilarDocsFunc(ctx, filteredDocs, nResults, func(doc *Document) (float32, error) {
+			return similarity(queryEmbedding, doc.Embedding)
+		})
+	} else if !normalized {
+		// Early abandoning and the Matryoshka search rely on normalized
+		// embeddings, so the plain dot product is used.
+		nMaxDocs, err = getMostSimilarDocsFunc(ctx, filteredDocs, nResults, func(doc *Document) (float32, error) {
+			return dotProduct(queryEmbedding, doc.Embedding)
+		})
+	} else if c.matryoshka.enabled(len(queryEmbedding)) {
+		nMaxDocs, err = c.matryoshka.getMostSimilarDocs(ctx, queryEmbedding, filteredDocs, nResults)
+	} else {
+		nMaxDocs, err = getMostSimilarDocs(ctx, queryEmbedding, filteredDocs, nResults)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
 	}
 
-	res := make([]Result, 0, nResults)
-	for i := 0; i < nResults; i++ {
-		res = append(res, Result{
-			ID:         nMaxDocs[i].docID,
-			Metadata:   c.documents[nMaxDocs[i].docID].Metadata,
-			Embedding:  c.documents[nMaxDocs[i].docID].Embedding,
-			Content:    c.documents[nMaxDocs[i].docID].Content,
-			Similarity: nMaxDocs[i].similarity,
-		})
+	// The filters might have left fewer than nResults documents.
+	res, err := c.docSimsToResults(nMaxDocs, withContent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Results without content can't serve queries that include it.
+	if cache && withContent {
+		c.queryCache.put(cacheKey, c.generation, res)
 	}
 
 	// Return the top nResults
 	return res, nil
 }
 
+// SetQueryCacheSize enables an LRU cache for query results with the given
+// maximum number of entries. This is useful for read-heavy workloads where
+// identical queries repeat, like suggested questions in a chat UI.
+// Entries are keyed by the query embedding, nResults, the filters and the
+// excluded IDs, and the whole cache is invalidated whenever documents are added
+// or deleted.
+// A size of 0 disables the cache, which is the default.
+func (c *Collection) SetQueryCacheSize(size int) error {
+	if size < 0 {
+		return errors.New("query cache size must be >= 0")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	if size == 0 {
+		c.queryCache = nil
+	} else {
+		c.queryCache = newQueryCache(size)
+	}
+	return nil
+}
+
+// invalidateQueryCache must be called on each write while holding the documents
+// write lock.
+func (c *Collection) invalidateQueryCache() {
+	c.generation++
+	if c.queryCache != nil {
+		c.queryCache.purge()
+	}
+}
+
+// ErrEmbeddingModelMismatch is returned when the embedding model of a query or
+// document doesn't match the model of the collection's documents.
+var ErrEmbeddingModelMismatch = errors.New("embedding model mismatch")
+
+// EmbeddingModel returns the name of the model of the collection's embedding
+// function, as set via [Collection.SetEmbeddingModel] or [Collection.Reembed].
+// It's empty if unknown.
+func (c *Collection) EmbeddingModel() string {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.embeddingModel
+}
+
+// SetEmbeddingModel sets the name of the model that the collection's embedding
+// function uses, for example "text-embedding-3-small". It's persisted if the DB
+// is persistent.
+//
+// Documents embedded by the collection from then on record the model name.
+// When querying by text and any of the documents was embedded with a different
+// model, the query fails with [ErrEmbeddingModelMismatch] instead of returning
+// garbage results, for example after loading a persistent DB with a different
+// embedding function than before. Documents with precomputed embeddings of a
+// different model are rejected as well. Use [Collection.Reembed] to switch models.
+//
+// Setting an empty model name disables the checks.
+func (c *Collection) SetEmbeddingModel(model string) error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	c.embeddingModel = model
+	c.countModelMismatches()
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+
+	return nil
+}
+
+// checkEmbeddingModel returns an error if any document was embedded with another
+// model than the collection's one. Documents with unknown model are ignored.
+func (c *Collection) checkEmbeddingModel() error {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.modelMismatches > 0 {
+		return fmt.Errorf("%w: collection uses '%s', but %d documents were embedded with another model", ErrEmbeddingModelMismatch, c.embeddingModel, c.modelMismatches)
+	}
+
+	return nil
+}
+
+// mismatchesModel returns whether the document was embedded with another model
+// than the collection's one. Must be called while holding documentsLock.
+func (c *Collection) mismatchesModel(doc *Document) bool {
+	return doc != nil && c.embeddingModel != "" && doc.EmbeddingModel != "" && doc.EmbeddingModel != c.embeddingModel
+}
+
+// trackEmbeddingModel updates the number of documents with another model for a
+// document that replaces an existing one. Either can be nil for an added or
+// deleted document. Must be called while holding the documents write lock.
+func (c *Collection) trackEmbeddingModel(existing, doc *Document) {
+	if c.mismatchesModel(existing) {
+		c.modelMismatches--
+	}
+	if c.mismatchesModel(doc) {
+		c.modelMismatches++
+	}
+}
+
+// countModelMismatches counts the documents with another model, after the
+// collection's model or all documents changed. Must be called while holding the
+// documents write lock, or before the collection is shared.
+func (c *Collection) countModelMismatches() {
+	c.modelMismatches = 0
+	if c.embeddingModel == "" {
+		return
+	}
+	for _, doc := range c.documents {
+		if c.mismatchesModel(doc) {
+			c.modelMismatches++
+		}
+	}
+}
+
+// getEmbed returns the collection's embedding function. It can be swapped by
+// [Collection.Reembed], so we must not access it without lock.
+func (c *Collection) getEmbed() EmbeddingFunc {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.embed
+}
+
+// getEmbedAndModel is like getEmbed, but also returns the model name. Both are
+// read under the same lock so they're consistent.
+func (c *Collection) getEmbedAndModel() (EmbeddingFunc, string) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.embed, c.embeddingModel
+}
+
 // getDocPath generates the path to the document file.
 func (c *Collection) getDocPath(docID string) string {
-	safeID := hash2hex(docID)
-	docPath := filepath.Join(c.persistDirectory, safeID)
-	docPath += ".gob"
-	if c.compress {
-		docPath += ".gz"
-	}
-	return docPath
+	safeID := persistedName(docID, c.readableNames)
+	return filepath.Join(c.persistDirectory, safeID+c.fileExt())
 }
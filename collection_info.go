@@ -0,0 +1,178 @@
+package chromem
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// First persistence format version with the document statistics in the
+// collection metadata, see [migrateDocumentStats].
+const documentStatsFormatVersion = 2
+
+// CollectionInfo describes a collection or namespace without its documents.
+type CollectionInfo struct {
+	Name           string
+	Metadata       map[string]string
+	EmbeddingModel string
+	// DocumentCount is the number of documents, not including the documents of
+	// namespaces.
+	DocumentCount int
+	// Dimensions is the number of dimensions of the embeddings, or of the
+	// first added embedding if they differ. It's 0 without documents.
+	Dimensions int
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+}
+
+// Info returns the collection's name, metadata and document statistics.
+func (c *Collection) Info() CollectionInfo {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return CollectionInfo{
+		Name:           c.Name,
+		Metadata:       maps.Clone(c.metadata),
+		EmbeddingModel: c.embeddingModel,
+		DocumentCount:  len(c.documents),
+		Dimensions:     c.dimensions(),
+		CreatedAt:      c.createdAt,
+		ModifiedAt:     c.modifiedAt,
+	}
+}
+
+// NamespaceInfos returns the infos of the collection's namespaces, sorted by
+// name. Persisted namespaces that aren't loaded are described by their
+// metadata file, without loading their documents. Like [Collection.ModifiedAt],
+// their document statistics might be outdated after a crash.
+func (c *Collection) NamespaceInfos() ([]CollectionInfo, error) {
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	res := make([]CollectionInfo, 0, len(c.namespaces))
+	for _, ns := range c.namespaces {
+		res = append(res, ns.Info())
+	}
+
+	if c.persistDirectory != "" {
+		infos, err := readCollectionInfos(filepath.Join(c.persistDirectory, namespacesDirName), c.compress, c.encoding)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read namespace infos: %w", err)
+		}
+		for _, info := range infos {
+			if _, ok := c.namespaces[info.Name]; !ok {
+				res = append(res, info)
+			}
+		}
+	}
+
+	slices.SortFunc(res, func(a, b CollectionInfo) int { return cmp.Compare(a.Name, b.Name) })
+	return res, nil
+}
+
+// ReadCollectionInfos returns the infos of the collections of a persistent DB,
+// sorted by name, without opening the DB. Only the collections' metadata files
+// are read, not their documents, so it's fast even for large DBs, e.g. to show
+// an overview or decide which DB to open. Like [Collection.ModifiedAt], the
+// document statistics might be outdated after a crash, until the DB is opened
+// and flushed again. Collections that were persisted by older versions of
+// chromem-go and not opened since are counted from their files.
+//
+//   - path: The path of the DB's directory.
+//   - options: Must match the options the DB was created with, but only the
+//     encoding and compression are used.
+func ReadCollectionInfos(path string, options PersistentDBOptions) ([]CollectionInfo, error) {
+	return readCollectionInfos(path, options.Compress, options.Encoding)
+}
+
+// readCollectionInfos returns the infos of the collection directories in the
+// directory, sorted by name. Directories without metadata file are skipped.
+func readCollectionInfos(path string, compress bool, encoding Encoding) ([]CollectionInfo, error) {
+	dirEntries, err := os.ReadDir(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("couldn't read directory: %w", err)
+	}
+
+	var res []CollectionInfo
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		collectionPath := filepath.Join(path, dirEntry.Name())
+		pc := persistedCollectionMetadata{}
+		err := readFromFile(filepath.Join(collectionPath, metadataFileName+fileExt(encoding, compress)), &pc, encoding, "")
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Likely a user-added directory
+				continue
+			}
+			return nil, fmt.Errorf("couldn't read collection metadata: %w", err)
+		}
+		if pc.FormatVersion > formatVersion() {
+			return nil, fmt.Errorf("collection '%s' has persistence format version %d, but only versions up to %d are supported", pc.Name, pc.FormatVersion, formatVersion())
+		}
+		// The files aren't migrated here, as this only reads.
+		if pc.FormatVersion < documentStatsFormatVersion {
+			pc.DocumentCount, pc.Dimensions, err = readDocumentStats(collectionPath, compress, encoding)
+			if err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, CollectionInfo{
+			Name:           pc.Name,
+			Metadata:       pc.Metadata,
+			EmbeddingModel: pc.EmbeddingModel,
+			DocumentCount:  pc.DocumentCount,
+			Dimensions:     pc.Dimensions,
+			CreatedAt:      pc.CreatedAt,
+			ModifiedAt:     pc.ModifiedAt,
+		})
+	}
+
+	slices.SortFunc(res, func(a, b CollectionInfo) int { return cmp.Compare(a.Name, b.Name) })
+	return res, nil
+}
+
+// readDocumentStats counts the document files of the collection directory and
+// reads the dimensions from the first one with an embedding.
+func readDocumentStats(collectionPath string, compress bool, encoding Encoding) (count, dims int, err error) {
+	dirEntries, err := os.ReadDir(collectionPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't read collection directory: %w", err)
+	}
+	ext := fileExt(encoding, compress)
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), ext) || dirEntry.Name() == metadataFileName+ext {
+			continue
+		}
+		count++
+		if dims != 0 {
+			continue
+		}
+		doc := &Document{}
+		err := readFromFile(filepath.Join(collectionPath, dirEntry.Name()), doc, encoding, "")
+		if err != nil {
+			return 0, 0, fmt.Errorf("couldn't read document: %w", err)
+		}
+		dims = len(doc.Embedding)
+	}
+	return count, dims, nil
+}
+
+// dimensions returns the number of dimensions of the collection's embeddings,
+// or 0 if it has no documents. Must be called while holding documentsLock.
+func (c *Collection) dimensions() int {
+	if len(c.documents) == 0 {
+		return 0
+	}
+	return c.column.dims
+}
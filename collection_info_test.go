@@ -0,0 +1,143 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCollectionInfos(t *testing.T) {
+	ctx := context.Background()
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("b", map[string]string{"foo": "bar"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = db.CreateCollection("a", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0, 0}},
+		{ID: "2", Embedding: []float32{0, 1, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	info := c.Info()
+	if info.Name != "b" || info.DocumentCount != 2 || info.Dimensions != 3 || info.Metadata["foo"] != "bar" {
+		t.Fatal("expected b with 2 documents of 3 dimensions, got", info)
+	}
+
+	// The statistics are persisted with the flush.
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	infos, err := ReadCollectionInfos(path, PersistentDBOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "a" || infos[0].DocumentCount != 0 || infos[0].Dimensions != 0 {
+		t.Fatal("expected empty collection a first, got", infos)
+	}
+	if infos[1].Name != "b" || infos[1].DocumentCount != 2 || infos[1].Dimensions != 3 || infos[1].ModifiedAt != info.ModifiedAt {
+		t.Fatal("expected", info, "got", infos[1])
+	}
+
+	// Metadata files of older versions without statistics are counted from
+	// the documents.
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName+".gob")
+	pc := persistedCollectionMetadata{}
+	err = readFromFile(metadataPath, &pc, nil, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	pc.FormatVersion = 1
+	pc.DocumentCount, pc.Dimensions = 0, 0
+	err = persistToFile(metadataPath, pc, nil, false, "", filePerms{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	infos, err = ReadCollectionInfos(path, PersistentDBOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if infos[1].DocumentCount != 2 || infos[1].Dimensions != 3 {
+		t.Fatal("expected 2 documents of 3 dimensions, got", infos[1])
+	}
+
+	// Opening the DB migrates the metadata file.
+	_, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	pc = persistedCollectionMetadata{}
+	err = readFromFile(metadataPath, &pc, nil, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if pc.DocumentCount != 2 || pc.Dimensions != 3 {
+		t.Fatal("expected 2 documents of 3 dimensions, got", pc.DocumentCount, pc.Dimensions)
+	}
+}
+
+func TestCollection_NamespaceInfos(t *testing.T) {
+	ctx := context.Background()
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, name := range []string{"bob", "alice"} {
+		ns, err := c.Namespace(name)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = ns.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = ns.Flush(ctx)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	// A persisted namespace that isn't loaded
+	err = c.EvictNamespace("bob")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	infos, err := c.NamespaceInfos()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "alice" || infos[1].Name != "bob" {
+		t.Fatal("expected alice and bob, got", infos)
+	}
+	for _, info := range infos {
+		if info.DocumentCount != 1 || info.Dimensions != 2 {
+			t.Fatal("expected 1 document of 2 dimensions, got", info)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package chromem
 import (
 	"context"
 	"errors"
+	"io/fs"
 	"math/rand"
 	"os"
 	"slices"
@@ -304,7 +305,7 @@ func TestCollection_AddConcurrently_Error(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 	// Bad concurrency
-	err = c.AddConcurrently(ctx, ids, embeddings, metadatas, contents, 0)
+	err = c.AddConcurrently(ctx, ids, embeddings, metadatas, contents, -1)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -375,7 +376,23 @@ func TestCollection_QueryError(t *testing.T) {
 				_, err := c.Query(context.Background(), "foo", 1, nil, map[string]string{"invalid": "foo"})
 				return err
 			},
-			expErr: "unsupported operator",
+			expErr: "unsupported operator 'invalid'",
+		},
+		{
+			name: "Bad content filter option",
+			query: func() error {
+				_, err := c.Query(context.Background(), "foo", 1, nil, map[string]string{"$contains": "foo", "$options": "fuzzy"})
+				return err
+			},
+			expErr: "unsupported whereDocument option 'fuzzy'",
+		},
+		{
+			name: "Bad regex",
+			query: func() error {
+				_, err := c.Query(context.Background(), "foo", 1, nil, map[string]string{"$regex": "("})
+				return err
+			},
+			expErr: "invalid $regex pattern '(': error parsing regexp: missing closing ): `(`",
 		},
 	}
 
@@ -461,11 +478,13 @@ func TestCollection_Delete(t *testing.T) {
 		t.Fatal("expected 4 documents, got", c.Count())
 	}
 
-	// Check number of files in the persist directory
+	// Check number of files in the persist directory, without the change log
+	// directory
 	d, err := os.ReadDir(c.persistDirectory)
 	if err != nil {
 		t.Fatal("expected nil, got", err)
 	}
+	d = slices.DeleteFunc(d, fs.DirEntry.IsDir)
 	if len(d) != 5 { // 4 documents + 1 metadata file
 		t.Fatal("expected 4 document files + 1 metadata file in persist_dir, got", len(d))
 	}
@@ -481,6 +500,7 @@ func TestCollection_Delete(t *testing.T) {
 		if err != nil {
 			t.Fatal("expected nil, got", err)
 		}
+		d = slices.DeleteFunc(d, fs.DirEntry.IsDir)
 		if len(d) != expected+1 { // 3 document + 1 metadata file
 			t.Fatalf("expected %d document files + 1 metadata file in persist_dir, got %d", expected, len(d))
 		}
@@ -513,6 +533,270 @@ func TestCollection_Delete(t *testing.T) {
 // Global var for assignment in the benchmark to avoid compiler optimizations.
 var globalRes []Result
 
+func TestCollection_EmbeddingModel(t *testing.T) {
+	ctx := context.Background()
+	vectors := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return vectors, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Without model, nothing is recorded or checked
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "hello world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.documents["1"].EmbeddingModel != "" {
+		t.Fatal("expected empty model, got", c.documents["1"].EmbeddingModel)
+	}
+
+	// With model
+	err = c.SetEmbeddingModel("model-a")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "hallo welt"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.documents["2"].EmbeddingModel != "model-a" {
+		t.Fatal("expected model-a, got", c.documents["2"].EmbeddingModel)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Precomputed embeddings of another model are rejected
+	err = c.AddDocument(ctx, Document{ID: "3", Embedding: vectors, EmbeddingModel: "model-b"})
+	if !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Fatal("expected ErrEmbeddingModelMismatch, got", err)
+	}
+
+	// Queries with another model are rejected
+	err = c.SetEmbeddingModel("model-b")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Fatal("expected ErrEmbeddingModelMismatch, got", err)
+	}
+
+	// Until the document of the other model is replaced or deleted
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "hallo welt"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetEmbeddingModel("model-a")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Fatal("expected ErrEmbeddingModelMismatch, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
+
+func TestCollection_QueryWithOptions(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	queryEmbeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if text != "hello" {
+			t.Fatal("expected hello, got", text)
+		}
+		return []float32{0, 1}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}},
+		{ID: "2", Embedding: []float32{0, 1}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name    string
+		options QueryOptions
+		want    string
+	}{
+		{
+			name:    "Collection's embedding func",
+			options: QueryOptions{QueryText: "hello", NResults: 1},
+			want:    "1",
+		},
+		{
+			name:    "Overridden embedding func",
+			options: QueryOptions{QueryText: "hello", NResults: 1, EmbeddingFunc: queryEmbeddingFunc},
+			want:    "2",
+		},
+		{
+			name:    "Precomputed embedding",
+			options: QueryOptions{QueryText: "ignored", QueryEmbedding: []float32{0, 1}, NResults: 1, EmbeddingFunc: queryEmbeddingFunc},
+			want:    "2",
+		},
+		{
+			name:    "Excluded IDs",
+			options: QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1, ExcludeIDs: []string{"1"}},
+			want:    "2",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := c.QueryWithOptions(ctx, tc.options)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if res[0].ID != tc.want {
+				t.Fatal("expected", tc.want, "got", res[0].ID)
+			}
+		})
+	}
+
+	_, err = c.QueryWithOptions(ctx, QueryOptions{NResults: 1})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_UpsertDocument(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Must not exist yet
+	err = c.UpsertDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "v1"}, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.UpsertDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "v1"}, 0)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatal("expected ErrVersionConflict, got", err)
+	}
+
+	// Two editors read the same version
+	doc1, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc2, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc1.Version != 1 {
+		t.Fatal("expected version 1, got", doc1.Version)
+	}
+
+	doc1.Content = "edited by 1"
+	err = c.UpsertDocument(ctx, doc1, doc1.Version)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc2.Content = "edited by 2"
+	err = c.UpsertDocument(ctx, doc2, doc2.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatal("expected ErrVersionConflict, got", err)
+	}
+
+	// Unconditional writes still increment the version
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "v3"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Version != 3 || doc.Content != "v3" {
+		t.Fatal("expected version 3 with content v3, got", doc.Version, doc.Content)
+	}
+
+	_, err = c.GetByID(ctx, "2")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_UpsertDocument_Deleted(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "old"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// An editor reads the document, which is then deleted and added again.
+	old, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The version isn't reused, also not after loading the DB again.
+	db, err = NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "new"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	old.Content = "edited"
+	err = c.UpsertDocument(ctx, old, old.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatal("expected ErrVersionConflict, got", err)
+	}
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "new" || doc.Version <= old.Version {
+		t.Fatal("expected the new document with a higher version, got", doc)
+	}
+}
+
 func BenchmarkCollection_Query_NoContent_100(b *testing.B) {
 	benchmarkCollection_Query(b, 100, false)
 }
@@ -553,9 +837,61 @@ func BenchmarkCollection_Query_100000(b *testing.B) {
 	benchmarkCollection_Query(b, 100_000, true)
 }
 
+// The contents are compressed in memory, so that reading them is expensive,
+// and only the IDs and metadata are included in the results of the second one.
+func BenchmarkCollection_Query_Compressed_25000(b *testing.B) {
+	benchmarkCollection_QueryCompressed(b, 25000, nil)
+}
+
+func BenchmarkCollection_Query_Compressed_ExcludeContent_25000(b *testing.B) {
+	benchmarkCollection_QueryCompressed(b, 25000, []IncludeField{IncludeMetadata})
+}
+
+func benchmarkCollection_QueryCompressed(b *testing.B, n int, include []IncludeField) {
+	ctx := context.Background()
+	c, qv := newBenchmarkCollection(b, n, true)
+	err := c.SetContentCompression(64)
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+	options := QueryOptions{QueryEmbedding: qv, NResults: 10, Include: include}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	var res []Result
+	for i := 0; i < b.N; i++ {
+		res, err = c.QueryWithOptions(ctx, options)
+	}
+	if err != nil {
+		b.Fatal("expected nil, got", err)
+	}
+	globalRes = res
+}
+
 // n is number of documents in the collection
 func benchmarkCollection_Query(b *testing.B, n int, withContent bool) {
 	ctx := context.Background()
+	c, qv := newBenchmarkCollection(b, n, withContent)
+
+	b.ResetTimer()
+
+	// Query
+	var res []Result
+	var err error
+	for i := 0; i < b.N; i++ {
+		res, err = c.QueryEmbedding(ctx, qv, 10, nil, nil)
+	}
+	if err != nil {
+		b.Fatal("expected nil, got", err)
+	}
+	globalRes = res
+}
+
+// newBenchmarkCollection returns a collection with n random documents and a
+// random query vector.
+func newBenchmarkCollection(b *testing.B, n int, withContent bool) (*Collection, []float32) {
+	ctx := context.Background()
 
 	// Seed to make deterministic
 	r := rand.New(rand.NewSource(42))
@@ -606,18 +942,7 @@ func benchmarkCollection_Query(b *testing.B, n int, withContent bool) {
 		}
 		c.AddDocument(ctx, doc)
 	}
-
-	b.ResetTimer()
-
-	// Query
-	var res []Result
-	for i := 0; i < b.N; i++ {
-		res, err = c.QueryEmbedding(ctx, qv, 10, nil, nil)
-	}
-	if err != nil {
-		b.Fatal("expected nil, got", err)
-	}
-	globalRes = res
+	return c, qv
 }
 
 // randomString returns a random string of length n using lowercase letters and space.
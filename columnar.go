@@ -0,0 +1,168 @@
+package chromem
+
+import "sync/atomic"
+
+// Initial capacity of an embedding column, in rows.
+const minColumnRows = 64
+
+// embeddingColumn stores the embeddings of a collection's documents in one
+// contiguous slice, one row per document, with an index from document ID to row.
+// The documents' Embedding fields are sub-slices of it. This way a brute-force
+// scan in row order reads memory sequentially, instead of following a pointer to
+// a separate allocation per document, which is much more cache friendly.
+//
+// Rows are append-only and never modified after they're written, because
+// results, events and replaced documents can still reference them. Replacing or
+// deleting a document only marks its row as dead. When the slice is full, the
+// live rows are copied to a new slice and the documents are replaced by copies
+// that point to it (the same way projections and re-embedding replace them).
+//
+// Documents with a different number of dimensions than the first document
+// keep their own embedding slice and aren't part of the column.
+//
+// All methods must be called while holding the collection's documents write
+// lock, except docs, which only needs the read lock.
+type embeddingColumn struct {
+	dims int
+	data []float32
+	// The documents by row, nil for dead rows.
+	rows []*Document
+	// The row per document ID, only for live rows.
+	index map[string]int
+	// The result of docs, so that queries don't allocate and fill a slice of
+	// all documents each time. It's computed by the first call to docs after a
+	// change, which might be concurrent, as it only requires the read lock.
+	snapshot atomic.Pointer[[]*Document]
+}
+
+// put adds the document's embedding to the column, if it has the column's
+// dimensions, and returns the document to store, which might be a copy that
+// points to the column. The documents map is needed to replace the documents
+// when the column is reallocated.
+func (ec *embeddingColumn) put(documents map[string]*Document, doc *Document) *Document {
+	// The document is new or replaced, even if it's not part of the column.
+	ec.snapshot.Store(nil)
+	ec.remove(doc.ID)
+	if len(doc.Embedding) == 0 {
+		return doc
+	}
+	if ec.dims == 0 {
+		ec.dims = len(doc.Embedding)
+	}
+	if len(doc.Embedding) != ec.dims {
+		return doc
+	}
+
+	if len(ec.data)+ec.dims > cap(ec.data) {
+		ec.reallocate(documents, len(ec.index)+1)
+	}
+	start := len(ec.data)
+	ec.data = append(ec.data, doc.Embedding...)
+	// Limit the capacity, so that appending to the embedding can't overwrite the
+	// next row.
+	newDoc := *doc
+	newDoc.Embedding = ec.data[start:len(ec.data):len(ec.data)]
+	if ec.index == nil {
+		ec.index = make(map[string]int)
+	}
+	ec.index[doc.ID] = len(ec.rows)
+	ec.rows = append(ec.rows, &newDoc)
+	return &newDoc
+}
+
+// remove marks the document's row as dead, if it has one.
+func (ec *embeddingColumn) remove(id string) {
+	// The document might not be part of the column, but it's removed from the
+	// documents anyway.
+	ec.snapshot.Store(nil)
+	row, ok := ec.index[id]
+	if !ok {
+		return
+	}
+	ec.rows[row] = nil
+	delete(ec.index, id)
+}
+
+// replace points the document's row to the given document, which must be a
+// copy of the document in the row with the same embedding, e.g. after evicting
+// its content.
+func (ec *embeddingColumn) replace(doc *Document) {
+	ec.snapshot.Store(nil)
+	if row, ok := ec.index[doc.ID]; ok {
+		ec.rows[row] = doc
+	}
+}
+
+// reallocate copies the live rows to a new slice with room for twice the given
+// number of rows, and replaces the documents in the map with copies that point
+// to it.
+func (ec *embeddingColumn) reallocate(documents map[string]*Document, liveRows int) {
+	capacity := max(2*liveRows, minColumnRows)
+	data := make([]float32, 0, capacity*ec.dims)
+	rows := make([]*Document, 0, capacity)
+	for _, doc := range ec.rows {
+		if doc == nil {
+			continue
+		}
+		start := len(data)
+		data = append(data, doc.Embedding...)
+		newDoc := *doc
+		newDoc.Embedding = data[start:len(data):len(data)]
+		ec.index[doc.ID] = len(rows)
+		rows = append(rows, &newDoc)
+		documents[doc.ID] = &newDoc
+	}
+	ec.data = data
+	ec.rows = rows
+	ec.snapshot.Store(nil)
+}
+
+// rebuild replaces the column with one containing the embeddings of all given
+// documents, and replaces the documents in the map with copies that point to it.
+// It's used after bulk changes, like loading or re-embedding a collection.
+func (ec *embeddingColumn) rebuild(documents map[string]*Document) {
+	ec.snapshot.Store(nil)
+	ec.dims, ec.data, ec.rows, ec.index = 0, nil, nil, nil
+	for _, doc := range documents {
+		if ec.dims == 0 && len(doc.Embedding) > 0 {
+			ec.dims = len(doc.Embedding)
+		}
+	}
+	if ec.dims == 0 {
+		return
+	}
+
+	ec.data = make([]float32, 0, max(len(documents), minColumnRows)*ec.dims)
+	ec.rows = make([]*Document, 0, max(len(documents), minColumnRows))
+	ec.index = make(map[string]int, len(documents))
+	for _, doc := range documents {
+		documents[doc.ID] = ec.put(documents, doc)
+	}
+}
+
+// docs returns the documents in the order of their rows, followed by the
+// documents that aren't part of the column, for scans over all documents. The
+// slice is shared by all callers until the next change, so it must not be
+// modified.
+func (ec *embeddingColumn) docs(documents map[string]*Document) []*Document {
+	if snapshot := ec.snapshot.Load(); snapshot != nil {
+		return *snapshot
+	}
+	docs := make([]*Document, 0, len(documents))
+	for _, doc := range ec.rows {
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) != len(documents) {
+		for id, doc := range documents {
+			if _, ok := ec.index[id]; !ok {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	// Appending to the shared slice must not write to its backing array.
+	docs = docs[:len(docs):len(docs)]
+	ec.snapshot.Store(&docs)
+	return docs
+}
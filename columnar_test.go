@@ -0,0 +1,112 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestEmbeddingColumn(t *testing.T) {
+	documents := make(map[string]*Document)
+	var ec embeddingColumn
+	put := func(id string, embedding []float32) {
+		documents[id] = ec.put(documents, &Document{ID: id, Embedding: embedding})
+	}
+
+	// Enough documents to reallocate
+	for i := 0; i < minColumnRows+10; i++ {
+		put(strconv.Itoa(i), []float32{float32(i), 1})
+	}
+	// Different dimensions aren't part of the column
+	put("other", []float32{1, 2, 3})
+
+	// Keep a reference to check that rows aren't modified
+	old := documents["5"].Embedding
+
+	put("5", []float32{-5, -1})
+	delete(documents, "6")
+	ec.remove("6")
+
+	if !slices.Equal(old, []float32{5, 1}) {
+		t.Fatal("expected old embedding to be unchanged, got", old)
+	}
+	for id, doc := range documents {
+		if id == "other" {
+			continue
+		}
+		i, _ := strconv.Atoi(id)
+		want := []float32{float32(i), 1}
+		if id == "5" {
+			want = []float32{-5, -1}
+		}
+		if !slices.Equal(doc.Embedding, want) {
+			t.Fatal("expected", want, "got", doc.Embedding)
+		}
+		if cap(doc.Embedding) != 2 {
+			t.Fatal("expected capacity to be limited, got", cap(doc.Embedding))
+		}
+	}
+
+	docs := ec.docs(documents)
+	if len(docs) != len(documents) {
+		t.Fatal("expected", len(documents), "documents, got", len(docs))
+	}
+	if docs[len(docs)-1].ID != "other" {
+		t.Fatal("expected document outside the column last, got", docs[len(docs)-1].ID)
+	}
+	for i := 1; i < len(docs)-1; i++ {
+		if ec.index[docs[i].ID] <= ec.index[docs[i-1].ID] {
+			t.Fatal("expected documents in row order")
+		}
+	}
+
+	// Rebuild
+	ec.rebuild(documents)
+	if len(ec.index) != len(documents)-1 {
+		t.Fatal("expected", len(documents)-1, "rows, got", len(ec.index))
+	}
+	if !slices.Equal(documents["5"].Embedding, []float32{-5, -1}) {
+		t.Fatal("expected embedding to be kept, got", documents["5"].Embedding)
+	}
+}
+
+func TestCollection_Columnar(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Add, replace and delete documents concurrently with queries, so that the
+	// race detector can find unsafe accesses to the column.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_, _ = c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, nil)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i % 50), Embedding: []float32{float32(i), 1}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if i%3 == 0 {
+			err = c.Delete(ctx, nil, nil, strconv.Itoa(i%50))
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+	}
+	<-done
+
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// The most similar document is the one with the largest first dimension.
+	if res[0].ID != "49" {
+		t.Fatal("expected document 49, got", res[0].ID)
+	}
+}
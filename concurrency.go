@@ -0,0 +1,213 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults of the automatic concurrency, see [ConcurrencyDefaults].
+const (
+	autoConcurrencyInitial = 4
+	autoConcurrencyMax     = 64
+	// Number of times a document is retried after a rate limit response.
+	autoConcurrencyMaxRetries = 5
+	// Wait time before retrying after a rate limit response that doesn't say
+	// how long to wait.
+	autoConcurrencyRetryWait = time.Second
+	// Latency factor over the lowest observed latency from which the provider
+	// is considered saturated, so the concurrency isn't increased anymore.
+	autoConcurrencyLatencyFactor = 2
+)
+
+// ConcurrencyDefaults configures the concurrency of adding documents when 0 is
+// passed as concurrency, e.g. to [Collection.AddDocuments], see
+// [DB.SetConcurrencyDefaults].
+//
+// By default, the concurrency is automatic: It starts at Initial and adapts to
+// the embedding provider with additive increase, multiplicative decrease
+// (AIMD), like TCP congestion control. Each successfully added document
+// increases the concurrency by 1/concurrency, so roughly by 1 per "round",
+// unless the latency rose to more than twice the lowest observed latency,
+// which indicates that the provider is saturated. A [RateLimitError] halves
+// the concurrency, and the document is retried after the wait time the API
+// asks for, up to 5 times. Other errors cancel the operation like with a fixed
+// concurrency.
+type ConcurrencyDefaults struct {
+	// Concurrency is a fixed concurrency. Optional, 0 means automatic.
+	Concurrency int
+	// Initial is the initial automatic concurrency. Optional, defaults to 4.
+	Initial int
+	// Max is the maximum automatic concurrency. Optional, defaults to 64.
+	Max int
+}
+
+// concurrencyConfig holds the [ConcurrencyDefaults] of a DB. It's shared with
+// the DB's collections, so that changes apply to all of them.
+type concurrencyConfig struct {
+	lock     sync.RWMutex
+	defaults ConcurrencyDefaults
+}
+
+// get returns the defaults. A nil config returns the zero value.
+func (cc *concurrencyConfig) get() ConcurrencyDefaults {
+	if cc == nil {
+		return ConcurrencyDefaults{}
+	}
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	return cc.defaults
+}
+
+// SetConcurrencyDefaults sets the concurrency of adding documents to the DB's
+// collections when 0 is passed as concurrency. The defaults aren't persisted.
+func (db *DB) SetConcurrencyDefaults(defaults ConcurrencyDefaults) error {
+	if defaults.Concurrency < 0 || defaults.Initial < 0 || defaults.Max < 0 {
+		return errors.New("concurrency defaults must be >= 0")
+	}
+	if defaults.Max > 0 && defaults.Initial > defaults.Max {
+		return errors.New("initial concurrency must be <= the maximum")
+	}
+
+	db.concurrency.lock.Lock()
+	defer db.concurrency.lock.Unlock()
+	db.concurrency.defaults = defaults
+	return nil
+}
+
+// ConcurrencyDefaults returns the DB's concurrency defaults, see
+// [DB.SetConcurrencyDefaults].
+func (db *DB) ConcurrencyDefaults() ConcurrencyDefaults {
+	return db.concurrency.get()
+}
+
+// resolveConcurrency validates the concurrency passed by the caller and
+// replaces 0 by the default. It returns a limiter if the concurrency is
+// automatic.
+func (c *Collection) resolveConcurrency(concurrency int) (int, *aimdLimiter, error) {
+	if concurrency < 0 {
+		return 0, nil, errors.New("concurrency must be >= 0")
+	}
+	if concurrency > 0 {
+		return concurrency, nil, nil
+	}
+	defaults := c.concurrency.get()
+	if defaults.Concurrency > 0 {
+		return defaults.Concurrency, nil, nil
+	}
+	initial, maxConcurrency := defaults.Initial, defaults.Max
+	if maxConcurrency == 0 {
+		maxConcurrency = max(autoConcurrencyMax, initial)
+	}
+	if initial == 0 {
+		initial = min(autoConcurrencyInitial, maxConcurrency)
+	}
+	return 0, newAIMDLimiter(initial, maxConcurrency), nil
+}
+
+// addDocumentAdaptive adds the document when the limiter allows it, and
+// retries it after rate limit responses.
+func (c *Collection) addDocumentAdaptive(ctx context.Context, doc Document, limiter *aimdLimiter) error {
+	for retries := 0; ; retries++ {
+		err := limiter.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		err = c.AddDocument(ctx, doc)
+		limiter.release(time.Since(start), err)
+
+		var rlErr *RateLimitError
+		if err == nil || !errors.As(err, &rlErr) || retries >= autoConcurrencyMaxRetries {
+			return err
+		}
+		wait := rlErr.RetryAfter
+		if wait <= 0 {
+			wait = autoConcurrencyRetryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// aimdLimiter limits the number of concurrent operations to a limit that's
+// adapted to the operations' latency and rate limit errors, see
+// [ConcurrencyDefaults].
+type aimdLimiter struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	// limit is fractional so that it can be increased by 1/limit.
+	limit        float64
+	max          float64
+	inFlight     int
+	minLatency   time.Duration
+	lastDecrease time.Time
+}
+
+func newAIMDLimiter(initial, maxLimit int) *aimdLimiter {
+	l := &aimdLimiter{
+		limit: float64(initial),
+		max:   float64(maxLimit),
+	}
+	l.cond = sync.NewCond(&l.lock)
+	return l
+}
+
+// acquire waits until fewer operations than the limit are running, or the
+// context is done.
+func (l *aimdLimiter) acquire(ctx context.Context) error {
+	// Wake up the waiters when the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.cond.Broadcast()
+	})
+	defer stop()
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for l.inFlight >= int(l.limit) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		l.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	l.inFlight++
+	return nil
+}
+
+// release records the result of an operation and adapts the limit.
+func (l *aimdLimiter) release(latency time.Duration, err error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.inFlight--
+
+	var rlErr *RateLimitError
+	switch {
+	case errors.As(err, &rlErr):
+		// Concurrent operations are likely rate limited as well, so the limit
+		// is only halved once per latency.
+		if time.Since(l.lastDecrease) > l.minLatency {
+			l.limit = max(1, l.limit/2)
+			l.lastDecrease = time.Now()
+		}
+	case err == nil:
+		if l.minLatency == 0 || latency < l.minLatency {
+			l.minLatency = latency
+		}
+		if latency <= autoConcurrencyLatencyFactor*l.minLatency {
+			l.limit = min(l.max, l.limit+1/l.limit)
+		}
+	}
+	l.cond.Broadcast()
+}
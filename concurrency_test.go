@@ -0,0 +1,125 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCollection_AddDocuments_AutoConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	// The provider rate limits more than 6 concurrent requests.
+	const providerLimit = 6
+	lock := sync.Mutex{}
+	inFlight, maxInFlight, rateLimited := 0, 0, 0
+	embed := func(_ context.Context, _ string) ([]float32, error) {
+		lock.Lock()
+		inFlight++
+		if inFlight > providerLimit {
+			inFlight--
+			rateLimited++
+			lock.Unlock()
+			return nil, &RateLimitError{Status: "429 Too Many Requests", RetryAfter: time.Millisecond}
+		}
+		maxInFlight = max(maxInFlight, inFlight)
+		lock.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return []float32{1, 0}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embed)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := make([]Document, 300)
+	for i := range docs {
+		docs[i] = Document{ID: strconv.Itoa(i), Content: "hello"}
+	}
+
+	// The rate limited documents are retried.
+	err = c.AddDocuments(ctx, docs, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != len(docs) {
+		t.Fatal("expected", len(docs), "documents, got", c.Count())
+	}
+	// The concurrency was increased from the initial 4 up to the rate limit.
+	if maxInFlight <= autoConcurrencyInitial {
+		t.Fatal("expected more than", autoConcurrencyInitial, "concurrent requests, got", maxInFlight)
+	}
+
+	// DB-level fixed default
+	err = db.SetConcurrencyDefaults(ConcurrencyDefaults{Concurrency: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	maxInFlight, rateLimited = 0, 0
+	err = c.AddDocuments(ctx, docs, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if maxInFlight > 2 || rateLimited != 0 {
+		t.Fatal("expected at most 2 concurrent requests without rate limits, got", maxInFlight, rateLimited)
+	}
+
+	err = db.SetConcurrencyDefaults(ConcurrencyDefaults{Initial: 8, Max: 4})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.AddDocuments(ctx, docs, -1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestAIMDLimiter(t *testing.T) {
+	l := newAIMDLimiter(2, 3)
+
+	// Additive increase by 1/limit per success, up to the maximum
+	l.release(10*time.Millisecond, nil)
+	if l.limit != 2.5 {
+		t.Fatal("expected 2.5, got", l.limit)
+	}
+	for i := 0; i < 10; i++ {
+		l.release(10*time.Millisecond, nil)
+	}
+	if l.limit != 3 {
+		t.Fatal("expected 3, got", l.limit)
+	}
+
+	// Multiplicative decrease on rate limits
+	l.limit = 2
+	l.release(0, &RateLimitError{})
+	if l.limit != 1 {
+		t.Fatal("expected 1, got", l.limit)
+	}
+
+	// No increase when the latency rises
+	l.release(time.Second, nil)
+	if l.limit != 1 {
+		t.Fatal("expected 1, got", l.limit)
+	}
+
+	// Acquiring waits for the limit and the context.
+	l.inFlight = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := l.acquire(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = l.acquire(ctx)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
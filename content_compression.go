@@ -0,0 +1,405 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Estimated memory overhead of a compressed content entry, for its ID in the
+// block and the map entry of its location.
+const compressedEntryOverhead = 2*mapEntryOverhead + 8
+
+// compressedContents keeps the contents of documents compressed in memory, in
+// blocks of multiple contents, see [Collection.SetContentCompression].
+// Modifications must only happen while holding the collection's documents
+// write lock, reads need the read lock.
+type compressedContents struct {
+	blockSize int
+	blocks    map[int]*contentBlock
+	nextBlock int
+	// The block that new contents are added to. Its contents are kept
+	// uncompressed until it's full.
+	open    *contentBlock
+	openKey int
+	// Block and position per document ID
+	locs  map[string]contentLoc
+	codec Codec
+
+	// The most recently decompressed block, as consecutive reads are often from
+	// the same block, e.g. when filtering by content. Reads happen concurrently
+	// while holding the read lock, so it has its own lock.
+	cacheLock  sync.Mutex
+	cacheKey   int
+	cacheBlock *contentBlock
+	cache      string
+}
+
+type contentBlock struct {
+	// Document IDs by position, empty for removed contents
+	ids  []string
+	live int
+	// Uncompressed contents while the block is open
+	pending []string
+	// Compressed concatenated contents and the end offset of each content once
+	// the block is sealed
+	data []byte
+	ends []int
+}
+
+type contentLoc struct {
+	block int
+	index int
+}
+
+func newCompressedContents(blockSize int, codec Codec) *compressedContents {
+	return &compressedContents{
+		blockSize: blockSize,
+		blocks:    make(map[int]*contentBlock),
+		locs:      make(map[string]contentLoc),
+		codec:     codec,
+	}
+}
+
+// add stores the document's content, replacing its previous one.
+func (cc *compressedContents) add(id, content string) error {
+	cc.remove(id)
+	if cc.open == nil {
+		cc.open = &contentBlock{}
+		cc.openKey = cc.nextBlock
+		cc.blocks[cc.openKey] = cc.open
+		cc.nextBlock++
+	}
+	b := cc.open
+	b.ids = append(b.ids, id)
+	b.pending = append(b.pending, content)
+	b.live++
+	cc.locs[id] = contentLoc{block: cc.openKey, index: len(b.ids) - 1}
+	if len(b.ids) < cc.blockSize {
+		return nil
+	}
+	cc.open = nil
+	return cc.seal(b)
+}
+
+// seal compresses the pending contents of the block.
+func (cc *compressedContents) seal(b *contentBlock) error {
+	size := 0
+	for _, content := range b.pending {
+		size += len(content)
+	}
+	text := make([]byte, 0, size)
+	ends := make([]int, len(b.pending))
+	for i, content := range b.pending {
+		text = append(text, content...)
+		ends[i] = len(text)
+	}
+	data, err := cc.codec.Compress(nil, text)
+	if err != nil {
+		return fmt.Errorf("couldn't compress content: %w", err)
+	}
+	b.data, b.ends = data, ends
+	b.pending = nil
+	return nil
+}
+
+// get returns the document's content, and whether it's stored.
+func (cc *compressedContents) get(id string) (string, bool, error) {
+	loc, ok := cc.locs[id]
+	if !ok {
+		return "", false, nil
+	}
+	b := cc.blocks[loc.block]
+	if b.pending != nil {
+		return b.pending[loc.index], true, nil
+	}
+	text, err := cc.decompress(loc.block, b)
+	if err != nil {
+		return "", true, err
+	}
+	start := 0
+	if loc.index > 0 {
+		start = b.ends[loc.index-1]
+	}
+	// Cloned so that the content doesn't keep the whole block in memory.
+	return strings.Clone(text[start:b.ends[loc.index]]), true, nil
+}
+
+// decompress returns the concatenated contents of the sealed block.
+func (cc *compressedContents) decompress(key int, b *contentBlock) (string, error) {
+	cc.cacheLock.Lock()
+	defer cc.cacheLock.Unlock()
+	if cc.cacheBlock == b && cc.cacheKey == key {
+		return cc.cache, nil
+	}
+	text, err := cc.codec.Decompress(make([]byte, 0, b.ends[len(b.ends)-1]), b.data)
+	if err != nil {
+		return "", fmt.Errorf("couldn't decompress content: %w", err)
+	}
+	cc.cacheKey, cc.cacheBlock, cc.cache = key, b, string(text)
+	return cc.cache, nil
+}
+
+// remove removes the document's content, if it's stored. Blocks without
+// contents are removed, and blocks where most contents were removed are
+// compacted by adding their remaining contents again. It's a no-op on nil
+// compressed contents.
+func (cc *compressedContents) remove(id string) {
+	if cc == nil {
+		return
+	}
+	loc, ok := cc.locs[id]
+	if !ok {
+		return
+	}
+	delete(cc.locs, id)
+	b := cc.blocks[loc.block]
+	b.ids[loc.index] = ""
+	b.live--
+	if b.pending != nil {
+		b.pending[loc.index] = ""
+		if b.live == 0 {
+			delete(cc.blocks, loc.block)
+			cc.open = nil
+		}
+		return
+	}
+	if b.live > 0 && 2*b.live >= len(b.ids) {
+		return
+	}
+	var contents map[string]string
+	if b.live > 0 {
+		text, err := cc.decompress(loc.block, b)
+		if err != nil {
+			// Keep the block, its other contents might still be readable.
+			return
+		}
+		contents = make(map[string]string, b.live)
+		start := 0
+		for i, id := range b.ids {
+			if id != "" {
+				contents[id] = strings.Clone(text[start:b.ends[i]])
+			}
+			start = b.ends[i]
+		}
+	}
+	delete(cc.blocks, loc.block)
+	for id, content := range contents {
+		delete(cc.locs, id)
+		// Only sealing a full block can fail, and only if compression fails,
+		// in which case the contents are still kept uncompressed.
+		_ = cc.add(id, content)
+	}
+}
+
+// memoryUsage estimates the memory usage of the compressed contents in bytes.
+func (cc *compressedContents) memoryUsage() int64 {
+	var usage int64
+	for _, b := range cc.blocks {
+		usage += int64(len(b.data) + 8*len(b.ends))
+		for _, content := range b.pending {
+			usage += int64(len(content))
+		}
+	}
+	for id := range cc.locs {
+		usage += int64(2*len(id) + compressedEntryOverhead)
+	}
+	return usage
+}
+
+// SetContentCompression keeps the contents of the collection's documents
+// compressed in memory, in blocks of blockSize documents, and decompresses a
+// block when one of its contents is needed, e.g. for query results or content
+// filters. This trades CPU time for a large reduction of the memory usage of
+// text-heavy corpora. Larger blocks compress better, but reading a content is
+// slower, as the whole block has to be decompressed, e.g. 32 to 128 documents.
+// The contents of the most recently filled block are kept uncompressed until
+// the block is full.
+//
+// The contents are compressed with DEFLATE, which is part of Go's standard
+// library, see [Collection.SetContentCompressionWithCodec] for other codecs
+// like zstd. Documents whose content is in the content store (see
+// [Collection.EnableContentStore]) or evicted from memory (see
+// [DB.SetMemoryBudget]) aren't compressed again. The files of a persistent DB
+// aren't affected, see [NewPersistentDB] for compressing them.
+//
+// The existing documents are compressed as well. A blockSize of 0 disables the
+// compression and decompresses the contents again. The setting is persisted if
+// the DB is persistent, and the contents are compressed again when the DB is
+// loaded.
+func (c *Collection) SetContentCompression(blockSize int) error {
+	return c.SetContentCompressionWithCodec(blockSize, "")
+}
+
+// SetContentCompressionWithCodec is like [Collection.SetContentCompression],
+// but compresses the blocks with the codec of the given name, either
+// [CodecDeflate] or one registered via [RegisterCodec], e.g. zstd from the
+// separate module github.com/philippgille/chromem-go/zstd, which compresses
+// better and decompresses faster. An empty name is DEFLATE. The codec's name is
+// persisted with the collection.
+func (c *Collection) SetContentCompressionWithCodec(blockSize int, codec string) error {
+	if blockSize < 0 {
+		return errors.New("blockSize must be >= 0")
+	}
+	cd, err := lookupCodec(codec)
+	if err != nil {
+		return err
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.compressed != nil && c.compressed.blockSize == blockSize && c.compressed.codec.Name() == cd.Name() {
+		return nil
+	}
+	err = c.setContentCompression(blockSize, cd)
+	if err != nil {
+		return err
+	}
+	c.invalidateQueryCache()
+
+	err = c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// ContentCompression returns the block size of the content compression, or 0
+// if it's disabled, see [Collection.SetContentCompression].
+func (c *Collection) ContentCompression() int {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.contentCompression()
+}
+
+// ContentCompressionCodec returns the name of the codec of the content
+// compression, or "" if it's disabled, see
+// [Collection.SetContentCompressionWithCodec].
+func (c *Collection) ContentCompressionCodec() string {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.contentCodec()
+}
+
+// setContentCompression decompresses the contents and compresses them again
+// with the block size and codec, or disables the compression if the block size
+// is 0. Must be called while holding the documents write lock, or before the
+// collection is shared.
+func (c *Collection) setContentCompression(blockSize int, codec Codec) error {
+	// Decompress the contents first, also to compress them with the new block
+	// size.
+	if c.compressed != nil {
+		for id, doc := range c.documents {
+			content, ok, err := c.compressed.get(id)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			// We replace the document instead of modifying it, because the old
+			// one might still be referenced, e.g. by cached query results.
+			newDoc := *doc
+			newDoc.Content = content
+			c.documents[id] = &newDoc
+			c.column.replace(&newDoc)
+			c.memory.adjust(int64(len(content)))
+		}
+		c.compressed = nil
+	}
+	if blockSize > 0 {
+		return c.compressContents(blockSize, codec)
+	}
+	return nil
+}
+
+// compressContents enables the content compression and compresses the
+// contents of the documents. Must be called while holding the documents write
+// lock, or before the collection is shared.
+func (c *Collection) compressContents(blockSize int, codec Codec) error {
+	c.compressed = newCompressedContents(blockSize, codec)
+	for id, doc := range c.documents {
+		if !compressible(doc) {
+			continue
+		}
+		if _, ok := c.evicted[id]; ok {
+			continue
+		}
+		// We replace the document instead of modifying it, because the old one
+		// might still be referenced, e.g. by cached query results.
+		newDoc := *doc
+		err := c.compressContent(&newDoc)
+		if err != nil {
+			return err
+		}
+		c.documents[id] = &newDoc
+		c.column.replace(&newDoc)
+		c.memory.adjust(-int64(len(doc.Content)))
+	}
+	return nil
+}
+
+// compressContent moves the content of the document, which mustn't be shared
+// yet, to the compressed contents, if the compression is enabled. Must be
+// called while holding the documents write lock.
+func (c *Collection) compressContent(doc *Document) error {
+	if c.compressed == nil {
+		return nil
+	}
+	if !compressible(doc) {
+		c.compressed.remove(doc.ID)
+		return nil
+	}
+	err := c.compressed.add(doc.ID, doc.Content)
+	if err != nil {
+		return err
+	}
+	doc.Content = ""
+	return nil
+}
+
+// compressible returns whether compressing the document's content frees
+// memory. Content in the content store is shared and isn't compressed.
+func compressible(doc *Document) bool {
+	return doc.Content != "" && doc.ContentHash == ""
+}
+
+// decompressed returns the document with its content if it's compressed. Must
+// be called while holding documentsLock.
+func (c *Collection) decompressed(doc *Document) (*Document, error) {
+	if c.compressed == nil {
+		return doc, nil
+	}
+	content, ok, err := c.compressed.get(doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decompress content of document '%s': %w", doc.ID, err)
+	}
+	if !ok {
+		return doc, nil
+	}
+	res := *doc
+	res.Content = content
+	return &res, nil
+}
+
+// contentCompression returns the block size of the content compression, or 0
+// if it's disabled. Must be called while holding documentsLock.
+func (c *Collection) contentCompression() int {
+	if c.compressed == nil {
+		return 0
+	}
+	return c.compressed.blockSize
+}
+
+// contentCodec returns the name of the codec of the content compression, or ""
+// if it's disabled. Must be called while holding documentsLock.
+func (c *Collection) contentCodec() string {
+	if c.compressed == nil {
+		return ""
+	}
+	return c.compressed.codec.Name()
+}
@@ -0,0 +1,241 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompressedContents(t *testing.T) {
+	cc := newCompressedContents(3, deflateCodec{})
+	for i := 0; i < 7; i++ {
+		err := cc.add(strconv.Itoa(i), strings.Repeat(strconv.Itoa(i), i+1))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	// Two sealed blocks and the open one
+	if len(cc.blocks) != 3 || cc.blocks[0].data == nil || cc.blocks[2].pending == nil {
+		t.Fatal("expected two sealed blocks and an open one, got", cc.blocks)
+	}
+	check := func(ids ...int) {
+		t.Helper()
+		for _, i := range ids {
+			content, ok, err := cc.get(strconv.Itoa(i))
+			if err != nil || !ok || content != strings.Repeat(strconv.Itoa(i), i+1) {
+				t.Fatal("expected content of", i, "got", content, ok, err)
+			}
+		}
+	}
+	check(0, 1, 2, 3, 4, 5, 6)
+
+	// Removing most contents of a block compacts it
+	cc.remove("0")
+	cc.remove("1")
+	if _, ok := cc.blocks[0]; ok {
+		t.Fatal("expected the first block to be compacted")
+	}
+	if _, ok, _ := cc.get("0"); ok {
+		t.Fatal("expected removed content to be gone")
+	}
+	check(2, 3, 4, 5, 6)
+	// Replacing a content
+	err := cc.add("3", "33")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if content, _, _ := cc.get("3"); content != "33" {
+		t.Fatal("expected replaced content, got", content)
+	}
+}
+
+func TestCollection_SetContentCompression(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	content := func(i int) string {
+		return strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) + strconv.Itoa(i)
+	}
+	var docs []Document
+	for i := 0; i < 10; i++ {
+		docs = append(docs, Document{ID: strconv.Itoa(i), Content: content(i), Embedding: []float32{1, float32(i) / 10}})
+	}
+	err = c.AddDocuments(ctx, docs[:5], 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	uncompressed := c.MemoryUsage().Content
+
+	// Existing and new documents are compressed.
+	err = c.SetContentCompression(4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, docs[5:], 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if usage := c.MemoryUsage().Content; usage >= uncompressed {
+		t.Fatal("expected less content memory than", uncompressed, "for twice the documents, got", usage)
+	}
+	if c.documents["0"].Content != "" {
+		t.Fatal("expected compressed content not to be in the document")
+	}
+
+	check := func(c *Collection) {
+		t.Helper()
+		doc, err := c.GetByID(ctx, "7")
+		if err != nil || doc.Content != content(7) {
+			t.Fatal("expected content of document 7, got", doc.Content, err)
+		}
+		res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, map[string]string{"$contains": "dog. 3"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != "3" || res[0].Content != content(3) {
+			t.Fatal("expected document 3, got", res)
+		}
+	}
+	check(c)
+
+	// Replaced and deleted documents
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "replaced", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil || doc.Content != "replaced" {
+		t.Fatal("expected replaced content, got", doc.Content, err)
+	}
+	if _, ok := c.compressed.locs["2"]; ok {
+		t.Fatal("expected content of deleted document to be removed")
+	}
+
+	// The setting is persisted and the contents are compressed when loading.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.ContentCompression() != 4 || c2.documents["7"].Content != "" {
+		t.Fatal("expected compressed contents after loading, got", c2.ContentCompression())
+	}
+	check(c2)
+
+	// Disabling decompresses the contents.
+	err = c.SetContentCompression(0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.documents["7"].Content != content(7) {
+		t.Fatal("expected decompressed content, got", c.documents["7"].Content)
+	}
+	check(c)
+
+	err = c.SetContentCompression(-1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// countingCodec is DEFLATE that counts the compressed blocks.
+type countingCodec struct {
+	deflateCodec
+	blocks *atomic.Int32
+}
+
+func (countingCodec) Name() string { return "test-counting" }
+
+func (c countingCodec) Compress(dst, src []byte) ([]byte, error) {
+	c.blocks.Add(1)
+	return c.deflateCodec.Compress(dst, src)
+}
+
+func TestCollection_SetContentCompressionWithCodec(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 4; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Content: "content " + strconv.Itoa(i), Embedding: []float32{1, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	err = c.SetContentCompressionWithCodec(2, "test-counting")
+	if err == nil {
+		t.Fatal("expected error for unregistered codec, got nil")
+	}
+	codec := countingCodec{blocks: &atomic.Int32{}}
+	err = RegisterCodec(codec)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = RegisterCodec(codec)
+	if err == nil {
+		t.Fatal("expected error for registering twice, got nil")
+	}
+	err = c.SetContentCompressionWithCodec(2, "test-counting")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n := codec.blocks.Load(); n != 2 {
+		t.Fatal("expected 2 compressed blocks, got", n)
+	}
+	doc, err := c.GetByID(ctx, "3")
+	if err != nil || doc.Content != "content 3" {
+		t.Fatal("expected content of document 3, got", doc.Content, err)
+	}
+
+	// The codec is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.ContentCompressionCodec() != "test-counting" || codec.blocks.Load() != 4 {
+		t.Fatal("expected contents compressed with the codec after loading, got", c2.ContentCompressionCodec())
+	}
+	doc, err = c2.GetByID(ctx, "1")
+	if err != nil || doc.Content != "content 1" {
+		t.Fatal("expected content of document 1, got", doc.Content, err)
+	}
+
+	// The default is DEFLATE.
+	err = c.SetContentCompression(2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.ContentCompressionCodec() != CodecDeflate {
+		t.Fatal("expected deflate, got", c.ContentCompressionCodec())
+	}
+}
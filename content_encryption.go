@@ -0,0 +1,265 @@
+package chromem
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrContentEncrypted is returned when the content of a document is needed, but
+// it's encrypted and the collection doesn't have the key, for example after
+// loading a persistent DB. See [Collection.SetContentEncryption].
+var ErrContentEncrypted = errors.New("document content is encrypted and the collection has no key")
+
+// SetContentEncryption encrypts the content of the collection's documents with
+// AES-GCM and the given key, independently of the encryption of exports. The
+// embeddings and metadata aren't encrypted, so queries and metadata filters work
+// as usual.
+//
+// The content is encrypted on disk (if the DB is persistent) and in exports. If
+// inMemory is true, it's also kept encrypted in memory and only decrypted for
+// query results, [Collection.GetByID] and content filters, which makes those
+// slower. Existing documents are converted when calling this method, and it can
+// be called again with a new key to rotate the key. If converting a document
+// fails, e.g. because of a wrong key, the collection and its files are left
+// unchanged.
+//
+// The key isn't persisted. After loading a persistent DB or importing it, call
+// this method with the same key again, otherwise reading the content fails with
+// [ErrContentEncrypted]. The content encryption can't be combined with the
+// content store (see [Collection.EnableContentStore]).
+//
+//   - key: The encryption key, must be 32 bytes long.
+//   - inMemory: Whether to keep the content encrypted in memory as well.
+func (c *Collection) SetContentEncryption(key string, inMemory bool) error {
+	// AES 256 requires a 32 byte key
+	if len(key) != 32 {
+		return errors.New("encryption key must be 32 bytes long")
+	}
+	aead, err := newContentCipher(key)
+	if err != nil {
+		return err
+	}
+
+	c.persistLock.RLock()
+	defer c.persistLock.RUnlock()
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.contents != nil {
+		return errors.New("content encryption can't be combined with the content store")
+	}
+
+	// First build all new documents and write their files to temporary paths,
+	// so that an error, e.g. a wrong key, doesn't leave the collection half
+	// converted.
+	oldKey := c.contentKey
+	if oldKey == nil {
+		// After loading, the content can only be decrypted with the given key.
+		oldKey = aead
+	}
+	// All contents change, so compressed ones are compressed into a new store,
+	// which replaces the old one together with the documents.
+	var compressed *compressedContents
+	if c.compressed != nil {
+		compressed = newCompressedContents(c.compressed.blockSize, c.compressed.codec)
+	}
+	newDocs := make(map[string]*Document, len(c.documents))
+	files := documentFiles{c: c}
+	for id, doc := range c.documents {
+		full, err := c.withContent(doc)
+		if err != nil {
+			files.discard()
+			return err
+		}
+		plain := full
+		if c.contentEncryptedInMemory {
+			plain, err = cryptTexts(full, func(s string) (string, error) { return decryptContent(oldKey, id, s) })
+			if err != nil {
+				files.discard()
+				return fmt.Errorf("couldn't decrypt content of document '%s', wrong key?: %w", id, err)
+			}
+		}
+		encrypted, err := cryptTexts(plain, func(s string) (string, error) { return encryptContent(aead, id, s) })
+		if err != nil {
+			files.discard()
+			return err
+		}
+		// Evicted documents stay evicted, only their files are rewritten.
+		if _, ok := c.evicted[id]; !ok {
+			// We replace the document instead of modifying it, because the old
+			// one might still be referenced, e.g. by cached query results.
+			newDoc := *doc
+			newDoc.Content, newDoc.EmbeddingText = plain.Content, plain.EmbeddingText
+			if inMemory {
+				newDoc.Content, newDoc.EmbeddingText = encrypted.Content, encrypted.EmbeddingText
+			}
+			if compressed != nil && compressible(&newDoc) {
+				err := compressed.add(id, newDoc.Content)
+				if err != nil {
+					files.discard()
+					return err
+				}
+				newDoc.Content = ""
+			}
+			newDocs[id] = &newDoc
+		}
+		if c.persistDirectory != "" {
+			diskDoc := *full
+			diskDoc.Content, diskDoc.EmbeddingText = encrypted.Content, encrypted.EmbeddingText
+			err := files.write(&diskDoc)
+			if err != nil {
+				files.discard()
+				return err
+			}
+		}
+	}
+
+	// Replacing a file can't fail half way through it. If it fails for a file,
+	// the remaining files keep the old key, but the collection is switched to
+	// the new key anyway, so that calling this method again with the new key
+	// rewrites them.
+	commitErr := files.commit()
+	if commitErr != nil {
+		commitErr = fmt.Errorf("call SetContentEncryption with the new key again: %w", commitErr)
+	}
+
+	// Swap the documents, their compressed contents and the key together.
+	for id, newDoc := range newDocs {
+		if c.memory != nil {
+			c.memory.adjust(documentMemory(newDoc).Content - documentMemory(c.documents[id]).Content)
+		}
+		c.documents[id] = newDoc
+		c.column.replace(newDoc)
+	}
+	if compressed != nil {
+		c.compressed = compressed
+	}
+	c.contentKey = aead
+	c.contentEncrypted = true
+	c.contentEncryptedInMemory = inMemory
+	c.invalidateQueryCache()
+
+	err = c.persistMetadata()
+	if err != nil {
+		return errors.Join(commitErr, fmt.Errorf("couldn't persist collection metadata: %w", err))
+	}
+	return commitErr
+}
+
+// readable returns the document with its content in plaintext, reading evicted
+// content from disk and decrypting encrypted content. Must be called while
+// holding documentsLock.
+func (c *Collection) readable(doc *Document) (*Document, error) {
+	doc, err := c.withContent(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !c.contentEncryptedInMemory || doc.Content == "" {
+		return doc, nil
+	}
+	if c.contentKey == nil {
+		return nil, ErrContentEncrypted
+	}
+	res, err := cryptTexts(doc, func(s string) (string, error) { return decryptContent(c.contentKey, doc.ID, s) })
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt content of document '%s': %w", doc.ID, err)
+	}
+	return res, nil
+}
+
+// encryptedForDisk returns the document as it's written to disk and exports,
+// with encrypted content if the content encryption is enabled, but the content
+// is kept in plaintext in memory. Must be called while holding documentsLock.
+func (c *Collection) encryptedForDisk(doc *Document) (*Document, error) {
+	if !c.contentEncrypted || c.contentEncryptedInMemory || doc.Content == "" {
+		return doc, nil
+	}
+	return cryptTexts(doc, func(s string) (string, error) { return encryptContent(c.contentKey, doc.ID, s) })
+}
+
+// decryptedFromDisk is the opposite of encryptedForDisk, for documents read from
+// disk. Must be called while holding documentsLock.
+func (c *Collection) decryptedFromDisk(doc *Document) (*Document, error) {
+	if !c.contentEncrypted || c.contentEncryptedInMemory || doc.Content == "" {
+		return doc, nil
+	}
+	res, err := cryptTexts(doc, func(s string) (string, error) { return decryptContent(c.contentKey, doc.ID, s) })
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt content of document '%s': %w", doc.ID, err)
+	}
+	return res, nil
+}
+
+func newContentCipher(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create new AES cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create GCM wrapper: %w", err)
+	}
+	return gcm, nil
+}
+
+// cryptTexts returns a copy of the document with its content and embedding text
+// encrypted or decrypted by crypt. The embedding text contains the content, so
+// it's encrypted along with it.
+func cryptTexts(doc *Document, crypt func(string) (string, error)) (*Document, error) {
+	res := *doc
+	var err error
+	if doc.Content != "" {
+		res.Content, err = crypt(doc.Content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if doc.EmbeddingText != "" {
+		res.EmbeddingText, err = crypt(doc.EmbeddingText)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &res, nil
+}
+
+// encryptContent encrypts the content with a random nonce, which is prepended
+// to the result. The document ID is authenticated as well, so that the content
+// can't be moved to another document. The result is base64 encoded, so that it's
+// valid UTF-8 for text encodings like JSON.
+func encryptContent(aead cipher.AEAD, docID, content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("couldn't read random bytes for nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(content), []byte(docID))), nil
+}
+
+// decryptContent decrypts content that was encrypted with encryptContent.
+func decryptContent(aead cipher.AEAD, docID, encoded string) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("couldn't decode encrypted content: %w", err)
+	}
+	nonceSize := aead.NonceSize()
+	if len(encrypted) < nonceSize {
+		return "", errors.New("encrypted content is too short")
+	}
+	nonce, ciphertext := encrypted[:nonceSize], encrypted[nonceSize:]
+	content, err := aead.Open(nil, nonce, ciphertext, []byte(docID))
+	if err != nil {
+		return "", fmt.Errorf("couldn't decrypt content: %w", err)
+	}
+	return string(content), nil
+}
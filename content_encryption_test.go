@@ -0,0 +1,242 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCollection_SetContentEncryption(t *testing.T) {
+	ctx := context.Background()
+	key := "0123456789abcdef0123456789abcdef"
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentEncryption("too short", false)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Existing documents are converted
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "secret one", Embedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentEncryption(key, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "secret two", EmbeddingText: "Title\n\nsecret two", Embedding: []float32{0, 1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The content is encrypted on disk
+	for _, id := range []string{"1", "2"} {
+		d := &Document{}
+		err = readFromFile(c.getDocPath(id), d, nil, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if d.Content == "" || strings.Contains(d.Content, "secret") {
+			t.Fatal("expected encrypted content, got", d.Content)
+		}
+		if strings.Contains(d.EmbeddingText, "secret") {
+			t.Fatal("expected encrypted embedding text, got", d.EmbeddingText)
+		}
+	}
+
+	// Queries, including content filters, work on the plaintext
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0, 0}, 2, nil, map[string]string{"$contains": "two"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].Content != "secret two" {
+		t.Fatal("expected document 2 with plaintext content, got", res)
+	}
+
+	// After loading, the content can only be read with the key
+	db2, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	_, err = c2.GetByID(ctx, "1")
+	if !errors.Is(err, ErrContentEncrypted) {
+		t.Fatal("expected ErrContentEncrypted, got", err)
+	}
+	err = c2.SetContentEncryption("fedcba9876543210fedcba9876543210", true)
+	if err == nil {
+		t.Fatal("expected error for wrong key, got nil")
+	}
+	err = c2.SetContentEncryption(key, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c2.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "secret one" {
+		t.Fatal("expected plaintext content, got", doc.Content)
+	}
+	c2.documentsLock.RLock()
+	inMemory := c2.documents["1"].Content
+	c2.documentsLock.RUnlock()
+	if strings.Contains(inMemory, "secret") {
+		t.Fatal("expected encrypted content in memory, got", inMemory)
+	}
+
+	// Exports contain the encrypted content
+	var buf bytes.Buffer
+	err = db.ExportToWriter(&buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret")) {
+		t.Fatal("expected export without plaintext content")
+	}
+	db3 := NewDB()
+	err = db3.ImportFromReader(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c3 := db3.GetCollection("test", nil)
+	_, err = c3.GetByID(ctx, "2")
+	if !errors.Is(err, ErrContentEncrypted) {
+		t.Fatal("expected ErrContentEncrypted, got", err)
+	}
+	err = c3.SetContentEncryption(key, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err = c3.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "secret two" {
+		t.Fatal("expected plaintext content, got", doc.Content)
+	}
+}
+
+func TestCollection_SetContentEncryption_Failure(t *testing.T) {
+	ctx := context.Background()
+	key := "0123456789abcdef0123456789abcdef"
+	newKey := "fedcba9876543210fedcba9876543210"
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// The compressed contents are replaced as well.
+	err = c.SetContentCompression(2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentEncryption(key, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		err = c.AddDocument(ctx, Document{ID: id, Content: "secret " + id, Embedding: []float32{1, 0, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	// Writing the file of one document fails, so rotating the key must not
+	// change anything.
+	err = os.Mkdir(c.getDocPath("2")+".tmp", 0o700)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentEncryption(newKey, true)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = os.RemoveAll(c.getDocPath("2") + ".tmp")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	entries, err := os.ReadDir(c.persistDirectory)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Fatal("expected temporary files to be removed, got", entry.Name())
+		}
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		doc, err := c.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.Content != "secret "+id {
+			t.Fatal("expected plaintext content, got", doc.Content)
+		}
+	}
+
+	// Rotating the key works after the error.
+	err = c.SetContentEncryption(newKey, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		doc, err := c.GetByID(ctx, id)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.Content != "secret "+id {
+			t.Fatal("expected plaintext content, got", doc.Content)
+		}
+	}
+	c.documentsLock.RLock()
+	inMemory := c.documents["1"].Content
+	c.documentsLock.RUnlock()
+	if inMemory != "" {
+		t.Fatal("expected compressed content, got", inMemory)
+	}
+	key = newKey
+
+	// The files can be read with the current key.
+	db2, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	err = c2.SetContentEncryption(key, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c2.GetByID(ctx, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "secret 3" {
+		t.Fatal("expected plaintext content, got", doc.Content)
+	}
+}
@@ -0,0 +1,170 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrContentTooLong is returned when adding a document whose content exceeds
+// the collection's token limit, see [Collection.SetContentLimit].
+var ErrContentTooLong = errors.New("content too long")
+
+// ContentLimit is the limit of the content length of documents that are
+// embedded by the collection, see [Collection.SetContentLimit].
+type ContentLimit struct {
+	// MaxTokens is the maximum number of tokens of a document's content, which
+	// should be the context length of the embedding model, e.g. 8191 for
+	// OpenAI's text-embedding-3 models. 0 disables the limit.
+	MaxTokens int
+	// Split splits documents that exceed the limit into chunks instead of
+	// rejecting them with [ErrContentTooLong].
+	Split bool
+	// Overlap is the number of tokens by which consecutive chunks overlap, see
+	// [NewTextSplitter]. Must be < MaxTokens.
+	Overlap int
+	// CountTokens counts the tokens. Optional, defaults to
+	// [CountTokensApprox]. Approximate counts can be lower than the exact ones,
+	// so leave some headroom in MaxTokens when using it.
+	CountTokens TokenCounter
+}
+
+// SetContentLimit sets a limit for the number of tokens of the content of
+// documents that the collection embeds. Embedding providers often silently
+// truncate texts that exceed the model's context, so that the end of long
+// documents isn't represented by their embedding, which the limit prevents.
+// Documents that are added with their embedding aren't affected.
+//
+// Documents that exceed the limit are rejected with [ErrContentTooLong], or if
+// [ContentLimit.Split] is set, they're split into chunks that are added as
+// separate documents instead of the document. The chunks have the ID of the
+// document followed by "/" and the chunk's index, like "doc-42/0", and the
+// document's metadata. [Filter.IDPrefix] with "doc-42/" matches all chunks of a
+// document. When a document is added again, the chunks that are left over from
+// a previous version are deleted, as is the unsplit previous version, so don't
+// use IDs of that form for other documents.
+//
+// Splitting isn't supported by [Collection.UpsertDocument], which rejects
+// documents that exceed the limit. Hooks are called for each chunk.
+//
+// Like the embedding function, the limit isn't persisted, as the token counter
+// can't be. It's applied to the collection's namespaces as well.
+func (c *Collection) SetContentLimit(limit ContentLimit) error {
+	if limit.MaxTokens < 0 {
+		return errors.New("max tokens must be >= 0")
+	}
+	if limit.CountTokens == nil {
+		limit.CountTokens = CountTokensApprox
+	}
+	var splitter TextSplitter
+	if limit.MaxTokens > 0 && limit.Split {
+		var err error
+		splitter, err = NewTextSplitter(limit.MaxTokens, limit.Overlap, limit.CountTokens)
+		if err != nil {
+			return fmt.Errorf("couldn't create text splitter: %w", err)
+		}
+	}
+
+	c.documentsLock.Lock()
+	c.contentLimit = limit
+	c.contentSplitter = splitter
+	c.documentsLock.Unlock()
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+	for _, ns := range c.namespaces {
+		ns.documentsLock.Lock()
+		ns.contentLimit = limit
+		ns.contentSplitter = splitter
+		ns.documentsLock.Unlock()
+	}
+
+	return nil
+}
+
+// ContentLimit returns the collection's content limit, see
+// [Collection.SetContentLimit].
+func (c *Collection) ContentLimit() ContentLimit {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.contentLimit
+}
+
+// getContentLimit returns the content limit and the splitter of its chunks.
+func (c *Collection) getContentLimit() (ContentLimit, TextSplitter) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.contentLimit, c.contentSplitter
+}
+
+// exceeds returns the number of tokens of the document's content and whether
+// it exceeds the limit.
+func (l ContentLimit) exceeds(doc Document) (int, bool) {
+	if l.MaxTokens == 0 || len(doc.Embedding) != 0 || doc.Content == "" {
+		return 0, false
+	}
+	tokens := l.CountTokens(doc.Content)
+	return tokens, tokens > l.MaxTokens
+}
+
+// addDocumentWithLimit adds the document, or its chunks if it exceeds the
+// content limit, and deletes the leftovers of previous versions.
+func (c *Collection) addDocumentWithLimit(ctx context.Context, doc Document, limit ContentLimit, splitter TextSplitter) error {
+	tokens, exceeds := limit.exceeds(doc)
+	if !exceeds {
+		err := c.addDocument(ctx, doc, nil)
+		if err != nil {
+			return err
+		}
+		return c.deleteStaleChunks(ctx, doc.ID, 0, false)
+	}
+	if splitter == nil {
+		return fmt.Errorf("%w: document '%s' has %d tokens, the limit is %d", ErrContentTooLong, doc.ID, tokens, limit.MaxTokens)
+	}
+
+	chunks := splitter(doc.Content)
+	for i, content := range chunks {
+		chunk := Document{
+			ID:       chunkID(doc.ID, i),
+			Metadata: doc.Metadata,
+			Content:  content,
+		}
+		err := c.addDocument(ctx, chunk, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't add chunk %d of document '%s': %w", i, doc.ID, err)
+		}
+	}
+	return c.deleteStaleChunks(ctx, doc.ID, len(chunks), true)
+}
+
+// deleteStaleChunks deletes the chunks of the document from the index n on,
+// and the unsplit document if it was split.
+func (c *Collection) deleteStaleChunks(ctx context.Context, id string, n int, split bool) error {
+	var ids []string
+	c.documentsLock.RLock()
+	if _, ok := c.documents[id]; ok && split {
+		ids = append(ids, id)
+	}
+	for i := n; ; i++ {
+		if _, ok := c.documents[chunkID(id, i)]; !ok {
+			break
+		}
+		ids = append(ids, chunkID(id, i))
+	}
+	c.documentsLock.RUnlock()
+
+	if len(ids) == 0 {
+		return nil
+	}
+	err := c.Delete(ctx, nil, nil, ids...)
+	if err != nil {
+		return fmt.Errorf("couldn't delete previous version of document '%s': %w", id, err)
+	}
+	return nil
+}
+
+// chunkID returns the ID of the document's chunk with the index.
+func chunkID(id string, i int) string {
+	return id + "/" + strconv.Itoa(i)
+}
@@ -0,0 +1,96 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCollection_SetContentLimit(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	var embedded []string
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{1, 0}, nil
+	}
+	c, err := db.CreateCollection("test", nil, embed)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// One token per word.
+	countWords := func(text string) int { return len(strings.Fields(text)) }
+
+	// Invalid overlap
+	err = c.SetContentLimit(ContentLimit{MaxTokens: 2, Split: true, Overlap: 2})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Reject
+	err = c.SetContentLimit(ContentLimit{MaxTokens: 3, CountTokens: countWords})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "a b c d e"})
+	if !errors.Is(err, ErrContentTooLong) {
+		t.Fatal("expected", ErrContentTooLong, "got", err)
+	}
+	err = c.UpsertDocument(ctx, Document{ID: "1", Content: "a b c d e"}, 0)
+	if !errors.Is(err, ErrContentTooLong) {
+		t.Fatal("expected", ErrContentTooLong, "got", err)
+	}
+	// Documents with embeddings aren't affected.
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "a b c d e", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(embedded) != 0 {
+		t.Fatal("expected no embeddings, got", embedded)
+	}
+
+	// Split, replacing the unsplit document.
+	err = c.SetContentLimit(ContentLimit{MaxTokens: 3, Split: true, CountTokens: countWords})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "a b c d e f g", Metadata: map[string]string{"k": "v"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := []string{"a b c", "d e f", "g"}
+	if !slices.Equal(embedded, exp) {
+		t.Fatal("expected", exp, "got", embedded)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 chunks, got", c.Count())
+	}
+	doc, err := c.GetByID(ctx, "1/2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "g" || doc.Metadata["k"] != "v" {
+		t.Fatal("expected last chunk with metadata, got", doc)
+	}
+
+	// Stale chunks of the previous version are deleted.
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "a b c d"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 2 {
+		t.Fatal("expected 2 chunks, got", c.Count())
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "a"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+	if _, err := c.GetByID(ctx, "1"); err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
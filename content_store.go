@@ -0,0 +1,297 @@
+package chromem
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Name of the subdirectory of a collection's directory with the content files
+// of the content store.
+const contentDirName = "content"
+
+// contentStore stores each distinct document content only once, see
+// [Collection.EnableContentStore]. Must only be accessed while holding the
+// collection's documentsLock.
+type contentStore struct {
+	// Content per content hash
+	blobs map[string]*contentBlob
+	// Directory of the content files, empty for non-persistent collections.
+	dir      string
+	compress bool
+	encoding Encoding
+	perms    filePerms
+}
+
+type contentBlob struct {
+	content string
+	// Number of documents referencing the content. When it's 0, the blob is
+	// kept until removeUnreferenced is called, so that its file isn't removed
+	// while a document file still references it.
+	refs int
+}
+
+func newContentStore(dir string, compress bool, encoding Encoding, perms filePerms) *contentStore {
+	return &contentStore{
+		blobs:    make(map[string]*contentBlob),
+		dir:      dir,
+		compress: compress,
+		encoding: encoding,
+		perms:    perms,
+	}
+}
+
+// EnableContentStore makes the collection store each distinct document content
+// only once, in a content-addressable store, and lets the documents reference it
+// by its SHA-256 hash (see [Document.ContentHash]). This reduces the memory and
+// disk usage for corpora where many documents or chunks share the same content,
+// like repeated boilerplate.
+//
+// In a persistent DB, the contents are written to separate files in the
+// collection's directory, and the document files only contain the reference.
+// Existing documents are converted when the store is enabled. The setting is
+// persisted, and it can't be disabled again. Exports contain the full content
+// of each document, and imported collections don't use a content store.
+func (c *Collection) EnableContentStore() error {
+	c.persistLock.RLock()
+	defer c.persistLock.RUnlock()
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.contents != nil {
+		return nil
+	}
+	if c.contentEncrypted {
+		return errors.New("content store can't be combined with content encryption")
+	}
+
+	var contentDir string
+	if c.persistDirectory != "" {
+		contentDir = filepath.Join(c.persistDirectory, contentDirName)
+	}
+	contents := newContentStore(contentDir, c.compress, c.encoding, c.perms)
+	converted := make(map[string]*Document, len(c.documents))
+	for id, doc := range c.documents {
+		// The content might have been evicted from memory.
+		doc, err := c.withContent(doc)
+		if err != nil {
+			return err
+		}
+		if doc.Content == "" {
+			continue
+		}
+		// We replace the document instead of modifying it, because the old one
+		// might still be referenced, e.g. by cached query results.
+		newDoc := *doc
+		newDoc.Content, newDoc.ContentHash, err = contents.add(doc.Content)
+		if err != nil {
+			return err
+		}
+		converted[id] = &newDoc
+	}
+	for id, doc := range converted {
+		if _, ok := c.evicted[id]; ok {
+			// The content and data are in memory again.
+			c.memory.adjust(int64(len(doc.Content) + len(doc.EmbeddingText) + len(doc.Data)))
+			delete(c.evicted, id)
+		}
+		// The content is shared now, so it's not compressed anymore.
+		c.compressed.remove(id)
+		c.documents[id] = doc
+		c.column.replace(doc)
+	}
+	c.contents = contents
+
+	if c.persistDirectory != "" {
+		for _, doc := range converted {
+			err := c.persistDocument(doc)
+			if err != nil {
+				return err
+			}
+		}
+		err := c.persistMetadata()
+		if err != nil {
+			return fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// add adds a reference to the content and returns the stored content, which
+// is shared by all documents with the same content, and its hash. New contents
+// are persisted.
+func (cs *contentStore) add(content string) (string, string, error) {
+	hash := contentHash(content)
+	if blob, ok := cs.blobs[hash]; ok {
+		blob.refs++
+		return blob.content, hash, nil
+	}
+	if cs.dir != "" {
+		blobPath := cs.path(hash)
+		err := persistToFile(blobPath, content, cs.encoding, cs.compress, "", cs.perms)
+		if err != nil {
+			return "", "", fmt.Errorf("couldn't persist content to %q: %w", blobPath, err)
+		}
+	}
+	cs.blobs[hash] = &contentBlob{content: content, refs: 1}
+	return content, hash, nil
+}
+
+// release removes a reference to the content with the given hash.
+func (cs *contentStore) release(hash string) {
+	if blob, ok := cs.blobs[hash]; ok && blob.refs > 0 {
+		blob.refs--
+	}
+}
+
+// removeUnreferenced removes the content with the given hash, including its
+// file, if no document references it anymore.
+func (cs *contentStore) removeUnreferenced(hash string) error {
+	blob, ok := cs.blobs[hash]
+	if !ok || blob.refs > 0 {
+		return nil
+	}
+	delete(cs.blobs, hash)
+	if cs.dir != "" {
+		blobPath := cs.path(hash)
+		err := removeFile(blobPath)
+		if err != nil {
+			return fmt.Errorf("couldn't remove content at %q: %w", blobPath, err)
+		}
+	}
+	return nil
+}
+
+// load adds a reference to the content with the given hash, reading it from
+// its file if it's not loaded yet. It's used when reading a collection from
+// disk.
+func (cs *contentStore) load(hash string) (string, error) {
+	if blob, ok := cs.blobs[hash]; ok {
+		blob.refs++
+		return blob.content, nil
+	}
+	content, err := readContentFile(cs.path(hash), cs.encoding)
+	if err != nil {
+		return "", err
+	}
+	cs.blobs[hash] = &contentBlob{content: content, refs: 1}
+	return content, nil
+}
+
+func (cs *contentStore) path(hash string) string {
+	return filepath.Join(cs.dir, hash+fileExt(cs.encoding, cs.compress))
+}
+
+// readContentFile reads a content file of a content store.
+func readContentFile(blobPath string, encoding Encoding) (string, error) {
+	var content string
+	err := readFromFile(blobPath, &content, encoding, "")
+	if err != nil {
+		return "", fmt.Errorf("couldn't read content at %q: %w", blobPath, err)
+	}
+	return content, nil
+}
+
+// contentHash returns the hex encoded SHA-256 hash of the content.
+func contentHash(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// persistDocument writes the document's file. Must be called while holding
+// documentsLock.
+func (c *Collection) persistDocument(doc *Document) error {
+	doc, err := c.encryptedForDisk(doc)
+	if err != nil {
+		return err
+	}
+	return c.writeDocument(doc)
+}
+
+// writeDocument writes the document's file as is, except that documents with
+// content in the content store are written without it, as the content has its
+// own file.
+func (c *Collection) writeDocument(doc *Document) error {
+	err := c.writeDocumentFile(doc, c.getDocPath(doc.ID))
+	if err != nil {
+		return err
+	}
+	return c.logChanges(c.documentChange(doc.ID))
+}
+
+// writeDocumentFile writes the document's file to the path, like
+// [Collection.writeDocument], but without logging the change.
+func (c *Collection) writeDocumentFile(doc *Document, docPath string) error {
+	if doc.ContentHash != "" {
+		withoutContent := *doc
+		withoutContent.Content = ""
+		doc = &withoutContent
+	}
+	err := persistToFile(docPath, doc, c.encoding, c.compress, "", c.perms)
+	if err != nil {
+		return fmt.Errorf("couldn't persist document to %q: %w", docPath, err)
+	}
+	return nil
+}
+
+// documentFiles replaces the files of many documents at once, e.g. when all
+// documents are converted. The files are written to temporary paths first, so
+// that an error while writing them leaves the documents' files unchanged, and
+// then moved to the documents' paths. Must be used while holding the documents
+// write lock.
+type documentFiles struct {
+	c *Collection
+	// IDs of the documents whose temporary files were written
+	ids []string
+}
+
+// write writes the document's file to its temporary path. The temporary path
+// doesn't have the extension of the collection's files, so it's ignored when
+// loading the collection after a crash, and removed by [DB.GC].
+func (f *documentFiles) write(doc *Document) error {
+	tmpPath := f.c.getDocPath(doc.ID) + ".tmp"
+	err := f.c.writeDocumentFile(doc, tmpPath)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	f.ids = append(f.ids, doc.ID)
+	return nil
+}
+
+// discard removes the temporary files that weren't moved yet.
+func (f *documentFiles) discard() {
+	for _, id := range f.ids {
+		_ = os.Remove(f.c.getDocPath(id) + ".tmp")
+	}
+	f.ids = nil
+}
+
+// commit moves the temporary files to the documents' paths and logs the
+// changes. Moving a file replaces the document's file atomically, but if it
+// fails, the files that were moved before keep their new content, and the
+// remaining temporary files are removed.
+func (f *documentFiles) commit() error {
+	changes := make([]changeRecord, 0, len(f.ids))
+	var err error
+	for i, id := range f.ids {
+		docPath := f.c.getDocPath(id)
+		err = os.Rename(docPath+".tmp", docPath)
+		if err != nil {
+			f.ids = f.ids[i:]
+			f.discard()
+			err = fmt.Errorf("couldn't replace document file %q: %w", docPath, err)
+			break
+		}
+		changes = append(changes, f.c.documentChange(id))
+	}
+	f.ids = nil
+	return errors.Join(err, f.c.logChanges(changes...))
+}
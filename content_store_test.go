@@ -0,0 +1,116 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_EnableContentStore(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	boilerplate := "This page is part of the documentation."
+
+	// Documents added before the store is enabled are converted
+	err = c.AddDocument(ctx, Document{ID: "0", Content: boilerplate, Embedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.EnableContentStore()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 1; i < 5; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Content: boilerplate, Embedding: []float32{1, float32(i), 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = c.AddDocument(ctx, Document{ID: "unique", Content: "unique content", Embedding: []float32{0, 1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	contentFiles := func() int {
+		t.Helper()
+		entries, err := os.ReadDir(filepath.Join(c.persistDirectory, contentDirName))
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return len(entries)
+	}
+	if contentFiles() != 2 {
+		t.Fatal("expected 2 content files, got", contentFiles())
+	}
+	doc, err := c.GetByID(ctx, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != boilerplate || doc.ContentHash != contentHash(boilerplate) {
+		t.Fatal("expected boilerplate content and its hash, got", doc.Content, doc.ContentHash)
+	}
+	if usage := c.MemoryUsage(); usage.Content != int64(len(boilerplate)+len("unique content")) {
+		t.Fatal("expected content to be counted once, got", usage.Content)
+	}
+
+	// Contents are removed when they're not referenced anymore
+	err = c.AddDocument(ctx, Document{ID: "unique", Content: "changed content", Embedding: []float32{0, 1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "0", "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if contentFiles() != 2 {
+		t.Fatal("expected 2 content files, got", contentFiles())
+	}
+
+	// The contents are loaded with the DB
+	db2, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	res, err := c2.QueryEmbedding(ctx, []float32{1, 0, 0}, 4, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, r := range res {
+		expected := boilerplate
+		if r.ID == "unique" {
+			expected = "changed content"
+		}
+		if r.Content != expected {
+			t.Fatal("expected content", expected, "got", r.Content)
+		}
+	}
+
+	// New documents of the loaded collection use the store as well
+	err = c2.AddDocument(ctx, Document{ID: "5", Content: boilerplate, Embedding: []float32{1, 5, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c2.Delete(ctx, nil, nil, "2", "3", "4", "5")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if contentFiles() != 1 {
+		t.Fatal("expected 1 content file, got", contentFiles())
+	}
+}
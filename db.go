@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 // EmbeddingFunc is a function that creates embeddings for a given text.
@@ -20,6 +21,23 @@ import (
 // others like Nomic's "nomic-embed-text-v1.5" don't.
 type EmbeddingFunc func(ctx context.Context, text string) ([]float32, error)
 
+// EmbeddingFuncBatch is like [EmbeddingFunc], but creates the embeddings of
+// multiple texts at once, e.g. with a single API request, which is faster for
+// bulk ingestion. It returns the embeddings in the order of the texts. Use it
+// to create the embeddings of documents before adding them to a collection.
+type EmbeddingFuncBatch func(ctx context.Context, texts []string) ([][]float32, error)
+
+// ImageEmbeddingFunc is a function that creates embeddings for an image, like
+// the image encoder of a CLIP model. To store images and texts in the same
+// collection and query across them, it must create embeddings in the same
+// vector space as the collection's [EmbeddingFunc], which is the case for the
+// image and text encoders of the same multimodal model.
+// Like [EmbeddingFunc], it must return a *normalized* vector.
+//
+//   - data: The raw image, e.g. the content of a PNG or JPEG file.
+//   - mimeType: The image's MIME type, e.g. "image/png". Optional.
+type ImageEmbeddingFunc func(ctx context.Context, data []byte, mimeType string) ([]float32, error)
+
 // DB is the chromem-go database. It holds collections, which hold documents.
 //
 //	+----+    1-n    +------------+    n-n    +----------+
@@ -28,9 +46,22 @@ type EmbeddingFunc func(ctx context.Context, text string) ([]float32, error)
 type DB struct {
 	collections     map[string]*Collection
 	collectionsLock sync.RWMutex
+	// closed is set by [DB.Close]. Must only be accessed while holding
+	// collectionsLock.
+	closed bool
 
 	persistDirectory string
 	compress         bool
+	perms            filePerms
+	readableNames    bool
+	encoding         Encoding
+
+	// Optional memory budget, see [DB.SetMemoryBudget]. Must only be accessed
+	// while holding collectionsLock.
+	memory *memoryBudget
+	// Concurrency defaults of adding documents, shared with the collections,
+	// see [DB.SetConcurrencyDefaults].
+	concurrency *concurrencyConfig
 
 	// ⚠️ When adding fields here, consider adding them to the persistence struct
 	// versions in [DB.Export] and [DB.Import] as well!
@@ -43,6 +74,7 @@ type DB struct {
 func NewDB() *DB {
 	return &DB{
 		collections: make(map[string]*Collection),
+		concurrency: &concurrencyConfig{},
 	}
 }
 
@@ -57,13 +89,52 @@ func NewDB() *DB {
 // existing collection and adding more documents to it.
 //
 // Currently the persistence is done synchronously on each write operation, and
-// each document addition leads to a new file, encoded as gob. In the future we
-// will make this configurable (encoding, async writes, WAL-based writes, etc.).
+// each document addition leads to a new file, encoded as gob. To use another
+// encoding, see [NewPersistentDBWithOptions].
 //
 // In addition to persistence for each added collection and document you can use
 // [DB.Export] and [DB.Import] to export and import the entire DB to/from a file,
 // which also works for the pure in-memory DB.
 func NewPersistentDB(path string, compress bool) (*DB, error) {
+	return NewPersistentDBWithOptions(path, PersistentDBOptions{Compress: compress})
+}
+
+// PersistentDBOptions are the options for [NewPersistentDBWithOptions].
+type PersistentDBOptions struct {
+	// Compress the files with gzip.
+	Compress bool
+	// Encoding of the files. Optional, defaults to [EncodingGob]. A DB must
+	// be loaded with the same encoding as it was written with, as files with
+	// other extensions are ignored.
+	Encoding Encoding
+	// FileMode is the permission of new files, e.g. 0o640 for group-readable
+	// files on a shared volume. Optional, defaults to 0o666 like [os.Create]
+	// (0o600 for the audit log), restricted by the process's umask.
+	FileMode fs.FileMode
+	// DirMode is the permission of new directories. Optional, defaults to
+	// 0o700. With [fs.ModeSetgid], e.g. 0o2750, new files and directories
+	// belong to the group of their parent directory instead of the process's
+	// group, so that the group of a shared volume is kept.
+	DirMode fs.FileMode
+	// IgnoreUmask sets FileMode and DirMode exactly, regardless of the
+	// process's umask, which otherwise removes permissions, e.g. all group and
+	// other permissions with the common umask 077 of services. Existing files
+	// get FileMode when they're written.
+	IgnoreUmask bool
+	// ReadableNames names the directories and files of new collections,
+	// namespaces and documents after their sanitized names, followed by a
+	// hash of the name to avoid collisions, like "my_docs-1a2b3c4d", instead
+	// of just the hash. This makes the DB's directory easier to inspect.
+	// Existing collections keep their naming scheme. Regardless of this
+	// option, a manifest file in the DB's directory maps the collection
+	// directories to the collection names.
+	ReadableNames bool
+}
+
+// NewPersistentDBWithOptions is like [NewPersistentDB], but with options, for
+// example to encode the files as JSON for debugging, with [EncodingJSON].
+// Exports (see [DB.Export]) are always encoded as gob.
+func NewPersistentDBWithOptions(path string, options PersistentDBOptions) (*DB, error) {
 	if path == "" {
 		path = "./chromem-go"
 	} else {
@@ -71,23 +142,21 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 		path = filepath.Clean(path)
 	}
 
-	// We check for this file extension and skip others
-	ext := ".gob"
-	if compress {
-		ext += ".gz"
-	}
-
 	db := &DB{
 		collections:      make(map[string]*Collection),
 		persistDirectory: path,
-		compress:         compress,
+		compress:         options.Compress,
+		encoding:         options.Encoding,
+		perms:            newFilePerms(options),
+		readableNames:    options.ReadableNames,
+		concurrency:      &concurrencyConfig{},
 	}
 
 	// If the directory doesn't exist, create it and return an empty DB.
 	fi, err := os.Stat(path)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
-			err := os.MkdirAll(path, 0o700)
+			err := db.perms.mkdirAll(path)
 			if err != nil {
 				return nil, fmt.Errorf("couldn't create persistence directory: %w", err)
 			}
@@ -115,52 +184,13 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 		// TODO: Parallelize this (e.g. chan with $numCPU buffer and $numCPU goroutines
 		// reading from it).
 		collectionPath := filepath.Join(path, dirEntry.Name())
-		collectionDirEntries, err := os.ReadDir(collectionPath)
+		err := migrateCollectionDir(collectionPath, options.Compress, options.Encoding, db.perms)
 		if err != nil {
-			return nil, fmt.Errorf("couldn't read collection directory: %w", err)
+			return nil, err
 		}
-		c := &Collection{
-			documents:        make(map[string]*Document),
-			persistDirectory: collectionPath,
-			compress:         compress,
-			// We can fill Name and metadata only after reading
-			// the metadata.
-			// We can fill embed only when the user calls DB.GetCollection() or
-			// DB.GetOrCreateCollection().
-		}
-		for _, collectionDirEntry := range collectionDirEntries {
-			// Files should be metadata and documents; skip subdirectories which
-			// the user might have placed.
-			if collectionDirEntry.IsDir() {
-				continue
-			}
-
-			fPath := filepath.Join(collectionPath, collectionDirEntry.Name())
-			// Differentiate between collection metadata, documents and other files.
-			if collectionDirEntry.Name() == metadataFileName+ext {
-				// Read name and metadata
-				pc := struct {
-					Name     string
-					Metadata map[string]string
-				}{}
-				err := readFromFile(fPath, &pc, "")
-				if err != nil {
-					return nil, fmt.Errorf("couldn't read collection metadata: %w", err)
-				}
-				c.Name = pc.Name
-				c.metadata = pc.Metadata
-			} else if strings.HasSuffix(collectionDirEntry.Name(), ext) {
-				// Read document
-				d := &Document{}
-				err := readFromFile(fPath, d, "")
-				if err != nil {
-					return nil, fmt.Errorf("couldn't read document: %w", err)
-				}
-				c.documents[d.ID] = d
-			} else {
-				// Might be a file that the user has placed
-				continue
-			}
+		c, err := readCollectionDir(collectionPath, options.Compress, options.Encoding, db.perms)
+		if err != nil {
+			return nil, err
 		}
 		// If we have neither name nor documents, it was likely a user-added
 		// directory, so skip it.
@@ -172,12 +202,107 @@ func NewPersistentDB(path string, compress bool) (*DB, error) {
 			return nil, fmt.Errorf("collection metadata file not found: %s", collectionPath)
 		}
 
+		c.concurrency = db.concurrency
 		db.collections[c.Name] = c
 	}
+	err = db.writeManifest()
+	if err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
 
+// readCollectionDir reads a collection's metadata and documents from its
+// directory. If there's no metadata file, the name of the returned collection
+// is empty.
+func readCollectionDir(collectionPath string, compress bool, encoding Encoding, perms filePerms) (*Collection, error) {
+	// We check for this file extension and skip others
+	ext := fileExt(encoding, compress)
+
+	collectionDirEntries, err := os.ReadDir(collectionPath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read collection directory: %w", err)
+	}
+	c := &Collection{
+		persistDirectory: collectionPath,
+		compress:         compress,
+		encoding:         encoding,
+		perms:            perms,
+		// We can fill embed only when the user calls DB.GetCollection() or
+		// DB.GetOrCreateCollection().
+	}
+
+	// Read name and metadata first, so the documents map can be sized by the
+	// document count.
+	pc := persistedCollectionMetadata{}
+	err = readFromFile(filepath.Join(collectionPath, metadataFileName+ext), &pc, encoding, "")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("couldn't read collection metadata: %w", err)
+	}
+	c.Name = pc.Name
+	c.applyPersistedMetadata(pc)
+	if pc.ContentStore {
+		c.contents = newContentStore(filepath.Join(collectionPath, contentDirName), compress, encoding, perms)
+	}
+	c.documents = make(map[string]*Document, pc.DocumentCount)
+
+	for _, collectionDirEntry := range collectionDirEntries {
+		// Files should be metadata and documents; skip subdirectories which
+		// the user might have placed.
+		if collectionDirEntry.IsDir() || collectionDirEntry.Name() == metadataFileName+ext {
+			continue
+		}
+
+		fPath := filepath.Join(collectionPath, collectionDirEntry.Name())
+		// Differentiate between documents and other files.
+		if strings.HasSuffix(collectionDirEntry.Name(), ext) {
+			// Read document
+			d := &Document{}
+			err := readFromFile(fPath, d, encoding, "")
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read document: %w", err)
+			}
+			c.documents[d.ID] = d
+		} else {
+			// Might be a file that the user has placed
+			continue
+		}
+	}
+	if c.contents != nil {
+		for _, d := range c.documents {
+			if d.ContentHash == "" {
+				continue
+			}
+			d.Content, err = c.contents.load(d.ContentHash)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't read content of document '%s': %w", d.ID, err)
+			}
+		}
+	}
+	c.initVersion(pc.LastVersion)
+	c.persistedVersion = pc.LastVersion
+	c.countModelMismatches()
+	c.column.rebuild(c.documents)
+	err = c.applyPersistedIndexes(pc)
+	if err != nil {
+		return nil, err
+	}
+	// The statistics are outdated if documents were written after the last
+	// flush, e.g. before a crash. They're corrected with the next one.
+	if c.Name != "" && (pc.DocumentCount != len(c.documents) || pc.Dimensions != c.dimensions()) {
+		c.modifiedAtStale = true
+	}
+	if c.Name != "" {
+		err = c.changes.open(filepath.Join(collectionPath, changeLogDirName), perms)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open change log: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
 // Import imports the DB from a file at the given path. The file must be encoded
 // as gob and can optionally be compressed with flate (as gzip) and encrypted
 // with AES-GCM.
@@ -225,9 +350,31 @@ func (db *DB) ImportFromFile(filePath string, encryptionKey string) error {
 	// Create persistence structs with exported fields so that they can be decoded
 	// from gob.
 	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+		Name             string
+		Metadata         map[string]string
+		EmbeddingModel   string
+		Projection       *projection
+		MetadataSchema   *MetadataSchema
+		QueryDefaults    QueryDefaults
+		Normalization    NormalizationPolicy
+		Similarity       string
+		Matryoshka       matryoshkaConfig
+		LateInteraction  bool
+		StrictMode       bool
+		CreatedAt        time.Time
+		ModifiedAt       time.Time
+		DiscardContent   bool
+		ContentEncrypted bool
+		// Like in [persistedCollectionMetadata], the indexes are rebuilt from
+		// the documents on import.
+		PhraseIndex        bool
+		MetadataStatsKeys  []string
+		BM25Index          *BM25IndexOptions
+		ContentCompression int
+		ContentCodec       string
+		Shard              *shardInfo
+		LastVersion        uint64
+		Documents          map[string]*Document
 	}
 	persistenceDB := struct {
 		Collections map[string]*persistenceCollection
@@ -237,27 +384,81 @@ func (db *DB) ImportFromFile(filePath string, encryptionKey string) error {
 
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
 
-	err = readFromFile(filePath, &persistenceDB, encryptionKey)
+	err = readFromFile(filePath, &persistenceDB, nil, encryptionKey)
 	if err != nil {
 		return fmt.Errorf("couldn't read file: %w", err)
 	}
 
 	for _, pc := range persistenceDB.Collections {
+		// Imported collections don't have a content store, so the documents
+		// keep their content.
+		for _, d := range pc.Documents {
+			d.ContentHash = ""
+		}
 		c := &Collection{
 			Name: pc.Name,
 
-			metadata:  pc.Metadata,
-			documents: pc.Documents,
+			metadata:        pc.Metadata,
+			embeddingModel:  pc.EmbeddingModel,
+			projection:      pc.Projection,
+			metadataSchema:  pc.MetadataSchema,
+			queryDefaults:   pc.QueryDefaults,
+			normalization:   pc.Normalization,
+			similarity:      pc.Similarity,
+			matryoshka:      pc.Matryoshka,
+			lateInteraction: pc.LateInteraction,
+			strict:          pc.StrictMode,
+			createdAt:       pc.CreatedAt,
+			modifiedAt:      pc.ModifiedAt,
+			discardContent:  pc.DiscardContent,
+			shard:           pc.Shard,
+			documents:       pc.Documents,
+			// The content stays encrypted in memory until the key is set.
+			contentEncrypted:         pc.ContentEncrypted,
+			contentEncryptedInMemory: pc.ContentEncrypted,
+		}
+		c.initVersion(pc.LastVersion)
+		c.countModelMismatches()
+		c.column.rebuild(c.documents)
+		// Before the collection gets its directory, so that the BM25 index is
+		// built from the imported documents instead of being loaded from the
+		// file of an overwritten collection.
+		err = c.applyPersistedIndexes(persistedCollectionMetadata{
+			PhraseIndex:        pc.PhraseIndex,
+			MetadataStatsKeys:  pc.MetadataStatsKeys,
+			BM25Index:          pc.BM25Index,
+			ContentCompression: pc.ContentCompression,
+			ContentCodec:       pc.ContentCodec,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't import collection '%s': %w", pc.Name, err)
 		}
 		if db.persistDirectory != "" {
-			c.persistDirectory = filepath.Join(db.persistDirectory, hash2hex(pc.Name))
+			c.readableNames = db.readableNames
+			if old, ok := db.collections[c.Name]; ok {
+				// Keep the directory of the overwritten collection.
+				c.readableNames = old.readableNames
+			}
+			c.persistDirectory = filepath.Join(db.persistDirectory, persistedName(pc.Name, c.readableNames))
 			c.compress = db.compress
+			c.encoding = db.encoding
+			c.perms = db.perms
 		}
+		if old, ok := db.collections[c.Name]; ok {
+			old.removeMemoryBudget()
+		}
+		if db.memory != nil {
+			c.setMemoryBudget(db.memory)
+		}
+		c.concurrency = db.concurrency
 		db.collections[c.Name] = c
 	}
 
-	return nil
+	return db.writeManifest()
 }
 
 // ImportFromReader imports the DB from a reader. The stream must be encoded as
@@ -280,9 +481,31 @@ func (db *DB) ImportFromReader(reader io.ReadSeeker, encryptionKey string) error
 	// Create persistence structs with exported fields so that they can be decoded
 	// from gob.
 	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+		Name             string
+		Metadata         map[string]string
+		EmbeddingModel   string
+		Projection       *projection
+		MetadataSchema   *MetadataSchema
+		QueryDefaults    QueryDefaults
+		Normalization    NormalizationPolicy
+		Similarity       string
+		Matryoshka       matryoshkaConfig
+		LateInteraction  bool
+		StrictMode       bool
+		CreatedAt        time.Time
+		ModifiedAt       time.Time
+		DiscardContent   bool
+		ContentEncrypted bool
+		// Like in [persistedCollectionMetadata], the indexes are rebuilt from
+		// the documents on import.
+		PhraseIndex        bool
+		MetadataStatsKeys  []string
+		BM25Index          *BM25IndexOptions
+		ContentCompression int
+		ContentCodec       string
+		Shard              *shardInfo
+		LastVersion        uint64
+		Documents          map[string]*Document
 	}
 	persistenceDB := struct {
 		Collections map[string]*persistenceCollection
@@ -292,27 +515,81 @@ func (db *DB) ImportFromReader(reader io.ReadSeeker, encryptionKey string) error
 
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
 
-	err := readFromReader(reader, &persistenceDB, encryptionKey)
+	err := readFromReader(reader, &persistenceDB, nil, encryptionKey)
 	if err != nil {
 		return fmt.Errorf("couldn't read stream: %w", err)
 	}
 
 	for _, pc := range persistenceDB.Collections {
+		// Imported collections don't have a content store, so the documents
+		// keep their content.
+		for _, d := range pc.Documents {
+			d.ContentHash = ""
+		}
 		c := &Collection{
 			Name: pc.Name,
 
-			metadata:  pc.Metadata,
-			documents: pc.Documents,
+			metadata:        pc.Metadata,
+			embeddingModel:  pc.EmbeddingModel,
+			projection:      pc.Projection,
+			metadataSchema:  pc.MetadataSchema,
+			queryDefaults:   pc.QueryDefaults,
+			normalization:   pc.Normalization,
+			similarity:      pc.Similarity,
+			matryoshka:      pc.Matryoshka,
+			lateInteraction: pc.LateInteraction,
+			strict:          pc.StrictMode,
+			createdAt:       pc.CreatedAt,
+			modifiedAt:      pc.ModifiedAt,
+			discardContent:  pc.DiscardContent,
+			shard:           pc.Shard,
+			documents:       pc.Documents,
+			// The content stays encrypted in memory until the key is set.
+			contentEncrypted:         pc.ContentEncrypted,
+			contentEncryptedInMemory: pc.ContentEncrypted,
+		}
+		c.initVersion(pc.LastVersion)
+		c.countModelMismatches()
+		c.column.rebuild(c.documents)
+		// Before the collection gets its directory, so that the BM25 index is
+		// built from the imported documents instead of being loaded from the
+		// file of an overwritten collection.
+		err = c.applyPersistedIndexes(persistedCollectionMetadata{
+			PhraseIndex:        pc.PhraseIndex,
+			MetadataStatsKeys:  pc.MetadataStatsKeys,
+			BM25Index:          pc.BM25Index,
+			ContentCompression: pc.ContentCompression,
+			ContentCodec:       pc.ContentCodec,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't import collection '%s': %w", pc.Name, err)
 		}
 		if db.persistDirectory != "" {
-			c.persistDirectory = filepath.Join(db.persistDirectory, hash2hex(pc.Name))
+			c.readableNames = db.readableNames
+			if old, ok := db.collections[c.Name]; ok {
+				// Keep the directory of the overwritten collection.
+				c.readableNames = old.readableNames
+			}
+			c.persistDirectory = filepath.Join(db.persistDirectory, persistedName(pc.Name, c.readableNames))
 			c.compress = db.compress
+			c.encoding = db.encoding
+			c.perms = db.perms
+		}
+		if old, ok := db.collections[c.Name]; ok {
+			old.removeMemoryBudget()
 		}
+		if db.memory != nil {
+			c.setMemoryBudget(db.memory)
+		}
+		c.concurrency = db.concurrency
 		db.collections[c.Name] = c
 	}
 
-	return nil
+	return db.writeManifest()
 }
 
 // Export exports the DB to a file at the given path. The file is encoded as gob,
@@ -359,9 +636,31 @@ func (db *DB) ExportToFile(filePath string, compress bool, encryptionKey string)
 	// Create persistence structs with exported fields so that they can be encoded
 	// as gob.
 	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+		Name             string
+		Metadata         map[string]string
+		EmbeddingModel   string
+		Projection       *projection
+		MetadataSchema   *MetadataSchema
+		QueryDefaults    QueryDefaults
+		Normalization    NormalizationPolicy
+		Similarity       string
+		Matryoshka       matryoshkaConfig
+		LateInteraction  bool
+		StrictMode       bool
+		CreatedAt        time.Time
+		ModifiedAt       time.Time
+		DiscardContent   bool
+		ContentEncrypted bool
+		// Like in [persistedCollectionMetadata], the indexes are rebuilt from
+		// the documents on import.
+		PhraseIndex        bool
+		MetadataStatsKeys  []string
+		BM25Index          *BM25IndexOptions
+		ContentCompression int
+		ContentCodec       string
+		Shard              *shardInfo
+		LastVersion        uint64
+		Documents          map[string]*Document
 	}
 	persistenceDB := struct {
 		Collections map[string]*persistenceCollection
@@ -371,16 +670,47 @@ func (db *DB) ExportToFile(filePath string, compress bool, encryptionKey string)
 
 	db.collectionsLock.RLock()
 	defer db.collectionsLock.RUnlock()
+	if db.closed {
+		return ErrClosed
+	}
 
 	for k, v := range db.collections {
+		v.documentsLock.RLock()
+		documents, err := v.documentsWithContent()
+		phraseIndex, statsKeys, bm25Index, contentCompression, shard := v.phrases != nil, v.metadataStats.keys(), v.bm25Options(), v.contentCompression(), v.shard
+		contentCodec := v.contentCodec()
+		v.documentsLock.RUnlock()
+		if err != nil {
+			return fmt.Errorf("couldn't export collection '%s': %w", k, err)
+		}
 		persistenceDB.Collections[k] = &persistenceCollection{
-			Name:      v.Name,
-			Metadata:  v.metadata,
-			Documents: v.documents,
+			Name:               v.Name,
+			Metadata:           v.metadata,
+			EmbeddingModel:     v.embeddingModel,
+			Projection:         v.projection,
+			MetadataSchema:     v.metadataSchema,
+			QueryDefaults:      v.queryDefaults,
+			Normalization:      v.normalization,
+			Similarity:         v.similarity,
+			Matryoshka:         v.matryoshka,
+			LateInteraction:    v.lateInteraction,
+			StrictMode:         v.strict,
+			CreatedAt:          v.createdAt,
+			ModifiedAt:         v.modifiedAt,
+			DiscardContent:     v.discardContent,
+			ContentEncrypted:   v.contentEncrypted,
+			PhraseIndex:        phraseIndex,
+			MetadataStatsKeys:  statsKeys,
+			BM25Index:          bm25Index,
+			ContentCompression: contentCompression,
+			ContentCodec:       contentCodec,
+			Shard:              shard,
+			LastVersion:        v.lastVersion,
+			Documents:          documents,
 		}
 	}
 
-	err := persistToFile(filePath, persistenceDB, compress, encryptionKey)
+	err := persistToFile(filePath, persistenceDB, nil, compress, encryptionKey, filePerms{})
 	if err != nil {
 		return fmt.Errorf("couldn't export DB: %w", err)
 	}
@@ -408,9 +738,31 @@ func (db *DB) ExportToWriter(writer io.Writer, compress bool, encryptionKey stri
 	// Create persistence structs with exported fields so that they can be encoded
 	// as gob.
 	type persistenceCollection struct {
-		Name      string
-		Metadata  map[string]string
-		Documents map[string]*Document
+		Name             string
+		Metadata         map[string]string
+		EmbeddingModel   string
+		Projection       *projection
+		MetadataSchema   *MetadataSchema
+		QueryDefaults    QueryDefaults
+		Normalization    NormalizationPolicy
+		Similarity       string
+		Matryoshka       matryoshkaConfig
+		LateInteraction  bool
+		StrictMode       bool
+		CreatedAt        time.Time
+		ModifiedAt       time.Time
+		DiscardContent   bool
+		ContentEncrypted bool
+		// Like in [persistedCollectionMetadata], the indexes are rebuilt from
+		// the documents on import.
+		PhraseIndex        bool
+		MetadataStatsKeys  []string
+		BM25Index          *BM25IndexOptions
+		ContentCompression int
+		ContentCodec       string
+		Shard              *shardInfo
+		LastVersion        uint64
+		Documents          map[string]*Document
 	}
 	persistenceDB := struct {
 		Collections map[string]*persistenceCollection
@@ -420,16 +772,47 @@ func (db *DB) ExportToWriter(writer io.Writer, compress bool, encryptionKey stri
 
 	db.collectionsLock.RLock()
 	defer db.collectionsLock.RUnlock()
+	if db.closed {
+		return ErrClosed
+	}
 
 	for k, v := range db.collections {
+		v.documentsLock.RLock()
+		documents, err := v.documentsWithContent()
+		phraseIndex, statsKeys, bm25Index, contentCompression, shard := v.phrases != nil, v.metadataStats.keys(), v.bm25Options(), v.contentCompression(), v.shard
+		contentCodec := v.contentCodec()
+		v.documentsLock.RUnlock()
+		if err != nil {
+			return fmt.Errorf("couldn't export collection '%s': %w", k, err)
+		}
 		persistenceDB.Collections[k] = &persistenceCollection{
-			Name:      v.Name,
-			Metadata:  v.metadata,
-			Documents: v.documents,
+			Name:               v.Name,
+			Metadata:           v.metadata,
+			EmbeddingModel:     v.embeddingModel,
+			Projection:         v.projection,
+			MetadataSchema:     v.metadataSchema,
+			QueryDefaults:      v.queryDefaults,
+			Normalization:      v.normalization,
+			Similarity:         v.similarity,
+			Matryoshka:         v.matryoshka,
+			LateInteraction:    v.lateInteraction,
+			StrictMode:         v.strict,
+			CreatedAt:          v.createdAt,
+			ModifiedAt:         v.modifiedAt,
+			DiscardContent:     v.discardContent,
+			ContentEncrypted:   v.contentEncrypted,
+			PhraseIndex:        phraseIndex,
+			MetadataStatsKeys:  statsKeys,
+			BM25Index:          bm25Index,
+			ContentCompression: contentCompression,
+			ContentCodec:       contentCodec,
+			Shard:              shard,
+			LastVersion:        v.lastVersion,
+			Documents:          documents,
 		}
 	}
 
-	err := persistToWriter(writer, persistenceDB, compress, encryptionKey)
+	err := persistToWriter(writer, persistenceDB, nil, compress, encryptionKey)
 	if err != nil {
 		return fmt.Errorf("couldn't export DB: %w", err)
 	}
@@ -450,14 +833,44 @@ func (db *DB) CreateCollection(name string, metadata map[string]string, embeddin
 	if embeddingFunc == nil {
 		embeddingFunc = NewEmbeddingFuncDefault()
 	}
-	collection, err := newCollection(name, metadata, embeddingFunc, db.persistDirectory, db.compress)
+	db.collectionsLock.RLock()
+	closed := db.closed
+	readableNames := db.readableNames
+	if old, ok := db.collections[name]; ok {
+		// Keep the directory of the overwritten collection.
+		readableNames = old.readableNames
+	}
+	err := db.checkDirCollision(name, readableNames)
+	db.collectionsLock.RUnlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := newCollection(name, metadata, embeddingFunc, db.persistDirectory, db.compress, db.encoding, db.perms, readableNames)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create collection: %w", err)
 	}
 
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return nil, ErrClosed
+	}
+	if old, ok := db.collections[name]; ok {
+		old.removeMemoryBudget()
+	}
+	if db.memory != nil {
+		collection.setMemoryBudget(db.memory)
+	}
+	collection.concurrency = db.concurrency
 	db.collections[name] = collection
+	err = db.writeManifest()
+	if err != nil {
+		return nil, err
+	}
 	return collection, nil
 }
 
@@ -468,6 +881,8 @@ func (db *DB) CreateCollection(name string, metadata map[string]string, embeddin
 // The map is not an entirely deep clone, so the collections themselves are still
 // the original ones. Any methods on the collections like Add() for adding documents
 // will be reflected on the DB's collections and are concurrency-safe.
+// To get an overview of a persistent DB without opening it, use
+// [ReadCollectionInfos].
 func (db *DB) ListCollections() map[string]*Collection {
 	db.collectionsLock.RLock()
 	defer db.collectionsLock.RUnlock()
@@ -534,6 +949,9 @@ func (db *DB) GetOrCreateCollection(name string, metadata map[string]string, emb
 func (db *DB) DeleteCollection(name string) error {
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
 
 	col, ok := db.collections[name]
 	if !ok {
@@ -548,8 +966,9 @@ func (db *DB) DeleteCollection(name string) error {
 		}
 	}
 
+	col.removeMemoryBudget()
 	delete(db.collections, name)
-	return nil
+	return db.writeManifest()
 }
 
 // Reset removes all collections from the DB.
@@ -558,6 +977,9 @@ func (db *DB) DeleteCollection(name string) error {
 func (db *DB) Reset() error {
 	db.collectionsLock.Lock()
 	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
 
 	if db.persistDirectory != "" {
 		err := os.RemoveAll(db.persistDirectory)
@@ -565,12 +987,15 @@ func (db *DB) Reset() error {
 			return fmt.Errorf("couldn't delete persistence directory: %w", err)
 		}
 		// Recreate empty root level directory
-		err = os.MkdirAll(db.persistDirectory, 0o700)
+		err = db.perms.mkdirAll(db.persistDirectory)
 		if err != nil {
 			return fmt.Errorf("couldn't recreate persistence directory: %w", err)
 		}
 	}
 
+	for _, c := range db.collections {
+		c.removeMemoryBudget()
+	}
 	// Just assign a new map, the GC will take care of the rest.
 	db.collections = make(map[string]*Collection)
 	return nil
@@ -1,11 +1,13 @@
 package chromem
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"slices"
 	"testing"
 )
@@ -67,6 +69,50 @@ func TestNewPersistentDB_Errors(t *testing.T) {
 	})
 }
 
+func TestNewPersistentDBWithOptions_FileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes aren't supported on Windows")
+	}
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "db")
+
+	db, err := NewPersistentDBWithOptions(path, PersistentDBOptions{
+		FileMode:    0o640,
+		DirMode:     0o750,
+		IgnoreUmask: true,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(context.Background(), Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	for path, exp := range map[string]os.FileMode{
+		path:               0o750,
+		c.persistDirectory: 0o750,
+		c.getDocPath("1"):  0o640,
+		filepath.Join(c.persistDirectory, metadataFileName+c.fileExt()): 0o640,
+	} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if fi.Mode().Perm() != exp {
+			t.Fatalf("expected mode %o of %s, got %o", exp, path, fi.Mode().Perm())
+		}
+	}
+}
+
 func TestDB_ImportExport(t *testing.T) {
 	r := rand.New(rand.NewSource(rand.Int63()))
 	randString := randomString(r, 10)
@@ -153,9 +199,12 @@ func TestDB_ImportExport(t *testing.T) {
 			}
 
 			// Check expectations
-			// We have to reset the embed function, but otherwise the DB objects
-			// should be deep equal.
+			// We have to reset the embed function, the write generation and
+			// whether the modification time is persisted (which aren't
+			// persisted), but otherwise the DB objects should be deep equal.
 			c.embed = nil
+			c.generation = 0
+			c.modifiedAtStale = false
 			if !reflect.DeepEqual(orig, new) {
 				t.Fatalf("expected DB %+v, got %+v", orig, new)
 			}
@@ -163,6 +212,68 @@ func TestDB_ImportExport(t *testing.T) {
 	}
 }
 
+func TestDB_ImportExport_Indexes(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	orig := NewDB()
+	c, err := orig.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "the quick brown fox", Metadata: map[string]string{"lang": "en"}},
+		{ID: "2", Content: "the lazy dog", Metadata: map[string]string{"lang": "en"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, err := range []error{
+		c.EnablePhraseIndex(),
+		c.EnableMetadataStats("lang"),
+		c.EnableBM25Index(BM25IndexOptions{Language: "en"}),
+		c.SetContentCompression(2),
+	} {
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	err = orig.ExportToWriter(&buf, true, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db := NewDB()
+	err = db.ImportFromReader(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	if c == nil {
+		t.Fatal("expected collection, got nil")
+	}
+
+	if c.phrases == nil {
+		t.Fatal("expected phrase index")
+	}
+	if stats, ok := c.MetadataStats("lang"); !ok || stats["en"] != 2 {
+		t.Fatal("expected metadata stats, got", stats)
+	}
+	if c.ContentCompression() != 2 {
+		t.Fatal("expected content compression, got", c.ContentCompression())
+	}
+	res, err := c.QueryBM25(ctx, "foxes", 2, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" || res[0].Content != "the quick brown fox" {
+		t.Fatal("expected document 1, got", res)
+	}
+}
+
 func TestDB_CreateCollection(t *testing.T) {
 	// Values in the collection
 	name := "test"
@@ -0,0 +1,53 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrContentNotStored is returned when filtering by content in a collection that
+// doesn't store content, see [Collection.SetDiscardContent].
+var ErrContentNotStored = errors.New("collection doesn't store document content")
+
+// SetDiscardContent sets whether the collection discards the content and binary
+// data of documents after embedding them, for privacy-sensitive setups where the
+// raw text must not be retained. Only the embeddings and metadata are stored
+// then (and persisted, if the DB is persistent), and queries return the IDs,
+// metadata and embeddings of the documents, but no content. Content filters
+// (whereDocument) fail with [ErrContentNotStored], and [Collection.Reembed]
+// isn't possible.
+//
+// The setting only applies to documents added afterwards, existing documents
+// keep their content. It's persisted if the DB is persistent, and it's applied
+// to the collection's namespaces as well.
+func (c *Collection) SetDiscardContent(discard bool) error {
+	c.documentsLock.Lock()
+	if c.closed {
+		c.documentsLock.Unlock()
+		return ErrClosed
+	}
+	c.discardContent = discard
+	err := c.persistMetadata()
+	c.documentsLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+	for _, ns := range c.namespaces {
+		ns.documentsLock.Lock()
+		ns.discardContent = discard
+		ns.documentsLock.Unlock()
+	}
+
+	return nil
+}
+
+// DiscardsContent returns whether the collection discards the content of new
+// documents, see [Collection.SetDiscardContent].
+func (c *Collection) DiscardsContent() bool {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.discardContent
+}
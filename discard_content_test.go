@@ -0,0 +1,88 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCollection_SetDiscardContent(t *testing.T) {
+	ctx := context.Background()
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var embedded []string
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		embedded = append(embedded, text)
+		return []float32{1, 0, 0}, nil
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetDiscardContent(true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "secret", Metadata: map[string]string{"a": "b"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(embedded) != 1 || embedded[0] != "secret" {
+		t.Fatal("expected content to be embedded, got", embedded)
+	}
+
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0, 0}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" || res[0].Metadata["a"] != "b" || res[0].Content != "" {
+		t.Fatal("expected result without content, got", res)
+	}
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0, 0}, 1, nil, map[string]string{"$contains": "secret"})
+	if !errors.Is(err, ErrContentNotStored) {
+		t.Fatal("expected ErrContentNotStored, got", err)
+	}
+
+	// The content isn't persisted, and the setting is
+	db2, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if !c2.DiscardsContent() {
+		t.Fatal("expected collection to discard content")
+	}
+	doc, err := c2.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "" {
+		t.Fatal("expected no content, got", doc.Content)
+	}
+
+	// Namespaces discard content as well
+	ns, err := c2.Namespace("tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = ns.AddDocument(ctx, Document{ID: "1", Content: "secret", Embedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err = ns.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "" {
+		t.Fatal("expected no content, got", doc.Content)
+	}
+}
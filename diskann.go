@@ -0,0 +1,736 @@
+package chromem
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+)
+
+const (
+	diskIndexMagic      = "CHRMDANN"
+	diskIndexVersion    = 1
+	diskIndexHeaderSize = 64
+
+	defaultDiskIndexMaxDegree      = 32
+	defaultDiskIndexSearchListSize = 64
+	defaultDiskIndexAlpha          = 1.2
+	defaultDiskIndexRoutingNodes   = 1024
+	// Number of routing nodes a search starts from.
+	diskIndexEntryPoints = 4
+)
+
+// DiskIndexOptions are the options for building a [DiskIndex].
+type DiskIndexOptions struct {
+	// Maximum number of neighbors per node in the graph. Higher values improve
+	// the recall, but make the index bigger and queries slower. Defaults to 32.
+	MaxDegree int
+
+	// Size of the candidate list when searching the graph during the build.
+	// Higher values improve the quality of the graph, but make the build slower.
+	// Defaults to 64.
+	SearchListSize int
+
+	// Factor by which a neighbor must be closer than a candidate to prune the
+	// candidate. Values > 1 keep more long-range edges, which makes searches
+	// converge faster. Defaults to 1.2.
+	Alpha float32
+
+	// Number of nodes whose embeddings are kept in memory when the index is
+	// opened, to route queries to good entry points of the graph.
+	// Defaults to 1024.
+	RoutingNodes int
+}
+
+// DiskIndexBuilder builds a [DiskIndex] file. Embeddings are added one at a
+// time and buffered in a temporary file next to the index file, so the memory
+// usage only depends on the number of embeddings and the graph's degree, but not
+// on the dimensions: about MaxDegree*4+40 bytes per embedding, e.g. 1.6 GB for
+// 10 million embeddings with the default degree.
+type DiskIndexBuilder struct {
+	path    string
+	options DiskIndexOptions
+
+	dims    int
+	count   int
+	sum     []float64
+	vectors *os.File
+	ids     *os.File
+	// Buffered writers for the temporary files
+	vectorsW *bufio.Writer
+	idsW     *bufio.Writer
+	// Offsets of the IDs in the temporary ID file, plus its end
+	idOffsets []uint64
+}
+
+// NewDiskIndexBuilder creates a builder for a [DiskIndex] at the given path.
+// Options with zero values are set to their defaults.
+func NewDiskIndexBuilder(path string, options DiskIndexOptions) (*DiskIndexBuilder, error) {
+	if path == "" {
+		return nil, errors.New("path is empty")
+	}
+	if options.MaxDegree <= 0 {
+		options.MaxDegree = defaultDiskIndexMaxDegree
+	}
+	if options.SearchListSize <= 0 {
+		options.SearchListSize = defaultDiskIndexSearchListSize
+	}
+	if options.Alpha <= 0 {
+		options.Alpha = defaultDiskIndexAlpha
+	}
+	if options.RoutingNodes <= 0 {
+		options.RoutingNodes = defaultDiskIndexRoutingNodes
+	}
+
+	vectors, err := os.Create(path + ".vectors.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+	ids, err := os.Create(path + ".ids.tmp")
+	if err != nil {
+		_ = vectors.Close()
+		_ = os.Remove(vectors.Name())
+		return nil, fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+
+	return &DiskIndexBuilder{
+		path:      path,
+		options:   options,
+		vectors:   vectors,
+		ids:       ids,
+		vectorsW:  bufio.NewWriter(vectors),
+		idsW:      bufio.NewWriter(ids),
+		idOffsets: []uint64{0},
+	}, nil
+}
+
+// Add adds an embedding with the ID of its document. The embedding is
+// normalized if that's not the case yet. All embeddings must have the same
+// dimensions. IDs aren't checked for uniqueness.
+func (b *DiskIndexBuilder) Add(id string, embedding []float32) error {
+	if id == "" {
+		return errors.New("id is empty")
+	}
+	if len(embedding) == 0 {
+		return errors.New("embedding is empty")
+	}
+	if b.dims == 0 {
+		b.dims = len(embedding)
+		b.sum = make([]float64, b.dims)
+	} else if len(embedding) != b.dims {
+		return fmt.Errorf("%w: embedding has %d dimensions, expected %d", ErrDimensionMismatch, len(embedding), b.dims)
+	}
+	if b.count == math.MaxUint32 {
+		return errors.New("too many embeddings")
+	}
+	if !isNormalized(embedding) {
+		embedding = normalizeVector(embedding)
+	}
+
+	err := writeFloat32s(b.vectorsW, embedding)
+	if err != nil {
+		return fmt.Errorf("couldn't write embedding: %w", err)
+	}
+	_, err = b.idsW.WriteString(id)
+	if err != nil {
+		return fmt.Errorf("couldn't write ID: %w", err)
+	}
+	for i, v := range embedding {
+		b.sum[i] += float64(v)
+	}
+	b.idOffsets = append(b.idOffsets, b.idOffsets[len(b.idOffsets)-1]+uint64(len(id)))
+	b.count++
+	return nil
+}
+
+// Build builds the graph with the Vamana algorithm of DiskANN and writes the
+// index file. The embeddings are read from the temporary file as needed, so
+// for large indexes the build is bound by disk I/O, which the OS page cache
+// mitigates. The builder can't be used anymore afterwards, and the temporary
+// files are removed, also if the build fails.
+func (b *DiskIndexBuilder) Build(ctx context.Context) error {
+	defer b.cleanup()
+
+	if b.count == 0 {
+		return errors.New("no embeddings were added")
+	}
+	err := b.vectorsW.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write embeddings: %w", err)
+	}
+	err = b.idsW.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write IDs: %w", err)
+	}
+
+	n := b.count
+	vectorSize := int64(b.dims) * 4
+	vector := func(node uint32) ([]float32, error) {
+		return readFloat32s(b.vectors, int64(node)*vectorSize, b.dims)
+	}
+
+	// The entry point is the node closest to the mean of all embeddings.
+	mean := make([]float32, b.dims)
+	for i, s := range b.sum {
+		mean[i] = float32(s / float64(n))
+	}
+	medoid, err := b.nearestTo(ctx, mean, vector)
+	if err != nil {
+		return err
+	}
+
+	// Start with a random graph, then refine it in two passes: the first one
+	// without keeping long-range edges, the second one with them.
+	r := rand.New(rand.NewSource(int64(n)))
+	degree := min(b.options.MaxDegree, n-1)
+	graph := make([][]uint32, n)
+	for i := range graph {
+		graph[i] = make([]uint32, 0, degree)
+		for len(graph[i]) < degree {
+			j := uint32(r.Intn(n))
+			if j != uint32(i) && !slices.Contains(graph[i], j) {
+				graph[i] = append(graph[i], j)
+			}
+		}
+	}
+	s := &graphSearcher{
+		vector: vector,
+		neighbors: func(node uint32) ([]uint32, error) {
+			return graph[node], nil
+		},
+	}
+	for _, alpha := range []float32{1, b.options.Alpha} {
+		for _, i := range r.Perm(n) {
+			p := uint32(i)
+			pv, err := vector(p)
+			if err != nil {
+				return fmt.Errorf("couldn't read embedding: %w", err)
+			}
+			visited, err := s.search(ctx, pv, []uint32{medoid}, b.options.SearchListSize)
+			if err != nil {
+				return fmt.Errorf("couldn't search graph: %w", err)
+			}
+			graph[p], err = b.robustPrune(p, pv, append(visited, graph[p]...), alpha, vector)
+			if err != nil {
+				return err
+			}
+			// Add the reverse edges, pruning the neighbors if they get too many.
+			for _, j := range graph[p] {
+				if slices.Contains(graph[j], p) {
+					continue
+				}
+				if len(graph[j]) < b.options.MaxDegree {
+					graph[j] = append(graph[j], p)
+					continue
+				}
+				jv, err := vector(j)
+				if err != nil {
+					return fmt.Errorf("couldn't read embedding: %w", err)
+				}
+				graph[j], err = b.robustPrune(j, jv, append(slices.Clone(graph[j]), p), alpha, vector)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Sample the routing nodes, always including the medoid.
+	routing := []uint32{medoid}
+	for _, i := range r.Perm(n) {
+		if len(routing) >= min(b.options.RoutingNodes, n) {
+			break
+		}
+		if uint32(i) != medoid {
+			routing = append(routing, uint32(i))
+		}
+	}
+
+	return b.write(graph, medoid, routing)
+}
+
+// nearestTo returns the node with the highest similarity to v.
+func (b *DiskIndexBuilder) nearestTo(ctx context.Context, v []float32, vector func(uint32) ([]float32, error)) (uint32, error) {
+	var nearest uint32
+	best := float32(math.Inf(-1))
+	for i := 0; i < b.count; i++ {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		nv, err := vector(uint32(i))
+		if err != nil {
+			return 0, fmt.Errorf("couldn't read embedding: %w", err)
+		}
+		sim, _ := dotProduct(v, nv)
+		if sim > best {
+			nearest, best = uint32(i), sim
+		}
+	}
+	return nearest, nil
+}
+
+// robustPrune selects up to MaxDegree neighbors for node p from the candidates.
+// A candidate is skipped if an already selected neighbor is alpha times closer
+// to it than p is, so the neighbors point in diverse directions.
+func (b *DiskIndexBuilder) robustPrune(p uint32, pv []float32, candidates []uint32, alpha float32, vector func(uint32) ([]float32, error)) ([]uint32, error) {
+	type candidate struct {
+		node   uint32
+		vector []float32
+		dist   float32
+	}
+	cs := make([]candidate, 0, len(candidates))
+	seen := make(map[uint32]struct{}, len(candidates))
+	for _, c := range candidates {
+		if _, ok := seen[c]; ok || c == p {
+			continue
+		}
+		seen[c] = struct{}{}
+		cv, err := vector(c)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read embedding: %w", err)
+		}
+		sim, _ := dotProduct(pv, cv)
+		cs = append(cs, candidate{node: c, vector: cv, dist: 1 - sim})
+	}
+	slices.SortFunc(cs, func(a, b candidate) int {
+		return cmp.Compare(a.dist, b.dist)
+	})
+
+	neighbors := make([]uint32, 0, b.options.MaxDegree)
+	for len(cs) > 0 && len(neighbors) < b.options.MaxDegree {
+		nearest := cs[0]
+		neighbors = append(neighbors, nearest.node)
+		remaining := cs[:0]
+		for _, c := range cs[1:] {
+			sim, _ := dotProduct(nearest.vector, c.vector)
+			if alpha*(1-sim) > c.dist {
+				remaining = append(remaining, c)
+			}
+		}
+		cs = remaining
+	}
+	return neighbors, nil
+}
+
+// write writes the index file. It's first written to a temporary file, which
+// is then renamed, so that a failed build doesn't leave a broken index.
+//
+// The file consists of a header, a fixed-size record per node with its
+// embedding and neighbors, so that a search step needs only one read, the
+// routing nodes, and the IDs.
+func (b *DiskIndexBuilder) write(graph [][]uint32, medoid uint32, routing []uint32) error {
+	tmpPath := b.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("couldn't create index file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	n := len(graph)
+	recordSize := diskIndexRecordSize(b.dims, b.options.MaxDegree)
+	routingOffset := uint64(diskIndexHeaderSize) + uint64(n)*uint64(recordSize)
+	idsOffset := routingOffset + uint64(len(routing))*4
+
+	header := make([]byte, diskIndexHeaderSize)
+	copy(header, diskIndexMagic)
+	binary.LittleEndian.PutUint32(header[8:], diskIndexVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(b.dims))
+	binary.LittleEndian.PutUint32(header[16:], uint32(b.options.MaxDegree))
+	binary.LittleEndian.PutUint32(header[20:], uint32(n))
+	binary.LittleEndian.PutUint32(header[24:], medoid)
+	binary.LittleEndian.PutUint32(header[28:], uint32(len(routing)))
+	binary.LittleEndian.PutUint64(header[32:], routingOffset)
+	binary.LittleEndian.PutUint64(header[40:], idsOffset)
+	_, err = w.Write(header)
+	if err != nil {
+		return fmt.Errorf("couldn't write header: %w", err)
+	}
+
+	// Node records
+	record := make([]byte, recordSize)
+	for i, neighbors := range graph {
+		v, err := readFloat32s(b.vectors, int64(i)*int64(b.dims)*4, b.dims)
+		if err != nil {
+			return fmt.Errorf("couldn't read embedding: %w", err)
+		}
+		clear(record)
+		for j, f := range v {
+			binary.LittleEndian.PutUint32(record[j*4:], math.Float32bits(f))
+		}
+		off := b.dims * 4
+		binary.LittleEndian.PutUint32(record[off:], uint32(len(neighbors)))
+		for j, neighbor := range neighbors {
+			binary.LittleEndian.PutUint32(record[off+4+j*4:], neighbor)
+		}
+		_, err = w.Write(record)
+		if err != nil {
+			return fmt.Errorf("couldn't write node: %w", err)
+		}
+	}
+
+	// Routing nodes
+	for _, node := range routing {
+		err = binary.Write(w, binary.LittleEndian, node)
+		if err != nil {
+			return fmt.Errorf("couldn't write routing nodes: %w", err)
+		}
+	}
+
+	// IDs: offsets relative to the start of the ID data, then the data
+	for _, off := range b.idOffsets {
+		err = binary.Write(w, binary.LittleEndian, off)
+		if err != nil {
+			return fmt.Errorf("couldn't write ID offsets: %w", err)
+		}
+	}
+	_, err = b.ids.Seek(0, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("couldn't read IDs: %w", err)
+	}
+	_, err = io.Copy(w, b.ids)
+	if err != nil {
+		return fmt.Errorf("couldn't write IDs: %w", err)
+	}
+
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write index file: %w", err)
+	}
+	err = f.Sync()
+	if err != nil {
+		return fmt.Errorf("couldn't sync index file: %w", err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't close index file: %w", err)
+	}
+	err = os.Rename(tmpPath, b.path)
+	if err != nil {
+		return fmt.Errorf("couldn't rename index file: %w", err)
+	}
+	return nil
+}
+
+func (b *DiskIndexBuilder) cleanup() {
+	for _, f := range []*os.File{b.vectors, b.ids} {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+}
+
+// DiskIndex is a disk-resident graph index for approximate nearest neighbor
+// search, like DiskANN. It's for sets of embeddings that don't fit into memory:
+// only a small routing layer and the offsets of the IDs are kept in memory,
+// while the embeddings and the graph are read from disk during a search, one
+// read per visited node. It's read-only; to change it, build a new one with
+// a [DiskIndexBuilder]. It's safe for concurrent use.
+//
+// A DiskIndex only contains the IDs and embeddings, so the results of a query
+// don't have metadata or content. Look them up in the application's own
+// storage, or a collection with [Collection.GetByID].
+type DiskIndex struct {
+	f          *os.File
+	dims       int
+	maxDegree  int
+	n          int
+	recordSize int
+	idsOffset  int64
+
+	routing        []uint32
+	routingVectors [][]float32
+}
+
+// OpenDiskIndex opens an index file that was created with a [DiskIndexBuilder].
+func OpenDiskIndex(path string) (*DiskIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open index file: %w", err)
+	}
+	d, err := openDiskIndex(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func openDiskIndex(f *os.File) (*DiskIndex, error) {
+	header := make([]byte, diskIndexHeaderSize)
+	_, err := f.ReadAt(header, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read header: %w", err)
+	}
+	if string(header[:8]) != diskIndexMagic {
+		return nil, errors.New("not a chromem-go disk index file")
+	}
+	if version := binary.LittleEndian.Uint32(header[8:]); version != diskIndexVersion {
+		return nil, fmt.Errorf("unsupported disk index version %d", version)
+	}
+
+	d := &DiskIndex{
+		f:         f,
+		dims:      int(binary.LittleEndian.Uint32(header[12:])),
+		maxDegree: int(binary.LittleEndian.Uint32(header[16:])),
+		n:         int(binary.LittleEndian.Uint32(header[20:])),
+		idsOffset: int64(binary.LittleEndian.Uint64(header[40:])),
+	}
+	d.recordSize = diskIndexRecordSize(d.dims, d.maxDegree)
+
+	// Load the routing layer
+	routingOffset := int64(binary.LittleEndian.Uint64(header[32:]))
+	buf := make([]byte, 4*binary.LittleEndian.Uint32(header[28:]))
+	_, err = f.ReadAt(buf, routingOffset)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read routing nodes: %w", err)
+	}
+	for i := 0; i < len(buf); i += 4 {
+		node := binary.LittleEndian.Uint32(buf[i:])
+		v, _, err := d.readNode(node)
+		if err != nil {
+			return nil, err
+		}
+		d.routing = append(d.routing, node)
+		d.routingVectors = append(d.routingVectors, v)
+	}
+
+	return d, nil
+}
+
+// Len returns the number of embeddings in the index.
+func (d *DiskIndex) Len() int {
+	return d.n
+}
+
+// Close closes the index file.
+func (d *DiskIndex) Close() error {
+	return d.f.Close()
+}
+
+// Query performs an approximate nearest neighbor search. The results only have
+// the ID, embedding and similarity set.
+//
+//   - queryEmbedding: The embedding of the query. It's normalized if that's not
+//     the case yet.
+//   - nResults: The number of results to return. Must be > 0.
+//   - searchListSize: The size of the candidate list. Higher values improve the
+//     recall, but make queries slower. If it's less than nResults, nResults is
+//     used, or 64 if that's more.
+func (d *DiskIndex) Query(ctx context.Context, queryEmbedding []float32, nResults, searchListSize int) ([]Result, error) {
+	if len(queryEmbedding) != d.dims {
+		return nil, fmt.Errorf("%w: queryEmbedding has %d dimensions, expected %d", ErrDimensionMismatch, len(queryEmbedding), d.dims)
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	nResults = min(nResults, d.n)
+	if searchListSize < nResults {
+		searchListSize = max(nResults, defaultDiskIndexSearchListSize)
+	}
+	if !isNormalized(queryEmbedding) {
+		queryEmbedding = normalizeVector(queryEmbedding)
+	}
+
+	// Start from the routing nodes closest to the query.
+	sims := make([]float32, len(d.routing))
+	order := make([]int, len(d.routing))
+	for i, v := range d.routingVectors {
+		sims[i], _ = dotProduct(queryEmbedding, v)
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Compare(sims[b], sims[a])
+	})
+	entryNodes := make([]uint32, 0, diskIndexEntryPoints)
+	for _, i := range order[:min(diskIndexEntryPoints, len(order))] {
+		entryNodes = append(entryNodes, d.routing[i])
+	}
+
+	// Each record is read once and has both the embedding and neighbors.
+	neighbors := make(map[uint32][]uint32)
+	s := &graphSearcher{
+		vector: func(node uint32) ([]float32, error) {
+			v, ns, err := d.readNode(node)
+			if err != nil {
+				return nil, err
+			}
+			neighbors[node] = ns
+			return v, nil
+		},
+		neighbors: func(node uint32) ([]uint32, error) {
+			return neighbors[node], nil
+		},
+	}
+	_, err := s.search(ctx, queryEmbedding, entryNodes, searchListSize)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't search graph: %w", err)
+	}
+
+	res := make([]Result, 0, nResults)
+	for _, c := range s.list[:min(nResults, len(s.list))] {
+		id, err := d.readID(c.node)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, Result{
+			ID:         id,
+			Embedding:  c.vector,
+			Similarity: c.sim,
+		})
+	}
+	return res, nil
+}
+
+// readNode reads the embedding and neighbors of a node.
+func (d *DiskIndex) readNode(node uint32) ([]float32, []uint32, error) {
+	if int(node) >= d.n {
+		return nil, nil, fmt.Errorf("node %d doesn't exist", node)
+	}
+	record := make([]byte, d.recordSize)
+	_, err := d.f.ReadAt(record, diskIndexHeaderSize+int64(node)*int64(d.recordSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read node: %w", err)
+	}
+	v := make([]float32, d.dims)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(record[i*4:]))
+	}
+	off := d.dims * 4
+	neighbors := make([]uint32, min(int(binary.LittleEndian.Uint32(record[off:])), d.maxDegree))
+	for i := range neighbors {
+		neighbors[i] = binary.LittleEndian.Uint32(record[off+4+i*4:])
+	}
+	return v, neighbors, nil
+}
+
+// readID reads the ID of a node.
+func (d *DiskIndex) readID(node uint32) (string, error) {
+	offsets := make([]byte, 16)
+	_, err := d.f.ReadAt(offsets, d.idsOffset+int64(node)*8)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read ID offset: %w", err)
+	}
+	start := binary.LittleEndian.Uint64(offsets)
+	end := binary.LittleEndian.Uint64(offsets[8:])
+	id := make([]byte, end-start)
+	dataOffset := d.idsOffset + int64(d.n+1)*8
+	_, err = d.f.ReadAt(id, dataOffset+int64(start))
+	if err != nil {
+		return "", fmt.Errorf("couldn't read ID: %w", err)
+	}
+	return string(id), nil
+}
+
+// graphSearcher does a best-first beam search on a graph.
+type graphSearcher struct {
+	vector    func(node uint32) ([]float32, error)
+	neighbors func(node uint32) ([]uint32, error)
+
+	// The candidate list of the last search, sorted by similarity (descending)
+	list []graphCandidate
+}
+
+type graphCandidate struct {
+	node     uint32
+	vector   []float32
+	sim      float32
+	expanded bool
+}
+
+// search searches the graph for the nodes most similar to the normalized
+// vector v, starting at the entry nodes and keeping a candidate list of size l.
+// It returns the expanded nodes. The candidate list is in s.list afterwards.
+func (s *graphSearcher) search(ctx context.Context, v []float32, entries []uint32, l int) ([]uint32, error) {
+	s.list = s.list[:0]
+	seen := make(map[uint32]struct{})
+	insert := func(node uint32) error {
+		if _, ok := seen[node]; ok {
+			return nil
+		}
+		seen[node] = struct{}{}
+		nv, err := s.vector(node)
+		if err != nil {
+			return err
+		}
+		sim, err := dotProduct(v, nv)
+		if err != nil {
+			return err
+		}
+		if len(s.list) == l && sim <= s.list[l-1].sim {
+			return nil
+		}
+		i, _ := slices.BinarySearchFunc(s.list, sim, func(c graphCandidate, sim float32) int {
+			return cmp.Compare(sim, c.sim)
+		})
+		s.list = slices.Insert(s.list, i, graphCandidate{node: node, vector: nv, sim: sim})
+		if len(s.list) > l {
+			s.list = s.list[:l]
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		err := insert(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var expanded []uint32
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		i := slices.IndexFunc(s.list, func(c graphCandidate) bool { return !c.expanded })
+		if i == -1 {
+			return expanded, nil
+		}
+		s.list[i].expanded = true
+		node := s.list[i].node
+		expanded = append(expanded, node)
+		neighbors, err := s.neighbors(node)
+		if err != nil {
+			return nil, err
+		}
+		for _, neighbor := range neighbors {
+			err = insert(neighbor)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func diskIndexRecordSize(dims, maxDegree int) int {
+	return dims*4 + 4 + maxDegree*4
+}
+
+func writeFloat32s(w io.Writer, v []float32) error {
+	buf := make([]byte, len(v)*4)
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(f))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFloat32s reads n little-endian float32s at the offset.
+func readFloat32s(r io.ReaderAt, off int64, n int) ([]float32, error) {
+	raw := make([]byte, n*4)
+	_, err := r.ReadAt(raw, off)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+	}
+	return v, nil
+}
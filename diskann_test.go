@@ -0,0 +1,136 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestDiskIndex(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 32)
+		for i := range v {
+			v[i] = r.Float32() - 0.5
+		}
+		return normalizeVector(v)
+	}
+
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index")
+
+	b, err := NewDiskIndexBuilder(path, DiskIndexOptions{RoutingNodes: 16})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	vectors := make([][]float32, 500)
+	for i := range vectors {
+		vectors[i] = randomVector()
+		err = b.Add("doc-"+strconv.Itoa(i), vectors[i])
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = b.Add("wrong", []float32{1, 2})
+	if err == nil {
+		t.Fatal("expected error for different dimensions, got nil")
+	}
+	err = b.Build(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Only the index file is left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "index" {
+		t.Fatal("expected only the index file, got", entries)
+	}
+
+	d, err := OpenDiskIndex(path)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer d.Close()
+	if d.Len() != 500 {
+		t.Fatal("expected 500 embeddings, got", d.Len())
+	}
+	if len(d.routing) != 16 {
+		t.Fatal("expected 16 routing nodes, got", len(d.routing))
+	}
+
+	// Compare with exhaustive search
+	found, total := 0, 0
+	for q := 0; q < 20; q++ {
+		query := randomVector()
+		type idSim struct {
+			id  string
+			sim float32
+		}
+		exact := make([]idSim, 0, len(vectors))
+		for i, v := range vectors {
+			sim, _ := dotProduct(query, v)
+			exact = append(exact, idSim{id: "doc-" + strconv.Itoa(i), sim: sim})
+		}
+		slices.SortFunc(exact, func(a, b idSim) int {
+			return cmp.Compare(b.sim, a.sim)
+		})
+
+		res, err := d.Query(ctx, query, 10, 0)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 10 {
+			t.Fatal("expected 10 results, got", len(res))
+		}
+		for i := 1; i < len(res); i++ {
+			if res[i].Similarity > res[i-1].Similarity {
+				t.Fatal("expected results sorted by similarity, got", res)
+			}
+		}
+		for _, e := range exact[:10] {
+			total++
+			if slices.ContainsFunc(res, func(r Result) bool { return r.ID == e.id }) {
+				found++
+			}
+		}
+	}
+	if recall := float64(found) / float64(total); recall < 0.9 {
+		t.Fatal("expected recall >= 0.9, got", recall)
+	}
+
+	// An exact match is found
+	res, err := d.Query(ctx, vectors[123], 1, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "doc-123" || len(res[0].Embedding) != 32 {
+		t.Fatal("expected doc-123 with its embedding, got", res[0].ID, len(res[0].Embedding))
+	}
+
+	_, err = d.Query(ctx, []float32{1, 2}, 1, 0)
+	if err == nil {
+		t.Fatal("expected error for different dimensions, got nil")
+	}
+
+	// Not an index file
+	err = os.WriteFile(path, []byte("not an index, but long enough for the header, which is 64 bytes"), 0o600)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = OpenDiskIndex(path)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -12,6 +12,53 @@ type Document struct {
 	Embedding []float32
 	Content   string
 
+	// EmbeddingModel is the name of the model the embedding was created with.
+	// Optional. When the document is embedded by a collection, it's set to the
+	// collection's model (see [Collection.SetEmbeddingModel]).
+	EmbeddingModel string
+
+	// SparseEmbedding is an optional sparse embedding, e.g. from SPLADE or BM25,
+	// for [Collection.QuerySparse] and [Collection.QueryHybridEmbedding].
+	// Unlike the dense embedding, it's not created by the collection and not
+	// normalized.
+	SparseEmbedding SparseVector
+
+	// TokenEmbeddings are optional token-level embeddings from a
+	// late-interaction model like ColBERT, for [Collection.QueryLateInteraction].
+	// They require [Collection.SetLateInteraction]. Like the sparse embedding,
+	// they're not created by the collection, but they're normalized. If there's
+	// neither an embedding nor content or data, the normalized mean of the
+	// token embeddings is used as embedding.
+	TokenEmbeddings [][]float32
+
+	// Data is optional binary content, like an image, which is embedded with the
+	// collection's image embedding function (see [Collection.SetImageEmbeddingFunc])
+	// when there's neither an embedding nor text content.
+	Data []byte
+	// MIMEType is the MIME type of Data, e.g. "image/png". Optional.
+	MIMEType string
+
+	// EmbeddingText is optional text that the collection embeds instead of the
+	// content, e.g. the content with a contextual header (see
+	// [SplitStageWithHeaders]), while the content is stored for display. It's
+	// ignored if the content is empty. It's stored along with the content, so
+	// that [Collection.Reembed] embeds it again, and a document whose embedding
+	// text changed counts as changed in [Collection.AddDocumentsWithSummary].
+	EmbeddingText string
+
+	// ContentHash is the hex encoded SHA-256 hash of the content, if the
+	// collection stores it in its content store (see
+	// [Collection.EnableContentStore]). The value you set is ignored.
+	ContentHash string
+
+	// Version is set by the collection each time the document is added or
+	// replaced, for optimistic concurrency control with
+	// [Collection.UpsertDocument]. It's taken from a counter of the collection
+	// that starts at 1 and only increases, so a version is never reused for an
+	// ID, even after deleting the document and adding it again. The value you
+	// set is ignored.
+	Version uint64
+
 	// ⚠️ When adding unexported fields here, consider adding a persistence struct
 	// version of this in [DB.Export] and [DB.Import].
 }
@@ -51,3 +98,11 @@ func NewDocument(ctx context.Context, id string, metadata map[string]string, emb
 		Content:   content,
 	}, nil
 }
+
+// embeddingText returns the text that the document's embedding is created from.
+func (d *Document) embeddingText() string {
+	if d.EmbeddingText != "" && d.Content != "" {
+		return d.EmbeddingText
+	}
+	return d.Content
+}
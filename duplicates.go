@@ -0,0 +1,141 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// DuplicatePair is a pair of near-duplicate documents found by
+// [Collection.FindNearDuplicates].
+type DuplicatePair struct {
+	// ID1 is the lower of the two IDs.
+	ID1 string
+	ID2 string
+	// Similarity is the cosine similarity of the two documents' embeddings.
+	Similarity float32
+}
+
+// Outlier is a document whose embedding is far from the others, found by
+// [Collection.FindOutliers].
+type Outlier struct {
+	ID string
+	// Similarity is the cosine similarity of the document's embedding to the
+	// centroid of the documents.
+	Similarity float32
+	// ZScore is the number of standard deviations by which Similarity is below
+	// the mean similarity. It's always positive.
+	ZScore float32
+}
+
+// FindNearDuplicates returns all pairs of documents whose embeddings have
+// a similarity of at least threshold, e.g. to clean up a corpus with the same
+// content from different sources. The pairs are sorted by similarity, highest
+// first. All documents are compared with each other, so it takes quadratic
+// time and is meant for maintenance, not for each query.
+//
+//   - threshold: The minimum similarity, between -1 and 1. Values like 0.95 or
+//     higher find documents with (almost) the same meaning.
+//   - filter: Only documents that match it are compared. The zero value
+//     matches all documents.
+func (c *Collection) FindNearDuplicates(ctx context.Context, threshold float32, filter Filter) ([]DuplicatePair, error) {
+	if threshold < -1 || threshold > 1 {
+		return nil, errors.New("threshold must be between -1 and 1")
+	}
+	docs, err := c.embeddedDocs(nil, filter)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	vectors := normalizedEmbeddings(docs)
+
+	// Each goroutine only writes the pairs of its own documents, so no lock is
+	// needed.
+	pairs := make([][]DuplicatePair, len(docs))
+	err = parallelFor(ctx, len(docs), func(i int) {
+		ea := newEarlyAbandon(vectors[i])
+		for j := i + 1; j < len(docs); j++ {
+			// The dimensions were checked already.
+			sim, _ := ea.dotProduct(vectors[j], threshold)
+			if sim >= threshold {
+				// The documents are sorted by ID, so docs[i] has the lower one.
+				pairs[i] = append(pairs[i], DuplicatePair{ID1: docs[i].ID, ID2: docs[j].ID, Similarity: sim})
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compare documents: %w", err)
+	}
+
+	var res []DuplicatePair
+	for _, p := range pairs {
+		res = append(res, p...)
+	}
+	slices.SortStableFunc(res, func(a, b DuplicatePair) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	return res, nil
+}
+
+// FindOutliers returns the documents whose embeddings are unusually far from
+// the centroid of the documents, e.g. to find content that was embedded with
+// a different model, is empty or garbled, or doesn't belong in the collection.
+// A document is an outlier if its similarity to the centroid is more than
+// minZScore standard deviations below the mean similarity. The outliers are
+// sorted by their z-score, highest first.
+//
+//   - minZScore: The minimum number of standard deviations. Must be > 0,
+//     2 or 3 are common choices.
+//   - filter: Only documents that match it are considered. The zero value
+//     matches all documents.
+func (c *Collection) FindOutliers(ctx context.Context, minZScore float32, filter Filter) ([]Outlier, error) {
+	if minZScore <= 0 {
+		return nil, errors.New("minZScore must be > 0")
+	}
+	docs, err := c.embeddedDocs(nil, filter)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	vectors := normalizedEmbeddings(docs)
+
+	centroid := meanDirection(vectors)
+
+	sims := make([]float32, len(vectors))
+	err = parallelFor(ctx, len(vectors), func(i int) {
+		// The dimensions were checked already.
+		sims[i], _ = dotProduct(centroid, vectors[i])
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't compare documents: %w", err)
+	}
+
+	var sum, sqSum float64
+	for _, sim := range sims {
+		sum += float64(sim)
+		sqSum += float64(sim) * float64(sim)
+	}
+	mean := sum / float64(len(sims))
+	stdDev := math.Sqrt(max(0, sqSum/float64(len(sims))-mean*mean))
+	if stdDev == 0 {
+		// All documents are equally far from the centroid.
+		return nil, nil
+	}
+
+	var res []Outlier
+	for i, sim := range sims {
+		z := float32((mean - float64(sim)) / stdDev)
+		if z > minZScore {
+			res = append(res, Outlier{ID: docs[i].ID, Similarity: sim, ZScore: z})
+		}
+	}
+	slices.SortStableFunc(res, func(a, b Outlier) int {
+		return cmp.Compare(b.ZScore, a.ZScore)
+	})
+	return res, nil
+}
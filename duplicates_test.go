@@ -0,0 +1,85 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollection_FindNearDuplicates(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "a", Embedding: []float32{1, 0, 0}},
+		{ID: "b", Embedding: []float32{0.99, 0.01, 0}},
+		{ID: "c", Embedding: []float32{0.99, 0.01, 0}, Metadata: map[string]string{"source": "web"}},
+		{ID: "d", Embedding: []float32{0, 1, 0}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.FindNearDuplicates(ctx, 1.5, Filter{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	pairs, err := c.FindNearDuplicates(ctx, 0.99, Filter{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(pairs) != 3 {
+		t.Fatal("expected 3 pairs, got", pairs)
+	}
+	// b and c are identical.
+	if pairs[0].ID1 != "b" || pairs[0].ID2 != "c" {
+		t.Fatal("expected b and c first, got", pairs)
+	}
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i].Similarity > pairs[i-1].Similarity {
+			t.Fatal("expected pairs sorted by similarity, got", pairs)
+		}
+	}
+
+	// Filtered
+	pairs, err = c.FindNearDuplicates(ctx, 0.99, Where().Ne("source", "web"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(pairs) != 1 || pairs[0].ID1 != "a" || pairs[0].ID2 != "b" {
+		t.Fatal("expected only a and b, got", pairs)
+	}
+}
+
+func TestCollection_FindOutliers(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var docs []Document
+	for i, v := range []float32{0, 0.01, 0.02, 0.03, 0.04, 0.05, 0.06, 0.07, 0.08, 0.09} {
+		docs = append(docs, Document{ID: string(rune('a' + i)), Embedding: []float32{1, v}})
+	}
+	docs = append(docs, Document{ID: "outlier", Embedding: []float32{0, 1}})
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.FindOutliers(ctx, 0, Filter{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	outliers, err := c.FindOutliers(ctx, 2, Filter{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(outliers) != 1 || outliers[0].ID != "outlier" || outliers[0].ZScore <= 2 {
+		t.Fatal("expected only the outlier, got", outliers)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
@@ -80,11 +79,8 @@ type cohereResponse struct {
 // You can also keep the prefix in the document, and only remove it after querying.
 //
 // We plan to improve this in the future.
-func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) EmbeddingFunc {
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	cfg := newEmbeddingFuncConfig(opts)
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
@@ -121,9 +117,10 @@ func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) Embedding
 		req.Header.Set("Accept", "application/json")
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Authorization", "Bearer "+apiKey)
+		cfg.setHeaders(req)
 
 		// Send the request.
-		resp, err := client.Do(req)
+		resp, err := cfg.client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't send request: %w", err)
 		}
@@ -135,12 +132,13 @@ func NewEmbeddingFuncCohere(apiKey string, model EmbeddingModelCohere) Embedding
 		}
 
 		// Read and decode the response body.
-		body, err := io.ReadAll(resp.Body)
+		body, err := readAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't read response body: %w", err)
 		}
+		defer putBuffer(body)
 		var embeddingResponse cohereResponse
-		err = json.Unmarshal(body, &embeddingResponse)
+		err = json.Unmarshal(body.Bytes(), &embeddingResponse)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
 		}
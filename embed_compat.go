@@ -8,14 +8,38 @@ const (
 
 // NewEmbeddingFuncMistral returns a function that creates embeddings for a text
 // using the Mistral API.
-func NewEmbeddingFuncMistral(apiKey string) EmbeddingFunc {
+func NewEmbeddingFuncMistral(apiKey string, opts ...EmbeddingFuncOption) EmbeddingFunc {
 	// Mistral embeddings are normalized, see section "Distance Measures" on
 	// https://docs.mistral.ai/guides/embeddings/.
 	normalized := true
 
-	// The Mistral API docs don't mention the `encoding_format` as optional,
-	// but it seems to be, just like OpenAI. So we reuse the OpenAI function.
-	return NewEmbeddingFuncOpenAICompat(baseURLMistral, apiKey, embeddingModelMistral, &normalized)
+	// The Mistral API docs list the `encoding_format` as part of the request,
+	// so we set it explicitly. Otherwise the API is OpenAI compatible, so we
+	// reuse the OpenAI function.
+	opts = append([]EmbeddingFuncOption{withBodyParams(map[string]any{"encoding_format": "float"})}, opts...)
+	return NewEmbeddingFuncOpenAICompat(baseURLMistral, apiKey, embeddingModelMistral, &normalized, opts...)
+}
+
+const baseURLTogether = "https://api.together.xyz/v1"
+
+type EmbeddingModelTogether string
+
+const (
+	EmbeddingModelTogetherM2Bert8k  EmbeddingModelTogether = "togethercomputer/m2-bert-80M-8k-retrieval"
+	EmbeddingModelTogetherM2Bert32k EmbeddingModelTogether = "togethercomputer/m2-bert-80M-32k-retrieval"
+	EmbeddingModelTogetherBGEBase   EmbeddingModelTogether = "BAAI/bge-base-en-v1.5"
+	EmbeddingModelTogetherBGELarge  EmbeddingModelTogether = "BAAI/bge-large-en-v1.5"
+	EmbeddingModelTogetherUAELarge  EmbeddingModelTogether = "WhereIsAI/UAE-Large-V1"
+)
+
+// NewEmbeddingFuncTogether returns a function that creates embeddings for a text
+// using the Together AI API.
+// When the API's rate limit is hit, the function returns a [RateLimitError]
+// with the wait time from the response headers.
+func NewEmbeddingFuncTogether(apiKey string, model EmbeddingModelTogether, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	// The API is OpenAI compatible, but not all of the models return normalized
+	// embeddings (e.g. the M2-BERT ones), so we let the function autodetect it.
+	return NewEmbeddingFuncOpenAICompat(baseURLTogether, apiKey, string(model), nil, opts...)
 }
 
 const baseURLJina = "https://api.jina.ai/v1"
@@ -27,12 +51,95 @@ const (
 	EmbeddingModelJina2BaseDE   EmbeddingModelJina = "jina-embeddings-v2-base-de"
 	EmbeddingModelJina2BaseCode EmbeddingModelJina = "jina-embeddings-v2-base-code"
 	EmbeddingModelJina2BaseZH   EmbeddingModelJina = "jina-embeddings-v2-base-zh"
+	EmbeddingModelJina3         EmbeddingModelJina = "jina-embeddings-v3"
+)
+
+// TaskJina is the downstream task that Jina's v3 model optimizes the embeddings for.
+type TaskJina string
+
+const (
+	TaskJinaRetrievalQuery   TaskJina = "retrieval.query"
+	TaskJinaRetrievalPassage TaskJina = "retrieval.passage"
+	TaskJinaTextMatching     TaskJina = "text-matching"
+	TaskJinaClassification   TaskJina = "classification"
+	TaskJinaSeparation       TaskJina = "separation"
 )
 
 // NewEmbeddingFuncJina returns a function that creates embeddings for a text
 // using the Jina API.
-func NewEmbeddingFuncJina(apiKey string, model EmbeddingModelJina) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLJina, apiKey, string(model), nil)
+func NewEmbeddingFuncJina(apiKey string, model EmbeddingModelJina, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return NewEmbeddingFuncOpenAICompat(baseURLJina, apiKey, string(model), nil, opts...)
+}
+
+// NewEmbeddingFuncJinaTask is like [NewEmbeddingFuncJina], but optimizes the
+// embeddings for the given task, which is supported by the v3 model.
+// For retrieval, create the documents with [TaskJinaRetrievalPassage] and
+// the queries with [TaskJinaRetrievalQuery].
+func NewEmbeddingFuncJinaTask(apiKey string, model EmbeddingModelJina, task TaskJina, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	opts = append([]EmbeddingFuncOption{withBodyParams(map[string]any{"task": task})}, opts...)
+	return NewEmbeddingFuncOpenAICompat(baseURLJina, apiKey, string(model), nil, opts...)
+}
+
+// NewEmbeddingFuncJinaBatch is like [NewEmbeddingFuncJina], but returns a
+// function that creates the embeddings of multiple texts with a single request.
+// The Jina API accepts up to 2048 texts per request.
+func NewEmbeddingFuncJinaBatch(apiKey string, model EmbeddingModelJina, opts ...EmbeddingFuncOption) EmbeddingFuncBatch {
+	return NewEmbeddingFuncOpenAICompatBatch(baseURLJina, apiKey, string(model), nil, opts...)
+}
+
+// NewEmbeddingFuncJinaTaskBatch is like [NewEmbeddingFuncJinaTask], but returns
+// a function that creates the embeddings of multiple texts with a single
+// request, like [NewEmbeddingFuncJinaBatch].
+func NewEmbeddingFuncJinaTaskBatch(apiKey string, model EmbeddingModelJina, task TaskJina, opts ...EmbeddingFuncOption) EmbeddingFuncBatch {
+	opts = append([]EmbeddingFuncOption{withBodyParams(map[string]any{"task": task})}, opts...)
+	return NewEmbeddingFuncOpenAICompatBatch(baseURLJina, apiKey, string(model), nil, opts...)
+}
+
+const baseURLVoyage = "https://api.voyageai.com/v1"
+
+type EmbeddingModelVoyage string
+
+const (
+	EmbeddingModelVoyage3      EmbeddingModelVoyage = "voyage-3"
+	EmbeddingModelVoyage3Lite  EmbeddingModelVoyage = "voyage-3-lite"
+	EmbeddingModelVoyageCode2  EmbeddingModelVoyage = "voyage-code-2"
+	EmbeddingModelVoyageLaw2   EmbeddingModelVoyage = "voyage-law-2"
+	EmbeddingModelVoyageMulti2 EmbeddingModelVoyage = "voyage-multilingual-2"
+)
+
+// InputTypeVoyage is the type of the input text, which Voyage AI uses to
+// prepend a retrieval-optimized prompt.
+type InputTypeVoyage string
+
+const (
+	InputTypeVoyageNone     InputTypeVoyage = ""
+	InputTypeVoyageQuery    InputTypeVoyage = "query"
+	InputTypeVoyageDocument InputTypeVoyage = "document"
+)
+
+// NewEmbeddingFuncVoyage returns a function that creates embeddings for a text
+// using the Voyage AI API.
+// For retrieval, create the documents with [InputTypeVoyageDocument] and
+// the queries with [InputTypeVoyageQuery].
+func NewEmbeddingFuncVoyage(apiKey string, model EmbeddingModelVoyage, inputType InputTypeVoyage, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	// Voyage embeddings are normalized, see https://docs.voyageai.com/docs/faq
+	normalized := true
+	if inputType != InputTypeVoyageNone {
+		opts = append([]EmbeddingFuncOption{withBodyParams(map[string]any{"input_type": inputType})}, opts...)
+	}
+	return NewEmbeddingFuncOpenAICompat(baseURLVoyage, apiKey, string(model), &normalized, opts...)
+}
+
+// NewEmbeddingFuncVoyageBatch is like [NewEmbeddingFuncVoyage], but returns a
+// function that creates the embeddings of multiple texts with a single request.
+// The Voyage AI API accepts up to 128 texts per request, or 1000 for the lite
+// models.
+func NewEmbeddingFuncVoyageBatch(apiKey string, model EmbeddingModelVoyage, inputType InputTypeVoyage, opts ...EmbeddingFuncOption) EmbeddingFuncBatch {
+	normalized := true
+	if inputType != InputTypeVoyageNone {
+		opts = append([]EmbeddingFuncOption{withBodyParams(map[string]any{"input_type": inputType})}, opts...)
+	}
+	return NewEmbeddingFuncOpenAICompatBatch(baseURLVoyage, apiKey, string(model), &normalized, opts...)
 }
 
 const baseURLMixedbread = "https://api.mixedbread.ai"
@@ -52,8 +159,8 @@ const (
 
 // NewEmbeddingFuncMixedbread returns a function that creates embeddings for a text
 // using the mixedbread.ai API.
-func NewEmbeddingFuncMixedbread(apiKey string, model EmbeddingModelMixedbread) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLMixedbread, apiKey, string(model), nil)
+func NewEmbeddingFuncMixedbread(apiKey string, model EmbeddingModelMixedbread, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return NewEmbeddingFuncOpenAICompat(baseURLMixedbread, apiKey, string(model), nil, opts...)
 }
 
 const baseURLLocalAI = "http://localhost:8080/v1"
@@ -67,8 +174,33 @@ const baseURLLocalAI = "http://localhost:8080/v1"
 // And then call this constructor with model "bert-cpp-minilm-v6".
 // But other embedding models are supported as well. See the LocalAI documentation
 // for details.
-func NewEmbeddingFuncLocalAI(model string) EmbeddingFunc {
-	return NewEmbeddingFuncOpenAICompat(baseURLLocalAI, "", model, nil)
+func NewEmbeddingFuncLocalAI(model string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return NewEmbeddingFuncOpenAICompat(baseURLLocalAI, "", model, nil, opts...)
+}
+
+const defaultBaseURLLlamaCpp = "http://localhost:8080/v1"
+
+// NewEmbeddingFuncLlamaCpp returns a function that creates embeddings for a text
+// using the OpenAI compatible API of the llama.cpp server, which can run any GGUF
+// embedding model locally, like "nomic-embed-text" or "bge". For example:
+//
+//	llama-server -m nomic-embed-text-v1.5.Q8_0.gguf --embedding --parallel 4
+//
+// The server batches concurrent requests (see its "--parallel" and "--batch-size"
+// flags), so it's best used with [Collection.AddDocuments] and a concurrency
+// matching the server's parallelism.
+// This also works with llamafile, which is based on the llama.cpp server.
+//
+// baseURL is the base URL of the server's OpenAI compatible API. If it's empty,
+// "http://localhost:8080/v1" is used.
+func NewEmbeddingFuncLlamaCpp(baseURL string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	if baseURL == "" {
+		baseURL = defaultBaseURLLlamaCpp
+	}
+	// The server only serves one model, so we don't need to pass any.
+	// Whether the embeddings are normalized depends on the server flags, so we
+	// let it be autodetected.
+	return NewEmbeddingFuncOpenAICompat(baseURL, "", "", nil, opts...)
 }
 
 const (
@@ -79,9 +211,9 @@ const (
 // using the Azure OpenAI API.
 // The `deploymentURL` is the URL of the deployed model, e.g. "https://YOUR_RESOURCE_NAME.openai.azure.com/openai/deployments/YOUR_DEPLOYMENT_NAME"
 // See https://learn.microsoft.com/en-us/azure/ai-services/openai/how-to/embeddings?tabs=console#how-to-get-embeddings
-func NewEmbeddingFuncAzureOpenAI(apiKey string, deploymentURL string, apiVersion string, model string) EmbeddingFunc {
+func NewEmbeddingFuncAzureOpenAI(apiKey string, deploymentURL string, apiVersion string, model string, opts ...EmbeddingFuncOption) EmbeddingFunc {
 	if apiVersion == "" {
 		apiVersion = azureDefaultAPIVersion
 	}
-	return newEmbeddingFuncOpenAICompat(deploymentURL, apiKey, model, nil, map[string]string{"api-key": apiKey}, map[string]string{"api-version": apiVersion})
+	return newEmbeddingFuncOpenAICompat(deploymentURL, apiKey, model, nil, map[string]string{"api-key": apiKey}, map[string]string{"api-version": apiVersion}, opts...)
 }
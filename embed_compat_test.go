@@ -0,0 +1,260 @@
+package chromem_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFuncLlamaCpp(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	// Mock server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check URL
+		if r.URL.Path != "/v1/embeddings" {
+			t.Fatal("expected URL", "/v1/embeddings", "got", r.URL.Path)
+		}
+		// Check body
+		var body map[string]string
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if body["input"] != "hello world" {
+			t.Fatal("expected input", "hello world", "got", body["input"])
+		}
+
+		// Write response
+		resp := openAIResponse{
+			Data: []struct {
+				Embedding []float32 `json:"embedding"`
+			}{
+				{Embedding: []float32{-0.1, 0.1, 0.2}},
+			},
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	f := chromem.NewEmbeddingFuncLlamaCpp(ts.URL + "/v1")
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
+
+func TestNewEmbeddingFuncJinaTask_Voyage(t *testing.T) {
+	apiKey := "secret"
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655}
+
+	tt := []struct {
+		name      string
+		f         func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFunc
+		wantHost  string
+		wantModel string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name: "Jina",
+			f: func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFunc {
+				return chromem.NewEmbeddingFuncJinaTask(apiKey, chromem.EmbeddingModelJina3, chromem.TaskJinaRetrievalQuery, opts...)
+			},
+			wantHost:  "api.jina.ai",
+			wantModel: "jina-embeddings-v3",
+			wantKey:   "task",
+			wantValue: "retrieval.query",
+		},
+		{
+			name: "Voyage",
+			f: func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFunc {
+				return chromem.NewEmbeddingFuncVoyage(apiKey, chromem.EmbeddingModelVoyage3, chromem.InputTypeVoyageDocument, opts...)
+			},
+			wantHost:  "api.voyageai.com",
+			wantModel: "voyage-3",
+			wantKey:   "input_type",
+			wantValue: "document",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The base URLs are fixed, so we intercept the requests in the client.
+			client := &http.Client{
+				Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					if r.URL.Host != tc.wantHost {
+						t.Fatal("expected host", tc.wantHost, "got", r.URL.Host)
+					}
+					if r.Header.Get("Authorization") != "Bearer "+apiKey {
+						t.Fatal("expected Authorization header", "Bearer "+apiKey, "got", r.Header.Get("Authorization"))
+					}
+					var body map[string]string
+					err := json.NewDecoder(r.Body).Decode(&body)
+					if err != nil {
+						t.Fatal("unexpected error:", err)
+					}
+					if body["model"] != tc.wantModel {
+						t.Fatal("expected model", tc.wantModel, "got", body["model"])
+					}
+					if body[tc.wantKey] != tc.wantValue {
+						t.Fatal("expected", tc.wantKey, tc.wantValue, "got", body[tc.wantKey])
+					}
+
+					rec := httptest.NewRecorder()
+					_ = json.NewEncoder(rec).Encode(openAIResponse{
+						Data: []struct {
+							Embedding []float32 `json:"embedding"`
+						}{
+							{Embedding: wantRes},
+						},
+					})
+					return rec.Result(), nil
+				}),
+			}
+
+			res, err := tc.f(chromem.WithHTTPClient(client))(context.Background(), "hello world")
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if slices.Compare(wantRes, res) != 0 {
+				t.Fatal("expected res", wantRes, "got", res)
+			}
+		})
+	}
+}
+
+func TestNewEmbeddingFuncTogether_RateLimit(t *testing.T) {
+	// Mock server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.Header().Set("X-RateLimit-Reset", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	// The base URL is fixed, so we redirect the requests in the client.
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host != "api.together.xyz" {
+				t.Fatal("expected host", "api.together.xyz", "got", r.URL.Host)
+			}
+			r.URL.Scheme = "http"
+			r.URL.Host = ts.Listener.Addr().String()
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+
+	f := chromem.NewEmbeddingFuncTogether("secret", chromem.EmbeddingModelTogetherBGEBase, chromem.WithHTTPClient(client))
+	_, err := f(context.Background(), "hello world")
+	var rlErr *chromem.RateLimitError
+	if !errors.As(err, &rlErr) {
+		t.Fatal("expected RateLimitError, got", err)
+	}
+	if rlErr.RetryAfter != 3*time.Second {
+		t.Fatal("expected retry after", 3*time.Second, "got", rlErr.RetryAfter)
+	}
+}
+
+func TestNewEmbeddingFuncJinaTaskBatch_VoyageBatch(t *testing.T) {
+	apiKey := "secret"
+	texts := []string{"a", "b", "c"}
+	// The embeddings are returned out of order, with their index in the input.
+	vectors := map[string][]float32{
+		"a": {1, 0, 0},
+		"b": {0, 1, 0},
+		"c": {0, 0, 1},
+	}
+
+	tt := []struct {
+		name      string
+		f         func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFuncBatch
+		wantHost  string
+		wantKey   string
+		wantValue string
+	}{
+		{
+			name: "Jina",
+			f: func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFuncBatch {
+				return chromem.NewEmbeddingFuncJinaTaskBatch(apiKey, chromem.EmbeddingModelJina3, chromem.TaskJinaRetrievalPassage, opts...)
+			},
+			wantHost:  "api.jina.ai",
+			wantKey:   "task",
+			wantValue: "retrieval.passage",
+		},
+		{
+			name: "Voyage",
+			f: func(opts ...chromem.EmbeddingFuncOption) chromem.EmbeddingFuncBatch {
+				return chromem.NewEmbeddingFuncVoyageBatch(apiKey, chromem.EmbeddingModelVoyage3, chromem.InputTypeVoyageDocument, opts...)
+			},
+			wantHost:  "api.voyageai.com",
+			wantKey:   "input_type",
+			wantValue: "document",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &http.Client{
+				Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					if r.URL.Host != tc.wantHost {
+						t.Fatal("expected host", tc.wantHost, "got", r.URL.Host)
+					}
+					var body struct {
+						Input     []string `json:"input"`
+						Task      string   `json:"task"`
+						InputType string   `json:"input_type"`
+					}
+					err := json.NewDecoder(r.Body).Decode(&body)
+					if err != nil {
+						t.Fatal("unexpected error:", err)
+					}
+					if slices.Compare(body.Input, texts) != 0 {
+						t.Fatal("expected input", texts, "got", body.Input)
+					}
+					if value := body.Task + body.InputType; value != tc.wantValue {
+						t.Fatal("expected", tc.wantKey, tc.wantValue, "got", value)
+					}
+
+					type data struct {
+						Index     int       `json:"index"`
+						Embedding []float32 `json:"embedding"`
+					}
+					var resp struct {
+						Data []data `json:"data"`
+					}
+					for i := len(body.Input) - 1; i >= 0; i-- {
+						resp.Data = append(resp.Data, data{Index: i, Embedding: vectors[body.Input[i]]})
+					}
+					rec := httptest.NewRecorder()
+					_ = json.NewEncoder(rec).Encode(resp)
+					return rec.Result(), nil
+				}),
+			}
+
+			res, err := tc.f(chromem.WithHTTPClient(client))(context.Background(), texts)
+			if err != nil {
+				t.Fatal("expected nil, got", err)
+			}
+			if len(res) != len(texts) {
+				t.Fatal("expected", len(texts), "embeddings, got", len(res))
+			}
+			for i, text := range texts {
+				if slices.Compare(vectors[text], res[i]) != 0 {
+					t.Fatal("expected", vectors[text], "for", text, "got", res[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package chromem
+
+import "context"
+
+// EmbeddingOperation is the operation for which an embedding is created, see
+// [EmbeddingCallInfo].
+type EmbeddingOperation string
+
+const (
+	// EmbeddingOperationAdd is the embedding of a document that's added.
+	EmbeddingOperationAdd EmbeddingOperation = "add"
+	// EmbeddingOperationQuery is the embedding of a query.
+	EmbeddingOperationQuery EmbeddingOperation = "query"
+	// EmbeddingOperationReembed is the embedding of a document by
+	// [Collection.Reembed].
+	EmbeddingOperationReembed EmbeddingOperation = "reembed"
+)
+
+// EmbeddingCallInfo describes a call of an [EmbeddingFunc] or
+// [ImageEmbeddingFunc] by a collection. It's passed via the context, so that
+// embedding functions and middlewares can log, rate-limit or route per
+// collection without global state. See [EmbeddingCallInfoFromContext].
+type EmbeddingCallInfo struct {
+	// Collection is the name of the collection, or of the namespace for
+	// namespaces (see [Collection.Namespace]).
+	Collection string
+	// DocumentID is the ID of the embedded document. It's empty for queries.
+	DocumentID string
+	Operation  EmbeddingOperation
+}
+
+type embeddingCallInfoContextKey struct{}
+
+// EmbeddingCallInfoFromContext returns the info about the call of the embedding
+// function, and whether it exists. It doesn't exist when the embedding function
+// is called directly instead of by a collection, e.g. by [NewDocument].
+func EmbeddingCallInfoFromContext(ctx context.Context) (EmbeddingCallInfo, bool) {
+	info, ok := ctx.Value(embeddingCallInfoContextKey{}).(EmbeddingCallInfo)
+	return info, ok
+}
+
+// embeddingContext returns a context with the info about the embedding call.
+func embeddingContext(ctx context.Context, collection string, op EmbeddingOperation, docID string) context.Context {
+	return context.WithValue(ctx, embeddingCallInfoContextKey{}, EmbeddingCallInfo{
+		Collection: collection,
+		DocumentID: docID,
+		Operation:  op,
+	})
+}
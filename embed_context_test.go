@@ -0,0 +1,59 @@
+package chromem_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestEmbeddingCallInfoFromContext(t *testing.T) {
+	ctx := context.Background()
+	var infos []chromem.EmbeddingCallInfo
+	lock := sync.Mutex{}
+	f := func(ctx context.Context, _ string) ([]float32, error) {
+		info, ok := chromem.EmbeddingCallInfoFromContext(ctx)
+		if !ok {
+			t.Error("expected call info, got none")
+		}
+		lock.Lock()
+		infos = append(infos, info)
+		lock.Unlock()
+		return []float32{1, 0}, nil
+	}
+
+	db := chromem.NewDB()
+	c, err := db.CreateCollection("test", nil, f)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, chromem.Document{ID: "1", Content: "foo"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "foo", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Reembed(ctx, f, "new-model", 1, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	expected := []chromem.EmbeddingCallInfo{
+		{Collection: "test", DocumentID: "1", Operation: chromem.EmbeddingOperationAdd},
+		{Collection: "test", Operation: chromem.EmbeddingOperationQuery},
+		{Collection: "test", DocumentID: "1", Operation: chromem.EmbeddingOperationReembed},
+	}
+	if !slices.Equal(infos, expected) {
+		t.Fatal("expected", expected, "got", infos)
+	}
+
+	// Not set for direct calls
+	_, ok := chromem.EmbeddingCallInfoFromContext(ctx)
+	if ok {
+		t.Fatal("expected no call info, got one")
+	}
+}
@@ -0,0 +1,136 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFallbackFailureThreshold = 3
+	defaultFallbackCooldown         = 30 * time.Second
+)
+
+// circuitBreaker tracks consecutive failures of an embedding provider. When the
+// threshold is reached, the circuit opens and the provider is skipped until the
+// cooldown has passed. Then one trial request is let through ("half-open"),
+// which closes the circuit on success and opens it again on failure.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	failures  int
+	openUntil time.Time
+	lock      sync.Mutex
+}
+
+// allow returns whether a request to the provider is allowed.
+func (cb *circuitBreaker) allow(now time.Time) bool {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+
+	if cb.failures < cb.threshold {
+		return true
+	}
+	if now.Before(cb.openUntil) {
+		return false
+	}
+	// Half-open: Let this request through, but block others until it's done
+	// or the cooldown passed again.
+	cb.openUntil = now.Add(cb.cooldown)
+	return true
+}
+
+func (cb *circuitBreaker) success() {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.failures = 0
+}
+
+func (cb *circuitBreaker) failure(now time.Time) {
+	cb.lock.Lock()
+	defer cb.lock.Unlock()
+	cb.failures++
+	if cb.failures >= cb.threshold {
+		cb.openUntil = now.Add(cb.cooldown)
+	}
+}
+
+// NewEmbeddingFuncFallback returns a function that tries the given embedding
+// functions in order, until one succeeds. For example a local Ollama instance
+// first, then a hosted API. This keeps ingestion and queries available when one
+// provider is down.
+//
+// ⚠️ All functions must create compatible embeddings, i.e. use the same model!
+// Embeddings of different models can't be compared with each other. So this is
+// meant for example for multiple instances or deployments of the same model, like
+// OpenAI and Azure OpenAI, or Ollama on different hosts.
+//
+// Each provider has a circuit breaker: After failureThreshold consecutive
+// failures, the provider is skipped for the cooldown duration, after which a
+// single trial request is let through.
+//
+//   - funcs: The embedding functions, in the order of preference. Mandatory.
+//   - timeout: The timeout per provider call. Optional, 0 means no timeout other
+//     than the one of the context that's passed to the returned function.
+//   - failureThreshold: The number of consecutive failures after which a provider
+//     is skipped. Optional, defaults to 3 if <= 0.
+//   - cooldown: How long a provider is skipped. Optional, defaults to 30 seconds
+//     if <= 0.
+func NewEmbeddingFuncFallback(funcs []EmbeddingFunc, timeout time.Duration, failureThreshold int, cooldown time.Duration) EmbeddingFunc {
+	if failureThreshold <= 0 {
+		failureThreshold = defaultFallbackFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultFallbackCooldown
+	}
+	breakers := make([]*circuitBreaker, len(funcs))
+	for i := range funcs {
+		breakers[i] = &circuitBreaker{
+			threshold: failureThreshold,
+			cooldown:  cooldown,
+		}
+	}
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		if len(funcs) == 0 {
+			return nil, errors.New("no embedding functions configured")
+		}
+
+		var errs []error
+		for i, f := range funcs {
+			if !breakers[i].allow(time.Now()) {
+				errs = append(errs, fmt.Errorf("provider %d: skipped due to previous failures", i))
+				continue
+			}
+
+			v, err := callWithTimeout(ctx, f, text, timeout)
+			if err == nil {
+				breakers[i].success()
+				return v, nil
+			}
+			// If the caller's context is done, it's not the provider's fault,
+			// and there's no point in trying the other providers.
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("couldn't create embedding: %w", ctx.Err())
+			}
+			breakers[i].failure(time.Now())
+			errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+		}
+
+		return nil, fmt.Errorf("all embedding providers failed: %w", errors.Join(errs...))
+	}
+}
+
+// callWithTimeout calls the embedding function with a context that has the
+// given timeout, if it's > 0.
+func callWithTimeout(ctx context.Context, f EmbeddingFunc, text string, timeout time.Duration) ([]float32, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return f(ctx, text)
+}
@@ -0,0 +1,104 @@
+package chromem_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFuncFallback(t *testing.T) {
+	ctx := context.Background()
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	var calls1, calls2 int
+	down := true
+	f1 := func(_ context.Context, _ string) ([]float32, error) {
+		calls1++
+		if down {
+			return nil, errors.New("connection refused")
+		}
+		return wantRes, nil
+	}
+	f2 := func(_ context.Context, _ string) ([]float32, error) {
+		calls2++
+		return wantRes, nil
+	}
+
+	cooldown := 50 * time.Millisecond
+	f := chromem.NewEmbeddingFuncFallback([]chromem.EmbeddingFunc{f1, f2}, 0, 2, cooldown)
+
+	// The first provider fails twice, then its circuit opens
+	for i := 0; i < 3; i++ {
+		res, err := f(ctx, "hello world")
+		if err != nil {
+			t.Fatal("expected nil, got", err)
+		}
+		if !slices.Equal(res, wantRes) {
+			t.Fatal("expected res", wantRes, "got", res)
+		}
+	}
+	if calls1 != 2 {
+		t.Fatal("expected 2 calls of first provider, got", calls1)
+	}
+	if calls2 != 3 {
+		t.Fatal("expected 3 calls of second provider, got", calls2)
+	}
+
+	// After the cooldown the first provider is tried again
+	down = false
+	time.Sleep(cooldown)
+	_, err := f(ctx, "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if calls1 != 3 {
+		t.Fatal("expected 3 calls of first provider, got", calls1)
+	}
+	if calls2 != 3 {
+		t.Fatal("expected 3 calls of second provider, got", calls2)
+	}
+}
+
+func TestNewEmbeddingFuncFallback_Timeout(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+	slow := func(ctx context.Context, _ string) ([]float32, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	fast := func(_ context.Context, _ string) ([]float32, error) {
+		return wantRes, nil
+	}
+
+	f := chromem.NewEmbeddingFuncFallback([]chromem.EmbeddingFunc{slow, fast}, 10*time.Millisecond, 0, 0)
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if !slices.Equal(res, wantRes) {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
+
+func TestNewEmbeddingFuncFallback_AllFail(t *testing.T) {
+	failing := func(_ context.Context, _ string) ([]float32, error) {
+		return nil, errors.New("boom")
+	}
+
+	f := chromem.NewEmbeddingFuncFallback([]chromem.EmbeddingFunc{failing, failing}, 0, 0, 0)
+	_, err := f(context.Background(), "hello world")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = f(ctx, "hello world")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+}
@@ -0,0 +1,186 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const baseURLHuggingFace = "https://api-inference.huggingface.co/pipeline/feature-extraction"
+
+// NewEmbeddingFuncHuggingFace returns a function that creates embeddings for a text
+// using the Hugging Face Inference API. You can pass any sentence-transformers
+// model, e.g. "sentence-transformers/all-MiniLM-L6-v2" or "BAAI/bge-small-en-v1.5".
+// Models that return token embeddings instead of sentence embeddings are not
+// supported.
+func NewEmbeddingFuncHuggingFace(apiKey, model string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return newEmbeddingFuncHuggingFace(baseURLHuggingFace+"/"+model, apiKey, opts...)
+}
+
+// NewEmbeddingFuncHuggingFaceInferenceEndpoint returns a function that creates
+// embeddings for a text using a dedicated Hugging Face Inference Endpoint.
+// The endpointURL is the URL of the deployed endpoint, e.g.
+// "https://YOUR_ENDPOINT.endpoints.huggingface.cloud".
+func NewEmbeddingFuncHuggingFaceInferenceEndpoint(endpointURL, apiKey string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return newEmbeddingFuncHuggingFace(endpointURL, apiKey, opts...)
+}
+
+func newEmbeddingFuncHuggingFace(url, apiKey string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	cfg := newEmbeddingFuncConfig(opts)
+
+	var checkedNormalized bool
+	checkNormalized := sync.Once{}
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		// Prepare the request body.
+		reqBody, err := json.Marshal(map[string]any{
+			"inputs": text,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout.
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		cfg.setHeaders(req)
+
+		// Send the request.
+		resp, err := cfg.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("error response from the embedding API: " + resp.Status)
+		}
+
+		// Read and decode the response body.
+		body, err := readAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		defer putBuffer(body)
+		// Depending on the model, the API returns the embedding either directly
+		// or wrapped in an array.
+		var v []float32
+		err = json.Unmarshal(body.Bytes(), &v)
+		if err != nil {
+			var wrapped [][]float32
+			if err2 := json.Unmarshal(body.Bytes(), &wrapped); err2 != nil {
+				return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+			}
+			if len(wrapped) != 1 {
+				return nil, errors.New("the model returned token embeddings instead of a sentence embedding")
+			}
+			v = wrapped[0]
+		}
+
+		// Check if the response contains embeddings.
+		if len(v) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+
+		checkNormalized.Do(func() {
+			if isNormalized(v) {
+				checkedNormalized = true
+			} else {
+				checkedNormalized = false
+			}
+		})
+		if !checkedNormalized {
+			v = normalizeVector(v)
+		}
+
+		return v, nil
+	}
+}
+
+const defaultBaseURLTEI = "http://localhost:8080"
+
+// NewEmbeddingFuncTEI returns a function that creates embeddings for a text
+// using a self-hosted Hugging Face text-embeddings-inference (TEI) server.
+// See https://github.com/huggingface/text-embeddings-inference.
+// It uses the server's native "/embed" endpoint with its batch format.
+// TEI normalizes the embeddings by default.
+//
+//   - baseURL: The base URL of the TEI server. If it's empty, "http://localhost:8080"
+//     is used.
+//   - apiKey: Optional, for servers started with "--api-key".
+//   - truncate: Whether the server should truncate texts that are longer than
+//     the model's maximum input length, instead of returning an error.
+func NewEmbeddingFuncTEI(baseURL, apiKey string, truncate bool, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	if baseURL == "" {
+		baseURL = defaultBaseURLTEI
+	}
+
+	cfg := newEmbeddingFuncConfig(opts)
+
+	return func(ctx context.Context, text string) ([]float32, error) {
+		// Prepare the request body.
+		reqBody, err := json.Marshal(map[string]any{
+			"inputs":    []string{text},
+			"normalize": true,
+			"truncate":  truncate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout.
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embed", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		cfg.setHeaders(req)
+
+		// Send the request.
+		resp, err := cfg.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("error response from the embedding API: " + resp.Status)
+		}
+
+		// Read and decode the response body.
+		body, err := readAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		defer putBuffer(body)
+		var embeddingResponse [][]float32
+		err = json.Unmarshal(body.Bytes(), &embeddingResponse)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+
+		// Check if the response contains embeddings.
+		if len(embeddingResponse) == 0 || len(embeddingResponse[0]) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+
+		return embeddingResponse[0], nil
+	}
+}
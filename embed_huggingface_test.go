@@ -0,0 +1,95 @@
+package chromem_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFuncHuggingFaceInferenceEndpoint(t *testing.T) {
+	apiKey := "secret"
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	for _, wrapped := range []bool{false, true} {
+		// Mock server
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Check headers
+			if r.Header.Get("Authorization") != "Bearer "+apiKey {
+				t.Fatal("expected Authorization header", "Bearer "+apiKey, "got", r.Header.Get("Authorization"))
+			}
+			// Check body
+			var body map[string]string
+			err := json.NewDecoder(r.Body).Decode(&body)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if body["inputs"] != "hello world" {
+				t.Fatal("expected inputs", "hello world", "got", body["inputs"])
+			}
+
+			// Write response
+			w.WriteHeader(http.StatusOK)
+			if wrapped {
+				_ = json.NewEncoder(w).Encode([][]float32{{-0.1, 0.1, 0.2}})
+			} else {
+				_ = json.NewEncoder(w).Encode([]float32{-0.1, 0.1, 0.2})
+			}
+		}))
+
+		f := chromem.NewEmbeddingFuncHuggingFaceInferenceEndpoint(ts.URL, apiKey)
+		res, err := f(context.Background(), "hello world")
+		ts.Close()
+		if err != nil {
+			t.Fatal("expected nil, got", err)
+		}
+		if slices.Compare(wantRes, res) != 0 {
+			t.Fatal("expected res", wantRes, "got", res)
+		}
+	}
+}
+
+func TestNewEmbeddingFuncTEI(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	// Mock server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check URL
+		if r.URL.Path != "/embed" {
+			t.Fatal("expected URL", "/embed", "got", r.URL.Path)
+		}
+		// Check body
+		var body struct {
+			Inputs   []string `json:"inputs"`
+			Truncate bool     `json:"truncate"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if !slices.Equal(body.Inputs, []string{"hello world"}) {
+			t.Fatal("expected inputs", []string{"hello world"}, "got", body.Inputs)
+		}
+		if !body.Truncate {
+			t.Fatal("expected truncate to be true")
+		}
+
+		// Write response
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode([][]float32{wantRes})
+	}))
+	defer ts.Close()
+
+	f := chromem.NewEmbeddingFuncTEI(ts.URL, "", true)
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
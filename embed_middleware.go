@@ -0,0 +1,181 @@
+package chromem
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// EmbeddingMiddleware decorates an [EmbeddingFunc] with additional behavior,
+// like caching or retries. Use [ChainEmbeddingFunc] to apply multiple ones.
+type EmbeddingMiddleware func(EmbeddingFunc) EmbeddingFunc
+
+// ChainEmbeddingFunc decorates the embedding function with the given middlewares.
+// The first middleware is the outermost one, so for example:
+//
+//	f := chromem.ChainEmbeddingFunc(
+//		chromem.NewEmbeddingFuncOpenAI(apiKey, chromem.EmbeddingModelOpenAI3Small),
+//		chromem.WithEmbeddingCache(10_000),
+//		chromem.WithEmbeddingRetry(3, time.Second),
+//		chromem.WithEmbeddingTimeout(10*time.Second),
+//	)
+//
+// first checks the cache, and only on a cache miss calls the function with retries,
+// with each try having a timeout of 10 seconds.
+func ChainEmbeddingFunc(f EmbeddingFunc, middlewares ...EmbeddingMiddleware) EmbeddingFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		f = middlewares[i](f)
+	}
+	return f
+}
+
+// WithEmbeddingCache returns a middleware that caches up to size embeddings in
+// memory, keyed by the text's SHA-256 hash. The least recently used embeddings
+// are evicted first. This is useful for example for repeated queries. Cache
+// hits are reported by [Collection.AddDocumentsWithSummary].
+func WithEmbeddingCache(size int) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		cache := newLRUCache[[sha256.Size]byte, []float32](max(size, 1))
+		return func(ctx context.Context, text string) ([]float32, error) {
+			key := sha256.Sum256([]byte(text))
+			if v, ok := cache.get(key); ok {
+				if stats := addStatsFromContext(ctx); stats != nil {
+					stats.cached.Add(1)
+				}
+				// Copy so that callers can't modify the cached vector.
+				return slices.Clone(v), nil
+			}
+
+			v, err := next(ctx, text)
+			if err != nil {
+				return nil, err
+			}
+			cache.put(key, slices.Clone(v))
+			return v, nil
+		}
+	}
+}
+
+// WithEmbeddingRetry returns a middleware that retries failed calls up to
+// maxRetries times, with exponential backoff starting at initialBackoff.
+// It stops early when the context is done.
+// When the error is a [RateLimitError], it waits at least as long as the API
+// asks for.
+func WithEmbeddingRetry(maxRetries int, initialBackoff time.Duration) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			backoff := initialBackoff
+			var errs []error
+			for i := 0; ; i++ {
+				v, err := next(ctx, text)
+				if err == nil {
+					return v, nil
+				}
+				errs = append(errs, err)
+				if i >= maxRetries {
+					break
+				}
+
+				wait := backoff
+				var rlErr *RateLimitError
+				if errors.As(err, &rlErr) {
+					wait = max(wait, rlErr.RetryAfter)
+				}
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, fmt.Errorf("couldn't create embedding: %w", errors.Join(append(errs, ctx.Err())...))
+				case <-timer.C:
+				}
+				backoff *= 2
+			}
+			return nil, fmt.Errorf("couldn't create embedding after %d retries: %w", maxRetries, errors.Join(errs...))
+		}
+	}
+}
+
+// WithEmbeddingRateLimit returns a middleware that limits the calls to the
+// embedding function to n per the given duration, for example 3000 per minute.
+// The calls are spread evenly, so there are no bursts. Calls wait until they're
+// allowed or the context is done.
+func WithEmbeddingRateLimit(n int, per time.Duration) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		interval := per / time.Duration(max(n, 1))
+		var nextSlot time.Time
+		lock := sync.Mutex{}
+
+		return func(ctx context.Context, text string) ([]float32, error) {
+			// Reserve a slot
+			lock.Lock()
+			now := time.Now()
+			slot := nextSlot
+			if slot.Before(now) {
+				slot = now
+			}
+			nextSlot = slot.Add(interval)
+			lock.Unlock()
+
+			if wait := time.Until(slot); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, fmt.Errorf("couldn't create embedding: %w", ctx.Err())
+				case <-timer.C:
+				}
+			}
+
+			return next(ctx, text)
+		}
+	}
+}
+
+// WithEmbeddingTimeout returns a middleware that sets a timeout for each call to
+// the embedding function.
+func WithEmbeddingTimeout(timeout time.Duration) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			return callWithTimeout(ctx, next, text, timeout)
+		}
+	}
+}
+
+// WithEmbeddingPrefix returns a middleware that prepends the prefix to each
+// text, for models that expect an instruction prefix, like "search_query: " and
+// "search_document: " for Nomic's "nomic-embed-text-v1.5", or "query: " and
+// "passage: " for the E5 models. For the query prefix, you can wrap the
+// collection's embedding function and pass it via [QueryOptions.EmbeddingFunc].
+func WithEmbeddingPrefix(prefix string) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			return next(ctx, prefix+text)
+		}
+	}
+}
+
+// WithEmbeddingLogging returns a middleware that logs each call to the embedding
+// function with its duration and error, if any. The text itself isn't logged,
+// only its length. If logger is nil, [slog.Default] is used.
+func WithEmbeddingLogging(logger *slog.Logger) EmbeddingMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			start := time.Now()
+			v, err := next(ctx, text)
+			if err != nil {
+				logger.ErrorContext(ctx, "Couldn't create embedding", "textLength", len(text), "duration", time.Since(start), "error", err)
+				return nil, err
+			}
+			logger.DebugContext(ctx, "Created embedding", "textLength", len(text), "dimensions", len(v), "duration", time.Since(start))
+			return v, nil
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package chromem_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestChainEmbeddingFunc(t *testing.T) {
+	var order []string
+	mw := func(name string) chromem.EmbeddingMiddleware {
+		return func(next chromem.EmbeddingFunc) chromem.EmbeddingFunc {
+			return func(ctx context.Context, text string) ([]float32, error) {
+				order = append(order, name)
+				return next(ctx, text)
+			}
+		}
+	}
+	f := func(_ context.Context, _ string) ([]float32, error) {
+		order = append(order, "f")
+		return []float32{1}, nil
+	}
+
+	_, err := chromem.ChainEmbeddingFunc(f, mw("a"), mw("b"))(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if !slices.Equal(order, []string{"a", "b", "f"}) {
+		t.Fatal("expected order a, b, f, got", order)
+	}
+}
+
+func TestWithEmbeddingCache(t *testing.T) {
+	calls := 0
+	f := func(_ context.Context, text string) ([]float32, error) {
+		calls++
+		return []float32{float32(len(text))}, nil
+	}
+	cached := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingCache(1))
+
+	ctx := context.Background()
+	for _, text := range []string{"a", "a", "bb", "a"} {
+		_, err := cached(ctx, text)
+		if err != nil {
+			t.Fatal("expected nil, got", err)
+		}
+	}
+	// "a" was evicted by "bb"
+	if calls != 3 {
+		t.Fatal("expected 3 calls, got", calls)
+	}
+
+	// Modifying the result doesn't modify the cache
+	v, _ := cached(ctx, "a")
+	v[0] = 42
+	v, _ = cached(ctx, "a")
+	if v[0] != 1 {
+		t.Fatal("expected 1, got", v[0])
+	}
+}
+
+func TestWithEmbeddingRetry(t *testing.T) {
+	calls := 0
+	f := func(_ context.Context, _ string) ([]float32, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("rate limited")
+		}
+		return []float32{1}, nil
+	}
+
+	_, err := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingRetry(2, time.Millisecond))(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if calls != 3 {
+		t.Fatal("expected 3 calls, got", calls)
+	}
+
+	calls = 0
+	_, err = chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingRetry(1, time.Millisecond))(context.Background(), "hello")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 2 {
+		t.Fatal("expected 2 calls, got", calls)
+	}
+}
+
+func TestWithEmbeddingRateLimit(t *testing.T) {
+	f := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1}, nil
+	}
+	limited := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingRateLimit(100, time.Second))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := limited(context.Background(), "hello")
+		if err != nil {
+			t.Fatal("expected nil, got", err)
+		}
+	}
+	// The first call is immediate, the other two wait 10ms each
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatal("expected at least 20ms, got", elapsed)
+	}
+
+	// Canceled context while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = limited(ctx, "hello") // Might get a slot immediately
+	_, err := limited(ctx, "hello")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+}
+
+func TestWithEmbeddingTimeout(t *testing.T) {
+	f := func(ctx context.Context, _ string) ([]float32, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	_, err := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingTimeout(time.Millisecond))(context.Background(), "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded, got", err)
+	}
+}
+
+func TestWithEmbeddingPrefix(t *testing.T) {
+	f := func(_ context.Context, text string) ([]float32, error) {
+		if text != "search_query: hello" {
+			t.Fatal("expected prefixed text, got", text)
+		}
+		return []float32{1}, nil
+	}
+	_, err := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingPrefix("search_query: "))(context.Background(), "hello")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
+
+func TestWithEmbeddingLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	f := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 2}, nil
+	}
+
+	_, err := chromem.ChainEmbeddingFunc(f, chromem.WithEmbeddingLogging(logger))(context.Background(), "secret text")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if !strings.Contains(buf.String(), "dimensions=2") {
+		t.Fatal("expected dimensions in log, got", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret text") {
+		t.Fatal("expected text to not be logged, got", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+type EmbeddingModelJinaCLIP string
+
+const (
+	EmbeddingModelJinaCLIP1 EmbeddingModelJinaCLIP = "jina-clip-v1"
+	EmbeddingModelJinaCLIP2 EmbeddingModelJinaCLIP = "jina-clip-v2"
+)
+
+// NewEmbeddingFuncJinaCLIP returns a function that creates embeddings for a text
+// using a Jina CLIP model via the Jina API. Use it together with
+// [NewImageEmbeddingFuncJinaCLIP] for a collection with both texts and images.
+func NewEmbeddingFuncJinaCLIP(apiKey string, model EmbeddingModelJinaCLIP, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	f := newEmbeddingFuncJinaCLIP(apiKey, model, opts...)
+	return func(ctx context.Context, text string) ([]float32, error) {
+		return f(ctx, map[string]string{"text": text})
+	}
+}
+
+// NewImageEmbeddingFuncJinaCLIP returns a function that creates embeddings for an
+// image using a Jina CLIP model via the Jina API. The image is sent base64
+// encoded, and the MIME type is ignored as the API detects it.
+// See [Collection.SetImageEmbeddingFunc].
+func NewImageEmbeddingFuncJinaCLIP(apiKey string, model EmbeddingModelJinaCLIP, opts ...EmbeddingFuncOption) ImageEmbeddingFunc {
+	f := newEmbeddingFuncJinaCLIP(apiKey, model, opts...)
+	return func(ctx context.Context, data []byte, _ string) ([]float32, error) {
+		return f(ctx, map[string]string{"image": base64.StdEncoding.EncodeToString(data)})
+	}
+}
+
+// newEmbeddingFuncJinaCLIP returns a function that creates an embedding for
+// one input object, which is either {"text": ...} or {"image": ...}.
+func newEmbeddingFuncJinaCLIP(apiKey string, model EmbeddingModelJinaCLIP, opts ...EmbeddingFuncOption) func(ctx context.Context, input map[string]string) ([]float32, error) {
+	cfg := newEmbeddingFuncConfig(opts)
+	baseURL := baseURLJina
+	if cfg.baseURL != "" {
+		baseURL = cfg.baseURL
+	}
+
+	var checkedNormalized bool
+	checkNormalized := sync.Once{}
+
+	return func(ctx context.Context, input map[string]string) ([]float32, error) {
+		// Prepare the request body.
+		reqBody, err := json.Marshal(map[string]any{
+			"model": model,
+			"input": []map[string]string{input},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		// Create the request. Creating it with context is important for a timeout
+		// to be possible, because the client is configured without a timeout.
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/embeddings", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		cfg.setHeaders(req)
+
+		// Send the request.
+		resp, err := cfg.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		// Check the response status.
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, newRateLimitError(resp)
+		} else if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("error response from the embedding API: " + resp.Status)
+		}
+
+		// Read and decode the response body. The response is OpenAI compatible.
+		body, err := readAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read response body: %w", err)
+		}
+		defer putBuffer(body)
+		var embeddingResponse openAIResponse
+		err = json.Unmarshal(body.Bytes(), &embeddingResponse)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+
+		// Check if the response contains embeddings.
+		if len(embeddingResponse.Data) == 0 || len(embeddingResponse.Data[0].Embedding) == 0 {
+			return nil, errors.New("no embeddings found in the response")
+		}
+
+		v := embeddingResponse.Data[0].Embedding
+		checkNormalized.Do(func() {
+			checkedNormalized = isNormalized(v)
+		})
+		if !checkedNormalized {
+			v = normalizeVector(v)
+		}
+
+		return v, nil
+	}
+}
@@ -0,0 +1,81 @@
+package chromem_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestNewEmbeddingFuncJinaCLIP(t *testing.T) {
+	apiKey := "secret"
+	image := []byte{0x89, 'P', 'N', 'G'}
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	// Mock server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check URL
+		if r.URL.Path != "/v1/embeddings" {
+			t.Fatal("expected URL", "/v1/embeddings", "got", r.URL.Path)
+		}
+		// Check headers
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			t.Fatal("expected Authorization header", "Bearer "+apiKey, "got", r.Header.Get("Authorization"))
+		}
+		// Check body
+		var body struct {
+			Model string              `json:"model"`
+			Input []map[string]string `json:"input"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if body.Model != string(chromem.EmbeddingModelJinaCLIP1) {
+			t.Fatal("expected model", chromem.EmbeddingModelJinaCLIP1, "got", body.Model)
+		}
+		if len(body.Input) != 1 {
+			t.Fatal("expected 1 input, got", len(body.Input))
+		}
+		if text, ok := body.Input[0]["text"]; ok && text != "hello world" {
+			t.Fatal("expected text", "hello world", "got", text)
+		} else if img, ok := body.Input[0]["image"]; ok && img != base64.StdEncoding.EncodeToString(image) {
+			t.Fatal("expected base64 image, got", img)
+		}
+
+		// Write response
+		resp := openAIResponse{
+			Data: []struct {
+				Embedding []float32 `json:"embedding"`
+			}{
+				{Embedding: []float32{-0.1, 0.1, 0.2}},
+			},
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	f := chromem.NewEmbeddingFuncJinaCLIP(apiKey, chromem.EmbeddingModelJinaCLIP1, chromem.WithBaseURL(ts.URL+"/v1"))
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+
+	fImage := chromem.NewImageEmbeddingFuncJinaCLIP(apiKey, chromem.EmbeddingModelJinaCLIP1, chromem.WithBaseURL(ts.URL+"/v1"))
+	res, err = fImage(context.Background(), image, "image/png")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"sync"
 )
@@ -23,15 +22,12 @@ type ollamaResponse struct {
 // See https://ollama.com/library/nomic-embed-text
 // baseURLOllama is the base URL of the Ollama API. If it's empty,
 // "http://localhost:11434/api" is used.
-func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
+func NewEmbeddingFuncOllama(model string, baseURLOllama string, opts ...EmbeddingFuncOption) EmbeddingFunc {
 	if baseURLOllama == "" {
 		baseURLOllama = defaultBaseURLOllama
 	}
 
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+	cfg := newEmbeddingFuncConfig(opts)
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
@@ -53,9 +49,10 @@ func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
 			return nil, fmt.Errorf("couldn't create request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
+		cfg.setHeaders(req)
 
 		// Send the request.
-		resp, err := client.Do(req)
+		resp, err := cfg.client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't send request: %w", err)
 		}
@@ -67,12 +64,13 @@ func NewEmbeddingFuncOllama(model string, baseURLOllama string) EmbeddingFunc {
 		}
 
 		// Read and decode the response body.
-		body, err := io.ReadAll(resp.Body)
+		body, err := readAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't read response body: %w", err)
 		}
+		defer putBuffer(body)
 		var embeddingResponse ollamaResponse
-		err = json.Unmarshal(body, &embeddingResponse)
+		err = json.Unmarshal(body.Bytes(), &embeddingResponse)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
 		}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"sync"
@@ -24,6 +23,8 @@ const (
 
 type openAIResponse struct {
 	Data []struct {
+		// Index of the text in the request's input
+		Index     int       `json:"index"`
 		Embedding []float32 `json:"embedding"`
 	} `json:"data"`
 }
@@ -32,17 +33,17 @@ type openAIResponse struct {
 // using OpenAI`s "text-embedding-3-small" model via their API.
 // The model supports a maximum text length of 8191 tokens.
 // The API key is read from the environment variable "OPENAI_API_KEY".
-func NewEmbeddingFuncDefault() EmbeddingFunc {
+func NewEmbeddingFuncDefault(opts ...EmbeddingFuncOption) EmbeddingFunc {
 	apiKey := os.Getenv("OPENAI_API_KEY")
-	return NewEmbeddingFuncOpenAI(apiKey, EmbeddingModelOpenAI3Small)
+	return NewEmbeddingFuncOpenAI(apiKey, EmbeddingModelOpenAI3Small, opts...)
 }
 
 // NewEmbeddingFuncOpenAI returns a function that creates embeddings for a text
 // using the OpenAI API.
-func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI) EmbeddingFunc {
+func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI, opts ...EmbeddingFuncOption) EmbeddingFunc {
 	// OpenAI embeddings are normalized
 	normalized := true
-	return NewEmbeddingFuncOpenAICompat(BaseURLOpenAI, apiKey, string(model), &normalized)
+	return NewEmbeddingFuncOpenAICompat(BaseURLOpenAI, apiKey, string(model), &normalized, opts...)
 }
 
 // NewEmbeddingFuncOpenAICompat returns a function that creates embeddings for a text
@@ -56,8 +57,21 @@ func NewEmbeddingFuncOpenAI(apiKey string, model EmbeddingModelOpenAI) Embedding
 // model are already normalized, as is the case for OpenAI's and Mistral's models.
 // The flag is optional. If it's nil, it will be autodetected on the first request
 // (which bears a small risk that the vector just happens to have a length of 1).
-func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool) EmbeddingFunc {
-	return newEmbeddingFuncOpenAICompat(baseURL, apiKey, model, normalized, nil, nil)
+func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	return newEmbeddingFuncOpenAICompat(baseURL, apiKey, model, normalized, nil, nil, opts...)
+}
+
+// NewEmbeddingFuncOpenAICompatBatch is like [NewEmbeddingFuncOpenAICompat], but
+// returns a function that creates the embeddings of multiple texts with a
+// single request. The number of texts per request is limited by the API.
+func NewEmbeddingFuncOpenAICompatBatch(baseURL, apiKey, model string, normalized *bool, opts ...EmbeddingFuncOption) EmbeddingFuncBatch {
+	embed := newEmbeddingsOpenAICompat(baseURL, apiKey, model, normalized, nil, nil, opts...)
+	return func(ctx context.Context, texts []string) ([][]float32, error) {
+		if len(texts) == 0 {
+			return nil, nil
+		}
+		return embed(ctx, texts, len(texts))
+	}
 }
 
 // newEmbeddingFuncOpenAICompat returns a function that creates embeddings for a text
@@ -69,21 +83,40 @@ func NewEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 // model are already normalized, as is the case for OpenAI's and Mistral's models.
 // The flag is optional. If it's nil, it will be autodetected on the first request
 // (which bears a small risk that the vector just happens to have a length of 1).
-func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, headers map[string]string, queryParams map[string]string) EmbeddingFunc {
-	// We don't set a default timeout here, although it's usually a good idea.
-	// In our case though, the library user can set the timeout on the context,
-	// and it might have to be a long timeout, depending on the text length.
-	client := &http.Client{}
+func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *bool, headers map[string]string, queryParams map[string]string, opts ...EmbeddingFuncOption) EmbeddingFunc {
+	embed := newEmbeddingsOpenAICompat(baseURL, apiKey, model, normalized, headers, queryParams, opts...)
+	return func(ctx context.Context, text string) ([]float32, error) {
+		vs, err := embed(ctx, text, 1)
+		if err != nil {
+			return nil, err
+		}
+		return vs[0], nil
+	}
+}
+
+// newEmbeddingsOpenAICompat returns a function that requests the embeddings of
+// the input from an OpenAI compatible API, see [newEmbeddingFuncOpenAICompat].
+// The input is either a single text or a slice of n texts, and the embeddings
+// are returned in its order.
+func newEmbeddingsOpenAICompat(baseURL, apiKey, model string, normalized *bool, headers map[string]string, queryParams map[string]string, opts ...EmbeddingFuncOption) func(ctx context.Context, input any, n int) ([][]float32, error) {
+	cfg := newEmbeddingFuncConfig(opts)
+	if cfg.baseURL != "" {
+		baseURL = cfg.baseURL
+	}
 
 	var checkedNormalized bool
 	checkNormalized := sync.Once{}
 
-	return func(ctx context.Context, text string) ([]float32, error) {
+	return func(ctx context.Context, input any, n int) ([][]float32, error) {
 		// Prepare the request body.
-		reqBody, err := json.Marshal(map[string]string{
-			"input": text,
+		params := map[string]any{
+			"input": input,
 			"model": model,
-		})
+		}
+		for k, v := range cfg.bodyParams {
+			params[k] = v
+		}
+		reqBody, err := json.Marshal(params)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't marshal request body: %w", err)
 		}
@@ -101,6 +134,7 @@ func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 		for k, v := range headers {
 			req.Header.Add(k, v)
 		}
+		cfg.setHeaders(req)
 
 		// Add query parameters
 		q := req.URL.Query()
@@ -110,24 +144,27 @@ func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 		req.URL.RawQuery = q.Encode()
 
 		// Send the request.
-		resp, err := client.Do(req)
+		resp, err := cfg.client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't send request: %w", err)
 		}
 		defer resp.Body.Close()
 
 		// Check the response status.
-		if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, newRateLimitError(resp)
+		} else if resp.StatusCode != http.StatusOK {
 			return nil, errors.New("error response from the embedding API: " + resp.Status)
 		}
 
 		// Read and decode the response body.
-		body, err := io.ReadAll(resp.Body)
+		body, err := readAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't read response body: %w", err)
 		}
+		defer putBuffer(body)
 		var embeddingResponse openAIResponse
-		err = json.Unmarshal(body, &embeddingResponse)
+		err = json.Unmarshal(body.Bytes(), &embeddingResponse)
 		if err != nil {
 			return nil, fmt.Errorf("couldn't unmarshal response body: %w", err)
 		}
@@ -136,25 +173,32 @@ func newEmbeddingFuncOpenAICompat(baseURL, apiKey, model string, normalized *boo
 		if len(embeddingResponse.Data) == 0 || len(embeddingResponse.Data[0].Embedding) == 0 {
 			return nil, errors.New("no embeddings found in the response")
 		}
+		if len(embeddingResponse.Data) != n {
+			return nil, fmt.Errorf("expected %d embeddings in the response, got %d", n, len(embeddingResponse.Data))
+		}
 
-		v := embeddingResponse.Data[0].Embedding
-		if normalized != nil {
-			if *normalized {
-				return v, nil
+		// The embeddings are in the order of their index in the input.
+		res := make([][]float32, n)
+		for _, data := range embeddingResponse.Data {
+			if data.Index < 0 || data.Index >= n || res[data.Index] != nil || len(data.Embedding) == 0 {
+				return nil, fmt.Errorf("invalid embedding with index %d in the response", data.Index)
 			}
-			return normalizeVector(v), nil
-		}
-		checkNormalized.Do(func() {
-			if isNormalized(v) {
-				checkedNormalized = true
+			v := data.Embedding
+			if normalized != nil {
+				if !*normalized {
+					v = normalizeVector(v)
+				}
 			} else {
-				checkedNormalized = false
+				checkNormalized.Do(func() {
+					checkedNormalized = isNormalized(v)
+				})
+				if !checkedNormalized {
+					v = normalizeVector(v)
+				}
 			}
-		})
-		if !checkedNormalized {
-			v = normalizeVector(v)
+			res[data.Index] = v
 		}
 
-		return v, nil
+		return res, nil
 	}
 }
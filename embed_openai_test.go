@@ -84,3 +84,117 @@ func TestNewEmbeddingFuncOpenAICompat(t *testing.T) {
 		t.Fatal("expected res", wantRes, "got", res)
 	}
 }
+
+func TestNewEmbeddingFuncOpenAICompat_Options(t *testing.T) {
+	wantRes := []float32{-0.40824828, 0.40824828, 0.81649655} // normalized version of `{-0.1, 0.1, 0.2}`
+
+	// Mock server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Check headers
+		if r.Header.Get("X-Gateway-Key") != "gateway-secret" {
+			t.Fatal("expected X-Gateway-Key header", "gateway-secret", "got", r.Header.Get("X-Gateway-Key"))
+		}
+		if r.Header.Get("Authorization") != "Bearer override" {
+			t.Fatal("expected Authorization header", "Bearer override", "got", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("X-Transport") != "custom" {
+			t.Fatal("expected request to go through custom transport")
+		}
+
+		// Write response
+		resp := openAIResponse{
+			Data: []struct {
+				Embedding []float32 `json:"embedding"`
+			}{
+				{Embedding: wantRes},
+			},
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r.Header.Set("X-Transport", "custom")
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+	f := chromem.NewEmbeddingFuncOpenAICompat(ts.URL, "secret", "model-small", nil,
+		chromem.WithHTTPClient(client),
+		chromem.WithHTTPHeaders(map[string]string{
+			"X-Gateway-Key": "gateway-secret",
+			"Authorization": "Bearer override",
+		}),
+	)
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
+
+func TestNewEmbeddingFuncOpenAI_Options(t *testing.T) {
+	wantRes := []float32{0.6, 0.8}
+
+	// Mock server, acting as gateway
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/v1/embeddings" {
+			t.Fatal("expected URL", "/openai/v1/embeddings", "got", r.URL.Path)
+		}
+		if r.Header.Get("OpenAI-Organization") != "org-123" {
+			t.Fatal("expected organization header", "org-123", "got", r.Header.Get("OpenAI-Organization"))
+		}
+		if r.Header.Get("OpenAI-Project") != "proj-456" {
+			t.Fatal("expected project header", "proj-456", "got", r.Header.Get("OpenAI-Project"))
+		}
+		var body struct {
+			Model      string `json:"model"`
+			Dimensions int    `json:"dimensions"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if body.Model != string(chromem.EmbeddingModelOpenAI3Large) {
+			t.Fatal("expected model", chromem.EmbeddingModelOpenAI3Large, "got", body.Model)
+		}
+		if body.Dimensions != 256 {
+			t.Fatal("expected dimensions", 256, "got", body.Dimensions)
+		}
+
+		// Write response
+		resp := openAIResponse{
+			Data: []struct {
+				Embedding []float32 `json:"embedding"`
+			}{
+				{Embedding: wantRes},
+			},
+		}
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	f := chromem.NewEmbeddingFuncOpenAI("secret", chromem.EmbeddingModelOpenAI3Large,
+		chromem.WithBaseURL(ts.URL+"/openai/v1"),
+		chromem.WithDimensions(256),
+		chromem.WithOpenAIOrganization("org-123"),
+		chromem.WithOpenAIProject("proj-456"),
+	)
+	res, err := f(context.Background(), "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if slices.Compare(wantRes, res) != 0 {
+		t.Fatal("expected res", wantRes, "got", res)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,130 @@
+package chromem
+
+import (
+	"net/http"
+)
+
+// EmbeddingFuncOption configures the built-in embedding functions that call an
+// HTTP API, like [NewEmbeddingFuncOpenAI] or [NewEmbeddingFuncOllama].
+type EmbeddingFuncOption func(*embeddingFuncConfig)
+
+type embeddingFuncConfig struct {
+	client  *http.Client
+	headers map[string]string
+	// Overrides the base URL of OpenAI compatible APIs.
+	baseURL string
+	// Extra request body parameters for OpenAI compatible APIs, like the task
+	// for Jina or the input type for Voyage AI. Not exposed as option because
+	// they're provider specific.
+	bodyParams map[string]any
+}
+
+// newEmbeddingFuncConfig applies the options to the default config.
+func newEmbeddingFuncConfig(opts []EmbeddingFuncOption) embeddingFuncConfig {
+	cfg := embeddingFuncConfig{
+		// We don't set a default timeout here, although it's usually a good idea.
+		// In our case though, the library user can set the timeout on the context,
+		// and it might have to be a long timeout, depending on the text length.
+		client: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// setHeaders sets the configured extra headers on the request. They're set
+// last, so they can override the default ones.
+func (cfg embeddingFuncConfig) setHeaders(req *http.Request) {
+	for k, v := range cfg.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// WithHTTPClient sets the HTTP client that's used for requests to the embedding
+// API. Use it to configure a proxy, custom TLS settings (like a corporate root CA
+// or client certificates), or other transport options. For example:
+//
+//	proxyURL, _ := url.Parse("http://proxy.example.com:8080")
+//	client := &http.Client{
+//		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+//	}
+//	f := chromem.NewEmbeddingFuncOpenAI(apiKey, chromem.EmbeddingModelOpenAI3Small, chromem.WithHTTPClient(client))
+//
+// Note that the default client already respects the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables.
+// If the client is nil, the option is ignored.
+func WithHTTPClient(client *http.Client) EmbeddingFuncOption {
+	return func(cfg *embeddingFuncConfig) {
+		if client != nil {
+			cfg.client = client
+		}
+	}
+}
+
+// WithHTTPHeaders sets extra headers that are sent with each request to the
+// embedding API, for example for authentication at an API gateway. They override
+// headers with the same name that chromem-go sets, like "Authorization".
+func WithHTTPHeaders(headers map[string]string) EmbeddingFuncOption {
+	return func(cfg *embeddingFuncConfig) {
+		if cfg.headers == nil {
+			cfg.headers = make(map[string]string, len(headers))
+		}
+		for k, v := range headers {
+			cfg.headers[k] = v
+		}
+	}
+}
+
+// withBodyParams sets extra request body parameters for OpenAI compatible APIs.
+func withBodyParams(params map[string]any) EmbeddingFuncOption {
+	return func(cfg *embeddingFuncConfig) {
+		if cfg.bodyParams == nil {
+			cfg.bodyParams = make(map[string]any, len(params))
+		}
+		for k, v := range params {
+			cfg.bodyParams[k] = v
+		}
+	}
+}
+
+// WithBaseURL overrides the base URL of the embedding API, for example to route
+// the requests through an API gateway or proxy like LiteLLM, or to use a regional
+// endpoint. It applies to the embedding functions that use an OpenAI compatible
+// API, like [NewEmbeddingFuncOpenAI], [NewEmbeddingFuncMistral] or
+// [NewEmbeddingFuncJina], as well as to [NewEmbeddingFuncJinaCLIP] and
+// [NewImageEmbeddingFuncJinaCLIP]. The URL must include the version path, e.g.
+// "https://gateway.example.com/openai/v1". If it's empty, the option is ignored.
+func WithBaseURL(baseURL string) EmbeddingFuncOption {
+	return func(cfg *embeddingFuncConfig) {
+		if baseURL != "" {
+			cfg.baseURL = baseURL
+		}
+	}
+}
+
+// WithDimensions sets the number of dimensions the embeddings should have, for
+// models that support shortening them, like OpenAI's "text-embedding-3-small"
+// and "text-embedding-3-large". Shorter embeddings need less memory and make
+// queries faster, at the cost of some accuracy.
+// It applies to the embedding functions that use an OpenAI compatible API.
+// If dimensions is 0 or less, the option is ignored and the model's default is
+// used.
+func WithDimensions(dimensions int) EmbeddingFuncOption {
+	if dimensions <= 0 {
+		return func(*embeddingFuncConfig) {}
+	}
+	return withBodyParams(map[string]any{"dimensions": dimensions})
+}
+
+// WithOpenAIOrganization sets the "OpenAI-Organization" header, for users who
+// belong to multiple organizations. Usage is then counted for that organization.
+func WithOpenAIOrganization(organization string) EmbeddingFuncOption {
+	return WithHTTPHeaders(map[string]string{"OpenAI-Organization": organization})
+}
+
+// WithOpenAIProject sets the "OpenAI-Project" header, for usage to be counted
+// for that project.
+func WithOpenAIProject(project string) EmbeddingFuncOption {
+	return WithHTTPHeaders(map[string]string{"OpenAI-Project": project})
+}
@@ -0,0 +1,66 @@
+package chromem
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitError is returned by the built-in embedding functions when the
+// embedding API responds with "429 Too Many Requests". RetryAfter is the time
+// the API asks to wait before the next request, as indicated by the response
+// headers. It's 0 if the API didn't indicate it.
+//
+// [WithEmbeddingRetry] waits at least RetryAfter before retrying.
+type RateLimitError struct {
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	msg := "error response from the embedding API: " + e.Status
+	if e.RetryAfter > 0 {
+		msg += " (retry after " + e.RetryAfter.String() + ")"
+	}
+	return msg
+}
+
+// newRateLimitError creates a [RateLimitError] from a 429 response.
+func newRateLimitError(resp *http.Response) *RateLimitError {
+	return &RateLimitError{
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+}
+
+// parseRetryAfter reads the wait time from the response headers. Providers
+// use different headers:
+//   - "Retry-After" (standard, e.g. Mistral and Together AI): Seconds or HTTP date
+//   - "X-RateLimit-Reset-Requests" and "X-RateLimit-Reset-Tokens" (OpenAI): Go-like
+//     duration, e.g. "1s" or "6m0s"
+//   - "X-RateLimit-Reset" (e.g. Together AI): Seconds
+//
+// If multiple are set, the longest wait time is used.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	var d time.Duration
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			d = max(d, time.Duration(secs*float64(time.Second)))
+		} else if t, err := http.ParseTime(v); err == nil {
+			d = max(d, t.Sub(now))
+		}
+	}
+	for _, k := range []string{"X-RateLimit-Reset-Requests", "X-RateLimit-Reset-Tokens"} {
+		if v := h.Get(k); v != "" {
+			if dur, err := time.ParseDuration(v); err == nil {
+				d = max(d, dur)
+			}
+		}
+	}
+	if v := h.Get("X-RateLimit-Reset"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			d = max(d, time.Duration(secs*float64(time.Second)))
+		}
+	}
+	return max(d, 0)
+}
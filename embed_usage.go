@@ -0,0 +1,153 @@
+package chromem
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// UsageKey identifies what the usage of an embedding provider is attributed to.
+type UsageKey struct {
+	// Provider is the name passed to [WithEmbeddingUsage], e.g. "openai".
+	Provider string
+	// Collection is the name of the collection that called the embedding
+	// function, see [EmbeddingCallInfo]. It's empty for direct calls.
+	Collection string
+	// Job is the name set via [ContextWithUsageJob], e.g. an ingestion job.
+	// It's empty for calls without job, like queries by default.
+	Job string
+}
+
+// EmbeddingUsage is the usage of an embedding provider, see [UsageTracker].
+type EmbeddingUsage struct {
+	// Requests is the number of calls to the embedding function, including
+	// failed ones.
+	Requests int64
+	// Errors is the number of failed calls.
+	Errors int64
+	// Tokens is the number of tokens of the texts of the successful calls, as
+	// counted by the tracker's [TokenCounter].
+	Tokens int64
+}
+
+// UsageEvent describes a single call to the embedding function, see
+// [NewUsageTracker].
+type UsageEvent struct {
+	UsageKey
+	// Tokens is the number of tokens of the text.
+	Tokens   int
+	Duration time.Duration
+	// Err is the error of the call, or nil.
+	Err error
+}
+
+// UsageTracker tracks the requests and tokens sent to embedding providers,
+// e.g. to attribute the spend to collections and ingestion jobs. Use
+// [WithEmbeddingUsage] to track the calls of an embedding function. It's safe
+// for concurrent use.
+type UsageTracker struct {
+	countTokens TokenCounter
+	onUsage     func(ctx context.Context, event UsageEvent)
+
+	lock  sync.Mutex
+	usage map[UsageKey]EmbeddingUsage
+}
+
+type usageJobContextKey struct{}
+
+// NewUsageTracker creates a tracker for embedding usage.
+//
+//   - countTokens: Counts the tokens of the texts with the tokenizer of the
+//     embedding provider. Optional, defaults to [CountTokensApprox].
+//   - onUsage: Called after each call to the embedding function, e.g. to export
+//     metrics. It's called concurrently when embedding concurrently. Optional.
+func NewUsageTracker(countTokens TokenCounter, onUsage func(ctx context.Context, event UsageEvent)) *UsageTracker {
+	if countTokens == nil {
+		countTokens = CountTokensApprox
+	}
+	return &UsageTracker{
+		countTokens: countTokens,
+		onUsage:     onUsage,
+		usage:       make(map[UsageKey]EmbeddingUsage),
+	}
+}
+
+// Usage returns a snapshot of the usage so far, per provider, collection and
+// job.
+func (t *UsageTracker) Usage() map[UsageKey]EmbeddingUsage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return maps.Clone(t.usage)
+}
+
+// ProviderUsage returns the usage of the provider so far, summed over all
+// collections and jobs.
+func (t *UsageTracker) ProviderUsage(provider string) EmbeddingUsage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var res EmbeddingUsage
+	for k, u := range t.usage {
+		if k.Provider == provider {
+			res.Requests += u.Requests
+			res.Errors += u.Errors
+			res.Tokens += u.Tokens
+		}
+	}
+	return res
+}
+
+// Reset clears the usage, e.g. at the start of a billing period.
+func (t *UsageTracker) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	clear(t.usage)
+}
+
+// record adds a call to the usage and calls the callback, if any.
+func (t *UsageTracker) record(ctx context.Context, event UsageEvent) {
+	t.lock.Lock()
+	u := t.usage[event.UsageKey]
+	u.Requests++
+	if event.Err != nil {
+		u.Errors++
+	} else {
+		u.Tokens += int64(event.Tokens)
+	}
+	t.usage[event.UsageKey] = u
+	t.lock.Unlock()
+
+	if t.onUsage != nil {
+		t.onUsage(ctx, event)
+	}
+}
+
+// ContextWithUsageJob returns a context that attributes the embedding usage
+// with it to the job, e.g. an ingestion job, see [UsageTracker].
+func ContextWithUsageJob(ctx context.Context, job string) context.Context {
+	return context.WithValue(ctx, usageJobContextKey{}, job)
+}
+
+// WithEmbeddingUsage returns a middleware that records each call to the
+// embedding function in the tracker, attributed to the provider, the calling
+// collection and the job of the context (see [ContextWithUsageJob]). Place it
+// after caches (see [WithEmbeddingCache]) in the chain, so that only the calls
+// that reach the provider are counted. With retries (see [WithEmbeddingRetry]) placed before
+// it, each try is counted as request.
+func WithEmbeddingUsage(tracker *UsageTracker, provider string) EmbeddingMiddleware {
+	return func(next EmbeddingFunc) EmbeddingFunc {
+		return func(ctx context.Context, text string) ([]float32, error) {
+			job, _ := ctx.Value(usageJobContextKey{}).(string)
+			info, _ := EmbeddingCallInfoFromContext(ctx)
+			start := time.Now()
+			v, err := next(ctx, text)
+			tracker.record(ctx, UsageEvent{
+				UsageKey: UsageKey{Provider: provider, Collection: info.Collection, Job: job},
+				Tokens:   tracker.countTokens(text),
+				Duration: time.Since(start),
+				Err:      err,
+			})
+			return v, err
+		}
+	}
+}
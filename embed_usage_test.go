@@ -0,0 +1,70 @@
+package chromem_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestWithEmbeddingUsage(t *testing.T) {
+	ctx := context.Background()
+	f := func(_ context.Context, text string) ([]float32, error) {
+		if text == "fail" {
+			return nil, errors.New("unavailable")
+		}
+		return []float32{1}, nil
+	}
+	var events []chromem.UsageEvent
+	countWords := func(text string) int { return len(strings.Fields(text)) }
+	tracker := chromem.NewUsageTracker(countWords, func(_ context.Context, event chromem.UsageEvent) {
+		events = append(events, event)
+	})
+	embed := chromem.ChainEmbeddingFunc(f,
+		chromem.WithEmbeddingCache(10),
+		chromem.WithEmbeddingUsage(tracker, "test"),
+	)
+
+	_, err := embed(ctx, "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	// Cache hits don't reach the provider.
+	_, err = embed(ctx, "hello world")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	jobCtx := chromem.ContextWithUsageJob(ctx, "import")
+	_, err = embed(jobCtx, "foo bar baz")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	_, err = embed(jobCtx, "fail")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	usage := tracker.Usage()
+	if len(usage) != 2 {
+		t.Fatal("expected usage of 2 keys, got", usage)
+	}
+	if u := usage[chromem.UsageKey{Provider: "test"}]; u != (chromem.EmbeddingUsage{Requests: 1, Tokens: 2}) {
+		t.Fatal("expected 1 request with 2 tokens, got", u)
+	}
+	if u := usage[chromem.UsageKey{Provider: "test", Job: "import"}]; u != (chromem.EmbeddingUsage{Requests: 2, Errors: 1, Tokens: 3}) {
+		t.Fatal("expected 2 requests with 1 error and 3 tokens, got", u)
+	}
+	if u := tracker.ProviderUsage("test"); u != (chromem.EmbeddingUsage{Requests: 3, Errors: 1, Tokens: 5}) {
+		t.Fatal("expected 3 requests with 1 error and 5 tokens, got", u)
+	}
+	if len(events) != 3 || events[2].Err == nil || events[2].Job != "import" {
+		t.Fatal("expected 3 events with the last one failed, got", events)
+	}
+
+	tracker.Reset()
+	if len(tracker.Usage()) != 0 {
+		t.Fatal("expected no usage, got", tracker.Usage())
+	}
+}
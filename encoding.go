@@ -0,0 +1,85 @@
+package chromem
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"io"
+)
+
+// Encoding serializes the files of a persistent DB, see [PersistentDBOptions].
+// The built-in ones are [EncodingGob] and [EncodingJSON]. MessagePack is
+// available via the separate module github.com/philippgille/chromem-go/msgpack,
+// so that this one stays free of third-party dependencies. Other formats can be
+// plugged in by implementing this interface, for example with a third-party
+// library.
+//
+// Documents, collection metadata and contents are encoded as Go structs and
+// strings, so the encoding must support those, including []float32, []byte and
+// maps with string keys and values.
+type Encoding interface {
+	// Extension returns the file name extension without the dot, e.g. "gob".
+	// It distinguishes the DB's files from other files in the directories.
+	Extension() string
+	// NewEncoder returns an encoder writing to w.
+	NewEncoder(w io.Writer) Encoder
+	// NewDecoder returns a decoder reading from r.
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder encodes values, like [gob.Encoder] and [json.Encoder].
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder decodes values, like [gob.Decoder] and [json.Decoder].
+type Decoder interface {
+	Decode(v any) error
+}
+
+var (
+	// EncodingGob encodes files as [gob]. It's the default, compact and fast,
+	// but Go specific.
+	EncodingGob Encoding = gobEncoding{}
+	// EncodingJSON encodes files as indented JSON, which is human readable and
+	// makes debugging easier, at the cost of larger files and slower
+	// persistence.
+	EncodingJSON Encoding = jsonEncoding{}
+)
+
+type gobEncoding struct{}
+
+func (gobEncoding) Extension() string { return "gob" }
+
+func (gobEncoding) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+
+func (gobEncoding) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type jsonEncoding struct{}
+
+func (jsonEncoding) Extension() string { return "json" }
+
+func (jsonEncoding) NewEncoder(w io.Writer) Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc
+}
+
+func (jsonEncoding) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+// orGob returns the encoding, or [EncodingGob] if it's nil.
+func orGob(encoding Encoding) Encoding {
+	if encoding == nil {
+		return EncodingGob
+	}
+	return encoding
+}
+
+// fileExt returns the extension of files with the encoding and compression,
+// including the dot.
+func fileExt(encoding Encoding, compress bool) string {
+	ext := "." + orGob(encoding).Extension()
+	if compress {
+		ext += ".gz"
+	}
+	return ext
+}
@@ -0,0 +1,100 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNewPersistentDBWithOptions_Encoding(t *testing.T) {
+	ctx := context.Background()
+	for _, compress := range []bool{false, true} {
+		t.Run("compress="+strconv.FormatBool(compress), func(t *testing.T) {
+			tempDir, err := os.MkdirTemp(os.TempDir(), "")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			options := PersistentDBOptions{Compress: compress, Encoding: EncodingJSON}
+			db, err := NewPersistentDBWithOptions(tempDir, options)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			c, err := db.CreateCollection("test", map[string]string{"foo": "bar"}, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.SetContentEncryption("0123456789abcdef0123456789abcdef", false)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			doc := Document{
+				ID:              "1",
+				Metadata:        map[string]string{"a": "b"},
+				Embedding:       []float32{1, 0, 0},
+				Content:         "hello world",
+				SparseEmbedding: SparseVector{Indices: []uint32{1, 5}, Values: []float32{0.5, 0.25}},
+				Data:            []byte{0, 1, 2},
+			}
+			err = c.AddDocument(ctx, doc)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+
+			// The files have the encoding's extension
+			ext := ".json"
+			if compress {
+				ext += ".gz"
+			}
+			docPath := c.getDocPath("1")
+			if !strings.HasSuffix(docPath, ext) {
+				t.Fatal("expected extension", ext, "got", filepath.Base(docPath))
+			}
+			if !compress {
+				b, err := os.ReadFile(docPath)
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				if !json.Valid(b) {
+					t.Fatal("expected valid JSON, got", string(b))
+				}
+			}
+
+			// Loading with the same options restores everything
+			db2, err := NewPersistentDBWithOptions(tempDir, options)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			c2 := db2.GetCollection("test", nil)
+			if c2 == nil || c2.Metadata()["foo"] != "bar" {
+				t.Fatal("expected collection with metadata, got", c2)
+			}
+			err = c2.SetContentEncryption("0123456789abcdef0123456789abcdef", false)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			res, err := c2.GetByID(ctx, "1")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if res.Content != doc.Content || res.Metadata["a"] != "b" || len(res.Data) != 3 ||
+				len(res.SparseEmbedding.Indices) != 2 || res.Embedding[0] != 1 {
+				t.Fatal("expected the added document, got", res)
+			}
+
+			// Other encodings ignore the files
+			db3, err := NewPersistentDB(tempDir, compress)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(db3.ListCollections()) != 0 {
+				t.Fatal("expected no collections, got", len(db3.ListCollections()))
+			}
+		})
+	}
+}
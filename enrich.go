@@ -0,0 +1,364 @@
+package chromem
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTitleLength is the maximum number of characters of a title that
+// [TitleEnricher] extracts.
+const maxTitleLength = 100
+
+// Prompts for [LLMEnricher]. The document's content is appended to them.
+const (
+	LLMPromptSummary = "Summarize the following text in one or two sentences. Reply with the summary only."
+	LLMPromptTitle   = "Write a short title for the following text. Reply with the title only, without quotes."
+)
+
+// Kinds of personally identifiable information that [PIIEnricher] detects.
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+	PIIIBAN       = "iban"
+)
+
+// MetadataEnricher returns metadata to add to a document, e.g. extracted from
+// its content, so that it can be used in filters and displayed with the
+// results. See [MetadataStage].
+type MetadataEnricher func(ctx context.Context, doc Document) (map[string]string, error)
+
+// MetadataStage returns a [Pipeline] stage that adds the metadata of the
+// enrichers to each document. The enrichers are called in order, and each one
+// sees the metadata of the previous ones. Keys that the document already has
+// are kept, so that values which were set by the loader aren't overwritten.
+//
+//   - name: The name of the stage, see [PipelineStage.Name].
+//   - concurrency: The number of documents that are enriched concurrently,
+//     e.g. > 1 for enrichers that call an LLM. Optional, defaults to 1.
+//   - enrichers: The enrichers, e.g. [TitleEnricher], [KeywordsEnricher],
+//     [PIIEnricher] or [LLMEnricher].
+func MetadataStage(name string, concurrency int, enrichers ...MetadataEnricher) PipelineStage {
+	return EnrichStage(name, func(ctx context.Context, doc *Document) error {
+		for _, enrich := range enrichers {
+			metadata, err := enrich(ctx, *doc)
+			if err != nil {
+				return err
+			}
+			if len(metadata) == 0 {
+				continue
+			}
+			// Copy the metadata, as the loader's map must not be modified.
+			merged := make(map[string]string, len(doc.Metadata)+len(metadata))
+			for k, v := range metadata {
+				merged[k] = v
+			}
+			for k, v := range doc.Metadata {
+				merged[k] = v
+			}
+			doc.Metadata = merged
+		}
+		return nil
+	}, concurrency)
+}
+
+// TitleEnricher returns an enricher that uses the first non-empty line of a
+// document's content as its title, without Markdown heading markers and
+// shortened to 100 characters at a word boundary.
+func TitleEnricher(key string) MetadataEnricher {
+	return func(_ context.Context, doc Document) (map[string]string, error) {
+		for _, line := range strings.Split(doc.Content, "\n") {
+			line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+			if line == "" {
+				continue
+			}
+			if utf8.RuneCountInString(line) > maxTitleLength {
+				line = string([]rune(line)[:maxTitleLength])
+				if i := strings.LastIndexFunc(line, unicode.IsSpace); i > 0 {
+					line = line[:i]
+				}
+				line += "…"
+			}
+			return map[string]string{key: line}, nil
+		}
+		return nil, nil
+	}
+}
+
+// KeywordsEnricher returns an enricher that adds the n most frequent terms of a
+// document's content as JSON array, e.g. `["vector","database"]`, so that
+// documents can be filtered by keyword with [Filter.PathEq] and a path like
+// "keywords[]".
+//
+//   - key: The metadata key.
+//   - n: The maximum number of keywords. Must be > 0.
+//   - analyzer: Splits the content into terms. Optional, defaults to lowercase
+//     terms with at least 3 characters, without the [Stopwords] of the
+//     content's language (see [DetectLanguage]) and numbers.
+func KeywordsEnricher(key string, n int, analyzer Analyzer) (MetadataEnricher, error) {
+	if n <= 0 {
+		return nil, errors.New("n must be > 0")
+	}
+
+	return func(_ context.Context, doc Document) (map[string]string, error) {
+		if doc.Content == "" {
+			return nil, nil
+		}
+		analyze := analyzer
+		if analyze == nil {
+			analyze = NewAnalyzer(AnalyzerOptions{
+				Lowercase: true,
+				Stopwords: Stopwords(DetectLanguage(doc.Content)),
+				MinLength: 3,
+			})
+		}
+
+		type termCount struct {
+			term  string
+			count int
+		}
+		var counts []termCount
+		indexes := map[string]int{}
+		for _, term := range analyze(doc.Content) {
+			if analyzer == nil && strings.IndexFunc(term, unicode.IsLetter) == -1 {
+				continue
+			}
+			i, ok := indexes[term]
+			if !ok {
+				i = len(counts)
+				indexes[term] = i
+				counts = append(counts, termCount{term: term})
+			}
+			counts[i].count++
+		}
+		// Stable, so that terms with the same count are in order of appearance.
+		slices.SortStableFunc(counts, func(a, b termCount) int {
+			return cmp.Compare(b.count, a.count)
+		})
+
+		keywords := make([]string, 0, min(n, len(counts)))
+		for _, tc := range counts[:min(n, len(counts))] {
+			keywords = append(keywords, tc.term)
+		}
+		b, err := json.Marshal(keywords)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal keywords: %w", err)
+		}
+		return map[string]string{key: string(b)}, nil
+	}, nil
+}
+
+var (
+	piiEmailRegex      = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	piiPhoneRegex      = regexp.MustCompile(`\+?\(?\d[\d ()/.-]{6,}\d`)
+	piiCreditCardRegex = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	piiIBANRegex       = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+)
+
+// PIIEnricher returns an enricher that flags documents whose content contains
+// personally identifiable information. It adds the detected kinds as JSON
+// array, e.g. `["email","phone"]`, or `[]` if there are none. Documents with
+// PII can be found with [Filter.PathExists] and a path like "pii[]", or with a
+// certain kind with [Filter.PathEq], e.g. PathEq("pii[]", chromem.PIIEmail).
+//
+// The detection is based on patterns of email addresses, phone numbers,
+// credit card numbers and IBANs, with checksum validation of the latter two.
+// It's meant for flagging documents for review or excluding them from queries,
+// not as a guarantee that the other documents don't contain PII.
+func PIIEnricher(key string) MetadataEnricher {
+	return func(_ context.Context, doc Document) (map[string]string, error) {
+		kinds := DetectPII(doc.Content)
+		b, err := json.Marshal(kinds)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal PII kinds: %w", err)
+		}
+		return map[string]string{key: string(b)}, nil
+	}
+}
+
+// DetectPII returns the kinds of personally identifiable information that the
+// text contains, like [PIIEmail], see [PIIEnricher]. It never returns nil.
+func DetectPII(text string) []string {
+	kinds := []string{}
+	if piiEmailRegex.MatchString(text) {
+		kinds = append(kinds, PIIEmail)
+	}
+
+	// Numbers that are credit card numbers aren't phone numbers as well.
+	creditCard := false
+	for _, m := range piiCreditCardRegex.FindAllString(text, -1) {
+		if luhnValid(digitsOf(m)) {
+			creditCard = true
+			text = strings.ReplaceAll(text, m, "")
+		}
+	}
+	for _, m := range piiPhoneRegex.FindAllString(text, -1) {
+		if digits := len(digitsOf(m)); digits >= 9 && digits <= 15 {
+			kinds = append(kinds, PIIPhone)
+			break
+		}
+	}
+	if creditCard {
+		kinds = append(kinds, PIICreditCard)
+	}
+
+	for _, m := range piiIBANRegex.FindAllString(text, -1) {
+		if ibanValid(strings.ReplaceAll(m, " ", "")) {
+			kinds = append(kinds, PIIIBAN)
+			break
+		}
+	}
+	return kinds
+}
+
+// digitsOf returns the digits of the string.
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// luhnValid returns whether the digits have a valid Luhn checksum, like
+// credit card numbers.
+func luhnValid(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ibanValid returns whether the IBAN without spaces has a valid checksum.
+func ibanValid(iban string) bool {
+	// Move the country code and checksum to the end and replace the letters by
+	// numbers, A = 10 etc.
+	var sb strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprint(&sb, int(r-'A')+10)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// CompletionFunc returns the completion of a prompt by an LLM, e.g. for
+// [LLMEnricher]. See [NewCompletionFuncOpenAICompat].
+type CompletionFunc func(ctx context.Context, prompt string) (string, error)
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// NewCompletionFuncOpenAICompat returns a function that completes prompts with
+// the chat completions endpoint of an OpenAI compatible API, like the ones of
+// OpenAI ([BaseURLOpenAI]), Mistral, Ollama or LiteLLM. The prompt is sent as
+// user message. [WithHTTPClient], [WithHTTPHeaders] and [WithBaseURL] apply as
+// for the embedding functions.
+func NewCompletionFuncOpenAICompat(baseURL, apiKey, model string, opts ...EmbeddingFuncOption) CompletionFunc {
+	cfg := newEmbeddingFuncConfig(opts)
+	if cfg.baseURL != "" {
+		baseURL = cfg.baseURL
+	}
+
+	return func(ctx context.Context, prompt string) (string, error) {
+		reqBody, err := json.Marshal(map[string]any{
+			"model": model,
+			"messages": []map[string]string{
+				{"role": "user", "content": prompt},
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("couldn't marshal request body: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return "", fmt.Errorf("couldn't create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		cfg.setHeaders(req)
+
+		resp, err := cfg.client.Do(req)
+		if err != nil {
+			return "", fmt.Errorf("couldn't send request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return "", newRateLimitError(resp)
+		} else if resp.StatusCode != http.StatusOK {
+			return "", errors.New("error response from the completion API: " + resp.Status)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", fmt.Errorf("couldn't read response body: %w", err)
+		}
+		var chatResponse openAIChatResponse
+		err = json.Unmarshal(body, &chatResponse)
+		if err != nil {
+			return "", fmt.Errorf("couldn't unmarshal response body: %w", err)
+		}
+		if len(chatResponse.Choices) == 0 {
+			return "", errors.New("no choices found in the response")
+		}
+		return chatResponse.Choices[0].Message.Content, nil
+	}
+}
+
+// LLMEnricher returns an enricher that adds the LLM's completion of the prompt
+// followed by the document's content, e.g. with [LLMPromptSummary] or
+// [LLMPromptTitle]. Leading and trailing whitespace of the completion is
+// removed. Documents without content aren't sent to the LLM.
+//
+// Each document requires a request to the LLM, so use a concurrency > 1 for the
+// [MetadataStage], and keep in mind that the content might have to be shortened
+// for the LLM's context, e.g. with a [SplitStage] before the enrichment.
+func LLMEnricher(complete CompletionFunc, key, prompt string) MetadataEnricher {
+	return func(ctx context.Context, doc Document) (map[string]string, error) {
+		if doc.Content == "" {
+			return nil, nil
+		}
+		completion, err := complete(ctx, prompt+"\n\n"+doc.Content)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't complete prompt for key '%s': %w", key, err)
+		}
+		return map[string]string{key: strings.TrimSpace(completion)}, nil
+	}
+}
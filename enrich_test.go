@@ -0,0 +1,130 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMetadataStage(t *testing.T) {
+	ctx := context.Background()
+
+	keywords, err := KeywordsEnricher("keywords", 2, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = KeywordsEnricher("keywords", 0, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	stage := MetadataStage("enrich", 1,
+		TitleEnricher("title"),
+		keywords,
+		PIIEnricher("pii"),
+		// Sees the metadata of the previous enrichers
+		func(_ context.Context, doc Document) (map[string]string, error) {
+			return map[string]string{"heading": "Title: " + doc.Metadata["title"]}, nil
+		},
+	)
+
+	metadata := map[string]string{"title": "Given"}
+	docs, err := stage.Process(ctx, Document{
+		ID:       "1",
+		Metadata: metadata,
+		Content:  "# Vector databases\n\nA vector database stores vectors. Databases like this one are embedded. Mail jane@example.com for the 2024 vector report.",
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc := docs[0]
+	if doc.Metadata["title"] != "Given" || doc.Metadata["heading"] != "Title: Given" {
+		t.Fatal("expected the existing title to be kept, got", doc.Metadata)
+	}
+	if len(metadata) != 1 {
+		t.Fatal("expected the loader's metadata to be unchanged, got", metadata)
+	}
+	if doc.Metadata["keywords"] != `["vector","databases"]` {
+		t.Fatal("expected vector and databases as keywords, got", doc.Metadata["keywords"])
+	}
+	if doc.Metadata["pii"] != `["email"]` {
+		t.Fatal("expected email as PII, got", doc.Metadata["pii"])
+	}
+	if !Where().PathEq("keywords[]", "vector").matches(&doc) || !Where().PathExists("pii[]").matches(&doc) {
+		t.Fatal("expected the enriched metadata to be filterable, got", doc.Metadata)
+	}
+
+	res, err := TitleEnricher("title")(ctx, Document{Content: "\n  ## Heading  \nText"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res["title"] != "Heading" {
+		t.Fatal("expected Heading, got", res["title"])
+	}
+}
+
+func TestDetectPII(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected []string
+	}{
+		{"Nothing to see here, order 12345.", []string{}},
+		{"Call +49 (30) 1234567 or write to a@b.io", []string{PIIEmail, PIIPhone}},
+		{"Card: 4111 1111 1111 1111", []string{PIICreditCard}},
+		{"Not a card: 4111 1111 1111 1112", []string{}},
+		{"IBAN DE89 3704 0044 0532 0130 00", []string{PIIIBAN}},
+		{"Invalid IBAN DE88 3704 0044 0532 0130 00", []string{}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			kinds := DetectPII(tc.text)
+			if !slices.Equal(kinds, tc.expected) {
+				t.Fatal("expected", tc.expected, "got", kinds)
+			}
+		})
+	}
+}
+
+func TestLLMEnricher(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Fatal("expected /chat/completions, got", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Fatal("expected bearer token, got", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if req.Model != "model" || !strings.HasPrefix(req.Messages[0].Content, LLMPromptSummary+"\n\n") {
+			t.Fatal("expected the model and prompt, got", req)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":" A summary. \n"}}]}`))
+	}))
+	defer ts.Close()
+
+	enrich := LLMEnricher(NewCompletionFuncOpenAICompat(ts.URL, "key", "model"), "summary", LLMPromptSummary)
+	res, err := enrich(context.Background(), Document{Content: "Some long text"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res["summary"] != "A summary." {
+		t.Fatal("expected the trimmed summary, got", res["summary"])
+	}
+
+	// No request without content
+	res, err = enrich(context.Background(), Document{})
+	if err != nil || res != nil {
+		t.Fatal("expected no metadata, got", res, err)
+	}
+}
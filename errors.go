@@ -0,0 +1,23 @@
+package chromem
+
+import "errors"
+
+// The following errors are wrapped by the errors of the corresponding failures,
+// so that callers can tell them apart with [errors.Is]. The other errors of the
+// package are defined next to the feature they belong to, like [ErrClosed] or
+// [ErrVersionConflict].
+var (
+	// ErrDocumentExists is returned when adding a document with an ID that
+	// already exists to a collection in strict mode, see
+	// [Collection.SetStrictMode].
+	ErrDocumentExists = errors.New("document already exists")
+	// ErrNotFound is returned when a document or collection doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrDimensionMismatch is returned when embeddings that are compared or
+	// stored together have different dimensions, e.g. because they were created
+	// by different embedding models.
+	ErrDimensionMismatch = errors.New("dimension mismatch")
+	// ErrUnsupportedOperator is returned for filters with an unsupported
+	// operator.
+	ErrUnsupportedOperator = errors.New("unsupported operator")
+)
@@ -0,0 +1,106 @@
+package chromem
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// IngestionEstimateOptions configures [EstimateIngestion].
+type IngestionEstimateOptions struct {
+	// Splitter splits the documents' contents into chunks, which are embedded
+	// separately. Optional. If nil, each document is embedded as a whole.
+	Splitter TextSplitter
+	// CountTokens counts the tokens of the chunks with the tokenizer of the
+	// embedding provider. Optional, defaults to [CountTokensApprox].
+	CountTokens TokenCounter
+	// PricePerMillionTokens is the price of the embedding API per million
+	// tokens, e.g. 0.02 (USD) for OpenAI's "text-embedding-3-small". Optional.
+	PricePerMillionTokens float64
+	// TokensPerMinute is the provider's rate limit of tokens per minute.
+	// Optional, 0 means unlimited.
+	TokensPerMinute int
+	// RequestsPerMinute is the provider's rate limit of requests per minute,
+	// with one request per chunk. Optional, 0 means unlimited.
+	RequestsPerMinute int
+	// RequestLatency is the average duration of a request. Optional.
+	RequestLatency time.Duration
+	// Concurrency is the number of concurrent requests, like the concurrency
+	// of [Collection.AddDocuments]. Optional, defaults to 1.
+	Concurrency int
+}
+
+// IngestionEstimate is the result of [EstimateIngestion].
+type IngestionEstimate struct {
+	// Documents is the number of documents that need to be embedded, i.e.
+	// documents without embedding.
+	Documents int
+	// Chunks is the number of chunks, i.e. the number of embedding requests.
+	Chunks int
+	// Tokens is the total number of tokens of the chunks.
+	Tokens int
+	// MaxChunkTokens is the number of tokens of the largest chunk, which must
+	// fit into the embedding model's context.
+	MaxChunkTokens int
+	// Cost is the estimated price of the embedding API, in the currency of
+	// PricePerMillionTokens.
+	Cost float64
+	// Duration is the estimated duration of the embedding, limited by the rate
+	// limits and the request latency with the given concurrency. It's 0 if
+	// neither rate limits nor the latency are set.
+	Duration time.Duration
+}
+
+// EstimateIngestion is a dry run of adding the documents to a collection. It
+// chunks the documents, counts their tokens and estimates the cost and duration
+// of embedding them, without making any API calls. This helps to budget large
+// imports.
+//
+// Documents that already have an embedding are skipped, as they don't need to
+// be embedded.
+func EstimateIngestion(documents []Document, options IngestionEstimateOptions) (IngestionEstimate, error) {
+	if options.PricePerMillionTokens < 0 || options.TokensPerMinute < 0 || options.RequestsPerMinute < 0 || options.RequestLatency < 0 {
+		return IngestionEstimate{}, errors.New("price, rate limits and latency must be >= 0")
+	}
+	if options.Concurrency < 0 {
+		return IngestionEstimate{}, errors.New("concurrency must be >= 0")
+	}
+	countTokens := options.CountTokens
+	if countTokens == nil {
+		countTokens = CountTokensApprox
+	}
+	concurrency := max(1, options.Concurrency)
+
+	var res IngestionEstimate
+	for _, doc := range documents {
+		if len(doc.Embedding) != 0 || doc.Content == "" {
+			continue
+		}
+		res.Documents++
+		chunks := []string{doc.Content}
+		if options.Splitter != nil {
+			chunks = options.Splitter(doc.Content)
+		}
+		for _, chunk := range chunks {
+			tokens := countTokens(chunk)
+			res.Chunks++
+			res.Tokens += tokens
+			res.MaxChunkTokens = max(res.MaxChunkTokens, tokens)
+		}
+	}
+
+	res.Cost = float64(res.Tokens) * options.PricePerMillionTokens / 1_000_000
+
+	// The duration is limited by the slowest of the rate limits and the latency.
+	var minutes float64
+	if options.TokensPerMinute > 0 {
+		minutes = float64(res.Tokens) / float64(options.TokensPerMinute)
+	}
+	if options.RequestsPerMinute > 0 {
+		minutes = max(minutes, float64(res.Chunks)/float64(options.RequestsPerMinute))
+	}
+	latency := options.RequestLatency * time.Duration(math.Ceil(float64(res.Chunks)/float64(concurrency)))
+	res.Duration = max(time.Duration(minutes*float64(time.Minute)), latency)
+
+	return res, nil
+}
@@ -0,0 +1,65 @@
+package chromem
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateIngestion(t *testing.T) {
+	countWords := func(text string) int {
+		return len(strings.Fields(text))
+	}
+	split, err := NewTextSplitter(4, 0, countWords)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Content: "one two three four five six"},
+		{ID: "2", Content: "one two"},
+		// Already embedded
+		{ID: "3", Content: "one two three", Embedding: []float32{1, 0}},
+	}
+
+	res, err := EstimateIngestion(docs, IngestionEstimateOptions{
+		Splitter:              split,
+		CountTokens:           countWords,
+		PricePerMillionTokens: 100_000,
+		RequestsPerMinute:     60,
+		RequestLatency:        2 * time.Second,
+		Concurrency:           2,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	want := IngestionEstimate{
+		Documents:      2,
+		Chunks:         3,
+		Tokens:         8,
+		MaxChunkTokens: 4,
+		Cost:           0.8,
+		// 2 rounds of concurrent requests take longer than 3 requests with the
+		// rate limit.
+		Duration: 4 * time.Second,
+	}
+	if res != want {
+		t.Fatal("expected", want, "got", res)
+	}
+
+	// The rate limit of tokens is the bottleneck
+	res, err = EstimateIngestion(docs, IngestionEstimateOptions{
+		CountTokens:     countWords,
+		TokensPerMinute: 4,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res.Chunks != 2 || res.Duration != 2*time.Minute {
+		t.Fatal("expected 2 chunks in 2 minutes, got", res)
+	}
+
+	_, err = EstimateIngestion(docs, IngestionEstimateOptions{TokensPerMinute: -1})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
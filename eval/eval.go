@@ -0,0 +1,273 @@
+// Package eval provides a harness to evaluate the retrieval quality of
+// chromem-go collections, so you can validate the accuracy tradeoffs of
+// settings like approximate nearest neighbor indexes against exact search.
+//
+// Given queries with the IDs of their relevant documents, [Evaluate] computes
+// recall@k, the mean reciprocal rank (MRR) and the normalized discounted
+// cumulative gain (nDCG@k). [Compare] does the same for multiple settings of a
+// collection, e.g.:
+//
+//	results, err := eval.Compare(ctx, c, queries, 10, []eval.Setting{
+//		eval.Exact(),
+//		eval.IVF(0, 1),
+//		eval.IVF(0, 4),
+//		eval.Matryoshka(256, 4),
+//	})
+//
+// If you don't have labeled queries, [GroundTruth] uses the results of an exact
+// search as relevant documents, so you can measure how many of the exact nearest
+// neighbors an approximate search finds.
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/philippgille/chromem-go"
+)
+
+// Query is a query with the IDs of its relevant documents.
+type Query struct {
+	// Text is the query text. It's embedded with the collection's embedding
+	// function, unless Embedding is set.
+	Text string
+	// Embedding is the query embedding. Optional.
+	Embedding []float32
+	// Relevant are the IDs of the documents that are relevant for the query.
+	// Must not be empty.
+	Relevant []string
+}
+
+// Metrics are retrieval quality metrics, averaged over the queries.
+type Metrics struct {
+	// K is the number of results per query.
+	K int
+	// Queries is the number of evaluated queries.
+	Queries int
+	// Recall is recall@k, the fraction of the relevant documents that are in
+	// the results.
+	Recall float64
+	// MRR is the mean reciprocal rank of the first relevant document in the
+	// results, or 0 if there's none.
+	MRR float64
+	// NDCG is nDCG@k with binary relevance, which also takes the ranks of the
+	// relevant documents into account.
+	NDCG float64
+	// MeanLatency is the average duration of a query.
+	MeanLatency time.Duration
+}
+
+// SearchFunc returns the IDs of the k most similar documents for the query,
+// sorted by similarity (descending).
+type SearchFunc func(ctx context.Context, query Query, k int) ([]string, error)
+
+// CollectionSearch returns a [SearchFunc] that queries the collection with its
+// current settings.
+func CollectionSearch(c *chromem.Collection) SearchFunc {
+	return func(ctx context.Context, query Query, k int) ([]string, error) {
+		var res []chromem.Result
+		var err error
+		if len(query.Embedding) != 0 {
+			res, err = c.QueryEmbedding(ctx, query.Embedding, k, nil, nil)
+		} else {
+			res, err = c.Query(ctx, query.Text, k, nil, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(res))
+		for i, r := range res {
+			ids[i] = r.ID
+		}
+		return ids, nil
+	}
+}
+
+// Evaluate runs the queries with the search function and computes the metrics.
+//
+//   - search: The search to evaluate, e.g. [CollectionSearch].
+//   - queries: The queries with their relevant documents. Must not be empty.
+//   - k: The number of results per query. Must be > 0.
+func Evaluate(ctx context.Context, search SearchFunc, queries []Query, k int) (Metrics, error) {
+	if len(queries) == 0 {
+		return Metrics{}, errors.New("queries are empty")
+	}
+	if k <= 0 {
+		return Metrics{}, errors.New("k must be > 0")
+	}
+
+	m := Metrics{K: k, Queries: len(queries)}
+	var latency time.Duration
+	for i, query := range queries {
+		if len(query.Relevant) == 0 {
+			return Metrics{}, fmt.Errorf("query %d has no relevant documents", i)
+		}
+		start := time.Now()
+		ids, err := search(ctx, query, k)
+		if err != nil {
+			return Metrics{}, fmt.Errorf("couldn't run query %d: %w", i, err)
+		}
+		latency += time.Since(start)
+
+		recall, rr, ndcg := score(ids, query.Relevant, k)
+		m.Recall += recall
+		m.MRR += rr
+		m.NDCG += ndcg
+	}
+	n := float64(len(queries))
+	m.Recall /= n
+	m.MRR /= n
+	m.NDCG /= n
+	m.MeanLatency = latency / time.Duration(len(queries))
+
+	return m, nil
+}
+
+// score returns the recall@k, reciprocal rank and nDCG@k of the results.
+func score(ids, relevant []string, k int) (recall, rr, ndcg float64) {
+	isRelevant := make(map[string]bool, len(relevant))
+	for _, id := range relevant {
+		isRelevant[id] = true
+	}
+
+	found := 0
+	var dcg float64
+	for i, id := range ids[:min(k, len(ids))] {
+		if !isRelevant[id] {
+			continue
+		}
+		// Count each relevant document only once.
+		isRelevant[id] = false
+		found++
+		if rr == 0 {
+			rr = 1 / float64(i+1)
+		}
+		dcg += 1 / math.Log2(float64(i+2))
+	}
+	var idcg float64
+	for i := 0; i < min(k, len(relevant)); i++ {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	return float64(found) / float64(len(relevant)), rr, dcg / idcg
+}
+
+// Setting is a named configuration of a collection for [Compare].
+type Setting struct {
+	// Name describes the setting, e.g. "IVF with 4 probes".
+	Name string
+	// Apply configures the collection. As the settings are applied one after
+	// the other to the same collection, it must also undo the configuration of
+	// other settings, e.g. drop an index that another setting built.
+	Apply func(ctx context.Context, c *chromem.Collection) error
+}
+
+// Result are the metrics of a setting.
+type Result struct {
+	Setting string
+	Metrics Metrics
+}
+
+// Exact returns a setting for the exact (exhaustive) search, without IVF index
+// and Matryoshka search.
+func Exact() Setting {
+	return Setting{
+		Name: "exact",
+		Apply: func(_ context.Context, c *chromem.Collection) error {
+			c.DropIVFIndex()
+			return c.SetMatryoshkaSearch(0, 0)
+		},
+	}
+}
+
+// IVF returns a setting with an IVF index, see [chromem.Collection.BuildIVFIndex].
+// The index is built once per setting.
+func IVF(numLists, numProbes int) Setting {
+	lists := "√n"
+	if numLists > 0 {
+		lists = strconv.Itoa(numLists)
+	}
+	return Setting{
+		Name: fmt.Sprintf("IVF (%s lists, %d probes)", lists, numProbes),
+		Apply: func(ctx context.Context, c *chromem.Collection) error {
+			err := c.SetMatryoshkaSearch(0, 0)
+			if err != nil {
+				return err
+			}
+			return c.BuildIVFIndex(ctx, numLists, numProbes)
+		},
+	}
+}
+
+// Matryoshka returns a setting with the two-stage Matryoshka search, see
+// [chromem.Collection.SetMatryoshkaSearch].
+func Matryoshka(dimensions, oversampling int) Setting {
+	name := fmt.Sprintf("Matryoshka (%d dimensions)", dimensions)
+	if oversampling > 0 {
+		name = fmt.Sprintf("Matryoshka (%d dimensions, %dx oversampling)", dimensions, oversampling)
+	}
+	return Setting{
+		Name: name,
+		Apply: func(_ context.Context, c *chromem.Collection) error {
+			c.DropIVFIndex()
+			return c.SetMatryoshkaSearch(dimensions, oversampling)
+		},
+	}
+}
+
+// Compare applies the settings one after the other to the collection and
+// evaluates the queries with each of them. The collection keeps the last
+// setting.
+func Compare(ctx context.Context, c *chromem.Collection, queries []Query, k int, settings []Setting) ([]Result, error) {
+	results := make([]Result, 0, len(settings))
+	for _, s := range settings {
+		if s.Apply != nil {
+			err := s.Apply(ctx, c)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't apply setting '%s': %w", s.Name, err)
+			}
+		}
+		m, err := Evaluate(ctx, CollectionSearch(c), queries, k)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't evaluate setting '%s': %w", s.Name, err)
+		}
+		results = append(results, Result{Setting: s.Name, Metrics: m})
+	}
+	return results, nil
+}
+
+// GroundTruth returns the queries with the IDs of their k nearest neighbors as
+// relevant documents, determined with an exact search. The collection keeps the
+// exact search setting (see [Exact]). The returned queries have embeddings, so
+// the query texts are only embedded once.
+//
+//   - embeddingFunc: Creates the embeddings of the query texts, usually the
+//     collection's embedding function. Optional if all queries have embeddings.
+func GroundTruth(ctx context.Context, c *chromem.Collection, queries []Query, k int, embeddingFunc chromem.EmbeddingFunc) ([]Query, error) {
+	err := Exact().Apply(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Query, len(queries))
+	for i, query := range queries {
+		if len(query.Embedding) == 0 {
+			if embeddingFunc == nil {
+				return nil, fmt.Errorf("query %d has no embedding and embeddingFunc is nil", i)
+			}
+			query.Embedding, err = embeddingFunc(ctx, query.Text)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create embedding of query %d: %w", i, err)
+			}
+		}
+		query.Relevant, err = CollectionSearch(c)(ctx, query, k)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't run query %d: %w", i, err)
+		}
+		res[i] = query
+	}
+	return res, nil
+}
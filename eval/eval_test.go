@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestScore(t *testing.T) {
+	tt := []struct {
+		name       string
+		ids        []string
+		relevant   []string
+		k          int
+		recall, rr float64
+		ndcg       float64
+	}{
+		{"perfect", []string{"a", "b"}, []string{"a", "b"}, 2, 1, 1, 1},
+		{"none", []string{"c", "d"}, []string{"a", "b"}, 2, 0, 0, 0},
+		{"second", []string{"c", "a"}, []string{"a"}, 2, 1, 0.5, 1 / math.Log2(3)},
+		{"beyond k", []string{"c", "d", "a"}, []string{"a"}, 2, 0, 0, 0},
+		{"duplicate", []string{"a", "a"}, []string{"a", "b"}, 2, 0.5, 1, 1 / (1 + 1/math.Log2(3))},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			recall, rr, ndcg := score(tc.ids, tc.relevant, tc.k)
+			if math.Abs(recall-tc.recall) > 1e-9 || math.Abs(rr-tc.rr) > 1e-9 || math.Abs(ndcg-tc.ndcg) > 1e-9 {
+				t.Fatal("expected", tc.recall, tc.rr, tc.ndcg, "got", recall, rr, ndcg)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = r.Float32()*2 - 1
+		}
+		return v
+	}
+
+	db := chromem.NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := make([]chromem.Document, 500)
+	for i := range docs {
+		docs[i] = chromem.Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+	err = c.AddDocuments(ctx, docs, 4)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	queries := make([]Query, 20)
+	for i := range queries {
+		queries[i] = Query{Embedding: randomVector()}
+	}
+	queries, err = GroundTruth(ctx, c, queries, 10, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	results, err := Compare(ctx, c, queries, 10, []Setting{Exact(), IVF(20, 1)})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(results) != 2 {
+		t.Fatal("expected 2 results, got", len(results))
+	}
+	exact := results[0].Metrics
+	if exact.Recall != 1 || exact.MRR != 1 || math.Abs(exact.NDCG-1) > 1e-9 {
+		t.Fatal("expected perfect metrics for exact search, got", exact)
+	}
+	ivf := results[1].Metrics
+	if ivf.Recall >= 1 || ivf.Recall <= 0 {
+		t.Fatal("expected lower recall for IVF with 1 probe, got", ivf.Recall)
+	}
+
+	// Queries without relevant documents can't be evaluated
+	_, err = Evaluate(ctx, CollectionSearch(c), []Query{{Embedding: randomVector()}}, 10)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
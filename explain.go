@@ -0,0 +1,92 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"slices"
+)
+
+// MatchExplanation describes why a document matched a hybrid query, e.g. to
+// debug relevance complaints, see [Collection.QueryHybridEmbeddingExplained].
+type MatchExplanation struct {
+	// Dense is the cosine similarity of the document's embedding to the query
+	// embedding, or the dot product without normalization.
+	Dense float32 `json:"dense"`
+	// Sparse is the dot product of the document's sparse embedding with the
+	// sparse query embedding, e.g. the BM25 score.
+	Sparse float32 `json:"sparse"`
+	// SparseNormalized is Sparse divided by the highest sparse score among the
+	// filtered documents, as it's fused with the dense score.
+	SparseNormalized float32 `json:"sparse_normalized"`
+	// Alpha is the weight of the dense score, so the fused score is
+	// Alpha*Dense + (1-Alpha)*SparseNormalized.
+	Alpha float32 `json:"alpha"`
+	// Terms are the matched dimensions of the sparse embeddings, sorted by
+	// their contribution to Sparse, in descending order.
+	Terms []TermMatch `json:"terms,omitempty"`
+}
+
+// TermMatch is a dimension of the sparse query embedding that the document
+// matched, see [MatchExplanation].
+type TermMatch struct {
+	// Index is the dimension, e.g. the hash of a BM25 term or a SPLADE token ID.
+	Index uint32 `json:"index"`
+	// Term is the term of the dimension, if known, see
+	// [Collection.QueryHybridEmbeddingExplained].
+	Term string `json:"term,omitempty"`
+	// Contribution is the product of the query's and the document's values.
+	Contribution float32 `json:"contribution"`
+}
+
+// QueryHybridEmbeddingExplained is like [Collection.QueryHybridEmbedding], but
+// sets [Result.Explanation] with the dense and sparse components of the fused
+// score and the matched terms of each result.
+//
+//   - queryTerms: Maps the dimensions of the sparse query embedding to their
+//     terms, e.g. from [BM25.QueryTerms], so that the matched terms are
+//     readable. Optional, only the dimensions are explained without it.
+func (c *Collection) QueryHybridEmbeddingExplained(ctx context.Context, queryEmbedding []float32, querySparse SparseVector, nResults int, where, whereDocument map[string]string, alpha float32, queryTerms map[uint32]string) ([]Result, error) {
+	return c.queryHybridEmbedding(ctx, queryEmbedding, querySparse, nResults, where, whereDocument, alpha, true, queryTerms, "")
+}
+
+// explainHybrid explains the fused score of the document for the prepared
+// query embeddings.
+func explainHybrid(queryEmbedding []float32, querySparse SparseVector, doc *Document, alpha, maxSparse float32, queryTerms map[uint32]string) (*MatchExplanation, error) {
+	dense, err := dotProduct(queryEmbedding, doc.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	res := &MatchExplanation{
+		Dense: dense,
+		Alpha: alpha,
+	}
+
+	// Like sparseDotProduct, but recording each matched dimension.
+	a, b := querySparse, doc.SparseEmbedding
+	i, j := 0, 0
+	for i < len(a.Indices) && j < len(b.Indices) {
+		switch {
+		case a.Indices[i] < b.Indices[j]:
+			i++
+		case a.Indices[i] > b.Indices[j]:
+			j++
+		default:
+			contribution := a.Values[i] * b.Values[j]
+			res.Sparse += contribution
+			res.Terms = append(res.Terms, TermMatch{
+				Index:        a.Indices[i],
+				Term:         queryTerms[a.Indices[i]],
+				Contribution: contribution,
+			})
+			i++
+			j++
+		}
+	}
+	if maxSparse > 0 {
+		res.SparseNormalized = res.Sparse / maxSparse
+	}
+	slices.SortStableFunc(res.Terms, func(a, b TermMatch) int {
+		return cmp.Compare(b.Contribution, a.Contribution)
+	})
+	return res, nil
+}
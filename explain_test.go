@@ -0,0 +1,78 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollection_QueryHybridEmbeddingExplained(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	bm25 := NewBM25(nil, 0, -1)
+	texts := map[string]string{
+		"cats":  "Cats purr and cats sleep",
+		"dogs":  "Dogs bark",
+		"other": "Something else entirely",
+	}
+	for _, text := range texts {
+		bm25.Fit(text)
+	}
+	embeddings := map[string][]float32{
+		"cats":  {0, 1},
+		"dogs":  {1, 0},
+		"other": {-1, 0},
+	}
+	for id, text := range texts {
+		err = c.AddDocument(ctx, Document{ID: id, Content: text, Embedding: embeddings[id], SparseEmbedding: bm25.EncodeDocument(text)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	query := "sleeping cats bark"
+	res, err := c.QueryHybridEmbeddingExplained(ctx, []float32{1, 0}, bm25.EncodeQuery(query), 3, nil, nil, 0.5, bm25.QueryTerms(query))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 3 {
+		t.Fatal("expected 3 results, got", len(res))
+	}
+	for _, r := range res {
+		e := r.Explanation
+		if e == nil {
+			t.Fatal("expected explanation, got nil")
+		}
+		if fused := e.Alpha*e.Dense + (1-e.Alpha)*e.SparseNormalized; fused != r.Similarity {
+			t.Fatal("expected fused score", r.Similarity, "got", fused)
+		}
+		switch r.ID {
+		case "cats":
+			// "sleeping" doesn't match "sleep" without stemming.
+			if e.Dense != 0 || len(e.Terms) != 1 || e.Terms[0].Term != "cats" || e.Terms[0].Contribution != e.Sparse {
+				t.Fatal("expected only cats to match, got", e)
+			}
+		case "dogs":
+			if e.Dense != 1 || len(e.Terms) != 1 || e.Terms[0].Term != "bark" {
+				t.Fatal("expected dense match and bark to match, got", e)
+			}
+		case "other":
+			if e.Sparse != 0 || len(e.Terms) != 0 || e.SparseNormalized != 0 {
+				t.Fatal("expected no sparse match, got", e)
+			}
+		}
+	}
+
+	// Not explained by default
+	res, err = c.QueryHybridEmbedding(ctx, []float32{1, 0}, bm25.EncodeQuery(query), 1, nil, nil, 0.5)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].Explanation != nil {
+		t.Fatal("expected no explanation, got", res[0].Explanation)
+	}
+}
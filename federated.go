@@ -0,0 +1,228 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Constant of the reciprocal rank fusion, see [FederatedMergeRRF]. 60 is the
+// common choice from the original paper.
+const rrfK = 60
+
+// FederatedMerge defines how [DB.QueryCollectionsWithMerge] makes the results
+// of different collections comparable before merging them.
+type FederatedMerge string
+
+const (
+	// FederatedMergeMinMax min-max normalizes the similarities of each
+	// collection's results to [0, 1]. It works with any embedding models and
+	// similarity functions, but each collection's best result gets a score of
+	// 1, even if it's barely relevant, so a collection without good matches
+	// pushes its results up. It's the default.
+	FederatedMergeMinMax FederatedMerge = "minmax"
+	// FederatedMergeRaw uses the similarities as they are. It keeps the
+	// differences in relevance between the collections, but it's only
+	// meaningful if they use the same embedding model and similarity function.
+	FederatedMergeRaw FederatedMerge = "raw"
+	// FederatedMergeRRF scores the results by reciprocal rank fusion, i.e.
+	// 1/(60+rank) with the rank within the collection's results starting at 1.
+	// Like min-max normalization it works with any models, and it's less
+	// sensitive to single outliers, but it ignores how relevant the results
+	// are in absolute terms as well.
+	FederatedMergeRRF FederatedMerge = "rrf"
+)
+
+// FederatedResult represents a single result from a query over multiple collections.
+type FederatedResult struct {
+	Result
+
+	// The name of the collection the document belongs to.
+	Collection string `json:"collection"`
+	// The similarity made comparable across collections (see [FederatedMerge])
+	// and multiplied with the collection's weight. The original similarity is
+	// still available via Result.Similarity, and the calibrated score via
+	// Result.Score.
+	Score float32 `json:"score"`
+}
+
+// QueryCollections queries multiple collections in parallel and merges the
+// results. This is useful when corpora are split by source, but should be
+// searched together.
+//
+// As the collections can use different embedding models, similarities aren't
+// comparable across collections. So the similarities of each collection's results
+// are min-max normalized to [0, 1] and then multiplied by the collection's weight.
+// The merged results are sorted by this score (descending). This means each
+// collection's best result gets the full weight, even if it's a poor match, so
+// a collection without relevant documents ranks as high as one with a perfect
+// match. For collections with the same embedding model and similarity
+// function, merging the raw similarities avoids this, see
+// [DB.QueryCollectionsWithMerge] and [FederatedMerge].
+//
+//   - names: The names of the collections to query. Must not be empty.
+//   - queryText: The text to search for. Its embedding is created with each
+//     collection's embedding function.
+//   - nResults: The number of results to return. Must be > 0. Each collection is
+//     queried for up to nResults documents.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+//   - weights: The weight per collection name. Optional. Collections without
+//     weight have a weight of 1.
+func (db *DB) QueryCollections(ctx context.Context, names []string, queryText string, nResults int, where, whereDocument map[string]string, weights map[string]float32) ([]FederatedResult, error) {
+	return db.QueryCollectionsWithMerge(ctx, names, queryText, nResults, where, whereDocument, weights, FederatedMergeMinMax)
+}
+
+// QueryCollectionsWithMerge is like [DB.QueryCollections], but makes the
+// similarities comparable across collections with the given merge method
+// instead of the min-max normalization per collection. An empty merge method
+// is the default, [FederatedMergeMinMax].
+func (db *DB) QueryCollectionsWithMerge(ctx context.Context, names []string, queryText string, nResults int, where, whereDocument map[string]string, weights map[string]float32, merge FederatedMerge) ([]FederatedResult, error) {
+	switch merge {
+	case "", FederatedMergeMinMax, FederatedMergeRaw, FederatedMergeRRF:
+	default:
+		return nil, fmt.Errorf("unknown merge method '%s'", merge)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("names are empty")
+	}
+	if queryText == "" {
+		return nil, errors.New("queryText is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	for name, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of collection '%s' must be >= 0", name)
+		}
+	}
+
+	collections := make([]*Collection, 0, len(names))
+	embeds := make([]EmbeddingFunc, 0, len(names))
+	db.collectionsLock.RLock()
+	for _, name := range names {
+		c, ok := db.collections[name]
+		if !ok {
+			db.collectionsLock.RUnlock()
+			return nil, fmt.Errorf("collection '%s' %w", name, ErrNotFound)
+		}
+		// We don't fall back to the default embedding func here, because that
+		// would lead to garbage results if it's not the one the documents were
+		// embedded with. The function is read under the collection's lock, as
+		// it can be swapped by Collection.Reembed.
+		embed := c.getEmbed()
+		if embed == nil {
+			db.collectionsLock.RUnlock()
+			return nil, fmt.Errorf("collection '%s' has no embedding function yet, get it via DB.GetCollection() first", name)
+		}
+		collections = append(collections, c)
+		embeds = append(embeds, embed)
+	}
+	db.collectionsLock.RUnlock()
+
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+			// Cancel the operation for all other goroutines.
+			cancel(sharedErr)
+		}
+	}
+
+	resultsPerCollection := make([][]FederatedResult, len(collections))
+	wg := sync.WaitGroup{}
+	for i, c := range collections {
+		wg.Add(1)
+		go func(i int, c *Collection) {
+			defer wg.Done()
+
+			n := min(nResults, c.Count())
+			if n == 0 {
+				return
+			}
+			res, err := c.QueryWithOptions(ctx, QueryOptions{
+				QueryText:     queryText,
+				NResults:      n,
+				Where:         where,
+				WhereDocument: whereDocument,
+				EmbeddingFunc: embeds[i],
+			})
+			if err != nil {
+				setSharedErr(fmt.Errorf("couldn't query collection '%s': %w", c.CurrentName(), err))
+				return
+			}
+
+			weight := float32(1)
+			if w, ok := weights[c.CurrentName()]; ok {
+				weight = w
+			}
+			resultsPerCollection[i] = federatedResults(c.CurrentName(), res, weight, merge)
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	if sharedErr != nil {
+		return nil, sharedErr
+	}
+
+	var merged []FederatedResult
+	for _, res := range resultsPerCollection {
+		merged = append(merged, res...)
+	}
+	slices.SortStableFunc(merged, func(a, b FederatedResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(merged) > nResults {
+		merged = merged[:nResults]
+	}
+
+	return merged, nil
+}
+
+// federatedResults makes the similarities of one collection's results, in the
+// order of the query, comparable with the merge method and applies the weight. With min-max normalization, equal similarities are normalized to 1.
+func federatedResults(collectionName string, results []Result, weight float32, merge FederatedMerge) []FederatedResult {
+	if len(results) == 0 {
+		return nil
+	}
+
+	minSim, maxSim := results[0].Similarity, results[0].Similarity
+	for _, res := range results[1:] {
+		minSim = min(minSim, res.Similarity)
+		maxSim = max(maxSim, res.Similarity)
+	}
+
+	fedResults := make([]FederatedResult, 0, len(results))
+	for i, res := range results {
+		var normalized float32
+		switch merge {
+		case FederatedMergeRaw:
+			normalized = res.Similarity
+		case FederatedMergeRRF:
+			normalized = 1 / float32(rrfK+i+1)
+		default:
+			normalized = 1
+			if maxSim > minSim {
+				normalized = (res.Similarity - minSim) / (maxSim - minSim)
+			}
+		}
+		fedResults = append(fedResults, FederatedResult{
+			Result:     res,
+			Collection: collectionName,
+			Score:      normalized * weight,
+		})
+	}
+
+	return fedResults
+}
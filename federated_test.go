@@ -0,0 +1,179 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestDB_QueryCollections(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		switch text {
+		case "a":
+			return []float32{1, 0, 0}, nil
+		case "b":
+			return []float32{0.8, 0.6, 0}, nil
+		default:
+			return []float32{0, 0, 1}, nil
+		}
+	}
+
+	db := NewDB()
+	c1, err := db.CreateCollection("c1", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c1.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "a"},
+		{ID: "2", Content: "c"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2, err := db.CreateCollection("c2", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c2.AddDocuments(ctx, []Document{
+		{ID: "3", Content: "b"},
+		{ID: "4", Content: "c"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	t.Run("Without weights", func(t *testing.T) {
+		res, err := db.QueryCollections(ctx, []string{"c1", "c2"}, "a", 4, nil, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 4 {
+			t.Fatal("expected 4 results, got", len(res))
+		}
+		// Both best matches are normalized to 1, so their order follows the
+		// order of the names.
+		if res[0].ID != "1" || res[0].Collection != "c1" || res[0].Score != 1 {
+			t.Fatal("expected doc 1 from c1 with score 1, got", res[0])
+		}
+		if res[1].ID != "3" || res[1].Collection != "c2" || res[1].Score != 1 {
+			t.Fatal("expected doc 3 from c2 with score 1, got", res[1])
+		}
+		// The original similarity is kept
+		if res[1].Similarity < 0.79 || res[1].Similarity > 0.81 {
+			t.Fatal("expected similarity 0.8, got", res[1].Similarity)
+		}
+		if res[2].Score != 0 || res[3].Score != 0 {
+			t.Fatal("expected score 0, got", res[2].Score, res[3].Score)
+		}
+	})
+
+	t.Run("With weights", func(t *testing.T) {
+		res, err := db.QueryCollections(ctx, []string{"c1", "c2"}, "a", 1, nil, nil, map[string]float32{"c1": 0.5})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 {
+			t.Fatal("expected 1 result, got", len(res))
+		}
+		if res[0].ID != "3" {
+			t.Fatal("expected doc 3, got", res[0].ID)
+		}
+	})
+
+	t.Run("Merge methods", func(t *testing.T) {
+		// The raw similarities keep c2's best match below c1's.
+		res, err := db.QueryCollectionsWithMerge(ctx, []string{"c2", "c1"}, "a", 2, nil, nil, nil, FederatedMergeRaw)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 2 || res[0].ID != "1" || res[1].ID != "3" || res[1].Score < 0.79 || res[1].Score > 0.81 {
+			t.Fatal("expected doc 1 and doc 3 with score 0.8, got", res)
+		}
+
+		res, err = db.QueryCollectionsWithMerge(ctx, []string{"c1", "c2"}, "a", 4, nil, nil, map[string]float32{"c2": 2}, FederatedMergeRRF)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 4 || res[0].ID != "3" || res[0].Score != 2.0/61 || res[1].ID != "4" || res[2].ID != "1" || res[2].Score != 1.0/61 {
+			t.Fatal("expected docs 3, 4 and 1 with reciprocal rank scores, got", res)
+		}
+
+		_, err = db.QueryCollectionsWithMerge(ctx, []string{"c1"}, "a", 1, nil, nil, nil, "foo")
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := db.QueryCollections(ctx, nil, "a", 1, nil, nil, nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		_, err = db.QueryCollections(ctx, []string{"c1", "foo"}, "a", 1, nil, nil, nil)
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+		_, err = db.QueryCollections(ctx, []string{"c1"}, "a", 1, nil, nil, map[string]float32{"c1": -1})
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
+
+func TestDB_QueryCollections_Reembed(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("c", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{{ID: "1", Content: "a"}, {ID: "2", Content: "b"}}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Swapping the embedding function while querying must not race (run with
+	// -race).
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := c.Reembed(ctx, embeddingFunc, "model-b", 1, nil)
+		if err != nil {
+			t.Error("expected no error, got", err)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, err := db.QueryCollections(ctx, []string{"c"}, "a", 1, nil, nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestFederatedResult_JSON(t *testing.T) {
+	res := FederatedResult{
+		Result:     Result{ID: "1", Similarity: 0.5, Score: 0.75},
+		Collection: "a",
+		Score:      0.25,
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	var m map[string]any
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if m["score"] != 0.25 || m["calibrated_score"] != 0.75 || m["similarity"] != 0.5 {
+		t.Fatal("expected both scores and the similarity, got", string(b))
+	}
+}
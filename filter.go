@@ -0,0 +1,278 @@
+package chromem
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Filter is a typed filter on the metadata and content of documents, built with
+// [Where] and chained methods, e.g.:
+//
+//	chromem.Where().Eq("lang", "en").Gt("year", 2020).Or(chromem.Where().Eq("pinned", "true"))
+//
+// Chained conditions must all match. Filters are immutable, so a filter can be
+// reused as the base of multiple filters. The zero value matches all documents.
+// Use it with [QueryOptions.Filter], in addition to or instead of the where and
+// whereDocument maps.
+type Filter struct {
+	conds []filterCond
+}
+
+type filterCond struct {
+	// desc describes the condition, for String and query cache keys.
+	desc  string
+	match func(doc *Document) bool
+	// content is whether the condition needs the document's content.
+	content bool
+	// eqKey and eqValues are set for conditions that match documents whose
+	// metadata value of the key is one of the values, so that their
+	// selectivity can be estimated with the metadata statistics.
+	eqKey    string
+	eqValues []string
+}
+
+// Where returns an empty filter, which matches all documents, to chain
+// conditions to.
+func Where() Filter {
+	return Filter{}
+}
+
+// with returns a copy of the filter with the condition added.
+func (f Filter) with(c filterCond) Filter {
+	conds := make([]filterCond, len(f.conds), len(f.conds)+1)
+	copy(conds, f.conds)
+	return Filter{conds: append(conds, c)}
+}
+
+// Eq matches documents whose metadata value of the key equals the value.
+func (f Filter) Eq(key, value string) Filter {
+	return f.with(filterCond{
+		desc: strconv.Quote(key) + " == " + strconv.Quote(value),
+		match: func(doc *Document) bool {
+			v, ok := doc.Metadata[key]
+			return ok && v == value
+		},
+		eqKey:    key,
+		eqValues: []string{value},
+	})
+}
+
+// Ne matches documents whose metadata value of the key doesn't equal the value,
+// including documents without the key.
+func (f Filter) Ne(key, value string) Filter {
+	return f.with(filterCond{
+		desc: strconv.Quote(key) + " != " + strconv.Quote(value),
+		match: func(doc *Document) bool {
+			v, ok := doc.Metadata[key]
+			return !ok || v != value
+		},
+	})
+}
+
+// In matches documents whose metadata value of the key is one of the values.
+func (f Filter) In(key string, values ...string) Filter {
+	set := make(map[string]struct{}, len(values))
+	quoted := make([]string, len(values))
+	uniqueValues := make([]string, 0, len(values))
+	for i, v := range values {
+		if _, ok := set[v]; !ok {
+			uniqueValues = append(uniqueValues, v)
+		}
+		set[v] = struct{}{}
+		quoted[i] = strconv.Quote(v)
+	}
+	return f.with(filterCond{
+		desc: strconv.Quote(key) + " in [" + strings.Join(quoted, ", ") + "]",
+		match: func(doc *Document) bool {
+			v, ok := doc.Metadata[key]
+			if !ok {
+				return false
+			}
+			_, ok = set[v]
+			return ok
+		},
+		eqKey:    key,
+		eqValues: uniqueValues,
+	})
+}
+
+// Exists matches documents that have the metadata key.
+func (f Filter) Exists(key string) Filter {
+	return f.with(filterCond{
+		desc: "exists " + strconv.Quote(key),
+		match: func(doc *Document) bool {
+			_, ok := doc.Metadata[key]
+			return ok
+		},
+	})
+}
+
+// IDs matches documents with one of the IDs, e.g. to re-rank a set of
+// candidates from another search.
+func (f Filter) IDs(ids ...string) Filter {
+	set := make(map[string]struct{}, len(ids))
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		set[id] = struct{}{}
+		quoted[i] = strconv.Quote(id)
+	}
+	return f.with(filterCond{
+		desc: "id in [" + strings.Join(quoted, ", ") + "]",
+		match: func(doc *Document) bool {
+			_, ok := set[doc.ID]
+			return ok
+		},
+	})
+}
+
+// IDPrefix matches documents whose ID starts with the prefix, e.g. "doc-42/"
+// for all chunks of a document whose chunk IDs are prefixed with its ID.
+func (f Filter) IDPrefix(prefix string) Filter {
+	return f.with(filterCond{
+		desc:  "id starts with " + strconv.Quote(prefix),
+		match: func(doc *Document) bool { return strings.HasPrefix(doc.ID, prefix) },
+	})
+}
+
+// Gt matches documents whose metadata value of the key is a number greater than
+// the value. Documents whose value isn't a number don't match.
+func (f Filter) Gt(key string, value float64) Filter {
+	return f.compare(key, ">", value, func(v float64) bool { return v > value })
+}
+
+// Gte matches documents whose metadata value of the key is a number greater
+// than or equal to the value. Documents whose value isn't a number don't match.
+func (f Filter) Gte(key string, value float64) Filter {
+	return f.compare(key, ">=", value, func(v float64) bool { return v >= value })
+}
+
+// Lt matches documents whose metadata value of the key is a number less than
+// the value. Documents whose value isn't a number don't match.
+func (f Filter) Lt(key string, value float64) Filter {
+	return f.compare(key, "<", value, func(v float64) bool { return v < value })
+}
+
+// Lte matches documents whose metadata value of the key is a number less than
+// or equal to the value. Documents whose value isn't a number don't match.
+func (f Filter) Lte(key string, value float64) Filter {
+	return f.compare(key, "<=", value, func(v float64) bool { return v <= value })
+}
+
+func (f Filter) compare(key, op string, value float64, cmp func(v float64) bool) Filter {
+	return f.with(filterCond{
+		desc: strconv.Quote(key) + " " + op + " " + strconv.FormatFloat(value, 'g', -1, 64),
+		match: func(doc *Document) bool {
+			s, ok := doc.Metadata[key]
+			if !ok {
+				return false
+			}
+			v, err := strconv.ParseFloat(s, 64)
+			return err == nil && cmp(v)
+		},
+	})
+}
+
+// Contains matches documents whose content contains the text.
+func (f Filter) Contains(text string) Filter {
+	return f.with(filterCond{
+		desc:    "content contains " + strconv.Quote(text),
+		match:   func(doc *Document) bool { return strings.Contains(doc.Content, text) },
+		content: true,
+	})
+}
+
+// NotContains matches documents whose content doesn't contain the text.
+func (f Filter) NotContains(text string) Filter {
+	return f.with(filterCond{
+		desc:    "content not contains " + strconv.Quote(text),
+		match:   func(doc *Document) bool { return !strings.Contains(doc.Content, text) },
+		content: true,
+	})
+}
+
+// And matches documents that match the filter and all of the given filters.
+// It's the same as chaining their conditions, but allows composing filters that
+// were built separately.
+func (f Filter) And(filters ...Filter) Filter {
+	for _, other := range filters {
+		for _, c := range other.conds {
+			f = f.with(c)
+		}
+	}
+	return f
+}
+
+// Or matches documents that match the filter or any of the given filters. For
+// example, Where().Eq("a", "1").Or(Where().Eq("b", "2")) matches documents
+// with a=1 or b=2. If the filter is empty, like Where(), it matches documents
+// that match any of the given filters.
+func (f Filter) Or(filters ...Filter) Filter {
+	all := filters
+	if !f.isEmpty() {
+		all = append([]Filter{f}, filters...)
+	}
+	descs := make([]string, len(all))
+	content := false
+	for i, other := range all {
+		descs[i] = "(" + other.String() + ")"
+		content = content || other.needsContent()
+	}
+	return Filter{conds: []filterCond{{
+		desc: strings.Join(descs, " or "),
+		match: func(doc *Document) bool {
+			for _, other := range all {
+				if other.matches(doc) {
+					return true
+				}
+			}
+			return false
+		},
+		content: content,
+	}}}
+}
+
+// Not matches documents that match the filter but not the given filter.
+func (f Filter) Not(filter Filter) Filter {
+	return f.with(filterCond{
+		desc:    "not (" + filter.String() + ")",
+		match:   func(doc *Document) bool { return !filter.matches(doc) },
+		content: filter.needsContent(),
+	})
+}
+
+// String describes the filter, e.g. `"lang" == "en" and "year" > 2020`.
+func (f Filter) String() string {
+	if len(f.conds) == 0 {
+		return "all"
+	}
+	descs := make([]string, len(f.conds))
+	for i, c := range f.conds {
+		descs[i] = c.desc
+	}
+	return strings.Join(descs, " and ")
+}
+
+// isEmpty returns whether the filter matches all documents.
+func (f Filter) isEmpty() bool {
+	return len(f.conds) == 0
+}
+
+// needsContent returns whether any condition needs the document's content.
+func (f Filter) needsContent() bool {
+	for _, c := range f.conds {
+		if c.content {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns whether the document matches all conditions.
+func (f Filter) matches(doc *Document) bool {
+	for _, c := range f.conds {
+		if !c.match(doc) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package chromem
+
+import (
+	"fmt"
+)
+
+// Maximum number of documents whose filter matches are counted to estimate the
+// selectivity of a query's filters, see [FilterStrategyAuto].
+const selectivitySampleSize = 512
+
+// FilterStrategy defines how the filters of a query are combined with an
+// approximate nearest neighbor index, i.e. the IVF index (see
+// [Collection.BuildIVFIndex]). Without an index, or without filters, all
+// strategies are the same.
+type FilterStrategy string
+
+const (
+	// FilterStrategyAuto chooses pre-filtering if the filters are so selective
+	// that the probed clusters are expected to contain fewer than nResults
+	// matching documents, and post-filtering otherwise. The selectivity is
+	// estimated by counting the matches in a sample of up to 512 documents, or
+	// with the metadata statistics, see [Collection.EnableMetadataStats].
+	// Content filters are only part of the estimate if the content is in
+	// memory uncompressed. After post-filtering, the query falls back to an exhaustive
+	// search if fewer than nResults documents are left. It's the default.
+	FilterStrategyAuto FilterStrategy = "auto"
+	// FilterStrategyPre filters all documents and then searches the matching
+	// ones exhaustively, without the index. The results are exact, and queries
+	// with selective filters, like the documents of a single tenant, are fast,
+	// but unselective filters make the search slow.
+	FilterStrategyPre FilterStrategy = "pre"
+	// FilterStrategyPost searches the probed clusters of the index and then
+	// filters the results. It's fast, but with selective filters fewer than
+	// nResults documents might be found, or none at all, because the matching
+	// documents are in other clusters.
+	FilterStrategyPost FilterStrategy = "post"
+)
+
+// validate checks the strategy. The empty strategy is valid and means the
+// default.
+func (s FilterStrategy) validate() error {
+	switch s {
+	case "", FilterStrategyAuto, FilterStrategyPre, FilterStrategyPost:
+		return nil
+	}
+	return fmt.Errorf("unsupported filter strategy '%s'", s)
+}
+
+// estimateSelectivity estimates the fraction of the documents that match the
+// filters, between 0 and 1. Equality conditions on keys with metadata
+// statistics are estimated with them, the other filters by counting the
+// matches in an evenly spaced sample of the documents. Content filters are
+// ignored if matching them would require reading or decrypting the content.
+// Must be called while holding documentsLock.
+func (c *Collection) estimateSelectivity(docs []*Document, where, whereDocument map[string]string, filter Filter) float64 {
+	if len(docs) == 0 {
+		return 0
+	}
+	statsSelectivity, where, filter := c.statsSelectivity(where, filter)
+	if len(where) == 0 && len(whereDocument) == 0 && filter.isEmpty() {
+		return statsSelectivity
+	}
+	return statsSelectivity * c.sampleSelectivity(docs, where, whereDocument, filter)
+}
+
+// sampleSelectivity estimates the selectivity of the filters by counting the
+// matches in an evenly spaced sample of the documents. Must be called while
+// holding documentsLock.
+func (c *Collection) sampleSelectivity(docs []*Document, where, whereDocument map[string]string, filter Filter) float64 {
+	step := max(1, len(docs)/selectivitySampleSize)
+	sample := make([]*Document, 0, min(len(docs), selectivitySampleSize))
+	for i := 0; i < len(docs) && len(sample) < selectivitySampleSize; i += step {
+		sample = append(sample, docs[i])
+	}
+
+	if len(c.evicted) > 0 || c.contentEncryptedInMemory || c.discardContent || c.compressed != nil {
+		whereDocument = nil
+		metadataOnly := Filter{}
+		for _, cond := range filter.conds {
+			if !cond.content {
+				metadataOnly.conds = append(metadataOnly.conds, cond)
+			}
+		}
+		filter = metadataOnly
+	}
+	matches, err := c.filterWith(sample, where, whereDocument, filter)
+	if err != nil {
+		// Assume that all documents match, so that the index is used.
+		return 1
+	}
+	return float64(len(matches)) / float64(len(sample))
+}
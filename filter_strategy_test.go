@@ -0,0 +1,86 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_FilterStrategy(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Two clusters, with the documents of tenant x only in the second one.
+	var docs []Document
+	for i := 0; i < 100; i++ {
+		v := float32(i%50) / 100
+		doc := Document{ID: strconv.Itoa(i), Metadata: map[string]string{"tenant": "y"}, Embedding: []float32{1, v}}
+		if i >= 50 {
+			doc.Embedding = []float32{v, 1}
+			if i >= 98 {
+				doc.Metadata["tenant"] = "x"
+			}
+		}
+		docs = append(docs, doc)
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.BuildIVFIndex(ctx, 2, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	query := func(strategy FilterStrategy, tenant string) []Result {
+		t.Helper()
+		res, err := c.QueryWithOptions(ctx, QueryOptions{
+			QueryEmbedding: []float32{1, 0},
+			NResults:       1,
+			Where:          map[string]string{"tenant": tenant},
+			FilterStrategy: strategy,
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return res
+	}
+
+	// Post-filtering only searches the probed cluster, which doesn't contain
+	// documents of tenant x.
+	if res := query(FilterStrategyPost, "x"); len(res) != 0 {
+		t.Fatal("expected no results, got", res)
+	}
+	for _, strategy := range []FilterStrategy{FilterStrategyPre, FilterStrategyAuto, ""} {
+		if res := query(strategy, "x"); len(res) != 1 || res[0].ID != "99" {
+			t.Fatal("expected document 99 with strategy", strategy, "got", res)
+		}
+	}
+	// Unselective filters are applied to the probed cluster.
+	for _, strategy := range []FilterStrategy{FilterStrategyPost, FilterStrategyAuto} {
+		if res := query(strategy, "y"); len(res) != 1 || res[0].ID != "0" {
+			t.Fatal("expected document 0 with strategy", strategy, "got", res)
+		}
+	}
+
+	// Collection default
+	err = c.SetQueryDefaults(QueryDefaults{FilterStrategy: FilterStrategyPost})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res := query("", "x"); len(res) != 0 {
+		t.Fatal("expected no results, got", res)
+	}
+
+	err = c.SetQueryDefaults(QueryDefaults{FilterStrategy: "unknown"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1, FilterStrategy: "unknown"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,133 @@
+package chromem
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	docs := []*Document{
+		{ID: "doc-1/1", Metadata: map[string]string{"lang": "en", "year": "2019"}, Content: "hello world"},
+		{ID: "doc-1/2", Metadata: map[string]string{"lang": "en", "year": "2021"}, Content: "hello again"},
+		{ID: "doc-10/1", Metadata: map[string]string{"lang": "de", "year": "2022"}, Content: "hallo welt"},
+		{ID: "doc-2/1", Metadata: map[string]string{"lang": "de", "year": "unknown", "pinned": "true"}, Content: "hallo"},
+	}
+
+	tt := []struct {
+		name   string
+		filter Filter
+		want   []string
+		desc   string
+	}{
+		{"empty", Where(), []string{"1", "2", "3", "4"}, "all"},
+		{"eq", Where().Eq("lang", "en"), []string{"1", "2"}, `"lang" == "en"`},
+		{"ne", Where().Ne("pinned", "true"), []string{"1", "2", "3"}, `"pinned" != "true"`},
+		{"in", Where().In("year", "2019", "2022"), []string{"1", "3"}, `"year" in ["2019", "2022"]`},
+		{"ids", Where().IDs("doc-1/2", "doc-2/1", "doc-3/1"), []string{"2", "4"}, `id in ["doc-1/2", "doc-2/1", "doc-3/1"]`},
+		{"id prefix", Where().IDPrefix("doc-1/"), []string{"1", "2"}, `id starts with "doc-1/"`},
+		{"exists", Where().Exists("pinned"), []string{"4"}, `exists "pinned"`},
+		{"gt", Where().Gt("year", 2020), []string{"2", "3"}, `"year" > 2020`},
+		{"gte", Where().Gte("year", 2021), []string{"2", "3"}, `"year" >= 2021`},
+		{"lt", Where().Lt("year", 2021), []string{"1"}, `"year" < 2021`},
+		{"lte", Where().Lte("year", 2021), []string{"1", "2"}, `"year" <= 2021`},
+		{"contains", Where().Contains("hallo"), []string{"3", "4"}, `content contains "hallo"`},
+		{"not contains", Where().NotContains("hallo"), []string{"1", "2"}, `content not contains "hallo"`},
+		{"chained", Where().Eq("lang", "en").Gt("year", 2020), []string{"2"}, `"lang" == "en" and "year" > 2020`},
+		{
+			"or",
+			Where().Eq("lang", "en").Gt("year", 2020).Or(Where().Eq("pinned", "true")),
+			[]string{"2", "4"},
+			`("lang" == "en" and "year" > 2020) or ("pinned" == "true")`,
+		},
+		{"or on empty", Where().Or(Where().Eq("year", "2019"), Where().Exists("pinned")), []string{"1", "4"}, `("year" == "2019") or (exists "pinned")`},
+		{"and", Where().Eq("lang", "de").And(Where().Contains("welt")), []string{"3"}, `"lang" == "de" and content contains "welt"`},
+		{"not", Where().Eq("lang", "de").Not(Where().Exists("pinned")), []string{"3"}, `"lang" == "de" and not (exists "pinned")`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The positions of the matching documents.
+			var got []string
+			for i, doc := range docs {
+				if tc.filter.matches(doc) {
+					got = append(got, strconv.Itoa(i+1))
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+			if tc.filter.String() != tc.desc {
+				t.Fatal("expected", tc.desc, "got", tc.filter.String())
+			}
+		})
+	}
+
+	// Filters are immutable.
+	base := Where().Eq("lang", "en")
+	_ = base.Gt("year", 2020)
+	if base.String() != `"lang" == "en"` {
+		t.Fatal("expected base filter to be unchanged, got", base.String())
+	}
+}
+
+func TestCollection_QueryWithOptions_Filter(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"lang": "en", "year": "2019"}, Embedding: []float32{1, 0}, Content: "hello world"},
+		{ID: "2", Metadata: map[string]string{"lang": "en", "year": "2021"}, Embedding: []float32{0.8, 0.6}, Content: "hello again"},
+		{ID: "3", Metadata: map[string]string{"lang": "de", "year": "2022"}, Embedding: []float32{0.6, 0.8}, Content: "hallo welt"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryText: "foo",
+		NResults:  3,
+		Where:     map[string]string{"lang": "en"},
+		Filter:    Where().Gt("year", 2020),
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatal("expected document 2, got", res)
+	}
+
+	// Restricting the query to a set of IDs, e.g. for re-ranking.
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "foo", NResults: 3, Filter: Where().IDs("3", "2")})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[1].ID != "3" {
+		t.Fatal("expected documents 2 and 3, got", res)
+	}
+
+	// The query cache distinguishes filters.
+	err = c.SetQueryCacheSize(10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, want := range []string{"1", "3"} {
+		filter := Where().Lt("year", 2020)
+		if want == "3" {
+			filter = Where().Contains("welt")
+		}
+		res, err = c.QueryWithOptions(ctx, QueryOptions{QueryText: "foo", NResults: 3, Filter: filter})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 1 || res[0].ID != want {
+			t.Fatal("expected document", want, "got", res)
+		}
+	}
+}
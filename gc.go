@@ -0,0 +1,237 @@
+package chromem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GCOptions configures [DB.GC].
+type GCOptions struct {
+	// DryRun only reports the orphaned files without removing them.
+	DryRun bool
+	// QuarantineDir is a directory to move the orphaned files to instead of
+	// removing them, e.g. to inspect them before deleting them for good. The
+	// files keep their path relative to the DB's directory. It must be on the
+	// same file system as the DB's directory. Optional.
+	QuarantineDir string
+}
+
+// GCReport is the result of [DB.GC].
+type GCReport struct {
+	// Paths of the orphaned files and directories.
+	Files []string
+	// Bytes is the total size of the orphaned files.
+	Bytes int64
+}
+
+// GC removes files of a persistent DB that don't belong to any document,
+// content or collection anymore. They're usually left behind by crashes, e.g.
+// when a document's file couldn't be removed after deleting it, or by failed
+// operations like deleting a collection. It's a no-op for in-memory DBs.
+//
+// Files that chromem-go doesn't create, e.g. ones that the user placed in the
+// DB's directory, are kept. Namespaces are only checked if they're loaded (see
+// [Collection.Namespace]). Writes to the DB are blocked while it's running.
+func (db *DB) GC(ctx context.Context, options GCOptions) (GCReport, error) {
+	report := GCReport{}
+	if db.persistDirectory == "" {
+		return report, nil
+	}
+
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+	if db.closed {
+		return report, ErrClosed
+	}
+
+	collectionDirs := make(map[string]struct{}, len(db.collections))
+	for _, c := range db.collections {
+		collectionDirs[c.persistDirectory] = struct{}{}
+		err := c.gc(ctx, db.persistDirectory, options, &report)
+		if err != nil {
+			return report, fmt.Errorf("couldn't collect garbage of collection '%s': %w", c.Name, err)
+		}
+	}
+
+	// Directories of collections that were deleted or whose deletion failed.
+	dirEntries, err := os.ReadDir(db.persistDirectory)
+	if err != nil {
+		return report, fmt.Errorf("couldn't read persistence directory: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		path := filepath.Join(db.persistDirectory, dirEntry.Name())
+		if _, ok := collectionDirs[path]; ok || !dirEntry.IsDir() || len(dirEntry.Name()) == 2*sha256.Size || !isPersistedName(dirEntry.Name()) {
+			continue
+		}
+		err := collectGarbage(ctx, db.persistDirectory, path, options, db.perms, &report)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// gc collects the orphaned files of the collection and its loaded namespaces.
+func (c *Collection) gc(ctx context.Context, dbDir string, options GCOptions, report *GCReport) error {
+	// Block writes, which persist documents outside of documentsLock.
+	c.persistLock.Lock()
+	defer c.persistLock.Unlock()
+	c.namespacesLock.Lock()
+	namespaces := make([]*Collection, 0, len(c.namespaces))
+	for _, ns := range c.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	c.namespacesLock.Unlock()
+
+	c.documentsLock.RLock()
+	// The expected files of documents, and content files if there's a store.
+	expected := make(map[string]struct{}, len(c.documents)+1)
+	expected[filepath.Join(c.persistDirectory, metadataFileName+c.fileExt())] = struct{}{}
+	for id := range c.documents {
+		expected[c.getDocPath(id)] = struct{}{}
+	}
+	var contentDir string
+	if c.contents != nil {
+		contentDir = c.contents.dir
+		for hash := range c.contents.blobs {
+			expected[c.contents.path(hash)] = struct{}{}
+		}
+	}
+	c.documentsLock.RUnlock()
+
+	ext := c.fileExt()
+	orphaned := func(dir string) ([]string, error) {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("couldn't read directory: %w", err)
+		}
+		var res []string
+		for _, dirEntry := range dirEntries {
+			if !dirEntry.Type().IsRegular() {
+				continue
+			}
+			path := filepath.Join(dir, dirEntry.Name())
+			// Temporary files of interrupted atomic writes are orphaned as well.
+			stem, ok := strings.CutSuffix(dirEntry.Name(), ext)
+			if !ok {
+				stem, ok = strings.CutSuffix(dirEntry.Name(), ext+".tmp")
+			}
+			if !ok || !isPersistedName(stem) {
+				continue
+			}
+			if _, ok := expected[path]; !ok {
+				res = append(res, path)
+			}
+		}
+		return res, nil
+	}
+
+	paths, err := orphaned(c.persistDirectory)
+	if err != nil {
+		return err
+	}
+	if contentDir != "" {
+		contentPaths, err := orphaned(contentDir)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, contentPaths...)
+	}
+	for _, path := range paths {
+		err := collectGarbage(ctx, dbDir, path, options, c.perms, report)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ns := range namespaces {
+		err := ns.gc(ctx, dbDir, options, report)
+		if err != nil {
+			return fmt.Errorf("couldn't collect garbage of namespace '%s': %w", ns.Name, err)
+		}
+	}
+	return nil
+}
+
+// collectGarbage adds the file or directory to the report and removes it or
+// moves it to the quarantine directory, depending on the options.
+func collectGarbage(ctx context.Context, dbDir, path string, options GCOptions, perms filePerms, report *GCReport) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	size, err := diskUsage(path)
+	if err != nil {
+		return err
+	}
+	report.Files = append(report.Files, path)
+	report.Bytes += size
+
+	switch {
+	case options.DryRun:
+		return nil
+	case options.QuarantineDir != "":
+		rel, err := filepath.Rel(dbDir, path)
+		if err != nil {
+			return fmt.Errorf("couldn't get relative path of %q: %w", path, err)
+		}
+		dst := filepath.Join(options.QuarantineDir, rel)
+		err = perms.mkdirAll(filepath.Dir(dst))
+		if err != nil {
+			return fmt.Errorf("couldn't create quarantine directory: %w", err)
+		}
+		err = os.Rename(path, dst)
+		if err != nil {
+			return fmt.Errorf("couldn't move %q to quarantine: %w", path, err)
+		}
+	default:
+		err := os.RemoveAll(path)
+		if err != nil {
+			return fmt.Errorf("couldn't remove %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// diskUsage returns the total size of the file or the files in the directory.
+func diskUsage(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get size of %q: %w", path, err)
+	}
+	return size, nil
+}
+
+// isHashName returns whether the name is a hex encoded hash like the ones of
+// collection directories, document files (see hash2hex) and content files, so
+// that files and directories of the user aren't touched.
+func isHashName(name string) bool {
+	if len(name) != 8 && len(name) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil && strings.ToLower(name) == name
+}
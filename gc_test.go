@@ -0,0 +1,100 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDB_GC(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Orphans: a document file, an interrupted metadata write and the directory
+	// of a deleted collection. The user's file must be kept.
+	orphanDoc := c.getDocPath("2")
+	orphanTmp := filepath.Join(c.persistDirectory, metadataFileName+c.fileExt()+".tmp")
+	orphanDir := filepath.Join(dir, hash2hex("deleted"))
+	userFile := filepath.Join(c.persistDirectory, "notes.txt")
+	for _, path := range []string{orphanDoc, orphanTmp, filepath.Join(orphanDir, hash2hex("3")+c.fileExt()), userFile} {
+		err = os.MkdirAll(filepath.Dir(path), 0o700)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = os.WriteFile(path, []byte("foo"), 0o600)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	exp := []string{orphanDir, orphanDoc, orphanTmp}
+	slices.Sort(exp)
+
+	// Dry run
+	report, err := db.GC(ctx, GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	slices.Sort(report.Files)
+	if !slices.Equal(report.Files, exp) || report.Bytes != 9 {
+		t.Fatal("expected", exp, "and 9 bytes, got", report)
+	}
+	if _, err := os.Stat(orphanDoc); err != nil {
+		t.Fatal("expected file to be kept in dry run, got", err)
+	}
+
+	// Quarantine
+	quarantineDir := filepath.Join(dir, "quarantine")
+	report, err = db.GC(ctx, GCOptions{QuarantineDir: quarantineDir})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Files) != 3 {
+		t.Fatal("expected 3 orphans, got", report.Files)
+	}
+	for _, path := range exp {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatal("expected file to be moved, got", err)
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if _, err := os.Stat(filepath.Join(quarantineDir, rel)); err != nil {
+			t.Fatal("expected file in quarantine, got", err)
+		}
+	}
+	if _, err := os.Stat(userFile); err != nil {
+		t.Fatal("expected user file to be kept, got", err)
+	}
+
+	// Nothing left, and the collection is intact.
+	report, err = db.GC(ctx, GCOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Files) != 0 {
+		t.Fatal("expected no orphans, got", report.Files)
+	}
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n := db2.GetCollection("test", nil).Count(); n != 1 {
+		t.Fatal("expected 1 document, got", n)
+	}
+}
@@ -0,0 +1,51 @@
+package chromem
+
+import (
+	"math"
+	"strconv"
+)
+
+// earthRadius is the mean radius of the earth in meters.
+const earthRadius = 6_371_008.8
+
+// WithinRadius matches documents whose location is within the radius around the
+// given location, for local search like "restaurants near me similar to ...".
+// The location of a document is read from the metadata keys latKey and lonKey,
+// as decimal degrees, e.g. "52.52" and "13.405". Documents without a valid
+// location don't match. The distance is the great-circle distance by the
+// haversine formula.
+//
+// Like all filters, it's applied before the similarity scoring.
+//
+//   - latKey, lonKey: The metadata keys of the latitude and longitude.
+//   - lat, lon: The center in decimal degrees.
+//   - radius: The radius in meters.
+func (f Filter) WithinRadius(latKey, lonKey string, lat, lon, radius float64) Filter {
+	return f.with(filterCond{
+		desc: "(" + strconv.Quote(latKey) + ", " + strconv.Quote(lonKey) + ") within_radius " +
+			strconv.FormatFloat(radius, 'g', -1, 64) + "m of (" +
+			strconv.FormatFloat(lat, 'g', -1, 64) + ", " + strconv.FormatFloat(lon, 'g', -1, 64) + ")",
+		match: func(doc *Document) bool {
+			docLat, err := strconv.ParseFloat(doc.Metadata[latKey], 64)
+			if err != nil || docLat < -90 || docLat > 90 {
+				return false
+			}
+			docLon, err := strconv.ParseFloat(doc.Metadata[lonKey], 64)
+			if err != nil || docLon < -180 || docLon > 180 {
+				return false
+			}
+			return haversineDistance(lat, lon, docLat, docLon) <= radius
+		},
+	})
+}
+
+// haversineDistance returns the great-circle distance in meters between two
+// locations in decimal degrees.
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(min(1, a)))
+}
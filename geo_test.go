@@ -0,0 +1,51 @@
+package chromem
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestHaversineDistance(t *testing.T) {
+	// Berlin to Paris is about 878 km.
+	d := haversineDistance(52.5200, 13.4050, 48.8566, 2.3522)
+	if math.Abs(d-877_500) > 2000 {
+		t.Fatal("expected about 877.5 km, got", d)
+	}
+	if d := haversineDistance(10, 20, 10, 20); d != 0 {
+		t.Fatal("expected 0, got", d)
+	}
+}
+
+func TestCollection_QueryWithOptions_WithinRadius(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		// Berlin Mitte
+		{ID: "1", Metadata: map[string]string{"lat": "52.5206", "lon": "13.4094"}, Embedding: []float32{0.6, 0.8}},
+		// Berlin Kreuzberg, about 3 km away
+		{ID: "2", Metadata: map[string]string{"lat": "52.4986", "lon": "13.4033"}, Embedding: []float32{1, 0}},
+		// Potsdam, about 27 km away
+		{ID: "3", Metadata: map[string]string{"lat": "52.3906", "lon": "13.0645"}, Embedding: []float32{1, 0}},
+		// No location
+		{ID: "4", Embedding: []float32{1, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       4,
+		Filter:         Where().WithinRadius("lat", "lon", 52.5200, 13.4050, 5000),
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[1].ID != "1" {
+		t.Fatal("expected documents 2 and 1, got", res)
+	}
+}
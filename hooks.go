@@ -0,0 +1,57 @@
+package chromem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Hooks are functions that are called when documents are added to or deleted
+// from a collection, or when it's queried, see [Collection.AddHooks]. All of them are optional.
+// They're called synchronously, so they should be fast. They may call methods of
+// the collection.
+type Hooks struct {
+	// BeforeAdd is called before a document is embedded and added, by
+	// [Collection.AddDocument] and the methods that use it. It can validate the
+	// document and reject it by returning an error, or modify it, e.g. to enrich
+	// its metadata.
+	BeforeAdd func(ctx context.Context, doc *Document) error
+
+	// AfterAdd is called after a document was added and persisted.
+	AfterAdd func(ctx context.Context, doc Document)
+
+	// AfterDelete is called after documents were deleted by [Collection.Delete],
+	// with the IDs of the documents that existed.
+	AfterDelete func(ctx context.Context, ids []string)
+
+	// AfterQuery is called after each [Collection.QueryEmbedding], which the other
+	// dense query methods use as well, with the duration of the search (without
+	// creating the query embedding) and its error, if any. It's meant for metrics.
+	AfterQuery func(ctx context.Context, duration time.Duration, err error)
+}
+
+type hooks struct {
+	list []Hooks
+	lock sync.RWMutex
+}
+
+// get returns the registered hooks. The returned slice must not be modified.
+func (h *hooks) get() []Hooks {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.list
+}
+
+// AddHooks registers hooks that are called when documents are added or deleted,
+// or the collection is queried, so that applications can enforce rules, maintain
+// derived data or record metrics without wrapping every call site. Hooks are
+// called in the order they were added.
+func (c *Collection) AddHooks(h Hooks) {
+	c.hooks.lock.Lock()
+	defer c.hooks.lock.Unlock()
+
+	// Copy on write, so that get() doesn't need to copy.
+	list := make([]Hooks, 0, len(c.hooks.list)+1)
+	list = append(list, c.hooks.list...)
+	c.hooks.list = append(list, h)
+}
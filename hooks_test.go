@@ -0,0 +1,105 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollection_Hooks(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var added, deleted []string
+	c.AddHooks(Hooks{
+		BeforeAdd: func(_ context.Context, doc *Document) error {
+			if doc.Metadata["lang"] == "" {
+				return errors.New("missing lang")
+			}
+			doc.Metadata["source"] = "hook"
+			return nil
+		},
+		AfterAdd: func(_ context.Context, doc Document) {
+			added = append(added, doc.ID)
+		},
+	})
+	c.AddHooks(Hooks{
+		AfterDelete: func(_ context.Context, ids []string) {
+			deleted = append(deleted, ids...)
+		},
+	})
+
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	metadata := map[string]string{"lang": "en"}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{1, 0}, Metadata: metadata})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["source"] != "hook" {
+		t.Fatal("expected enriched metadata, got", doc.Metadata)
+	}
+	if _, ok := metadata["source"]; ok {
+		t.Fatal("expected the caller's metadata to be unchanged, got", metadata)
+	}
+	if !slices.Equal(added, []string{"2"}) {
+		t.Fatal("expected [2], got", added)
+	}
+
+	err = c.Delete(ctx, nil, nil, "1", "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(deleted, []string{"2"}) {
+		t.Fatal("expected [2], got", deleted)
+	}
+}
+
+func TestCollection_Hooks_AfterQuery(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var calls, failed int
+	c.AddHooks(Hooks{
+		AfterQuery: func(_ context.Context, _ time.Duration, err error) {
+			calls++
+			if err != nil {
+				failed++
+			}
+		},
+	})
+
+	_, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "hello", 2, nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if calls != 2 || failed != 1 {
+		t.Fatal("expected 2 calls with 1 failure, got", calls, failed)
+	}
+}
@@ -0,0 +1,444 @@
+package chromem
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Number of documents per concurrent worker that are decoded from a stream
+// before they're added to the collection as a batch.
+const streamBatchSizePerWorker = 32
+
+// jsonlDocument is the JSON representation of a document in JSONL streams, with
+// the same field names as [Result]. The metadata can be nested, see
+// [NestedMetadata].
+type jsonlDocument struct {
+	ID        string         `json:"id"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Embedding []float32      `json:"embedding,omitempty"`
+	Content   string         `json:"content,omitempty"`
+}
+
+// AddFromJSONLStream reads documents from a JSONL stream (one JSON object per
+// line) and adds them to the collection. The field names are the ones defined
+// on [Result] ("id", "metadata", "embedding", "content"). Metadata values that
+// aren't strings, like numbers or nested objects, are stored as JSON, see
+// [NestedMetadata]. Documents without embedding are embedded with the
+// collection's embedding function.
+//
+// The documents are decoded incrementally and added in batches with
+// [Collection.AddDocuments], so large files don't have to fit into memory.
+// It returns the number of added documents. Upon error, the documents of the
+// previous batches are already added, so the number can be used to resume.
+// If the reader has to be closed, it's the caller's responsibility.
+//
+//   - r: The JSONL stream.
+//   - concurrency: The number of documents that are embedded and added
+//     concurrently. 0 uses the DB's default, see [DB.SetConcurrencyDefaults].
+func (c *Collection) AddFromJSONLStream(ctx context.Context, r io.Reader, concurrency int) (int, error) {
+	if concurrency < 0 {
+		return 0, errors.New("concurrency must be >= 0")
+	}
+
+	return c.addFromStream(ctx, concurrency, NewJSONLLoader(r))
+}
+
+// NewJSONLLoader returns a loader that decodes the documents of a JSONL stream
+// incrementally, like [Collection.AddFromJSONLStream], e.g. for a [Pipeline].
+// If the reader has to be closed, it's the caller's responsibility.
+func NewJSONLLoader(r io.Reader) DocumentLoader {
+	dec := json.NewDecoder(r)
+	// Keep numbers in metadata as they're written.
+	dec.UseNumber()
+	n := 0
+	return func() (Document, error) {
+		var jd jsonlDocument
+		err := dec.Decode(&jd)
+		if errors.Is(err, io.EOF) {
+			return Document{}, err
+		} else if err != nil {
+			return Document{}, fmt.Errorf("couldn't decode document %d: %w", n+1, err)
+		}
+		n++
+		var metadata map[string]string
+		if jd.Metadata != nil {
+			metadata, err = NestedMetadata(jd.Metadata)
+			if err != nil {
+				return Document{}, fmt.Errorf("couldn't encode metadata of document %d: %w", n, err)
+			}
+		}
+		return Document{
+			ID:        jd.ID,
+			Metadata:  metadata,
+			Embedding: jd.Embedding,
+			Content:   jd.Content,
+		}, nil
+	}
+}
+
+// addFromStream adds the documents returned by next in batches, until it
+// returns io.EOF. It returns the number of added documents.
+func (c *Collection) addFromStream(ctx context.Context, concurrency int, next func() (Document, error)) (int, error) {
+	// The automatic concurrency is shared by the batches, so that it doesn't
+	// start over with each batch.
+	concurrency, limiter, err := c.resolveConcurrency(concurrency)
+	if err != nil {
+		return 0, err
+	}
+	batchSize := streamBatchSizePerWorker * concurrency
+	if limiter != nil {
+		batchSize = streamBatchSizePerWorker * int(limiter.max)
+	}
+	batch := make([]Document, 0, batchSize)
+	added := 0
+	for {
+		doc, err := next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return added, err
+		}
+		if err == nil {
+			batch = append(batch, doc)
+		}
+		if len(batch) == batchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			addErr := c.addDocuments(ctx, batch, concurrency, limiter)
+			if addErr != nil {
+				return added, addErr
+			}
+			added += len(batch)
+			// The documents are copied by the collection, so the batch can be reused.
+			batch = batch[:0]
+		}
+		if errors.Is(err, io.EOF) {
+			return added, nil
+		}
+	}
+}
+
+// CSVHeader defines whether the first row of a CSV stream is a header row.
+type CSVHeader int
+
+const (
+	// CSVHeaderAuto detects whether the first row is a header row. It's a header
+	// row if a column is referenced by name, or if one of its values isn't a
+	// number while the value in the same column of the second row is one.
+	CSVHeaderAuto CSVHeader = iota
+	// CSVHeaderPresent treats the first row as header row.
+	CSVHeaderPresent
+	// CSVHeaderAbsent treats all rows as data rows.
+	CSVHeaderAbsent
+)
+
+// CSVOptions maps the columns of a CSV stream to the document fields for
+// [Collection.AddFromCSV]. Columns are referenced by their name in the header
+// row, or by their position as string, starting at "1".
+type CSVOptions struct {
+	// ID is the column with the document IDs. Optional. If empty, the row
+	// numbers (starting at "1", not counting the header row) are used as IDs.
+	ID string
+	// Content is the column with the document contents. Required unless
+	// Embedding is set.
+	Content string
+	// Embedding is the column with the document embeddings, as JSON arrays like
+	// "[0.1, 0.2]". Optional. Documents without embedding are embedded with the
+	// collection's embedding function.
+	Embedding string
+	// Metadata maps columns to metadata keys. Optional. If nil, all columns that
+	// aren't mapped to another field are metadata, with the column name (or
+	// position) as key.
+	Metadata map[string]string
+	// Types are type hints per metadata key. Values of keys with a type hint are
+	// validated and normalized, e.g. " 042" becomes "42" for [MetadataTypeInt]
+	// and "yes" or "1" become "true" for [MetadataTypeBool]. Optional.
+	Types map[string]MetadataType
+	// Header defines whether the first row is a header row. Defaults to
+	// [CSVHeaderAuto].
+	Header CSVHeader
+	// Comma is the field delimiter. Optional, defaults to ','.
+	Comma rune
+}
+
+// AddFromCSV reads documents from a CSV stream and adds them to the collection,
+// with the column mapping of the options. Empty metadata values are omitted, as
+// CSV can't distinguish them from missing ones.
+//
+// Like [Collection.AddFromJSONLStream], the rows are read incrementally and the
+// documents added in batches. It returns the number of added documents.
+// If the reader has to be closed, it's the caller's responsibility.
+//
+//   - r: The CSV stream.
+//   - options: The column mapping. Content or Embedding must be set.
+//   - concurrency: The number of documents that are embedded and added
+//     concurrently. 0 uses the DB's default, see [DB.SetConcurrencyDefaults].
+func (c *Collection) AddFromCSV(ctx context.Context, r io.Reader, options CSVOptions, concurrency int) (int, error) {
+	if options.Content == "" && options.Embedding == "" {
+		return 0, errors.New("either the content or the embedding column must be set")
+	}
+	for key, typ := range options.Types {
+		switch typ {
+		case "", MetadataTypeString, MetadataTypeInt, MetadataTypeFloat, MetadataTypeBool:
+		default:
+			return 0, fmt.Errorf("unsupported type '%s' for metadata key '%s'", typ, key)
+		}
+	}
+	if concurrency < 0 {
+		return 0, errors.New("concurrency must be >= 0")
+	}
+
+	loader, err := NewCSVLoader(r, options)
+	if err != nil {
+		return 0, err
+	}
+	return c.addFromStream(ctx, concurrency, loader)
+}
+
+// NewCSVLoader returns a loader that reads the documents of a CSV stream
+// incrementally, with the column mapping of the options, like
+// [Collection.AddFromCSV], e.g. for a [Pipeline]. The first rows are read to
+// detect the header row. If the reader has to be closed, it's the caller's
+// responsibility.
+func NewCSVLoader(r io.Reader, options CSVOptions) (DocumentLoader, error) {
+	cr := csv.NewReader(r)
+	if options.Comma != 0 {
+		cr.Comma = options.Comma
+	}
+	// Rows can have different numbers of fields, missing ones are empty.
+	cr.FieldsPerRecord = -1
+
+	// Read the first two rows to detect the header row.
+	var rows [][]string
+	for len(rows) < 2 {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't read row %d: %w", len(rows)+1, err)
+		}
+		rows = append(rows, record)
+	}
+	if len(rows) == 0 {
+		return func() (Document, error) { return Document{}, io.EOF }, nil
+	}
+	var header []string
+	if isCSVHeader(options, rows) {
+		header = rows[0]
+		rows = rows[1:]
+	}
+	m, err := newCSVMapper(options, header)
+	if err != nil {
+		return nil, err
+	}
+
+	row := 0
+	return func() (Document, error) {
+		var record []string
+		if len(rows) > 0 {
+			record, rows = rows[0], rows[1:]
+		} else {
+			var err error
+			record, err = cr.Read()
+			if errors.Is(err, io.EOF) {
+				return Document{}, err
+			} else if err != nil {
+				return Document{}, fmt.Errorf("couldn't read row %d: %w", row+1, err)
+			}
+		}
+		row++
+		doc, err := m.document(record, row)
+		if err != nil {
+			return Document{}, fmt.Errorf("couldn't map row %d: %w", row, err)
+		}
+		return doc, nil
+	}, nil
+}
+
+// isCSVHeader returns whether the first of the given rows is a header row.
+func isCSVHeader(options CSVOptions, rows [][]string) bool {
+	switch options.Header {
+	case CSVHeaderPresent:
+		return true
+	case CSVHeaderAbsent:
+		return false
+	}
+
+	// Columns that are referenced by name require a header row.
+	refs := []string{options.ID, options.Content, options.Embedding}
+	for col := range options.Metadata {
+		refs = append(refs, col)
+	}
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(ref); err != nil {
+			return true
+		}
+	}
+
+	// Header rows usually don't contain numbers, unlike data rows.
+	if len(rows) < 2 {
+		return false
+	}
+	for _, v := range rows[0] {
+		if isNumber(v) {
+			return false
+		}
+	}
+	for i, v := range rows[0] {
+		if i < len(rows[1]) && isNumber(rows[1][i]) && !isNumber(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
+
+// csvMapper maps CSV records to documents.
+type csvMapper struct {
+	// Column indexes of the fields, -1 if they're not mapped.
+	id, content, embedding int
+	// Metadata keys per column index, nil if all other columns are metadata.
+	metadata map[int]string
+	header   []string
+	types    map[string]MetadataType
+}
+
+func newCSVMapper(options CSVOptions, header []string) (*csvMapper, error) {
+	m := &csvMapper{header: header, types: options.Types}
+	var err error
+	if m.id, err = m.column(options.ID); err != nil {
+		return nil, err
+	}
+	if m.content, err = m.column(options.Content); err != nil {
+		return nil, err
+	}
+	if m.embedding, err = m.column(options.Embedding); err != nil {
+		return nil, err
+	}
+	if options.Metadata != nil {
+		m.metadata = make(map[int]string, len(options.Metadata))
+		for col, key := range options.Metadata {
+			i, err := m.column(col)
+			if err != nil {
+				return nil, err
+			}
+			m.metadata[i] = key
+		}
+	}
+	return m, nil
+}
+
+// column returns the index of the referenced column, or -1 for an empty
+// reference.
+func (m *csvMapper) column(ref string) (int, error) {
+	if ref == "" {
+		return -1, nil
+	}
+	if i := slices.Index(m.header, ref); i >= 0 {
+		return i, nil
+	}
+	pos, err := strconv.Atoi(ref)
+	if err != nil || pos < 1 || (m.header != nil && pos > len(m.header)) {
+		return 0, fmt.Errorf("column '%s' not found", ref)
+	}
+	return pos - 1, nil
+}
+
+// document maps the record to a document. row is the number of the data row,
+// starting at 1.
+func (m *csvMapper) document(record []string, row int) (Document, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	doc := Document{
+		ID:      field(m.id),
+		Content: field(m.content),
+	}
+	if m.id < 0 {
+		doc.ID = strconv.Itoa(row)
+	}
+	if embedding := field(m.embedding); embedding != "" {
+		err := json.Unmarshal([]byte(embedding), &doc.Embedding)
+		if err != nil {
+			return Document{}, fmt.Errorf("couldn't parse embedding: %w", err)
+		}
+	}
+
+	addMetadata := func(key, v string) error {
+		if v == "" {
+			return nil
+		}
+		v, err := normalizeMetadataValue(v, m.types[key])
+		if err != nil {
+			return fmt.Errorf("couldn't parse value of metadata key '%s': %w", key, err)
+		}
+		if doc.Metadata == nil {
+			doc.Metadata = make(map[string]string)
+		}
+		doc.Metadata[key] = v
+		return nil
+	}
+	if m.metadata != nil {
+		for i, key := range m.metadata {
+			if err := addMetadata(key, field(i)); err != nil {
+				return Document{}, err
+			}
+		}
+		return doc, nil
+	}
+	for i, v := range record {
+		if i == m.id || i == m.content || i == m.embedding {
+			continue
+		}
+		key := strconv.Itoa(i + 1)
+		if i < len(m.header) {
+			key = m.header[i]
+		}
+		if err := addMetadata(key, v); err != nil {
+			return Document{}, err
+		}
+	}
+	return doc, nil
+}
+
+// normalizeMetadataValue validates the value for the type hint and returns it
+// in the format of [MetadataSchema] types.
+func normalizeMetadataValue(v string, typ MetadataType) (string, error) {
+	switch typ {
+	case MetadataTypeInt:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(i, 10), nil
+	case MetadataTypeFloat:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case MetadataTypeBool:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "t", "yes", "y", "1":
+			return "true", nil
+		case "false", "f", "no", "n", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("invalid bool '%s'", v)
+	}
+	return v, nil
+}
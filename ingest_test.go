@@ -0,0 +1,155 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCollection_AddFromJSONLStream(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// More documents than fit into one batch
+	var sb strings.Builder
+	for i := 0; i < 70; i++ {
+		fmt.Fprintf(&sb, `{"id":"%d","metadata":{"n":"%d"},"embedding":[1,%d],"content":"doc %d"}`+"\n", i, i, i, i)
+	}
+	added, err := c.AddFromJSONLStream(ctx, strings.NewReader(sb.String()), 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if added != 70 || c.Count() != 70 {
+		t.Fatal("expected 70 documents, got", added, c.Count())
+	}
+	doc, err := c.GetByID(ctx, "42")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "doc 42" || doc.Metadata["n"] != "42" {
+		t.Fatal("expected document 42, got", doc)
+	}
+
+	// Decoding errors are returned before the pending batch is added
+	stream := `{"id":"a","embedding":[1,0]}` + "\n" + `{"id":` + "\n"
+	added, err = c.AddFromJSONLStream(ctx, strings.NewReader(stream), 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if added != 0 {
+		t.Fatal("expected 0 added documents, got", added)
+	}
+
+	// Invalid concurrency
+	_, err = c.AddFromJSONLStream(ctx, strings.NewReader(sb.String()), -1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_AddFromCSV(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Columns by name, with type hints
+	csvData := "sku,description,vector,price,in_stock,color\n" +
+		`a1,red shirt,"[1,0]", 19.90 ,yes,red` + "\n" +
+		`a2,blue shirt,"[0,1]",25,no,` + "\n"
+	options := CSVOptions{
+		ID:        "sku",
+		Content:   "description",
+		Embedding: "vector",
+		Metadata:  map[string]string{"price": "price", "in_stock": "available"},
+		Types:     map[string]MetadataType{"price": MetadataTypeFloat, "available": MetadataTypeBool},
+	}
+	added, err := c.AddFromCSV(ctx, strings.NewReader(csvData), options, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if added != 2 {
+		t.Fatal("expected 2 added documents, got", added)
+	}
+	doc, err := c.GetByID(ctx, "a1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "red shirt" || len(doc.Metadata) != 2 || doc.Metadata["price"] != "19.9" || doc.Metadata["available"] != "true" {
+		t.Fatal("expected mapped document, got", doc)
+	}
+
+	// Without header, columns by position, all other columns are metadata and
+	// IDs are generated
+	csvData = "1,hello,x\n2,world,\n"
+	options = CSVOptions{Content: "2"}
+	c2, err := db.CreateCollection("test2", nil, func(_ context.Context, text string) ([]float32, error) {
+		return []float32{1, float32(len(text))}, nil
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	added, err = c2.AddFromCSV(ctx, strings.NewReader(csvData), options, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if added != 2 {
+		t.Fatal("expected 2 added documents, got", added)
+	}
+	doc, err = c2.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "hello" || doc.Metadata["1"] != "1" || doc.Metadata["3"] != "x" {
+		t.Fatal("expected mapped document, got", doc)
+	}
+	doc, err = c2.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := doc.Metadata["3"]; ok {
+		t.Fatal("expected empty value to be omitted, got", doc.Metadata)
+	}
+
+	// Invalid values for type hints and unknown columns
+	options = CSVOptions{Content: "description", Types: map[string]MetadataType{"price": MetadataTypeInt}}
+	_, err = c.AddFromCSV(ctx, strings.NewReader("description,price\nfoo,bar\n"), options, 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = c.AddFromCSV(ctx, strings.NewReader("text\nfoo\n"), CSVOptions{Content: "description"}, 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestIsCSVHeader(t *testing.T) {
+	tt := []struct {
+		name    string
+		options CSVOptions
+		rows    [][]string
+		want    bool
+	}{
+		{"by name", CSVOptions{Content: "text"}, [][]string{{"a", "b"}, {"c", "d"}}, true},
+		{"numbers", CSVOptions{Content: "2"}, [][]string{{"id", "text"}, {"1", "foo"}}, true},
+		{"no numbers", CSVOptions{Content: "2"}, [][]string{{"a", "b"}, {"c", "d"}}, false},
+		{"data", CSVOptions{Content: "2"}, [][]string{{"1", "foo"}, {"2", "bar"}}, false},
+		{"single row", CSVOptions{Content: "2"}, [][]string{{"id", "text"}}, false},
+		{"present", CSVOptions{Content: "2", Header: CSVHeaderPresent}, [][]string{{"a", "b"}}, true},
+		{"absent", CSVOptions{Content: "text", Header: CSVHeaderAbsent}, [][]string{{"a", "b"}}, false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isCSVHeader(tc.options, tc.rows); got != tc.want {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+		})
+	}
+}
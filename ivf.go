@@ -0,0 +1,406 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+const (
+	// Maximum number of k-means iterations when building an IVF index.
+	ivfMaxIterations = 10
+	// Maximum number of training documents per list. More don't noticeably
+	// improve the centroids, but make building the index slower.
+	ivfTrainingDocsPerList = 64
+	// By default, 1/ivfDefaultProbesDivisor of the lists are probed per query.
+	ivfDefaultProbesDivisor = 8
+)
+
+// ivfIndex is an inverted file index: the documents are clustered with
+// k-means, and a query only scans the documents of the clusters whose centroids
+// are closest to it.
+//
+// All methods must be called while holding the collection's documents write
+// lock, except docs, which only needs the read lock.
+type ivfIndex struct {
+	// Normalized centroids
+	centroids [][]float32
+	// Document IDs per centroid. The last list contains the documents that
+	// can't be assigned to a centroid, because their embeddings have different
+	// dimensions. It's always probed.
+	lists []map[string]struct{}
+	// List index per document ID
+	assignments map[string]int
+	numProbes   int
+	// Number of documents that were added, changed or deleted since the index
+	// was built.
+	changes int
+	// Number of changes after which the index is stale and not used by queries
+	// anymore. 0 means never.
+	maxChanges int
+}
+
+// BuildIVFIndex builds an inverted file (IVF) index for approximate nearest
+// neighbor search, which makes queries on large collections much faster.
+// The documents are clustered with k-means into numLists clusters, and
+// a query only scans the documents of the numProbes clusters whose centroids
+// are closest to the query embedding. The similarities in the results are
+// exact, but some of the nearest neighbors might be missed when they're in
+// a cluster that wasn't probed.
+//
+// Documents that are added or deleted afterwards are added to and removed from
+// the clusters, but the centroids stay the same. If a collection changes a lot,
+// build the index again, or let [Collection.MaintainIVFIndex] do it. Most of the work is done without blocking reads or
+// writes, so the index can be rebuilt while the collection is in use.
+// The index isn't persisted and is dropped when the embeddings change, e.g.
+// with [Collection.Reembed] or [Collection.FitProjection].
+//
+// When the filters of a query leave fewer than nResults documents in the probed
+// clusters, the query falls back to an exhaustive search. Selective filters
+// are applied before the search instead. See [FilterStrategy] to choose how
+// filters are combined with the index.
+//
+//   - numLists: Number of clusters. If it's 0 or less, the square root of the
+//     number of documents is used, which is a good default.
+//   - numProbes: Number of clusters to probe per query. Higher values improve
+//     the recall but make queries slower. If it's 0 or less, an eighth of
+//     numLists is used. See [Collection.SetIVFProbes] to change it later.
+func (c *Collection) BuildIVFIndex(ctx context.Context, numLists, numProbes int) error {
+	return c.buildIVFIndex(ctx, numLists, numProbes, 0)
+}
+
+// buildIVFIndex builds the IVF index and records the build's status. The index
+// becomes stale after rebuildRatio*documents changes, if rebuildRatio > 0.
+func (c *Collection) buildIVFIndex(ctx context.Context, numLists, numProbes int, rebuildRatio float64) (err error) {
+	// Only one build at a time
+	c.ivfBuild.buildLock.Lock()
+	defer c.ivfBuild.buildLock.Unlock()
+	c.ivfBuild.start()
+	defer func() {
+		c.ivfBuild.finish(err)
+	}()
+
+	// Take a snapshot of the documents. They're never modified, only replaced,
+	// so they can be used without holding the lock.
+	c.documentsLock.RLock()
+	if c.closed {
+		c.documentsLock.RUnlock()
+		return ErrClosed
+	}
+	snapshot := c.column.docs(c.documents)
+	dims := c.column.dims
+	c.documentsLock.RUnlock()
+
+	docs := make([]*Document, 0, len(snapshot))
+	for _, doc := range snapshot {
+		if len(doc.Embedding) == dims {
+			docs = append(docs, doc)
+		}
+	}
+	if numLists <= 0 {
+		numLists = max(1, int(math.Sqrt(float64(len(docs)))))
+	}
+	if numLists > len(docs) {
+		return fmt.Errorf("numLists must be <= the number of documents with embeddings (%d)", len(docs))
+	}
+	if numProbes <= 0 {
+		numProbes = max(1, numLists/ivfDefaultProbesDivisor)
+	}
+	numProbes = min(numProbes, numLists)
+
+	// The training iterations plus the assignment of all documents
+	totalSteps := ivfMaxIterations + 1
+	centroids, err := trainCentroids(ctx, docs, numLists, func(step int) {
+		c.ivfBuild.setProgress(float64(step) / float64(totalSteps))
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't train centroids: %w", err)
+	}
+	assignments := make([]int, len(docs))
+	err = parallelFor(ctx, len(docs), func(i int) {
+		assignments[i] = nearestCentroid(centroids, docs[i].Embedding)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't assign documents: %w", err)
+	}
+	c.ivfBuild.setProgress(1)
+
+	idx := &ivfIndex{
+		centroids:   centroids,
+		lists:       make([]map[string]struct{}, numLists+1),
+		assignments: make(map[string]int, len(docs)),
+		numProbes:   numProbes,
+	}
+	for i := range idx.lists {
+		idx.lists[i] = make(map[string]struct{})
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	// Documents that are unchanged since the snapshot keep their assignment,
+	// others are assigned now.
+	for i, doc := range docs {
+		if c.documents[doc.ID] == doc {
+			idx.assign(doc.ID, assignments[i])
+		}
+	}
+	for id, doc := range c.documents {
+		if _, ok := idx.assignments[id]; !ok {
+			idx.add(doc)
+		}
+	}
+	idx.changes = 0
+	if rebuildRatio > 0 {
+		idx.maxChanges = max(1, int(rebuildRatio*float64(len(c.documents))))
+	}
+	c.ivf = idx
+	// The results might differ in recall.
+	c.invalidateQueryCache()
+
+	return nil
+}
+
+// SetIVFProbes sets the number of clusters to probe per query for the IVF
+// index, see [Collection.BuildIVFIndex]. It returns an error if there's no
+// index.
+func (c *Collection) SetIVFProbes(numProbes int) error {
+	if numProbes <= 0 {
+		return errors.New("numProbes must be > 0")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	if c.ivf == nil {
+		return errors.New("collection has no IVF index")
+	}
+	c.ivf.numProbes = min(numProbes, len(c.ivf.centroids))
+	c.invalidateQueryCache()
+	return nil
+}
+
+// DropIVFIndex removes the IVF index, if there is one, so that queries are
+// exhaustive again.
+func (c *Collection) DropIVFIndex() {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	if c.ivf != nil {
+		c.ivf = nil
+		c.invalidateQueryCache()
+	}
+}
+
+// add assigns the document to its nearest centroid, replacing a previous
+// assignment. It's a no-op on a nil index.
+func (idx *ivfIndex) add(doc *Document) {
+	if idx == nil {
+		return
+	}
+	list := len(idx.centroids)
+	if len(doc.Embedding) == len(idx.centroids[0]) {
+		list = nearestCentroid(idx.centroids, doc.Embedding)
+	}
+	idx.assign(doc.ID, list)
+	idx.changes++
+}
+
+func (idx *ivfIndex) assign(id string, list int) {
+	if previous, ok := idx.assignments[id]; ok {
+		delete(idx.lists[previous], id)
+	}
+	idx.lists[list][id] = struct{}{}
+	idx.assignments[id] = list
+}
+
+// remove removes the document from its cluster. It's a no-op on a nil index.
+func (idx *ivfIndex) remove(id string) {
+	if idx == nil {
+		return
+	}
+	list, ok := idx.assignments[id]
+	if !ok {
+		return
+	}
+	delete(idx.lists[list], id)
+	delete(idx.assignments, id)
+	idx.changes++
+}
+
+// usable returns whether queries can use the index, i.e. whether it's not nil
+// and not stale.
+func (idx *ivfIndex) usable() bool {
+	return idx != nil && (idx.maxChanges == 0 || idx.changes < idx.maxChanges)
+}
+
+// docs returns the documents in the clusters whose centroids are closest to the
+// normalized query embedding.
+func (idx *ivfIndex) docs(documents map[string]*Document, queryEmbedding []float32) []*Document {
+	// Queries with different dimensions than the centroids can only match the
+	// unassigned documents, in the last list.
+	var order []int
+	if len(queryEmbedding) == len(idx.centroids[0]) {
+		sims := make([]float32, len(idx.centroids))
+		order = make([]int, len(idx.centroids))
+		for i, centroid := range idx.centroids {
+			sims[i], _ = dotProduct(queryEmbedding, centroid)
+			order[i] = i
+		}
+		slices.SortFunc(order, func(a, b int) int {
+			return cmp.Compare(sims[b], sims[a])
+		})
+	}
+	lists := append(order[:min(idx.numProbes, len(order))], len(idx.centroids))
+
+	n := 0
+	for _, list := range lists {
+		n += len(idx.lists[list])
+	}
+	docs := make([]*Document, 0, n)
+	for _, list := range lists {
+		for id := range idx.lists[list] {
+			docs = append(docs, documents[id])
+		}
+	}
+	return docs
+}
+
+// trainCentroids runs spherical k-means (with cosine similarity) on a sample of
+// the documents and returns k normalized centroids. progress is called with the
+// number of finished iterations.
+func trainCentroids(ctx context.Context, docs []*Document, k int, progress func(step int)) ([][]float32, error) {
+	// Seed to make deterministic
+	r := rand.New(rand.NewSource(int64(len(docs))))
+
+	// Sample the training documents. The first k are the initial centroids.
+	perm := r.Perm(len(docs))
+	sample := make([][]float32, min(len(docs), k*ivfTrainingDocsPerList))
+	for i := range sample {
+		sample[i] = docs[perm[i]].Embedding
+	}
+	return kMeans(ctx, sample, k, ivfMaxIterations, r, progress)
+}
+
+// kMeans runs spherical k-means (with cosine similarity) on the vectors, which
+// must have the same dimensions, and returns k normalized centroids. The first
+// k vectors are the initial centroids, so they should be in random order. r is
+// used to reseed empty clusters. progress is called with the number of finished
+// iterations, with maxIterations when the clusters converged.
+func kMeans(ctx context.Context, vectors [][]float32, k, maxIterations int, r *rand.Rand, progress func(step int)) ([][]float32, error) {
+	dims := len(vectors[0])
+	centroids := make([][]float32, k)
+	for i := range centroids {
+		centroids[i] = slices.Clone(vectors[i])
+	}
+
+	assignments := make([]int, len(vectors))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	for iter := 0; iter < maxIterations; iter++ {
+		var changed bool
+		var changedLock sync.Mutex
+		err := parallelFor(ctx, len(vectors), func(i int) {
+			nearest := nearestCentroid(centroids, vectors[i])
+			if nearest != assignments[i] {
+				assignments[i] = nearest
+				changedLock.Lock()
+				changed = true
+				changedLock.Unlock()
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			progress(maxIterations)
+			break
+		}
+		progress(iter + 1)
+
+		// Move the centroids to the mean of their vectors. As we're using
+		// cosine similarity, the direction of the sum is enough.
+		sums := make([][]float32, k)
+		for i := range sums {
+			sums[i] = make([]float32, dims)
+		}
+		counts := make([]int, k)
+		for i, v := range vectors {
+			sum := sums[assignments[i]]
+			for j := range sum {
+				sum[j] += v[j]
+			}
+			counts[assignments[i]]++
+		}
+		for i := range centroids {
+			if counts[i] == 0 {
+				// Reseed empty clusters with a random vector.
+				centroids[i] = slices.Clone(vectors[r.Intn(len(vectors))])
+				continue
+			}
+			centroids[i] = normalizeVector(sums[i])
+		}
+	}
+
+	return centroids, nil
+}
+
+// nearestCentroid returns the index of the centroid with the highest similarity
+// to the normalized vector.
+func nearestCentroid(centroids [][]float32, v []float32) int {
+	ea := newEarlyAbandon(v)
+	nearest := 0
+	best := float32(math.Inf(-1))
+	for i, centroid := range centroids {
+		sim, _ := ea.dotProduct(centroid, best)
+		if sim > best {
+			nearest, best = i, sim
+		}
+	}
+	return nearest
+}
+
+// parallelFor calls fn for all indexes from 0 to n-1, split across as many
+// goroutines as there are CPUs. It stops early if the context is canceled.
+func parallelFor(ctx context.Context, n int, fn func(i int)) error {
+	concurrency := min(runtime.NumCPU(), n)
+	if concurrency == 0 {
+		return nil
+	}
+
+	wg := sync.WaitGroup{}
+	subSliceSize := n / concurrency // Can leave remainder, e.g. 10/3 = 3; leaves 1
+	rem := n % concurrency
+	for i := 0; i < concurrency; i++ {
+		start := i * subSliceSize
+		end := start + subSliceSize
+		// Add remainder to last goroutine
+		if i == concurrency-1 {
+			end += rem
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for j := start; j < end; j++ {
+				if j%256 == 0 && ctx.Err() != nil {
+					return
+				}
+				fn(j)
+			}
+		}(start, end)
+	}
+
+	wg.Wait()
+
+	return ctx.Err()
+}
@@ -0,0 +1,180 @@
+package chromem
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIVFMaintenanceInterval     = time.Minute
+	defaultIVFMaintenanceMinDocuments = 10_000
+	defaultIVFMaintenanceRebuildRatio = 0.2
+)
+
+// IVFMaintenanceOptions are the options for [Collection.MaintainIVFIndex].
+type IVFMaintenanceOptions struct {
+	// How often to check whether the index must be built. Defaults to 1 minute.
+	Interval time.Duration
+
+	// Minimum number of documents for building an index. Exhaustive search on
+	// fewer documents is fast enough. Defaults to 10,000.
+	MinDocuments int
+
+	// Fraction of the indexed documents that can be added, changed or deleted
+	// before the index is rebuilt. Until the rebuild is done, queries use
+	// exhaustive search, because the clusters don't represent the documents
+	// well anymore. Defaults to 0.2.
+	RebuildRatio float64
+
+	// Number of clusters and clusters to probe per query, see
+	// [Collection.BuildIVFIndex]. Optional.
+	NumLists  int
+	NumProbes int
+}
+
+// IndexStatus is the status of a collection's IVF index, see
+// [Collection.IndexStatus].
+type IndexStatus struct {
+	// Whether there's an index that queries use. It's false before the first
+	// build and while the index is stale, see [IVFMaintenanceOptions.RebuildRatio].
+	// Queries use exhaustive search then.
+	Ready bool
+
+	// Number of documents in the index.
+	Documents int
+
+	// Number of documents that were added, changed or deleted since the last
+	// build.
+	Changes int
+
+	// Whether a build is running, and its progress from 0 to 1.
+	Building bool
+	Progress float64
+
+	// End and duration of the last build, and its error, if it failed.
+	LastBuild         time.Time
+	LastBuildDuration time.Duration
+	LastError         error
+}
+
+// ivfBuildState tracks the builds of a collection's IVF index.
+type ivfBuildState struct {
+	// Held during a build, so that there's only one at a time.
+	buildLock sync.Mutex
+
+	lock         sync.Mutex
+	building     bool
+	progress     float64
+	started      time.Time
+	lastBuild    time.Time
+	lastDuration time.Duration
+	lastError    error
+}
+
+func (s *ivfBuildState) start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.building = true
+	s.progress = 0
+	s.started = time.Now()
+}
+
+func (s *ivfBuildState) setProgress(progress float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.progress = progress
+}
+
+func (s *ivfBuildState) finish(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.building = false
+	s.lastBuild = time.Now()
+	s.lastDuration = s.lastBuild.Sub(s.started)
+	s.lastError = err
+}
+
+// IndexStatus returns the status of the collection's IVF index and its builds.
+func (c *Collection) IndexStatus() IndexStatus {
+	c.documentsLock.RLock()
+	status := IndexStatus{
+		Ready: c.ivf.usable(),
+	}
+	if c.ivf != nil {
+		status.Documents = len(c.ivf.assignments)
+		status.Changes = c.ivf.changes
+	}
+	c.documentsLock.RUnlock()
+
+	c.ivfBuild.lock.Lock()
+	defer c.ivfBuild.lock.Unlock()
+	status.Building = c.ivfBuild.building
+	status.Progress = c.ivfBuild.progress
+	status.LastBuild = c.ivfBuild.lastBuild
+	status.LastBuildDuration = c.ivfBuild.lastDuration
+	status.LastError = c.ivfBuild.lastError
+	return status
+}
+
+// MaintainIVFIndex builds the collection's IVF index in the background once it
+// has enough documents, and rebuilds it after many changes, e.g. bulk adds.
+// Queries use exhaustive search while there's no up-to-date index, so they're
+// never blocked by a build. See [Collection.BuildIVFIndex] for details about the
+// index, and [Collection.IndexStatus] for the progress of builds.
+//
+// It checks every interval until the context is done or the collection is
+// closed, and then returns the context's error or [ErrClosed]. Errors of single
+// builds are recorded in the status, and the next check retries.
+func (c *Collection) MaintainIVFIndex(ctx context.Context, options IVFMaintenanceOptions) error {
+	if options.Interval <= 0 {
+		options.Interval = defaultIVFMaintenanceInterval
+	}
+	if options.MinDocuments <= 0 {
+		options.MinDocuments = defaultIVFMaintenanceMinDocuments
+	}
+	if options.RebuildRatio <= 0 {
+		options.RebuildRatio = defaultIVFMaintenanceRebuildRatio
+	}
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+	for {
+		needsBuild, err := c.ivfNeedsBuild(options.MinDocuments, options.RebuildRatio)
+		if err != nil {
+			return err
+		}
+		if needsBuild {
+			_ = c.buildIVFIndex(ctx, options.NumLists, options.NumProbes, options.RebuildRatio)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ivfNeedsBuild returns whether the collection has enough documents, but no
+// index, or one with too many changes since it was built.
+func (c *Collection) ivfNeedsBuild(minDocuments int, rebuildRatio float64) (bool, error) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.closed {
+		return false, ErrClosed
+	}
+	if len(c.documents) < minDocuments {
+		return false, nil
+	}
+	if c.ivf == nil {
+		return true, nil
+	}
+	maxChanges := c.ivf.maxChanges
+	if maxChanges == 0 {
+		// Built with [Collection.BuildIVFIndex]
+		maxChanges = max(1, int(rebuildRatio*float64(len(c.ivf.assignments))))
+	}
+	return c.ivf.changes >= maxChanges, nil
+}
@@ -0,0 +1,115 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCollection_MaintainIVFIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := rand.New(rand.NewSource(42))
+	randomVector := func() []float32 {
+		v := make([]float32, 8)
+		for i := range v {
+			v[i] = r.Float32() - 0.5
+		}
+		return v
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	addDocs := func(from, to int) {
+		for i := from; i < to; i++ {
+			err := c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector()})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+	}
+	waitFor := func(cond func(s IndexStatus) bool) IndexStatus {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			s := c.IndexStatus()
+			if cond(s) {
+				return s
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out, status: %+v", s)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	addDocs(0, 50)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.MaintainIVFIndex(ctx, IVFMaintenanceOptions{
+			Interval:     10 * time.Millisecond,
+			MinDocuments: 100,
+			RebuildRatio: 0.5,
+		})
+	}()
+
+	// Not enough documents yet
+	time.Sleep(50 * time.Millisecond)
+	s := c.IndexStatus()
+	if s.Ready || !s.LastBuild.IsZero() {
+		t.Fatalf("expected no build, got %+v", s)
+	}
+
+	addDocs(50, 100)
+	s = waitFor(func(s IndexStatus) bool { return s.Ready })
+	if s.Documents != 100 || s.Changes != 0 || s.Progress != 1 || s.LastError != nil {
+		t.Fatalf("expected a successful build of 100 documents, got %+v", s)
+	}
+	firstBuild := s.LastBuild
+
+	// Bulk adds make the index stale, so it's rebuilt.
+	addDocs(100, 150)
+	s = waitFor(func(s IndexStatus) bool { return s.LastBuild.After(firstBuild) && s.Ready })
+	if s.Documents != 150 || s.Changes != 0 {
+		t.Fatalf("expected a rebuild with 150 documents, got %+v", s)
+	}
+
+	// Queries work during builds, with exact search while the index is stale.
+	_, err = c.QueryEmbedding(ctx, randomVector(), 5, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	cancel()
+	err = <-errCh
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+}
+
+func TestIVFIndex_Stale(t *testing.T) {
+	idx := &ivfIndex{
+		centroids:   [][]float32{{1, 0}},
+		lists:       []map[string]struct{}{{}, {}},
+		assignments: map[string]int{},
+		maxChanges:  2,
+	}
+	if !idx.usable() {
+		t.Fatal("expected new index to be usable")
+	}
+	idx.add(&Document{ID: "1", Embedding: []float32{1, 0}})
+	idx.remove("1")
+	if idx.usable() {
+		t.Fatal("expected index to be stale after 2 changes")
+	}
+	var nilIdx *ivfIndex
+	if nilIdx.usable() {
+		t.Fatal("expected nil index to be unusable")
+	}
+}
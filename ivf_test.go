@@ -0,0 +1,166 @@
+package chromem
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_BuildIVFIndex(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+
+	// Documents in 10 clusters around random centers
+	d := 32
+	centers := make([][]float32, 10)
+	for i := range centers {
+		centers[i] = make([]float32, d)
+		for j := range centers[i] {
+			centers[i][j] = r.Float32() - 0.5
+		}
+	}
+	nearCenter := func(i int) []float32 {
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = centers[i][j] + (r.Float32()-0.5)*0.1
+		}
+		return v
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 500; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: nearCenter(i % 10)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	err = c.SetIVFProbes(1)
+	if err == nil {
+		t.Fatal("expected error without index, got nil")
+	}
+	err = c.BuildIVFIndex(ctx, 501, 0)
+	if err == nil {
+		t.Fatal("expected error for more lists than documents, got nil")
+	}
+
+	query := nearCenter(3)
+	want, err := c.QueryEmbedding(ctx, query, 10, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.BuildIVFIndex(ctx, 10, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(c.ivf.centroids) != 10 || c.ivf.numProbes != 2 {
+		t.Fatal("expected 10 lists and 2 probes, got", len(c.ivf.centroids), c.ivf.numProbes)
+	}
+	if len(c.ivf.assignments) != 500 {
+		t.Fatal("expected 500 assigned documents, got", len(c.ivf.assignments))
+	}
+
+	// The clusters are well separated, so the results must be the same.
+	got, err := c.QueryEmbedding(ctx, query, 10, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Similarity != want[i].Similarity {
+			t.Fatal("expected", want[i].ID, want[i].Similarity, "got", got[i].ID, got[i].Similarity)
+		}
+	}
+
+	// New documents are assigned, deleted ones removed
+	err = c.AddDocument(ctx, Document{ID: "new", Embedding: query})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	got, err = c.QueryEmbedding(ctx, query, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if got[0].ID != "new" {
+		t.Fatal("expected new document, got", got[0].ID)
+	}
+	err = c.Delete(ctx, nil, nil, "new")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := c.ivf.assignments["new"]; ok {
+		t.Fatal("expected deleted document to be removed from the index")
+	}
+
+	// Filters that leave too few documents in the probed clusters fall back
+	// to an exhaustive search.
+	err = c.AddDocument(ctx, Document{ID: "far", Metadata: map[string]string{"k": "v"}, Embedding: nearCenter(7)})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	got, err = c.QueryEmbedding(ctx, query, 1, map[string]string{"k": "v"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(got) != 1 || got[0].ID != "far" {
+		t.Fatal("expected far document, got", got)
+	}
+
+	c.DropIVFIndex()
+	if c.ivf != nil {
+		t.Fatal("expected index to be dropped")
+	}
+}
+
+func BenchmarkCollection_Query_IVF_25000(b *testing.B) {
+	ctx := context.Background()
+
+	// Seed to make deterministic
+	r := rand.New(rand.NewSource(42))
+
+	// Unlike uniformly random vectors, real embeddings are clustered by topic.
+	d := 1536 // dimensions, same as text-embedding-3-small
+	topics := make([][]float32, 200)
+	for i := range topics {
+		topics[i] = make([]float32, d)
+		for j := range topics[i] {
+			topics[i][j] = r.Float32() - 0.5
+		}
+	}
+	randomVector := func() []float32 {
+		topic := topics[r.Intn(len(topics))]
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = topic[j] + (r.Float32()-0.5)*0.5
+		}
+		return normalizeVector(v)
+	}
+
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 25000; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector()})
+		if err != nil {
+			b.Fatal("expected no error, got", err)
+		}
+	}
+	err = c.BuildIVFIndex(ctx, 0, 0)
+	if err != nil {
+		b.Fatal("expected no error, got", err)
+	}
+	qv := randomVector()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err = c.QueryEmbedding(ctx, qv, 10, nil, nil)
+		if err != nil {
+			b.Fatal("expected nil, got", err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DetectLanguage detects the language of a text and returns its ISO 639-1
+// code, or an empty string if it's unknown. It's a lightweight heuristic
+// without language models: Texts in non-Latin scripts are detected by their
+// script ("zh", "ja", "ko", "ru", "el", "ar", "he", "hi" and "th"), and texts
+// in Latin script by their most frequent stopwords ("en", "de", "fr" and "es").
+// Cyrillic is always detected as "ru". Short texts like queries with only a few
+// words might not be detected.
+func DetectLanguage(text string) string {
+	scripts := map[string]int{}
+	latin := 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			scripts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	// Japanese uses Han characters as well, so any kana decide for Japanese.
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > latin {
+		return "ja"
+	}
+	best, bestCount := "", latin
+	for lang, n := range scripts {
+		if n > bestCount || (n == bestCount && n > 0 && lang < best) {
+			best, bestCount = lang, n
+		}
+	}
+	if best != "" {
+		return best
+	}
+	if latin == 0 {
+		return ""
+	}
+
+	// Count the stopwords per language.
+	counts := map[string]int{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for lang, set := range stopwordSets {
+			if _, ok := set[word]; ok {
+				counts[lang]++
+			}
+		}
+	}
+	best, bestCount = "", 0
+	for lang, n := range counts {
+		if n > bestCount || (n == bestCount && lang < best) {
+			best, bestCount = lang, n
+		}
+	}
+	return best
+}
+
+// stopwordSets are the stopwords per language, for the language detection.
+var stopwordSets = func() map[string]map[string]struct{} {
+	res := make(map[string]map[string]struct{}, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		res[lang] = set
+	}
+	return res
+}()
+
+// LanguageMetadataHooks returns hooks that add the language of a document's
+// content (see [DetectLanguage]) to its metadata with the given key, unless the
+// document already has the key or the language is unknown. Register them with
+// [Collection.AddHooks]. Queries can then be filtered by language, e.g. with the
+// language of the query text:
+//
+//	c.Query(ctx, q, 10, map[string]string{"lang": chromem.DetectLanguage(q)}, nil)
+func LanguageMetadataHooks(key string) Hooks {
+	return Hooks{
+		BeforeAdd: func(_ context.Context, doc *Document) error {
+			if _, ok := doc.Metadata[key]; ok || doc.Content == "" {
+				return nil
+			}
+			lang := DetectLanguage(doc.Content)
+			if lang == "" {
+				return nil
+			}
+			// Copy the metadata, as the caller's map must not be modified.
+			metadata := make(map[string]string, len(doc.Metadata)+1)
+			for k, v := range doc.Metadata {
+				metadata[k] = v
+			}
+			metadata[key] = lang
+			doc.Metadata = metadata
+			return nil
+		},
+	}
+}
+
+// LanguageRouter routes documents and queries to a collection per language,
+// based on the language of their text (see [DetectLanguage]). Each collection
+// can use an embedding function that's specialized in its language, which
+// improves the retrieval quality compared to a single multilingual model.
+// Embeddings of different models aren't comparable, which is why the router
+// uses separate collections instead of multiple embedding functions for the
+// same collection.
+type LanguageRouter struct {
+	collections map[string]*Collection
+	fallback    *Collection
+}
+
+// NewLanguageRouter creates a router for the collections per language code.
+//
+//   - collections: The collections per ISO 639-1 code as returned by
+//     [DetectLanguage], e.g. "en". Must not be empty.
+//   - fallback: The collection for texts in other or unknown languages.
+//     Optional. Without it, such texts can't be added or queried.
+func NewLanguageRouter(collections map[string]*Collection, fallback *Collection) (*LanguageRouter, error) {
+	if len(collections) == 0 {
+		return nil, errors.New("collections are empty")
+	}
+	for lang, c := range collections {
+		if c == nil {
+			return nil, fmt.Errorf("collection for language '%s' is nil", lang)
+		}
+	}
+	return &LanguageRouter{collections: collections, fallback: fallback}, nil
+}
+
+// Collection returns the collection for the language of the text, or the
+// fallback collection, which can be nil.
+func (r *LanguageRouter) Collection(text string) *Collection {
+	if c, ok := r.collections[DetectLanguage(text)]; ok {
+		return c
+	}
+	return r.fallback
+}
+
+// AddDocuments adds the documents to the collections of their content's
+// language. See [Collection.AddDocuments].
+func (r *LanguageRouter) AddDocuments(ctx context.Context, documents []Document, concurrency int) error {
+	if len(documents) == 0 {
+		return errors.New("documents slice is nil or empty")
+	}
+	perCollection := make(map[*Collection][]Document)
+	var order []*Collection
+	for _, doc := range documents {
+		c := r.Collection(doc.Content)
+		if c == nil {
+			return fmt.Errorf("no collection for the language of document '%s'", doc.ID)
+		}
+		if _, ok := perCollection[c]; !ok {
+			order = append(order, c)
+		}
+		perCollection[c] = append(perCollection[c], doc)
+	}
+	for _, c := range order {
+		err := c.AddDocuments(ctx, perCollection[c], concurrency)
+		if err != nil {
+			return fmt.Errorf("couldn't add documents to collection '%s': %w", c.CurrentName(), err)
+		}
+	}
+	return nil
+}
+
+// Query queries the collection of the query text's language. See
+// [Collection.Query].
+func (r *LanguageRouter) Query(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	c := r.Collection(queryText)
+	if c == nil {
+		return nil, errors.New("no collection for the language of the query")
+	}
+	return c.Query(ctx, queryText, nResults, where, whereDocument)
+}
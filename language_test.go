@@ -0,0 +1,114 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tt := map[string]string{
+		"The weather is nice and the sun is shining":     "en",
+		"Das Wetter ist schön und die Sonne scheint":     "de",
+		"Le temps est beau et le soleil brille pour moi": "fr",
+		"El tiempo es bueno y el sol brilla para todos":  "es",
+		"今日は天気がいいです":                                     "ja",
+		"今天天气很好":                                         "zh",
+		"오늘 날씨가 좋다":                                      "ko",
+		"Сегодня хорошая погода":                         "ru",
+		"Σήμερα ο καιρός είναι καλός":                    "el",
+		"xyz qwrt": "",
+		"":         "",
+	}
+	for text, want := range tt {
+		if got := DetectLanguage(text); got != want {
+			t.Fatal("expected", want, "for", text, "got", got)
+		}
+	}
+}
+
+func TestLanguageMetadataHooks(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c.AddHooks(LanguageMetadataHooks("lang"))
+
+	metadata := map[string]string{"foo": "bar"}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "Das ist ein Haus und ein Garten", Metadata: metadata, Embedding: []float32{1, 0}},
+		{ID: "2", Content: "This is a house with a garden", Metadata: map[string]string{"lang": "custom"}, Embedding: []float32{1, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["lang"] != "de" || doc.Metadata["foo"] != "bar" {
+		t.Fatal("expected detected language, got", doc.Metadata)
+	}
+	if _, ok := metadata["lang"]; ok {
+		t.Fatal("expected caller's metadata to be unchanged, got", metadata)
+	}
+	doc, err = c.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["lang"] != "custom" {
+		t.Fatal("expected existing language to be kept, got", doc.Metadata)
+	}
+}
+
+func TestLanguageRouter(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db := NewDB()
+	en, err := db.CreateCollection("en", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	de, err := db.CreateCollection("de", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = NewLanguageRouter(nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	r, err := NewLanguageRouter(map[string]*Collection{"en": en}, de)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "This is the house of the family"},
+		{ID: "2", Content: "Das ist das Haus der Familie"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if en.Count() != 1 || de.Count() != 1 {
+		t.Fatal("expected one document per collection, got", en.Count(), de.Count())
+	}
+
+	res, err := r.Query(ctx, "Where is the house of the family?", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+	// Unknown languages use the fallback
+	res, err = r.Query(ctx, "xyz", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatal("expected document 2, got", res)
+	}
+}
@@ -0,0 +1,150 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// SetLateInteraction enables or disables the storage of token-level embeddings
+// (see [Document.TokenEmbeddings]) for late-interaction scoring with
+// [Collection.QueryLateInteraction], like with ColBERT models. As each
+// document stores one embedding per token, this takes a multiple of the memory
+// and disk space of a single embedding, so it's disabled by default, and
+// documents with token embeddings are rejected. The setting is persisted with
+// the collection. Disabling it keeps the token embeddings of existing documents.
+func (c *Collection) SetLateInteraction(enabled bool) error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if enabled == c.lateInteraction {
+		return nil
+	}
+	c.lateInteraction = enabled
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// LateInteraction returns whether the collection stores token-level embeddings,
+// see [Collection.SetLateInteraction].
+func (c *Collection) LateInteraction() bool {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.lateInteraction
+}
+
+// QueryLateInteraction performs an exhaustive search with the token-level
+// embeddings of a query, scoring the documents by MaxSim like ColBERT: For each
+// query token, the highest cosine similarity to any of the document's tokens,
+// summed over all query tokens. Documents without token embeddings (see
+// [Document.TokenEmbeddings]) are ignored, so fewer than nResults results can be
+// returned. The results' similarity is the MaxSim score, which is between
+// -len(queryTokens) and len(queryTokens). It requires
+// [Collection.SetLateInteraction].
+//
+//   - queryTokens: The token embeddings of the query, created with the same
+//     model as the documents' token embeddings.
+//   - nResults: The maximum number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) QueryLateInteraction(ctx context.Context, queryTokens [][]float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if len(queryTokens) == 0 {
+		return nil, errors.New("queryTokens is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	queryTokens, err := normalizeTokenEmbeddings(queryTokens)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queryTokens: %w", err)
+	}
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if !c.lateInteraction {
+		return nil, errors.New("late interaction isn't enabled, see SetLateInteraction")
+	}
+
+	filteredDocs, err := c.filter(c.column.docs(c.documents), where, whereDocument)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	// The filtered documents might be the collection's shared slice of all
+	// documents, so it's cloned before removing documents.
+	filteredDocs = slices.DeleteFunc(slices.Clone(filteredDocs), func(doc *Document) bool {
+		return len(doc.TokenEmbeddings) == 0
+	})
+	if len(filteredDocs) == 0 {
+		return nil, nil
+	}
+
+	docSims, err := getMostSimilarDocsFunc(ctx, filteredDocs, min(nResults, len(filteredDocs)), func(doc *Document) (float32, error) {
+		return maxSim(queryTokens, doc.TokenEmbeddings)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+
+	return c.docSimsToResults(docSims, true)
+}
+
+// maxSim calculates the late-interaction score of the normalized query and
+// document token embeddings: the sum of the highest similarity of each query
+// token to any document token.
+func maxSim(queryTokens, docTokens [][]float32) (float32, error) {
+	var res float32
+	for _, q := range queryTokens {
+		best, err := dotProduct(q, docTokens[0])
+		if err != nil {
+			return 0, err
+		}
+		for _, d := range docTokens[1:] {
+			sim, _ := dotProduct(q, d) // The documents' tokens have the same dimensions.
+			best = max(best, sim)
+		}
+		res += best
+	}
+	return res, nil
+}
+
+// normalizeTokenEmbeddings validates that the token embeddings have the same
+// dimensions and returns a copy with normalized embeddings.
+func normalizeTokenEmbeddings(tokens [][]float32) ([][]float32, error) {
+	dims := len(tokens[0])
+	res := make([][]float32, len(tokens))
+	for i, v := range tokens {
+		if len(v) == 0 || len(v) != dims {
+			return nil, fmt.Errorf("%w: token embedding %d has %d dimensions, expected %d", ErrDimensionMismatch, i, len(v), dims)
+		}
+		if isNormalized(v) {
+			res[i] = slices.Clone(v)
+		} else {
+			res[i] = normalizeVector(v)
+		}
+	}
+	return res, nil
+}
+
+// cloneTokenEmbeddings returns a deep copy of the token embeddings.
+func cloneTokenEmbeddings(tokens [][]float32) [][]float32 {
+	if tokens == nil {
+		return nil
+	}
+	res := make([][]float32, len(tokens))
+	for i, v := range tokens {
+		res[i] = slices.Clone(v)
+	}
+	return res
+}
@@ -0,0 +1,94 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCollection_QueryLateInteraction(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	doc1 := Document{ID: "1", TokenEmbeddings: [][]float32{{1, 0, 0}, {0, 2, 0}}}
+	doc2 := Document{ID: "2", TokenEmbeddings: [][]float32{{1, 0, 0}, {0, 0, 1}}}
+	doc3 := Document{ID: "3", Embedding: []float32{0, 1, 0}}
+
+	// Disabled by default
+	err = c.AddDocument(ctx, doc1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetLateInteraction(true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{doc1, doc2, doc3}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "4", TokenEmbeddings: [][]float32{{1, 0, 0}, {0, 1}}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+
+	// The token embeddings are normalized, and without another embedding, their
+	// mean is the embedding.
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.TokenEmbeddings[1][1] != 1 {
+		t.Fatal("expected normalized token embeddings, got", doc.TokenEmbeddings)
+	}
+	if len(doc.Embedding) != 3 || !isNormalized(doc.Embedding) || doc.Embedding[0] != doc.Embedding[1] {
+		t.Fatal("expected the normalized mean as embedding, got", doc.Embedding)
+	}
+
+	// Both documents match the first query token, only document 1 the second.
+	res, err := c.QueryLateInteraction(ctx, [][]float32{{1, 0, 0}, {0, 1, 0}}, 5, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "1" || res[1].ID != "2" {
+		t.Fatal("expected documents 1 and 2, got", res)
+	}
+	if res[0].Similarity != 2 || res[1].Similarity != 1 {
+		t.Fatal("expected MaxSim scores 2 and 1, got", res[0].Similarity, res[1].Similarity)
+	}
+
+	_, err = c.QueryLateInteraction(ctx, [][]float32{{1, 0}}, 1, nil, nil)
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+
+	// The setting and token embeddings are persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if !c2.LateInteraction() {
+		t.Fatal("expected late interaction to be enabled")
+	}
+	res, err = c2.QueryLateInteraction(ctx, [][]float32{{0, 0, 1}}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "2" {
+		t.Fatal("expected document 2, got", res)
+	}
+}
@@ -0,0 +1,170 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrClosed is returned when using a DB or collection after [DB.Close].
+var ErrClosed = errors.New("DB is closed")
+
+// Flush waits for pending writes of the collection and its loaded namespaces to
+// finish and syncs their files to stable storage, so that they survive a crash
+// of the OS or a power loss. Documents are written to disk on each write
+// operation anyway, but without syncing.
+// It's a no-op for non-persistent collections.
+func (c *Collection) Flush(ctx context.Context) error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+
+	// Wait for pending writes and block new ones until we're done.
+	c.persistLock.Lock()
+	defer c.persistLock.Unlock()
+
+	err := c.persistModifiedAt()
+	if err != nil {
+		return err
+	}
+	err = c.persistBM25Index()
+	if err != nil {
+		return err
+	}
+	err = syncDir(ctx, c.persistDirectory)
+	if err != nil {
+		return fmt.Errorf("couldn't sync collection directory: %w", err)
+	}
+	err = syncDir(ctx, filepath.Join(c.persistDirectory, changeLogDirName))
+	if err != nil {
+		return fmt.Errorf("couldn't sync change log directory: %w", err)
+	}
+
+	c.namespacesLock.Lock()
+	namespaces := make([]*Collection, 0, len(c.namespaces))
+	for _, ns := range c.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	c.namespacesLock.Unlock()
+	for _, ns := range namespaces {
+		err := ns.Flush(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't flush namespace '%s': %w", ns.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// close flushes the collection and marks it and its loaded namespaces as
+// closed.
+func (c *Collection) close(ctx context.Context) error {
+	err := c.Flush(ctx)
+
+	c.documentsLock.Lock()
+	if !c.closed {
+		// All changes are logged, so replicas don't have to read all files
+		// again when the collection is loaded next time.
+		err = errors.Join(err, c.logChanges(changeRecord{Op: changeOpClose}))
+	}
+	c.closed = true
+	err = errors.Join(err, c.auditLog.close())
+	c.documentsLock.Unlock()
+	c.watchers.closeAll()
+
+	c.namespacesLock.Lock()
+	for _, ns := range c.namespaces {
+		ns.documentsLock.Lock()
+		if !ns.closed {
+			err = errors.Join(err, ns.logChanges(changeRecord{Op: changeOpClose}))
+		}
+		ns.closed = true
+		err = errors.Join(err, ns.auditLog.close())
+		ns.documentsLock.Unlock()
+		ns.watchers.closeAll()
+	}
+	c.namespacesLock.Unlock()
+
+	return err
+}
+
+// Close flushes all collections (see [Collection.Flush]) and closes the DB.
+// Afterwards, the methods of the DB and its collections that return an error
+// return [ErrClosed]. Closing a closed DB is a no-op.
+func (db *DB) Close() error {
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+
+	if db.closed {
+		return nil
+	}
+	db.closed = true
+
+	var errs []error
+	for _, c := range db.collections {
+		err := c.close(context.Background())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't flush collection '%s': %w", c.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ping returns [ErrClosed] if the DB is closed, and for a persistent DB an error
+// if its directory isn't accessible. It's meant for health checks.
+func (db *DB) Ping() error {
+	db.collectionsLock.RLock()
+	defer db.collectionsLock.RUnlock()
+	if db.closed {
+		return ErrClosed
+	}
+
+	if db.persistDirectory != "" {
+		_, err := os.Stat(db.persistDirectory)
+		if err != nil {
+			return fmt.Errorf("couldn't access persistence directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncDir syncs the regular files in the directory and the directory itself.
+func syncDir(ctx context.Context, dirPath string) error {
+	dirEntries, err := os.ReadDir(dirPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("couldn't read directory: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !dirEntry.Type().IsRegular() {
+			continue
+		}
+		err := syncFile(filepath.Join(dirPath, dirEntry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return syncFile(dirPath)
+}
+
+func syncFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("couldn't open %q: %w", path, err)
+	}
+	defer f.Close()
+	err = f.Sync()
+	// Syncing directories isn't supported on all platforms, e.g. Windows.
+	if err != nil && !errors.Is(err, os.ErrInvalid) && !errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("couldn't sync %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDB_Close(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	ns, err := c.Namespace("tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "foo"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = ns.AddDocument(ctx, Document{ID: "1", Content: "foo"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.Flush(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Closing twice is a no-op
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "bar"})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	err = ns.AddDocument(ctx, Document{ID: "2", Content: "bar"})
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	_, err = c.Query(ctx, "foo", 1, nil, nil)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "1")
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	_, err = db.CreateCollection("other", nil, embeddingFunc)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+
+	// The data is still there after reopening
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if db.GetCollection("test", embeddingFunc).Count() != 1 {
+		t.Fatal("expected 1 document")
+	}
+}
+
+func TestDB_Ping(t *testing.T) {
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Ping()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Ping()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Ping()
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+}
@@ -0,0 +1,101 @@
+package chromem
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a fixed-size cache that evicts the least recently used entry.
+// It's safe for concurrent use.
+type lruCache[K comparable, V any] struct {
+	size    int
+	entries map[K]*list.Element
+	lru     *list.List // Front is most recently used
+	lock    sync.Mutex
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache creates a new LRU cache holding at most size entries.
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{
+		size:    size,
+		entries: make(map[K]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// get returns the value for the key and marks it as recently used.
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// put stores the value for the key, evicting the least recently used entry if
+// the cache is full.
+func (c *lruCache[K, V]) put(key K, value V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry[K, V]).value = value
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&lruEntry[K, V]{key: key, value: value})
+	if c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// remove removes the entry for the key, if it exists.
+func (c *lruCache[K, V]) remove(key K) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// purge removes all entries.
+func (c *lruCache[K, V]) purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries = make(map[K]*list.Element, c.size)
+	c.lru.Init()
+}
+
+// len returns the number of entries.
+func (c *lruCache[K, V]) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// each calls fn for each entry, from the most to the least recently used one.
+// fn must not call other methods of the cache.
+func (c *lruCache[K, V]) each(fn func(key K, value V)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*lruEntry[K, V])
+		fn(entry.key, entry.value)
+	}
+}
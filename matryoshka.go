@@ -0,0 +1,130 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const defaultMatryoshkaOversampling = 4
+
+// matryoshkaConfig is the configuration of the two-stage search, see
+// [Collection.SetMatryoshkaSearch]. Exported fields so it can be persisted.
+type matryoshkaConfig struct {
+	// Number of leading dimensions used in the first stage. 0 means disabled.
+	Dimensions int
+	// Factor of nResults that are rescored in the second stage.
+	Oversampling int
+}
+
+// enabled returns whether the two-stage search is enabled and useful for
+// embeddings of the given length.
+func (m matryoshkaConfig) enabled(embeddingLen int) bool {
+	return m.Dimensions > 0 && m.Dimensions < embeddingLen
+}
+
+// SetMatryoshkaSearch enables a two-stage search for collections whose
+// embeddings were created by a model that's trained with Matryoshka
+// Representation Learning (MRL), like OpenAI's "text-embedding-3-*", Nomic's
+// "nomic-embed-text-v1.5" or Jina's "jina-embeddings-v3". Their embeddings carry
+// most of the information in the leading dimensions.
+//
+// The full embeddings are still stored. When querying, the first stage compares
+// only the first dimensions of the query and document embeddings, keeping the
+// nResults*oversampling best candidates. The second stage then rescores these
+// candidates with all dimensions, so the similarities in the results are exact.
+// This makes queries on large collections considerably faster, with a
+// negligible loss of recall for MRL models. For other models the recall can be
+// significantly worse.
+//
+// The setting is persisted if the DB is persistent, and it's included in
+// exports.
+//
+//   - dimensions: Number of leading dimensions to use in the first stage, e.g.
+//     256 for a 1536-dimensional embedding. 0 disables the two-stage search,
+//     which is the default.
+//   - oversampling: Factor of nResults that are rescored in the second stage.
+//     Higher values improve the recall but make queries slower. If it's 0 or
+//     less, a default of 4 is used.
+func (c *Collection) SetMatryoshkaSearch(dimensions, oversampling int) error {
+	if dimensions < 0 {
+		return errors.New("dimensions must be >= 0")
+	}
+	if oversampling <= 0 {
+		oversampling = defaultMatryoshkaOversampling
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	c.matryoshka = matryoshkaConfig{
+		Dimensions:   dimensions,
+		Oversampling: oversampling,
+	}
+	// The similarities are the same, but the results might differ in recall.
+	c.invalidateQueryCache()
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// MatryoshkaSearch returns the configuration of the two-stage search, see
+// [Collection.SetMatryoshkaSearch]. The dimensions are 0 if it's disabled.
+func (c *Collection) MatryoshkaSearch() (dimensions, oversampling int) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.matryoshka.Dimensions, c.matryoshka.Oversampling
+}
+
+// getMostSimilarDocs does the two-stage search. The query embedding must be
+// normalized.
+func (m matryoshkaConfig) getMostSimilarDocs(ctx context.Context, queryEmbedding []float32, docs []*Document, n int) ([]docSim, error) {
+	// First stage: cosine similarity of the truncated embeddings. The document
+	// embeddings are only normalized as a whole, so we normalize their prefix on
+	// the fly.
+	queryPrefix := normalizeVector(queryEmbedding[:m.Dimensions])
+	numCandidates := min(n*m.Oversampling, len(docs))
+	candidates, err := getMostSimilarDocsFunc(ctx, docs, numCandidates, func(doc *Document) (float32, error) {
+		return prefixCosineSimilarity(queryPrefix, doc.Embedding)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get candidates: %w", err)
+	}
+
+	// Second stage: Rescore the candidates with the full embeddings.
+	docsByID := make(map[string]*Document, len(docs))
+	for _, doc := range docs {
+		docsByID[doc.ID] = doc
+	}
+	candidateDocs := make([]*Document, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidateDocs = append(candidateDocs, docsByID[candidate.docID])
+	}
+	return getMostSimilarDocs(ctx, queryEmbedding, candidateDocs, n)
+}
+
+// prefixCosineSimilarity calculates the cosine similarity between the normalized
+// query prefix and the same number of leading dimensions of the embedding.
+func prefixCosineSimilarity(queryPrefix, embedding []float32) (float32, error) {
+	if len(embedding) < len(queryPrefix) {
+		return 0, fmt.Errorf("%w: embedding has %d dimensions, expected at least %d", ErrDimensionMismatch, len(embedding), len(queryPrefix))
+	}
+
+	var dot, sqSum float32
+	for i, q := range queryPrefix {
+		v := embedding[i]
+		dot += q * v
+		sqSum += v * v
+	}
+	if sqSum == 0 {
+		return 0, nil
+	}
+
+	return dot / float32(math.Sqrt(float64(sqSum))), nil
+}
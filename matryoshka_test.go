@@ -0,0 +1,138 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_MatryoshkaSearch(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+
+	d := 64
+	randomVector := func() []float32 {
+		v := make([]float32, d)
+		for j := range v {
+			v[j] = r.Float32()*2 - 1
+		}
+		return normalizeVector(v)
+	}
+
+	db := NewDB()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		return nil, errors.New("embedding func not expected to be called")
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 100; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector()})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	qv := randomVector()
+
+	exact, err := c.QueryEmbedding(ctx, qv, 5, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// With enough oversampling all docs are rescored, so the results must be
+	// the same as with the exhaustive search.
+	err = c.SetMatryoshkaSearch(16, 20)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.QueryEmbedding(ctx, qv, 5, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := range exact {
+		if res[i].ID != exact[i].ID || res[i].Similarity != exact[i].Similarity {
+			t.Fatal("expected", exact[i], "got", res[i])
+		}
+	}
+
+	// With little oversampling the recall can be lower, but the similarities
+	// must still be exact.
+	err = c.SetMatryoshkaSearch(16, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = c.QueryEmbedding(ctx, qv, 5, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 5 {
+		t.Fatal("expected 5 results, got", len(res))
+	}
+	for _, r := range res {
+		sim, _ := dotProduct(qv, r.Embedding)
+		if sim != r.Similarity {
+			t.Fatal("expected similarity", sim, "got", r.Similarity)
+		}
+	}
+
+	// Invalid
+	err = c.SetMatryoshkaSearch(-1, 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_MatryoshkaSearch_Persistence(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetMatryoshkaSearch(16, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Persisted
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	dimensions, oversampling := db2.GetCollection("test", nil).MatryoshkaSearch()
+	if dimensions != 16 || oversampling != defaultMatryoshkaOversampling {
+		t.Fatal("expected 16 and the default oversampling, got", dimensions, oversampling)
+	}
+
+	// Cloned
+	clone, err := db.CloneCollection(ctx, "test", "clone", Filter{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	dimensions, _ = clone.MatryoshkaSearch()
+	if dimensions != 16 {
+		t.Fatal("expected 16, got", dimensions)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetMatryoshkaSearch(0, 0)
+	if !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+}
@@ -0,0 +1,497 @@
+package chromem
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Estimated memory overhead of a document, for the struct, map entries and
+// slice headers.
+const documentOverhead = 200
+
+// Estimated memory overhead of an entry in a map with string keys, excluding
+// the key's bytes.
+const mapEntryOverhead = 48
+
+// Estimated memory overhead of a cached query result, excluding the content,
+// embedding and metadata, which are shared with the documents.
+const cachedResultOverhead = 100
+
+// MemoryUsage is the estimated memory usage of a collection in bytes, by
+// category. See [Collection.MemoryUsage].
+type MemoryUsage struct {
+	// Dense and sparse embeddings, including the unused capacity of the
+	// contiguous embedding storage.
+	Embeddings int64
+	// Content and binary data, except content that was evicted from memory
+	// (see [DB.SetMemoryBudget]).
+	Content int64
+	// Metadata, IDs and other fields of the documents, plus the overhead per
+	// document.
+	Metadata int64
+	// IVF index, projection, query cache and other lookup structures.
+	Indexes int64
+}
+
+// Total returns the sum of all categories.
+func (u MemoryUsage) Total() int64 {
+	return u.Embeddings + u.Content + u.Metadata + u.Indexes
+}
+
+// MemoryUsage estimates the memory usage of the collection, so that it can be
+// monitored, e.g. to alert before the process runs out of memory. The numbers
+// are approximations based on the sizes of the stored values plus an estimated
+// overhead for the Go data structures, not measurements of the heap. Loaded
+// namespaces (see [Collection.Namespace]) are separate collections and aren't
+// included.
+func (c *Collection) MemoryUsage() MemoryUsage {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	var usage MemoryUsage
+	for _, doc := range c.documents {
+		m := documentMemory(doc)
+		usage.Embeddings += m.Embeddings
+		usage.Content += m.Content
+		usage.Metadata += m.Metadata
+		if doc.ContentHash != "" {
+			// Counted once per content below
+			usage.Content -= int64(len(doc.Content))
+		}
+	}
+	if c.contents != nil {
+		for hash, blob := range c.contents.blobs {
+			usage.Content += int64(len(blob.content))
+			usage.Indexes += int64(len(hash) + mapEntryOverhead)
+		}
+	}
+
+	// The embeddings of the column's documents are sub-slices of one
+	// allocation, which has room for more rows.
+	usage.Embeddings += 4 * int64(cap(c.column.data)-len(c.column.index)*c.column.dims)
+	usage.Indexes += 8 * int64(cap(c.column.rows))
+	for id := range c.column.index {
+		usage.Indexes += int64(len(id) + mapEntryOverhead)
+	}
+
+	if c.ivf != nil {
+		for _, centroid := range c.ivf.centroids {
+			usage.Indexes += 4 * int64(len(centroid))
+		}
+		// Each document is in the assignments and in one list.
+		for id := range c.ivf.assignments {
+			usage.Indexes += int64(len(id) + 2*mapEntryOverhead)
+		}
+	}
+	if c.projection != nil {
+		for _, row := range c.projection.Matrix {
+			usage.Indexes += 4 * int64(len(row))
+		}
+	}
+	if c.queryCache != nil {
+		usage.Indexes += c.queryCache.memoryUsage()
+	}
+	usage.Indexes += int64(len(c.evicted) * mapEntryOverhead)
+	if c.compressed != nil {
+		usage.Content += c.compressed.memoryUsage()
+	}
+
+	return usage
+}
+
+// memoryBudget limits the estimated memory usage of the documents of a DB by
+// evicting the content and binary data of the least recently used documents,
+// see [DB.SetMemoryBudget]. All methods are no-ops on a nil budget.
+//
+// Lock order: a collection's documentsLock can be held when calling methods of
+// the budget, but the budget's lock is never held while locking a collection.
+type memoryBudget struct {
+	limit int64
+
+	lock sync.Mutex
+	used int64
+	// Evictable documents, front is most recently used
+	lru     *list.List
+	entries map[budgetKey]*list.Element
+}
+
+type budgetKey struct {
+	c  *Collection
+	id string
+}
+
+type budgetEntry struct {
+	key budgetKey
+	// The version of the document when it was tracked. If it changed, the
+	// document was replaced and must not be evicted based on this entry.
+	version uint64
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	return &memoryBudget{
+		limit:   limit,
+		lru:     list.New(),
+		entries: make(map[budgetKey]*list.Element),
+	}
+}
+
+// SetMemoryBudget limits the estimated memory usage of the documents in the
+// DB's collections to the given number of bytes, to keep the process within the
+// limits of a container, for example. When the budget is exceeded, the content
+// and binary data of the least recently used documents are evicted from memory.
+// Adding a document and returning it as a query result or via
+// [Collection.GetByID] count as usage.
+//
+// Evicted content is read from the document's file when it's needed, e.g. for
+// query results, content filters and exports, which is slower. That's why the
+// budget requires a persistent DB. The embeddings and metadata always stay in
+// memory, as they're needed for every query, so the usage can't go below their
+// size. Namespaces aren't included in the budget.
+//
+// A budget of 0 removes the limit, but content that was already evicted stays
+// on disk until the document is replaced.
+func (db *DB) SetMemoryBudget(bytes int64) error {
+	if bytes < 0 {
+		return errors.New("bytes must be >= 0")
+	}
+
+	db.collectionsLock.Lock()
+	if db.closed {
+		db.collectionsLock.Unlock()
+		return ErrClosed
+	}
+	if db.persistDirectory == "" {
+		db.collectionsLock.Unlock()
+		return errors.New("memory budget requires a persistent DB")
+	}
+
+	var budget *memoryBudget
+	if bytes > 0 {
+		budget = newMemoryBudget(bytes)
+	}
+	db.memory = budget
+	for _, c := range db.collections {
+		c.setMemoryBudget(budget)
+	}
+	db.collectionsLock.Unlock()
+
+	budget.enforce()
+	return nil
+}
+
+// setMemoryBudget attaches the budget to the collection (or detaches it, if
+// nil), adding its documents' usage to the budget.
+func (c *Collection) setMemoryBudget(budget *memoryBudget) {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	c.memory = budget
+	if budget == nil {
+		return
+	}
+	var used int64
+	for id, doc := range c.documents {
+		used += documentMemoryUsage(doc)
+		if _, ok := c.evicted[id]; ok || !evictable(doc) {
+			continue
+		}
+		// Documents that were imported aren't persisted.
+		if _, err := os.Stat(c.getDocPath(id)); err != nil {
+			continue
+		}
+		budget.track(c, doc)
+	}
+	budget.adjust(used)
+}
+
+// removeMemoryBudget removes the collection's documents from its budget, when
+// the collection is deleted.
+func (c *Collection) removeMemoryBudget() {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	if c.memory == nil {
+		return
+	}
+	var used int64
+	for _, doc := range c.documents {
+		used += documentMemoryUsage(doc)
+	}
+	c.memory.forget(c, used)
+	c.memory = nil
+}
+
+// evictable returns whether evicting the document frees memory. Content in the
+// content store (see [Collection.EnableContentStore]) is shared and isn't
+// evicted.
+func evictable(doc *Document) bool {
+	return (doc.Content != "" && doc.ContentHash == "") || len(doc.Data) > 0
+}
+
+// adjust changes the estimated usage by delta bytes.
+func (b *memoryBudget) adjust(delta int64) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.used += delta
+}
+
+// track makes the document evictable, as the most recently used one. It must
+// only be called after the document was persisted.
+func (b *memoryBudget) track(c *Collection, doc *Document) {
+	if b == nil || !evictable(doc) {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := budgetKey{c: c, id: doc.ID}
+	if elem, ok := b.entries[key]; ok {
+		elem.Value.(*budgetEntry).version = doc.Version
+		b.lru.MoveToFront(elem)
+		return
+	}
+	b.entries[key] = b.lru.PushFront(&budgetEntry{key: key, version: doc.Version})
+}
+
+// untrack makes the document not evictable, e.g. when it's deleted.
+func (b *memoryBudget) untrack(c *Collection, id string) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	key := budgetKey{c: c, id: id}
+	if elem, ok := b.entries[key]; ok {
+		b.lru.Remove(elem)
+		delete(b.entries, key)
+	}
+}
+
+// touch marks the document as recently used.
+func (b *memoryBudget) touch(c *Collection, id string) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if elem, ok := b.entries[budgetKey{c: c, id: id}]; ok {
+		b.lru.MoveToFront(elem)
+	}
+}
+
+// forget removes all documents of the collection and their usage.
+func (b *memoryBudget) forget(c *Collection, used int64) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for elem := b.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if entry := elem.Value.(*budgetEntry); entry.key.c == c {
+			b.lru.Remove(elem)
+			delete(b.entries, entry.key)
+		}
+		elem = next
+	}
+	b.used -= used
+}
+
+// enforce evicts the least recently used documents until the usage is within
+// the budget, or there's nothing left to evict.
+func (b *memoryBudget) enforce() {
+	if b == nil {
+		return
+	}
+	for {
+		b.lock.Lock()
+		if b.used <= b.limit || b.lru.Len() == 0 {
+			b.lock.Unlock()
+			return
+		}
+		elem := b.lru.Back()
+		entry := elem.Value.(*budgetEntry)
+		b.lru.Remove(elem)
+		delete(b.entries, entry.key)
+		b.lock.Unlock()
+
+		entry.key.c.evict(entry.key.id, entry.version)
+	}
+}
+
+// evict removes the content and data of the document from memory, if it still
+// has the given version.
+func (c *Collection) evict(id string, version uint64) {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	doc, ok := c.documents[id]
+	if c.closed || !ok || doc.Version != version {
+		return
+	}
+	if _, ok := c.evicted[id]; ok {
+		return
+	}
+	// We replace the document instead of modifying it, because the old one
+	// might still be referenced, e.g. by cached query results.
+	newDoc := *doc
+	freed := len(doc.Data)
+	if doc.ContentHash == "" {
+		newDoc.Content = ""
+		freed += len(doc.Content)
+	}
+	freed += len(doc.EmbeddingText)
+	newDoc.EmbeddingText = ""
+	newDoc.Data = nil
+	c.compressed.remove(id)
+	c.documents[id] = &newDoc
+	c.column.replace(&newDoc)
+	if c.evicted == nil {
+		c.evicted = make(map[string]struct{})
+	}
+	c.evicted[id] = struct{}{}
+	c.memory.adjust(-int64(freed))
+}
+
+// withContent returns the document with its content and data, which are read
+// from disk if they were evicted, or decompressed if they're compressed. Must
+// be called while holding documentsLock.
+func (c *Collection) withContent(doc *Document) (*Document, error) {
+	if _, ok := c.evicted[doc.ID]; !ok {
+		return c.decompressed(doc)
+	}
+	stored := &Document{}
+	err := readFromFile(c.getDocPath(doc.ID), stored, c.encoding, "")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read evicted content of document '%s': %w", doc.ID, err)
+	}
+	// Encrypted content might be kept in plaintext in memory.
+	stored, err = c.decryptedFromDisk(stored)
+	if err != nil {
+		return nil, err
+	}
+	res := *doc
+	if doc.ContentHash == "" {
+		res.Content = stored.Content
+	}
+	res.EmbeddingText = stored.EmbeddingText
+	res.Data = stored.Data
+	return &res, nil
+}
+
+// filter is like filterDocSlice, but reads evicted content from disk and
+// decrypts encrypted content when filtering by content. The returned documents
+// are the stored ones. Must be called while holding documentsLock.
+func (c *Collection) filter(docs []*Document, where, whereDocument map[string]string) ([]*Document, error) {
+	if len(whereDocument) > 0 && c.discardContent {
+		return nil, ErrContentNotStored
+	}
+	if phrase, ok := whereDocument["$contains_phrase"]; ok && c.phrases != nil {
+		docs = c.phrases.filter(docs, phrase)
+	}
+	if len(whereDocument) == 0 || (len(c.evicted) == 0 && !c.contentEncryptedInMemory && c.compressed == nil) {
+		return filterDocSlice(docs, where, whereDocument), nil
+	}
+	filtered := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		if !documentMatchesFilters(doc, where, nil) {
+			continue
+		}
+		readable, err := c.readable(doc)
+		if err != nil {
+			return nil, err
+		}
+		if documentMatchesFilters(readable, nil, whereDocument) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered, nil
+}
+
+// filterWith is like filter, but the documents must match the typed filter as
+// well.
+func (c *Collection) filterWith(docs []*Document, where, whereDocument map[string]string, filter Filter) ([]*Document, error) {
+	docs, err := c.filter(docs, where, whereDocument)
+	if err != nil || filter.isEmpty() {
+		return docs, err
+	}
+	needsContent := filter.needsContent()
+	if needsContent && c.discardContent {
+		return nil, ErrContentNotStored
+	}
+	filtered := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		readable := doc
+		if needsContent {
+			readable, err = c.readable(doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if filter.matches(readable) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered, nil
+}
+
+// documentsWithContent returns the documents as they're exported, with evicted
+// content read from disk and encrypted content if the content encryption is
+// enabled. Must be called while holding documentsLock.
+func (c *Collection) documentsWithContent() (map[string]*Document, error) {
+	if len(c.evicted) == 0 && c.compressed == nil && (!c.contentEncrypted || c.contentEncryptedInMemory) {
+		return c.documents, nil
+	}
+	docs := make(map[string]*Document, len(c.documents))
+	for id, doc := range c.documents {
+		doc, err := c.withContent(doc)
+		if err != nil {
+			return nil, err
+		}
+		docs[id], err = c.encryptedForDisk(doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// documentMemoryUsage estimates the memory usage of a document in bytes.
+func documentMemoryUsage(doc *Document) int64 {
+	return documentMemory(doc).Total()
+}
+
+// documentMemory estimates the memory usage of a document by category.
+func documentMemory(doc *Document) MemoryUsage {
+	metadata := documentOverhead + len(doc.ID) + len(doc.MIMEType) + len(doc.EmbeddingModel) + len(doc.ContentHash)
+	for k, v := range doc.Metadata {
+		metadata += len(k) + len(v)
+	}
+	embeddings := 4*len(doc.Embedding) + 4*len(doc.SparseEmbedding.Indices) + 4*len(doc.SparseEmbedding.Values)
+	for _, v := range doc.TokenEmbeddings {
+		embeddings += 4 * len(v)
+	}
+	return MemoryUsage{
+		Embeddings: int64(embeddings),
+		Content:    int64(len(doc.Content) + len(doc.EmbeddingText) + len(doc.Data)),
+		Metadata:   int64(metadata),
+	}
+}
+
+// documentsMemoryUsage estimates the memory usage of the documents in bytes.
+func documentsMemoryUsage(docs map[string]*Document) int64 {
+	var size int64
+	for _, doc := range docs {
+		size += documentMemoryUsage(doc)
+	}
+	return size
+}
@@ -0,0 +1,171 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDB_SetMemoryBudget(t *testing.T) {
+	ctx := context.Background()
+
+	// Non-persistent DBs can't read evicted content from disk
+	err := NewDB().SetMemoryBudget(1000)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Some documents are added before the budget is set
+	content := func(i int) string {
+		return "document " + strconv.Itoa(i) + " " + strings.Repeat("x", 1000)
+	}
+	for i := 0; i < 5; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Content: content(i), Embedding: []float32{1, float32(i), 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = db.SetMemoryBudget(5000)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 5; i < 20; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Content: content(i), Embedding: []float32{1, float32(i), 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	c.documentsLock.RLock()
+	evicted := len(c.evicted)
+	var resident int64
+	for _, doc := range c.documents {
+		resident += int64(len(doc.Content))
+	}
+	c.documentsLock.RUnlock()
+	if evicted == 0 {
+		t.Fatal("expected evicted documents, got none")
+	}
+	if resident > 5000 {
+		t.Fatal("expected resident content <= 5000 bytes, got", resident)
+	}
+	c.memory.lock.Lock()
+	used := c.memory.used
+	c.memory.lock.Unlock()
+	if used > 5000 {
+		t.Fatal("expected usage <= 5000, got", used)
+	}
+
+	// The first document was evicted, but its content is read from disk
+	doc, err := c.GetByID(ctx, "0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != content(0) {
+		t.Fatal("expected content of document 0, got", doc.Content)
+	}
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0, 0}, 20, nil, map[string]string{"$contains": "document 1 "})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" || res[0].Content != content(1) {
+		t.Fatal("expected document 1 with content, got", res)
+	}
+
+	// Exports contain the evicted content
+	var buf bytes.Buffer
+	err = db.ExportToWriter(&buf, false, "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db2 := NewDB()
+	err = db2.ImportFromReader(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err = db2.GetCollection("test", nil).GetByID(ctx, "0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != content(0) {
+		t.Fatal("expected content of document 0, got", doc.Content)
+	}
+
+	// Deleting the collection releases its usage
+	err = db.DeleteCollection("test")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	budget := db.memory
+	budget.lock.Lock()
+	defer budget.lock.Unlock()
+	if budget.used != 0 || budget.lru.Len() != 0 {
+		t.Fatal("expected empty budget, got", budget.used, budget.lru.Len())
+	}
+}
+
+func TestCollection_MemoryUsage(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	usage := c.MemoryUsage()
+	if usage.Content != 0 || usage.Metadata != 0 {
+		t.Fatal("expected no content and metadata, got", usage)
+	}
+
+	for i := 0; i < 100; i++ {
+		err = c.AddDocument(ctx, Document{
+			ID:        strconv.Itoa(i),
+			Content:   strings.Repeat("x", 100),
+			Metadata:  map[string]string{"k": "v"},
+			Embedding: []float32{1, float32(i), 0, 0},
+		})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	usage = c.MemoryUsage()
+	if usage.Content != 100*100 {
+		t.Fatal("expected content of 10000 bytes, got", usage.Content)
+	}
+	if usage.Embeddings < 100*4*4 {
+		t.Fatal("expected embeddings of at least 1600 bytes, got", usage.Embeddings)
+	}
+	if usage.Metadata < 100*(documentOverhead+2) {
+		t.Fatal("expected metadata of at least 20200 bytes, got", usage.Metadata)
+	}
+	if usage.Total() != usage.Embeddings+usage.Content+usage.Metadata+usage.Indexes {
+		t.Fatal("expected total to be the sum of the categories, got", usage.Total())
+	}
+
+	// Indexes are accounted for
+	err = c.BuildIVFIndex(ctx, 4, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	withIndex := c.MemoryUsage()
+	if withIndex.Indexes <= usage.Indexes {
+		t.Fatal("expected index usage to grow, got", withIndex.Indexes, usage.Indexes)
+	}
+}
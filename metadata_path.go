@@ -0,0 +1,172 @@
+package chromem
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NestedMetadata converts structured metadata, e.g. decoded from the JSON of a
+// source system, to document metadata. String values are kept as they are,
+// other values like numbers, bools, objects and arrays are stored as JSON, and
+// nil values are omitted. This keeps the structure in any persistence format
+// and export, without flattening it into ad-hoc keys. Nested values can be
+// filtered by path with [Filter.PathEq] and [Filter.PathExists], and read with
+// [MetadataPath] and [StructuredMetadata].
+func NestedMetadata(metadata map[string]any) (map[string]string, error) {
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			res[k] = v
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't encode metadata value of key '%s': %w", k, err)
+			}
+			res[k] = string(b)
+		}
+	}
+	return res, nil
+}
+
+// StructuredMetadata is the reverse of [NestedMetadata]. It decodes the values
+// that are JSON objects or arrays, with numbers as [json.Number]. Other values
+// are kept as strings, as their original type can't be told apart from a string,
+// e.g. "42".
+func StructuredMetadata(metadata map[string]string) map[string]any {
+	res := make(map[string]any, len(metadata))
+	for k, v := range metadata {
+		res[k] = decodeMetadataValue(v)
+	}
+	return res
+}
+
+// decodeMetadataValue decodes the value if it's a JSON object or array, and
+// returns it as it is otherwise.
+func decodeMetadataValue(v string) any {
+	if len(v) == 0 || (v[0] != '{' && v[0] != '[') {
+		return v
+	}
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.UseNumber()
+	var res any
+	if err := dec.Decode(&res); err != nil || dec.More() {
+		return v
+	}
+	return res
+}
+
+// MetadataPath returns the values at the path in the metadata. The path starts
+// with a metadata key, followed by object keys separated by dots, e.g.
+// "author.name". A segment can be suffixed with "[]" for all elements of an
+// array, e.g. "tags[]" or "authors[].name", or with an index like "[0]" for a
+// single element. Keys that contain dots or brackets can't be addressed.
+//
+// The values are strings, bools, [json.Number], nil, map[string]any or []any.
+// The result is empty if the path doesn't exist.
+func MetadataPath(metadata map[string]string, path string) []any {
+	segments := strings.Split(path, ".")
+	key, selectors := splitPathSegment(segments[0])
+	v, ok := metadata[key]
+	if !ok {
+		return nil
+	}
+	values := selectElements([]any{decodeMetadataValue(v)}, selectors)
+	for _, segment := range segments[1:] {
+		key, selectors := splitPathSegment(segment)
+		var next []any
+		for _, v := range values {
+			if obj, ok := v.(map[string]any); ok {
+				if child, ok := obj[key]; ok {
+					next = append(next, child)
+				}
+			}
+		}
+		values = selectElements(next, selectors)
+	}
+	return values
+}
+
+// splitPathSegment splits a path segment like "tags[][0]" into the key and the
+// element selectors, "[]" or an index.
+func splitPathSegment(segment string) (string, []string) {
+	i := strings.IndexByte(segment, '[')
+	if i < 0 {
+		return segment, nil
+	}
+	var selectors []string
+	for _, s := range strings.Split(segment[i+1:], "[") {
+		selectors = append(selectors, strings.TrimSuffix(s, "]"))
+	}
+	return segment[:i], selectors
+}
+
+// selectElements applies the element selectors to the values. Values that
+// aren't arrays, and indexes that are out of range, are dropped.
+func selectElements(values []any, selectors []string) []any {
+	for _, selector := range selectors {
+		var next []any
+		for _, v := range values {
+			arr, ok := v.([]any)
+			if !ok {
+				continue
+			}
+			if selector == "" {
+				next = append(next, arr...)
+			} else if i, err := strconv.Atoi(selector); err == nil && i >= 0 && i < len(arr) {
+				next = append(next, arr[i])
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// metadataValueString returns the scalar value as string, like it's written in
+// JSON but without the quotes of strings, or false for objects and arrays.
+func metadataValueString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case nil:
+		return "null", true
+	}
+	return "", false
+}
+
+// PathEq matches documents with a value at the metadata path (see
+// [MetadataPath]) that equals the value. Numbers and bools are compared by
+// their JSON representation, e.g. "2020" or "true". With "[]" in the path, any
+// element can match, e.g. PathEq("tags[]", "go") matches documents whose tags
+// contain "go".
+func (f Filter) PathEq(path, value string) Filter {
+	return f.with(filterCond{
+		desc: "path " + strconv.Quote(path) + " == " + strconv.Quote(value),
+		match: func(doc *Document) bool {
+			for _, v := range MetadataPath(doc.Metadata, path) {
+				if s, ok := metadataValueString(v); ok && s == value {
+					return true
+				}
+			}
+			return false
+		},
+	})
+}
+
+// PathExists matches documents with a value at the metadata path (see
+// [MetadataPath]), including null.
+func (f Filter) PathExists(path string) Filter {
+	return f.with(filterCond{
+		desc: "exists path " + strconv.Quote(path),
+		match: func(doc *Document) bool {
+			return len(MetadataPath(doc.Metadata, path)) > 0
+		},
+	})
+}
@@ -0,0 +1,119 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNestedMetadata(t *testing.T) {
+	var source map[string]any
+	err := json.Unmarshal([]byte(`{
+		"title": "Go",
+		"year": 2020,
+		"draft": false,
+		"deleted": null,
+		"author": {"name": "Rob", "affiliations": [{"name": "Google"}, {"name": "Bell Labs"}]},
+		"tags": ["go", "programming"]
+	}`), &source)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	metadata, err := NestedMetadata(source)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := map[string]string{
+		"title":  "Go",
+		"year":   "2020",
+		"draft":  "false",
+		"author": `{"affiliations":[{"name":"Google"},{"name":"Bell Labs"}],"name":"Rob"}`,
+		"tags":   `["go","programming"]`,
+	}
+	if !reflect.DeepEqual(metadata, exp) {
+		t.Fatal("expected", exp, "got", metadata)
+	}
+
+	structured := StructuredMetadata(metadata)
+	if structured["title"] != "Go" || structured["year"] != "2020" {
+		t.Fatal("expected strings for scalar values, got", structured)
+	}
+	if !reflect.DeepEqual(structured["tags"], []any{"go", "programming"}) {
+		t.Fatal("expected decoded tags, got", structured["tags"])
+	}
+
+	tt := []struct {
+		path string
+		exp  []any
+	}{
+		{"title", []any{"Go"}},
+		{"author.name", []any{"Rob"}},
+		{"author.affiliations[].name", []any{"Google", "Bell Labs"}},
+		{"author.affiliations[1].name", []any{"Bell Labs"}},
+		{"author.affiliations[2].name", nil},
+		{"tags[]", []any{"go", "programming"}},
+		{"tags[0]", []any{"go"}},
+		{"title.foo", nil},
+		{"missing", nil},
+	}
+	for _, tc := range tt {
+		got := MetadataPath(metadata, tc.path)
+		if !reflect.DeepEqual(got, tc.exp) {
+			t.Fatal("expected", tc.exp, "for", tc.path, "got", got)
+		}
+	}
+}
+
+func TestFilter_Path(t *testing.T) {
+	docs := []*Document{
+		{ID: "1", Metadata: map[string]string{"author": `{"name":"Rob","born":1956}`, "tags": `["go","plan9"]`}},
+		{ID: "2", Metadata: map[string]string{"author": `{"name":"Ken"}`, "tags": `["unix"]`}},
+		{ID: "3", Metadata: map[string]string{"author": "Dennis"}},
+	}
+	tt := []struct {
+		filter Filter
+		exp    []string
+	}{
+		{Where().PathEq("author.name", "Rob"), []string{"1"}},
+		{Where().PathEq("author.born", "1956"), []string{"1"}},
+		{Where().PathEq("tags[]", "unix"), []string{"2"}},
+		{Where().PathEq("author", "Dennis"), []string{"3"}},
+		{Where().PathExists("tags"), []string{"1", "2"}},
+		{Where().PathExists("author.born"), []string{"1"}},
+	}
+	for _, tc := range tt {
+		var got []string
+		for _, doc := range docs {
+			if tc.filter.matches(doc) {
+				got = append(got, doc.ID)
+			}
+		}
+		if !reflect.DeepEqual(got, tc.exp) {
+			t.Fatal("expected", tc.exp, "for", tc.filter, "got", got)
+		}
+	}
+}
+
+func TestCollection_AddFromJSONLStream_NestedMetadata(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	r := strings.NewReader(`{"id":"1","metadata":{"author":{"name":"Rob"},"year":2020},"embedding":[1,0]}` + "\n")
+	_, err = c.AddFromJSONLStream(ctx, r, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := map[string]string{"author": `{"name":"Rob"}`, "year": "2020"}
+	if !reflect.DeepEqual(doc.Metadata, exp) {
+		t.Fatal("expected", exp, "got", doc.Metadata)
+	}
+}
@@ -0,0 +1,221 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// metadataStats counts the documents per value of the tracked metadata keys,
+// see [Collection.EnableMetadataStats]. All methods must be called while
+// holding the collection's documents write lock, except the read-only ones,
+// which only need the read lock.
+type metadataStats struct {
+	// Number of documents per key and value
+	counts map[string]map[string]int
+}
+
+func newMetadataStats(keys []string) *metadataStats {
+	s := &metadataStats{counts: make(map[string]map[string]int, len(keys))}
+	for _, key := range keys {
+		s.counts[key] = map[string]int{}
+	}
+	return s
+}
+
+// add counts the document's values. It's a no-op on nil stats.
+func (s *metadataStats) add(doc *Document) {
+	if s == nil {
+		return
+	}
+	for key, values := range s.counts {
+		if v, ok := doc.Metadata[key]; ok {
+			values[v]++
+		}
+	}
+}
+
+// remove uncounts the document's values. It's a no-op on nil stats or a nil
+// document.
+func (s *metadataStats) remove(doc *Document) {
+	if s == nil || doc == nil {
+		return
+	}
+	for key, values := range s.counts {
+		if v, ok := doc.Metadata[key]; ok {
+			values[v]--
+			if values[v] <= 0 {
+				delete(values, v)
+			}
+		}
+	}
+}
+
+// keys returns the tracked keys, sorted.
+func (s *metadataStats) keys() []string {
+	if s == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(s.counts))
+	for key := range s.counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// count returns the number of documents with one of the values of the key,
+// and whether the key is tracked.
+func (s *metadataStats) count(key string, values ...string) (int, bool) {
+	if s == nil {
+		return 0, false
+	}
+	counts, ok := s.counts[key]
+	if !ok {
+		return 0, false
+	}
+	n := 0
+	for _, v := range values {
+		n += counts[v]
+	}
+	return n, true
+}
+
+// EnableMetadataStats maintains statistics of the values of the given metadata
+// keys: the number of documents per value. The query planner uses them to
+// estimate the selectivity of filters on these keys exactly instead of by
+// sampling documents, e.g. for [FilterStrategyAuto], and they're available via
+// [Collection.MetadataStats] and [Collection.EstimateSelectivity]. Use it for
+// keys that queries are commonly filtered by, like a tenant or language.
+// Equality filters of where maps and of [Filter.Eq] and [Filter.In] use the
+// statistics.
+//
+// The statistics are built from the existing documents and updated when
+// documents are added or deleted. Keys that are already tracked are kept. The
+// keys are persisted if the DB is persistent, and the statistics are rebuilt
+// when the DB is loaded.
+func (c *Collection) EnableMetadataStats(keys ...string) error {
+	if len(keys) == 0 {
+		return errors.New("no keys given")
+	}
+	for _, key := range keys {
+		if key == "" {
+			return errors.New("key is empty")
+		}
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	all := append(c.metadataStats.keys(), keys...)
+	slices.Sort(all)
+	all = slices.Compact(all)
+	if slices.Equal(all, c.metadataStats.keys()) {
+		return nil
+	}
+	c.buildMetadataStats(all)
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// DisableMetadataStats removes the metadata statistics, see
+// [Collection.EnableMetadataStats].
+func (c *Collection) DisableMetadataStats() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.metadataStats == nil {
+		return nil
+	}
+	c.metadataStats = nil
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// MetadataStats returns the number of documents per value of the metadata key,
+// and whether the key is tracked, see [Collection.EnableMetadataStats].
+func (c *Collection) MetadataStats(key string) (map[string]int, bool) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.metadataStats == nil {
+		return nil, false
+	}
+	counts, ok := c.metadataStats.counts[key]
+	return maps.Clone(counts), ok
+}
+
+// EstimateSelectivity estimates the fraction of the collection's documents
+// that match the filters, between 0 and 1, e.g. to choose a [FilterStrategy].
+// Equality filters on keys with statistics (see
+// [Collection.EnableMetadataStats]) are estimated exactly, the other filters by
+// counting the matches in a sample of the documents. Filters are assumed to be
+// independent, so that their selectivities are multiplied.
+//
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+//   - filter: Composable filter. Optional.
+func (c *Collection) EstimateSelectivity(where, whereDocument map[string]string, filter Filter) (float64, error) {
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return 0, err
+	}
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return 0, ErrClosed
+	}
+	return c.estimateSelectivity(c.column.docs(c.documents), where, whereDocument, filter), nil
+}
+
+// buildMetadataStats builds the metadata statistics of the keys from the
+// documents. Must be called while holding the documents write lock.
+func (c *Collection) buildMetadataStats(keys []string) {
+	c.metadataStats = newMetadataStats(keys)
+	for _, doc := range c.documents {
+		c.metadataStats.add(doc)
+	}
+}
+
+// statsSelectivity returns the selectivity of the equality conditions on keys
+// with statistics, and the where map and filter with the remaining
+// conditions. Must be called while holding documentsLock.
+func (c *Collection) statsSelectivity(where map[string]string, filter Filter) (float64, map[string]string, Filter) {
+	if c.metadataStats == nil || len(c.documents) == 0 {
+		return 1, where, filter
+	}
+	selectivity := 1.0
+	var rest map[string]string
+	for key, value := range where {
+		// Empty values match documents without the key as well.
+		n, ok := c.metadataStats.count(key, value)
+		if !ok || value == "" {
+			if rest == nil {
+				rest = make(map[string]string, len(where))
+			}
+			rest[key] = value
+			continue
+		}
+		selectivity *= float64(n) / float64(len(c.documents))
+	}
+	restFilter := Filter{}
+	for _, cond := range filter.conds {
+		if cond.eqKey != "" {
+			if n, ok := c.metadataStats.count(cond.eqKey, cond.eqValues...); ok {
+				selectivity *= float64(n) / float64(len(c.documents))
+				continue
+			}
+		}
+		restFilter.conds = append(restFilter.conds, cond)
+	}
+	return selectivity, rest, restFilter
+}
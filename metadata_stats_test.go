@@ -0,0 +1,104 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollection_MetadataStats(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"tenant": "a", "lang": "en"}, Embedding: []float32{1, 0}},
+		{ID: "2", Metadata: map[string]string{"tenant": "a", "lang": "de"}, Embedding: []float32{1, 0}},
+		{ID: "3", Metadata: map[string]string{"tenant": "b", "lang": "en"}, Embedding: []float32{1, 0}},
+		{ID: "4", Metadata: map[string]string{"tenant": "c"}, Embedding: []float32{1, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if _, ok := c.MetadataStats("tenant"); ok {
+		t.Fatal("expected no statistics before enabling them")
+	}
+	err = c.EnableMetadataStats("tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, ok := c.MetadataStats("tenant")
+	if !ok || len(stats) != 3 || stats["a"] != 2 || stats["b"] != 1 || stats["c"] != 1 {
+		t.Fatal("expected tenant statistics, got", stats, ok)
+	}
+
+	// Replace and delete
+	err = c.AddDocument(ctx, Document{ID: "2", Metadata: map[string]string{"tenant": "b"}, Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "4")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	stats, _ = c.MetadataStats("tenant")
+	if len(stats) != 2 || stats["a"] != 1 || stats["b"] != 2 {
+		t.Fatal("expected updated statistics, got", stats)
+	}
+
+	estimate := func(c *Collection, where map[string]string, filter Filter) float64 {
+		t.Helper()
+		s, err := c.EstimateSelectivity(where, nil, filter)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return s
+	}
+	if s := estimate(c, map[string]string{"tenant": "b"}, Filter{}); s != 2.0/3 {
+		t.Fatal("expected 2/3, got", s)
+	}
+	if s := estimate(c, nil, Where().In("tenant", "a", "b", "a")); s != 1 {
+		t.Fatal("expected 1, got", s)
+	}
+	if s := estimate(c, nil, Where().Eq("tenant", "c")); s != 0 {
+		t.Fatal("expected 0, got", s)
+	}
+	// Combined with the sample estimate of keys without statistics
+	if s := estimate(c, map[string]string{"lang": "en"}, Where().Eq("tenant", "a")); s != 1.0/3*2/3 {
+		t.Fatal("expected 2/9, got", s)
+	}
+
+	// The keys are persisted and the statistics rebuilt
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	stats, ok = c2.MetadataStats("tenant")
+	if !ok || len(stats) != 2 || stats["a"] != 1 || stats["b"] != 2 {
+		t.Fatal("expected loaded statistics, got", stats, ok)
+	}
+
+	err = c.DisableMetadataStats()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := c.MetadataStats("tenant"); ok {
+		t.Fatal("expected no statistics after disabling them")
+	}
+	err = c.EnableMetadataStats("")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,83 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// migration upgrades the files of a collection directory by one format version.
+// It gets the collection's metadata, which is persisted with the new version
+// afterwards, so it can modify the metadata as well.
+type migration func(collectionPath string, compress bool, encoding Encoding, pc *persistedCollectionMetadata) error
+
+// migrations contains the migration from format version i to i+1 at index i.
+// When changing the layout or the content of the persisted files, append a
+// migration that converts existing files, so that users' data isn't stranded.
+var migrations = []migration{
+	// Version 1 introduced the format version itself, the files didn't change.
+	func(string, bool, Encoding, *persistedCollectionMetadata) error { return nil },
+	// Version 2 added the document count and dimensions to the metadata.
+	migrateDocumentStats,
+}
+
+// formatVersion returns the current version of the persistence format, which
+// is written to the collection metadata files. The document files of a
+// collection directory have the version of its metadata file. Files without
+// version have version 0.
+func formatVersion() int {
+	return len(migrations)
+}
+
+// migrateCollectionDir upgrades the files of the collection directory to the
+// current format version, if necessary. It's a no-op for directories without
+// metadata file. Directories of newer versions can't be read, as they might be
+// incompatible.
+func migrateCollectionDir(collectionPath string, compress bool, encoding Encoding, perms filePerms) error {
+	metadataPath := filepath.Join(collectionPath, metadataFileName+fileExt(encoding, compress))
+	pc := persistedCollectionMetadata{}
+	err := readFromFile(metadataPath, &pc, encoding, "")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("couldn't read collection metadata: %w", err)
+	}
+	return migrateCollection(collectionPath, compress, encoding, perms, &pc)
+}
+
+// migrateCollection upgrades the files of the collection directory with the
+// given metadata to the current format version, if necessary.
+func migrateCollection(collectionPath string, compress bool, encoding Encoding, perms filePerms, pc *persistedCollectionMetadata) error {
+	if pc.FormatVersion > formatVersion() {
+		return fmt.Errorf("collection '%s' has persistence format version %d, but only versions up to %d are supported, it was likely written by a newer version of chromem-go", pc.Name, pc.FormatVersion, formatVersion())
+	}
+	metadataPath := filepath.Join(collectionPath, metadataFileName+fileExt(encoding, compress))
+	for pc.FormatVersion < formatVersion() {
+		err := migrations[pc.FormatVersion](collectionPath, compress, encoding, pc)
+		if err != nil {
+			return fmt.Errorf("couldn't migrate collection '%s' from persistence format version %d: %w", pc.Name, pc.FormatVersion, err)
+		}
+		// Persisted after each step, so that an interrupted migration resumes
+		// at the failed step.
+		pc.FormatVersion++
+		err = persistToFile(metadataPath, pc, encoding, compress, "", perms)
+		if err != nil {
+			return fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateDocumentStats sets the document count and dimensions in the metadata
+// from the document files.
+func migrateDocumentStats(collectionPath string, compress bool, encoding Encoding, pc *persistedCollectionMetadata) error {
+	count, dims, err := readDocumentStats(collectionPath, compress, encoding)
+	if err != nil {
+		return err
+	}
+	pc.DocumentCount = count
+	pc.Dimensions = dims
+	return nil
+}
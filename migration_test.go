@@ -0,0 +1,91 @@
+package chromem
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateCollectionDir(t *testing.T) {
+	tempDir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	db, err := NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", map[string]string{"foo": "bar"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName+".gob")
+	readMetadata := func() persistedCollectionMetadata {
+		t.Helper()
+		pc := persistedCollectionMetadata{}
+		err := readFromFile(metadataPath, &pc, nil, "")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		return pc
+	}
+	writeMetadata := func(pc persistedCollectionMetadata) {
+		t.Helper()
+		err := persistToFile(metadataPath, pc, nil, false, "", filePerms{})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	if v := readMetadata().FormatVersion; v != formatVersion() {
+		t.Fatal("expected current format version, got", v)
+	}
+
+	// Add a migration for the test
+	var calls int
+	defer func(original []migration) { migrations = original }(migrations)
+	migrations = append(migrations, func(_ string, _ bool, _ Encoding, pc *persistedCollectionMetadata) error {
+		calls++
+		pc.Metadata["migrated"] = "true"
+		return nil
+	})
+
+	// Opening the DB migrates the collection, but only once
+	for i := 0; i < 2; i++ {
+		db, err = NewPersistentDB(tempDir, false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if calls != 1 {
+			t.Fatal("expected 1 migration call, got", calls)
+		}
+		c = db.GetCollection("test", nil)
+		if c.Metadata()["foo"] != "bar" || c.Metadata()["migrated"] != "true" {
+			t.Fatal("expected migrated metadata, got", c.Metadata())
+		}
+		if v := readMetadata().FormatVersion; v != formatVersion() {
+			t.Fatal("expected current format version, got", v)
+		}
+	}
+
+	// Files of older versions without version are migrated from version 0
+	pc := readMetadata()
+	pc.FormatVersion = 0
+	writeMetadata(pc)
+	_, err = NewPersistentDB(tempDir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if calls != 2 {
+		t.Fatal("expected 2 migration calls, got", calls)
+	}
+
+	// Newer versions can't be read
+	pc.FormatVersion = formatVersion() + 1
+	writeMetadata(pc)
+	_, err = NewPersistentDB(tempDir, false)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,28 @@
+// Package msgpack provides a [MessagePack] encoding for the files of a
+// persistent chromem-go DB (github.com/vmihailenco/msgpack). The files are
+// smaller than with JSON and can be read by other languages, unlike gob.
+//
+// It's a separate Go module, so that the main chromem-go module stays free of
+// third-party dependencies.
+//
+// [MessagePack]: https://msgpack.org
+package msgpack
+
+import (
+	"io"
+
+	"github.com/philippgille/chromem-go"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoding encodes the files of a persistent DB as MessagePack, with the
+// extension "msgpack". Pass it via [chromem.PersistentDBOptions.Encoding].
+var Encoding chromem.Encoding = encoding{}
+
+type encoding struct{}
+
+func (encoding) Extension() string { return "msgpack" }
+
+func (encoding) NewEncoder(w io.Writer) chromem.Encoder { return msgpack.NewEncoder(w) }
+
+func (encoding) NewDecoder(r io.Reader) chromem.Decoder { return msgpack.NewDecoder(r) }
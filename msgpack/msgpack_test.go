@@ -0,0 +1,97 @@
+package msgpack
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestEncoding(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{0.6, 0.8}, nil
+	}
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+	options := chromem.PersistentDBOptions{Compress: true, Encoding: Encoding}
+
+	db, err := chromem.NewPersistentDBWithOptions(path, options)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", map[string]string{"foo": "bar"}, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, err := range []error{
+		c.EnableContentStore(),
+		c.EnableBM25Index(chromem.BM25IndexOptions{Language: "en"}),
+		c.SetQueryDefaults(chromem.QueryDefaults{NResults: 1, Include: []chromem.IncludeField{chromem.IncludeContent}}),
+	} {
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	docs := []chromem.Document{
+		{ID: "1", Content: "hello world", Metadata: map[string]string{"lang": "en"}},
+		{ID: "2", Content: "hallo welt", Metadata: map[string]string{"lang": "de"}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*", "*.msgpack.gz"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(files) != 3 {
+		t.Fatal("expected metadata and 2 document files, got", files)
+	}
+
+	db, err = chromem.NewPersistentDBWithOptions(path, options)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	if c == nil {
+		t.Fatal("expected collection, got nil")
+	}
+	if !reflect.DeepEqual(c.Metadata(), map[string]string{"foo": "bar"}) {
+		t.Fatal("expected metadata, got", c.Metadata())
+	}
+	for _, want := range docs {
+		doc, err := c.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if doc.Content != want.Content || !reflect.DeepEqual(doc.Metadata, want.Metadata) || !reflect.DeepEqual(doc.Embedding, []float32{0.6, 0.8}) {
+			t.Fatal("expected", want, "got", doc)
+		}
+	}
+	res, err := c.QueryBM25(ctx, "world", 2, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" {
+		t.Fatal("expected document 1, got", res)
+	}
+	res, err = c.Query(ctx, "hello", 0, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 {
+		t.Fatal("expected the default number of results, got", len(res))
+	}
+}
@@ -0,0 +1,63 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetImageEmbeddingFunc sets the function that creates embeddings for documents
+// that only have binary data (see [Document.Data]), like images, and for
+// [Collection.QueryImage]. It must create embeddings in the same vector space
+// as the collection's text embedding function, like the image and text encoders
+// of a CLIP model (see for example [NewEmbeddingFuncJinaCLIP] and
+// [NewImageEmbeddingFuncJinaCLIP]). Then images and texts can be stored in the
+// same collection and queried with either.
+// A nil function removes it.
+func (c *Collection) SetImageEmbeddingFunc(embeddingFunc ImageEmbeddingFunc) {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+
+	c.embedImage = embeddingFunc
+}
+
+// getEmbedImageAndModel returns the image embedding function and the collection's
+// embedding model, read under the same lock so they're consistent.
+func (c *Collection) getEmbedImageAndModel() (ImageEmbeddingFunc, string) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.embedImage, c.embeddingModel
+}
+
+// QueryImage performs an exhaustive nearest neighbor search on the collection,
+// with an image as query. The image is embedded with the collection's image
+// embedding function (see [Collection.SetImageEmbeddingFunc]), so it finds both
+// similar images and texts that describe the image.
+//
+//   - data: The raw image, e.g. the content of a PNG or JPEG file.
+//   - mimeType: The image's MIME type, e.g. "image/png". Optional.
+//   - nResults: The number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) QueryImage(ctx context.Context, data []byte, mimeType string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if len(data) == 0 {
+		return nil, errors.New("data is empty")
+	}
+
+	// Make sure the query is embedded with the same model as the documents.
+	err := c.checkEmbeddingModel()
+	if err != nil {
+		return nil, err
+	}
+
+	embedImage, _ := c.getEmbedImageAndModel()
+	if embedImage == nil {
+		return nil, errors.New("collection has no image embedding function")
+	}
+	queryVectors, err := embedImage(embeddingContext(ctx, c.CurrentName(), EmbeddingOperationQuery, ""), data, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create embedding of query: %w: %w", ErrEmbeddingUnavailable, err)
+	}
+
+	return c.QueryEmbedding(ctx, queryVectors, nResults, where, whereDocument)
+}
@@ -0,0 +1,69 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestCollection_Multimodal(t *testing.T) {
+	ctx := context.Background()
+	cat, dog := []byte("cat.png"), []byte("dog.png")
+	embedText := func(_ context.Context, text string) ([]float32, error) {
+		if text == "a cat" {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}
+	embedImage := func(_ context.Context, data []byte, mimeType string) ([]float32, error) {
+		if mimeType != "image/png" {
+			t.Fatal("expected image/png, got", mimeType)
+		}
+		if bytes.Equal(data, cat) {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embedText)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Without image embedding func
+	err = c.AddDocument(ctx, Document{ID: "cat", Data: cat, MIMEType: "image/png"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	c.SetImageEmbeddingFunc(embedImage)
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "cat", Data: cat, MIMEType: "image/png"},
+		{ID: "dog", Data: dog, MIMEType: "image/png"},
+	}, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Text to image
+	res, err := c.Query(ctx, "a cat", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "cat" {
+		t.Fatal("expected cat, got", res[0].ID)
+	}
+
+	// Image to image
+	res, err = c.QueryImage(ctx, dog, "image/png", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "dog" {
+		t.Fatal("expected dog, got", res[0].ID)
+	}
+	if !bytes.Equal(c.documents["dog"].Data, dog) {
+		t.Fatal("expected data to be stored")
+	}
+}
@@ -0,0 +1,142 @@
+package chromem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Name of the manifest file in the DB's directory, which maps the names of the
+// collection directories to the collection names.
+const manifestFileName = "manifest.json"
+
+// Maximum length of the sanitized part of readable names, so that file names
+// stay below the limits of common file systems.
+const maxReadableNameLength = 64
+
+// persistedName returns the name of the file or directory of a collection,
+// namespace or document with the given name or ID. By default it's the hash of
+// the name (see hash2hex). Readable names are the sanitized name followed by
+// the hash, like "my_docs-1a2b3c4d", see [PersistentDBOptions.ReadableNames].
+func persistedName(name string, readable bool) string {
+	if !readable {
+		return hash2hex(name)
+	}
+	return sanitizeName(name) + "-" + hash2hex(name)
+}
+
+// sanitizeName replaces the characters that aren't safe in file names on all
+// platforms with underscores and truncates the name.
+func sanitizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if b.Len() >= maxReadableNameLength {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		case r == '.' && b.Len() > 0:
+			// Not at the start, to avoid hidden files and "..".
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// isPersistedName returns whether the name, without extension, was created by
+// persistedName, or is the hash of a content file, so that files and
+// directories of the user can be told apart.
+func isPersistedName(name string) bool {
+	if isHashName(name) {
+		return true
+	}
+	i := strings.LastIndexByte(name, '-')
+	return i >= 0 && len(name)-i-1 == 8 && isHashName(name[i+1:])
+}
+
+// checkDocPathCollision returns an error if the file of the new document with
+// the ID belongs to another document of the collection. Names can only collide
+// if both the sanitized ID and the hash are the same, so this is just a
+// safeguard. Must be called while holding the documents lock.
+func (c *Collection) checkDocPathCollision(id string) error {
+	if c.persistDirectory == "" || !c.readableNames {
+		return nil
+	}
+	if _, ok := c.documents[id]; ok {
+		return nil
+	}
+	path := c.getDocPath(id)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	doc := &Document{}
+	err := readFromFile(path, doc, c.encoding, "")
+	if err != nil {
+		return fmt.Errorf("couldn't read document file %q: %w", path, err)
+	}
+	if _, ok := c.documents[doc.ID]; ok && doc.ID != id {
+		return fmt.Errorf("file name of document '%s' collides with document '%s'", id, doc.ID)
+	}
+	// Orphaned file, e.g. after a crash, see [DB.GC].
+	return nil
+}
+
+// writeManifest writes the manifest file, if its content changed. Must be
+// called while holding collectionsLock.
+func (db *DB) writeManifest() error {
+	if db.persistDirectory == "" {
+		return nil
+	}
+	manifest := make(map[string]string, len(db.collections))
+	for _, c := range db.collections {
+		manifest[filepath.Base(c.persistDirectory)] = c.Name
+	}
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't encode manifest: %w", err)
+	}
+	b = append(b, '\n')
+
+	path := filepath.Join(db.persistDirectory, manifestFileName)
+	old, err := os.ReadFile(path)
+	if err == nil && string(old) == string(b) {
+		return nil
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("couldn't read manifest: %w", err)
+	}
+	f, err := db.perms.openFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return fmt.Errorf("couldn't create manifest: %w", err)
+	}
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't write manifest: %w", err)
+	}
+	return nil
+}
+
+// checkDirCollision returns an error if the directory of a new collection with
+// the name belongs to another collection. Must be called while holding
+// collectionsLock.
+func (db *DB) checkDirCollision(name string, readableNames bool) error {
+	if db.persistDirectory == "" {
+		return nil
+	}
+	dir := filepath.Join(db.persistDirectory, persistedName(name, readableNames))
+	for _, c := range db.collections {
+		if c.Name != name && c.persistDirectory == dir {
+			return fmt.Errorf("directory of collection '%s' collides with collection '%s'", name, c.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package chromem
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPersistedName(t *testing.T) {
+	tt := []struct {
+		name     string
+		readable bool
+		exp      string
+	}{
+		{"foo", false, hash2hex("foo")},
+		{"foo", true, "foo-" + hash2hex("foo")},
+		{"My Docs/v1.2", true, "My_Docs_v1.2-" + hash2hex("My Docs/v1.2")},
+		{"../etc", true, "_._etc-" + hash2hex("../etc")},
+		{"äö", true, "__-" + hash2hex("äö")},
+	}
+	for _, tc := range tt {
+		if res := persistedName(tc.name, tc.readable); res != tc.exp {
+			t.Fatal("expected", tc.exp, "got", res)
+		}
+		if !isPersistedName(tc.exp) {
+			t.Fatal("expected persisted name, got false for", tc.exp)
+		}
+	}
+	if isPersistedName("notes") || isPersistedName("notes-1") {
+		t.Fatal("expected user names not to be persisted names")
+	}
+}
+
+func TestNewPersistentDBWithOptions_ReadableNames(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDBWithOptions(dir, PersistentDBOptions{ReadableNames: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("my docs", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "a/b", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	colDir := "my_docs-" + hash2hex("my docs")
+	docPath := filepath.Join(dir, colDir, "a_b-"+hash2hex("a/b")+c.fileExt())
+	if _, err := os.Stat(docPath); err != nil {
+		t.Fatal("expected readable document file, got", err)
+	}
+
+	// The manifest maps the directories to the collection names.
+	b, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	manifest := map[string]string{}
+	err = json.Unmarshal(b, &manifest)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(manifest) != 1 || manifest[colDir] != "my docs" {
+		t.Fatal("expected manifest with the collection, got", manifest)
+	}
+
+	// The naming scheme is kept when the DB is opened without the option.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("my docs", nil)
+	err = c2.AddDocument(ctx, Document{ID: "c", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, colDir, "c-"+hash2hex("c")+c.fileExt())); err != nil {
+		t.Fatal("expected readable document file, got", err)
+	}
+	// And nothing is garbage.
+	report, err := db2.GC(ctx, GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(report.Files) != 0 {
+		t.Fatal("expected no orphaned files, got", report.Files)
+	}
+
+	err = db2.RenameCollection("my docs", "other")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = db2.DeleteCollection("other")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	b, err = os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if string(b) != "{}\n" {
+		t.Fatal("expected empty manifest, got", string(b))
+	}
+}
+
+func TestCollection_checkDocPathCollision(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDBWithOptions(dir, PersistentDBOptions{ReadableNames: true})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Simulate a collision by placing the file of document "1" at the path of
+	// document "2".
+	err = os.Rename(c.getDocPath("1"), c.getDocPath("2"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0, 1}})
+	if err == nil {
+		t.Fatal("expected collision error, got nil")
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+}
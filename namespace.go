@@ -0,0 +1,222 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Name of the subdirectory of a collection's directory where the namespaces
+// are persisted.
+const namespacesDirName = "namespaces"
+
+// Namespace returns the namespace with the given name, creating it if it doesn't
+// exist yet. A namespace is an isolated set of documents within a collection,
+// for example for one tenant when hosting many users in one process. It has its
+// own document map, so adding, querying and deleting documents in a namespace
+// can never affect or return documents of another namespace or the collection
+// itself, independent of the metadata and filters.
+//
+// The namespace is returned as [Collection], with the name of the namespace and
+// the embedding functions and model of the parent collection. For persistent
+// DBs, namespaces are persisted in a subdirectory of the collection's directory,
+// and loaded lazily on the first call to this method, so that only the
+// namespaces in use take up memory (see [Collection.EvictNamespace]).
+// Namespaces are not included in [DB.Export], and nesting them is not supported.
+func (c *Collection) Namespace(name string) (*Collection, error) {
+	if name == "" {
+		return nil, errors.New("namespace name is empty")
+	}
+
+	c.documentsLock.RLock()
+	embed, embedImage, model, discardContent, strict, closed := c.embed, c.embedImage, c.embeddingModel, c.discardContent, c.strict, c.closed
+	contentLimit, contentSplitter, queryFallback := c.contentLimit, c.contentSplitter, c.queryFallback
+	c.documentsLock.RUnlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	ns, ok := c.namespaces[name]
+	if !ok {
+		var err error
+		ns, err = c.loadOrCreateNamespace(name, embed)
+		if err != nil {
+			return nil, err
+		}
+		if c.namespaces == nil {
+			c.namespaces = make(map[string]*Collection)
+		}
+		c.namespaces[name] = ns
+	}
+
+	// The embedding functions can't be persisted, and the parent's might have
+	// changed since the namespace was loaded.
+	ns.documentsLock.Lock()
+	ns.embed = embed
+	ns.embedImage = embedImage
+	ns.discardContent = discardContent
+	ns.strict = strict
+	ns.contentLimit = contentLimit
+	ns.contentSplitter = contentSplitter
+	ns.queryFallback = queryFallback
+	ns.concurrency = c.concurrency
+	if ns.embeddingModel == "" {
+		ns.embeddingModel = model
+		ns.countModelMismatches()
+	}
+	ns.documentsLock.Unlock()
+
+	return ns, nil
+}
+
+// loadOrCreateNamespace must be called while holding namespacesLock.
+func (c *Collection) loadOrCreateNamespace(name string, embed EmbeddingFunc) (*Collection, error) {
+	if c.persistDirectory == "" {
+		return newCollection(name, nil, embed, "", false, nil, filePerms{}, false)
+	}
+
+	nsPath := c.getNamespacePath(name)
+	_, err := os.Stat(nsPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return newCollection(name, nil, embed, filepath.Dir(nsPath), c.compress, c.encoding, c.perms, c.readableNames)
+		}
+		return nil, fmt.Errorf("couldn't get info about namespace directory: %w", err)
+	}
+
+	err = migrateCollectionDir(nsPath, c.compress, c.encoding, c.perms)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't migrate namespace: %w", err)
+	}
+	ns, err := readCollectionDir(nsPath, c.compress, c.encoding, c.perms)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read namespace: %w", err)
+	}
+	if ns.Name == "" {
+		return nil, fmt.Errorf("namespace metadata file not found: %s", nsPath)
+	}
+	return ns, nil
+}
+
+// ListNamespaces returns the names of the collection's namespaces, including the
+// persisted ones that aren't loaded, sorted by name.
+func (c *Collection) ListNamespaces() ([]string, error) {
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+
+	if c.persistDirectory != "" {
+		dirEntries, err := os.ReadDir(filepath.Join(c.persistDirectory, namespacesDirName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("couldn't read namespaces directory: %w", err)
+		}
+		for _, dirEntry := range dirEntries {
+			if !dirEntry.IsDir() {
+				continue
+			}
+			metadataPath := filepath.Join(c.persistDirectory, namespacesDirName, dirEntry.Name(), metadataFileName+c.fileExt())
+			pc := persistedCollectionMetadata{}
+			err := readFromFile(metadataPath, &pc, c.encoding, "")
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// Likely a user-added directory
+					continue
+				}
+				return nil, fmt.Errorf("couldn't read namespace metadata: %w", err)
+			}
+			if _, ok := c.namespaces[pc.Name]; !ok {
+				names = append(names, pc.Name)
+			}
+		}
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+// NamespaceCount returns the number of documents in the namespace, without
+// loading it if it's persisted but not loaded. It's 0 if the namespace doesn't
+// exist.
+func (c *Collection) NamespaceCount(name string) (int, error) {
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	if ns, ok := c.namespaces[name]; ok {
+		return ns.Count(), nil
+	}
+	if c.persistDirectory == "" {
+		return 0, nil
+	}
+
+	dirEntries, err := os.ReadDir(c.getNamespacePath(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("couldn't read namespace directory: %w", err)
+	}
+	count := 0
+	ext := c.fileExt()
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() && strings.HasSuffix(dirEntry.Name(), ext) && dirEntry.Name() != metadataFileName+ext {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteNamespace deletes the namespace and all its documents. If the namespace
+// doesn't exist, it's a no-op.
+// You shouldn't hold any references to the namespace after calling this method.
+func (c *Collection) DeleteNamespace(name string) error {
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	if c.persistDirectory != "" {
+		err := os.RemoveAll(c.getNamespacePath(name))
+		if err != nil {
+			return fmt.Errorf("couldn't delete namespace directory: %w", err)
+		}
+	}
+
+	delete(c.namespaces, name)
+	return nil
+}
+
+// EvictNamespace removes a persisted namespace from memory, for example when its
+// tenant has been idle for a while. The next call to [Collection.Namespace]
+// loads it from disk again. It's only supported for persistent DBs, as the
+// documents would be lost otherwise. If the namespace isn't loaded, it's a no-op.
+// You shouldn't hold any references to the namespace after calling this method.
+func (c *Collection) EvictNamespace(name string) error {
+	if c.persistDirectory == "" {
+		return errors.New("evicting namespaces is only supported for persistent DBs")
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+
+	delete(c.namespaces, name)
+	return nil
+}
+
+// getNamespacePath returns the path to the namespace's directory.
+func (c *Collection) getNamespacePath(name string) string {
+	return filepath.Join(c.persistDirectory, namespacesDirName, persistedName(name, c.readableNames))
+}
+
+// fileExt returns the extension of the collection's files.
+func (c *Collection) fileExt() string {
+	return fileExt(c.encoding, c.compress)
+}
@@ -0,0 +1,129 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestCollection_Namespace(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.Namespace("")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// The same document ID in two namespaces must be isolated.
+	alice, err := c.Namespace("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	bob, err := c.Namespace("bob")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = alice.AddDocuments(ctx, []Document{{ID: "1", Content: "a"}, {ID: "2", Content: "b"}}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = bob.AddDocument(ctx, Document{ID: "1", Content: "c"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 0 || alice.Count() != 2 || bob.Count() != 1 {
+		t.Fatal("expected 0, 2, 1, got", c.Count(), alice.Count(), bob.Count())
+	}
+	res, err := bob.Query(ctx, "foo", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].Content != "c" {
+		t.Fatal("expected c, got", res[0].Content)
+	}
+	err = bob.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if alice.Count() != 2 {
+		t.Fatal("expected 2, got", alice.Count())
+	}
+
+	// Eviction and lazy loading
+	err = c.EvictNamespace("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	count, err := c.NamespaceCount("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if count != 2 {
+		t.Fatal("expected 2, got", count)
+	}
+	alice, err = c.Namespace("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if alice.Count() != 2 {
+		t.Fatal("expected 2, got", alice.Count())
+	}
+
+	// After reopening the DB
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	names, err := c.ListNamespaces()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(names, []string{"alice", "bob"}) {
+		t.Fatal("expected [alice bob], got", names)
+	}
+	if c.Count() != 0 {
+		t.Fatal("expected namespace documents not to be loaded into the collection, got", c.Count())
+	}
+
+	err = c.DeleteNamespace("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	count, err = c.NamespaceCount("alice")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if count != 0 {
+		t.Fatal("expected 0, got", count)
+	}
+
+	// Eviction requires persistence
+	c, err = NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.EvictNamespace("alice")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,108 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NormalizationPolicy defines whether a collection normalizes the embeddings
+// of documents and queries to unit length, see
+// [Collection.SetNormalizationPolicy].
+type NormalizationPolicy int
+
+const (
+	// NormalizeAlways normalizes document and query embeddings, so the
+	// similarity is the cosine similarity. This is the default.
+	NormalizeAlways NormalizationPolicy = iota
+	// NormalizeNever keeps the embeddings as they are, so the similarity is the
+	// dot product, for models whose vector magnitudes carry meaning, e.g. models
+	// trained for maximum inner product search.
+	NormalizeNever
+	// NormalizeAuto decides by the first document embedding: If it's normalized,
+	// the provider returns normalized embeddings and the collection behaves like
+	// with [NormalizeAlways], otherwise like with [NormalizeNever]. The decision
+	// is persisted and returned by [Collection.NormalizationPolicy].
+	NormalizeAuto
+)
+
+// String returns the name of the policy.
+func (p NormalizationPolicy) String() string {
+	switch p {
+	case NormalizeAlways:
+		return "always"
+	case NormalizeNever:
+		return "never"
+	case NormalizeAuto:
+		return "auto"
+	}
+	return fmt.Sprintf("NormalizationPolicy(%d)", int(p))
+}
+
+// SetNormalizationPolicy sets whether the collection normalizes the embeddings
+// of documents and queries. It must be set before adding documents, as the
+// stored embeddings aren't changed. The policy is persisted with the collection.
+//
+// Without normalization, the two-stage Matryoshka search (see
+// [Collection.SetMatryoshkaSearch]) and the early abandoning of hopeless
+// documents aren't used, as they rely on unit-length embeddings. Note that
+// most built-in embedding functions normalize the embeddings themselves.
+func (c *Collection) SetNormalizationPolicy(policy NormalizationPolicy) error {
+	switch policy {
+	case NormalizeAlways, NormalizeNever, NormalizeAuto:
+	default:
+		return fmt.Errorf("unsupported normalization policy %d", policy)
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if policy == c.normalization {
+		return nil
+	}
+	if len(c.documents) > 0 {
+		return errors.New("normalization policy can only be changed while the collection is empty")
+	}
+	c.normalization = policy
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// NormalizationPolicy returns the collection's normalization policy, see
+// [Collection.SetNormalizationPolicy].
+func (c *Collection) NormalizationPolicy() NormalizationPolicy {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.normalization
+}
+
+// normalizeDocumentEmbedding applies the normalization policy to a document
+// embedding. With [NormalizeAuto], it decides the policy by the embedding. Must
+// be called while holding the documents write lock.
+func (c *Collection) normalizeDocumentEmbedding(embedding []float32) ([]float32, error) {
+	if c.normalization == NormalizeAuto {
+		if isNormalized(embedding) {
+			c.normalization = NormalizeAlways
+		} else {
+			c.normalization = NormalizeNever
+		}
+		err := c.persistMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+	}
+	return c.normalization.apply(embedding), nil
+}
+
+// apply normalizes the embedding if the policy requires it. The policy must be
+// decided, i.e. not [NormalizeAuto].
+func (p NormalizationPolicy) apply(embedding []float32) []float32 {
+	if p == NormalizeNever || isNormalized(embedding) {
+		return embedding
+	}
+	return normalizeVector(embedding)
+}
@@ -0,0 +1,115 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollection_SetNormalizationPolicy(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.NormalizationPolicy() != NormalizeAlways {
+		t.Fatal("expected default policy always, got", c.NormalizationPolicy())
+	}
+	err = c.SetNormalizationPolicy(NormalizeNever)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The magnitude is kept, so the longer vector is more similar despite the
+	// larger angle.
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0}, Content: "one"},
+		{ID: "2", Embedding: []float32{3, 4}, Content: "two"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := c.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Embedding[0] != 3 || doc.Embedding[1] != 4 {
+		t.Fatal("expected unnormalized embedding, got", doc.Embedding)
+	}
+	res, err := c.QueryEmbedding(ctx, []float32{2, 0}, 2, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[0].Similarity != 6 || res[1].Similarity != 2 {
+		t.Fatal("expected dot product ranking, got", res)
+	}
+	batch, err := c.QueryEmbeddings(ctx, [][]float32{{2, 0}}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(batch) != 1 || len(batch[0]) != 1 || batch[0][0].ID != "2" {
+		t.Fatal("expected dot product ranking in batch, got", batch)
+	}
+
+	// The policy can't be changed once there are documents.
+	err = c.SetNormalizationPolicy(NormalizeAlways)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// The policy is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if p := db2.GetCollection("test", nil).NormalizationPolicy(); p != NormalizeNever {
+		t.Fatal("expected persisted policy never, got", p)
+	}
+
+	err = c.SetNormalizationPolicy(NormalizationPolicy(42))
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_SetNormalizationPolicy_Auto(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+
+	tt := []struct {
+		name      string
+		embedding []float32
+		exp       NormalizationPolicy
+	}{
+		{"normalized", []float32{0.6, 0.8}, NormalizeAlways},
+		{"unnormalized", []float32{3, 4}, NormalizeNever},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := db.CreateCollection(tc.name, nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.SetNormalizationPolicy(NormalizeAuto)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			err = c.AddDocument(ctx, Document{ID: "1", Embedding: tc.embedding})
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if p := c.NormalizationPolicy(); p != tc.exp {
+				t.Fatal("expected", tc.exp, "got", p)
+			}
+		})
+	}
+}
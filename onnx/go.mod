@@ -0,0 +1,8 @@
+module github.com/philippgille/chromem-go/onnx
+
+go 1.21
+
+require (
+	github.com/yalue/onnxruntime_go v1.27.0
+	golang.org/x/text v0.14.0
+)
@@ -0,0 +1,193 @@
+// Package onnx provides an in-process embedding function for chromem-go, based
+// on the ONNX Runtime. With it, chromem-go can run fully self-contained, without
+// any embedding API or separate process like Ollama.
+//
+// It's a separate Go module, so that the main chromem-go module stays free of
+// third-party dependencies. It requires cgo and the ONNX Runtime shared library
+// (e.g. "libonnxruntime.so"), see https://github.com/microsoft/onnxruntime/releases.
+//
+// It works with BERT-based sentence embedding models that are exported to ONNX,
+// like "sentence-transformers/all-MiniLM-L6-v2" or "BAAI/bge-small-en-v1.5",
+// and their WordPiece vocabulary ("vocab.txt").
+package onnx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+const defaultMaxTokens = 512
+
+// The ONNX Runtime environment is global, so we initialize it only once.
+var initEnvironment = sync.OnceValue(func() error {
+	if ort.IsInitialized() {
+		return nil
+	}
+	return ort.InitializeEnvironment()
+})
+
+// Options configure the embedding function.
+type Options struct {
+	// SharedLibraryPath is the path to the ONNX Runtime shared library.
+	// Optional, if empty the library is looked up in the OS' default locations.
+	// As the ONNX Runtime environment is global, only the path of the first
+	// call to [NewEmbeddingFunc] is used.
+	SharedLibraryPath string
+	// MaxTokens is the maximum number of tokens (including the special ones) per
+	// text. Longer texts are truncated. Optional, defaults to 512, which is the
+	// maximum for most BERT models.
+	MaxTokens int
+	// CaseSensitive disables lowercasing and accent stripping of the text, which
+	// is required for "cased" models. Most sentence embedding models are uncased.
+	CaseSensitive bool
+}
+
+// NewEmbeddingFunc returns a function that creates embeddings for a text using
+// an ONNX model in-process. The returned function is compatible with
+// chromem.EmbeddingFunc and safe for concurrent use.
+// The returned close function releases the model. Don't use the embedding
+// function after calling it.
+//
+// The embeddings are the mean of the model's token embeddings ("mean pooling"),
+// normalized to a length of 1, like sentence-transformers does it.
+//
+//   - modelPath: Path to the ONNX model file, e.g. "model.onnx".
+//   - vocabPath: Path to the model's WordPiece vocabulary, e.g. "vocab.txt".
+func NewEmbeddingFunc(modelPath, vocabPath string, opts Options) (func(ctx context.Context, text string) ([]float32, error), func() error, error) {
+	if modelPath == "" {
+		return nil, nil, errors.New("model path is empty")
+	}
+	if vocabPath == "" {
+		return nil, nil, errors.New("vocabulary path is empty")
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultMaxTokens
+	} else if opts.MaxTokens < 2 {
+		return nil, nil, errors.New("max tokens must be at least 2")
+	}
+
+	tokenizer, err := newWordPieceTokenizer(vocabPath, !opts.CaseSensitive)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't create tokenizer: %w", err)
+	}
+
+	if opts.SharedLibraryPath != "" && !ort.IsInitialized() {
+		ort.SetSharedLibraryPath(opts.SharedLibraryPath)
+	}
+	err = initEnvironment()
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't initialize ONNX Runtime: %w", err)
+	}
+
+	// Some exported models don't have the token type input, so we check which
+	// inputs the model has.
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read model inputs and outputs: %w", err)
+	}
+	inputNames := []string{"input_ids", "attention_mask"}
+	withTokenTypes := slices.ContainsFunc(inputInfo, func(info ort.InputOutputInfo) bool {
+		return info.Name == "token_type_ids"
+	})
+	if withTokenTypes {
+		inputNames = append(inputNames, "token_type_ids")
+	}
+	if len(outputInfo) == 0 {
+		return nil, nil, errors.New("model has no outputs")
+	}
+	// The first output is the last hidden state in sentence-transformers exports.
+	outputNames := []string{outputInfo[0].Name}
+
+	session, err := ort.NewDynamicAdvancedSession(modelPath, inputNames, outputNames, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't create ONNX session: %w", err)
+	}
+
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		// Inference can't be canceled, but at least we don't start it.
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		ids := tokenizer.tokenize(text, opts.MaxTokens)
+		mask := make([]int64, len(ids))
+		for i := range mask {
+			mask[i] = 1
+		}
+		shape := ort.NewShape(1, int64(len(ids)))
+
+		var inputs []ort.Value
+		defer func() {
+			for _, v := range inputs {
+				v.Destroy()
+			}
+		}()
+		for _, data := range [][]int64{ids, mask} {
+			tensor, err := ort.NewTensor(shape, data)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create input tensor: %w", err)
+			}
+			inputs = append(inputs, tensor)
+		}
+		if withTokenTypes {
+			tensor, err := ort.NewTensor(shape, make([]int64, len(ids)))
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create input tensor: %w", err)
+			}
+			inputs = append(inputs, tensor)
+		}
+
+		// A nil output is allocated by the session.
+		outputs := []ort.Value{nil}
+		err := session.Run(inputs, outputs)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't run model: %w", err)
+		}
+		defer outputs[0].Destroy()
+
+		output, ok := outputs[0].(*ort.Tensor[float32])
+		if !ok {
+			return nil, errors.New("unexpected model output type, expected float32 tensor")
+		}
+		outShape := output.GetShape()
+		if len(outShape) != 3 || outShape[1] != int64(len(ids)) {
+			return nil, fmt.Errorf("unexpected model output shape %v, expected [1, %d, dimensions]", outShape, len(ids))
+		}
+
+		// The tensor data is owned by the ONNX Runtime, so we create a new slice.
+		return meanPool(output.GetData(), len(ids), int(outShape[2])), nil
+	}
+
+	return embed, session.Destroy, nil
+}
+
+// meanPool averages the token embeddings and normalizes the result.
+// All tokens are attended to, as we embed one text at a time without padding.
+func meanPool(hidden []float32, numTokens, dimensions int) []float32 {
+	v := make([]float32, dimensions)
+	for t := 0; t < numTokens; t++ {
+		for d := 0; d < dimensions; d++ {
+			v[d] += hidden[t*dimensions+d]
+		}
+	}
+
+	var norm float64
+	for d := range v {
+		v[d] /= float32(numTokens)
+		norm += float64(v[d]) * float64(v[d])
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for d := range v {
+			v[d] = float32(float64(v[d]) / norm)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,174 @@
+package onnx
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	tokenCLS = "[CLS]"
+	tokenSEP = "[SEP]"
+	tokenUNK = "[UNK]"
+
+	// Words longer than this are mapped to the unknown token, like in the
+	// original BERT implementation.
+	maxWordLength = 100
+)
+
+// wordPieceTokenizer is a BERT-style WordPiece tokenizer, as used by most
+// sentence embedding models like all-MiniLM-L6-v2 and bge-small-en.
+type wordPieceTokenizer struct {
+	vocab     map[string]int64
+	lowercase bool
+	clsID     int64
+	sepID     int64
+	unkID     int64
+}
+
+// newWordPieceTokenizer reads the vocabulary from a "vocab.txt" file, which
+// contains one token per line, with the line number being the token ID.
+func newWordPieceTokenizer(vocabPath string, lowercase bool) (*wordPieceTokenizer, error) {
+	f, err := os.Open(vocabPath)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open vocabulary file: %w", err)
+	}
+	defer f.Close()
+
+	vocab := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	var id int64
+	for scanner.Scan() {
+		vocab[strings.TrimRight(scanner.Text(), "\r")] = id
+		id++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read vocabulary file: %w", err)
+	}
+
+	return newWordPieceTokenizerFromVocab(vocab, lowercase)
+}
+
+func newWordPieceTokenizerFromVocab(vocab map[string]int64, lowercase bool) (*wordPieceTokenizer, error) {
+	t := &wordPieceTokenizer{
+		vocab:     vocab,
+		lowercase: lowercase,
+	}
+	for token, id := range map[string]*int64{tokenCLS: &t.clsID, tokenSEP: &t.sepID, tokenUNK: &t.unkID} {
+		v, ok := vocab[token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary doesn't contain the special token %s", token)
+		}
+		*id = v
+	}
+	return t, nil
+}
+
+// tokenize returns the token IDs of the text, including the [CLS] and [SEP]
+// tokens, truncated to maxTokens.
+func (t *wordPieceTokenizer) tokenize(text string, maxTokens int) []int64 {
+	ids := []int64{t.clsID}
+	for _, word := range t.splitWords(text) {
+		ids = append(ids, t.wordPieces(word)...)
+		if len(ids) >= maxTokens-1 {
+			ids = ids[:maxTokens-1]
+			break
+		}
+	}
+	return append(ids, t.sepID)
+}
+
+// splitWords cleans the text and splits it on whitespace and punctuation.
+// Chinese, Japanese and Korean characters become words of their own.
+func (t *wordPieceTokenizer) splitWords(text string) []string {
+	if t.lowercase {
+		text = strings.ToLower(text)
+		// Strip accents, like the original BERT uncased models.
+		text = norm.NFD.String(text)
+	}
+
+	var words []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+			continue
+		case t.lowercase && unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsSpace(r):
+			flush()
+		case isPunctuation(r) || isCJK(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// wordPieces splits a word into the longest matching vocabulary entries,
+// greedily from the start. Non-initial pieces are prefixed with "##".
+func (t *wordPieceTokenizer) wordPieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordLength {
+		return []int64{t.unkID}
+	}
+
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := t.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			// The whole word is unknown then.
+			return []int64{t.unkID}
+		}
+		start = end
+	}
+	return ids
+}
+
+// isPunctuation treats all non-alphanumeric ASCII characters as punctuation,
+// like the original BERT implementation, plus Unicode punctuation.
+func isPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
+
+// isCJK checks whether the rune is in the CJK Unicode blocks (not including
+// Hangul and Japanese kana, which are written with spaces).
+func isCJK(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) ||
+		(r >= 0x3400 && r <= 0x4DBF) ||
+		(r >= 0x20000 && r <= 0x2A6DF) ||
+		(r >= 0x2A700 && r <= 0x2B73F) ||
+		(r >= 0x2B740 && r <= 0x2B81F) ||
+		(r >= 0x2B820 && r <= 0x2CEAF) ||
+		(r >= 0xF900 && r <= 0xFAFF) ||
+		(r >= 0x2F800 && r <= 0x2FA1F)
+}
@@ -0,0 +1,99 @@
+package onnx
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestWordPieceTokenizer(t *testing.T) {
+	vocab := map[string]int64{
+		"[PAD]": 0,
+		"[UNK]": 1,
+		"[CLS]": 2,
+		"[SEP]": 3,
+		"the":   4,
+		"sky":   5,
+		"is":    6,
+		"blue":  7,
+		"!":     8,
+		"emb":   9,
+		"##edd": 10,
+		"##ing": 11,
+		"cafe":  12,
+		"中":     13,
+	}
+	tok, err := newWordPieceTokenizerFromVocab(vocab, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []int64
+	}{
+		{
+			name:      "Words and punctuation",
+			text:      "The sky is  blue!",
+			maxTokens: 512,
+			want:      []int64{2, 4, 5, 6, 7, 8, 3},
+		},
+		{
+			name:      "Word pieces",
+			text:      "embedding",
+			maxTokens: 512,
+			want:      []int64{2, 9, 10, 11, 3},
+		},
+		{
+			name:      "Unknown word",
+			text:      "embeddingx the",
+			maxTokens: 512,
+			want:      []int64{2, 1, 4, 3},
+		},
+		{
+			name:      "Accents and CJK",
+			text:      "Café中",
+			maxTokens: 512,
+			want:      []int64{2, 12, 13, 3},
+		},
+		{
+			name:      "Truncation",
+			text:      "the sky is blue",
+			maxTokens: 4,
+			want:      []int64{2, 4, 5, 3},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tok.tokenize(tc.text, tc.maxTokens)
+			if !slices.Equal(got, tc.want) {
+				t.Fatal("expected", tc.want, "got", got)
+			}
+		})
+	}
+
+	// Missing special tokens
+	_, err = newWordPieceTokenizerFromVocab(map[string]int64{"the": 0}, true)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestMeanPool(t *testing.T) {
+	hidden := []float32{
+		1, 0,
+		3, 0,
+		2, 3,
+	}
+	got := meanPool(hidden, 3, 2)
+	// Mean is {2, 1}, normalized by sqrt(5)
+	want := []float32{float32(2 / math.Sqrt(5)), float32(1 / math.Sqrt(5))}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Fatal("expected", want, "got", got)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package chromem
+
+import (
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// Number of documents per row group of Parquet exports. Each row group is
+// encoded in memory before it's written, so this limits the memory usage.
+const parquetRowGroupSize = 10000
+
+const parquetMagic = "PAR1"
+
+// Parquet enum values, see https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	parquetTypeFloat     = 4
+	parquetTypeByteArray = 6
+
+	parquetRepetitionRequired = 0
+	parquetRepetitionRepeated = 2
+
+	parquetConvertedUTF8 = 0
+	parquetConvertedList = 3
+	parquetConvertedJSON = 19
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecUncompressed = 0
+	parquetPageTypeData      = 0
+)
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// ExportParquet writes the collection's documents to the writer as a Parquet
+// file, so that they can be analyzed with tools like DuckDB or Spark, or imported
+// into other vector stores. The file has the following columns:
+//
+//   - id: The document ID, as string.
+//   - content: The document content, as string. It's empty for collections that
+//     discard the content, and encrypted if the content encryption is enabled,
+//     like in exports of the DB.
+//   - metadata: The document metadata, as JSON object string.
+//   - embedding: The document embedding, as list of floats.
+//
+// The rows are sorted by document ID. The data isn't compressed.
+// If the writer has to be closed, it's the caller's responsibility.
+func (c *Collection) ExportParquet(w io.Writer) error {
+	c.documentsLock.RLock()
+	if c.closed {
+		c.documentsLock.RUnlock()
+		return ErrClosed
+	}
+	documents, err := c.documentsWithContent()
+	if err != nil {
+		c.documentsLock.RUnlock()
+		return fmt.Errorf("couldn't export collection '%s': %w", c.CurrentName(), err)
+	}
+	// Documents are replaced instead of modified, so they can be written after
+	// releasing the lock.
+	docs := make([]*Document, 0, len(documents))
+	for _, doc := range documents {
+		docs = append(docs, doc)
+	}
+	c.documentsLock.RUnlock()
+	slices.SortFunc(docs, func(a, b *Document) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	pw := &parquetWriter{w: w}
+	err = pw.write([]byte(parquetMagic))
+	if err != nil {
+		return fmt.Errorf("couldn't write Parquet header: %w", err)
+	}
+	var rowGroups [][]parquetColumnChunk
+	for start := 0; start < len(docs); start += parquetRowGroupSize {
+		rowGroup, err := pw.writeRowGroup(docs[start:min(start+parquetRowGroupSize, len(docs))])
+		if err != nil {
+			return fmt.Errorf("couldn't write Parquet row group: %w", err)
+		}
+		rowGroups = append(rowGroups, rowGroup)
+	}
+	footer := parquetFileMetadata(len(docs), rowGroups, parquetRowGroupSize)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	footer = append(footer, parquetMagic...)
+	err = pw.write(footer)
+	if err != nil {
+		return fmt.Errorf("couldn't write Parquet footer: %w", err)
+	}
+
+	return nil
+}
+
+// parquetWriter writes a Parquet file and keeps track of the offset, which is
+// needed for the file metadata.
+type parquetWriter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (pw *parquetWriter) write(p []byte) error {
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	return err
+}
+
+// parquetColumnChunk is the metadata of a column chunk, which consists of a
+// single data page.
+type parquetColumnChunk struct {
+	typ       int32
+	path      []string
+	numValues int
+	offset    int64
+	size      int
+}
+
+// writeRowGroup writes the documents as row group with one data page per column.
+func (pw *parquetWriter) writeRowGroup(docs []*Document) ([]parquetColumnChunk, error) {
+	var id, content, metadata []byte
+	for _, doc := range docs {
+		id = appendParquetByteArray(id, doc.ID)
+		content = appendParquetByteArray(content, doc.Content)
+		m, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't marshal metadata of document '%s': %w", doc.ID, err)
+		}
+		if doc.Metadata == nil {
+			m = []byte("{}")
+		}
+		metadata = appendParquetByteArray(metadata, string(m))
+	}
+
+	// The embedding is a list, so each value needs a repetition level (0 for the
+	// first value of a row, 1 for the following ones) and a definition level (0
+	// for an empty list, 1 for a value).
+	var rep, def levelEncoder
+	var values []byte
+	numValues := 0
+	for _, doc := range docs {
+		if len(doc.Embedding) == 0 {
+			rep.add(0, 1)
+			def.add(0, 1)
+			numValues++
+			continue
+		}
+		rep.add(0, 1)
+		rep.add(1, len(doc.Embedding)-1)
+		def.add(1, len(doc.Embedding))
+		for _, v := range doc.Embedding {
+			values = binary.LittleEndian.AppendUint32(values, math.Float32bits(v))
+		}
+		numValues += len(doc.Embedding)
+	}
+	embedding := rep.appendTo(nil)
+	embedding = def.appendTo(embedding)
+	embedding = append(embedding, values...)
+
+	chunks := []parquetColumnChunk{
+		{typ: parquetTypeByteArray, path: []string{"id"}, numValues: len(docs)},
+		{typ: parquetTypeByteArray, path: []string{"content"}, numValues: len(docs)},
+		{typ: parquetTypeByteArray, path: []string{"metadata"}, numValues: len(docs)},
+		{typ: parquetTypeFloat, path: []string{"embedding", "list", "element"}, numValues: numValues},
+	}
+	for i, data := range [][]byte{id, content, metadata, embedding} {
+		header := parquetPageHeader(chunks[i].numValues, len(data))
+		chunks[i].offset = pw.offset
+		chunks[i].size = len(header) + len(data)
+		err := pw.write(header)
+		if err == nil {
+			err = pw.write(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't write column '%s': %w", chunks[i].path[0], err)
+		}
+	}
+	return chunks, nil
+}
+
+// appendParquetByteArray appends the string in the plain encoding of byte arrays,
+// which is the length as 4 byte little endian integer followed by the bytes.
+func appendParquetByteArray(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// levelEncoder encodes repetition or definition levels with a bit width of 1 in
+// the RLE/bit-packing hybrid encoding, using only RLE runs.
+type levelEncoder struct {
+	runs  []byte
+	value uint8
+	count int
+}
+
+func (e *levelEncoder) add(value uint8, count int) {
+	if count == 0 {
+		return
+	}
+	if value != e.value && e.count > 0 {
+		e.flush()
+	}
+	e.value = value
+	e.count += count
+}
+
+func (e *levelEncoder) flush() {
+	e.runs = binary.AppendUvarint(e.runs, uint64(e.count)<<1)
+	e.runs = append(e.runs, e.value)
+	e.count = 0
+}
+
+// appendTo appends the encoded levels, prefixed by their length as 4 byte little
+// endian integer.
+func (e *levelEncoder) appendTo(b []byte) []byte {
+	if e.count > 0 {
+		e.flush()
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(e.runs)))
+	return append(b, e.runs...)
+}
+
+func parquetPageHeader(numValues, size int) []byte {
+	t := newThriftWriter()
+	t.i32Field(1, parquetPageTypeData)
+	t.i32Field(2, int32(size))
+	t.i32Field(3, int32(size))
+	t.structField(5)
+	t.i32Field(1, int32(numValues))
+	t.i32Field(2, parquetEncodingPlain)
+	t.i32Field(3, parquetEncodingRLE)
+	t.i32Field(4, parquetEncodingRLE)
+	t.structEnd()
+	t.structEnd()
+	return t.buf
+}
+
+func parquetFileMetadata(numRows int, rowGroups [][]parquetColumnChunk, rowGroupSize int) []byte {
+	t := newThriftWriter()
+	t.i32Field(1, 1) // version
+
+	type schemaElement struct {
+		typ, repetition, convertedType int32 // -1 if unset
+		name                           string
+		numChildren                    int32
+	}
+	schema := []schemaElement{
+		{typ: -1, repetition: -1, convertedType: -1, name: "schema", numChildren: 4},
+		{typ: parquetTypeByteArray, repetition: parquetRepetitionRequired, convertedType: parquetConvertedUTF8, name: "id"},
+		{typ: parquetTypeByteArray, repetition: parquetRepetitionRequired, convertedType: parquetConvertedUTF8, name: "content"},
+		{typ: parquetTypeByteArray, repetition: parquetRepetitionRequired, convertedType: parquetConvertedJSON, name: "metadata"},
+		{typ: -1, repetition: parquetRepetitionRequired, convertedType: parquetConvertedList, name: "embedding", numChildren: 1},
+		{typ: -1, repetition: parquetRepetitionRepeated, convertedType: -1, name: "list", numChildren: 1},
+		{typ: parquetTypeFloat, repetition: parquetRepetitionRequired, convertedType: -1, name: "element"},
+	}
+	t.listField(2, thriftStruct, len(schema))
+	for _, e := range schema {
+		t.structBegin()
+		if e.typ >= 0 {
+			t.i32Field(1, e.typ)
+		}
+		if e.repetition >= 0 {
+			t.i32Field(3, e.repetition)
+		}
+		t.binaryField(4, e.name)
+		if e.numChildren > 0 {
+			t.i32Field(5, e.numChildren)
+		}
+		if e.convertedType >= 0 {
+			t.i32Field(6, e.convertedType)
+		}
+		t.structEnd()
+	}
+
+	t.i64Field(3, int64(numRows))
+	t.listField(4, thriftStruct, len(rowGroups))
+	for i, chunks := range rowGroups {
+		t.structBegin()
+		t.listField(1, thriftStruct, len(chunks))
+		totalSize := 0
+		for _, chunk := range chunks {
+			t.structBegin()
+			t.i64Field(2, chunk.offset)
+			t.structField(3)
+			t.i32Field(1, chunk.typ)
+			t.listField(2, thriftI32, 2)
+			t.i32(parquetEncodingPlain)
+			t.i32(parquetEncodingRLE)
+			t.listField(3, thriftBinary, len(chunk.path))
+			for _, p := range chunk.path {
+				t.binary(p)
+			}
+			t.i32Field(4, parquetCodecUncompressed)
+			t.i64Field(5, int64(chunk.numValues))
+			t.i64Field(6, int64(chunk.size))
+			t.i64Field(7, int64(chunk.size))
+			t.i64Field(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+			totalSize += chunk.size
+		}
+		t.i64Field(2, int64(totalSize))
+		t.i64Field(3, int64(min(rowGroupSize, numRows-i*rowGroupSize)))
+		t.structEnd()
+	}
+	t.binaryField(6, "chromem-go")
+	t.structEnd()
+	return t.buf
+}
+
+// thriftWriter encodes structs with the Thrift compact protocol, which is used
+// for the Parquet metadata. The outermost struct is begun implicitly.
+type thriftWriter struct {
+	buf []byte
+	// Last field ID per nested struct, as field IDs are encoded as delta.
+	lastFieldIDs []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{lastFieldIDs: []int16{0}}
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastFieldIDs[len(t.lastFieldIDs)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(v int32) {
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) binary(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.i32(v)
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) binaryField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(s)
+}
+
+// listField writes the header of a list field, which must be followed by size
+// elements of the given type.
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+	} else {
+		t.buf = append(t.buf, 0xf0|elemType)
+		t.buf = binary.AppendUvarint(t.buf, uint64(size))
+	}
+}
+
+// structField begins a struct field, which must be ended with structEnd.
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+}
+
+// structBegin begins a struct as list element, which must be ended with structEnd.
+func (t *thriftWriter) structBegin() {
+	t.lastFieldIDs = append(t.lastFieldIDs, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf = append(t.buf, 0)
+	t.lastFieldIDs = t.lastFieldIDs[:len(t.lastFieldIDs)-1]
+}
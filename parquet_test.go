@@ -0,0 +1,168 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestCollection_ExportParquet(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "2", Content: "world", Embedding: []float32{0, 1}},
+		{ID: "1", Content: "hello", Metadata: map[string]string{"lang": "en"}, Embedding: []float32{1, 0}},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	var buf bytes.Buffer
+	err = c.ExportParquet(&buf)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, []byte(parquetMagic)) || !bytes.HasSuffix(file, []byte(parquetMagic)) {
+		t.Fatal("expected Parquet magic at start and end")
+	}
+
+	// Decode the footer
+	footerLen := int(binary.LittleEndian.Uint32(file[len(file)-8:]))
+	footer, _ := readThriftStruct(t, file[len(file)-8-footerLen:])
+	if footer[3] != int64(2) {
+		t.Fatal("expected 2 rows, got", footer[3])
+	}
+	var names []string
+	for _, e := range footer[2].([]any) {
+		names = append(names, string(e.(map[int16]any)[4].([]byte)))
+	}
+	if !slices.Equal(names, []string{"schema", "id", "content", "metadata", "embedding", "list", "element"}) {
+		t.Fatal("expected schema, got", names)
+	}
+
+	// Decode the columns
+	rowGroups := footer[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatal("expected 1 row group, got", len(rowGroups))
+	}
+	var columns [][]byte
+	for _, chunk := range rowGroups[0].(map[int16]any)[1].([]any) {
+		offset := chunk.(map[int16]any)[3].(map[int16]any)[9].(int64)
+		header, n := readThriftStruct(t, file[offset:])
+		size := header[3].(int64)
+		columns = append(columns, file[offset+int64(n):offset+int64(n)+size])
+	}
+	readByteArrays := func(b []byte) []string {
+		var res []string
+		for len(b) > 0 {
+			l := binary.LittleEndian.Uint32(b)
+			res = append(res, string(b[4:4+l]))
+			b = b[4+l:]
+		}
+		return res
+	}
+	if ids := readByteArrays(columns[0]); !slices.Equal(ids, []string{"1", "2"}) {
+		t.Fatal("expected sorted IDs, got", ids)
+	}
+	if contents := readByteArrays(columns[1]); !slices.Equal(contents, []string{"hello", "world"}) {
+		t.Fatal("expected contents, got", contents)
+	}
+	if metadata := readByteArrays(columns[2]); !slices.Equal(metadata, []string{`{"lang":"en"}`, "{}"}) {
+		t.Fatal("expected metadata, got", metadata)
+	}
+
+	// The embedding column starts with the repetition and definition levels:
+	// 1x0, 1x1, 1x0, 1x1 and 4x1
+	embedding := columns[3]
+	repLen := binary.LittleEndian.Uint32(embedding)
+	rep := embedding[4 : 4+repLen]
+	if !bytes.Equal(rep, []byte{2, 0, 2, 1, 2, 0, 2, 1}) {
+		t.Fatal("expected repetition levels, got", rep)
+	}
+	embedding = embedding[4+repLen:]
+	defLen := binary.LittleEndian.Uint32(embedding)
+	def := embedding[4 : 4+defLen]
+	if !bytes.Equal(def, []byte{8, 1}) {
+		t.Fatal("expected definition levels, got", def)
+	}
+	embedding = embedding[4+defLen:]
+	var values []float32
+	for i := 0; i < len(embedding); i += 4 {
+		values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(embedding[i:])))
+	}
+	if !slices.Equal(values, []float32{1, 0, 0, 1}) {
+		t.Fatal("expected embedding values, got", values)
+	}
+}
+
+// readThriftStruct decodes a struct in the Thrift compact protocol, with the
+// subset of types that's used by Parquet. It returns the fields by ID and the
+// number of bytes read.
+func readThriftStruct(t *testing.T, b []byte) (map[int16]any, int) {
+	t.Helper()
+	pos := 0
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(b[pos:])
+		pos += n
+		return v
+	}
+	varint := func() int64 {
+		v, n := binary.Varint(b[pos:])
+		pos += n
+		return v
+	}
+	var readValue func(typ byte) any
+	readValue = func(typ byte) any {
+		switch typ {
+		case thriftI32, thriftI64:
+			return varint()
+		case thriftBinary:
+			l := int(uvarint())
+			pos += l
+			return b[pos-l : pos]
+		case thriftList:
+			header := b[pos]
+			pos++
+			size := int(header >> 4)
+			if size == 15 {
+				size = int(uvarint())
+			}
+			list := make([]any, size)
+			for i := range list {
+				list[i] = readValue(header & 0x0f)
+			}
+			return list
+		case thriftStruct:
+			s, n := readThriftStruct(t, b[pos:])
+			pos += n
+			return s
+		}
+		t.Fatal("unexpected Thrift type", typ)
+		return nil
+	}
+
+	fields := make(map[int16]any)
+	var lastID int16
+	for {
+		header := b[pos]
+		pos++
+		if header == 0 {
+			return fields, pos
+		}
+		id := lastID + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(varint())
+		}
+		fields[id] = readValue(header & 0x0f)
+		lastID = id
+	}
+}
@@ -0,0 +1,113 @@
+package chromem
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// maxCachedPatterns is the maximum number of compiled $regex and $glob patterns
+// in the cache. When it's full, it's cleared, which is cheap and good enough for
+// the typical case of a few patterns that are used in many queries.
+const maxCachedPatterns = 1000
+
+var patternCache = struct {
+	lock     sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// compilePattern returns the compiled regular expression for the value of a
+// "$regex" or "$glob" whereDocument filter. Compiled patterns are cached, so
+// that they aren't compiled again for each document and query.
+func compilePattern(operator, pattern string, ignoreCase bool) (*regexp.Regexp, error) {
+	key := operator + "\x00" + pattern
+	if ignoreCase {
+		key = "i" + key
+	}
+	patternCache.lock.Lock()
+	re, ok := patternCache.patterns[key]
+	patternCache.lock.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	expr := pattern
+	if operator == "$glob" {
+		expr = globToRegexp(pattern)
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s pattern '%s': %w", operator, pattern, err)
+	}
+
+	patternCache.lock.Lock()
+	if len(patternCache.patterns) >= maxCachedPatterns {
+		clear(patternCache.patterns)
+	}
+	patternCache.patterns[key] = re
+	patternCache.lock.Unlock()
+	return re, nil
+}
+
+// globToRegexp translates a glob pattern to an RE2 expression that matches the
+// whole text. "*" matches any sequence of characters, including newlines, "?"
+// matches any single character, "[...]" matches a character class ("[!...]"
+// negated) and "\" escapes the next character.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString(`(?s)\A`)
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '!' || runes[end] == '^') {
+				end++
+			}
+			// A "]" directly after the opening bracket is part of the class.
+			if end < len(runes) && runes[end] == ']' {
+				end++
+			}
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end >= len(runes) {
+				// Unclosed bracket, so it's a literal.
+				sb.WriteString(`\[`)
+				continue
+			}
+			sb.WriteByte('[')
+			class := runes[i+1 : end]
+			if class[0] == '!' || class[0] == '^' {
+				sb.WriteByte('^')
+				class = class[1:]
+			}
+			for _, c := range class {
+				// Only "-" keeps its meaning in the class.
+				if c == '\\' || c == '[' || c == ']' || c == '^' {
+					sb.WriteByte('\\')
+				}
+				sb.WriteRune(c)
+			}
+			sb.WriteByte(']')
+			i = end
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(`\z`)
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package chromem
+
+import "testing"
+
+func TestGlobToRegexp(t *testing.T) {
+	tt := []struct {
+		glob  string
+		text  string
+		match bool
+	}{
+		{"ERR-*", "ERR-42: disk full", true},
+		{"ERR-*", "WARN ERR-42", false},
+		{"*disk*", "ERR-42: disk\nfull", true},
+		{"ERR-??", "ERR-42", true},
+		{"ERR-??", "ERR-420", false},
+		{"ERR-[0-9][0-9]", "ERR-42", true},
+		{"ERR-[!0-9]*", "ERR-42", false},
+		{"ERR-[!0-9]*", "ERR-X1", true},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+		{"[]]", "]", true},
+		{"a[b", "a[b", true},
+	}
+
+	for _, tc := range tt {
+		re, err := compilePattern("$glob", tc.glob, false)
+		if err != nil {
+			t.Fatal("expected nil, got", err)
+		}
+		if re.MatchString(tc.text) != tc.match {
+			t.Fatal("expected", tc.match, "for", tc.glob, "and", tc.text)
+		}
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	re, err := compilePattern("$regex", `E\d{3}`, true)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if !re.MatchString("code e123") {
+		t.Fatal("expected case-insensitive match")
+	}
+	// The compiled pattern is cached.
+	re2, err := compilePattern("$regex", `E\d{3}`, true)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if re != re2 {
+		t.Fatal("expected cached pattern")
+	}
+
+	_, err = compilePattern("$regex", "(", false)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
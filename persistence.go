@@ -7,7 +7,6 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
-	"encoding/gob"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -27,11 +26,84 @@ func hash2hex(name string) string {
 	return hex.EncodeToString(hash[:4])
 }
 
+// filePerms are the permissions of the files and directories of a persistent
+// DB, see [PersistentDBOptions]. The zero value uses the default permissions.
+type filePerms struct {
+	file fs.FileMode
+	dir  fs.FileMode
+	// exact sets the permissions regardless of the process's umask.
+	exact bool
+}
+
+// newFilePerms returns the permissions configured in the options.
+func newFilePerms(options PersistentDBOptions) filePerms {
+	return filePerms{
+		file:  options.FileMode,
+		dir:   options.DirMode,
+		exact: options.IgnoreUmask,
+	}
+}
+
+// openFile is like [os.OpenFile], with the configured file mode, or def if
+// there's none.
+func (p filePerms) openFile(path string, flag int, def fs.FileMode) (*os.File, error) {
+	mode := def
+	if p.file != 0 {
+		mode = p.file
+	}
+	f, err := os.OpenFile(path, flag, mode)
+	if err != nil {
+		return nil, err
+	}
+	if p.exact && p.file != 0 {
+		// Also applies the mode to existing files, which keep theirs otherwise.
+		err = f.Chmod(mode)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// mkdirAll is like [os.MkdirAll], with the configured directory mode, or 0o700
+// if there's none.
+func (p filePerms) mkdirAll(path string) error {
+	mode := fs.FileMode(0o700)
+	if p.dir != 0 {
+		mode = p.dir
+	}
+	if !p.exact || p.dir == 0 {
+		return os.MkdirAll(path, mode)
+	}
+
+	// Remember the directories that don't exist yet, to set their mode
+	// regardless of the umask after creating them.
+	var missing []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	err := os.MkdirAll(path, mode)
+	if err != nil {
+		return err
+	}
+	for _, dir := range missing {
+		err := os.Chmod(dir, mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // persistToFile persists an object to a file at the given path. The object is serialized
-// as gob, optionally compressed with flate (as gzip) and optionally encrypted with
-// AES-GCM. The encryption key must be 32 bytes long. If the file exists, it's
-// overwritten, otherwise created.
-func persistToFile(filePath string, obj any, compress bool, encryptionKey string) error {
+// with the encoding (gob if nil), optionally compressed with flate (as gzip) and
+// optionally encrypted with AES-GCM. The encryption key must be 32 bytes long.
+// If the file exists, it's overwritten, otherwise created with the permissions.
+func persistToFile(filePath string, obj any, encoding Encoding, compress bool, encryptionKey string, perms filePerms) error {
 	if filePath == "" {
 		return fmt.Errorf("file path is empty")
 	}
@@ -50,7 +122,7 @@ func persistToFile(filePath string, obj any, compress bool, encryptionKey string
 			return fmt.Errorf("couldn't get info about the path: %w", err)
 		} else {
 			// If the file doesn't exist, create the parent path
-			err := os.MkdirAll(filepath.Dir(filePath), 0o700)
+			err := perms.mkdirAll(filepath.Dir(filePath))
 			if err != nil {
 				return fmt.Errorf("couldn't create parent directories to path: %w", err)
 			}
@@ -60,20 +132,32 @@ func persistToFile(filePath string, obj any, compress bool, encryptionKey string
 	}
 
 	// Open file for writing
-	f, err := os.Create(filePath)
+	f, err := perms.openFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
 	if err != nil {
 		return fmt.Errorf("couldn't create file: %w", err)
 	}
 	defer f.Close()
 
-	return persistToWriter(f, obj, compress, encryptionKey)
+	// The encoders write in small chunks, so they're buffered to reduce the
+	// number of syscalls.
+	bw := getBufWriter(f)
+	defer putBufWriter(bw)
+	err = persistToWriter(bw, obj, encoding, compress, encryptionKey)
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write file: %w", err)
+	}
+	return nil
 }
 
 // persistToWriter persists an object to a writer. The object is serialized
-// as gob, optionally compressed with flate (as gzip) and optionally encrypted with
-// AES-GCM. The encryption key must be 32 bytes long.
+// with the encoding (gob if nil), optionally compressed with flate (as gzip) and
+// optionally encrypted with AES-GCM. The encryption key must be 32 bytes long.
 // If the writer has to be closed, it's the caller's responsibility.
-func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string) error {
+func persistToWriter(w io.Writer, obj any, encoding Encoding, compress bool, encryptionKey string) error {
 	// AES 256 requires a 32 byte key
 	if encryptionKey != "" {
 		if len(encryptionKey) != 32 {
@@ -82,25 +166,31 @@ func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string)
 	}
 
 	// We want to:
-	// Encode as gob -> compress with flate -> encrypt with AES-GCM -> write to
+	// Encode -> compress with flate -> encrypt with AES-GCM -> write to
 	// passed writer.
 	// To reduce memory usage we chain the writers instead of buffering, so we start
 	// from the end. For AES GCM sealing the stdlib doesn't provide a writer though.
+	// The buffer for the encryption and the gzip writer are pooled, as they're
+	// needed for each document.
 
 	var chainedWriter io.Writer
+	var buf *bytes.Buffer
 	if encryptionKey == "" {
 		chainedWriter = w
 	} else {
-		chainedWriter = &bytes.Buffer{}
+		buf = getBuffer()
+		defer putBuffer(buf)
+		chainedWriter = buf
 	}
 
 	var gzw *gzip.Writer
-	var enc *gob.Encoder
+	var enc Encoder
 	if compress {
-		gzw = gzip.NewWriter(chainedWriter)
-		enc = gob.NewEncoder(gzw)
+		gzw = getGzipWriter(chainedWriter)
+		defer putGzipWriter(gzw)
+		enc = orGob(encoding).NewEncoder(gzw)
 	} else {
-		enc = gob.NewEncoder(chainedWriter)
+		enc = orGob(encoding).NewEncoder(chainedWriter)
 	}
 
 	// Start encoding, it will write to the chain of writers.
@@ -137,10 +227,15 @@ func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string)
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return fmt.Errorf("couldn't read random bytes for nonce: %w", err)
 	}
-	// chainedWriter is a *bytes.Buffer
-	buf := chainedWriter.(*bytes.Buffer)
-	encrypted := gcm.Seal(nonce, nonce, buf.Bytes(), nil)
-	_, err = w.Write(encrypted)
+	// The data is encrypted in place, with room for the authentication tag, and
+	// written after the nonce.
+	buf.Grow(gcm.Overhead())
+	plaintext := buf.Bytes()
+	encrypted := gcm.Seal(plaintext[:0], nonce, plaintext, nil)
+	_, err = w.Write(nonce)
+	if err == nil {
+		_, err = w.Write(encrypted)
+	}
 	if err != nil {
 		return fmt.Errorf("couldn't write encrypted data: %w", err)
 	}
@@ -149,10 +244,10 @@ func persistToWriter(w io.Writer, obj any, compress bool, encryptionKey string)
 }
 
 // readFromFile reads an object from a file at the given path. The object is deserialized
-// from gob. `obj` must be a pointer to an instantiated object. The file may
-// optionally be compressed as gzip and/or encrypted with AES-GCM. The encryption
-// key must be 32 bytes long.
-func readFromFile(filePath string, obj any, encryptionKey string) error {
+// with the encoding (gob if nil). `obj` must be a pointer to an instantiated
+// object. The file may optionally be compressed as gzip and/or encrypted with
+// AES-GCM. The encryption key must be 32 bytes long.
+func readFromFile(filePath string, obj any, encoding Encoding, encryptionKey string) error {
 	if filePath == "" {
 		return fmt.Errorf("file path is empty")
 	}
@@ -169,15 +264,15 @@ func readFromFile(filePath string, obj any, encryptionKey string) error {
 	}
 	defer r.Close()
 
-	return readFromReader(r, obj, encryptionKey)
+	return readFromReader(r, obj, encoding, encryptionKey)
 }
 
-// readFromReader reads an object from a Reader. The object is deserialized from gob.
-// `obj` must be a pointer to an instantiated object. The stream may optionally
+// readFromReader reads an object from a Reader. The object is deserialized
+// with the encoding (gob if nil). `obj` must be a pointer to an instantiated object. The stream may optionally
 // be compressed as gzip and/or encrypted with AES-GCM. The encryption key must
 // be 32 bytes long.
 // If the reader has to be closed, it's the caller's responsibility.
-func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
+func readFromReader(r io.Reader, obj any, encoding Encoding, encryptionKey string) error {
 	// AES 256 requires a 32 byte key
 	if encryptionKey != "" {
 		if len(encryptionKey) != 32 {
@@ -186,21 +281,21 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 	}
 
 	// We want to:
-	// Read from reader -> decrypt with AES-GCM -> decompress with flate -> decode
-	// as gob.
+	// Read from reader -> decrypt with AES-GCM -> decompress with flate -> decode.
 	// To reduce memory usage we chain the readers instead of buffering, so we start
-	// from the end. For the decryption there's no reader though.
+	// from the end. For the decryption there's no reader though, so the data is
+	// read into a pooled buffer and decrypted in place.
 
-	// For the chainedReader we don't declare it as ReadSeeker so we can reassign
-	// the gzip reader to it.
 	var chainedReader io.Reader
 
 	// Decrypt if an encryption key is provided
 	if encryptionKey != "" {
-		encrypted, err := io.ReadAll(r)
+		buf, err := readAll(r)
 		if err != nil {
 			return fmt.Errorf("couldn't read from reader: %w", err)
 		}
+		defer putBuffer(buf)
+		encrypted := buf.Bytes()
 		block, err := aes.NewCipher([]byte(encryptionKey))
 		if err != nil {
 			return fmt.Errorf("couldn't create AES cipher: %w", err)
@@ -214,7 +309,7 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 			return fmt.Errorf("encrypted data too short")
 		}
 		nonce, ciphertext := encrypted[:nonceSize], encrypted[nonceSize:]
-		data, err := gcm.Open(nil, nonce, ciphertext, nil)
+		data, err := gcm.Open(ciphertext[:0], nonce, ciphertext, nil)
 		if err != nil {
 			return fmt.Errorf("couldn't decrypt data: %w", err)
 		}
@@ -224,37 +319,28 @@ func readFromReader(r io.ReadSeeker, obj any, encryptionKey string) error {
 		chainedReader = r
 	}
 
+	// The reader is buffered, so that the magic number can be peeked at instead
+	// of seeking back, and so that the gzip reader and the gob decoder don't
+	// allocate their own buffers.
+	br := getBufReader(chainedReader)
+	defer putBufReader(br)
+	chainedReader = br
+
 	// Determine if the stream is compressed
-	magicNumber := make([]byte, 2)
-	_, err := chainedReader.Read(magicNumber)
+	magicNumber, err := br.Peek(2)
 	if err != nil {
 		return fmt.Errorf("couldn't read magic number to determine whether the stream is compressed: %w", err)
 	}
-	var compressed bool
 	if magicNumber[0] == 0x1f && magicNumber[1] == 0x8b {
-		compressed = true
-	}
-
-	// Reset reader. Both the reader from the param and bytes.Reader support seeking.
-	if s, ok := chainedReader.(io.Seeker); !ok {
-		return fmt.Errorf("reader doesn't support seeking")
-	} else {
-		_, err := s.Seek(0, 0)
-		if err != nil {
-			return fmt.Errorf("couldn't reset reader: %w", err)
-		}
-	}
-
-	if compressed {
-		gzr, err := gzip.NewReader(chainedReader)
+		gzr, err := getGzipReader(br)
 		if err != nil {
 			return fmt.Errorf("couldn't create gzip reader: %w", err)
 		}
-		defer gzr.Close()
+		defer putGzipReader(gzr)
 		chainedReader = gzr
 	}
 
-	dec := gob.NewDecoder(chainedReader)
+	dec := orGob(encoding).NewDecoder(chainedReader)
 	err = dec.Decode(obj)
 	if err != nil {
 		return fmt.Errorf("couldn't decode object: %w", err)
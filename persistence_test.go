@@ -3,10 +3,13 @@ package chromem
 import (
 	"compress/gzip"
 	"encoding/gob"
+	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 )
 
@@ -28,7 +31,7 @@ func TestPersistenceWrite(t *testing.T) {
 
 	t.Run("gob", func(t *testing.T) {
 		tempFilePath := tempDir + ".gob"
-		persistToFile(tempFilePath, obj, false, "")
+		persistToFile(tempFilePath, obj, nil, false, "", filePerms{})
 
 		// Check if the file exists.
 		_, err = os.Stat(tempFilePath)
@@ -57,7 +60,7 @@ func TestPersistenceWrite(t *testing.T) {
 
 	t.Run("gob gzipped", func(t *testing.T) {
 		tempFilePath := tempDir + ".gob.gz"
-		persistToFile(tempFilePath, obj, true, "")
+		persistToFile(tempFilePath, obj, nil, true, "", filePerms{})
 
 		// Check if the file exists.
 		_, err = os.Stat(tempFilePath)
@@ -123,7 +126,7 @@ func TestPersistenceRead(t *testing.T) {
 
 		// Read the file.
 		var res s
-		err = readFromFile(tempFilePath, &res, "")
+		err = readFromFile(tempFilePath, &res, nil, "")
 		if err != nil {
 			t.Fatal("expected nil, got", err)
 		}
@@ -157,7 +160,7 @@ func TestPersistenceRead(t *testing.T) {
 
 		// Read the file.
 		var res s
-		err = readFromFile(tempFilePath, &res, "")
+		err = readFromFile(tempFilePath, &res, nil, "")
 		if err != nil {
 			t.Fatal("expected nil, got", err)
 		}
@@ -207,7 +210,7 @@ func TestPersistenceEncryption(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			err := persistToFile(tc.filePath, obj, tc.compress, encryptionKey)
+			err := persistToFile(tc.filePath, obj, nil, tc.compress, encryptionKey, filePerms{})
 			if err != nil {
 				t.Fatal("expected nil, got", err)
 			}
@@ -220,7 +223,7 @@ func TestPersistenceEncryption(t *testing.T) {
 
 			// Read the file.
 			var res s
-			err = readFromFile(tc.filePath, &res, encryptionKey)
+			err = readFromFile(tc.filePath, &res, nil, encryptionKey)
 			if err != nil {
 				t.Fatal("expected nil, got", err)
 			}
@@ -232,3 +235,100 @@ func TestPersistenceEncryption(t *testing.T) {
 		})
 	}
 }
+
+func TestPersistencePooledBuffers(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	r := rand.New(rand.NewSource(42))
+	encryptionKey := randomString(r, 32)
+	contents := make([]string, 16)
+	for i := range contents {
+		// Different sizes, so that the pooled buffers have to grow and shrink.
+		contents[i] = randomString(r, 1+i*i*100)
+	}
+
+	// Concurrent writes and reads with all combinations of compression and
+	// encryption must not mix up the pooled buffers.
+	var wg sync.WaitGroup
+	errs := make(chan error, len(contents))
+	for i, content := range contents {
+		i, content := i, content
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			compress := i%2 == 0
+			key := ""
+			if i%4 < 2 {
+				key = encryptionKey
+			}
+			path := filepath.Join(tempDir, strconv.Itoa(i))
+			for j := 0; j < 10; j++ {
+				doc := Document{ID: strconv.Itoa(j), Content: content}
+				err := persistToFile(path, doc, nil, compress, key, filePerms{})
+				if err != nil {
+					errs <- err
+					return
+				}
+				var res Document
+				err = readFromFile(path, &res, nil, key)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if !reflect.DeepEqual(doc, res) {
+					errs <- fmt.Errorf("expected %+v, got %+v", doc, res)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("expected nil, got", err)
+	}
+}
+
+func BenchmarkPersistence_Compressed(b *testing.B) {
+	benchmarkPersistence(b, true, "")
+}
+
+func BenchmarkPersistence_CompressedEncrypted(b *testing.B) {
+	benchmarkPersistence(b, true, randomString(rand.New(rand.NewSource(42)), 32))
+}
+
+// benchmarkPersistence writes and reads a document with a 1536-dimensional
+// embedding, as for each document when adding documents to a persistent DB.
+func benchmarkPersistence(b *testing.B, compress bool, encryptionKey string) {
+	tempDir, err := os.MkdirTemp("", "chromem-go")
+	if err != nil {
+		b.Fatal("expected nil, got", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	r := rand.New(rand.NewSource(42))
+	doc := Document{ID: "1", Content: randomString(r, 1875), Embedding: make([]float32, 1536)}
+	for i := range doc.Embedding {
+		doc.Embedding[i] = r.Float32()
+	}
+	path := filepath.Join(tempDir, "doc")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		err := persistToFile(path, doc, nil, compress, encryptionKey, filePerms{})
+		if err != nil {
+			b.Fatal("expected nil, got", err)
+		}
+		var res Document
+		err = readFromFile(path, &res, nil, encryptionKey)
+		if err != nil {
+			b.Fatal("expected nil, got", err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// phraseTerms splits a text into the terms of "$contains_phrase" filters,
+// which are sequences of letters, digits and combining marks. There's no
+// stemming, so "errors" doesn't match "error".
+func phraseTerms(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+}
+
+// containsPhrase returns whether the terms of the content contain the terms of
+// the phrase consecutively.
+func containsPhrase(content, phrase string) bool {
+	terms := phraseTerms(phrase)
+	if len(terms) == 0 {
+		return false
+	}
+	contentTerms := phraseTerms(content)
+outer:
+	for i := 0; i+len(terms) <= len(contentTerms); i++ {
+		for j, term := range terms {
+			if contentTerms[i+j] != term {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// indexTerm returns the term as it's stored in the phrase index. The index is
+// case-insensitive and without diacritics, so that it serves filters with all
+// options, and the candidates are checked exactly afterwards.
+func indexTerm(term string) string {
+	return strings.ToLower(foldDiacritics(term))
+}
+
+// phraseIndex is a positional inverted index of the terms of the documents'
+// contents, to find the candidates of "$contains_phrase" filters without
+// scanning all contents.
+//
+// All methods must be called while holding the collection's documents write
+// lock, except candidates, which only needs the read lock.
+type phraseIndex struct {
+	// Positions of each term per document ID.
+	postings map[string]map[string][]int32
+	// Distinct terms per document ID, to remove documents.
+	terms map[string][]string
+	// Documents whose content couldn't be read, e.g. because it's encrypted and
+	// the key isn't set. They're always candidates.
+	unindexed map[string]struct{}
+}
+
+func newPhraseIndex() *phraseIndex {
+	return &phraseIndex{
+		postings:  make(map[string]map[string][]int32),
+		terms:     make(map[string][]string),
+		unindexed: make(map[string]struct{}),
+	}
+}
+
+// add indexes the document's content, replacing a previous version.
+func (pi *phraseIndex) add(id, content string) {
+	if pi == nil {
+		return
+	}
+	pi.remove(id)
+	for pos, term := range phraseTerms(indexTerm(content)) {
+		docs, ok := pi.postings[term]
+		if !ok {
+			docs = make(map[string][]int32)
+			pi.postings[term] = docs
+		}
+		if _, ok := docs[id]; !ok {
+			pi.terms[id] = append(pi.terms[id], term)
+		}
+		docs[id] = append(docs[id], int32(pos))
+	}
+}
+
+// addUnindexed adds a document whose content couldn't be read.
+func (pi *phraseIndex) addUnindexed(id string) {
+	if pi == nil {
+		return
+	}
+	pi.remove(id)
+	pi.unindexed[id] = struct{}{}
+}
+
+// remove removes the document from the index.
+func (pi *phraseIndex) remove(id string) {
+	if pi == nil {
+		return
+	}
+	for _, term := range pi.terms[id] {
+		delete(pi.postings[term], id)
+		if len(pi.postings[term]) == 0 {
+			delete(pi.postings, term)
+		}
+	}
+	delete(pi.terms, id)
+	delete(pi.unindexed, id)
+}
+
+// candidates returns the IDs of the documents that might contain the phrase.
+func (pi *phraseIndex) candidates(phrase string) map[string]struct{} {
+	terms := phraseTerms(indexTerm(phrase))
+	res := make(map[string]struct{}, len(pi.unindexed))
+	for id := range pi.unindexed {
+		res[id] = struct{}{}
+	}
+	if len(terms) == 0 {
+		return res
+	}
+	// Start with the rarest term, and check the positions of the others
+	// relative to it.
+	rarest := 0
+	for i, term := range terms {
+		if len(pi.postings[term]) < len(pi.postings[terms[rarest]]) {
+			rarest = i
+		}
+	}
+	for id, positions := range pi.postings[terms[rarest]] {
+		for _, pos := range positions {
+			if pi.hasPhraseAt(id, terms, pos-int32(rarest)) {
+				res[id] = struct{}{}
+				break
+			}
+		}
+	}
+	return res
+}
+
+// hasPhraseAt returns whether the document has the terms at consecutive
+// positions, starting at start.
+func (pi *phraseIndex) hasPhraseAt(id string, terms []string, start int32) bool {
+	if start < 0 {
+		return false
+	}
+	for i, term := range terms {
+		positions := pi.postings[term][id]
+		// Positions are sorted, as they're appended in order.
+		if !containsSorted(positions, start+int32(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsSorted returns whether the sorted positions contain the position.
+func containsSorted(positions []int32, pos int32) bool {
+	lo, hi := 0, len(positions)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if positions[mid] < pos {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo < len(positions) && positions[lo] == pos
+}
+
+// filter returns the documents that might contain the phrase, in their order.
+func (pi *phraseIndex) filter(docs []*Document, phrase string) []*Document {
+	candidates := pi.candidates(phrase)
+	filtered := make([]*Document, 0, min(len(docs), len(candidates)))
+	for _, doc := range docs {
+		if _, ok := candidates[doc.ID]; ok {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered
+}
+
+// EnablePhraseIndex enables a positional index of the terms of the documents'
+// contents, which finds the candidates of "$contains_phrase" filters (see
+// [Collection.Query]) without scanning the contents of all documents. This
+// makes phrase filters cheap, so that they can narrow down the documents before
+// the vector search, e.g. for exact error codes like "error code 0x80070005".
+// Without the index, the filter scans the contents.
+//
+// The index is built from the existing documents and updated when documents
+// are added or deleted. It takes memory in the order of the contents' size. The
+// setting is persisted if the DB is persistent, and the index is rebuilt when
+// the DB is loaded.
+func (c *Collection) EnablePhraseIndex() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.discardContent {
+		return errors.New("phrase index can't be combined with discarding the content")
+	}
+	if c.phrases != nil {
+		return nil
+	}
+	c.buildPhraseIndex()
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// DisablePhraseIndex removes the phrase index, see
+// [Collection.EnablePhraseIndex].
+func (c *Collection) DisablePhraseIndex() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.phrases == nil {
+		return nil
+	}
+	c.phrases = nil
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// buildPhraseIndex builds the phrase index from the documents. Must be called
+// while holding the documents write lock.
+func (c *Collection) buildPhraseIndex() {
+	c.phrases = newPhraseIndex()
+	for id, doc := range c.documents {
+		c.indexPhrases(id, doc)
+	}
+}
+
+// indexPhrases adds the stored document to the phrase index, if there is one.
+// Must be called while holding the documents write lock.
+func (c *Collection) indexPhrases(id string, doc *Document) {
+	if c.phrases == nil {
+		return
+	}
+	readable, err := c.readable(doc)
+	if err != nil {
+		c.phrases.addUnindexed(id)
+		return
+	}
+	c.phrases.add(id, readable.Content)
+}
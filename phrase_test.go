@@ -0,0 +1,112 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestPhraseIndex_candidates(t *testing.T) {
+	pi := newPhraseIndex()
+	pi.add("1", "Error code 0x80070005: access denied")
+	pi.add("2", "code error 0x80070005")
+	pi.add("3", "error, code 0x80070005!")
+	pi.addUnindexed("4")
+
+	got := make([]string, 0, 3)
+	for id := range pi.candidates("error code 0x80070005") {
+		got = append(got, id)
+	}
+	slices.Sort(got)
+	exp := []string{"1", "3", "4"}
+	if !slices.Equal(got, exp) {
+		t.Fatal("expected", exp, "got", got)
+	}
+
+	pi.remove("1")
+	if _, ok := pi.candidates("access denied")["1"]; ok {
+		t.Fatal("expected removed document not to be a candidate")
+	}
+	if _, ok := pi.postings["access"]; ok {
+		t.Fatal("expected postings of removed document to be removed")
+	}
+}
+
+func TestCollection_EnablePhraseIndex(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "Error code 0x80070005", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.EnablePhraseIndex()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// Added after enabling the index.
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "code 0x80070005 error", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	query := func(c *Collection, whereDocument map[string]string) []string {
+		t.Helper()
+		res, err := c.QueryEmbedding(ctx, []float32{1, 0}, c.Count(), nil, whereDocument)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		ids := make([]string, 0, len(res))
+		for _, r := range res {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	// The index is case-insensitive, but the filter is exact.
+	if ids := query(c, map[string]string{"$contains_phrase": "error code 0x80070005"}); len(ids) != 0 {
+		t.Fatal("expected no result, got", ids)
+	}
+	if ids := query(c, map[string]string{"$contains_phrase": "error code 0x80070005", "$options": "ignore_case"}); !slices.Equal(ids, []string{"1"}) {
+		t.Fatal("expected document 1, got", ids)
+	}
+	if ids := query(c, map[string]string{"$contains_phrase": "0x80070005 error"}); !slices.Equal(ids, []string{"2"}) {
+		t.Fatal("expected document 2, got", ids)
+	}
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, map[string]string{"$contains_phrase": "!"})
+	if err == nil {
+		t.Fatal("expected error for phrase without terms, got nil")
+	}
+
+	// The index is rebuilt when the DB is loaded.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.phrases == nil {
+		t.Fatal("expected phrase index, got nil")
+	}
+	if ids := query(c2, map[string]string{"$contains_phrase": "0x80070005 error"}); !slices.Equal(ids, []string{"2"}) {
+		t.Fatal("expected document 2, got", ids)
+	}
+
+	err = c2.DisablePhraseIndex()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if ids := query(c2, map[string]string{"$contains_phrase": "0x80070005 error"}); !slices.Equal(ids, []string{"2"}) {
+		t.Fatal("expected document 2 without index, got", ids)
+	}
+}
@@ -0,0 +1,331 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPipelineBufferSize is the default number of documents that are
+// buffered between the stages of a [Pipeline].
+const defaultPipelineBufferSize = 16
+
+// DocumentLoader returns the documents of a source one by one, and io.EOF when
+// there are no more documents. It's the source of a [Pipeline]. See
+// [NewSliceLoader], [NewJSONLLoader] and [NewCSVLoader].
+type DocumentLoader func() (Document, error)
+
+// NewSliceLoader returns a loader that returns the given documents.
+func NewSliceLoader(docs []Document) DocumentLoader {
+	i := 0
+	return func() (Document, error) {
+		if i >= len(docs) {
+			return Document{}, io.EOF
+		}
+		i++
+		return docs[i-1], nil
+	}
+}
+
+// PipelineStage is a processing step of a [Pipeline] between loading and
+// embedding the documents, like splitting them into chunks or enriching their
+// metadata. See [SplitStage] and [EnrichStage].
+type PipelineStage struct {
+	// Name identifies the stage in errors and [PipelineStats]. Optional,
+	// defaults to "stage-" followed by the stage's position, starting at 1.
+	Name string
+	// Process transforms a document into zero or more documents, which are
+	// passed on to the next stage. An error stops the pipeline.
+	Process func(ctx context.Context, doc Document) ([]Document, error)
+	// Concurrency is the number of documents that are processed concurrently.
+	// Optional, defaults to 1. With more than 1, the order of the documents
+	// isn't preserved.
+	Concurrency int
+}
+
+// SplitStage returns a stage that splits the content of each document into
+// chunks with the splitter, e.g. from [NewTextSplitter]. Like with
+// [ContentLimit.Split], the chunks have the ID of the document followed by "/"
+// and the chunk's index, like "doc-42/0", and a copy of the document's
+// metadata. Documents with an embedding or a single chunk are passed on as
+// they are. Unlike with [Collection.SetContentLimit], chunks that are left
+// over from a previous version of a document aren't deleted.
+func SplitStage(splitter TextSplitter) PipelineStage {
+	return PipelineStage{
+		Name: "split",
+		Process: func(_ context.Context, doc Document) ([]Document, error) {
+			if len(doc.Embedding) != 0 || doc.Content == "" {
+				return []Document{doc}, nil
+			}
+			chunks := splitter(doc.Content)
+			if len(chunks) <= 1 {
+				return []Document{doc}, nil
+			}
+			res := make([]Document, len(chunks))
+			for i, content := range chunks {
+				res[i] = Document{
+					ID: chunkID(doc.ID, i),
+					// Copied so that later stages can modify each chunk's metadata.
+					Metadata: maps.Clone(doc.Metadata),
+					Content:  content,
+				}
+			}
+			return res, nil
+		},
+	}
+}
+
+// EnrichStage returns a stage that modifies each document in place with the
+// enrich function, e.g. to add metadata that's extracted from its content.
+//
+//   - name: The name of the stage, see [PipelineStage.Name].
+//   - enrich: Modifies the document. An error stops the pipeline.
+//   - concurrency: The number of documents that are enriched concurrently.
+//     Optional, defaults to 1.
+func EnrichStage(name string, enrich func(ctx context.Context, doc *Document) error, concurrency int) PipelineStage {
+	return PipelineStage{
+		Name: name,
+		Process: func(ctx context.Context, doc Document) ([]Document, error) {
+			err := enrich(ctx, &doc)
+			if err != nil {
+				return nil, err
+			}
+			return []Document{doc}, nil
+		},
+		Concurrency: concurrency,
+	}
+}
+
+// Pipeline declares an ingestion flow: The documents of the loader pass through
+// the stages, e.g. splitting and enrichment, and are then embedded and added to
+// a collection, see [Pipeline.Run].
+//
+// All stages run concurrently and are connected by bounded buffers, so a slow
+// stage like the embedding applies backpressure to the previous ones instead of
+// the documents piling up in memory.
+type Pipeline struct {
+	// Loader returns the documents. Required.
+	Loader DocumentLoader
+	// Stages process the documents in order. Optional.
+	Stages []PipelineStage
+	// Concurrency is the number of documents that are embedded and added
+	// concurrently. 0 uses the DB's default, see [DB.SetConcurrencyDefaults].
+	Concurrency int
+	// BufferSize is the number of documents that are buffered between two
+	// stages. Optional, defaults to 16.
+	BufferSize int
+}
+
+// StageStats are the metrics of a stage of a [Pipeline].
+type StageStats struct {
+	// Name is the name of the stage. The loader is called "load" and the
+	// embedding step "embed".
+	Name string
+	// In is the number of documents that the stage received.
+	In int
+	// Out is the number of documents that the stage passed on, or for the
+	// embedding step, that it added.
+	Out int
+	// Busy is the time that the stage spent processing documents, summed up
+	// over its concurrent workers. Time spent waiting for the previous or the
+	// next stage isn't included, so the stage with the highest Busy time per
+	// worker is the bottleneck.
+	Busy time.Duration
+}
+
+// PipelineStats are the metrics of a [Pipeline] run.
+type PipelineStats struct {
+	// Stages are the metrics of the loader, the stages and the embedding step,
+	// in that order.
+	Stages []StageStats
+}
+
+// stageCounters are the concurrently updated metrics of a stage.
+type stageCounters struct {
+	in, out, busy atomic.Int64
+}
+
+func (sc *stageCounters) stats(name string) StageStats {
+	return StageStats{
+		Name: name,
+		In:   int(sc.in.Load()),
+		Out:  int(sc.out.Load()),
+		Busy: time.Duration(sc.busy.Load()),
+	}
+}
+
+// Run runs the pipeline until all documents of the loader are added to the
+// collection, or an error occurs, which cancels the pipeline. The stats are
+// returned in both cases.
+func (p Pipeline) Run(ctx context.Context, c *Collection) (PipelineStats, error) {
+	if p.Loader == nil {
+		return PipelineStats{}, errors.New("loader is nil")
+	}
+	if p.BufferSize < 0 {
+		return PipelineStats{}, errors.New("buffer size must be >= 0")
+	}
+	names := make([]string, 0, len(p.Stages)+2)
+	names = append(names, "load")
+	for i, stage := range p.Stages {
+		if stage.Process == nil {
+			return PipelineStats{}, fmt.Errorf("process function of stage %d is nil", i+1)
+		}
+		if stage.Concurrency < 0 {
+			return PipelineStats{}, fmt.Errorf("concurrency of stage %d must be >= 0", i+1)
+		}
+		name := stage.Name
+		if name == "" {
+			name = "stage-" + strconv.Itoa(i+1)
+		}
+		names = append(names, name)
+	}
+	names = append(names, "embed")
+	concurrency, limiter, err := c.resolveConcurrency(p.Concurrency)
+	if err != nil {
+		return PipelineStats{}, err
+	}
+	// With the automatic concurrency, the limiter limits the workers.
+	if limiter != nil {
+		concurrency = int(limiter.max)
+	}
+	bufferSize := p.BufferSize
+	if bufferSize == 0 {
+		bufferSize = defaultPipelineBufferSize
+	}
+
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+			// Cancel the pipeline for all other goroutines.
+			cancel(sharedErr)
+		}
+	}
+	// send passes the document on, or returns false if the pipeline is canceled.
+	send := func(out chan<- Document, doc Document) bool {
+		select {
+		case out <- doc:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	counters := make([]stageCounters, len(names))
+	var wg sync.WaitGroup
+
+	// Load
+	out := make(chan Document, bufferSize)
+	wg.Add(1)
+	go func(out chan<- Document) {
+		defer wg.Done()
+		defer close(out)
+		for ctx.Err() == nil {
+			start := time.Now()
+			doc, err := p.Loader()
+			counters[0].busy.Add(int64(time.Since(start)))
+			if errors.Is(err, io.EOF) {
+				return
+			} else if err != nil {
+				setSharedErr(fmt.Errorf("couldn't load document: %w", err))
+				return
+			}
+			counters[0].out.Add(1)
+			if !send(out, doc) {
+				return
+			}
+		}
+	}(out)
+
+	// Stages
+	for i, stage := range p.Stages {
+		i, stage := i, stage
+		in := out
+		out = make(chan Document, bufferSize)
+		sc := &counters[i+1]
+		var stageWG sync.WaitGroup
+		for w := 0; w < max(1, stage.Concurrency); w++ {
+			stageWG.Add(1)
+			go func(out chan<- Document) {
+				defer stageWG.Done()
+				for doc := range in {
+					if ctx.Err() != nil {
+						return
+					}
+					sc.in.Add(1)
+					start := time.Now()
+					docs, err := stage.Process(ctx, doc)
+					sc.busy.Add(int64(time.Since(start)))
+					if err != nil {
+						setSharedErr(fmt.Errorf("stage '%s' couldn't process document '%s': %w", names[i+1], doc.ID, err))
+						return
+					}
+					for _, doc := range docs {
+						if !send(out, doc) {
+							return
+						}
+						sc.out.Add(1)
+					}
+				}
+			}(out)
+		}
+		wg.Add(1)
+		go func(out chan<- Document) {
+			defer wg.Done()
+			stageWG.Wait()
+			close(out)
+		}(out)
+	}
+
+	// Embed
+	sc := &counters[len(counters)-1]
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(in <-chan Document) {
+			defer wg.Done()
+			for doc := range in {
+				if ctx.Err() != nil {
+					return
+				}
+				sc.in.Add(1)
+				start := time.Now()
+				var err error
+				if limiter != nil {
+					err = c.addDocumentAdaptive(ctx, doc, limiter)
+				} else {
+					err = c.AddDocument(ctx, doc)
+				}
+				sc.busy.Add(int64(time.Since(start)))
+				if err != nil {
+					setSharedErr(fmt.Errorf("couldn't add document '%s': %w", doc.ID, err))
+					return
+				}
+				sc.out.Add(1)
+			}
+		}(out)
+	}
+
+	wg.Wait()
+	if sharedErr == nil && ctx.Err() != nil {
+		// The parent context was canceled.
+		sharedErr = ctx.Err()
+	}
+
+	stats := PipelineStats{Stages: make([]StageStats, len(names))}
+	for i, name := range names {
+		stats.Stages[i] = counters[i].stats(name)
+	}
+	return stats, sharedErr
+}
@@ -0,0 +1,106 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPipeline_Run(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, func(_ context.Context, text string) ([]float32, error) {
+		return []float32{float32(len(text)), 1}, nil
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	splitter, err := NewTextSplitter(2, 0, func(text string) int { return len(strings.Fields(text)) })
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	jsonl := `{"id":"1","content":"one two three four","metadata":{"lang":"en"}}
+{"id":"2","content":"five"}
+`
+	p := Pipeline{
+		Loader: NewJSONLLoader(strings.NewReader(jsonl)),
+		Stages: []PipelineStage{
+			SplitStage(splitter),
+			EnrichStage("words", func(_ context.Context, doc *Document) error {
+				if doc.Metadata == nil {
+					doc.Metadata = map[string]string{}
+				}
+				doc.Metadata["first"] = strings.Fields(doc.Content)[0]
+				return nil
+			}, 2),
+		},
+		Concurrency: 2,
+		BufferSize:  1,
+	}
+	stats, err := p.Run(ctx, c)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 3 {
+		t.Fatal("expected 3 documents, got", c.Count())
+	}
+	doc, err := c.GetByID(ctx, "1/1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "three four" || doc.Metadata["lang"] != "en" || doc.Metadata["first"] != "three" {
+		t.Fatal("expected the enriched second chunk, got", doc)
+	}
+	doc, err = c.GetByID(ctx, "1/0")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["first"] != "one" {
+		t.Fatal("expected the chunks' metadata to be independent, got", doc.Metadata)
+	}
+
+	// Per-stage metrics
+	expected := []StageStats{
+		{Name: "load", In: 0, Out: 2},
+		{Name: "split", In: 2, Out: 3},
+		{Name: "words", In: 3, Out: 3},
+		{Name: "embed", In: 3, Out: 3},
+	}
+	if len(stats.Stages) != len(expected) {
+		t.Fatal("expected", len(expected), "stages, got", len(stats.Stages))
+	}
+	for i, s := range stats.Stages {
+		if s.Name != expected[i].Name || s.In != expected[i].In || s.Out != expected[i].Out {
+			t.Fatal("expected", expected[i], "got", s)
+		}
+	}
+
+	// The first error stops the pipeline.
+	docs := make([]Document, 100)
+	for i := range docs {
+		docs[i] = Document{ID: "doc", Content: "hello"}
+	}
+	errFail := errors.New("fail")
+	p = Pipeline{
+		Loader: NewSliceLoader(docs),
+		Stages: []PipelineStage{{
+			Process: func(_ context.Context, _ Document) ([]Document, error) {
+				return nil, errFail
+			},
+		}},
+	}
+	stats, err = p.Run(ctx, c)
+	if !errors.Is(err, errFail) {
+		t.Fatal("expected errFail, got", err)
+	}
+	if stats.Stages[1].Name != "stage-1" || stats.Stages[1].In != 1 || stats.Stages[2].In != 0 {
+		t.Fatal("expected the pipeline to stop after the first document, got", stats)
+	}
+
+	_, err = Pipeline{}.Run(ctx, c)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
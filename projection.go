@@ -0,0 +1,325 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// ProjectionMethod is the method for reducing the dimensionality of the
+// embeddings, see [Collection.FitProjection].
+type ProjectionMethod string
+
+const (
+	// ProjectionPCA uses a principal component analysis (PCA) of the sample.
+	// It keeps the directions with the most variance and thus usually retains
+	// more of the similarity structure than a random projection.
+	// The embeddings are not centered, as that would change their cosine
+	// similarities, so strictly speaking it's a truncated singular value
+	// decomposition (SVD).
+	ProjectionPCA ProjectionMethod = "pca"
+	// ProjectionRandom uses a Gaussian random projection, which approximately
+	// preserves the distances (Johnson-Lindenstrauss lemma). It's fast to fit,
+	// but usually needs more dimensions than PCA for the same quality.
+	ProjectionRandom ProjectionMethod = "random"
+)
+
+// Number of iterations of the subspace iteration for PCA. The leading
+// components converge quickly, and as we only care about the subspace (cosine
+// similarity is invariant to rotations within it), a few iterations suffice.
+const pcaIterations = 10
+
+// projection is a linear projection to fewer dimensions.
+// Exported fields so it can be encoded as gob.
+type projection struct {
+	Method          ProjectionMethod
+	InputDimensions int
+	// One row per output dimension, each with InputDimensions values.
+	Matrix [][]float32
+}
+
+// apply projects the vector and normalizes the result. The vector must have
+// InputDimensions values.
+func (p *projection) apply(v []float32) []float32 {
+	res := make([]float32, len(p.Matrix))
+	for i, row := range p.Matrix {
+		var sum float32
+		for j, val := range v {
+			sum += row[j] * val
+		}
+		res[i] = sum
+	}
+	return normalizeVector(res)
+}
+
+// projectIfInput projects the vector if there's a projection and the vector has
+// its input dimensions. Vectors that are already projected are returned as is.
+func (p *projection) projectIfInput(v []float32) []float32 {
+	if p == nil || len(v) != p.InputDimensions {
+		return v
+	}
+	return p.apply(v)
+}
+
+// FitProjection reduces the dimensionality of the collection's embeddings, to
+// save memory and make queries faster. It fits a projection to the given number
+// of dimensions on a random sample of the collection's documents, then projects
+// all document embeddings.
+// The projection is stored with the collection (and persisted for persistent
+// DBs), and it's automatically applied to the embeddings of documents that are
+// added later, as well as to query embeddings. Embeddings that already have the
+// reduced dimensionality are used as is.
+//
+// The original embeddings are replaced, so this can't be undone other than by
+// re-embedding the documents with [Collection.Reembed], which also removes the
+// projection. The collection is locked for writes and queries while fitting.
+//
+//   - method: [ProjectionPCA] or [ProjectionRandom]
+//   - dimensions: Number of dimensions of the projected embeddings. Must be lower
+//     than the dimensions of the current embeddings, and for PCA not higher than
+//     the sample size.
+//   - sampleSize: Maximum number of documents to fit the projection on. If it's
+//     0 or less, all documents are used.
+func (c *Collection) FitProjection(ctx context.Context, method ProjectionMethod, dimensions, sampleSize int) error {
+	if method != ProjectionPCA && method != ProjectionRandom {
+		return fmt.Errorf("unsupported projection method '%s'", method)
+	}
+	if dimensions <= 0 {
+		return errors.New("dimensions must be > 0")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	if c.projection != nil {
+		return errors.New("collection already has a projection")
+	}
+	if len(c.documents) == 0 {
+		return errors.New("collection is empty")
+	}
+
+	// Take a random sample. We sort the IDs first so that the sample only
+	// depends on the seed.
+	ids := make([]string, 0, len(c.documents))
+	for id := range c.documents {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	r := rand.New(rand.NewSource(42))
+	r.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	if sampleSize > 0 && sampleSize < len(ids) {
+		ids = ids[:sampleSize]
+	}
+	sample := make([][]float32, 0, len(ids))
+	inputDimensions := len(c.documents[ids[0]].Embedding)
+	for _, id := range ids {
+		embedding := c.documents[id].Embedding
+		if len(embedding) != inputDimensions {
+			return fmt.Errorf("%w: documents have embeddings with different dimensions", ErrDimensionMismatch)
+		}
+		sample = append(sample, embedding)
+	}
+	if dimensions >= inputDimensions {
+		return fmt.Errorf("dimensions must be < %d, the dimensions of the current embeddings", inputDimensions)
+	}
+
+	var p *projection
+	var err error
+	if method == ProjectionPCA {
+		if dimensions > len(sample) {
+			return fmt.Errorf("dimensions must be <= the sample size %d for PCA", len(sample))
+		}
+		p, err = fitPCA(ctx, sample, dimensions, r)
+		if err != nil {
+			return fmt.Errorf("couldn't fit PCA: %w", err)
+		}
+	} else {
+		p = fitRandomProjection(inputDimensions, dimensions, r)
+	}
+
+	// Project all documents. All embeddings must have the input dimensions, so
+	// that there are no mixed embeddings afterwards.
+	projected := make(map[string]*Document, len(c.documents))
+	for id, doc := range c.documents {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(doc.Embedding) != inputDimensions {
+			return fmt.Errorf("%w: document '%s' has an embedding with different dimensions", ErrDimensionMismatch, id)
+		}
+		// We replace the document instead of modifying it, because the old one
+		// might still be referenced, e.g. by cached query results.
+		newDoc := *doc
+		newDoc.Embedding = p.apply(doc.Embedding)
+		projected[id] = &newDoc
+	}
+	if c.memory != nil {
+		c.memory.adjust(documentsMemoryUsage(projected) - documentsMemoryUsage(c.documents))
+	}
+	c.documents = projected
+	c.column.rebuild(c.documents)
+	// The centroids have the original dimensions.
+	c.ivf = nil
+	c.projection = p
+	c.invalidateQueryCache()
+
+	if c.persistDirectory != "" {
+		for _, doc := range c.documents {
+			doc, err := c.withContent(doc)
+			if err != nil {
+				return err
+			}
+			err = c.persistDocument(doc)
+			if err != nil {
+				return err
+			}
+		}
+		err := c.persistMetadata()
+		if err != nil {
+			return fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ProjectionDimensions returns the input and output dimensions of the
+// collection's projection, or 0, 0 if it doesn't have one.
+// See [Collection.FitProjection].
+func (c *Collection) ProjectionDimensions() (input, output int) {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+
+	if c.projection == nil {
+		return 0, 0
+	}
+	return c.projection.InputDimensions, len(c.projection.Matrix)
+}
+
+// fitRandomProjection creates a Gaussian random projection.
+func fitRandomProjection(inputDimensions, dimensions int, r *rand.Rand) *projection {
+	scale := 1 / math.Sqrt(float64(dimensions))
+	matrix := make([][]float32, dimensions)
+	for i := range matrix {
+		row := make([]float32, inputDimensions)
+		for j := range row {
+			row[j] = float32(r.NormFloat64() * scale)
+		}
+		matrix[i] = row
+	}
+	return &projection{
+		Method:          ProjectionRandom,
+		InputDimensions: inputDimensions,
+		Matrix:          matrix,
+	}
+}
+
+// fitPCA finds the top principal components of the (uncentered) sample with a
+// randomized subspace iteration, which doesn't require the full covariance
+// matrix.
+func fitPCA(ctx context.Context, sample [][]float32, dimensions int, r *rand.Rand) (*projection, error) {
+	n, d := len(sample), len(sample[0])
+
+	x := make([][]float64, n)
+	for i, v := range sample {
+		row := make([]float64, d)
+		for j, val := range v {
+			row[j] = float64(val)
+		}
+		x[i] = row
+	}
+
+	// q holds the components as rows, initialized randomly.
+	q := make([][]float64, dimensions)
+	for i := range q {
+		q[i] = randomVector64(d, r)
+	}
+	orthonormalize(q, r)
+
+	y := make([][]float64, dimensions) // x * q^T, one row per component
+	for i := range y {
+		y[i] = make([]float64, n)
+	}
+	for iter := 0; iter < pcaIterations; iter++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// q = (x^T * x * q^T)^T, i.e. multiply with the (unscaled) second moment
+		// matrix.
+		for c, comp := range q {
+			for i, row := range x {
+				y[c][i] = dot64(row, comp)
+			}
+		}
+		for c, comp := range q {
+			clear(comp)
+			for i, row := range x {
+				for j, val := range row {
+					comp[j] += y[c][i] * val
+				}
+			}
+		}
+		orthonormalize(q, r)
+	}
+
+	p := &projection{
+		Method:          ProjectionPCA,
+		InputDimensions: d,
+		Matrix:          make([][]float32, dimensions),
+	}
+	for i, comp := range q {
+		row := make([]float32, d)
+		for j, val := range comp {
+			row[j] = float32(val)
+		}
+		p.Matrix[i] = row
+	}
+	return p, nil
+}
+
+// orthonormalize makes the vectors orthonormal with the modified Gram-Schmidt
+// process. Vectors that turn out to be linearly dependent are replaced by
+// random ones.
+func orthonormalize(vs [][]float64, r *rand.Rand) {
+	for i := 0; i < len(vs); i++ {
+		for tries := 0; ; tries++ {
+			v := vs[i]
+			for _, prev := range vs[:i] {
+				proj := dot64(v, prev)
+				for j := range v {
+					v[j] -= proj * prev[j]
+				}
+			}
+			norm := math.Sqrt(dot64(v, v))
+			if norm > 1e-10 || tries >= 3 {
+				for j := range v {
+					v[j] /= norm
+				}
+				break
+			}
+			vs[i] = randomVector64(len(v), r)
+		}
+	}
+}
+
+func randomVector64(d int, r *rand.Rand) []float64 {
+	v := make([]float64, d)
+	for j := range v {
+		v[j] = r.NormFloat64()
+	}
+	return v
+}
+
+func dot64(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,148 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_FitProjection(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+
+	// The embeddings lie mostly in a 4-dimensional subspace of 32 dimensions,
+	// so PCA to 4 dimensions must keep the nearest neighbors.
+	inputDimensions := 32
+	basis := make([][]float32, 4)
+	for i := range basis {
+		basis[i] = make([]float32, inputDimensions)
+		for j := range basis[i] {
+			basis[i][j] = float32(r.NormFloat64())
+		}
+	}
+	randomVector := func() []float32 {
+		v := make([]float32, inputDimensions)
+		for _, b := range basis {
+			w := float32(r.NormFloat64())
+			for j := range v {
+				v[j] += w * b[j]
+			}
+		}
+		for j := range v {
+			v[j] += float32(r.NormFloat64()) * 0.01
+		}
+		return v
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return nil, errors.New("embedding func not expected to be called")
+	}
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := 0; i < 50; i++ {
+		err = c.AddDocument(ctx, Document{ID: strconv.Itoa(i), Embedding: randomVector()})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	qv := randomVector()
+	want, err := c.QueryEmbedding(ctx, qv, 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid
+	err = c.FitProjection(ctx, ProjectionPCA, inputDimensions, 0)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.FitProjection(ctx, ProjectionPCA, 20, 10)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	err = c.FitProjection(ctx, ProjectionPCA, 4, 30)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	in, out := c.ProjectionDimensions()
+	if in != inputDimensions || out != 4 {
+		t.Fatal("expected", inputDimensions, 4, "got", in, out)
+	}
+	err = c.FitProjection(ctx, ProjectionPCA, 2, 30)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Newly added documents are projected
+	err = c.AddDocument(ctx, Document{ID: "new", Embedding: randomVector()})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(c.documents["new"].Embedding) != 4 {
+		t.Fatal("expected 4 dimensions, got", len(c.documents["new"].Embedding))
+	}
+
+	// The query is projected as well, and the result must stay the same after
+	// reopening the DB.
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", embeddingFunc)
+	got, err := c.QueryEmbedding(ctx, qv, 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID {
+			t.Fatal("expected", want[i].ID, "got", got[i].ID)
+		}
+		if len(got[i].Embedding) != 4 {
+			t.Fatal("expected 4 dimensions, got", len(got[i].Embedding))
+		}
+	}
+}
+
+func TestCollection_FitProjection_Random(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.FitProjection(ctx, ProjectionRandom, 2, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0, 0, 0}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res[0].Embedding) != 2 {
+		t.Fatal("expected 2 dimensions, got", len(res[0].Embedding))
+	}
+	if res[0].Similarity < 0.999 {
+		t.Fatal("expected similarity 1, got", res[0].Similarity)
+	}
+}
@@ -0,0 +1,201 @@
+package chromem
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProjectorOptions configures [Collection.ExportProjectorTSV] and
+// [Collection.ExportProjectorJSON].
+type ProjectorOptions struct {
+	// Where and Filter restrict the export to the documents that match them.
+	// Optional, all documents are exported by default.
+	Where  map[string]string
+	Filter Filter
+	// Content adds the document content as label, e.g. to see it when hovering
+	// over a point. Long contents make the export large, so it's off by
+	// default.
+	Content bool
+}
+
+// projectorDoc is a document in the JSON export for projection tools.
+type projectorDoc struct {
+	ID        string            `json:"id"`
+	Embedding []float32         `json:"embedding"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Content   string            `json:"content,omitempty"`
+}
+
+// ExportProjectorTSV writes the embeddings and metadata of the documents in
+// the format of the TensorBoard Embedding Projector (https://projector.tensorflow.org),
+// to visually inspect the structure of the collection, e.g. clusters or
+// outliers. Load the two files with "Load" in the projector's UI.
+//
+//   - vectors: Receives one line per document with its embedding's values,
+//     separated by tabs.
+//   - metadata: Receives a header line and one line per document, in the same
+//     order, with its ID, content (if enabled) and the values of all metadata
+//     keys of the exported documents, sorted by key. If there's only the ID,
+//     the header is omitted, as the projector expects. Tabs and line breaks
+//     in the values are replaced by spaces, as the format doesn't support
+//     escaping.
+//     Optional, nil skips the metadata.
+//
+// The documents are sorted by ID, and all of them must have embeddings with
+// the same dimensions. If the writers have to be closed, it's the caller's
+// responsibility.
+func (c *Collection) ExportProjectorTSV(vectors, metadata io.Writer, options ProjectorOptions) error {
+	if vectors == nil {
+		return errors.New("vectors writer is nil")
+	}
+	docs, err := c.projectorDocs(options)
+	if err != nil {
+		return err
+	}
+
+	vw := bufio.NewWriter(vectors)
+	for _, doc := range docs {
+		for i, v := range doc.Embedding {
+			if i > 0 {
+				_ = vw.WriteByte('\t')
+			}
+			_, _ = vw.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+		}
+		_ = vw.WriteByte('\n')
+	}
+	err = vw.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write vectors: %w", err)
+	}
+	if metadata == nil {
+		return nil
+	}
+
+	keys := make(map[string]struct{})
+	for _, doc := range docs {
+		for k := range doc.Metadata {
+			keys[k] = struct{}{}
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	slices.Sort(sortedKeys)
+
+	mw := bufio.NewWriter(metadata)
+	writeRow := func(values ...string) {
+		for i, v := range values {
+			if i > 0 {
+				_ = mw.WriteByte('\t')
+			}
+			_, _ = mw.WriteString(projectorTSVReplacer.Replace(v))
+		}
+		_ = mw.WriteByte('\n')
+	}
+	header := []string{"id"}
+	if options.Content {
+		header = append(header, "content")
+	}
+	header = append(header, sortedKeys...)
+	// The projector expects no header if there's only one column.
+	if len(header) > 1 {
+		writeRow(header...)
+	}
+	row := make([]string, 0, len(header)+len(sortedKeys))
+	for _, doc := range docs {
+		row = append(row[:0], doc.ID)
+		if options.Content {
+			row = append(row, doc.Content)
+		}
+		for _, k := range sortedKeys {
+			row = append(row, doc.Metadata[k])
+		}
+		writeRow(row...)
+	}
+	err = mw.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write metadata: %w", err)
+	}
+	return nil
+}
+
+// projectorTSVReplacer replaces the characters that would break the TSV format.
+var projectorTSVReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// ExportProjectorJSON writes the documents as JSON array of objects with the
+// fields "id", "embedding", "metadata" and "content" (if enabled), e.g. to
+// reduce them to 2D with UMAP or t-SNE in Python or JavaScript tools. The
+// documents are sorted by ID, and all of them must have embeddings with the
+// same dimensions. If the writer has to be closed, it's the caller's
+// responsibility.
+func (c *Collection) ExportProjectorJSON(w io.Writer, options ProjectorOptions) error {
+	if w == nil {
+		return errors.New("writer is nil")
+	}
+	docs, err := c.projectorDocs(options)
+	if err != nil {
+		return err
+	}
+
+	// The documents are encoded one by one to not hold the whole JSON in memory.
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	_ = bw.WriteByte('[')
+	for i, doc := range docs {
+		if i > 0 {
+			_ = bw.WriteByte(',')
+		}
+		err = enc.Encode(doc)
+		if err != nil {
+			return fmt.Errorf("couldn't encode document '%s': %w", doc.ID, err)
+		}
+	}
+	_, _ = bw.WriteString("]\n")
+	err = bw.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't write documents: %w", err)
+	}
+	return nil
+}
+
+// projectorDocs returns the documents to export for projection tools, sorted
+// by ID.
+func (c *Collection) projectorDocs(options ProjectorOptions) ([]projectorDoc, error) {
+	docs, err := c.embeddedDocs(options.Where, options.Filter)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	res := make([]projectorDoc, len(docs))
+	for i, doc := range docs {
+		res[i] = projectorDoc{
+			ID:        doc.ID,
+			Embedding: doc.Embedding,
+			Metadata:  doc.Metadata,
+		}
+	}
+	if !options.Content {
+		return res, nil
+	}
+
+	// The content might have been evicted from memory or be encrypted.
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	for i, doc := range docs {
+		readable, err := c.readable(doc)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read document '%s': %w", doc.ID, err)
+		}
+		res[i].Content = readable.Content
+	}
+	return res, nil
+}
@@ -0,0 +1,86 @@
+package chromem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestCollection_ExportProjector(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "2", Embedding: []float32{0, 1}, Metadata: map[string]string{"lang": "de"}, Content: "zwei"},
+		{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"lang": "en", "source": "web"}, Content: "one\tline\nbreak"},
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	t.Run("TSV", func(t *testing.T) {
+		var vectors, metadata bytes.Buffer
+		err := c.ExportProjectorTSV(&vectors, &metadata, ProjectorOptions{Content: true})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		expVectors := "1\t0\n0\t1\n"
+		if vectors.String() != expVectors {
+			t.Fatalf("expected vectors %q, got %q", expVectors, vectors.String())
+		}
+		expMetadata := "id\tcontent\tlang\tsource\n1\tone line break\ten\tweb\n2\tzwei\tde\t\n"
+		if metadata.String() != expMetadata {
+			t.Fatalf("expected metadata %q, got %q", expMetadata, metadata.String())
+		}
+
+		// Filtered, without metadata
+		vectors.Reset()
+		err = c.ExportProjectorTSV(&vectors, nil, ProjectorOptions{Where: map[string]string{"lang": "de"}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if vectors.String() != "0\t1\n" {
+			t.Fatalf("expected vectors %q, got %q", "0\t1\n", vectors.String())
+		}
+
+		// Only IDs, without header
+		metadata.Reset()
+		err = c.ExportProjectorTSV(&vectors, &metadata, ProjectorOptions{Where: map[string]string{"lang": "fr"}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if metadata.String() != "" {
+			t.Fatalf("expected no metadata, got %q", metadata.String())
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := c.ExportProjectorJSON(&buf, ProjectorOptions{})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		var res []projectorDoc
+		err = json.Unmarshal(buf.Bytes(), &res)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if len(res) != 2 || res[0].ID != "1" || res[1].ID != "2" || res[0].Metadata["source"] != "web" || res[0].Content != "" {
+			t.Fatal("expected documents 1 and 2 without content, got", res)
+		}
+
+		// No matching documents
+		buf.Reset()
+		err = c.ExportProjectorJSON(&buf, ProjectorOptions{Where: map[string]string{"lang": "fr"}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if buf.String() != "[]\n" {
+			t.Fatal("expected empty array, got", buf.String())
+		}
+	})
+}
@@ -5,13 +5,106 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"math"
 	"runtime"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
-var supportedFilters = []string{"$contains", "$not_contains"}
+// supportedFilters are the whereDocument operators. Besides the substring
+// operators, there are:
+//
+//   - "$regex": The content matches the RE2 regular expression anywhere, see
+//     https://github.com/google/re2/wiki/Syntax. Use "^" and "$" to match the
+//     whole content.
+//   - "$glob": The whole content matches the glob pattern, with "*" for any
+//     characters including newlines, "?" for a single character and "[...]"
+//     for character classes, e.g. "ERR-[0-9][0-9]*".
+//   - "$contains_phrase": The content contains the terms of the phrase in the
+//     same order, without other terms in between. Terms are sequences of
+//     letters and digits, so punctuation and whitespace are ignored, e.g. "error
+//     code 0x80070005" matches "Error: code 0x80070005" with "ignore_case".
+//     There's no stemming. See [Collection.EnablePhraseIndex] to make it fast.
+//
+// Compiled patterns are cached, so they can be reused in many queries cheaply.
+var supportedFilters = []string{"$contains", "$not_contains", "$starts_with", "$ends_with", "$regex", "$glob", "$contains_phrase", whereDocumentOptionsKey}
+
+// whereDocumentOptionsKey is the key of the whereDocument filter options, which
+// is a comma-separated list of:
+//
+//   - "ignore_case": The filters are case-insensitive.
+//   - "normalize": Diacritics of Latin letters and combining marks are removed
+//     from the content and the filter values, e.g. "é" becomes "e", and sequences of
+//     whitespace are replaced by a single space, with leading and trailing
+//     whitespace removed. This makes composed and decomposed Unicode text
+//     match, as well as text with inconsistent whitespace, e.g. from PDFs.
+//     "$regex" and "$glob" patterns are matched against the normalized content,
+//     but aren't normalized themselves.
+//
+// For example, {"$contains": "cafe", "$options": "ignore_case,normalize"}
+// matches a document with the content "Das  Café".
+const whereDocumentOptionsKey = "$options"
+
+// validateWhereDocument checks the operators and options of a whereDocument
+// filter.
+func validateWhereDocument(whereDocument map[string]string) error {
+	for k := range whereDocument {
+		if !slices.Contains(supportedFilters, k) {
+			return fmt.Errorf("%w '%s'", ErrUnsupportedOperator, k)
+		}
+	}
+	options, ok := whereDocument[whereDocumentOptionsKey]
+	if ok {
+		for _, option := range strings.Split(options, ",") {
+			switch strings.TrimSpace(option) {
+			case "ignore_case", "normalize", "":
+			default:
+				return fmt.Errorf("unsupported whereDocument option '%s'", option)
+			}
+		}
+	}
+	if phrase, ok := whereDocument["$contains_phrase"]; ok && len(phraseTerms(phrase)) == 0 {
+		return fmt.Errorf("phrase '%s' has no terms", phrase)
+	}
+	// Compile the patterns, so that invalid ones are reported before filtering.
+	for _, op := range []string{"$regex", "$glob"} {
+		if pattern, ok := whereDocument[op]; ok {
+			if _, err := compilePattern(op, pattern, hasFilterOption(options, "ignore_case")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasFilterOption returns whether the whereDocument options contain the option.
+func hasFilterOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeForFilter prepares a text for whereDocument filters, according to
+// the options, see whereDocumentOptionsKey.
+func normalizeForFilter(text, options string) string {
+	if options == "" {
+		return text
+	}
+	for _, option := range strings.Split(options, ",") {
+		switch strings.TrimSpace(option) {
+		case "ignore_case":
+			text = strings.ToLower(text)
+		case "normalize":
+			text = strings.Join(strings.Fields(foldDiacritics(text)), " ")
+		}
+	}
+	return text
+}
 
 type docSim struct {
 	docID      string
@@ -47,26 +140,49 @@ type maxDocSims struct {
 	h    docMaxHeap
 	lock sync.RWMutex
 	size int
+	// The lowest similarity in the heap once it's full, as float32 bits, so it
+	// can be read without locking. Documents with a lower similarity are losers.
+	floor atomic.Uint32
 }
 
 // newMaxDocSims creates a new nMaxDocs with a fixed size.
 func newMaxDocSims(size int) *maxDocSims {
-	return &maxDocSims{
+	mds := &maxDocSims{
 		h:    make(docMaxHeap, 0, size),
 		size: size,
 	}
+	mds.floor.Store(math.Float32bits(float32(math.Inf(-1))))
+	return mds
+}
+
+// minSimilarity returns the similarity a document must exceed to get into the
+// heap. It's -Inf while the heap isn't full. It only increases over time, so a
+// stale value is still a valid lower bound.
+func (mds *maxDocSims) minSimilarity() float32 {
+	return math.Float32frombits(mds.floor.Load())
 }
 
 // add inserts a new docSim into the heap, keeping only the top n similarities.
+// The heap is modified in place instead of with heap.Push and heap.Pop, which
+// would allocate for boxing each docSim in an interface.
 func (mds *maxDocSims) add(doc docSim) {
+	// Losers are rejected without locking, as most documents are losers once
+	// the heap is full.
+	if doc.similarity <= mds.minSimilarity() {
+		return
+	}
 	mds.lock.Lock()
 	defer mds.lock.Unlock()
 	if mds.h.Len() < mds.size {
-		heap.Push(&mds.h, doc)
+		mds.h = append(mds.h, doc)
+		heap.Fix(&mds.h, mds.h.Len()-1)
 	} else if mds.h.Len() > 0 && mds.h[0].similarity < doc.similarity {
 		// Replace the smallest similarity if the new doc's similarity is higher
-		heap.Pop(&mds.h)
-		heap.Push(&mds.h, doc)
+		mds.h[0] = doc
+		heap.Fix(&mds.h, 0)
+	}
+	if mds.h.Len() == mds.size && mds.size > 0 {
+		mds.floor.Store(math.Float32bits(mds.h[0].similarity))
 	}
 }
 
@@ -85,8 +201,39 @@ func (d *maxDocSims) values() []docSim {
 // filterDocs filters a map of documents by metadata and content.
 // It does this concurrently.
 func filterDocs(docs map[string]*Document, where, whereDocument map[string]string) []*Document {
-	filteredDocs := make([]*Document, 0, len(docs))
-	filteredDocsLock := sync.Mutex{}
+	docSlice := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		docSlice = append(docSlice, doc)
+	}
+	return filterDocSlice(docSlice, where, whereDocument)
+}
+
+// excludeDocs returns the documents without the ones with the excluded IDs,
+// in their order. It returns the given slice itself if nothing is excluded.
+func excludeDocs(docs []*Document, excludeIDs map[string]struct{}) []*Document {
+	if len(excludeIDs) == 0 {
+		return docs
+	}
+	res := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		if _, ok := excludeIDs[doc.ID]; !ok {
+			res = append(res, doc)
+		}
+	}
+	return res
+}
+
+// filterDocSlice is like filterDocs, but for a slice, whose order it keeps.
+// It may return the given slice itself.
+func filterDocSlice(docs []*Document, where, whereDocument map[string]string) []*Document {
+	// With filteredDocs being initialized as potentially large slice, let's return
+	// nil instead of the empty slice.
+	if len(docs) == 0 {
+		return nil
+	}
+	if len(where) == 0 && len(whereDocument) == 0 {
+		return docs
+	}
 
 	// Determine concurrency. Use number of docs or CPUs, whichever is smaller.
 	numCPUs := runtime.NumCPU()
@@ -96,34 +243,36 @@ func filterDocs(docs map[string]*Document, where, whereDocument map[string]strin
 		concurrency = numDocs
 	}
 
-	docChan := make(chan *Document, concurrency*2)
-
+	// Each goroutine filters a contiguous sub-slice, so that we can concatenate
+	// the results in the original order.
+	filteredPerSubSlice := make([][]*Document, concurrency)
 	wg := sync.WaitGroup{}
+	subSliceSize := len(docs) / concurrency // Can leave remainder, e.g. 10/3 = 3; leaves 1
+	rem := len(docs) % concurrency
 	for i := 0; i < concurrency; i++ {
+		start := i * subSliceSize
+		end := start + subSliceSize
+		// Add remainder to last goroutine
+		if i == concurrency-1 {
+			end += rem
+		}
+
 		wg.Add(1)
-		go func() {
+		go func(i int, subSlice []*Document) {
 			defer wg.Done()
-			for doc := range docChan {
+			for _, doc := range subSlice {
 				if documentMatchesFilters(doc, where, whereDocument) {
-					filteredDocsLock.Lock()
-					filteredDocs = append(filteredDocs, doc)
-					filteredDocsLock.Unlock()
+					filteredPerSubSlice[i] = append(filteredPerSubSlice[i], doc)
 				}
 			}
-		}()
+		}(i, docs[start:end])
 	}
 
-	for _, doc := range docs {
-		docChan <- doc
-	}
-	close(docChan)
-
 	wg.Wait()
 
-	// With filteredDocs being initialized as potentially large slice, let's return
-	// nil instead of the empty slice.
-	if len(filteredDocs) == 0 {
-		filteredDocs = nil
+	var filteredDocs []*Document
+	for _, filtered := range filteredPerSubSlice {
+		filteredDocs = append(filteredDocs, filtered...)
 	}
 	return filteredDocs
 }
@@ -141,15 +290,44 @@ func documentMatchesFilters(document *Document, where, whereDocument map[string]
 		}
 	}
 
+	if len(whereDocument) == 0 {
+		return true
+	}
+	options := whereDocument[whereDocumentOptionsKey]
+	content := normalizeForFilter(document.Content, options)
+
 	// A document must satisfy *all* filters, until we support the `$or` operator.
 	for k, v := range whereDocument {
+		switch k {
+		case "$regex", "$glob":
+			// The pattern isn't normalized, as lowercasing it could change its
+			// meaning, e.g. of `\S`. Case-insensitivity is a flag instead.
+			re, err := compilePattern(k, v, hasFilterOption(options, "ignore_case"))
+			if err != nil || !re.MatchString(content) {
+				return false
+			}
+			continue
+		}
+		v = normalizeForFilter(v, options)
 		switch k {
 		case "$contains":
-			if !strings.Contains(document.Content, v) {
+			if !strings.Contains(content, v) {
 				return false
 			}
 		case "$not_contains":
-			if strings.Contains(document.Content, v) {
+			if strings.Contains(content, v) {
+				return false
+			}
+		case "$starts_with":
+			if !strings.HasPrefix(content, v) {
+				return false
+			}
+		case "$ends_with":
+			if !strings.HasSuffix(content, v) {
+				return false
+			}
+		case "$contains_phrase":
+			if !containsPhrase(content, v) {
 				return false
 			}
 		default:
@@ -163,6 +341,27 @@ func documentMatchesFilters(document *Document, where, whereDocument map[string]
 }
 
 func getMostSimilarDocs(ctx context.Context, queryVectors []float32, docs []*Document, n int) ([]docSim, error) {
+	// As the vectors are normalized, the dot product is the cosine similarity.
+	// Documents that can't make it into the top n anymore are abandoned early.
+	ea := newEarlyAbandon(queryVectors)
+	return getMostSimilarDocsBounded(ctx, docs, n, func(doc *Document, minSimilarity float32) (float32, error) {
+		return ea.dotProduct(doc.Embedding, minSimilarity)
+	})
+}
+
+// getMostSimilarDocsFunc is like getMostSimilarDocs, but uses the given function
+// to calculate the similarity of each document.
+func getMostSimilarDocsFunc(ctx context.Context, docs []*Document, n int, similarity func(doc *Document) (float32, error)) ([]docSim, error) {
+	return getMostSimilarDocsBounded(ctx, docs, n, func(doc *Document, _ float32) (float32, error) {
+		return similarity(doc)
+	})
+}
+
+// getMostSimilarDocsBounded is like getMostSimilarDocsFunc, but passes the
+// similarity a document currently has to exceed to make it into the top n to
+// the function. If the document can't reach it, the function can return any
+// value below it instead of the exact similarity.
+func getMostSimilarDocsBounded(ctx context.Context, docs []*Document, n int, similarity func(doc *Document, minSimilarity float32) (float32, error)) ([]docSim, error) {
 	nMaxDocs := newMaxDocSims(n)
 
 	// Determine concurrency. Use number of docs or CPUs, whichever is smaller.
@@ -211,8 +410,7 @@ func getMostSimilarDocs(ctx context.Context, queryVectors []float32, docs []*Doc
 					return
 				}
 
-				// As the vectors are normalized, the dot product is the cosine similarity.
-				sim, err := dotProduct(queryVectors, doc.Embedding)
+				sim, err := similarity(doc, nMaxDocs.minSimilarity())
 				if err != nil {
 					setSharedErr(fmt.Errorf("couldn't calculate similarity for document '%s': %w", doc.ID, err))
 					return
@@ -0,0 +1,120 @@
+package chromem
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math"
+	"slices"
+)
+
+// queryCache is an LRU cache for query results. Entries are tagged with the
+// collection's write generation, so results computed before a write are never
+// returned after it. It's safe for concurrent use.
+type queryCache struct {
+	lru *lruCache[string, queryCacheEntry]
+}
+
+type queryCacheEntry struct {
+	generation uint64
+	results    []Result
+}
+
+// newQueryCache creates a new query cache holding at most size entries.
+func newQueryCache(size int) *queryCache {
+	return &queryCache{
+		lru: newLRUCache[string, queryCacheEntry](size),
+	}
+}
+
+// get returns a copy of the cached results for the key, if they exist and were
+// computed for the given generation.
+func (qc *queryCache) get(key string, generation uint64) ([]Result, bool) {
+	entry, ok := qc.lru.get(key)
+	if !ok {
+		return nil, false
+	}
+	if entry.generation != generation {
+		// Stale, the collection was modified in the meantime.
+		qc.lru.remove(key)
+		return nil, false
+	}
+
+	// Copy the slice so that callers can't modify the cached one.
+	return slices.Clone(entry.results), true
+}
+
+// put stores a copy of the results for the key and generation, evicting the
+// least recently used entry if the cache is full.
+func (qc *queryCache) put(key string, generation uint64, results []Result) {
+	qc.lru.put(key, queryCacheEntry{
+		generation: generation,
+		results:    slices.Clone(results),
+	})
+}
+
+// purge removes all entries from the cache.
+func (qc *queryCache) purge() {
+	qc.lru.purge()
+}
+
+// memoryUsage estimates the memory usage of the cache in bytes.
+func (qc *queryCache) memoryUsage() int64 {
+	var size int64
+	qc.lru.each(func(key string, entry queryCacheEntry) {
+		size += int64(len(key) + mapEntryOverhead + len(entry.results)*cachedResultOverhead)
+	})
+	return size
+}
+
+// queryCacheKey creates a cache key from the query embedding and all options
+// that influence the query result.
+func queryCacheKey(queryEmbedding []float32, nResults int, where, whereDocument map[string]string, filter Filter, excludeIDs map[string]struct{}, strategy FilterStrategy) string {
+	h := sha256.New()
+	buf := make([]byte, 4)
+	for _, v := range queryEmbedding {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+		h.Write(buf)
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(nResults))
+	h.Write(buf)
+	writeMapToHash(h, "where", where)
+	writeMapToHash(h, "whereDocument", whereDocument)
+	if !filter.isEmpty() {
+		writeMapToHash(h, "filter", map[string]string{"": filter.String()})
+	}
+	if len(excludeIDs) > 0 {
+		exclude := make(map[string]string, len(excludeIDs))
+		for id := range excludeIDs {
+			exclude[id] = ""
+		}
+		writeMapToHash(h, "exclude", exclude)
+	}
+	if strategy != FilterStrategyAuto {
+		writeMapToHash(h, "strategy", map[string]string{"": string(strategy)})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeMapToHash writes the map to the hash in a deterministic order. The
+// strings are length-prefixed to avoid ambiguities between keys and values.
+func writeMapToHash(h hash.Hash, name string, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	buf := make([]byte, 4)
+	writeString := func(s string) {
+		binary.LittleEndian.PutUint32(buf, uint32(len(s)))
+		h.Write(buf)
+		h.Write([]byte(s))
+	}
+	writeString(name)
+	for _, k := range keys {
+		writeString(k)
+		writeString(m[k])
+	}
+}
@@ -0,0 +1,130 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryCache_LRU(t *testing.T) {
+	qc := newQueryCache(2)
+	qc.put("a", 0, []Result{{ID: "a"}})
+	qc.put("b", 0, []Result{{ID: "b"}})
+
+	// Access "a" so that "b" becomes the least recently used entry
+	if _, ok := qc.get("a", 0); !ok {
+		t.Fatal("expected cache hit for a")
+	}
+	qc.put("c", 0, []Result{{ID: "c"}})
+	if _, ok := qc.get("b", 0); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if res, ok := qc.get("c", 0); !ok || res[0].ID != "c" {
+		t.Fatal("expected cache hit for c, got", res)
+	}
+
+	// Other generation
+	if _, ok := qc.get("a", 1); ok {
+		t.Fatal("expected cache miss for stale entry")
+	}
+}
+
+func TestQueryCacheKey(t *testing.T) {
+	emb := []float32{0.1, 0.2}
+	k1 := queryCacheKey(emb, 1, map[string]string{"a": "b", "c": "d"}, nil, Filter{}, nil, FilterStrategyAuto)
+	k2 := queryCacheKey(emb, 1, map[string]string{"c": "d", "a": "b"}, nil, Filter{}, nil, FilterStrategyAuto)
+	if k1 != k2 {
+		t.Fatal("expected equal keys for equal maps")
+	}
+	k3 := queryCacheKey(emb, 1, nil, map[string]string{"a": "b", "c": "d"}, Filter{}, nil, FilterStrategyAuto)
+	if k1 == k3 {
+		t.Fatal("expected different keys for where and whereDocument")
+	}
+	k4 := queryCacheKey(emb, 2, map[string]string{"a": "b", "c": "d"}, nil, Filter{}, nil, FilterStrategyAuto)
+	if k1 == k4 {
+		t.Fatal("expected different keys for different nResults")
+	}
+	k5 := queryCacheKey(emb, 1, map[string]string{"a": "b", "c": "d"}, nil, Where().Eq("a", "b"), nil, FilterStrategyAuto)
+	if k1 == k5 {
+		t.Fatal("expected different keys for different filters")
+	}
+	k6 := queryCacheKey(emb, 1, map[string]string{"a": "b", "c": "d"}, nil, Filter{}, map[string]struct{}{"1": {}}, FilterStrategyAuto)
+	if k1 == k6 {
+		t.Fatal("expected different keys for excluded IDs")
+	}
+}
+
+func TestCollection_QueryCache(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		if text == "hello" {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetQueryCacheSize(10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Content: "world"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "1" {
+		t.Fatal("expected 1, got", res[0].ID)
+	}
+	if c.queryCache.lru.len() != 1 {
+		t.Fatal("expected 1 cache entry, got", c.queryCache.lru.len())
+	}
+
+	// Adding a more similar document must invalidate the cache
+	err = c.AddDocument(ctx, Document{ID: "2", Content: "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.queryCache.lru.len() != 0 {
+		t.Fatal("expected empty cache, got", c.queryCache.lru.len())
+	}
+	res, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "2" {
+		t.Fatal("expected 2, got", res[0].ID)
+	}
+
+	// Deleting must invalidate as well
+	err = c.Delete(ctx, nil, nil, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = c.Query(ctx, "hello", 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "1" {
+		t.Fatal("expected 1, got", res[0].ID)
+	}
+
+	// Disabling
+	err = c.SetQueryCacheSize(0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.queryCache != nil {
+		t.Fatal("expected cache to be disabled")
+	}
+	if err := c.SetQueryCacheSize(-1); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
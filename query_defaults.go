@@ -0,0 +1,303 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// IncludeField is a field of the query results that can be included or left
+// out, see [QueryOptions.Include]. The ID and similarity are always included.
+type IncludeField string
+
+const (
+	IncludeMetadata  IncludeField = "metadata"
+	IncludeContent   IncludeField = "content"
+	IncludeEmbedding IncludeField = "embedding"
+)
+
+// MMROptions configures the re-ranking of the results by maximal marginal
+// relevance (MMR), which selects results that are relevant to the query but
+// not similar to each other, to avoid redundant context in RAG.
+type MMROptions struct {
+	// Lambda trades off relevance against diversity, between 0 and 1. 1 ranks
+	// by relevance only, like without MMR, and 0 by diversity only. A common
+	// value is 0.5.
+	Lambda float32
+	// FetchK is the number of most similar documents that are re-ranked.
+	// Optional, defaults to 4 times the number of results.
+	FetchK int
+}
+
+// QueryDefaults are the default settings of a collection's queries, see
+// [Collection.SetQueryDefaults]. They're used by [Collection.Query],
+// [Collection.QueryEmbedding] and [Collection.QueryWithOptions] when the
+// corresponding argument or option isn't set.
+type QueryDefaults struct {
+	// NResults is the number of results when the nResults argument or option is
+	// 0. If the collection has fewer documents, all of them are returned.
+	NResults int
+	// MinSimilarity is the minimum similarity of the results. Less similar
+	// documents are left out, so there might be fewer results than requested.
+	MinSimilarity float32
+	// Include are the fields of the results. If empty, all fields are included.
+	// Without the content, it isn't read at all, which makes queries on
+	// collections with compressed, encrypted or evicted contents faster.
+	Include []IncludeField
+	// MMR enables the re-ranking by maximal marginal relevance if it's not nil.
+	MMR *MMROptions
+	// Calibration enables the score calibration if it's not nil.
+	Calibration *Calibration
+	// MinScore is the minimum calibrated score of the results.
+	MinScore float32
+	// FilterStrategy defines how filters are combined with the IVF index. If
+	// empty, it's [FilterStrategyAuto].
+	FilterStrategy FilterStrategy
+}
+
+// validate checks the defaults or options.
+func (d *QueryDefaults) validate() error {
+	if d.NResults < 0 {
+		return errors.New("nResults must be >= 0")
+	}
+	for _, f := range d.Include {
+		switch f {
+		case IncludeMetadata, IncludeContent, IncludeEmbedding:
+		default:
+			return fmt.Errorf("unsupported include field '%s'", f)
+		}
+	}
+	if d.MMR != nil {
+		if d.MMR.Lambda < 0 || d.MMR.Lambda > 1 {
+			return errors.New("MMR lambda must be between 0 and 1")
+		}
+		if d.MMR.FetchK < 0 {
+			return errors.New("MMR fetchK must be >= 0")
+		}
+	}
+	if d.Calibration != nil {
+		err := d.Calibration.validate()
+		if err != nil {
+			return err
+		}
+	}
+	return d.FilterStrategy.validate()
+}
+
+// SetQueryDefaults sets the default settings of the collection's queries, so
+// that call sites don't have to repeat them and retrieval can be tuned
+// centrally. The defaults are persisted with the collection. Use the zero value
+// to remove them.
+func (c *Collection) SetQueryDefaults(defaults QueryDefaults) error {
+	err := defaults.validate()
+	if err != nil {
+		return err
+	}
+	// Copy, so that the caller can't modify them.
+	defaults.Include = slices.Clone(defaults.Include)
+	if defaults.MMR != nil {
+		mmr := *defaults.MMR
+		defaults.MMR = &mmr
+	}
+	defaults.Calibration = defaults.Calibration.clone()
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.queryDefaults = defaults
+	err = c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// QueryDefaults returns the default settings of the collection's queries, see
+// [Collection.SetQueryDefaults].
+func (c *Collection) QueryDefaults() QueryDefaults {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	defaults := c.queryDefaults
+	defaults.Include = slices.Clone(defaults.Include)
+	if defaults.MMR != nil {
+		mmr := *defaults.MMR
+		defaults.MMR = &mmr
+	}
+	defaults.Calibration = defaults.Calibration.clone()
+	return defaults
+}
+
+// queryWithDefaults runs the query with the options, with the collection's
+// query defaults for the options that aren't set, and then applies the score
+// calibration, minimum similarity and score, MMR and included fields to the
+// results.
+func (c *Collection) queryWithDefaults(ctx context.Context, queryEmbedding []float32, options QueryOptions) ([]Result, error) {
+	c.documentsLock.RLock()
+	defaults := c.queryDefaults
+	count := len(c.documents)
+	c.documentsLock.RUnlock()
+
+	nResults := options.NResults
+	if nResults == 0 && defaults.NResults > 0 {
+		nResults = min(defaults.NResults, count)
+		if nResults == 0 {
+			return nil, nil
+		}
+	}
+	minSimilarity := options.MinSimilarity
+	if minSimilarity == 0 {
+		minSimilarity = defaults.MinSimilarity
+	}
+	include := options.Include
+	if len(include) == 0 {
+		include = defaults.Include
+	}
+	mmr := options.MMR
+	if mmr == nil {
+		mmr = defaults.MMR
+	}
+	calibration := options.Calibration
+	if calibration == nil {
+		calibration = defaults.Calibration
+	}
+	minScore := options.MinScore
+	if minScore == 0 {
+		minScore = defaults.MinScore
+	}
+	strategy := options.FilterStrategy
+	if strategy == "" {
+		strategy = defaults.FilterStrategy
+	}
+	if strategy == "" {
+		strategy = FilterStrategyAuto
+	}
+	if options.Include != nil || options.MMR != nil || options.Calibration != nil || options.FilterStrategy != "" {
+		err := (&QueryDefaults{Include: options.Include, MMR: options.MMR, Calibration: options.Calibration, FilterStrategy: options.FilterStrategy}).validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if minScore != 0 && calibration == nil {
+		return nil, errors.New("minScore requires score calibration")
+	}
+
+	fetch := nResults
+	if mmr != nil && nResults > 0 {
+		fetch = mmr.FetchK
+		if fetch == 0 {
+			fetch = 4 * nResults
+		}
+		fetch = min(max(fetch, nResults), count)
+	}
+	if calibration != nil && nResults > 0 {
+		fetch = min(max(fetch, calibration.CandidateK), count)
+	}
+
+	// Reading the content can be skipped if the results don't include it.
+	withContent := len(include) == 0 || slices.Contains(include, IncludeContent)
+	res, err := c.queryEmbedding(ctx, queryEmbedding, fetch, options.Where, options.WhereDocument, options.Filter, idSet(options.ExcludeIDs), options.SimilarityFunc, strategy, withContent)
+	if err != nil {
+		return nil, err
+	}
+
+	if calibration != nil {
+		// The results might be cached, so they're copied before setting the
+		// scores.
+		res = slices.Clone(res)
+		calibration.apply(res)
+		if minScore != 0 {
+			res = slices.DeleteFunc(res, func(r Result) bool { return r.Score < minScore })
+		}
+	}
+	if minSimilarity != 0 {
+		// The results are sorted by similarity.
+		i := slices.IndexFunc(res, func(r Result) bool { return r.Similarity < minSimilarity })
+		if i >= 0 {
+			res = res[:i]
+		}
+	}
+	if mmr != nil {
+		res = maximalMarginalRelevance(res, nResults, mmr.Lambda)
+	} else if nResults > 0 && len(res) > nResults {
+		// More candidates were fetched for the calibration.
+		res = res[:nResults]
+	}
+	return applyInclude(res, include), nil
+}
+
+// applyInclude returns the results with only the included fields. The results
+// might be cached, so they're copied if fields are removed.
+func applyInclude(res []Result, include []IncludeField) []Result {
+	if len(include) == 0 {
+		return res
+	}
+	res = slices.Clone(res)
+	for i := range res {
+		if !slices.Contains(include, IncludeMetadata) {
+			res[i].Metadata = nil
+		}
+		if !slices.Contains(include, IncludeContent) {
+			res[i].Content = ""
+		}
+		if !slices.Contains(include, IncludeEmbedding) {
+			res[i].Embedding = nil
+		}
+	}
+	return res
+}
+
+// idSet returns the IDs as set, or nil if there are none.
+func idSet(ids []string) map[string]struct{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	res := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		res[id] = struct{}{}
+	}
+	return res
+}
+
+// maximalMarginalRelevance selects n of the results, which must be sorted by
+// similarity, by maximal marginal relevance: Each next result is the one with
+// the highest lambda * similarity - (1 - lambda) * max similarity to the
+// already selected results. Like for the query, the similarity between results
+// is their dot product.
+func maximalMarginalRelevance(results []Result, n int, lambda float32) []Result {
+	if n >= len(results) && lambda >= 1 {
+		return results
+	}
+	n = min(n, len(results))
+	selected := make([]Result, 0, n)
+	remaining := slices.Clone(results)
+	// The maximum similarity of each remaining result to the selected ones.
+	// Before the first selection it's the same for all, so it doesn't matter.
+	maxSim := make([]float32, len(remaining))
+	for i := range maxSim {
+		maxSim[i] = -1
+	}
+	for len(selected) < n {
+		best := 0
+		bestScore := float32(0)
+		for i, r := range remaining {
+			score := lambda*r.Similarity - (1-lambda)*maxSim[i]
+			if i == 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		chosen := remaining[best]
+		selected = append(selected, chosen)
+		remaining = slices.Delete(remaining, best, best+1)
+		maxSim = slices.Delete(maxSim, best, best+1)
+		for i, r := range remaining {
+			sim, err := dotProduct(r.Embedding, chosen.Embedding)
+			if err == nil {
+				maxSim[i] = max(maxSim[i], sim)
+			}
+		}
+	}
+	return selected
+}
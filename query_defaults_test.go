@@ -0,0 +1,162 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCollection_SetQueryDefaults(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"a": "b"}, Embedding: []float32{1, 0}, Content: "one"},
+		{ID: "2", Metadata: map[string]string{"a": "b"}, Embedding: []float32{0.8, 0.6}, Content: "two"},
+		{ID: "3", Metadata: map[string]string{"a": "b"}, Embedding: []float32{0, 1}, Content: "three"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Without defaults, nResults is required.
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0}, 0, nil, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	err = c.SetQueryDefaults(QueryDefaults{NResults: 10, MinSimilarity: 0.5, Include: []IncludeField{IncludeContent}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 0, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := []Result{
+		{ID: "1", Content: "one", Similarity: 1},
+		{ID: "2", Content: "two", Similarity: 0.8},
+	}
+	if !reflect.DeepEqual(res, exp) {
+		t.Fatal("expected", exp, "got", res)
+	}
+
+	// Options override the defaults.
+	res, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: []float32{1, 0},
+		NResults:       1,
+		MinSimilarity:  -1,
+		Include:        []IncludeField{IncludeMetadata, IncludeContent, IncludeEmbedding},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].Metadata == nil || res[0].Embedding == nil {
+		t.Fatal("expected 1 result with all fields, got", res)
+	}
+
+	// The defaults are persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defaults := db2.GetCollection("test", nil).QueryDefaults()
+	if defaults.NResults != 10 || defaults.MinSimilarity != 0.5 || !reflect.DeepEqual(defaults.Include, []IncludeField{IncludeContent}) {
+		t.Fatal("expected persisted defaults, got", defaults)
+	}
+
+	err = c.SetQueryDefaults(QueryDefaults{Include: []IncludeField{"foo"}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_QueryWithOptions_MMR(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Embedding: []float32{1, 0, 0}},
+		// Near duplicate of 1
+		{ID: "2", Embedding: []float32{0.99, 0.14, 0}},
+		// Less similar to the query, but different from 1
+		{ID: "3", Embedding: []float32{0.8, 0, 0.6}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	res, err := c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0, 0}, NResults: 2})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "1" || res[1].ID != "2" {
+		t.Fatal("expected documents 1 and 2, got", res)
+	}
+
+	res, err = c.QueryWithOptions(ctx, QueryOptions{QueryEmbedding: []float32{1, 0, 0}, NResults: 2, MMR: &MMROptions{Lambda: 0.3}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "1" || res[1].ID != "3" {
+		t.Fatal("expected documents 1 and 3, got", res)
+	}
+}
+
+func TestCollection_QueryWithoutContent(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Metadata: map[string]string{"a": "b"}, Embedding: []float32{1, 0}, Content: "one"},
+		{ID: "2", Metadata: map[string]string{"a": "b"}, Embedding: []float32{0, 1}, Content: "two"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetContentCompression(1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetQueryCacheSize(10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	options := QueryOptions{QueryEmbedding: []float32{1, 0}, NResults: 1, Include: []IncludeField{IncludeMetadata}}
+	res, err := c.QueryWithOptions(ctx, options)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	exp := []Result{{ID: "1", Metadata: map[string]string{"a": "b"}, Similarity: 1}}
+	if !reflect.DeepEqual(res, exp) {
+		t.Fatal("expected", exp, "got", res)
+	}
+
+	// Results without content aren't cached for queries that include it.
+	options.Include = nil
+	res, err = c.QueryWithOptions(ctx, options)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].Content != "one" {
+		t.Fatal("expected the content, got", res)
+	}
+}
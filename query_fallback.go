@@ -0,0 +1,128 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrEmbeddingUnavailable is returned by queries when the embedding of the
+// query couldn't be created, e.g. because the embedding provider is
+// unreachable or rate limited. The error of the embedding function is wrapped
+// as well. See [Collection.SetQueryFallback] to fall back to a keyword search
+// instead.
+var ErrEmbeddingUnavailable = errors.New("embedding unavailable")
+
+// QueryFallback configures the keyword search that [Collection.Query] and
+// [Collection.QueryWithOptions] fall back to when the query text can't be
+// embedded, so that search degrades gracefully while the embedding provider is
+// unavailable.
+type QueryFallback struct {
+	// EncodeQuery encodes the query text as sparse vector for a search like
+	// [Collection.QuerySparse], e.g. [BM25.EncodeQuery]. The documents need
+	// sparse embeddings from the same encoder (see [Document.SparseEmbedding]),
+	// other documents aren't found. Mandatory.
+	EncodeQuery func(text string) SparseVector
+	// OnFallback is called with the embedding error before falling back, e.g.
+	// to log it or count the degraded queries. Optional.
+	OnFallback func(ctx context.Context, err error)
+}
+
+// SetQueryFallback sets the keyword search that queries fall back to when the
+// query text can't be embedded. The fallback results are scored like the
+// results of [Collection.QuerySparse], so their similarities aren't limited to
+// [-1, 1], and documents without any of the query's terms are left out.
+// [Collection.QueryHybrid] falls back to [Collection.QueryBM25] instead of
+// EncodeQuery, as the collection's BM25 index is the better keyword search. The
+// options MinSimilarity, MMR, Calibration and MinScore and the query cache
+// don't apply. The filters, excluded IDs, included fields and the number of
+// results, including their defaults, do. The fallback isn't used when the
+// context is canceled. Namespaces use the fallback of their collection.
+//
+// Without fallback, queries fail with [ErrEmbeddingUnavailable]. The fallback
+// can't be persisted, so it has to be set again after loading a persistent DB.
+// nil removes it.
+func (c *Collection) SetQueryFallback(fallback *QueryFallback) error {
+	if fallback != nil && fallback.EncodeQuery == nil {
+		return errors.New("fallback.EncodeQuery is nil")
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if fallback != nil {
+		cp := *fallback
+		fallback = &cp
+	}
+	c.queryFallback = fallback
+	return nil
+}
+
+// queryBM25WithFallback runs the keyword part of a hybrid search with the BM25
+// index after the embedding of the query failed with embedErr, if the
+// collection has a query fallback, or returns the error otherwise.
+func (c *Collection) queryBM25WithFallback(ctx context.Context, options QueryOptions, embedErr error) ([]Result, error) {
+	c.documentsLock.RLock()
+	fallback := c.queryFallback
+	c.documentsLock.RUnlock()
+	if fallback == nil || ctx.Err() != nil {
+		return nil, fmt.Errorf("couldn't create embedding of query: %w: %w", ErrEmbeddingUnavailable, embedErr)
+	}
+	if fallback.OnFallback != nil {
+		fallback.OnFallback(ctx, embedErr)
+	}
+
+	res, err := c.QueryBM25(ctx, options.QueryText, options.NResults, options.Where, options.WhereDocument)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run fallback query: %w", err)
+	}
+	return res, nil
+}
+
+// queryWithFallback runs the fallback keyword search of the query after its
+// embedding failed with embedErr, or returns the error if there's no fallback.
+func (c *Collection) queryWithFallback(ctx context.Context, options QueryOptions, embedErr error) ([]Result, error) {
+	c.documentsLock.RLock()
+	fallback := c.queryFallback
+	defaults := c.queryDefaults
+	count := len(c.documents)
+	c.documentsLock.RUnlock()
+	if fallback == nil || ctx.Err() != nil {
+		return nil, fmt.Errorf("couldn't create embedding of query: %w: %w", ErrEmbeddingUnavailable, embedErr)
+	}
+	if fallback.OnFallback != nil {
+		fallback.OnFallback(ctx, embedErr)
+	}
+
+	// Like in queryWithDefaults
+	nResults := options.NResults
+	if nResults == 0 && defaults.NResults > 0 {
+		nResults = min(defaults.NResults, count)
+		if nResults == 0 {
+			return nil, nil
+		}
+	}
+	include := options.Include
+	if len(include) == 0 {
+		include = defaults.Include
+	}
+
+	querySparse := fallback.EncodeQuery(options.QueryText)
+	if querySparse.IsEmpty() {
+		// None of the query's terms are known.
+		return nil, nil
+	}
+	res, err := c.querySparse(ctx, querySparse, nResults, options.Where, options.WhereDocument, options.Filter, idSet(options.ExcludeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run fallback query: %w", err)
+	}
+	// Documents without any of the query's terms aren't keyword matches. The
+	// results are sorted by score.
+	if i := slices.IndexFunc(res, func(r Result) bool { return r.Similarity <= 0 }); i >= 0 {
+		res = res[:i]
+	}
+	return applyInclude(res, include), nil
+}
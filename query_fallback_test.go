@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCollection_SetQueryFallback(t *testing.T) {
+	ctx := context.Background()
+	errUnreachable := errors.New("provider unreachable")
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return nil, errUnreachable
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	texts := map[string]string{
+		"1": "The quick brown fox jumps over the lazy dog",
+		"2": "Foxes are quick and clever animals",
+		"3": "A dog sleeps all day long in the sun",
+	}
+	e := NewBM25(NewLanguageAnalyzer("en"), 0, -1)
+	for _, text := range texts {
+		e.Fit(text)
+	}
+	for id, text := range texts {
+		err = c.AddDocument(ctx, Document{ID: id, Content: text, Embedding: []float32{1, 0}, SparseEmbedding: e.EncodeDocument(text)})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+
+	// Without fallback, the error is typed.
+	_, err = c.Query(ctx, "fox", 1, nil, nil)
+	if !errors.Is(err, ErrEmbeddingUnavailable) || !errors.Is(err, errUnreachable) {
+		t.Fatal("expected ErrEmbeddingUnavailable wrapping the embedding error, got", err)
+	}
+
+	err = c.SetQueryFallback(&QueryFallback{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	var fallbackErr error
+	err = c.SetQueryFallback(&QueryFallback{
+		EncodeQuery: e.EncodeQuery,
+		OnFallback:  func(_ context.Context, err error) { fallbackErr = err },
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Only the documents with the term are returned.
+	res, err := c.Query(ctx, "foxes", 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "2" || res[1].ID != "1" {
+		t.Fatal("expected documents 2 and 1, got", res)
+	}
+	if !errors.Is(fallbackErr, errUnreachable) {
+		t.Fatal("expected the embedding error, got", fallbackErr)
+	}
+
+	// With options
+	res, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryText:  "foxes",
+		NResults:   3,
+		ExcludeIDs: []string{"2"},
+		Include:    []IncludeField{IncludeMetadata},
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "1" || res[0].Content != "" {
+		t.Fatal("expected document 1 without content, got", res)
+	}
+
+	// Hybrid queries fall back to the BM25 index.
+	err = c.EnableBM25Index(BM25IndexOptions{Language: "en"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	fallbackErr = nil
+	res, err = c.QueryHybrid(ctx, "sleeps", 3, nil, nil, 0.5)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "3" || !errors.Is(fallbackErr, errUnreachable) {
+		t.Fatal("expected document 3 after the fallback, got", res, fallbackErr)
+	}
+
+	// The query's embedding function is used instead of the collection's.
+	res, err = c.QueryHybridWithOptions(ctx, QueryOptions{
+		QueryText: "sleeps",
+		NResults:  3,
+		EmbeddingFunc: func(_ context.Context, _ string) ([]float32, error) {
+			return []float32{1, 0}, nil
+		},
+	}, 0.5)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 3 || res[0].ID != "3" {
+		t.Fatal("expected all documents with 3 first, got", res)
+	}
+
+	// Not when the context is canceled
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.Query(canceledCtx, "foxes", 3, nil, nil)
+	if !errors.Is(err, ErrEmbeddingUnavailable) {
+		t.Fatal("expected ErrEmbeddingUnavailable, got", err)
+	}
+
+	// Removed
+	err = c.SetQueryFallback(nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = c.Query(ctx, "foxes", 3, nil, nil)
+	if !errors.Is(err, ErrEmbeddingUnavailable) {
+		t.Fatal("expected ErrEmbeddingUnavailable, got", err)
+	}
+}
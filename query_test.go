@@ -1,8 +1,12 @@
 package chromem
 
 import (
+	"cmp"
+	"context"
+	"math/rand"
 	"reflect"
 	"slices"
+	"strconv"
 	"testing"
 )
 
@@ -24,6 +28,14 @@ func TestFilterDocs(t *testing.T) {
 			Embedding: []float32{0.2, 0.3, 0.4},
 			Content:   "hallo welt",
 		},
+		"3": {
+			ID: "3",
+			Metadata: map[string]string{
+				"language": "fr",
+			},
+			Embedding: []float32{0.3, 0.4, 0.5},
+			Content:   "Bonjour,  le   Cafe\u0301 ",
+		},
 	}
 
 	tt := []struct {
@@ -40,7 +52,7 @@ func TestFilterDocs(t *testing.T) {
 		},
 		{
 			name:          "meta no match",
-			where:         map[string]string{"language": "fr"},
+			where:         map[string]string{"language": "it"},
 			whereDocument: nil,
 			want:          nil,
 		},
@@ -66,13 +78,13 @@ func TestFilterDocs(t *testing.T) {
 			name:          "content not_contains all",
 			where:         nil,
 			whereDocument: map[string]string{"$not_contains": "bonjour"},
-			want:          []*Document{docs["1"], docs["2"]},
+			want:          []*Document{docs["1"], docs["2"], docs["3"]},
 		},
 		{
 			name:          "content not_contains one",
 			where:         nil,
 			whereDocument: map[string]string{"$not_contains": "hello"},
-			want:          []*Document{docs["2"]},
+			want:          []*Document{docs["2"], docs["3"]},
 		},
 		{
 			name:          "meta and content match",
@@ -86,6 +98,84 @@ func TestFilterDocs(t *testing.T) {
 			whereDocument: map[string]string{"$contains": "hallo", "$not_contains": "bonjour"},
 			want:          []*Document{docs["2"]},
 		},
+		{
+			name:          "content starts_with",
+			where:         nil,
+			whereDocument: map[string]string{"$starts_with": "hallo"},
+			want:          []*Document{docs["2"]},
+		},
+		{
+			name:          "content ends_with",
+			where:         nil,
+			whereDocument: map[string]string{"$ends_with": "world"},
+			want:          []*Document{docs["1"]},
+		},
+		{
+			name:          "content contains case-sensitive",
+			where:         nil,
+			whereDocument: map[string]string{"$contains": "bonjour"},
+			want:          nil,
+		},
+		{
+			name:          "content contains ignore_case",
+			where:         nil,
+			whereDocument: map[string]string{"$contains": "BONJOUR", "$options": "ignore_case"},
+			want:          []*Document{docs["3"]},
+		},
+		{
+			name:          "content contains normalize",
+			where:         nil,
+			whereDocument: map[string]string{"$contains": "le Café", "$options": "normalize"},
+			want:          []*Document{docs["3"]},
+		},
+		{
+			name:          "content starts_with and ends_with ignore_case and normalize",
+			where:         nil,
+			whereDocument: map[string]string{"$starts_with": "bonjour, le", "$ends_with": "CAFÉ", "$options": "ignore_case,normalize"},
+			want:          []*Document{docs["3"]},
+		},
+		{
+			name:          "content regex",
+			where:         nil,
+			whereDocument: map[string]string{"$regex": `^h[ae]llo\s`},
+			want:          []*Document{docs["1"], docs["2"]},
+		},
+		{
+			name:          "content regex ignore_case",
+			where:         nil,
+			whereDocument: map[string]string{"$regex": `^BONJOUR,`, "$options": "ignore_case"},
+			want:          []*Document{docs["3"]},
+		},
+		{
+			name:          "content glob",
+			where:         nil,
+			whereDocument: map[string]string{"$glob": "h?llo w*"},
+			want:          []*Document{docs["1"], docs["2"]},
+		},
+		{
+			name:          "content glob whole content",
+			where:         nil,
+			whereDocument: map[string]string{"$glob": "hello"},
+			want:          nil,
+		},
+		{
+			name:          "content phrase",
+			where:         nil,
+			whereDocument: map[string]string{"$contains_phrase": "hello world"},
+			want:          []*Document{docs["1"]},
+		},
+		{
+			name:          "content phrase whole terms",
+			where:         nil,
+			whereDocument: map[string]string{"$contains_phrase": "hello wor"},
+			want:          nil,
+		},
+		{
+			name:          "content phrase normalized",
+			where:         nil,
+			whereDocument: map[string]string{"$contains_phrase": "bonjour le cafe", "$options": "ignore_case,normalize"},
+			want:          []*Document{docs["3"]},
+		},
 	}
 
 	for _, tc := range tt {
@@ -93,16 +183,81 @@ func TestFilterDocs(t *testing.T) {
 			got := filterDocs(docs, tc.where, tc.whereDocument)
 
 			if !reflect.DeepEqual(got, tc.want) {
-				// If len is 2, the order might be different (function under test
-				// is concurrent and order is not guaranteed).
-				if len(got) == 2 && len(tc.want) == 2 {
-					slices.Reverse(got)
-					if reflect.DeepEqual(got, tc.want) {
-						return
-					}
+				// The order might be different (function under test is
+				// concurrent and order is not guaranteed).
+				slices.SortFunc(got, func(a, b *Document) int { return cmp.Compare(a.ID, b.ID) })
+				if reflect.DeepEqual(got, tc.want) {
+					return
 				}
 				t.Fatalf("got %v; want %v", got, tc.want)
 			}
 		})
 	}
 }
+
+func TestGetMostSimilarDocs_EarlyAbandon(t *testing.T) {
+	ctx := context.Background()
+	r := rand.New(rand.NewSource(42))
+	// Like embeddings of Matryoshka models, where the leading dimensions carry
+	// most of the information, so that many documents are abandoned early.
+	d := 768
+	randomVector := func() []float32 {
+		v := make([]float32, d)
+		for i := range v {
+			v[i] = (r.Float32() - 0.5) / float32(1+i/32)
+		}
+		return normalizeVector(v)
+	}
+
+	docs := make([]*Document, 1000)
+	for i := range docs {
+		docs[i] = &Document{ID: strconv.Itoa(i), Embedding: randomVector()}
+	}
+	query := randomVector()
+
+	// Expected results via the plain dot product
+	want := make([]docSim, 0, len(docs))
+	for _, doc := range docs {
+		sim, err := dotProduct(query, doc.Embedding)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		want = append(want, docSim{docID: doc.ID, similarity: sim})
+	}
+	slices.SortFunc(want, func(a, b docSim) int {
+		return cmp.Compare(b.similarity, a.similarity)
+	})
+
+	got, err := getMostSimilarDocs(ctx, query, docs, 10)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(got) != 10 {
+		t.Fatal("expected 10 results, got", len(got))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatal("expected", want[i], "got", got[i])
+		}
+	}
+
+	// Abandoned documents must get a value below the minimum similarity.
+	ea := newEarlyAbandon(query)
+	abandoned := 0
+	for _, doc := range docs {
+		exact, _ := dotProduct(query, doc.Embedding)
+		sim, err := ea.dotProduct(doc.Embedding, want[9].similarity)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if sim != exact {
+			if sim >= want[9].similarity || sim < exact {
+				t.Fatal("expected an upper bound below", want[9].similarity, "got", sim, "for similarity", exact)
+			}
+			abandoned++
+		}
+	}
+	if abandoned == 0 {
+		t.Fatal("expected documents to be abandoned early, got none")
+	}
+}
@@ -0,0 +1,103 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultContextTemplate is the default template of [ContextOptions]. It
+// numbers the results for citations and adds their title, if they have one.
+const DefaultContextTemplate = "[{{.Number}}]{{with .Metadata.title}} {{.}}{{end}}\n{{.Content}}"
+
+// ContextOptions configures a [ContextFormatter].
+type ContextOptions struct {
+	// Template is a [text/template] for each result, which is executed with
+	// [ContextData], e.g. "{{.Metadata.source}}: {{.Content}}". Optional,
+	// defaults to [DefaultContextTemplate].
+	Template string
+	// Separator is written between the results. Optional, defaults to an empty
+	// line.
+	Separator string
+	// MaxTokens is the token budget of the context, e.g. what's left of the
+	// LLM's context for it after the prompt. Optional, 0 means no limit.
+	MaxTokens int
+	// CountTokens counts the tokens. Optional, defaults to
+	// [CountTokensApprox].
+	CountTokens TokenCounter
+}
+
+// ContextData is the data of the template of [ContextOptions].
+type ContextData struct {
+	Result
+	// Number is the result's position, starting at 1, e.g. for citations.
+	Number int
+}
+
+// ContextFormatter formats query results into the context of an LLM prompt for
+// retrieval augmented generation (RAG). Create it with [NewContextFormatter]
+// once and use it for all queries. It's safe for concurrent use.
+type ContextFormatter struct {
+	tmpl        *template.Template
+	separator   string
+	maxTokens   int
+	countTokens TokenCounter
+}
+
+// NewContextFormatter returns a formatter with the given options.
+func NewContextFormatter(options ContextOptions) (*ContextFormatter, error) {
+	if options.MaxTokens < 0 {
+		return nil, errors.New("max tokens must be >= 0")
+	}
+	text := options.Template
+	if text == "" {
+		text = DefaultContextTemplate
+	}
+	tmpl, err := template.New("context").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse context template: %w", err)
+	}
+	f := &ContextFormatter{
+		tmpl:        tmpl,
+		separator:   options.Separator,
+		maxTokens:   options.MaxTokens,
+		countTokens: options.CountTokens,
+	}
+	if f.separator == "" {
+		f.separator = "\n\n"
+	}
+	if f.countTokens == nil {
+		f.countTokens = CountTokensApprox
+	}
+	return f, nil
+}
+
+// Format formats the results with the template, in their order, until the
+// token budget is exhausted. The result that exceeds the budget and all later
+// ones are left out, so that the most relevant results are kept. It returns
+// the context and the number of results it contains.
+func (f *ContextFormatter) Format(results []Result) (string, int, error) {
+	var sb strings.Builder
+	tokens := 0
+	for i, res := range results {
+		var entry strings.Builder
+		err := f.tmpl.Execute(&entry, ContextData{Result: res, Number: i + 1})
+		if err != nil {
+			return "", 0, fmt.Errorf("couldn't execute context template for result '%s': %w", res.ID, err)
+		}
+		text := entry.String()
+		if i > 0 {
+			text = f.separator + text
+		}
+		if f.maxTokens > 0 {
+			// The sum of the parts' counts approximates the count of the context.
+			tokens += f.countTokens(text)
+			if tokens > f.maxTokens {
+				return sb.String(), i, nil
+			}
+		}
+		sb.WriteString(text)
+	}
+	return sb.String(), len(results), nil
+}
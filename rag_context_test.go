@@ -0,0 +1,58 @@
+package chromem
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContextFormatter(t *testing.T) {
+	results := []Result{
+		{ID: "1", Metadata: map[string]string{"title": "Intro"}, Content: "one two three"},
+		{ID: "2", Content: "four five"},
+		{ID: "3", Metadata: map[string]string{"title": "Outro"}, Content: "six"},
+	}
+
+	f, err := NewContextFormatter(ContextOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	context, n, err := f.Format(results)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	expected := "[1] Intro\none two three\n\n[2]\nfour five\n\n[3] Outro\nsix"
+	if context != expected || n != 3 {
+		t.Fatal("expected", expected, "got", context, n)
+	}
+
+	// The budget cuts off at the first result that doesn't fit.
+	f, err = NewContextFormatter(ContextOptions{
+		Template:    "{{.ID}}: {{.Content}}",
+		Separator:   "\n",
+		MaxTokens:   6,
+		CountTokens: func(text string) int { return len(strings.Fields(text)) },
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	context, n, err = f.Format(results)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if context != "1: one two three" || n != 1 {
+		t.Fatal("expected only the first result, got", context, n)
+	}
+
+	_, err = NewContextFormatter(ContextOptions{Template: "{{.Unknown"})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	f, err = NewContextFormatter(ContextOptions{Template: "{{.Unknown}}"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, _, err = f.Format(results)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,627 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// Directory of the progress files of a running or interrupted re-embedding
+	// in a persistent collection's directory, see [Collection.Reembed].
+	reembedDirName = "reembed"
+	// Number of documents that are read, embedded and persisted at once.
+	reembedBatchSize = 1024
+)
+
+// ReembedOptions configures [Collection.ReembedWithOptions].
+type ReembedOptions struct {
+	// The new embedding function. Mandatory.
+	EmbeddingFunc EmbeddingFunc
+
+	// The name of the new model, recorded in the collection and documents
+	// (see [Collection.SetEmbeddingModel]). Mandatory, it's also used to
+	// determine whether to resume a previous run.
+	Model string
+
+	// The number of concurrent calls to the embedding function. Must be at
+	// least 1.
+	Concurrency int
+
+	// Called after each re-embedded document with the number of done documents
+	// and the total. Optional.
+	Progress func(done, total int)
+
+	// Shadow is a collection that the re-embedded documents are written to,
+	// instead of swapping the embeddings of this collection. This collection
+	// keeps its embeddings, embedding function and model, so it can still be
+	// used while the new embeddings are evaluated with the shadow collection,
+	// e.g. by comparing query results. The shadow collection should be created
+	// with the new embedding function, and its model is set to Model. The
+	// documents are copied with their readable content, so a shadow collection
+	// of a collection with content encryption should have it enabled as well.
+	// Optional.
+	Shadow *Collection
+}
+
+// reembedState holds the new embeddings of a running or interrupted re-embedding,
+// so that it can be resumed.
+type reembedState struct {
+	model string
+	// docID -> new embedding plus the version and the hash of the embedded text
+	// of the document it was created from, so we can detect documents that were
+	// changed during the re-embedding.
+	embeddings map[string]reembeddedDoc
+	// Sequence number of the next progress file
+	seq int
+
+	shadow *Collection
+	// docID -> version of the documents that were copied to the shadow
+	// collection
+	copied map[string]uint64
+}
+
+type reembeddedDoc struct {
+	version     uint64
+	contentHash string
+	embedding   []float32
+}
+
+// persistedReembedding is the content of a progress file of a re-embedding,
+// with the embeddings that were created in one batch.
+type persistedReembedding struct {
+	Model string
+	Docs  []persistedReembeddedDoc
+}
+
+type persistedReembeddedDoc struct {
+	ID          string
+	Version     uint64
+	ContentHash string
+	Embedding   []float32
+}
+
+// Reembed re-computes the embeddings of all documents with a new embedding function,
+// for example after upgrading to a new embedding model. All documents must have
+// content. Documents that were added with an embedding text (see
+// [Document.EmbeddingText]) are embedded with it again. It's like
+// [Collection.ReembedWithOptions] without a shadow collection.
+//
+// The new embeddings are created in the background while the collection can still
+// be queried and modified, using the old embeddings. Only when all documents are
+// re-embedded, the embeddings, the collection's embedding function and the model
+// name are swapped at once (and persisted if the DB is persistent). That way queries
+// never compare vectors from different models. The documents get new versions
+// (see [Document.Version]), like when they're updated.
+//
+// If the re-embedding fails or the context is canceled, the already created
+// embeddings are kept, and calling Reembed again with the same model name
+// resumes where it stopped. For persistent collections they're also persisted,
+// so that a re-embedding can be resumed after a crash or restart. Documents
+// that were added or changed in the meantime are re-embedded as well. If only
+// persisting the swap fails or is interrupted, calling Reembed again with the
+// same model name completes it without creating embeddings again.
+//
+//   - embeddingFunc: The new embedding function. Mandatory.
+//   - model: The name of the new model, recorded in the collection and documents
+//     (see [Collection.SetEmbeddingModel]). Mandatory, it's also used to determine
+//     whether to resume a previous run.
+//   - concurrency: The number of concurrent calls to the embedding function.
+//   - progress: Called after each re-embedded document with the number of done
+//     documents and the total. Optional.
+func (c *Collection) Reembed(ctx context.Context, embeddingFunc EmbeddingFunc, model string, concurrency int, progress func(done, total int)) error {
+	return c.ReembedWithOptions(ctx, ReembedOptions{
+		EmbeddingFunc: embeddingFunc,
+		Model:         model,
+		Concurrency:   concurrency,
+		Progress:      progress,
+	})
+}
+
+// ReembedWithOptions re-computes the embeddings of all documents with a new
+// embedding function, like [Collection.Reembed]. The documents are read and
+// embedded in batches, so they aren't copied all at once.
+//
+// With a shadow collection (see [ReembedOptions.Shadow]), the re-embedded
+// documents are added to it batch by batch instead of swapping the embeddings
+// of this collection. Documents that are added, changed or deleted in this
+// collection during the re-embedding are added, updated or deleted in the
+// shadow collection as well, but later changes aren't.
+func (c *Collection) ReembedWithOptions(ctx context.Context, options ReembedOptions) error {
+	if options.EmbeddingFunc == nil {
+		return errors.New("embeddingFunc is nil")
+	}
+	if options.Model == "" {
+		return errors.New("model is empty")
+	}
+	if options.Concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+	if options.Shadow == c {
+		return errors.New("shadow collection must not be the collection itself")
+	}
+
+	c.reembedLock.Lock()
+	defer c.reembedLock.Unlock()
+	if c.reembedding == nil || c.reembedding.model != options.Model {
+		state, err := c.loadReembedState(options.Model)
+		if err != nil {
+			return err
+		}
+		c.reembedding = state
+	}
+	state := c.reembedding
+	if state.shadow != options.Shadow {
+		state.shadow = options.Shadow
+		state.copied = make(map[string]uint64)
+	}
+	if options.Shadow != nil {
+		err := options.Shadow.SetEmbeddingModel(options.Model)
+		if err != nil {
+			return fmt.Errorf("couldn't set model of shadow collection: %w", err)
+		}
+	}
+
+	// Documents can be added or changed while we're creating the embeddings, so
+	// we repeat until there's nothing left to do when we hold the lock for the
+	// swap. A shadow collection isn't swapped, so the read lock suffices.
+	lock, unlock := c.documentsLock.Lock, c.documentsLock.Unlock
+	if options.Shadow != nil {
+		lock, unlock = c.documentsLock.RLock, c.documentsLock.RUnlock
+	}
+	for {
+		lock()
+		if c.closed {
+			unlock()
+			return ErrClosed
+		}
+		ids, embeds, err := state.pending(c)
+		if err != nil {
+			unlock()
+			return err
+		}
+		if len(ids) == 0 {
+			var deleted []string
+			if options.Shadow == nil {
+				err = c.swapEmbeddings(state, options.EmbeddingFunc)
+			} else {
+				deleted = state.deleted(c)
+			}
+			unlock()
+			if err == nil && len(deleted) > 0 {
+				err = options.Shadow.Delete(ctx, nil, nil, deleted...)
+				if err != nil {
+					err = fmt.Errorf("couldn't delete documents from shadow collection: %w", err)
+				}
+			}
+			if err == nil {
+				c.reembedding = nil
+				err = c.removeReembedProgress()
+			}
+			return err
+		}
+		total := len(c.documents)
+		unlock()
+
+		done := total - embeds
+		for len(ids) > 0 {
+			n := min(len(ids), reembedBatchSize)
+			embedded, err := c.reembedBatch(ctx, state, options, ids[:n], done, total)
+			if err != nil {
+				return err
+			}
+			done += embedded
+			ids = ids[n:]
+		}
+	}
+}
+
+// pending returns the IDs of the documents that still need to be re-embedded
+// or copied to the shadow collection, sorted, and how many of them need to be
+// re-embedded. Must be called while holding the documents lock.
+func (s *reembedState) pending(c *Collection) ([]string, int, error) {
+	var ids []string
+	embeds := 0
+	for id, doc := range c.documents {
+		current, err := s.current(c, doc)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !current {
+			embeds++
+		} else if s.shadow != nil {
+			version, ok := s.copied[id]
+			current = ok && version == doc.Version
+		}
+		if !current {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids, embeds, nil
+}
+
+// current returns whether the document's new embedding was created from its
+// current content. Must be called while holding the documents lock.
+func (s *reembedState) current(c *Collection, doc *Document) (bool, error) {
+	rd, ok := s.embeddings[doc.ID]
+	if !ok {
+		return false, nil
+	}
+	if rd.version == doc.Version {
+		return true, nil
+	}
+	// The document was replaced, but maybe only its metadata changed. The
+	// content might have been evicted from memory or be encrypted.
+	doc, err := c.readable(doc)
+	if err != nil {
+		return false, err
+	}
+	return rd.contentHash == contentHash(doc.embeddingText()), nil
+}
+
+// deleted returns the IDs of the documents that were copied to the shadow
+// collection, but were deleted from the collection since. Must be called while
+// holding the documents lock.
+func (s *reembedState) deleted(c *Collection) []string {
+	var ids []string
+	for id := range s.copied {
+		if _, ok := c.documents[id]; !ok {
+			ids = append(ids, id)
+			delete(s.copied, id)
+		}
+	}
+	return ids
+}
+
+// reembedBatch creates the new embeddings of the documents with the IDs that
+// aren't current, persists them, and copies the documents to the shadow
+// collection if there is one. It returns the number of created embeddings.
+func (c *Collection) reembedBatch(ctx context.Context, state *reembedState, options ReembedOptions, ids []string, done, total int) (int, error) {
+	c.documentsLock.RLock()
+	var todo []Document
+	for _, id := range ids {
+		doc, ok := c.documents[id]
+		if !ok {
+			// Deleted in the meantime
+			continue
+		}
+		current, err := state.current(c, doc)
+		if err == nil && !current {
+			doc, err = c.readable(doc)
+			if err == nil {
+				todo = append(todo, *doc)
+			}
+		}
+		if err != nil {
+			c.documentsLock.RUnlock()
+			return 0, err
+		}
+	}
+	c.documentsLock.RUnlock()
+
+	embedded, err := state.embed(ctx, c.CurrentName(), options.EmbeddingFunc, todo, options.Concurrency, done, total, options.Progress)
+	// The embeddings that were created are persisted even if others failed, so
+	// that they don't have to be created again after a crash.
+	err = errors.Join(err, c.persistReembedProgress(state, embedded))
+	if err != nil {
+		return len(embedded), err
+	}
+
+	if state.shadow != nil {
+		err = c.copyToShadow(ctx, state, ids, options.Concurrency)
+	}
+	return len(embedded), err
+}
+
+// embed creates the embeddings of the given documents concurrently and stores
+// them in the state. It returns the IDs of the documents that were embedded,
+// which are all of them unless there's an error.
+func (s *reembedState) embed(ctx context.Context, collection string, embeddingFunc EmbeddingFunc, docs []Document, concurrency, done, total int, progress func(done, total int)) ([]string, error) {
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+			// Cancel the operation for all other goroutines.
+			cancel(sharedErr)
+		}
+	}
+
+	// Guards the state's map, the embedded IDs and the progress counter.
+	stateLock := sync.Mutex{}
+	embedded := make([]string, 0, len(docs))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for _, doc := range docs {
+		wg.Add(1)
+		go func(doc Document) {
+			defer wg.Done()
+
+			// Don't even start if another goroutine already failed.
+			if ctx.Err() != nil {
+				return
+			}
+
+			// Wait here while $concurrency other goroutines are creating embeddings.
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if doc.Content == "" {
+				setSharedErr(fmt.Errorf("document '%s' has no content to re-embed", doc.ID))
+				return
+			}
+			embedding, err := embeddingFunc(embeddingContext(ctx, collection, EmbeddingOperationReembed, doc.ID), doc.embeddingText())
+			if err != nil {
+				setSharedErr(fmt.Errorf("couldn't re-embed document '%s': %w", doc.ID, err))
+				return
+			}
+
+			stateLock.Lock()
+			defer stateLock.Unlock()
+			s.embeddings[doc.ID] = reembeddedDoc{
+				version:     doc.Version,
+				contentHash: contentHash(doc.embeddingText()),
+				embedding:   embedding,
+			}
+			embedded = append(embedded, doc.ID)
+			done++
+			if progress != nil {
+				progress(done, total)
+			}
+		}(doc)
+	}
+
+	wg.Wait()
+
+	// Goroutines that didn't start because the context was canceled don't set
+	// the error.
+	if sharedErr == nil {
+		sharedErr = context.Cause(ctx)
+	}
+	return embedded, sharedErr
+}
+
+// copyToShadow adds the documents with the IDs whose new embedding is current
+// to the shadow collection, unless they were copied already. Documents that
+// were changed in the meantime are re-embedded and copied in the next round.
+func (c *Collection) copyToShadow(ctx context.Context, state *reembedState, ids []string, concurrency int) error {
+	c.documentsLock.RLock()
+	var docs []Document
+	var versions []uint64
+	for _, id := range ids {
+		doc, ok := c.documents[id]
+		if !ok {
+			continue
+		}
+		if version, ok := state.copied[id]; ok && version == doc.Version {
+			continue
+		}
+		current, err := state.current(c, doc)
+		if err != nil {
+			c.documentsLock.RUnlock()
+			return err
+		}
+		if !current {
+			continue
+		}
+		cp, err := c.readableCopy(doc)
+		if err != nil {
+			c.documentsLock.RUnlock()
+			return err
+		}
+		cp.Embedding = slices.Clone(state.embeddings[id].embedding)
+		cp.EmbeddingModel = state.model
+		docs = append(docs, cp)
+		versions = append(versions, doc.Version)
+	}
+	c.documentsLock.RUnlock()
+
+	if len(docs) == 0 {
+		return nil
+	}
+	err := state.shadow.AddDocuments(ctx, docs, concurrency)
+	if err != nil {
+		return fmt.Errorf("couldn't add documents to shadow collection: %w", err)
+	}
+	for i, doc := range docs {
+		state.copied[doc.ID] = versions[i]
+	}
+	return nil
+}
+
+// swapEmbeddings replaces the documents' embeddings with the new ones, sets the
+// new embedding function and model and persists everything. The documents get
+// new versions, like when they're updated with [Collection.AddDocument].
+// Must be called while holding the documents write lock.
+func (c *Collection) swapEmbeddings(state *reembedState, embeddingFunc EmbeddingFunc) error {
+	if c.closed {
+		return ErrClosed
+	}
+
+	// First build the new documents and write their files to temporary paths,
+	// so that an error doesn't leave the collection half converted.
+	newDocs := make(map[string]*Document, len(c.documents))
+	lastVersion := c.lastVersion
+	for id, doc := range c.documents {
+		// We replace the document instead of modifying it, because the old one
+		// might still be referenced, e.g. by cached query results.
+		newDoc := *doc
+		// The policy is decided, as there are documents.
+		newDoc.Embedding = c.normalization.apply(state.embeddings[id].embedding)
+		newDoc.EmbeddingModel = state.model
+		lastVersion++
+		newDoc.Version = lastVersion
+		newDocs[id] = &newDoc
+	}
+	if c.persistDirectory != "" {
+		files := documentFiles{c: c}
+		for _, newDoc := range newDocs {
+			doc, err := c.withContent(newDoc)
+			if err == nil {
+				doc, err = c.encryptedForDisk(doc)
+			}
+			if err == nil {
+				err = files.write(doc)
+			}
+			if err != nil {
+				files.discard()
+				return err
+			}
+		}
+		// If moving the files fails, some of them have the new embeddings, but
+		// the metadata still has the old model. Calling Reembed again with the
+		// same model then swaps the embeddings again, as they're all created.
+		err := files.commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	var usage int64
+	if c.memory != nil {
+		usage = -documentsMemoryUsage(c.documents)
+	}
+	for id, newDoc := range newDocs {
+		c.documents[id] = newDoc
+		// The content didn't change, so only the version of the index entry.
+		c.bm25.setVersion(id, newDoc.Version)
+	}
+	c.lastVersion = lastVersion
+	c.column.rebuild(c.documents)
+	if c.memory != nil {
+		c.memory.adjust(usage + documentsMemoryUsage(c.documents))
+	}
+	// The centroids are in the old model's vector space.
+	c.ivf = nil
+	c.embed = embeddingFunc
+	c.embeddingModel = state.model
+	// All documents have the new model now.
+	c.modelMismatches = 0
+	// The new embeddings have the original dimensions.
+	c.projection = nil
+	c.invalidateQueryCache()
+
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// loadReembedState returns the state of a re-embedding with the model, with
+// the embeddings of an interrupted run that were persisted. The progress of a
+// run with another model is removed.
+func (c *Collection) loadReembedState(model string) (*reembedState, error) {
+	state := &reembedState{
+		model:      model,
+		embeddings: make(map[string]reembeddedDoc),
+	}
+	if c.persistDirectory == "" {
+		return state, nil
+	}
+
+	dir := filepath.Join(c.persistDirectory, reembedDirName)
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("couldn't read re-embedding progress directory: %w", err)
+	}
+	for _, dirEntry := range dirEntries {
+		// Temporary files of interrupted writes don't have the extension.
+		stem, ok := strings.CutSuffix(dirEntry.Name(), c.fileExt())
+		if !ok || dirEntry.IsDir() {
+			continue
+		}
+		seq, err := strconv.Atoi(stem)
+		if err != nil {
+			continue
+		}
+		pr := persistedReembedding{}
+		err = readFromFile(filepath.Join(dir, dirEntry.Name()), &pr, c.encoding, "")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read re-embedding progress: %w", err)
+		}
+		if pr.Model != model {
+			// Progress of another model, which is outdated now.
+			err := c.removeReembedProgress()
+			if err != nil {
+				return nil, err
+			}
+			return &reembedState{model: model, embeddings: make(map[string]reembeddedDoc)}, nil
+		}
+		for _, d := range pr.Docs {
+			state.embeddings[d.ID] = reembeddedDoc{
+				version:     d.Version,
+				contentHash: d.ContentHash,
+				embedding:   d.Embedding,
+			}
+		}
+		state.seq = max(state.seq, seq+1)
+	}
+	return state, nil
+}
+
+// persistReembedProgress writes the new embeddings of the documents with the
+// IDs to a new progress file. It's a no-op for non-persistent collections.
+func (c *Collection) persistReembedProgress(state *reembedState, ids []string) error {
+	if c.persistDirectory == "" || len(ids) == 0 {
+		return nil
+	}
+	pr := persistedReembedding{
+		Model: state.model,
+		Docs:  make([]persistedReembeddedDoc, 0, len(ids)),
+	}
+	for _, id := range ids {
+		rd := state.embeddings[id]
+		pr.Docs = append(pr.Docs, persistedReembeddedDoc{
+			ID:          id,
+			Version:     rd.version,
+			ContentHash: rd.contentHash,
+			Embedding:   rd.embedding,
+		})
+	}
+
+	// The file is written to a temporary path first, so that a crash can't
+	// leave a partially written file.
+	path := filepath.Join(c.persistDirectory, reembedDirName, fmt.Sprintf("%08d%s", state.seq, c.fileExt()))
+	tmpPath := path + ".tmp"
+	err := persistToFile(tmpPath, pr, c.encoding, c.compress, "", c.perms)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("couldn't persist re-embedding progress: %w", err)
+	}
+	state.seq++
+	return nil
+}
+
+// removeReembedProgress removes the progress files of a re-embedding. It's a
+// no-op for non-persistent collections.
+func (c *Collection) removeReembedProgress() error {
+	if c.persistDirectory == "" {
+		return nil
+	}
+	err := os.RemoveAll(filepath.Join(c.persistDirectory, reembedDirName))
+	if err != nil {
+		return fmt.Errorf("couldn't remove re-embedding progress: %w", err)
+	}
+	return nil
+}
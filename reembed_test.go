@@ -0,0 +1,465 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCollection_Reembed(t *testing.T) {
+	ctx := context.Background()
+	oldVectors := []float32{1, 0, 0}
+	newVectors := []float32{0, 1, 0}
+	oldEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return oldVectors, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, oldEmbeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar"},
+		{ID: "3", Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// First run fails on one document
+	var calls atomic.Int32
+	fail := true
+	newEmbeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		calls.Add(1)
+		if fail && text == "baz" {
+			return nil, errors.New("provider down")
+		}
+		return newVectors, nil
+	}
+	err = c.Reembed(ctx, newEmbeddingFunc, "new-model", 1, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Nothing must be swapped yet
+	if c.EmbeddingModel() != "" {
+		t.Fatal("expected empty model, got", c.EmbeddingModel())
+	}
+	if !slices.Equal(c.documents["1"].Embedding, oldVectors) {
+		t.Fatal("expected old embedding, got", c.documents["1"].Embedding)
+	}
+	// The order is not deterministic, so we only know that the failed document
+	// is not done.
+	doneBefore := len(c.reembedding.embeddings)
+	if doneBefore > 2 {
+		t.Fatal("expected at most 2 done documents, got", doneBefore)
+	}
+
+	// Second run resumes and only embeds the remaining documents
+	fail = false
+	calls.Store(0)
+	var lastDone, lastTotal int
+	err = c.Reembed(ctx, newEmbeddingFunc, "new-model", 1, func(done, total int) {
+		lastDone, lastTotal = done, total
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if int(calls.Load()) != 3-doneBefore {
+		t.Fatal("expected", 3-doneBefore, "calls, got", calls.Load())
+	}
+	if lastDone != 3 || lastTotal != 3 {
+		t.Fatal("expected progress 3/3, got", lastDone, lastTotal)
+	}
+	if c.EmbeddingModel() != "new-model" {
+		t.Fatal("expected new-model, got", c.EmbeddingModel())
+	}
+	for id, doc := range c.documents {
+		if !slices.Equal(doc.Embedding, newVectors) {
+			t.Fatal("expected new embedding for", id, "got", doc.Embedding)
+		}
+	}
+
+	// New documents use the new embedding func
+	err = c.AddDocument(ctx, Document{ID: "4", Content: "qux"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !slices.Equal(c.documents["4"].Embedding, newVectors) {
+		t.Fatal("expected new embedding, got", c.documents["4"].Embedding)
+	}
+
+	// Persisted
+	db2, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", newEmbeddingFunc)
+	if c2.EmbeddingModel() != "new-model" {
+		t.Fatal("expected new-model, got", c2.EmbeddingModel())
+	}
+	if !slices.Equal(c2.documents["1"].Embedding, newVectors) {
+		t.Fatal("expected new embedding, got", c2.documents["1"].Embedding)
+	}
+}
+
+func TestCollection_Reembed_Resume(t *testing.T) {
+	ctx := context.Background()
+	oldEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, oldEmbeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar"},
+		{ID: "3", Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The first run is canceled after two documents.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var calls atomic.Int32
+	newEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		if calls.Add(1) == 2 {
+			cancel()
+		}
+		return []float32{0, 1, 0}, nil
+	}
+	err = c.Reembed(runCtx, newEmbeddingFunc, "new-model", 1, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+
+	// A new DB instance, like after a crash, resumes from the persisted progress.
+	db2, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", oldEmbeddingFunc)
+	// A document that only changed its metadata isn't embedded again.
+	err = c2.AddDocument(ctx, Document{ID: "1", Content: "foo", Metadata: map[string]string{"a": "b"}, Embedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	calls.Store(0)
+	err = c2.Reembed(ctx, newEmbeddingFunc, "new-model", 1, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatal("expected 1 call, got", calls.Load())
+	}
+	if c2.EmbeddingModel() != "new-model" {
+		t.Fatal("expected new-model, got", c2.EmbeddingModel())
+	}
+	// The progress is removed after the swap.
+	_, err = os.Stat(filepath.Join(c2.persistDirectory, reembedDirName))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("expected progress to be removed, got", err)
+	}
+}
+
+func TestCollection_Reembed_Shadow(t *testing.T) {
+	ctx := context.Background()
+	oldVectors := []float32{1, 0, 0}
+	newVectors := []float32{0, 1, 0}
+	oldEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return oldVectors, nil
+	}
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, oldEmbeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar"},
+		{ID: "3", Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The documents are changed while they're re-embedded: document 2 after
+	// the first round, and document 3 after it was copied to the shadow
+	// collection.
+	var calls atomic.Int32
+	newEmbeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		calls.Add(1)
+		var err error
+		switch text {
+		case "foo":
+			err = c.AddDocument(ctx, Document{ID: "2", Content: "bar2", Embedding: oldVectors})
+		case "bar2":
+			err = c.Delete(ctx, nil, nil, "3")
+		}
+		return newVectors, err
+	}
+	shadow, err := db.CreateCollection("shadow", nil, newEmbeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.ReembedWithOptions(ctx, ReembedOptions{
+		EmbeddingFunc: newEmbeddingFunc,
+		Model:         "new-model",
+		Concurrency:   1,
+		Shadow:        shadow,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if calls.Load() != 4 {
+		t.Fatal("expected 4 calls, got", calls.Load())
+	}
+
+	// The collection is unchanged.
+	if c.EmbeddingModel() != "" {
+		t.Fatal("expected empty model, got", c.EmbeddingModel())
+	}
+	if !slices.Equal(c.documents["1"].Embedding, oldVectors) {
+		t.Fatal("expected old embedding, got", c.documents["1"].Embedding)
+	}
+	if c.reembedding != nil {
+		t.Fatal("expected no re-embedding state, got", c.reembedding)
+	}
+
+	// The shadow collection has the current documents with the new embeddings.
+	if shadow.EmbeddingModel() != "new-model" {
+		t.Fatal("expected new-model, got", shadow.EmbeddingModel())
+	}
+	if shadow.Count() != 2 {
+		t.Fatal("expected 2 documents, got", shadow.Count())
+	}
+	for id, doc := range shadow.documents {
+		if !slices.Equal(doc.Embedding, newVectors) {
+			t.Fatal("expected new embedding for", id, "got", doc.Embedding)
+		}
+		if doc.EmbeddingModel != "new-model" {
+			t.Fatal("expected new-model for", id, "got", doc.EmbeddingModel)
+		}
+	}
+	if shadow.documents["2"].Content != "bar2" {
+		t.Fatal("expected bar2, got", shadow.documents["2"].Content)
+	}
+
+	if err := c.ReembedWithOptions(ctx, ReembedOptions{EmbeddingFunc: newEmbeddingFunc, Model: "new-model", Concurrency: 1, Shadow: c}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_Reembed_Errors(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0, 0}, nil
+	}
+	c, err := NewDB().CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if err := c.Reembed(ctx, nil, "model", 1, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := c.Reembed(ctx, embeddingFunc, "", 1, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if err := c.Reembed(ctx, embeddingFunc, "model", 0, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Document without content
+	if err := c.Reembed(ctx, embeddingFunc, "model", 1, nil); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_Reembed_SwapFailure(t *testing.T) {
+	ctx := context.Background()
+	oldVectors := []float32{1, 0, 0}
+	newVectors := []float32{0, 1, 0}
+	oldEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return oldVectors, nil
+	}
+	var calls atomic.Int32
+	newEmbeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		calls.Add(1)
+		return newVectors, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, oldEmbeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "foo"},
+		{ID: "2", Content: "bar"},
+		{ID: "3", Content: "baz"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	version := c.documents["1"].Version
+
+	// Writing the file of one document fails, so nothing must be swapped.
+	blocker := c.getDocPath("2") + ".tmp"
+	err = os.Mkdir(blocker, 0o700)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Reembed(ctx, newEmbeddingFunc, "new-model", 1, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if c.EmbeddingModel() != "" {
+		t.Fatal("expected empty model, got", c.EmbeddingModel())
+	}
+	db2, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for id, doc := range db2.GetCollection("test", nil).documents {
+		if !slices.Equal(doc.Embedding, oldVectors) {
+			t.Fatal("expected old embedding on disk for", id, "got", doc.Embedding)
+		}
+	}
+
+	// Trying again completes the swap without embedding again.
+	err = os.RemoveAll(blocker)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	calls.Store(0)
+	err = c.Reembed(ctx, newEmbeddingFunc, "new-model", 1, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatal("expected no calls, got", calls.Load())
+	}
+	if c.EmbeddingModel() != "new-model" {
+		t.Fatal("expected new-model, got", c.EmbeddingModel())
+	}
+	// The documents were updated.
+	if c.documents["1"].Version <= version {
+		t.Fatal("expected new version, got", c.documents["1"].Version)
+	}
+	db3, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for id, doc := range db3.GetCollection("test", nil).documents {
+		if !slices.Equal(doc.Embedding, newVectors) {
+			t.Fatal("expected new embedding on disk for", id, "got", doc.Embedding)
+		}
+	}
+}
+
+func TestCollection_Reembed_EmbeddingText(t *testing.T) {
+	ctx := context.Background()
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	var texts []string
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		texts = append(texts, text)
+		return []float32{1, 0}, nil
+	}
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, embed)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		{ID: "1", Content: "foo", EmbeddingText: "Title\n\nfoo"},
+		{ID: "2", Content: "bar", EmbeddingText: "bar"},
+	}
+	_, err = c.AddDocumentsWithSummary(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// A changed embedding text counts as a change
+	docs[0].EmbeddingText = "Other title\n\nfoo"
+	summary, err := c.AddDocumentsWithSummary(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if summary.Embedded != 1 || summary.Skipped != 1 {
+		t.Fatal("expected 1 embedded and 1 skipped, got", summary)
+	}
+
+	// The embedding text is persisted and embedded again by Reembed
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	doc, err := c.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.EmbeddingText != "Other title\n\nfoo" {
+		t.Fatal("expected persisted embedding text, got", doc.EmbeddingText)
+	}
+	texts = nil
+	err = c.Reembed(ctx, embed, "new-model", 1, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	slices.Sort(texts)
+	if !slices.Equal(texts, []string{"Other title\n\nfoo", "bar"}) {
+		t.Fatal("expected the embedding text and content to be embedded, got", texts)
+	}
+}
@@ -0,0 +1,131 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RenameCollection renames the collection. For persistent DBs, the name is
+// updated in the collection's metadata file and the collection's directory,
+// which is named after a hash of the name (see
+// [PersistentDBOptions.ReadableNames]), is moved accordingly.
+//
+// The metadata file is replaced atomically, which is the commit point of the
+// rename: If the process crashes before the directory is moved, the collection
+// is loaded with the new name from the old directory, which works just as well.
+// If moving the directory fails, the old name is restored.
+//
+// Pending writes to the collection are awaited, and new ones are blocked until
+// the rename is done. References to the collection stay valid. The collection's
+// Name field is updated as well, so use [Collection.CurrentName] to read the
+// name while a rename might happen concurrently.
+func (db *DB) RenameCollection(oldName, newName string) error {
+	if newName == "" {
+		return errors.New("new collection name is empty")
+	}
+	if oldName == newName {
+		return nil
+	}
+
+	db.collectionsLock.Lock()
+	defer db.collectionsLock.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	c, ok := db.collections[oldName]
+	if !ok {
+		return fmt.Errorf("collection '%s' %w", oldName, ErrNotFound)
+	}
+	if _, ok := db.collections[newName]; ok {
+		return fmt.Errorf("collection '%s' already exists", newName)
+	}
+
+	// Wait for pending writes of the collection and its loaded namespaces, and
+	// block new ones.
+	c.persistLock.Lock()
+	defer c.persistLock.Unlock()
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	for _, ns := range c.namespaces {
+		ns.persistLock.Lock()
+		defer ns.persistLock.Unlock()
+		ns.documentsLock.Lock()
+		defer ns.documentsLock.Unlock()
+	}
+
+	if db.persistDirectory == "" {
+		c.setName(newName)
+	} else {
+		newDir := filepath.Join(db.persistDirectory, persistedName(newName, c.readableNames))
+		_, err := os.Stat(newDir)
+		if err == nil {
+			return fmt.Errorf("collection directory already exists: %s", newDir)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("couldn't get info about collection directory: %w", err)
+		}
+
+		c.setName(newName)
+		err = c.persistMetadataAtomically()
+		if err != nil {
+			c.setName(oldName)
+			return fmt.Errorf("couldn't persist collection metadata: %w", err)
+		}
+		err = os.Rename(c.persistDirectory, newDir)
+		if err != nil {
+			c.setName(oldName)
+			if rollbackErr := c.persistMetadataAtomically(); rollbackErr != nil {
+				err = errors.Join(err, fmt.Errorf("couldn't restore collection metadata: %w", rollbackErr))
+			}
+			return fmt.Errorf("couldn't move collection directory: %w", err)
+		}
+		c.setPersistDirectory(newDir)
+	}
+
+	delete(db.collections, oldName)
+	db.collections[newName] = c
+	return db.writeManifest()
+}
+
+// persistMetadataAtomically writes the collection's metadata file to a
+// temporary file first and then replaces the metadata file with it, so that a
+// crash can't leave a partially written file.
+func (c *Collection) persistMetadataAtomically() error {
+	metadataPath := filepath.Join(c.persistDirectory, metadataFileName+c.fileExt())
+	// The temporary file doesn't have the extension of the collection's files,
+	// so it's ignored when loading the collection after a crash.
+	tmpPath := metadataPath + ".tmp"
+	c.modifiedAt = time.Now().UTC()
+	c.modifiedAtStale = false
+	err := persistToFile(tmpPath, c.persistedMetadata(), c.encoding, c.compress, "", c.perms)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	err = os.Rename(tmpPath, metadataPath)
+	if err != nil {
+		return err
+	}
+	return c.logChanges(changeRecord{Op: changeOpMetadata})
+}
+
+// setPersistDirectory updates the paths of the collection and its loaded
+// namespaces after their directory was moved. Must be called while holding the
+// locks of the collection and its namespaces.
+func (c *Collection) setPersistDirectory(dir string) {
+	c.persistDirectory = dir
+	if c.contents != nil {
+		c.contents.dir = filepath.Join(dir, contentDirName)
+	}
+	for name, ns := range c.namespaces {
+		ns.setPersistDirectory(c.getNamespacePath(name))
+	}
+}
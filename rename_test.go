@@ -0,0 +1,127 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestDB_RenameCollection(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, persistent := range []bool{false, true} {
+		db := NewDB()
+		if persistent {
+			db, err = NewPersistentDB(dir, false)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+		}
+		c, err := db.CreateCollection("old", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "hello"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		_, err = db.CreateCollection("other", nil, nil)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		err = db.RenameCollection("old", "other")
+		if err == nil {
+			t.Fatal("expected error for existing collection, got nil")
+		}
+		err = db.RenameCollection("missing", "new")
+		if err == nil {
+			t.Fatal("expected error for missing collection, got nil")
+		}
+
+		err = db.RenameCollection("old", "new")
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		if db.GetCollection("old", nil) != nil {
+			t.Fatal("expected old collection to be gone")
+		}
+		if db.GetCollection("new", nil) != c || c.Name != "new" {
+			t.Fatal("expected renamed collection")
+		}
+		// The collection is still writable.
+		err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{0, 1}, Content: "world"})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+
+		if !persistent {
+			continue
+		}
+		_, err = os.Stat(filepath.Join(dir, hash2hex("old")))
+		if !os.IsNotExist(err) {
+			t.Fatal("expected old directory to be gone, got", err)
+		}
+		db2, err := NewPersistentDB(dir, false)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		c2 := db2.GetCollection("new", nil)
+		if c2 == nil {
+			t.Fatal("expected persisted collection with new name")
+		}
+		if c2.Count() != 2 {
+			t.Fatal("expected 2 documents, got", c2.Count())
+		}
+		if db2.GetCollection("old", nil) != nil {
+			t.Fatal("expected no collection with old name")
+		}
+	}
+}
+
+func TestDB_RenameCollection_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db := NewDB()
+	c, err := db.CreateCollection("a", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Queries read the name for the embedding context while it's renamed.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20000; i++ {
+			_, err := c.Query(ctx, "hello "+strconv.Itoa(i), 1, nil, nil)
+			if err != nil {
+				t.Error("expected no error, got", err)
+				return
+			}
+		}
+	}()
+	names := []string{"a", "b"}
+	for i := 0; i < 20000; i++ {
+		err := db.RenameCollection(names[i%2], names[(i+1)%2])
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	<-done
+	if c.CurrentName() != "a" {
+		t.Fatal("expected a, got", c.CurrentName())
+	}
+}
@@ -0,0 +1,448 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Replica is a read replica of a persistent DB. It follows the persistence
+// directory of a leader DB that's shared with it, e.g. via a network file
+// system, a synced directory or an object store (see [NewReplicaFS]), and
+// applies the leader's changes to an in-memory DB, which can serve queries with
+// bounded staleness.
+//
+// The leader logs the changes of each collection's files in segments of a
+// change log in the collection's directory. Each sync tails the log and only
+// reads the files that were written or removed since the last sync. All files
+// are read and compared instead on the first sync, when the replica fell behind
+// by more than the segments the leader keeps, and when the leader was loaded
+// without having been closed, as changes right before a crash might not be
+// logged. Files that the leader is writing during a sync are retried in the
+// next one.
+//
+// Don't write to the replica's DB, as the changes aren't persisted and are
+// overwritten by the leader's. The replica's collections emit the leader's
+// changes via [Collection.Watch].
+type Replica struct {
+	db       *DB
+	fsys     fs.FS
+	compress bool
+	encoding Encoding
+
+	// Sync state per collection directory
+	collections map[string]*replicaCollection
+	lastSync    time.Time
+	lock        sync.Mutex
+}
+
+type replicaCollection struct {
+	// Whether all files were read once, and the sequence number of the last
+	// applied record of the leader's change log.
+	synced bool
+	seq    uint64
+	// Document ID per file name
+	files map[string]string
+}
+
+// NewReplica creates a read replica of the persistent DB in the given directory,
+// and syncs it once. Call [Replica.Sync] or [Replica.Run] to apply subsequent
+// changes.
+//
+//   - path: The leader's persistence directory.
+//   - compress: Whether the leader compresses the files.
+func NewReplica(path string, compress bool) (*Replica, error) {
+	return NewReplicaWithOptions(path, PersistentDBOptions{Compress: compress})
+}
+
+// NewReplicaWithOptions is like [NewReplica], for a leader that was created with
+// [NewPersistentDBWithOptions] and the same options.
+func NewReplicaWithOptions(path string, options PersistentDBOptions) (*Replica, error) {
+	if path == "" {
+		return nil, errors.New("path is empty")
+	}
+	return NewReplicaFS(os.DirFS(filepath.Clean(path)), options)
+}
+
+// NewReplicaFS is like [NewReplicaWithOptions], for a leader whose persistence
+// directory is the root of the file system, e.g. an object store bucket that
+// the leader's directory is synced to, via an [fs.FS] implementation of the
+// store.
+func NewReplicaFS(fsys fs.FS, options PersistentDBOptions) (*Replica, error) {
+	if fsys == nil {
+		return nil, errors.New("file system is nil")
+	}
+
+	r := &Replica{
+		db:          NewDB(),
+		fsys:        fsys,
+		compress:    options.Compress,
+		encoding:    options.Encoding,
+		collections: make(map[string]*replicaCollection),
+	}
+	err := r.Sync()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DB returns the replica's in-memory DB.
+func (r *Replica) DB() *DB {
+	return r.db
+}
+
+// LastSync returns the time of the last successful sync. The replica's data is
+// at most as stale as the time since then.
+func (r *Replica) LastSync() time.Time {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.lastSync
+}
+
+// Run syncs the replica every interval until the context is done, and then
+// returns the context's error. Errors of single syncs are ignored, as the next
+// sync retries.
+func (r *Replica) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_ = r.Sync()
+		}
+	}
+}
+
+// Sync applies the leader's changes since the last sync.
+func (r *Replica) Sync() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	start := time.Now()
+	dirEntries, err := fs.ReadDir(r.fsys, ".")
+	if err != nil {
+		return fmt.Errorf("couldn't read persistence directory: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(dirEntries))
+	var errs []error
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+		seen[dirEntry.Name()] = struct{}{}
+		err := r.syncCollection(dirEntry.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't sync collection directory '%s': %w", dirEntry.Name(), err))
+		}
+	}
+
+	// Collections that were deleted by the leader
+	for dirName := range r.collections {
+		if _, ok := seen[dirName]; ok {
+			continue
+		}
+		if name, ok := r.collectionName(dirName); ok {
+			r.db.collectionsLock.Lock()
+			delete(r.db.collections, name)
+			r.db.collectionsLock.Unlock()
+		}
+		delete(r.collections, dirName)
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+	r.lastSync = start
+	return nil
+}
+
+// collectionName returns the name of the collection in the directory, if it
+// was synced before.
+func (r *Replica) collectionName(dirName string) (string, bool) {
+	r.db.collectionsLock.RLock()
+	defer r.db.collectionsLock.RUnlock()
+	for name, c := range r.db.collections {
+		if c.replicaDir == dirName {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// collection returns the collection in the directory, or nil if it wasn't
+// synced before.
+func (r *Replica) collection(dirName string) *Collection {
+	name, ok := r.collectionName(dirName)
+	if !ok {
+		return nil
+	}
+	r.db.collectionsLock.RLock()
+	defer r.db.collectionsLock.RUnlock()
+	return r.db.collections[name]
+}
+
+// syncCollection must be called while holding the replica's lock.
+func (r *Replica) syncCollection(dirName string) error {
+	state := r.collections[dirName]
+	if state == nil {
+		state = &replicaCollection{files: make(map[string]string)}
+		r.collections[dirName] = state
+	}
+
+	records, first, last, err := readChangeLog(r.fsys, path.Join(dirName, changeLogDirName), state.seq)
+	if err != nil {
+		return err
+	}
+	c := r.collection(dirName)
+	// Without a log, e.g. of a leader of an older version, all files are
+	// compared in each sync. A log that starts over, e.g. because it was
+	// removed, is like a reset.
+	full := !state.synced || c == nil || last == 0 || first > state.seq+1 || last < state.seq
+	for _, record := range records {
+		if record.Op == changeOpReset {
+			full = true
+		}
+	}
+	if full {
+		err := r.syncAll(dirName, state)
+		if err != nil {
+			return err
+		}
+		state.synced, state.seq = true, last
+		return nil
+	}
+
+	// Only the files of the logged changes. The current state of each file is
+	// applied, so it doesn't matter how often it changed.
+	if slices.ContainsFunc(records, func(record changeRecord) bool { return record.Op == changeOpMetadata }) {
+		c, err = r.syncMetadata(dirName)
+		if err != nil {
+			return err
+		}
+	}
+	files := make(map[string]string, len(records))
+	var errs []error
+	for _, record := range records {
+		if record.Op != changeOpDocument || record.File == "" {
+			continue
+		}
+		if _, ok := files[record.File]; ok {
+			continue
+		}
+		files[record.File] = record.ID
+		err := r.syncDocument(c, dirName, state, record.File, record.ID, false)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		// The changes are applied again in the next sync.
+		return errors.Join(errs...)
+	}
+	state.seq = last
+	return nil
+}
+
+// syncAll reads the metadata and all document files of the collection and
+// applies the documents that changed, and removes the documents whose files
+// were removed. Must be called while holding the replica's lock.
+func (r *Replica) syncAll(dirName string, state *replicaCollection) error {
+	ext := fileExt(r.encoding, r.compress)
+	dirEntries, err := fs.ReadDir(r.fsys, dirName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("couldn't read collection directory: %w", err)
+	}
+
+	// The metadata first, as we need the collection for the documents.
+	c, err := r.syncMetadata(dirName)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		// No metadata (yet), e.g. a user-added directory.
+		return nil
+	}
+
+	seen := make(map[string]struct{}, len(dirEntries))
+	var errs []error
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if dirEntry.IsDir() || name == metadataFileName+ext || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		seen[name] = struct{}{}
+		err := r.syncDocument(c, dirName, state, name, "", true)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Documents that were deleted by the leader
+	for name := range state.files {
+		if _, ok := seen[name]; !ok {
+			r.removeDocument(c, state, name, "")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// syncMetadata reads the collection's metadata file and applies it, creating
+// the collection if it doesn't exist yet. It returns the collection, which is
+// nil if there's neither a collection nor a metadata file yet. Must be called
+// while holding the replica's lock.
+func (r *Replica) syncMetadata(dirName string) (*Collection, error) {
+	c := r.collection(dirName)
+	pc := persistedCollectionMetadata{}
+	err := r.readFile(path.Join(dirName, metadataFileName+fileExt(r.encoding, r.compress)), &pc)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		// Might be written right now, retry in the next sync.
+		return nil, fmt.Errorf("couldn't read collection metadata: %w", err)
+	}
+	// The replica can't migrate the leader's files.
+	if pc.FormatVersion != formatVersion() {
+		return nil, fmt.Errorf("collection '%s' has persistence format version %d, but the replica requires version %d, open the leader first to migrate it", pc.Name, pc.FormatVersion, formatVersion())
+	}
+	if c == nil {
+		c = &Collection{
+			Name:       pc.Name,
+			documents:  make(map[string]*Document),
+			replicaDir: dirName,
+		}
+		r.db.collectionsLock.Lock()
+		c.concurrency = r.db.concurrency
+		r.db.collections[c.Name] = c
+		r.db.collectionsLock.Unlock()
+	}
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	c.applyPersistedMetadata(pc)
+	c.countModelMismatches()
+	// The documents that are applied later are added to the indexes.
+	err = c.applyPersistedIndexes(pc)
+	if err != nil {
+		return nil, err
+	}
+	c.invalidateQueryCache()
+	return c, nil
+}
+
+// syncDocument reads the document file and applies the document, or removes
+// the document if the file was removed. With onlyIfChanged, a document with
+// the same version and embedding as the applied one is skipped, without
+// emitting an event. id is the document's ID if it's known, in case the file
+// was removed. Must be called while holding the replica's lock.
+func (r *Replica) syncDocument(c *Collection, dirName string, state *replicaCollection, name, id string, onlyIfChanged bool) error {
+	d := &Document{}
+	err := r.readFile(path.Join(dirName, name), d)
+	if errors.Is(err, fs.ErrNotExist) {
+		r.removeDocument(c, state, name, id)
+		return nil
+	} else if err != nil {
+		// Might be written right now, retry in the next sync.
+		return fmt.Errorf("couldn't read document: %w", err)
+	}
+	if d.ContentHash != "" && d.Content == "" {
+		// The content is in the leader's content store.
+		ext := fileExt(r.encoding, r.compress)
+		err = r.readFile(path.Join(dirName, contentDirName, d.ContentHash+ext), &d.Content)
+		if err != nil {
+			return fmt.Errorf("couldn't read content of document '%s': %w", d.ID, err)
+		}
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	d, err = c.decryptedFromDisk(d)
+	if err != nil {
+		return fmt.Errorf("couldn't read document: %w", err)
+	}
+	state.files[name] = d.ID
+	existing, ok := c.documents[d.ID]
+	if onlyIfChanged && ok && existing.Version == d.Version && slices.Equal(existing.Embedding, d.Embedding) {
+		return nil
+	}
+	eventType := EventAdd
+	if ok {
+		eventType = EventUpdate
+		c.metadataStats.remove(existing)
+	}
+	memDoc := d
+	if c.compressed != nil {
+		compressed := *d
+		err = c.compressContent(&compressed)
+		if err != nil {
+			return fmt.Errorf("couldn't compress content of document '%s': %w", d.ID, err)
+		}
+		memDoc = &compressed
+	}
+	stored := c.column.put(c.documents, memDoc)
+	c.documents[d.ID] = stored
+	c.ivf.add(stored)
+	c.indexPhrases(d.ID, stored)
+	c.indexBM25(d.ID, stored)
+	c.metadataStats.add(stored)
+	c.trackEmbeddingModel(existing, stored)
+	c.invalidateQueryCache()
+	c.markModified()
+	c.watchers.emit(Event{Type: eventType, DocumentID: d.ID, Document: d})
+	return nil
+}
+
+// removeDocument removes the document of the removed file. id is the
+// document's ID if it's known. Must be called while holding the replica's
+// lock.
+func (r *Replica) removeDocument(c *Collection, state *replicaCollection, name, id string) {
+	if known, ok := state.files[name]; ok {
+		id = known
+	}
+	delete(state.files, name)
+	if id == "" {
+		return
+	}
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	existing, ok := c.documents[id]
+	if !ok {
+		return
+	}
+	c.metadataStats.remove(existing)
+	c.trackEmbeddingModel(existing, nil)
+	delete(c.documents, id)
+	c.column.remove(id)
+	c.ivf.remove(id)
+	c.phrases.remove(id)
+	c.bm25.remove(id)
+	c.compressed.remove(id)
+	c.invalidateQueryCache()
+	c.markModified()
+	c.watchers.emit(Event{Type: EventDelete, DocumentID: id})
+}
+
+// readFile reads an object from the file of the replica's file system.
+func (r *Replica) readFile(name string, obj any) error {
+	f, err := r.fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readFromReader(f, obj, r.encoding, "")
+}
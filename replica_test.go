@@ -0,0 +1,367 @@
+package chromem
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReplica(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	leader, err := NewPersistentDB(path, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	lc, err := leader.CreateCollection("test", map[string]string{"foo": "bar"}, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = lc.AddDocuments(ctx, []Document{{ID: "1", Content: "a"}, {ID: "2", Content: "b"}}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	r, err := NewReplica(path, true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if r.LastSync().IsZero() {
+		t.Fatal("expected last sync to be set")
+	}
+	rc := r.DB().GetCollection("test", embeddingFunc)
+	if rc == nil {
+		t.Fatal("expected collection, got nil")
+	}
+	if rc.Count() != 2 || rc.metadata["foo"] != "bar" {
+		t.Fatal("expected 2 documents and metadata, got", rc.Count(), rc.metadata)
+	}
+	events := rc.Watch(ctx)
+
+	// Changes on the leader
+	err = lc.AddDocument(ctx, Document{ID: "3", Content: "c"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = lc.Delete(ctx, nil, nil, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = lc.AddDocument(ctx, Document{ID: "2", Content: "b, updated"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if rc.Count() != 2 {
+		t.Fatal("expected 2 documents, got", rc.Count())
+	}
+	doc, err := rc.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "b, updated" {
+		t.Fatal("expected updated content, got", doc.Content)
+	}
+	counts := map[EventType]int{}
+	for i := 0; i < 3; i++ {
+		counts[(<-events).Type]++
+	}
+	if counts[EventAdd] != 1 || counts[EventUpdate] != 1 || counts[EventDelete] != 1 {
+		t.Fatal("expected one event of each type, got", counts)
+	}
+
+	// Deleted collection
+	err = leader.DeleteCollection("test")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if r.DB().GetCollection("test", nil) != nil {
+		t.Fatal("expected collection to be deleted")
+	}
+}
+
+// openLogFS is an fs.FS that records the names of the opened files.
+type openLogFS struct {
+	fs.FS
+	lock   sync.Mutex
+	opened []string
+}
+
+func (f *openLogFS) Open(name string) (fs.File, error) {
+	f.lock.Lock()
+	f.opened = append(f.opened, name)
+	f.lock.Unlock()
+	return f.FS.Open(name)
+}
+
+func (f *openLogFS) reset() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	res := f.opened
+	f.opened = nil
+	return res
+}
+
+func TestReplica_ChangeLog(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	leader, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	lc, err := leader.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = lc.AddDocuments(ctx, []Document{{ID: "1", Content: "a"}, {ID: "2", Content: "b"}}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	fsys := &openLogFS{FS: os.DirFS(path)}
+	r, err := NewReplicaFS(fsys, PersistentDBOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	rc := r.DB().GetCollection("test", embeddingFunc)
+	if rc == nil || rc.Count() != 2 {
+		t.Fatal("expected collection with 2 documents, got", rc)
+	}
+	fsys.reset()
+
+	// A rewrite with the same size, which is likely within the resolution of
+	// the modification time, is applied, and only its file is read.
+	err = lc.AddDocument(ctx, Document{ID: "2", Content: "c"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	doc, err := rc.GetByID(ctx, "2")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "c" {
+		t.Fatal("expected updated content, got", doc.Content)
+	}
+	dirName := filepath.Base(lc.persistDirectory)
+	docFiles := slices.DeleteFunc(fsys.reset(), func(name string) bool {
+		return !strings.HasPrefix(name, dirName+"/") || strings.HasPrefix(name, dirName+"/"+changeLogDirName)
+	})
+	if len(docFiles) != 1 || docFiles[0] != dirName+"/"+filepath.Base(lc.getDocPath("2")) {
+		t.Fatal("expected only the changed document's file to be read, got", docFiles)
+	}
+
+	// Changes of a leader that was loaded without being closed are compared
+	// with all files, as it might not have logged all changes before.
+	err = os.Remove(lc.getDocPath("1"))
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	leader, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if rc.Count() != 1 {
+		t.Fatal("expected 1 document, got", rc.Count())
+	}
+
+	// No reset after a clean close
+	err = leader.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	records, _, _, err := readChangeLog(os.DirFS(path), dirName+"/"+changeLogDirName, 0)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if op := records[len(records)-1].Op; op != changeOpClose {
+		t.Fatal("expected the close record to be last, got", op)
+	}
+}
+
+func TestReplica_Settings(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	leader, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	lc, err := leader.CreateCollection("test", nil, embeddingFunc)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = lc.AddDocuments(ctx, []Document{
+		{ID: "1", Content: "the quick brown fox", Metadata: map[string]string{"lang": "en"}},
+		{ID: "2", Content: "the lazy dog", Metadata: map[string]string{"lang": "en"}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	r, err := NewReplica(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	rc := r.DB().GetCollection("test", embeddingFunc)
+	if rc == nil {
+		t.Fatal("expected collection, got nil")
+	}
+
+	// Settings changed on the leader after the first sync
+	for _, err := range []error{
+		lc.SetStrictMode(true),
+		lc.SetQueryDefaults(QueryDefaults{NResults: 1}),
+		lc.EnablePhraseIndex(),
+		lc.EnableMetadataStats("lang"),
+		lc.EnableBM25Index(BM25IndexOptions{}),
+		lc.SetContentCompression(2),
+	} {
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	err = lc.AddDocument(ctx, Document{ID: "3", Content: "a brown dog", Metadata: map[string]string{"lang": "de"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	if !rc.strict || rc.queryDefaults.NResults != 1 {
+		t.Fatal("expected strict mode and query defaults, got", rc.strict, rc.queryDefaults)
+	}
+	if rc.phrases == nil {
+		t.Fatal("expected phrase index")
+	}
+	if stats, ok := rc.MetadataStats("lang"); !ok || stats["en"] != 2 || stats["de"] != 1 {
+		t.Fatal("expected metadata stats, got", stats)
+	}
+	if rc.ContentCompression() != 2 {
+		t.Fatal("expected content compression, got", rc.ContentCompression())
+	}
+	res, err := rc.QueryBM25(ctx, "brown", 10, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 {
+		t.Fatal("expected 2 results, got", len(res))
+	}
+	doc, err := rc.GetByID(ctx, "3")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "a brown dog" {
+		t.Fatal("expected content, got", doc.Content)
+	}
+
+	// Disabled on the leader
+	err = lc.DisablePhraseIndex()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = r.Sync()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if rc.phrases != nil {
+		t.Fatal("expected no phrase index")
+	}
+}
+
+func TestReplica_Metadata_Concurrent(t *testing.T) {
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	leader, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	lc, err := leader.CreateCollection("test", map[string]string{"foo": "bar"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	r, err := NewReplica(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	rc := r.DB().GetCollection("test", nil)
+
+	// The metadata is read while syncs replace it.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if rc.Metadata()["foo"] != "bar" {
+				t.Error("expected metadata, got", rc.Metadata())
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		err := lc.SetStrictMode(i%2 == 0)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+		err = r.Sync()
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	<-done
+}
@@ -0,0 +1,84 @@
+package chromem
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// csvResultHeader is the header row written by [WriteResultsCSV].
+var csvResultHeader = []string{"id", "similarity", "content", "metadata"}
+
+// WriteResultsJSON writes the query results to the writer as a JSON array.
+// The field names are the ones defined on [Result] ("id", "metadata", "embedding",
+// "content", "similarity"). Empty metadata, embeddings and contents are omitted.
+// If the writer has to be closed, it's the caller's responsibility.
+func WriteResultsJSON(w io.Writer, results []Result) error {
+	// Write an empty array instead of `null` for nil results.
+	if results == nil {
+		results = []Result{}
+	}
+
+	err := json.NewEncoder(w).Encode(results)
+	if err != nil {
+		return fmt.Errorf("couldn't encode results as JSON: %w", err)
+	}
+
+	return nil
+}
+
+// ReadResultsJSON reads query results from a reader, as written by [WriteResultsJSON].
+// If the reader has to be closed, it's the caller's responsibility.
+func ReadResultsJSON(r io.Reader) ([]Result, error) {
+	var results []Result
+	err := json.NewDecoder(r).Decode(&results)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode results from JSON: %w", err)
+	}
+
+	return results, nil
+}
+
+// WriteResultsCSV writes the query results to the writer as CSV, including a
+// header row. The columns are "id", "similarity", "content" and "metadata", with
+// the metadata being encoded as JSON object. Embeddings are not written, as they
+// are rarely useful in tabular form.
+// If the writer has to be closed, it's the caller's responsibility.
+func WriteResultsCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(csvResultHeader)
+	if err != nil {
+		return fmt.Errorf("couldn't write CSV header: %w", err)
+	}
+
+	for _, res := range results {
+		// json.Marshal sorts map keys, so the column value is stable.
+		metadata := []byte("{}")
+		if len(res.Metadata) > 0 {
+			metadata, err = json.Marshal(res.Metadata)
+			if err != nil {
+				return fmt.Errorf("couldn't encode metadata of result '%s': %w", res.ID, err)
+			}
+		}
+
+		err = cw.Write([]string{
+			res.ID,
+			strconv.FormatFloat(float64(res.Similarity), 'f', -1, 32),
+			res.Content,
+			string(metadata),
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't write CSV record for result '%s': %w", res.ID, err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("couldn't flush CSV writer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package chromem
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteResultsJSON(t *testing.T) {
+	results := []Result{
+		{
+			ID:         "1",
+			Metadata:   map[string]string{"foo": "bar"},
+			Content:    "hello world",
+			Similarity: 0.5,
+		},
+		{
+			ID:         "2",
+			Embedding:  []float32{0.1, 0.2},
+			Similarity: 0.25,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := WriteResultsJSON(buf, results)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	want := `[{"id":"1","metadata":{"foo":"bar"},"content":"hello world","similarity":0.5},{"id":"2","embedding":[0.1,0.2],"similarity":0.25}]` + "\n"
+	if buf.String() != want {
+		t.Fatal("expected", want, "got", buf.String())
+	}
+
+	// Roundtrip
+	got, err := ReadResultsJSON(buf)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if !reflect.DeepEqual(got, results) {
+		t.Fatal("expected", results, "got", got)
+	}
+
+	// Nil results are written as empty array
+	buf.Reset()
+	err = WriteResultsJSON(buf, nil)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatal("expected empty array, got", buf.String())
+	}
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	results := []Result{
+		{
+			ID:         "1",
+			Metadata:   map[string]string{"foo": "bar", "a": "b"},
+			Content:    "hello, world",
+			Similarity: 0.5,
+		},
+		{
+			ID:         "2",
+			Similarity: 0.25,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	err := WriteResultsCSV(buf, results)
+	if err != nil {
+		t.Fatal("expected nil, got", err)
+	}
+	want := "id,similarity,content,metadata\n" +
+		`1,0.5,"hello, world","{""a"":""b"",""foo"":""bar""}"` + "\n" +
+		"2,0.25,,{}\n"
+	if buf.String() != want {
+		t.Fatal("expected", want, "got", buf.String())
+	}
+}
@@ -0,0 +1,152 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+)
+
+// SampleOptions configures [Collection.Sample].
+type SampleOptions struct {
+	// StratifyBy is a metadata key to stratify the sample by. The sample then
+	// contains documents of each value of the key in proportion to their share
+	// of the matching documents, with at least one document per value if n
+	// allows it. Documents without the key form their own stratum. Optional,
+	// the sample is a simple random sample by default.
+	StratifyBy string
+	// Seed makes the sample reproducible, e.g. for evaluation sets. The same
+	// seed returns the same sample as long as the documents don't change.
+	// Optional, 0 means a random seed.
+	Seed int64
+}
+
+// Sample returns a random sample of n documents that match the where filter,
+// e.g. to build evaluation sets, spot-check embeddings or collect calibration
+// data (see [Calibration]). If fewer documents match, all of them are returned.
+// The documents are copies, in random order.
+//
+//   - n: The size of the sample. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - options: Stratification and seed. Optional.
+func (c *Collection) Sample(_ context.Context, n int, where map[string]string, options SampleOptions) ([]Document, error) {
+	if n <= 0 {
+		return nil, errors.New("n must be > 0")
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	docs := filterDocs(c.documents, where, nil)
+	// The map order is random, so the documents are sorted for reproducible
+	// samples.
+	slices.SortFunc(docs, func(a, b *Document) int { return cmp.Compare(a.ID, b.ID) })
+
+	var sample []*Document
+	if options.StratifyBy == "" {
+		sample = sampleDocs(r, docs, n)
+	} else {
+		sample = sampleStratified(r, docs, n, options.StratifyBy)
+	}
+
+	res := make([]Document, 0, len(sample))
+	for _, doc := range sample {
+		cp, err := c.readableCopy(doc)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, cp)
+	}
+	return res, nil
+}
+
+// sampleDocs returns n random documents in random order, or all of them if
+// there are fewer. The slice is shuffled in place.
+func sampleDocs(r *rand.Rand, docs []*Document, n int) []*Document {
+	n = min(n, len(docs))
+	// Partial Fisher-Yates shuffle
+	for i := 0; i < n; i++ {
+		j := i + r.Intn(len(docs)-i)
+		docs[i], docs[j] = docs[j], docs[i]
+	}
+	return docs[:n]
+}
+
+// sampleStratified returns n random documents, with each value of the
+// metadata key in proportion to its share, by the largest remainder method.
+func sampleStratified(r *rand.Rand, docs []*Document, n int, key string) []*Document {
+	if n >= len(docs) {
+		return sampleDocs(r, docs, n)
+	}
+
+	// Strata in the order of their first document, which is deterministic.
+	var values []string
+	strata := make(map[string][]*Document)
+	for _, doc := range docs {
+		v, ok := doc.Metadata[key]
+		if !ok {
+			// Can't collide with values, which are prefixed.
+			v = ""
+		} else {
+			v = "=" + v
+		}
+		if _, ok := strata[v]; !ok {
+			values = append(values, v)
+		}
+		strata[v] = append(strata[v], doc)
+	}
+
+	// Proportional allocation by the largest remainder method.
+	sizes := make(map[string]int, len(values))
+	type remainder struct {
+		value string
+		frac  float64
+	}
+	remainders := make([]remainder, 0, len(values))
+	allocated := 0
+	for _, v := range values {
+		exact := float64(n) * float64(len(strata[v])) / float64(len(docs))
+		sizes[v] = int(exact)
+		allocated += sizes[v]
+		remainders = append(remainders, remainder{v, exact - float64(sizes[v])})
+	}
+	// The sort is stable, so ties are broken by the order of the strata.
+	slices.SortStableFunc(remainders, func(a, b remainder) int { return cmp.Compare(b.frac, a.frac) })
+	for i := 0; allocated < n; i++ {
+		sizes[remainders[i].value]++
+		allocated++
+	}
+	// Every stratum gets at least one document if possible, taken from the
+	// largest one.
+	if n >= len(values) {
+		for _, v := range values {
+			if sizes[v] > 0 {
+				continue
+			}
+			largest := values[0]
+			for _, w := range values {
+				if sizes[w] > sizes[largest] {
+					largest = w
+				}
+			}
+			sizes[largest]--
+			sizes[v] = 1
+		}
+	}
+
+	sample := make([]*Document, 0, n)
+	for _, v := range values {
+		sample = append(sample, sampleDocs(r, strata[v], sizes[v])...)
+	}
+	r.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	return sample
+}
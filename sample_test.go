@@ -0,0 +1,96 @@
+package chromem
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestCollection_Sample(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewDB().CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// 80 "en", 20 "de" and 10 without language
+	var docs []Document
+	for i := 0; i < 110; i++ {
+		doc := Document{ID: strconv.Itoa(i), Embedding: []float32{1, 0}, Metadata: map[string]string{"source": "web"}}
+		switch {
+		case i < 80:
+			doc.Metadata["lang"] = "en"
+		case i < 100:
+			doc.Metadata["lang"] = "de"
+		}
+		docs = append(docs, doc)
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.Sample(ctx, 0, nil, SampleOptions{})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Reproducible with a seed
+	ids := func(docs []Document) string {
+		res := ""
+		for _, doc := range docs {
+			res += doc.ID + ","
+		}
+		return res
+	}
+	s1, err := c.Sample(ctx, 10, nil, SampleOptions{Seed: 42})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	s2, err := c.Sample(ctx, 10, nil, SampleOptions{Seed: 42})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(s1) != 10 || ids(s1) != ids(s2) {
+		t.Fatal("expected the same 10 documents, got", ids(s1), ids(s2))
+	}
+	seen := make(map[string]bool)
+	for _, doc := range s1 {
+		if seen[doc.ID] {
+			t.Fatal("expected distinct documents, got", ids(s1))
+		}
+		seen[doc.ID] = true
+	}
+
+	// Filtered, with fewer matches than n
+	s, err := c.Sample(ctx, 50, map[string]string{"lang": "de"}, SampleOptions{})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(s) != 20 {
+		t.Fatal("expected all 20 matching documents, got", len(s))
+	}
+
+	// Stratified, proportional to the strata with at least one per stratum.
+	s, err = c.Sample(ctx, 11, nil, SampleOptions{StratifyBy: "lang", Seed: 1})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	counts := make(map[string]int)
+	for _, doc := range s {
+		counts[doc.Metadata["lang"]]++
+	}
+	if len(s) != 11 || counts["en"] != 8 || counts["de"] != 2 || counts[""] != 1 {
+		t.Fatal("expected 8 en, 2 de and 1 without language, got", counts)
+	}
+	s, err = c.Sample(ctx, 3, nil, SampleOptions{StratifyBy: "lang"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	counts = make(map[string]int)
+	for _, doc := range s {
+		counts[doc.Metadata["lang"]]++
+	}
+	if counts["en"] != 1 || counts["de"] != 1 || counts[""] != 1 {
+		t.Fatal("expected one document per stratum, got", counts)
+	}
+}
@@ -0,0 +1,151 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrSchemaViolation is returned when a document's metadata doesn't match the
+// collection's metadata schema, see [Collection.SetMetadataSchema].
+var ErrSchemaViolation = errors.New("metadata schema violation")
+
+// MetadataType is the type of a metadata value. Metadata values are always
+// stored as strings, so the type defines which strings are valid.
+type MetadataType string
+
+const (
+	// MetadataTypeString allows any string.
+	MetadataTypeString MetadataType = "string"
+	// MetadataTypeInt allows integers like "42" or "-1".
+	MetadataTypeInt MetadataType = "int"
+	// MetadataTypeFloat allows numbers like "3.14" or "42".
+	MetadataTypeFloat MetadataType = "float"
+	// MetadataTypeBool allows "true" and "false".
+	MetadataTypeBool MetadataType = "bool"
+)
+
+// MetadataField describes a metadata key in a [MetadataSchema].
+type MetadataField struct {
+	// Type of the value. Optional, defaults to [MetadataTypeString].
+	Type MetadataType
+	// Required makes documents without the key invalid.
+	Required bool
+}
+
+// MetadataSchema describes the allowed metadata of a collection's documents.
+type MetadataSchema struct {
+	// Fields maps the metadata keys to their description.
+	Fields map[string]MetadataField
+	// DisallowUnknown makes documents with keys that aren't in Fields invalid.
+	DisallowUnknown bool
+}
+
+// validate checks the schema itself.
+func (s *MetadataSchema) validate() error {
+	for key, field := range s.Fields {
+		switch field.Type {
+		case "", MetadataTypeString, MetadataTypeInt, MetadataTypeFloat, MetadataTypeBool:
+		default:
+			return fmt.Errorf("unsupported type '%s' for metadata key '%s'", field.Type, key)
+		}
+	}
+	return nil
+}
+
+// check returns an error describing all violations of the metadata, or nil.
+func (s *MetadataSchema) check(metadata map[string]string) error {
+	var violations []string
+	for key, field := range s.Fields {
+		v, ok := metadata[key]
+		if !ok {
+			if field.Required {
+				violations = append(violations, fmt.Sprintf("missing required key '%s'", key))
+			}
+			continue
+		}
+		var err error
+		switch field.Type {
+		case MetadataTypeInt:
+			_, err = strconv.ParseInt(v, 10, 64)
+		case MetadataTypeFloat:
+			_, err = strconv.ParseFloat(v, 64)
+		case MetadataTypeBool:
+			if v != "true" && v != "false" {
+				err = errors.New("invalid bool")
+			}
+		}
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("value '%s' of key '%s' is not of type %s", v, key, field.Type))
+		}
+	}
+	if s.DisallowUnknown {
+		for key := range metadata {
+			if _, ok := s.Fields[key]; !ok {
+				violations = append(violations, fmt.Sprintf("unknown key '%s'", key))
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	// Sort for deterministic error messages, as we iterate over maps.
+	slices.Sort(violations)
+	return fmt.Errorf("%w: %s", ErrSchemaViolation, strings.Join(violations, ", "))
+}
+
+// SetMetadataSchema sets the schema that the metadata of documents must match
+// when they're added. Documents that violate it are rejected with an error that
+// wraps [ErrSchemaViolation] and describes the violations. This prevents a
+// silently heterogeneous collection, for example when multiple services write
+// to it. The schema is persisted with the collection.
+//
+// The existing documents must match the schema as well, otherwise the schema is
+// not set and the error describes the first violating document. A nil schema
+// removes it.
+func (c *Collection) SetMetadataSchema(schema *MetadataSchema) error {
+	if schema != nil {
+		err := schema.validate()
+		if err != nil {
+			return err
+		}
+		// Copy, so that the caller can't modify it.
+		fields := make(map[string]MetadataField, len(schema.Fields))
+		for k, v := range schema.Fields {
+			fields[k] = v
+		}
+		schema = &MetadataSchema{Fields: fields, DisallowUnknown: schema.DisallowUnknown}
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	if schema != nil {
+		// Sorted for a deterministic error
+		ids := make([]string, 0, len(c.documents))
+		for id := range c.documents {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		for _, id := range ids {
+			err := schema.check(c.documents[id].Metadata)
+			if err != nil {
+				return fmt.Errorf("existing document '%s' doesn't match: %w", id, err)
+			}
+		}
+	}
+
+	c.metadataSchema = schema
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+
+	return nil
+}
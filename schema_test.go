@@ -0,0 +1,109 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCollection_SetMetadataSchema(t *testing.T) {
+	ctx := context.Background()
+
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Metadata: map[string]string{"year": "2024"}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Invalid schema
+	err = c.SetMetadataSchema(&MetadataSchema{Fields: map[string]MetadataField{"year": {Type: "date"}}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	// Existing document violates the schema
+	err = c.SetMetadataSchema(&MetadataSchema{Fields: map[string]MetadataField{"lang": {Required: true}}})
+	if !errors.Is(err, ErrSchemaViolation) {
+		t.Fatal("expected ErrSchemaViolation, got", err)
+	}
+
+	err = c.SetMetadataSchema(&MetadataSchema{
+		Fields: map[string]MetadataField{
+			"year":  {Type: MetadataTypeInt, Required: true},
+			"score": {Type: MetadataTypeFloat},
+			"draft": {Type: MetadataTypeBool},
+		},
+		DisallowUnknown: true,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	tt := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  string
+	}{
+		{
+			name:     "Valid",
+			metadata: map[string]string{"year": "2023", "score": "0.5", "draft": "true"},
+		},
+		{
+			name:     "Missing required",
+			metadata: map[string]string{"score": "0.5"},
+			wantErr:  "missing required key 'year'",
+		},
+		{
+			name:     "Wrong types",
+			metadata: map[string]string{"year": "last year", "draft": "yes"},
+			wantErr:  "value 'last year' of key 'year' is not of type int, value 'yes' of key 'draft' is not of type bool",
+		},
+		{
+			name:     "Unknown key",
+			metadata: map[string]string{"year": "2023", "author": "me"},
+			wantErr:  "unknown key 'author'",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{1, 0}, Metadata: tc.metadata})
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatal("expected no error, got", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchemaViolation) {
+				t.Fatal("expected ErrSchemaViolation, got", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatal("expected error to contain", tc.wantErr, "got", err)
+			}
+		})
+	}
+
+	// The schema is persisted
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c = db.GetCollection("test", nil)
+	err = c.AddDocument(ctx, Document{ID: "3", Embedding: []float32{1, 0}})
+	if !errors.Is(err, ErrSchemaViolation) {
+		t.Fatal("expected ErrSchemaViolation, got", err)
+	}
+}
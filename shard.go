@@ -0,0 +1,362 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
+)
+
+// ShardedCollection is a logical collection whose documents are split across
+// multiple regular collections (shards), by the hash of the document ID or of
+// a metadata value. Each shard has its own map and lock, so a sharded collection
+// can grow larger and take more concurrent writes than a single collection.
+// Queries are sent to all shards in parallel and the results are merged.
+//
+// The shards are regular collections named "<name>-shard-<i>", so they're
+// persisted, exported and imported like any other collection.
+type ShardedCollection struct {
+	Name string
+
+	shards      []*Collection
+	metadataKey string
+}
+
+// shardInfo is the assignment of a collection as a shard. It's persisted with
+// the collection, as documents are assigned to shards by the number of shards
+// and the metadata key, so they must not change.
+type shardInfo struct {
+	Shards      int
+	MetadataKey string
+}
+
+// GetOrCreateShardedCollection returns the sharded collection with the given
+// name, creating its shards if they don't exist yet.
+//
+//   - name: The name of the sharded collection. Must not be empty.
+//   - metadata: Optional metadata of each shard.
+//   - embeddingFunc: The embedding function of each shard. If nil, the default
+//     embedding function is used.
+//   - shards: The number of shards. Must be > 0. For an existing sharded
+//     collection it must be the same as when it was created, as documents are
+//     assigned to shards by it, otherwise an error is returned.
+//   - metadataKey: Optional. If set, documents are assigned to shards by the
+//     value of this metadata key instead of their ID, so that documents with the
+//     same value end up in the same shard, and queries with a where filter on
+//     the key only hit that shard. Documents without the key are assigned by ID.
+//     Like shards, it must not change for an existing sharded collection.
+func (db *DB) GetOrCreateShardedCollection(name string, metadata map[string]string, embeddingFunc EmbeddingFunc, shards int, metadataKey string) (*ShardedCollection, error) {
+	if name == "" {
+		return nil, errors.New("collection name is empty")
+	}
+	if shards <= 0 {
+		return nil, errors.New("shards must be > 0")
+	}
+
+	// Shards of older versions don't have the persisted shard count, so we
+	// check the existing shards as well.
+	db.collectionsLock.RLock()
+	_, exists := db.collections[shardName(name, 0)]
+	_, more := db.collections[shardName(name, shards)]
+	db.collectionsLock.RUnlock()
+	if more {
+		return nil, fmt.Errorf("sharded collection '%s' has more than %d shards", name, shards)
+	}
+
+	info := shardInfo{Shards: shards, MetadataKey: metadataKey}
+	sc := &ShardedCollection{
+		Name:        name,
+		shards:      make([]*Collection, 0, shards),
+		metadataKey: metadataKey,
+	}
+	for i := 0; i < shards; i++ {
+		c := db.GetCollection(shardName(name, i), embeddingFunc)
+		if c == nil && exists {
+			return nil, fmt.Errorf("sharded collection '%s' has %d shards, not %d", name, i, shards)
+		}
+		if c == nil {
+			var err error
+			c, err = db.CreateCollection(shardName(name, i), metadata, embeddingFunc)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't create shard %d: %w", i, err)
+			}
+		}
+		err := c.setShardInfo(info)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get shard %d: %w", i, err)
+		}
+		sc.shards = append(sc.shards, c)
+	}
+
+	return sc, nil
+}
+
+// setShardInfo assigns the collection as a shard, or checks that an existing
+// assignment matches.
+func (c *Collection) setShardInfo(info shardInfo) error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.shard != nil {
+		if c.shard.Shards != info.Shards {
+			return fmt.Errorf("sharded collection has %d shards, not %d", c.shard.Shards, info.Shards)
+		}
+		if c.shard.MetadataKey != info.MetadataKey {
+			return fmt.Errorf("sharded collection has metadata key '%s', not '%s'", c.shard.MetadataKey, info.MetadataKey)
+		}
+		return nil
+	}
+
+	c.shard = &info
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// Shards returns the collections the documents are split across.
+func (sc *ShardedCollection) Shards() []*Collection {
+	return slices.Clone(sc.shards)
+}
+
+// AddDocument adds a document to its shard.
+// See [Collection.AddDocument] for details.
+func (sc *ShardedCollection) AddDocument(ctx context.Context, doc Document) error {
+	err := sc.shards[sc.shardOf(doc.ID, doc.Metadata)].AddDocument(ctx, doc)
+	if err != nil {
+		return err
+	}
+	return sc.removeFromOtherShards(ctx, []Document{doc})
+}
+
+// AddDocuments adds documents to their shards, concurrently.
+// See [Collection.AddDocuments] for details.
+func (sc *ShardedCollection) AddDocuments(ctx context.Context, documents []Document, concurrency int) error {
+	if len(documents) == 0 {
+		return errors.New("documents slice is nil or empty")
+	}
+	if concurrency < 0 {
+		return errors.New("concurrency must be >= 0")
+	}
+
+	docsPerShard := make([][]Document, len(sc.shards))
+	for _, doc := range documents {
+		i := sc.shardOf(doc.ID, doc.Metadata)
+		docsPerShard[i] = append(docsPerShard[i], doc)
+	}
+
+	err := sc.forEachShard(ctx, func(ctx context.Context, i int, c *Collection) error {
+		if len(docsPerShard[i]) == 0 {
+			return nil
+		}
+		return c.AddDocuments(ctx, docsPerShard[i], concurrency)
+	})
+	if err != nil {
+		return err
+	}
+	return sc.removeFromOtherShards(ctx, documents)
+}
+
+// removeFromOtherShards deletes the added documents from the shards other than
+// their own. With sharding by a metadata key, a document moves to another shard
+// when its value changes, and the previous version must not be found anymore.
+// The documents are added first, so that they're never missing.
+func (sc *ShardedCollection) removeFromOtherShards(ctx context.Context, documents []Document) error {
+	if sc.metadataKey == "" {
+		return nil
+	}
+	return sc.forEachShard(ctx, func(ctx context.Context, i int, c *Collection) error {
+		var ids []string
+		c.documentsLock.RLock()
+		for _, doc := range documents {
+			if _, ok := c.documents[doc.ID]; ok && sc.shardOf(doc.ID, doc.Metadata) != i {
+				ids = append(ids, doc.ID)
+			}
+		}
+		c.documentsLock.RUnlock()
+		if len(ids) == 0 {
+			return nil
+		}
+		err := c.Delete(ctx, nil, nil, ids...)
+		if err != nil {
+			return fmt.Errorf("couldn't delete moved documents from shard %d: %w", i, err)
+		}
+		return nil
+	})
+}
+
+// GetByID returns a copy of the document with the given ID.
+// See [Collection.GetByID] for details.
+func (sc *ShardedCollection) GetByID(ctx context.Context, id string) (Document, error) {
+	if sc.metadataKey == "" {
+		return sc.shards[sc.shardOf(id, nil)].GetByID(ctx, id)
+	}
+	// With metadata based sharding we don't know the shard from the ID alone.
+	for _, c := range sc.shards {
+		doc, err := c.GetByID(ctx, id)
+		if err == nil {
+			return doc, nil
+		}
+	}
+	return Document{}, fmt.Errorf("document with ID '%v' %w", id, ErrNotFound)
+}
+
+// Delete deletes documents from all shards.
+// See [Collection.Delete] for details.
+func (sc *ShardedCollection) Delete(ctx context.Context, where, whereDocument map[string]string, ids ...string) error {
+	return sc.forEachShard(ctx, func(ctx context.Context, i int, c *Collection) error {
+		shardIDs := ids
+		if len(ids) > 0 && sc.metadataKey == "" {
+			shardIDs = nil
+			for _, id := range ids {
+				if sc.shardOf(id, nil) == i {
+					shardIDs = append(shardIDs, id)
+				}
+			}
+			if len(shardIDs) == 0 {
+				return nil
+			}
+		}
+		return c.Delete(ctx, where, whereDocument, shardIDs...)
+	})
+}
+
+// Count returns the number of documents in all shards.
+func (sc *ShardedCollection) Count() int {
+	count := 0
+	for _, c := range sc.shards {
+		count += c.Count()
+	}
+	return count
+}
+
+// Query performs an exhaustive nearest neighbor search over all shards.
+// The query embedding is created once, with the embedding function of the first
+// shard. See [Collection.Query] for details, except that nResults may be larger
+// than the number of documents.
+func (sc *ShardedCollection) Query(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if queryText == "" {
+		return nil, errors.New("queryText is empty")
+	}
+
+	queryVector, err := sc.shards[0].getEmbed()(embeddingContext(ctx, sc.Name, EmbeddingOperationQuery, ""), queryText)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create embedding of query: %w: %w", ErrEmbeddingUnavailable, err)
+	}
+
+	return sc.QueryEmbedding(ctx, queryVector, nResults, where, whereDocument)
+}
+
+// QueryEmbedding performs an exhaustive nearest neighbor search over all shards
+// and merges the results. If the collection is sharded by a metadata key and
+// the where filter has a value for it, only that value's shard is queried.
+// See [Collection.QueryEmbedding] for details, except that nResults may be larger
+// than the number of documents.
+func (sc *ShardedCollection) QueryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	if len(queryEmbedding) == 0 {
+		return nil, errors.New("queryEmbedding is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+
+	shardIndex := -1
+	if v, ok := where[sc.metadataKey]; ok && sc.metadataKey != "" {
+		shardIndex = shardOfKey(v, len(sc.shards))
+	}
+
+	resultsPerShard := make([][]Result, len(sc.shards))
+	err := sc.forEachShard(ctx, func(ctx context.Context, i int, c *Collection) error {
+		if shardIndex >= 0 && i != shardIndex {
+			return nil
+		}
+		n := min(nResults, c.Count())
+		if n == 0 {
+			return nil
+		}
+		res, err := c.QueryEmbedding(ctx, queryEmbedding, n, where, whereDocument)
+		if err != nil {
+			return fmt.Errorf("couldn't query shard %d: %w", i, err)
+		}
+		resultsPerShard[i] = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []Result
+	for _, res := range resultsPerShard {
+		merged = append(merged, res...)
+	}
+	slices.SortStableFunc(merged, func(a, b Result) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	if len(merged) > nResults {
+		merged = merged[:nResults]
+	}
+
+	return merged, nil
+}
+
+// forEachShard calls fn for each shard in parallel and returns the first error.
+// The context passed to fn is canceled when one of the calls fails.
+func (sc *ShardedCollection) forEachShard(ctx context.Context, fn func(ctx context.Context, i int, c *Collection) error) error {
+	var sharedErr error
+	sharedErrLock := sync.Mutex{}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	setSharedErr := func(err error) {
+		sharedErrLock.Lock()
+		defer sharedErrLock.Unlock()
+		// Another goroutine might have already set the error.
+		if sharedErr == nil {
+			sharedErr = err
+			// Cancel the operation for all other goroutines.
+			cancel(sharedErr)
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for i, c := range sc.shards {
+		wg.Add(1)
+		go func(i int, c *Collection) {
+			defer wg.Done()
+
+			err := fn(ctx, i, c)
+			if err != nil {
+				setSharedErr(err)
+			}
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	return sharedErr
+}
+
+// shardOf returns the index of the shard for a document.
+func (sc *ShardedCollection) shardOf(id string, metadata map[string]string) int {
+	if sc.metadataKey != "" {
+		if v, ok := metadata[sc.metadataKey]; ok {
+			return shardOfKey(v, len(sc.shards))
+		}
+	}
+	return shardOfKey(id, len(sc.shards))
+}
+
+func shardOfKey(key string, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+func shardName(name string, i int) string {
+	return fmt.Sprintf("%s-shard-%d", name, i)
+}
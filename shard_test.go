@@ -0,0 +1,205 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestShardedCollection(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, text string) ([]float32, error) {
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, err
+		}
+		return []float32{float32(i), 1}, nil
+	}
+
+	tt := []struct {
+		name        string
+		metadataKey string
+	}{
+		{
+			name: "By ID",
+		},
+		{
+			name:        "By metadata",
+			metadataKey: "tenant",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := NewDB()
+			sc, err := db.GetOrCreateShardedCollection("test", nil, embeddingFunc, 4, tc.metadataKey)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(db.ListCollections()) != 4 {
+				t.Fatal("expected 4 shards, got", len(db.ListCollections()))
+			}
+
+			var docs []Document
+			for i := 0; i < 20; i++ {
+				docs = append(docs, Document{
+					ID:       strconv.Itoa(i),
+					Content:  strconv.Itoa(i),
+					Metadata: map[string]string{"tenant": strconv.Itoa(i % 2)},
+				})
+			}
+			err = sc.AddDocuments(ctx, docs, 2)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if sc.Count() != 20 {
+				t.Fatal("expected 20 documents, got", sc.Count())
+			}
+			nonEmpty := 0
+			for _, c := range sc.Shards() {
+				if c.Count() > 0 {
+					nonEmpty++
+				}
+			}
+			if tc.metadataKey != "" && nonEmpty > 2 {
+				t.Fatal("expected at most 2 non-empty shards, got", nonEmpty)
+			} else if tc.metadataKey == "" && nonEmpty < 2 {
+				t.Fatal("expected documents to be spread across shards, got", nonEmpty)
+			}
+
+			// nResults may exceed the number of documents in a shard
+			res, err := sc.Query(ctx, "19", 15, nil, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(res) != 15 || res[0].ID != "19" {
+				t.Fatal("expected 15 results starting with 19, got", res)
+			}
+			for i := 1; i < len(res); i++ {
+				if res[i].Similarity > res[i-1].Similarity {
+					t.Fatal("expected results to be sorted")
+				}
+			}
+			res, err = sc.Query(ctx, "19", 20, map[string]string{"tenant": "0"}, nil)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if len(res) != 10 || res[0].ID != "18" {
+				t.Fatal("expected 10 results starting with 18, got", res)
+			}
+
+			doc, err := sc.GetByID(ctx, "7")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if doc.Content != "7" {
+				t.Fatal("expected content 7, got", doc.Content)
+			}
+
+			err = sc.Delete(ctx, nil, nil, "7", "8")
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if sc.Count() != 18 {
+				t.Fatal("expected 18 documents, got", sc.Count())
+			}
+			_, err = sc.GetByID(ctx, "7")
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestShardedCollection_Shards(t *testing.T) {
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = db.GetOrCreateShardedCollection("test", nil, embeddingFunc, 4, "tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// The shard count and metadata key are persisted and must not change.
+	db, err = NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	for _, tc := range []struct {
+		shards      int
+		metadataKey string
+	}{
+		{shards: 2, metadataKey: "tenant"},
+		{shards: 8, metadataKey: "tenant"},
+		{shards: 4, metadataKey: ""},
+	} {
+		_, err = db.GetOrCreateShardedCollection("test", nil, embeddingFunc, tc.shards, tc.metadataKey)
+		if err == nil {
+			t.Fatal("expected error for", tc, "got nil")
+		}
+	}
+	if len(db.ListCollections()) != 4 {
+		t.Fatal("expected 4 shards, got", len(db.ListCollections()))
+	}
+	_, err = db.GetOrCreateShardedCollection("test", nil, embeddingFunc, 4, "tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+}
+
+func TestShardedCollection_MovedDocument(t *testing.T) {
+	ctx := context.Background()
+	embeddingFunc := func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	}
+	db := NewDB()
+	sc, err := db.GetOrCreateShardedCollection("test", nil, embeddingFunc, 4, "tenant")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Two tenants in different shards
+	tenants := []string{"0"}
+	for i := 1; len(tenants) < 2; i++ {
+		if sc.shardOf("", map[string]string{"tenant": strconv.Itoa(i)}) != sc.shardOf("", map[string]string{"tenant": "0"}) {
+			tenants = append(tenants, strconv.Itoa(i))
+		}
+	}
+
+	err = sc.AddDocument(ctx, Document{ID: "1", Content: "a", Metadata: map[string]string{"tenant": tenants[0]}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = sc.AddDocument(ctx, Document{ID: "1", Content: "a", Metadata: map[string]string{"tenant": tenants[1]}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if sc.Count() != 1 {
+		t.Fatal("expected 1 document, got", sc.Count())
+	}
+	err = sc.AddDocuments(ctx, []Document{{ID: "1", Content: "a", Metadata: map[string]string{"tenant": tenants[0]}}}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if sc.Count() != 1 {
+		t.Fatal("expected 1 document, got", sc.Count())
+	}
+	doc, err := sc.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["tenant"] != tenants[0] {
+		t.Fatal("expected tenant", tenants[0], "got", doc.Metadata["tenant"])
+	}
+}
@@ -0,0 +1,140 @@
+package chromem
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// SimilarityFunc calculates the similarity of two embeddings, e.g. the query
+// embedding and a document embedding. A higher value means the embeddings are
+// more similar. It must return [ErrDimensionMismatch] if the embeddings have
+// different dimensions, and be safe for concurrent use.
+//
+// Set it per collection via [RegisterSimilarityFunc] and
+// [Collection.SetSimilarityFunc], or per query via [QueryOptions.SimilarityFunc].
+type SimilarityFunc func(a, b []float32) (float32, error)
+
+// Names of the built-in similarity functions, see [Collection.SetSimilarityFunc].
+const (
+	// SimilarityCosine is the cosine similarity, which normalizes the
+	// embeddings if they aren't yet.
+	SimilarityCosine = "cosine"
+	// SimilarityDotProduct is the dot product of the embeddings as they are.
+	SimilarityDotProduct = "dot"
+	// SimilarityEuclidean is the negative Euclidean distance, so that closer
+	// embeddings are more similar, with a maximum of 0.
+	SimilarityEuclidean = "euclidean"
+)
+
+var (
+	similarityFuncsLock sync.RWMutex
+	similarityFuncs     = map[string]SimilarityFunc{
+		SimilarityCosine:     cosineSimilarity,
+		SimilarityDotProduct: dotProduct,
+		SimilarityEuclidean:  negativeEuclideanDistance,
+	}
+)
+
+// RegisterSimilarityFunc registers a custom similarity function by name, e.g.
+// a weighted cosine similarity or a learned Mahalanobis distance, so that
+// collections can use it via [Collection.SetSimilarityFunc]. Collections only
+// persist the name, so the function must be registered again before querying a
+// loaded collection, e.g. in an init function. Names can't be registered twice.
+func RegisterSimilarityFunc(name string, f SimilarityFunc) error {
+	if name == "" {
+		return errors.New("name is empty")
+	}
+	if f == nil {
+		return errors.New("similarity function is nil")
+	}
+
+	similarityFuncsLock.Lock()
+	defer similarityFuncsLock.Unlock()
+	if _, ok := similarityFuncs[name]; ok {
+		return fmt.Errorf("similarity function '%s' is already registered", name)
+	}
+	similarityFuncs[name] = f
+	return nil
+}
+
+// lookupSimilarityFunc returns the similarity function registered by name.
+func lookupSimilarityFunc(name string) (SimilarityFunc, error) {
+	similarityFuncsLock.RLock()
+	defer similarityFuncsLock.RUnlock()
+	f, ok := similarityFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("similarity function '%s' isn't registered", name)
+	}
+	return f, nil
+}
+
+// SetSimilarityFunc sets the similarity function of the collection's queries by
+// its name, either of a built-in one like [SimilarityEuclidean] or of one
+// registered via [RegisterSimilarityFunc]. The name is persisted with the
+// collection. An empty name restores the default, which is the cosine
+// similarity or the dot product, depending on the normalization policy (see
+// [Collection.SetNormalizationPolicy]).
+//
+// The query embedding is projected and normalized like by default before it's
+// compared with the stored embeddings. A similarity function disables the early
+// abandoning of hopeless documents and the Matryoshka search (see
+// [Collection.SetMatryoshkaSearch]). The candidates of an IVF index (see
+// [Collection.BuildIVFIndex]) are still selected by cosine similarity.
+func (c *Collection) SetSimilarityFunc(name string) error {
+	if name != "" {
+		_, err := lookupSimilarityFunc(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if name == c.similarity {
+		return nil
+	}
+	c.similarity = name
+	c.invalidateQueryCache()
+	err := c.persistMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
+
+// SimilarityFunc returns the name of the collection's similarity function, or
+// an empty string for the default. See [Collection.SetSimilarityFunc].
+func (c *Collection) SimilarityFunc() string {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.similarity
+}
+
+// similarityFunc returns the similarity function to use for a query, or nil
+// for the default. Must be called while holding documentsLock.
+func (c *Collection) similarityFunc(override SimilarityFunc) (SimilarityFunc, error) {
+	if override != nil || c.similarity == "" {
+		return override, nil
+	}
+	return lookupSimilarityFunc(c.similarity)
+}
+
+// negativeEuclideanDistance calculates the negative Euclidean distance between
+// two vectors, so that a higher value means the vectors are more similar.
+func negativeEuclideanDistance(a, b []float32) (float32, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: vectors have %d and %d dimensions", ErrDimensionMismatch, len(a), len(b))
+	}
+
+	var sum float32
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return -float32(math.Sqrt(float64(sum))), nil
+}
@@ -0,0 +1,115 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestCollection_SetSimilarityFunc(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.SetNormalizationPolicy(NormalizeNever)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "near", Embedding: []float32{1, 0}},
+		{ID: "far", Embedding: []float32{10, 0}},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	query := []float32{1, 0}
+
+	// The default dot product prefers the longer vector.
+	res, err := c.QueryEmbedding(ctx, query, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "far" {
+		t.Fatal("expected far, got", res[0].ID)
+	}
+
+	err = c.SetSimilarityFunc("unknown")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetSimilarityFunc(SimilarityEuclidean)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = c.QueryEmbedding(ctx, query, 2, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "near" || res[0].Similarity != 0 || res[1].Similarity != -9 {
+		t.Fatal("expected near with 0 and far with -9, got", res)
+	}
+	batchRes, err := c.QueryEmbeddings(ctx, [][]float32{query}, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if batchRes[0][0].ID != "near" {
+		t.Fatal("expected near, got", batchRes[0][0].ID)
+	}
+
+	// Per query override
+	res, err = c.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: query,
+		NResults:       1,
+		SimilarityFunc: dotProduct,
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "far" {
+		t.Fatal("expected far, got", res[0].ID)
+	}
+
+	// Custom functions
+	weighted := func(a, b []float32) (float32, error) {
+		return -a[0]*b[0] + a[1]*b[1], nil
+	}
+	err = RegisterSimilarityFunc("test-weighted", weighted)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = RegisterSimilarityFunc("test-weighted", weighted)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.SetSimilarityFunc("test-weighted")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	res, err = c.QueryEmbedding(ctx, query, 1, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if res[0].ID != "near" || res[0].Similarity != -1 {
+		t.Fatal("expected near with -1, got", res[0])
+	}
+
+	// The name is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.SimilarityFunc() != "test-weighted" {
+		t.Fatal("expected test-weighted, got", c2.SimilarityFunc())
+	}
+}
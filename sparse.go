@@ -0,0 +1,278 @@
+package chromem
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// SparseVector is a sparse embedding, like the ones from learned sparse models
+// such as SPLADE, or BM25 term weights. Only the non-zero dimensions are stored,
+// with their index (e.g. a token ID) and value.
+type SparseVector struct {
+	Indices []uint32
+	Values  []float32
+}
+
+// IsEmpty returns whether the vector has no non-zero dimensions.
+func (v SparseVector) IsEmpty() bool {
+	return len(v.Indices) == 0
+}
+
+// sorted validates the vector and returns a copy that's sorted by index.
+func (v SparseVector) sorted() (SparseVector, error) {
+	if len(v.Indices) != len(v.Values) {
+		return SparseVector{}, errors.New("sparse vector must have the same number of indices and values")
+	}
+
+	type dim struct {
+		index uint32
+		value float32
+	}
+	dims := make([]dim, len(v.Indices))
+	for i := range v.Indices {
+		dims[i] = dim{v.Indices[i], v.Values[i]}
+	}
+	slices.SortFunc(dims, func(a, b dim) int {
+		return cmp.Compare(a.index, b.index)
+	})
+
+	res := SparseVector{
+		Indices: make([]uint32, len(dims)),
+		Values:  make([]float32, len(dims)),
+	}
+	for i, d := range dims {
+		if i > 0 && d.index == dims[i-1].index {
+			return SparseVector{}, fmt.Errorf("sparse vector has duplicate index %d", d.index)
+		}
+		res.Indices[i] = d.index
+		res.Values[i] = d.value
+	}
+	return res, nil
+}
+
+// sparseDotProduct calculates the dot product of two sparse vectors that are
+// sorted by index.
+func sparseDotProduct(a, b SparseVector) float32 {
+	var res float32
+	i, j := 0, 0
+	for i < len(a.Indices) && j < len(b.Indices) {
+		switch {
+		case a.Indices[i] < b.Indices[j]:
+			i++
+		case a.Indices[i] > b.Indices[j]:
+			j++
+		default:
+			res += a.Values[i] * b.Values[j]
+			i++
+			j++
+		}
+	}
+	return res
+}
+
+// QuerySparse performs an exhaustive search with a sparse query vector, scoring
+// the documents by the dot product of their sparse embeddings (see
+// [Document.SparseEmbedding]) with the query. Documents without a sparse
+// embedding are ignored, so fewer than nResults results can be returned.
+// The results' similarity is the dot product, which is not limited to [-1, 1].
+//
+//   - querySparse: The sparse embedding of the query, created with the same
+//     model as the documents' sparse embeddings.
+//   - nResults: The maximum number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (c *Collection) QuerySparse(ctx context.Context, querySparse SparseVector, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return c.querySparse(ctx, querySparse, nResults, where, whereDocument, Filter{}, nil)
+}
+
+// querySparse is like QuerySparse, but with the typed filter and excluded IDs
+// of [QueryOptions].
+func (c *Collection) querySparse(ctx context.Context, querySparse SparseVector, nResults int, where, whereDocument map[string]string, filter Filter, excludeIDs map[string]struct{}) ([]Result, error) {
+	if querySparse.IsEmpty() {
+		return nil, errors.New("querySparse is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	querySparse, err := querySparse.sorted()
+	if err != nil {
+		return nil, fmt.Errorf("invalid querySparse: %w", err)
+	}
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	filteredDocs, err := c.filterWith(excludeDocs(c.column.docs(c.documents), excludeIDs), where, whereDocument, filter)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	// The filtered documents might be the collection's shared slice of all
+	// documents, so it's cloned before removing documents.
+	filteredDocs = slices.DeleteFunc(slices.Clone(filteredDocs), func(doc *Document) bool {
+		return doc.SparseEmbedding.IsEmpty()
+	})
+	if len(filteredDocs) == 0 {
+		return nil, nil
+	}
+
+	docSims, err := getMostSimilarDocsFunc(ctx, filteredDocs, min(nResults, len(filteredDocs)), func(doc *Document) (float32, error) {
+		return sparseDotProduct(querySparse, doc.SparseEmbedding), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+
+	return c.docSimsToResults(docSims, true)
+}
+
+// QueryHybridEmbedding performs an exhaustive search that fuses the scores of
+// the dense and sparse embeddings, for hybrid retrieval with learned sparse
+// models like SPLADE, or BM25 term weights.
+//
+// The score of each document is alpha*dense + (1-alpha)*sparse, where dense is
+// the cosine similarity of the embeddings, and sparse is the dot product of the
+// sparse embeddings, divided by the highest one among the filtered documents so
+// that it's in the same range. Documents without a sparse embedding have a
+// sparse score of 0. The results' similarity is the fused score.
+//
+//   - queryEmbedding: The dense embedding of the query.
+//   - querySparse: The sparse embedding of the query.
+//   - nResults: The number of results to return. Must be > 0.
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+//   - alpha: Weight of the dense score, between 0 and 1. 1 is a pure dense
+//     search, 0 a pure sparse one.
+func (c *Collection) QueryHybridEmbedding(ctx context.Context, queryEmbedding []float32, querySparse SparseVector, nResults int, where, whereDocument map[string]string, alpha float32) ([]Result, error) {
+	return c.queryHybridEmbedding(ctx, queryEmbedding, querySparse, nResults, where, whereDocument, alpha, false, nil, "")
+}
+
+// queryHybridEmbedding is like QueryHybridEmbedding, but optionally explains
+// the fused scores of the results, see [Collection.QueryHybridEmbeddingExplained].
+// If queryText is set, the sparse scores are the BM25 scores of the BM25 index
+// instead, see [Collection.QueryHybrid].
+func (c *Collection) queryHybridEmbedding(ctx context.Context, queryEmbedding []float32, querySparse SparseVector, nResults int, where, whereDocument map[string]string, alpha float32, explain bool, queryTerms map[uint32]string, queryText string) ([]Result, error) {
+	if len(queryEmbedding) == 0 {
+		return nil, errors.New("queryEmbedding is empty")
+	}
+	if nResults <= 0 {
+		return nil, errors.New("nResults must be > 0")
+	}
+	if alpha < 0 || alpha > 1 {
+		return nil, errors.New("alpha must be between 0 and 1")
+	}
+	querySparse, err := querySparse.sorted()
+	if err != nil {
+		return nil, fmt.Errorf("invalid querySparse: %w", err)
+	}
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return nil, ErrClosed
+	}
+	if nResults > len(c.documents) {
+		return nil, errors.New("nResults must be <= the number of documents in the collection")
+	}
+	if queryText != "" && c.bm25 == nil {
+		return nil, errors.New("collection has no BM25 index")
+	}
+
+	filteredDocs, err := c.filter(c.column.docs(c.documents), where, whereDocument)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	if len(filteredDocs) == 0 {
+		return nil, nil
+	}
+
+	queryEmbedding = c.projection.projectIfInput(queryEmbedding)
+	queryEmbedding = c.normalization.apply(queryEmbedding)
+
+	// The sparse scores are cheap to calculate, and we need their maximum before
+	// we can fuse them with the dense ones.
+	sparseScores := make(map[string]float32, len(filteredDocs))
+	var keywordScores map[string]float32
+	if queryText != "" {
+		keywordScores = c.bm25.scores(queryText)
+	}
+	var maxSparse float32
+	for _, doc := range filteredDocs {
+		var score float32
+		if keywordScores != nil {
+			score = keywordScores[doc.ID]
+		} else if !doc.SparseEmbedding.IsEmpty() {
+			score = sparseDotProduct(querySparse, doc.SparseEmbedding)
+		} else {
+			continue
+		}
+		sparseScores[doc.ID] = score
+		maxSparse = max(maxSparse, score)
+	}
+
+	docSims, err := getMostSimilarDocsFunc(ctx, filteredDocs, min(nResults, len(filteredDocs)), func(doc *Document) (float32, error) {
+		dense, err := dotProduct(queryEmbedding, doc.Embedding)
+		if err != nil {
+			return 0, err
+		}
+		var sparse float32
+		if maxSparse > 0 {
+			sparse = sparseScores[doc.ID] / maxSparse
+		}
+		return alpha*dense + (1-alpha)*sparse, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get most similar docs: %w", err)
+	}
+
+	res, err := c.docSimsToResults(docSims, true)
+	if err != nil {
+		return nil, err
+	}
+	if explain {
+		for i := range res {
+			res[i].Explanation, err = explainHybrid(queryEmbedding, querySparse, c.documents[res[i].ID], alpha, maxSparse, queryTerms)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't explain document '%s': %w", res[i].ID, err)
+			}
+		}
+	}
+	return res, nil
+}
+
+// docSimsToResults converts the docSims to results, reading evicted content
+// from disk and decrypting encrypted content. Without content, the content
+// isn't read at all, which saves reading, decompressing and decrypting it when
+// the results don't include it. The caller must hold the documents read lock.
+func (c *Collection) docSimsToResults(docSims []docSim, withContent bool) ([]Result, error) {
+	res := make([]Result, len(docSims))
+	for i, ds := range docSims {
+		doc := c.documents[ds.docID]
+		r := &res[i]
+		if withContent {
+			var err error
+			doc, err = c.readable(doc)
+			if err != nil {
+				return nil, err
+			}
+			r.Content = doc.Content
+		}
+		c.memory.touch(c, ds.docID)
+		r.ID = ds.docID
+		r.Metadata = doc.Metadata
+		r.Embedding = doc.Embedding
+		r.Similarity = ds.similarity
+	}
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSparseDotProduct(t *testing.T) {
+	a := SparseVector{Indices: []uint32{1, 3, 7}, Values: []float32{1, 2, 3}}
+	b := SparseVector{Indices: []uint32{0, 3, 7, 9}, Values: []float32{5, 0.5, 2, 1}}
+	if got := sparseDotProduct(a, b); got != 7 {
+		t.Fatal("expected 7, got", got)
+	}
+	if got := sparseDotProduct(a, SparseVector{}); got != 0 {
+		t.Fatal("expected 0, got", got)
+	}
+
+	// Sorting and validation
+	s, err := SparseVector{Indices: []uint32{7, 1}, Values: []float32{3, 1}}.sorted()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if s.Indices[0] != 1 || s.Values[0] != 1 || s.Indices[1] != 7 || s.Values[1] != 3 {
+		t.Fatal("expected sorted vector, got", s)
+	}
+	_, err = SparseVector{Indices: []uint32{1, 1}, Values: []float32{1, 2}}.sorted()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	_, err = SparseVector{Indices: []uint32{1}, Values: []float32{1, 2}}.sorted()
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestCollection_QuerySparse_Hybrid(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs := []Document{
+		// Dense match, no sparse match
+		{ID: "dense", Embedding: []float32{1, 0}, SparseEmbedding: SparseVector{Indices: []uint32{5}, Values: []float32{1}}},
+		// Sparse match, no dense match. Unsorted on purpose.
+		{ID: "sparse", Embedding: []float32{0, 1}, SparseEmbedding: SparseVector{Indices: []uint32{2, 1}, Values: []float32{1, 2}}},
+		// No sparse embedding
+		{ID: "none", Embedding: []float32{-1, 0}},
+	}
+	for _, doc := range docs {
+		err = c.AddDocument(ctx, doc)
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	qv := []float32{1, 0}
+	qs := SparseVector{Indices: []uint32{1, 2}, Values: []float32{1, 1}}
+
+	res, err := c.QuerySparse(ctx, qs, 10, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 {
+		t.Fatal("expected 2 results, got", len(res))
+	}
+	if res[0].ID != "sparse" || res[0].Similarity != 3 {
+		t.Fatal("expected sparse with 3, got", res[0].ID, res[0].Similarity)
+	}
+
+	tt := []struct {
+		name  string
+		alpha float32
+		want  string
+	}{
+		{name: "Dense", alpha: 1, want: "dense"},
+		{name: "Sparse", alpha: 0, want: "sparse"},
+		{name: "Mostly sparse", alpha: 0.4, want: "sparse"},
+		{name: "Mostly dense", alpha: 0.6, want: "dense"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := c.QueryHybridEmbedding(ctx, qv, qs, 1, nil, nil, tc.alpha)
+			if err != nil {
+				t.Fatal("expected no error, got", err)
+			}
+			if res[0].ID != tc.want {
+				t.Fatal("expected", tc.want, "got", res[0].ID)
+			}
+		})
+	}
+
+	_, err = c.QueryHybridEmbedding(ctx, qv, qs, 1, nil, nil, 2)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	err = c.AddDocument(ctx, Document{ID: "invalid", Embedding: qv, SparseEmbedding: SparseVector{Indices: []uint32{1}}})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,47 @@
+package chromem
+
+import "testing"
+
+func TestStemEnglish(t *testing.T) {
+	tt := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"ties":           "ti",
+		"caress":         "caress",
+		"cats":           "cat",
+		"feed":           "feed",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"conflated":      "conflat",
+		"troubled":       "troubl",
+		"sized":          "size",
+		"hopping":        "hop",
+		"tanned":         "tan",
+		"falling":        "fall",
+		"hissing":        "hiss",
+		"fizzed":         "fizz",
+		"failing":        "fail",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"rational":       "ration",
+		"generalization": "gener",
+		"connection":     "connect",
+		"connected":      "connect",
+		"connecting":     "connect",
+		"adjustable":     "adjust",
+		"electricity":    "electr",
+		"controlling":    "control",
+		"roll":           "roll",
+		"is":             "is",
+		"café":           "café",
+	}
+	for word, want := range tt {
+		if got := StemEnglish(word); got != want {
+			t.Fatal("expected", want, "for", word, "got", got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package chromem
+
+import "fmt"
+
+// SetStrictMode sets whether the collection rejects documents instead of
+// silently accepting them when they likely result from a mistake:
+//
+//   - Adding a document with an ID that already exists fails with
+//     [ErrDocumentExists] instead of replacing the document. Use
+//     [Collection.UpsertDocument] to replace documents deliberately.
+//   - Adding a document with an embedding whose dimensions differ from the
+//     other documents' fails with [ErrDimensionMismatch], instead of failing
+//     later when querying.
+//
+// The setting is persisted if the DB is persistent, and it's applied to the
+// collection's namespaces as well.
+func (c *Collection) SetStrictMode(strict bool) error {
+	c.documentsLock.Lock()
+	if c.closed {
+		c.documentsLock.Unlock()
+		return ErrClosed
+	}
+	c.strict = strict
+	err := c.persistMetadata()
+	c.documentsLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+
+	c.namespacesLock.Lock()
+	defer c.namespacesLock.Unlock()
+	for _, ns := range c.namespaces {
+		ns.documentsLock.Lock()
+		ns.strict = strict
+		ns.documentsLock.Unlock()
+	}
+
+	return nil
+}
+
+// StrictMode returns whether the collection is in strict mode, see
+// [Collection.SetStrictMode].
+func (c *Collection) StrictMode() bool {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.strict
+}
+
+// checkDimensions checks that the embedding has the same dimensions as the
+// embeddings of the existing documents. Must be called while holding the
+// documents lock.
+func (c *Collection) checkDimensions(embedding []float32) error {
+	for _, doc := range c.documents {
+		if len(doc.Embedding) != len(embedding) {
+			return fmt.Errorf("%w: embedding has %d dimensions, the collection's documents have %d", ErrDimensionMismatch, len(embedding), len(doc.Embedding))
+		}
+		// Comparing with one document is enough to detect a different
+		// embedding model, and keeps adding fast.
+		break
+	}
+	return nil
+}
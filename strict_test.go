@@ -0,0 +1,99 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestCollection_SetStrictMode(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	// Without strict mode, the document is replaced.
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	err = c.SetStrictMode(true)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if !errors.Is(err, ErrDocumentExists) {
+		t.Fatal("expected ErrDocumentExists, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{{ID: "1", Embedding: []float32{1, 0}}}, 1)
+	if !errors.Is(err, ErrDocumentExists) {
+		t.Fatal("expected ErrDocumentExists, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "2", Embedding: []float32{1, 0, 0}})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+	// Replacing deliberately is still possible.
+	err = c.UpsertDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}}, 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if c.Count() != 1 {
+		t.Fatal("expected 1 document, got", c.Count())
+	}
+
+	// The mode is persisted.
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !db2.GetCollection("test", nil).StrictMode() {
+		t.Fatal("expected persisted strict mode")
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}, Content: "foo"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	_, err = c.GetByID(ctx, "2")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+	err = db.RenameCollection("foo", "bar")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0, 0}, 1, nil, nil)
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatal("expected ErrDimensionMismatch, got", err)
+	}
+	_, err = c.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, map[string]string{"$foo": "bar"})
+	if !errors.Is(err, ErrUnsupportedOperator) {
+		t.Fatal("expected ErrUnsupportedOperator, got", err)
+	}
+}
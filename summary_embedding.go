@@ -0,0 +1,88 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+)
+
+// Values of the metadata key of [SummaryStage] that distinguish the summaries
+// of documents from their content.
+const (
+	DocumentKindContent = "content"
+	DocumentKindSummary = "summary"
+)
+
+// SummaryStage returns a [Pipeline] stage that passes on a summary document
+// along with each document, so that both the content (or its chunks) and the
+// summary are embedded. Queries for a document as a whole, like "which report
+// covers the migration?", often match the summary better than any of the
+// chunks, while detailed questions match the chunks better.
+//
+// The summary document has the ID of the document followed by "/summary", like
+// "doc-42/summary", so [Filter.IDPrefix] with "doc-42/" matches it together
+// with the chunks of a [SplitStage] after this stage. Both have the document's
+// metadata, with the kind key set to [DocumentKindSummary] or
+// [DocumentKindContent], so that queries can be restricted to either, e.g.
+//
+//	c.Query(ctx, q, 10, map[string]string{"kind": chromem.DocumentKindSummary}, nil)
+//
+// or target both without the filter. Documents with an embedding or without
+// content aren't summarized, but have the kind key set as well.
+//
+//   - summarize: Returns the summary of a document's content, e.g. via an LLM
+//     with [LLMPromptSummary]. Required.
+//   - kindKey: The metadata key of the kind. Required.
+//   - concurrency: The number of documents that are summarized concurrently.
+//     Optional, defaults to 1.
+func SummaryStage(summarize func(ctx context.Context, content string) (string, error), kindKey string, concurrency int) (PipelineStage, error) {
+	if summarize == nil {
+		return PipelineStage{}, errors.New("summarize function is nil")
+	}
+	if kindKey == "" {
+		return PipelineStage{}, errors.New("kind key is empty")
+	}
+
+	withKind := func(metadata map[string]string, kind string) map[string]string {
+		// Copied, as the loader's map must not be modified and the summary and
+		// content have different kinds.
+		metadata = maps.Clone(metadata)
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+		metadata[kindKey] = kind
+		return metadata
+	}
+
+	return PipelineStage{
+		Name: "summarize",
+		Process: func(ctx context.Context, doc Document) ([]Document, error) {
+			metadata := doc.Metadata
+			doc.Metadata = withKind(metadata, DocumentKindContent)
+			if len(doc.Embedding) != 0 || doc.Content == "" {
+				return []Document{doc}, nil
+			}
+
+			summary, err := summarize(ctx, doc.Content)
+			if err != nil {
+				return nil, err
+			}
+			summary = strings.TrimSpace(summary)
+			if summary == "" {
+				return []Document{doc}, nil
+			}
+			return []Document{doc, {
+				ID:       summaryID(doc.ID),
+				Metadata: withKind(metadata, DocumentKindSummary),
+				Content:  summary,
+			}}, nil
+		},
+		Concurrency: concurrency,
+	}, nil
+}
+
+// summaryID returns the ID of the document's summary.
+func summaryID(id string) string {
+	return id + "/summary"
+}
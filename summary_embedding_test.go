@@ -0,0 +1,95 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSummaryStage(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	// Embeds texts about the migration in the first dimension, others in the
+	// second.
+	c, err := db.CreateCollection("test", nil, func(_ context.Context, text string) ([]float32, error) {
+		if strings.Contains(text, "migration") {
+			return []float32{1, 0}, nil
+		}
+		return []float32{0, 1}, nil
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	splitter, err := NewTextSplitter(3, 0, func(text string) int { return len(strings.Fields(text)) })
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	summarize := func(_ context.Context, content string) (string, error) {
+		return "About the migration ", nil
+	}
+	stage, err := SummaryStage(summarize, "kind", 2)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	p := Pipeline{
+		Loader: NewSliceLoader([]Document{
+			{ID: "report", Metadata: map[string]string{"team": "a"}, Content: "We moved all six services to the new cluster"},
+			{ID: "vec", Embedding: []float32{0, 1}},
+		}),
+		Stages: []PipelineStage{stage, SplitStage(splitter)},
+	}
+	_, err = p.Run(ctx, c)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	// 3 chunks, the summary and the document with embedding
+	if c.Count() != 5 {
+		t.Fatal("expected 5 documents, got", c.Count())
+	}
+	doc, err := c.GetByID(ctx, "report/summary")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Content != "About the migration" || doc.Metadata["kind"] != DocumentKindSummary || doc.Metadata["team"] != "a" {
+		t.Fatal("expected the summary document, got", doc)
+	}
+	doc, err = c.GetByID(ctx, "vec")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if doc.Metadata["kind"] != DocumentKindContent {
+		t.Fatal("expected content kind, got", doc.Metadata)
+	}
+
+	// Whole-document question against the summaries only
+	res, err := c.QueryEmbedding(ctx, []float32{1, 0}, 1, map[string]string{"kind": DocumentKindSummary}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "report/summary" {
+		t.Fatal("expected the summary, got", res)
+	}
+	res, err = c.QueryEmbedding(ctx, []float32{1, 0}, 5, map[string]string{"kind": DocumentKindContent}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 4 || res[0].ID == "report/summary" {
+		t.Fatal("expected the chunks and the document with embedding, got", res)
+	}
+
+	errFail := errors.New("fail")
+	stage, err = SummaryStage(func(context.Context, string) (string, error) { return "", errFail }, "kind", 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	_, err = stage.Process(ctx, Document{ID: "1", Content: "text"})
+	if !errors.Is(err, errFail) {
+		t.Fatal("expected errFail, got", err)
+	}
+	_, err = SummaryStage(summarize, "", 1)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,52 @@
+package chromem
+
+import (
+	"fmt"
+	"time"
+)
+
+// CreatedAt returns when the collection was created. It's the zero time for
+// collections that were persisted or exported by a version of chromem-go that
+// didn't record it yet.
+func (c *Collection) CreatedAt() time.Time {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.createdAt
+}
+
+// ModifiedAt returns when the collection's documents or settings were last
+// changed, e.g. to apply retention policies or to detect stale indexes. Like
+// [Collection.CreatedAt], it's the zero time for old collections until they're
+// changed.
+//
+// Settings changes are persisted right away. Document changes are persisted
+// with the next settings change or [Collection.Flush], so that adding documents
+// doesn't rewrite the collection's metadata file each time. After a crash, the
+// time might be older.
+func (c *Collection) ModifiedAt() time.Time {
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	return c.modifiedAt
+}
+
+// markModified records a change of the collection's documents. Must be called
+// while holding the documents write lock.
+func (c *Collection) markModified() {
+	c.modifiedAt = time.Now().UTC()
+	c.modifiedAtStale = true
+}
+
+// persistModifiedAt persists the modification time if documents were changed
+// since the metadata file was written.
+func (c *Collection) persistModifiedAt() error {
+	c.documentsLock.Lock()
+	defer c.documentsLock.Unlock()
+	if !c.modifiedAtStale || c.closed {
+		return nil
+	}
+	err := c.writeMetadata()
+	if err != nil {
+		return fmt.Errorf("couldn't persist collection metadata: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package chromem
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCollection_Timestamps(t *testing.T) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	before := time.Now()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	created := c.CreatedAt()
+	if created.Before(before.Add(-time.Second)) || created.After(time.Now()) {
+		t.Fatal("expected creation time around now, got", created)
+	}
+	if !c.ModifiedAt().Equal(created) {
+		t.Fatal("expected modification time", created, "got", c.ModifiedAt())
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	modified := c.ModifiedAt()
+	if !modified.After(created) {
+		t.Fatal("expected modification time after", created, "got", modified)
+	}
+	if !c.CreatedAt().Equal(created) {
+		t.Fatal("expected unchanged creation time, got", c.CreatedAt())
+	}
+
+	// The modification time of documents is persisted on flush.
+	err = c.Flush(ctx)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	db2, err := NewPersistentDB(dir, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.ListCollections()["test"]
+	if !c2.CreatedAt().Equal(created) || !c2.ModifiedAt().Equal(modified) {
+		t.Fatal("expected persisted timestamps", created, modified, "got", c2.CreatedAt(), c2.ModifiedAt())
+	}
+
+	// Settings changes count as modification as well.
+	time.Sleep(10 * time.Millisecond)
+	err = c.SetEmbeddingModel("foo")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if !c.ModifiedAt().After(modified) {
+		t.Fatal("expected modification time after", modified, "got", c.ModifiedAt())
+	}
+}
@@ -1,13 +1,21 @@
 package chromem
 
 import (
-	"errors"
 	"fmt"
 	"math"
 )
 
 const isNormalizedPrecisionTolerance = 1e-6
 
+// Number of dimensions after which the similarity calculation checks whether it
+// can be abandoned early.
+const earlyAbandonStep = 128
+
+// Margin for rounding errors when comparing the upper bound of a similarity with
+// the lowest similarity of the current top k, so that no document is abandoned
+// that would have made it into the results.
+const earlyAbandonMargin = 1e-4
+
 // cosineSimilarity calculates the cosine similarity between two vectors.
 // Vectors are normalized first.
 // The resulting value represents the similarity, so a higher value means the
@@ -15,7 +23,7 @@ const isNormalizedPrecisionTolerance = 1e-6
 func cosineSimilarity(a, b []float32) (float32, error) {
 	// The vectors must have the same length
 	if len(a) != len(b) {
-		return 0, errors.New("vectors must have the same length")
+		return 0, fmt.Errorf("%w: vectors have %d and %d dimensions", ErrDimensionMismatch, len(a), len(b))
 	}
 
 	if !isNormalized(a) || !isNormalized(b) {
@@ -38,7 +46,7 @@ func cosineSimilarity(a, b []float32) (float32, error) {
 func dotProduct(a, b []float32) (float32, error) {
 	// The vectors must have the same length
 	if len(a) != len(b) {
-		return 0, errors.New("vectors must have the same length")
+		return 0, fmt.Errorf("%w: vectors have %d and %d dimensions", ErrDimensionMismatch, len(a), len(b))
 	}
 
 	var dotProduct float32
@@ -73,3 +81,64 @@ func isNormalized(v []float32) bool {
 	magnitude := math.Sqrt(sqSum)
 	return math.Abs(magnitude-1) < isNormalizedPrecisionTolerance
 }
+
+// earlyAbandon calculates the dot product of a normalized query with normalized
+// document embeddings, but stops as soon as a document can't reach a given
+// minimum similarity anymore. After each step of dimensions, the similarity of
+// the remaining dimensions is at most the norm of the query's remaining
+// dimensions (Cauchy-Schwarz, with the document's norm being 1). So if the
+// partial sum plus that norm is lower than the minimum, the document is a loser.
+// For models that concentrate the information in the leading dimensions, like
+// Matryoshka models, the remaining norm shrinks quickly and most documents of a
+// large collection are abandoned long before the last dimension.
+type earlyAbandon struct {
+	query []float32
+	// restNorms[i] is the norm of query[(i+1)*earlyAbandonStep:].
+	restNorms []float32
+}
+
+func newEarlyAbandon(query []float32) earlyAbandon {
+	steps := (len(query) - 1) / earlyAbandonStep
+	restNorms := make([]float32, steps)
+	var sqSum float64
+	for i := len(query) - 1; i >= earlyAbandonStep; i-- {
+		sqSum += float64(query[i]) * float64(query[i])
+		if i%earlyAbandonStep == 0 {
+			restNorms[i/earlyAbandonStep-1] = float32(math.Sqrt(sqSum))
+		}
+	}
+	return earlyAbandon{
+		query:     query,
+		restNorms: restNorms,
+	}
+}
+
+// dotProduct returns the dot product of the query and the embedding. If it can't
+// reach minSimilarity, it might instead return an upper bound of it that's lower
+// than minSimilarity.
+func (ea earlyAbandon) dotProduct(embedding []float32, minSimilarity float32) (float32, error) {
+	a, b := ea.query, embedding
+	// The vectors must have the same length
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: vectors have %d and %d dimensions", ErrDimensionMismatch, len(a), len(b))
+	}
+
+	var dotProduct float32
+	for _, restNorm := range ea.restNorms {
+		// Re-slicing lets the compiler eliminate the bounds checks.
+		as, bs := a[:earlyAbandonStep], b[:earlyAbandonStep]
+		for i := range as {
+			dotProduct += as[i] * bs[i]
+		}
+		if bound := dotProduct + restNorm; bound+earlyAbandonMargin < minSimilarity {
+			return bound, nil
+		}
+		a, b = a[earlyAbandonStep:], b[earlyAbandonStep:]
+	}
+	b = b[:len(a)]
+	for i := range a {
+		dotProduct += a[i] * b[i]
+	}
+
+	return dotProduct, nil
+}
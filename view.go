@@ -0,0 +1,118 @@
+package chromem
+
+import (
+	"context"
+	"fmt"
+)
+
+// View is a read-only view of a collection that's restricted to the documents
+// that match a filter, see [Collection.View].
+type View struct {
+	collection *Collection
+	filter     Filter
+}
+
+// View returns a read-only view of the collection that's restricted to the
+// documents that match the filter, e.g. the documents of a tenant with
+// Where().Eq("tenant", tenantID). Queries, counts and gets on the view only
+// consider these documents, without passing the filter to every call, so a
+// view can be handed to code that must only see them.
+//
+// Views are lightweight: They don't copy documents and reflect later changes
+// of the collection, so they can be created per request. Use [View.View] to
+// restrict a view further. For isolated sets of documents that are stored
+// separately, see [Collection.Namespace].
+func (c *Collection) View(filter Filter) *View {
+	return &View{
+		collection: c,
+		filter:     filter,
+	}
+}
+
+// View returns a view that's restricted to the documents of this view that
+// match the filter as well.
+func (v *View) View(filter Filter) *View {
+	return &View{
+		collection: v.collection,
+		filter:     v.filter.And(filter),
+	}
+}
+
+// Filter returns the filter of the view.
+func (v *View) Filter() Filter {
+	return v.filter
+}
+
+// Count returns the number of documents in the view.
+func (v *View) Count() (int, error) {
+	c := v.collection
+	c.documentsLock.RLock()
+	defer c.documentsLock.RUnlock()
+	if c.closed {
+		return 0, ErrClosed
+	}
+	if v.filter.isEmpty() {
+		return len(c.documents), nil
+	}
+	docs, err := c.filterWith(c.column.docs(c.documents), nil, nil, v.filter)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't filter documents: %w", err)
+	}
+	return len(docs), nil
+}
+
+// GetByID returns a document of the view by its ID, like
+// [Collection.GetByID]. Documents that exist in the collection but not in the
+// view aren't found.
+func (v *View) GetByID(ctx context.Context, id string) (Document, error) {
+	doc, err := v.collection.GetByID(ctx, id)
+	if err != nil {
+		return Document{}, err
+	}
+	if !v.filter.matches(&doc) {
+		return Document{}, fmt.Errorf("document with ID '%v' %w", id, ErrNotFound)
+	}
+	return doc, nil
+}
+
+// GetWhere returns copies of the documents of the view that match the
+// filters, sorted by ID.
+//
+//   - where: Conditional filtering on metadata. Optional.
+//   - whereDocument: Conditional filtering on documents. Optional.
+func (v *View) GetWhere(_ context.Context, where, whereDocument map[string]string) ([]Document, error) {
+	if err := validateWhereDocument(whereDocument); err != nil {
+		return nil, err
+	}
+	return v.collection.copyDocuments(where, whereDocument, v.filter)
+}
+
+// Query is like [Collection.Query], but only considers the documents of the
+// view.
+func (v *View) Query(ctx context.Context, queryText string, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return v.QueryWithOptions(ctx, QueryOptions{
+		QueryText:     queryText,
+		NResults:      nResults,
+		Where:         where,
+		WhereDocument: whereDocument,
+	})
+}
+
+// QueryEmbedding is like [Collection.QueryEmbedding], but only considers the
+// documents of the view.
+func (v *View) QueryEmbedding(ctx context.Context, queryEmbedding []float32, nResults int, where, whereDocument map[string]string) ([]Result, error) {
+	return v.QueryWithOptions(ctx, QueryOptions{
+		QueryEmbedding: queryEmbedding,
+		NResults:       nResults,
+		Where:          where,
+		WhereDocument:  whereDocument,
+	})
+}
+
+// QueryWithOptions is like [Collection.QueryWithOptions], but only considers
+// the documents of the view. The view's filter is combined with the filter of
+// the options.
+func (v *View) QueryWithOptions(ctx context.Context, options QueryOptions) ([]Result, error) {
+	options.Filter = v.filter.And(options.Filter)
+	return v.collection.QueryWithOptions(ctx, options)
+}
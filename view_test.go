@@ -0,0 +1,84 @@
+package chromem
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCollection_View(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, func(_ context.Context, _ string) ([]float32, error) {
+		return []float32{1, 0}, nil
+	})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocuments(ctx, []Document{
+		{ID: "a1", Metadata: map[string]string{"tenant": "a", "lang": "en"}, Embedding: []float32{1, 0}, Content: "hello"},
+		{ID: "a2", Metadata: map[string]string{"tenant": "a", "lang": "de"}, Embedding: []float32{0, 1}, Content: "hallo"},
+		{ID: "b1", Metadata: map[string]string{"tenant": "b", "lang": "en"}, Embedding: []float32{1, 0}, Content: "hello"},
+	}, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	v := c.View(Where().Eq("tenant", "a"))
+	n, err := v.Count()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if n != 2 {
+		t.Fatal("expected 2 documents, got", n)
+	}
+
+	res, err := v.Query(ctx, "hello", 3, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 2 || res[0].ID != "a1" || res[1].ID != "a2" {
+		t.Fatal("expected the tenant's documents, got", res)
+	}
+	res, err = v.QueryEmbedding(ctx, []float32{1, 0}, 3, map[string]string{"lang": "de"}, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].ID != "a2" {
+		t.Fatal("expected a2, got", res)
+	}
+
+	docs, err := v.GetWhere(ctx, nil, map[string]string{"$contains": "hello"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "a1" {
+		t.Fatal("expected a1, got", docs)
+	}
+
+	_, err = v.GetByID(ctx, "b1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("expected ErrNotFound, got", err)
+	}
+	doc, err := v.GetByID(ctx, "a2")
+	if err != nil || doc.ID != "a2" {
+		t.Fatal("expected a2, got", doc, err)
+	}
+
+	// Narrowed view, which reflects later changes of the collection
+	en := v.View(Where().Eq("lang", "en"))
+	err = c.AddDocument(ctx, Document{ID: "a3", Metadata: map[string]string{"tenant": "a", "lang": "en"}, Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	docs, err = en.GetWhere(ctx, nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(docs) != 2 || docs[0].ID != "a1" || docs[1].ID != "a3" {
+		t.Fatal("expected a1 and a3, got", docs)
+	}
+	if v.Filter().String() != `"tenant" == "a"` {
+		t.Fatal("expected the view's filter to be unchanged, got", v.Filter())
+	}
+}
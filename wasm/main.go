@@ -10,12 +10,20 @@ import (
 	"github.com/philippgille/chromem-go"
 )
 
-var c *chromem.Collection
+const collectionName = "chromem"
+
+var (
+	db            *chromem.DB
+	embeddingFunc chromem.EmbeddingFunc
+	c             *chromem.Collection
+)
 
 func main() {
 	js.Global().Set("initDB", js.FuncOf(initDB))
 	js.Global().Set("addDocument", js.FuncOf(addDocument))
 	js.Global().Set("query", js.FuncOf(query))
+	js.Global().Set("saveDB", js.FuncOf(saveDB))
+	js.Global().Set("loadDB", js.FuncOf(loadDB))
 
 	select {} // prevent main from exiting
 }
@@ -28,11 +36,11 @@ func initDB(this js.Value, args []js.Value) interface{} {
 	}
 
 	openAIAPIKey := args[0].String()
-	embeddingFunc := chromem.NewEmbeddingFuncOpenAI(openAIAPIKey, chromem.EmbeddingModelOpenAI3Small)
+	embeddingFunc = chromem.NewEmbeddingFuncOpenAI(openAIAPIKey, chromem.EmbeddingModelOpenAI3Small)
 
-	db := chromem.NewDB()
+	db = chromem.NewDB()
 	var err error
-	c, err = db.CreateCollection("chromem", nil, embeddingFunc)
+	c, err = db.CreateCollection(collectionName, nil, embeddingFunc)
 	if err != nil {
 		return err.Error()
 	}
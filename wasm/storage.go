@@ -0,0 +1,161 @@
+//go:build js
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"syscall/js"
+)
+
+// The DB is stored as a single gob export in an IndexedDB object store, so it
+// survives page reloads. The in-memory DB is the primary storage, IndexedDB is
+// only written to on saveDB().
+const (
+	idbName      = "chromem-go"
+	idbStoreName = "db"
+	idbKey       = "export"
+)
+
+// Exported function to save the DB to the browser's IndexedDB.
+// Takes no arguments. Returns a promise.
+func saveDB(this js.Value, args []js.Value) interface{} {
+	return newPromise(func() (interface{}, error) {
+		if db == nil {
+			return nil, errors.New("DB is not initialized")
+		}
+		buf := &bytes.Buffer{}
+		err := db.ExportToWriter(buf, true, "")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't export DB: %w", err)
+		}
+
+		data := js.Global().Get("Uint8Array").New(buf.Len())
+		js.CopyBytesToJS(data, buf.Bytes())
+		err = idbDo("readwrite", func(store js.Value) js.Value {
+			return store.Call("put", data, idbKey)
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't write to IndexedDB: %w", err)
+		}
+		return nil, nil
+	})
+}
+
+// Exported function to load the DB from the browser's IndexedDB, overwriting the
+// collection in memory. Must be called after initDB().
+// Takes no arguments. Returns a promise that resolves to true if a saved DB was
+// found, false otherwise.
+func loadDB(this js.Value, args []js.Value) interface{} {
+	return newPromise(func() (interface{}, error) {
+		if db == nil {
+			return nil, errors.New("DB is not initialized")
+		}
+
+		var data []byte
+		err := idbDo("readonly", func(store js.Value) js.Value {
+			return store.Call("get", idbKey)
+		}, func(result js.Value) {
+			if result.IsUndefined() {
+				return
+			}
+			data = make([]byte, result.Get("length").Int())
+			js.CopyBytesToGo(data, result)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read from IndexedDB: %w", err)
+		}
+		if data == nil {
+			return false, nil
+		}
+
+		err = db.ImportFromReader(bytes.NewReader(data), "")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't import DB: %w", err)
+		}
+		c = db.GetCollection(collectionName, embeddingFunc)
+		if c == nil {
+			return nil, errors.New("saved DB doesn't contain the collection")
+		}
+		return true, nil
+	})
+}
+
+// idbDo opens the IndexedDB, runs the request that newRequest creates on the
+// object store in a transaction with the given mode, and passes the request's
+// result to onResult (if not nil). It blocks, so it must not be called on the
+// JS event loop, but in a goroutine.
+func idbDo(mode string, newRequest func(store js.Value) js.Value, onResult func(result js.Value)) error {
+	openReq := js.Global().Get("indexedDB").Call("open", idbName, 1)
+	upgrade := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		openReq.Get("result").Call("createObjectStore", idbStoreName)
+		return nil
+	})
+	defer upgrade.Release()
+	openReq.Set("onupgradeneeded", upgrade)
+	idb, err := await(openReq)
+	if err != nil {
+		return fmt.Errorf("couldn't open database: %w", err)
+	}
+	defer idb.Call("close")
+
+	store := idb.Call("transaction", idbStoreName, mode).Call("objectStore", idbStoreName)
+	result, err := await(newRequest(store))
+	if err != nil {
+		return err
+	}
+	if onResult != nil {
+		onResult(result)
+	}
+	return nil
+}
+
+// await waits for an IndexedDB request to succeed or fail.
+func await(req js.Value) (js.Value, error) {
+	type outcome struct {
+		result js.Value
+		err    error
+	}
+	ch := make(chan outcome, 1)
+	onSuccess := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		ch <- outcome{result: req.Get("result")}
+		return nil
+	})
+	defer onSuccess.Release()
+	onError := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		msg := "unknown error"
+		if e := req.Get("error"); !e.IsNull() && !e.IsUndefined() {
+			msg = e.Get("message").String()
+		}
+		ch <- outcome{err: errors.New(msg)}
+		return nil
+	})
+	defer onError.Release()
+	req.Set("onsuccess", onSuccess)
+	req.Set("onerror", onError)
+
+	o := <-ch
+	return o.result, o.err
+}
+
+// newPromise returns a JS promise that runs fn in a goroutine and resolves with
+// its result or rejects with its error.
+func newPromise(fn func() (interface{}, error)) js.Value {
+	handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		resolve := args[0]
+		reject := args[1]
+		go func() {
+			res, err := fn()
+			if err != nil {
+				handleErr(err, reject)
+				return
+			}
+			resolve.Invoke(res)
+		}()
+		return nil
+	})
+
+	promiseConstructor := js.Global().Get("Promise")
+	return promiseConstructor.New(handler)
+}
@@ -0,0 +1,126 @@
+package chromem
+
+import (
+	"context"
+	"sync"
+)
+
+// Number of events that are buffered per watcher, see [Collection.Watch].
+const watchBufferSize = 1024
+
+// EventType is the type of a change to a collection's documents.
+type EventType string
+
+const (
+	// EventAdd is emitted when a new document is added.
+	EventAdd EventType = "add"
+	// EventUpdate is emitted when an existing document is replaced.
+	EventUpdate EventType = "update"
+	// EventDelete is emitted when a document is deleted.
+	EventDelete EventType = "delete"
+)
+
+// Event is a change to a collection's documents, see [Collection.Watch].
+type Event struct {
+	Type       EventType
+	DocumentID string
+	// Document is the new document for add and update events, and nil for
+	// delete events. It's shared with the collection and must not be modified.
+	Document *Document
+}
+
+type watcher struct {
+	ch chan Event
+	// Stops the removal of the watcher when its context is done.
+	stop func() bool
+}
+
+// close closes the watcher's channel and stops waiting for its context. Must be
+// called while holding the lock of the watchers, after unsubscribing.
+func (w *watcher) close() {
+	w.stop()
+	close(w.ch)
+}
+
+// watchers manages the subscriptions of a collection.
+type watchers struct {
+	subs map[*watcher]struct{}
+	// Set by closeAll, so that no new watchers subscribe afterwards.
+	closed bool
+	lock   sync.Mutex
+}
+
+// Watch returns a channel that receives an event for each document that's
+// added, updated or deleted in the collection, in the order of the changes,
+// so that downstream systems like caches, search UIs or replicas can react to
+// them without polling. Changes of the embeddings by [Collection.Reembed] and
+// [Collection.FitProjection] are not included.
+//
+// The channel is closed when the context is done or the DB is closed, and it's
+// closed right away if the DB is already closed. Events
+// are buffered, but the collection doesn't wait for slow receivers: If the
+// buffer is full, the channel is closed as well, and the receiver has to call
+// Watch again and re-read the collection to catch up.
+func (c *Collection) Watch(ctx context.Context) <-chan Event {
+	w := &watcher{ch: make(chan Event, watchBufferSize)}
+
+	c.watchers.lock.Lock()
+	defer c.watchers.lock.Unlock()
+	if c.watchers.closed {
+		close(w.ch)
+		return w.ch
+	}
+	if c.watchers.subs == nil {
+		c.watchers.subs = make(map[*watcher]struct{})
+	}
+	c.watchers.subs[w] = struct{}{}
+	// Instead of a goroutine per watcher that waits for the context, which
+	// would leak when the channel is closed for another reason.
+	w.stop = context.AfterFunc(ctx, func() {
+		c.watchers.remove(w)
+	})
+
+	return w.ch
+}
+
+// emit sends the event to all watchers without blocking. It must be called
+// while holding the documents write lock, so that the order of the events
+// matches the order of the changes.
+func (ws *watchers) emit(e Event) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	for w := range ws.subs {
+		select {
+		case w.ch <- e:
+		default:
+			// Slow receiver, see [Collection.Watch].
+			delete(ws.subs, w)
+			w.close()
+		}
+	}
+}
+
+// remove closes the watcher's channel if it's still subscribed.
+func (ws *watchers) remove(w *watcher) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	if _, ok := ws.subs[w]; ok {
+		delete(ws.subs, w)
+		w.close()
+	}
+}
+
+// closeAll closes the channels of all watchers, and of the ones that are
+// created afterwards.
+func (ws *watchers) closeAll() {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	ws.closed = true
+	for w := range ws.subs {
+		delete(ws.subs, w)
+		w.close()
+	}
+}
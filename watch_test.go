@@ -0,0 +1,77 @@
+package chromem
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollection_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db := NewDB()
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	events := c.Watch(ctx)
+
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{1, 0}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.AddDocument(ctx, Document{ID: "1", Embedding: []float32{0, 1}})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	err = c.Delete(ctx, nil, nil, "1", "unknown")
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+
+	want := []EventType{EventAdd, EventUpdate, EventDelete}
+	for _, wantType := range want {
+		e := <-events
+		if e.Type != wantType || e.DocumentID != "1" {
+			t.Fatal("expected", wantType, "for 1, got", e.Type, "for", e.DocumentID)
+		}
+		if (e.Document == nil) != (wantType == EventDelete) {
+			t.Fatal("unexpected document", e.Document)
+		}
+	}
+
+	// The channel is closed when the context is done.
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed channel")
+	}
+
+	// Slow receivers are dropped instead of blocking writes.
+	events = c.Watch(context.Background())
+	for i := 0; i < watchBufferSize+1; i++ {
+		err = c.AddDocument(context.Background(), Document{ID: "1", Embedding: []float32{1, 0}})
+		if err != nil {
+			t.Fatal("expected no error, got", err)
+		}
+	}
+	n := 0
+	for range events {
+		n++
+	}
+	if n != watchBufferSize {
+		t.Fatal("expected", watchBufferSize, "events, got", n)
+	}
+
+	// Closing the DB closes the channels, including the ones of later calls.
+	events = c.Watch(context.Background())
+	err = db.Close()
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if _, ok := <-events; ok {
+		t.Fatal("expected closed channel")
+	}
+	if _, ok := <-c.Watch(context.Background()); ok {
+		t.Fatal("expected closed channel")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/philippgille/chromem-go/zstd
+
+go 1.22
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/philippgille/chromem-go v0.0.0
+)
+
+replace github.com/philippgille/chromem-go => ./..
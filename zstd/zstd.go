@@ -0,0 +1,66 @@
+// Package zstd provides a [Zstandard] codec for the in-memory content
+// compression of chromem-go (github.com/klauspost/compress). It compresses
+// better and decompresses faster than the built-in DEFLATE codec.
+//
+// Importing the package registers the codec, so that collections can use it via
+// [chromem.Collection.SetContentCompressionWithCodec] with [Name], and
+// persistent DBs with such collections can be loaded.
+//
+// It's a separate Go module, so that the main chromem-go module stays free of
+// third-party dependencies.
+//
+// [Zstandard]: https://facebook.github.io/zstd/
+package zstd
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/philippgille/chromem-go"
+)
+
+// Name is the name of the codec, see
+// [chromem.Collection.SetContentCompressionWithCodec].
+const Name = "zstd"
+
+// Codec compresses with Zstandard. It's registered when importing the package.
+var Codec chromem.Codec = codec{}
+
+func init() {
+	err := chromem.RegisterCodec(Codec)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// The encoder and decoder are safe for concurrent use of EncodeAll and
+// DecodeAll, so they're shared. The blocks are compressed and decompressed as
+// a whole, so there's no need for goroutines of their own.
+var (
+	encoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+	})
+	decoder = sync.OnceValues(func() (*zstd.Decoder, error) {
+		return zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+)
+
+type codec struct{}
+
+func (codec) Name() string { return Name }
+
+func (codec) Compress(dst, src []byte) ([]byte, error) {
+	enc, err := encoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(src, dst), nil
+}
+
+func (codec) Decompress(dst, src []byte) ([]byte, error) {
+	dec, err := decoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(src, dst)
+}
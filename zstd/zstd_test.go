@@ -0,0 +1,71 @@
+package zstd
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/chromem-go"
+)
+
+func TestCodec(t *testing.T) {
+	ctx := context.Background()
+	path, err := os.MkdirTemp(os.TempDir(), "")
+	if err != nil {
+		t.Fatal("couldn't create temp dir:", err)
+	}
+	defer os.RemoveAll(path)
+
+	db, err := chromem.NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c, err := db.CreateCollection("test", nil, nil)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	content := func(i int) string {
+		return strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) + strconv.Itoa(i)
+	}
+	var docs []chromem.Document
+	for i := 0; i < 8; i++ {
+		docs = append(docs, chromem.Document{ID: strconv.Itoa(i), Content: content(i), Embedding: []float32{1, float32(i) / 10}})
+	}
+	err = c.AddDocuments(ctx, docs, 1)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	uncompressed := c.MemoryUsage().Content
+
+	err = c.SetContentCompressionWithCodec(4, Name)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if usage := c.MemoryUsage().Content; usage >= uncompressed/2 {
+		t.Fatal("expected less than half of", uncompressed, "bytes of content memory, got", usage)
+	}
+	doc, err := c.GetByID(ctx, "5")
+	if err != nil || doc.Content != content(5) {
+		t.Fatal("expected content of document 5, got", doc.Content, err)
+	}
+
+	// The codec is persisted, and the contents are compressed with it again
+	// when loading.
+	db2, err := chromem.NewPersistentDB(path, false)
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	c2 := db2.GetCollection("test", nil)
+	if c2.ContentCompressionCodec() != Name {
+		t.Fatal("expected zstd after loading, got", c2.ContentCompressionCodec())
+	}
+	res, err := c2.QueryEmbedding(ctx, []float32{1, 0}, 1, nil, map[string]string{"$contains": "dog. 3"})
+	if err != nil {
+		t.Fatal("expected no error, got", err)
+	}
+	if len(res) != 1 || res[0].Content != content(3) {
+		t.Fatal("expected document 3, got", res)
+	}
+}